@@ -13,6 +13,23 @@
 \i fixtures/chain_social_links.sql
 \i fixtures/chain_assets.sql
 
+-- Fill in the exact amounts that trades are priced from
+UPDATE virtual_pools vp SET
+    cnpy_reserve_ucnpy = round(vp.cnpy_reserve * 1000000),
+    token_reserve_units = vp.token_reserve * power(10::numeric, c.token_decimals)
+FROM chains c WHERE c.id = vp.chain_id;
+UPDATE virtual_pool_transactions t SET
+    cnpy_amount_ucnpy = round(t.cnpy_amount * 1000000),
+    token_amount_units = t.token_amount * power(10::numeric, c.token_decimals),
+    pool_cnpy_reserve_after_ucnpy = round(t.pool_cnpy_reserve_after * 1000000),
+    pool_token_reserve_after_units = t.pool_token_reserve_after * power(10::numeric, c.token_decimals)
+FROM chains c WHERE c.id = t.chain_id;
+UPDATE user_virtual_positions p SET
+    token_balance_units = p.token_balance * power(10::numeric, c.token_decimals),
+    total_cnpy_invested_ucnpy = round(p.total_cnpy_invested * 1000000),
+    total_cnpy_withdrawn_ucnpy = round(p.total_cnpy_withdrawn * 1000000)
+FROM chains c WHERE c.id = p.chain_id;
+
 -- Update user statistics based on fixture data
 UPDATE users SET
     total_chains_created = (SELECT COUNT(*) FROM chains WHERE created_by = users.id),
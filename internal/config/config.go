@@ -1,6 +1,10 @@
 package config
 
 import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -18,6 +22,10 @@ type Config struct {
 	// Security configuration
 	JWTSecret          string
 	JWTExpirationHours int
+	AdminAPIKey        string // Bearer token for the admin endpoints; they are disabled when empty
+	QuoteSecret        string // Key signing trade quotes; derived from JWT_SECRET when unset outside production
+	QuoteTTL           time.Duration
+	ChainKeyPassword   string // Encrypts the keys generated for chains, which sign payouts; required
 
 	// External services
 	GithubClientID     string
@@ -31,32 +39,82 @@ type Config struct {
 	DefaultPageSize int
 	MaxPageSize     int
 
-	// Root chain configuration
+	// Root chain configuration. RootChains lists every root chain served; new
+	// chains are created on the first. The ROOT_CHAIN_* settings describe the
+	// root chain used when ROOT_CHAINS is not set.
+	RootChains      []RootChainConfig
 	RootChainURL    string // WebSocket URL for root chain subscription
 	RootChainID     uint64 // Chain ID to subscribe to
 	RootChainRPCURL string // HTTP URL for RPC client to fetch transactions
+	RootChainStart  uint64 // Height to start ingesting from when no checkpoint exists (0 = first height seen)
+	RootChainDepth  uint64 // Confirmation depth: blocks required above a height before its deposits are applied
+	RootNetworkID   uint64 // Network ID signed into payout transactions sent on the root chain
 
 	// Graduation configuration
-	GraduationRPCURL string // HTTP URL for graduation RPC endpoint
+	GraduationRPCURL    string // HTTP URL for graduation RPC endpoint
+	GraduationRPCSecret string // Shared secret signing graduation requests and deployer callbacks
+
+	// Trading fee split, in basis points of each trade's fee; the shares must add up to 10000
+	FeeProtocolBasisPoints uint64 // Share kept by the protocol treasury
+	FeeCreatorBasisPoints  uint64 // Share owed to the creator of the traded chain
+	FeeReferrerBasisPoints uint64 // Share owed to the trade's referrer; the protocol keeps it when there is none
+}
+
+// RootChainConfig describes a root chain the launchpad takes deposits from and
+// sends payouts on
+type RootChainConfig struct {
+	ID            uint64 `json:"id"`            // Root chain ID
+	URL           string `json:"url"`           // WebSocket URL for the subscription
+	RPCURL        string `json:"rpc_url"`       // HTTP URL for the RPC client
+	StartHeight   uint64 `json:"start_height"`  // Height to start ingesting from when no checkpoint exists
+	Confirmations uint64 `json:"confirmations"` // Blocks required above a height before its deposits are applied
+	NetworkID     uint64 `json:"network_id"`    // Network ID signed into payout transactions
 }
 
 func Load() (*Config, error) {
 	cfg := &Config{
-		Port:               getEnv("PORT", "3001"),
-		Environment:        getEnv("ENVIRONMENT", "development"),
-		DatabaseURL:        getEnv("DATABASE_URL", ""),
-		JWTSecret:          getEnv("JWT_SECRET", ""),
-		JWTExpirationHours: getEnvInt("JWT_EXPIRATION_HOURS", 24),
-		GithubClientID:     getEnv("GITHUB_CLIENT_ID", ""),
-		GithubClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
-		MaxFileUploadSize:  getEnvInt64("MAX_FILE_UPLOAD_SIZE", 10*1024*1024), // 10MB
-		RequestTimeout:     time.Duration(getEnvInt("REQUEST_TIMEOUT_SECONDS", 60)) * time.Second,
-		DefaultPageSize:    getEnvInt("DEFAULT_PAGE_SIZE", 20),
-		MaxPageSize:        getEnvInt("MAX_PAGE_SIZE", 100),
-		RootChainURL:       getEnv("ROOT_CHAIN_URL", "ws://localhost:8081"),
-		RootChainID:        uint64(getEnvInt("ROOT_CHAIN_ID", 1)),
-		RootChainRPCURL:    getEnv("ROOT_CHAIN_RPC_URL", "http://localhost:8081"),
-		GraduationRPCURL:   getEnv("GRADUATION_RPC_URL", "http://localhost:8082/graduate"),
+		Port:                getEnv("PORT", "3001"),
+		Environment:         getEnv("ENVIRONMENT", "development"),
+		DatabaseURL:         getEnv("DATABASE_URL", ""),
+		JWTSecret:           getEnv("JWT_SECRET", ""),
+		JWTExpirationHours:  getEnvInt("JWT_EXPIRATION_HOURS", 24),
+		AdminAPIKey:         getEnv("ADMIN_API_KEY", ""),
+		QuoteSecret:         getEnv("QUOTE_SECRET", ""),
+		QuoteTTL:            time.Duration(getEnvInt("QUOTE_TTL_SECONDS", 30)) * time.Second,
+		ChainKeyPassword:    getEnv("CHAIN_KEY_PASSWORD", ""),
+		GithubClientID:      getEnv("GITHUB_CLIENT_ID", ""),
+		GithubClientSecret:  getEnv("GITHUB_CLIENT_SECRET", ""),
+		MaxFileUploadSize:   getEnvInt64("MAX_FILE_UPLOAD_SIZE", 10*1024*1024), // 10MB
+		RequestTimeout:      time.Duration(getEnvInt("REQUEST_TIMEOUT_SECONDS", 60)) * time.Second,
+		DefaultPageSize:     getEnvInt("DEFAULT_PAGE_SIZE", 20),
+		MaxPageSize:         getEnvInt("MAX_PAGE_SIZE", 100),
+		RootChainURL:        getEnv("ROOT_CHAIN_URL", "ws://localhost:8081"),
+		RootChainID:         uint64(getEnvInt("ROOT_CHAIN_ID", 1)),
+		RootChainRPCURL:     getEnv("ROOT_CHAIN_RPC_URL", "http://localhost:8081"),
+		RootChainStart:      uint64(getEnvInt64("ROOT_CHAIN_START_HEIGHT", 0)),
+		RootChainDepth:      uint64(getEnvInt("ROOT_CHAIN_CONFIRMATIONS", 0)),
+		RootNetworkID:       uint64(getEnvInt("ROOT_CHAIN_NETWORK_ID", 1)),
+		GraduationRPCURL:    getEnv("GRADUATION_RPC_URL", "http://localhost:8082/graduate"),
+		GraduationRPCSecret: getEnv("GRADUATION_RPC_SECRET", ""),
+
+		FeeProtocolBasisPoints: uint64(getEnvInt("FEE_PROTOCOL_BPS", 5000)),
+		FeeCreatorBasisPoints:  uint64(getEnvInt("FEE_CREATOR_BPS", 4000)),
+		FeeReferrerBasisPoints: uint64(getEnvInt("FEE_REFERRER_BPS", 1000)),
+	}
+
+	rootChains, err := cfg.loadRootChains(getEnv("ROOT_CHAINS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.RootChains = rootChains
+
+	// Outside production the quote key may be derived from the JWT secret, under
+	// its own label so that neither key can stand in for the other
+	if cfg.QuoteSecret == "" && !cfg.IsProduction() {
+		cfg.QuoteSecret, err = deriveSecret(cfg.JWTSecret, quoteSecretLabel)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -79,9 +137,108 @@ func (c *Config) validate() error {
 		return fmt.Errorf("JWT_SECRET must be at least 32 characters long")
 	}
 
+	if c.ChainKeyPassword == "" {
+		return fmt.Errorf("CHAIN_KEY_PASSWORD is required")
+	}
+
+	if c.IsProduction() && c.QuoteSecret == "" {
+		return fmt.Errorf("QUOTE_SECRET is required in production")
+	}
+
+	if c.QuoteSecret == c.JWTSecret {
+		return fmt.Errorf("QUOTE_SECRET must differ from JWT_SECRET")
+	}
+
+	if c.IsProduction() && c.GraduationRPCSecret == "" {
+		return fmt.Errorf("GRADUATION_RPC_SECRET is required in production")
+	}
+
+	if total := c.FeeProtocolBasisPoints + c.FeeCreatorBasisPoints + c.FeeReferrerBasisPoints; total != 10000 {
+		return fmt.Errorf("FEE_PROTOCOL_BPS, FEE_CREATOR_BPS and FEE_REFERRER_BPS must add up to 10000, got %d", total)
+	}
+
+	seen := make(map[uint64]bool)
+	for _, rootChain := range c.RootChains {
+		if rootChain.ID == 0 {
+			return fmt.Errorf("ROOT_CHAINS entries require an id")
+		}
+		if seen[rootChain.ID] {
+			return fmt.Errorf("root chain %d is configured more than once", rootChain.ID)
+		}
+		seen[rootChain.ID] = true
+		if rootChain.URL == "" || rootChain.RPCURL == "" {
+			return fmt.Errorf("root chain %d requires url and rpc_url", rootChain.ID)
+		}
+	}
+
 	return nil
 }
 
+// loadRootChains parses the ROOT_CHAINS JSON array. Without it the launchpad
+// serves the single root chain set by the ROOT_CHAIN_* variables. Entries take
+// their confirmations and network ID from those variables unless they set their
+// own, and an entry for ROOT_CHAIN_ID also inherits its URLs and start height.
+func (c *Config) loadRootChains(raw string) ([]RootChainConfig, error) {
+	defaults := RootChainConfig{
+		ID:            c.RootChainID,
+		URL:           c.RootChainURL,
+		RPCURL:        c.RootChainRPCURL,
+		StartHeight:   c.RootChainStart,
+		Confirmations: c.RootChainDepth,
+		NetworkID:     c.RootNetworkID,
+	}
+	if raw == "" {
+		return []RootChainConfig{defaults}, nil
+	}
+
+	// Confirmations is a pointer so that an explicit 0 is not mistaken for unset
+	var entries []struct {
+		RootChainConfig
+		Confirmations *uint64 `json:"confirmations"`
+	}
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, fmt.Errorf("invalid ROOT_CHAINS: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("ROOT_CHAINS must list at least one root chain")
+	}
+
+	rootChains := make([]RootChainConfig, len(entries))
+	for i, entry := range entries {
+		rootChain := entry.RootChainConfig
+		if rootChain.ID == defaults.ID {
+			if rootChain.URL == "" {
+				rootChain.URL = defaults.URL
+			}
+			if rootChain.RPCURL == "" {
+				rootChain.RPCURL = defaults.RPCURL
+			}
+			if rootChain.StartHeight == 0 {
+				rootChain.StartHeight = defaults.StartHeight
+			}
+		}
+		rootChain.Confirmations = defaults.Confirmations
+		if entry.Confirmations != nil {
+			rootChain.Confirmations = *entry.Confirmations
+		}
+		if rootChain.NetworkID == 0 {
+			rootChain.NetworkID = defaults.NetworkID
+		}
+		rootChains[i] = rootChain
+	}
+
+	return rootChains, nil
+}
+
+// RootChainIDs returns the IDs of the root chains served, in configured order
+func (c *Config) RootChainIDs() []uint64 {
+	ids := make([]uint64, len(c.RootChains))
+	for i, rootChain := range c.RootChains {
+		ids[i] = rootChain.ID
+	}
+	return ids
+}
+
 func (c *Config) IsDevelopment() bool {
 	return c.Environment == "development"
 }
@@ -90,6 +247,19 @@ func (c *Config) IsProduction() bool {
 	return c.Environment == "production"
 }
 
+// quoteSecretLabel is the HKDF info a quote key derived from JWT_SECRET is bound to
+const quoteSecretLabel = "launchpad/quote-signing/v1"
+
+// deriveSecret derives a hex encoded 32 byte key from secret with HKDF-SHA256
+// under label, so keys derived for different labels are unrelated
+func deriveSecret(secret, label string) (string, error) {
+	key, err := hkdf.Key(sha256.New, []byte(secret), nil, label, 32)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive %s key: %w", label, err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,410 @@
+package graduator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canopy-network/canopy/fsm"
+	"github.com/canopy-network/canopy/lib"
+	"github.com/canopy-network/canopy/lib/crypto"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/google/uuid"
+)
+
+// GenesisInput contains everything the genesis builder needs for a chain
+type GenesisInput struct {
+	Chain       *models.Chain
+	RootChainID uint64
+	Validator   *models.ChainKey
+	// Treasury receives the treasury bucket; only required when the bucket is non-empty
+	Treasury *models.ChainKey
+	// Vesting schedules locking part of the creator bucket
+	Vesting []models.ChainVestingSchedule
+	Holders []interfaces.UserPositionWithAddress
+	Time    time.Time
+}
+
+// BuildGenesis builds the canopy genesis state for a graduating chain. Every
+// amount is in token base units, so fractional holdings carry over exactly. The
+// total supply is split by the chain's token allocation: holder balances, the creator
+// and treasury buckets become accounts (sorted by address so the output is
+// stable), and the chain operation key becomes the sole genesis validator staked
+// at the chain's minimum stake, holding the rest of the liquidity reserve as its
+// balance. Vesting schedules become unstaking delegate stakes released to their
+// beneficiaries (see buildVestingStakes). Block-count params are scaled to the
+// chain's block time. The result is validated before it is returned, and must
+// allocate exactly the total supply.
+func BuildGenesis(input GenesisInput) (*fsm.GenesisState, error) {
+	if input.Chain == nil {
+		return nil, errors.New("chain is required")
+	}
+	if input.Validator == nil {
+		return nil, errors.New("genesis validator key is required")
+	}
+	if input.Time.IsZero() {
+		return nil, errors.New("genesis time is required")
+	}
+	if input.RootChainID == 0 {
+		return nil, errors.New("root chain id is required")
+	}
+
+	validator, err := buildGenesisValidator(input.Validator, input.Chain.ValidatorMinStake, input.Chain.TokenUnit(), input.RootChainID)
+	if err != nil {
+		return nil, err
+	}
+
+	vesting, err := buildVestingStakes(input)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := buildGenesisAccounts(input, validator, vesting)
+	if err != nil {
+		return nil, err
+	}
+
+	params := fsm.DefaultParams()
+	params.Consensus.RootChainId = input.RootChainID
+	if input.Chain.BlockTimeSeconds != nil {
+		if err := scaleBlockParams(params.Validator, *input.Chain.BlockTimeSeconds); err != nil {
+			return nil, err
+		}
+	}
+
+	genesis := &fsm.GenesisState{
+		// The genesis file only carries second precision
+		Time:       uint64(input.Time.UTC().Truncate(time.Second).UnixMicro()),
+		Accounts:   accounts,
+		Validators: append([]*fsm.Validator{validator}, vesting...),
+		Params:     params,
+	}
+
+	if err := ValidateGenesis(genesis, input.Chain.TokenTotalSupplyUnits()); err != nil {
+		return nil, err
+	}
+
+	return genesis, nil
+}
+
+// ValidateGenesis applies canopy's own genesis checks and verifies the
+// allocated balances and stakes add up to exactly the chain's total token
+// supply, given in base units
+func ValidateGenesis(genesis *fsm.GenesisState, totalSupply int64) error {
+	if genesis.Params == nil {
+		return errors.New("invalid genesis: params are required")
+	}
+	if len(genesis.Validators) == 0 {
+		return errors.New("invalid genesis: at least one validator is required")
+	}
+
+	// ValidateGenesisState doesn't depend on any state machine fields
+	if err := new(fsm.StateMachine).ValidateGenesisState(genesis); err != nil {
+		return fmt.Errorf("invalid genesis: %w", err)
+	}
+
+	var allocated uint64
+	for _, account := range genesis.Accounts {
+		allocated += account.Amount
+	}
+	for _, validator := range genesis.Validators {
+		allocated += validator.StakedAmount
+	}
+	if totalSupply <= 0 || allocated != uint64(totalSupply) {
+		return fmt.Errorf("invalid genesis: allocated %d does not match total supply %d", allocated, totalSupply)
+	}
+
+	return nil
+}
+
+// MarshalGenesis encodes the genesis state in canonical form (see CanonicalizeGenesis)
+func MarshalGenesis(genesis *fsm.GenesisState) ([]byte, error) {
+	data, err := json.Marshal(genesis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal genesis: %w", err)
+	}
+	return CanonicalizeGenesis(data)
+}
+
+// CanonicalizeGenesis re-encodes genesis JSON with object keys sorted and no
+// insignificant whitespace. Numbers keep their original text, so two genesis
+// files that differ only in formatting canonicalise to the same bytes.
+func CanonicalizeGenesis(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid genesis JSON: %w", err)
+	}
+	if decoder.More() {
+		return nil, errors.New("invalid genesis JSON: unexpected data after top-level value")
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	// encoding/json writes map keys in sorted order
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to encode canonical genesis: %w", err)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// HashGenesis returns the hex-encoded SHA-256 of canonical genesis bytes
+func HashGenesis(canonical []byte) string {
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// ChainIDFromGenesisHash derives the network chain ID from a genesis hash. The
+// ID is the first 8 bytes of the hash with the top bit cleared, so it fits both
+// canopy's uint64 chain IDs and signed 64-bit columns, and anyone holding the
+// genesis file can recompute it.
+func ChainIDFromGenesisHash(hash string) (string, error) {
+	bz, err := hex.DecodeString(hash)
+	if err != nil || len(bz) != sha256.Size {
+		return "", fmt.Errorf("invalid genesis hash %q", hash)
+	}
+	id := binary.BigEndian.Uint64(bz[:8]) &^ (1 << 63)
+	return strconv.FormatUint(id, 10), nil
+}
+
+// buildGenesisAccounts splits the chain's total supply into genesis accounts
+// following its token allocation. Holder balances are paid from the holders
+// bucket and whatever the virtual pool didn't sell joins the liquidity reserve,
+// which funds the validator stake and is credited to the validator for the rest.
+// Vesting stakes are paid from the creator bucket. Duplicate addresses are
+// merged and empty balances dropped.
+func buildGenesisAccounts(input GenesisInput, validator *fsm.Validator, vesting []*fsm.Validator) ([]*fsm.Account, error) {
+	chain := input.Chain
+	if total := chain.TotalBps(); total != models.AllocationTotalBps {
+		return nil, fmt.Errorf("token allocation sums to %d basis points, expected %d", total, models.AllocationTotalBps)
+	}
+	amounts := chain.Amounts(chain.TokenTotalSupplyUnits())
+
+	balances := make(map[string]uint64, len(input.Holders)+3)
+	var sold uint64
+	for _, holder := range input.Holders {
+		if holder.TokenBalanceUnits == 0 {
+			continue
+		}
+		address, err := decodeAddress(holder.WalletAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holder address %q: %w", holder.WalletAddress, err)
+		}
+		balances[string(address)] += holder.TokenBalanceUnits
+		sold += holder.TokenBalanceUnits
+	}
+	if sold > uint64(amounts.Holders) {
+		return nil, fmt.Errorf("holder balances of %d exceed the holders bucket of %d", sold, amounts.Holders)
+	}
+
+	var locked int64
+	for _, stake := range vesting {
+		locked += int64(stake.StakedAmount)
+	}
+	if locked > amounts.Creator {
+		return nil, fmt.Errorf("vesting schedules lock %d token units but the creator bucket is %d", locked, amounts.Creator)
+	}
+
+	if liquid := amounts.Creator - locked; liquid > 0 {
+		if chain.Creator == nil {
+			return nil, errors.New("chain creator is required for the creator allocation")
+		}
+		address, err := decodeAddress(chain.Creator.WalletAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid creator address %q: %w", chain.Creator.WalletAddress, err)
+		}
+		balances[string(address)] += uint64(liquid)
+	}
+
+	if amounts.Treasury > 0 {
+		if input.Treasury == nil {
+			return nil, errors.New("treasury key is required for the treasury allocation")
+		}
+		address, err := decodeAddress(input.Treasury.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid treasury address %q: %w", input.Treasury.Address, err)
+		}
+		balances[string(address)] += uint64(amounts.Treasury)
+	}
+
+	reserve := amounts.Liquidity + amounts.Holders - int64(sold)
+	if reserve < int64(validator.StakedAmount) {
+		return nil, fmt.Errorf("liquidity reserve of %d cannot fund the validator stake of %d", reserve, validator.StakedAmount)
+	}
+	if remaining := uint64(reserve) - validator.StakedAmount; remaining > 0 {
+		balances[string(validator.Address)] += remaining
+	}
+
+	accounts := make([]*fsm.Account, 0, len(balances))
+	for address, amount := range balances {
+		accounts = append(accounts, &fsm.Account{Address: []byte(address), Amount: amount})
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return string(accounts[i].Address) < string(accounts[j].Address)
+	})
+
+	return accounts, nil
+}
+
+// buildGenesisValidator stakes the chain operation key as the genesis validator
+// for the root chain committee. minStake is in whole tokens, unit of which make
+// one, and the stake is rounded up to the next base unit.
+func buildGenesisValidator(key *models.ChainKey, minStake float64, unit int64, rootChainID uint64) (*fsm.Validator, error) {
+	address, err := decodeAddress(key.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validator address %q: %w", key.Address, err)
+	}
+	if minStake <= 0 {
+		return nil, fmt.Errorf("validator minimum stake must be positive, got %v", minStake)
+	}
+
+	return &fsm.Validator{
+		Address:      address,
+		PublicKey:    key.PublicKey,
+		Committees:   []uint64{rootChainID},
+		StakedAmount: stakeUnits(minStake, unit),
+		Output:       address,
+		Compound:     true,
+	}, nil
+}
+
+// stakeUnits converts a whole token stake to base units, rounding up. The stake
+// is taken at its shortest decimal form so binary rounding can't add a unit.
+func stakeUnits(stake float64, unit int64) uint64 {
+	amount, _ := new(big.Rat).SetString(strconv.FormatFloat(stake, 'f', -1, 64))
+	amount.Mul(amount, new(big.Rat).SetInt64(unit))
+	units := new(big.Int).Add(amount.Num(), amount.Denom())
+	units.Sub(units, big.NewInt(1))
+	return units.Quo(units, amount.Denom()).Uint64()
+}
+
+// buildVestingStakes turns the chain's vesting schedules into the locked stakes
+// canopy genesis supports. Canopy has no vesting accounts, but a genesis
+// validator may already be unstaking, in which case its stake is paid to its
+// output address at the unstaking height. Each vesting tranche becomes such a
+// delegate stake: it has no committees, its address is derived from the
+// schedule and has no key, and it unstakes to the beneficiary at the height
+// the tranche vests.
+func buildVestingStakes(input GenesisInput) ([]*fsm.Validator, error) {
+	consensus := lib.DefaultConsensusConfig()
+	blockTimeMS := uint64(consensus.BlockTimeMS())
+	if input.Chain.BlockTimeSeconds != nil && *input.Chain.BlockTimeSeconds > 0 {
+		blockTimeMS = uint64(*input.Chain.BlockTimeSeconds) * 1000
+	}
+
+	var stakes []*fsm.Validator
+	for _, schedule := range input.Vesting {
+		beneficiary, err := decodeAddress(schedule.BeneficiaryAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vesting beneficiary %q: %w", schedule.BeneficiaryAddress, err)
+		}
+		if schedule.DurationDays <= 0 || schedule.CliffDays < 0 || schedule.CliffDays > schedule.DurationDays {
+			return nil, fmt.Errorf("invalid vesting schedule %s: cliff %d days, duration %d days",
+				schedule.ID, schedule.CliffDays, schedule.DurationDays)
+		}
+
+		amount := uint64(models.BpsShare(input.Chain.TokenTotalSupplyUnits(), schedule.AllocationBps))
+		for i, tranche := range vestingTranches(amount, schedule.CliffDays, schedule.DurationDays) {
+			// Genesis is height 1, so a tranche vesting after n blocks unlocks at height n+1
+			elapsedMS := uint64(tranche.days) * uint64(24*time.Hour/time.Millisecond)
+			stakes = append(stakes, &fsm.Validator{
+				Address:         vestingAddress(schedule.ID, i),
+				StakedAmount:    tranche.amount,
+				UnstakingHeight: 1 + (elapsedMS+blockTimeMS-1)/blockTimeMS,
+				Output:          beneficiary,
+				Delegate:        true,
+			})
+		}
+	}
+
+	return stakes, nil
+}
+
+// vestingTranche is an amount released a number of days after genesis
+type vestingTranche struct {
+	days   int
+	amount uint64
+}
+
+// vestingTranches splits a linear vesting schedule into discrete releases:
+// whatever has vested by the cliff is released at the cliff, then whatever
+// vested since every models.VestingTrancheDays until the schedule ends
+func vestingTranches(amount uint64, cliffDays, durationDays int) []vestingTranche {
+	var tranches []vestingTranche
+	var released uint64
+	day := cliffDays
+	if day == 0 {
+		day = min(models.VestingTrancheDays, durationDays)
+	}
+	for {
+		vested := amount * uint64(day) / uint64(durationDays)
+		if vested > released {
+			tranches = append(tranches, vestingTranche{days: day, amount: vested - released})
+			released = vested
+		}
+		if day >= durationDays {
+			return tranches
+		}
+		day = min(day+models.VestingTrancheDays, durationDays)
+	}
+}
+
+// vestingAddress derives the keyless address holding one tranche of a vesting schedule
+func vestingAddress(scheduleID uuid.UUID, tranche int) []byte {
+	sum := sha256.Sum256([]byte("vesting/" + scheduleID.String() + "/" + strconv.Itoa(tranche)))
+	return sum[:crypto.AddressSize]
+}
+
+// scaleBlockParams rescales the default block-count params, which assume
+// canopy's default block time, so they cover the same wall-clock durations
+// at the chain's block time
+func scaleBlockParams(params *fsm.ValidatorParams, blockTimeSeconds int) error {
+	if blockTimeSeconds <= 0 {
+		return fmt.Errorf("block time must be positive, got %d", blockTimeSeconds)
+	}
+
+	consensus := lib.DefaultConsensusConfig()
+	defaultBlockTimeMS := uint64(consensus.BlockTimeMS())
+	blockTimeMS := uint64(blockTimeSeconds) * 1000
+
+	scale := func(blocks uint64) uint64 {
+		scaled := (blocks*defaultBlockTimeMS + blockTimeMS - 1) / blockTimeMS
+		if scaled == 0 {
+			return 1
+		}
+		return scaled
+	}
+
+	params.UnstakingBlocks = scale(params.UnstakingBlocks)
+	params.MaxPauseBlocks = scale(params.MaxPauseBlocks)
+	params.DelegateUnstakingBlocks = scale(params.DelegateUnstakingBlocks)
+	params.BuyDeadlineBlocks = scale(params.BuyDeadlineBlocks)
+
+	return nil
+}
+
+// decodeAddress parses a hex address, with or without a 0x prefix
+func decodeAddress(address string) ([]byte, error) {
+	bz, err := hex.DecodeString(strings.TrimPrefix(address, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(bz) != crypto.AddressSize {
+		return nil, fmt.Errorf("expected %d bytes, got %d", crypto.AddressSize, len(bz))
+	}
+	return bz, nil
+}
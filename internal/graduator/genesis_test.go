@@ -0,0 +1,365 @@
+package graduator
+
+import (
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canopy-network/canopy/fsm"
+	"github.com/canopy-network/canopy/lib/crypto"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGenesisChain(chainID uuid.UUID) *models.Chain {
+	return &models.Chain{
+		ID:                chainID,
+		ChainName:         "TestChain",
+		TokenTotalSupply:  1000000000,
+		ValidatorMinStake: 1000,
+		TokenAllocation:   models.TokenAllocation{LiquidityBps: 2000, HoldersBps: 8000},
+	}
+}
+
+// genesisBalances maps hex account addresses to their genesis balance
+func genesisBalances(genesis *fsm.GenesisState) map[string]uint64 {
+	balances := make(map[string]uint64, len(genesis.Accounts))
+	for _, account := range genesis.Accounts {
+		balances[hex.EncodeToString(account.Address)] = account.Amount
+	}
+	return balances
+}
+
+func newValidatorKey(t *testing.T, chainID uuid.UUID) *models.ChainKey {
+	t.Helper()
+	privateKey, err := crypto.NewBLS12381PrivateKey()
+	require.NoError(t, err)
+	publicKey := privateKey.PublicKey()
+	return &models.ChainKey{
+		ChainID:    chainID,
+		Address:    publicKey.Address().String(),
+		PublicKey:  publicKey.Bytes(),
+		KeyPurpose: models.KeyPurposeChainOperation,
+		IsActive:   true,
+	}
+}
+
+func TestBuildGenesis(t *testing.T) {
+	genesisTime := time.Date(2025, 10, 21, 14, 30, 12, 500, time.UTC)
+	chainID := uuid.New()
+	key := newValidatorKey(t, chainID)
+
+	newInput := func() GenesisInput {
+		return GenesisInput{
+			Chain:       newGenesisChain(chainID),
+			RootChainID: 3,
+			Validator:   key,
+			Holders: []interfaces.UserPositionWithAddress{
+				{WalletAddress: strings.Repeat("bb", 20), TokenBalanceUnits: 200},
+				{WalletAddress: "0x" + strings.Repeat("aa", 20), TokenBalanceUnits: 100},
+				{WalletAddress: strings.Repeat("bb", 20), TokenBalanceUnits: 50},
+				{WalletAddress: strings.Repeat("cc", 20), TokenBalanceUnits: 0},
+			},
+			Time: genesisTime,
+		}
+	}
+
+	t.Run("builds accounts, validator and params", func(t *testing.T) {
+		genesis, err := BuildGenesis(newInput())
+		require.NoError(t, err)
+
+		// Duplicate holders are merged, empty balances dropped, and accounts sorted.
+		// The unsold holders bucket and the liquidity reserve, less the stake, go
+		// to the validator.
+		assert.Equal(t, map[string]uint64{
+			strings.Repeat("aa", 20): 100,
+			strings.Repeat("bb", 20): 250,
+			key.Address:              1000000000 - 350 - 1000,
+		}, genesisBalances(genesis))
+		assert.True(t, sort.SliceIsSorted(genesis.Accounts, func(i, j int) bool {
+			return string(genesis.Accounts[i].Address) < string(genesis.Accounts[j].Address)
+		}))
+
+		require.Len(t, genesis.Validators, 1)
+		validator := genesis.Validators[0]
+		assert.Equal(t, key.Address, hex.EncodeToString(validator.Address))
+		assert.Equal(t, key.PublicKey, validator.PublicKey)
+		assert.Equal(t, []uint64{3}, validator.Committees)
+		assert.Equal(t, uint64(1000), validator.StakedAmount)
+
+		assert.Equal(t, uint64(3), genesis.Params.Consensus.RootChainId)
+		assert.Equal(t, uint64(genesisTime.Truncate(time.Second).UnixMicro()), genesis.Time)
+	})
+
+	t.Run("marshals deterministically", func(t *testing.T) {
+		first, err := BuildGenesis(newInput())
+		require.NoError(t, err)
+		second, err := BuildGenesis(newInput())
+		require.NoError(t, err)
+
+		a, err := MarshalGenesis(first)
+		require.NoError(t, err)
+		b, err := MarshalGenesis(second)
+		require.NoError(t, err)
+		assert.Equal(t, string(a), string(b))
+		assert.Contains(t, string(a), `"rootChainID":3`)
+	})
+
+	t.Run("block params scale with block time", func(t *testing.T) {
+		input := newInput()
+		blockTime := 5
+		input.Chain.BlockTimeSeconds = &blockTime
+
+		genesis, err := BuildGenesis(input)
+		require.NoError(t, err)
+
+		// Canopy's defaults assume 20 second blocks, so 5 second blocks need 4x as many
+		defaults := fsm.DefaultParams().Validator
+		assert.Equal(t, defaults.MaxPauseBlocks*4, genesis.Params.Validator.MaxPauseBlocks)
+		assert.Equal(t, defaults.UnstakingBlocks*4, genesis.Params.Validator.UnstakingBlocks)
+	})
+
+	t.Run("creator and treasury buckets", func(t *testing.T) {
+		input := newInput()
+		creator := strings.Repeat("aa", 20)
+		treasury := newValidatorKey(t, chainID)
+		treasury.KeyPurpose = models.KeyPurposeTreasury
+		input.Chain.Creator = &models.User{WalletAddress: "0x" + creator}
+		input.Chain.TokenAllocation = models.TokenAllocation{CreatorBps: 1000, TreasuryBps: 500, LiquidityBps: 500, HoldersBps: 8000}
+		input.Treasury = treasury
+
+		genesis, err := BuildGenesis(input)
+		require.NoError(t, err)
+
+		// The creator's bucket is added to their own holder balance
+		assert.Equal(t, map[string]uint64{
+			creator:                  100000000 + 100,
+			strings.Repeat("bb", 20): 250,
+			treasury.Address:         50000000,
+			key.Address:              50000000 + 800000000 - 350 - 1000,
+		}, genesisBalances(genesis))
+		require.NoError(t, ValidateGenesis(genesis, input.Chain.TokenTotalSupplyUnits()))
+	})
+
+	t.Run("vesting schedules become unstaking stakes", func(t *testing.T) {
+		input := newInput()
+		creator := strings.Repeat("ee", 20)
+		beneficiary := strings.Repeat("ff", 20)
+		blockTime := 10
+		input.Chain.BlockTimeSeconds = &blockTime
+		input.Chain.Creator = &models.User{WalletAddress: creator}
+		input.Chain.TokenAllocation = models.TokenAllocation{CreatorBps: 1000, LiquidityBps: 1000, HoldersBps: 8000}
+		input.Vesting = []models.ChainVestingSchedule{
+			{ID: uuid.New(), BeneficiaryAddress: "0x" + beneficiary, AllocationBps: 600, CliffDays: 90, DurationDays: 150},
+		}
+
+		genesis, err := BuildGenesis(input)
+		require.NoError(t, err)
+
+		// 60M tokens vest over 150 days: 36M at the 90 day cliff, then 12M every 30 days
+		require.Len(t, genesis.Validators, 4)
+		blocksPerDay := uint64(24 * 60 * 60 / blockTime)
+		expected := []struct {
+			amount uint64
+			days   uint64
+		}{{36000000, 90}, {12000000, 120}, {12000000, 150}}
+		addresses := map[string]bool{}
+		for i, tranche := range expected {
+			stake := genesis.Validators[i+1]
+			assert.True(t, stake.Delegate)
+			assert.Empty(t, stake.Committees)
+			assert.Equal(t, tranche.amount, stake.StakedAmount)
+			assert.Equal(t, 1+tranche.days*blocksPerDay, stake.UnstakingHeight)
+			assert.Equal(t, beneficiary, hex.EncodeToString(stake.Output))
+			addresses[hex.EncodeToString(stake.Address)] = true
+		}
+		assert.Len(t, addresses, 3, "each tranche needs its own address")
+
+		// The unlocked rest of the creator bucket is liquid
+		assert.Equal(t, uint64(40000000), genesisBalances(genesis)[creator])
+		require.NoError(t, ValidateGenesis(genesis, input.Chain.TokenTotalSupplyUnits()))
+
+		var vesting []GenesisAllocation
+		for _, allocation := range genesisAllocations(genesis) {
+			if allocation.Type == AllocationTypeVesting {
+				vesting = append(vesting, allocation)
+			}
+		}
+		require.Len(t, vesting, 3)
+		assert.Equal(t, beneficiary, vesting[0].Address)
+		assert.Equal(t, 1+90*blocksPerDay, vesting[0].ReleaseHeight)
+	})
+
+	t.Run("amounts are in token base units", func(t *testing.T) {
+		input := newInput()
+		input.Chain.TokenDecimals = 6
+		input.Chain.ValidatorMinStake = 1000.5
+		input.Holders = []interfaces.UserPositionWithAddress{
+			{WalletAddress: strings.Repeat("aa", 20), TokenBalanceUnits: 1500000},
+			{WalletAddress: strings.Repeat("bb", 20), TokenBalanceUnits: 1},
+		}
+
+		genesis, err := BuildGenesis(input)
+		require.NoError(t, err)
+
+		// Fractional balances carry over exactly and the supply is minted in base units
+		assert.Equal(t, map[string]uint64{
+			strings.Repeat("aa", 20): 1500000,
+			strings.Repeat("bb", 20): 1,
+			key.Address:              1000000000*1000000 - 1500001 - 1000500000,
+		}, genesisBalances(genesis))
+		assert.Equal(t, uint64(1000500000), genesis.Validators[0].StakedAmount)
+		require.NoError(t, ValidateGenesis(genesis, 1000000000*1000000))
+	})
+
+	t.Run("validation failures", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(*GenesisInput)
+			errMsg string
+		}{
+			{"missing validator", func(in *GenesisInput) { in.Validator = nil }, "validator key is required"},
+			{"missing time", func(in *GenesisInput) { in.Time = time.Time{} }, "genesis time is required"},
+			{"missing root chain", func(in *GenesisInput) { in.RootChainID = 0 }, "root chain id is required"},
+			{"zero stake", func(in *GenesisInput) { in.Chain.ValidatorMinStake = 0 }, "minimum stake must be positive"},
+			{"bad validator public key", func(in *GenesisInput) {
+				k := *key
+				k.PublicKey = []byte{1, 2, 3}
+				in.Validator = &k
+			}, "invalid genesis"},
+			{"bad holder address", func(in *GenesisInput) {
+				in.Holders = append(in.Holders, interfaces.UserPositionWithAddress{WalletAddress: "0xabc", TokenBalanceUnits: 1})
+			}, "invalid holder address"},
+			{"allocation does not sum to total", func(in *GenesisInput) { in.Chain.HoldersBps = 7000 }, "sums to 9000 basis points"},
+			{"holders exceed bucket", func(in *GenesisInput) { in.Chain.TokenTotalSupply = 400 }, "exceed the holders bucket"},
+			{"reserve cannot fund stake", func(in *GenesisInput) {
+				in.Chain.TokenTotalSupply = 1200
+				in.Chain.TokenAllocation = models.TokenAllocation{HoldersBps: 10000}
+			}, "cannot fund the validator stake"},
+			{"creator bucket without creator", func(in *GenesisInput) {
+				in.Chain.TokenAllocation = models.TokenAllocation{CreatorBps: 500, LiquidityBps: 1500, HoldersBps: 8000}
+			}, "chain creator is required"},
+			{"vesting exceeds creator bucket", func(in *GenesisInput) {
+				in.Chain.Creator = &models.User{WalletAddress: strings.Repeat("ee", 20)}
+				in.Chain.TokenAllocation = models.TokenAllocation{CreatorBps: 500, LiquidityBps: 1500, HoldersBps: 8000}
+				in.Vesting = []models.ChainVestingSchedule{
+					{BeneficiaryAddress: strings.Repeat("ff", 20), AllocationBps: 600, DurationDays: 365},
+				}
+			}, "vesting schedules lock"},
+			{"bad vesting beneficiary", func(in *GenesisInput) {
+				in.Vesting = []models.ChainVestingSchedule{{BeneficiaryAddress: "0xabc", AllocationBps: 100, DurationDays: 365}}
+			}, "invalid vesting beneficiary"},
+			{"treasury bucket without key", func(in *GenesisInput) {
+				in.Chain.TokenAllocation = models.TokenAllocation{TreasuryBps: 500, LiquidityBps: 1500, HoldersBps: 8000}
+			}, "treasury key is required"},
+			{"invalid block time", func(in *GenesisInput) {
+				blockTime := 0
+				in.Chain.BlockTimeSeconds = &blockTime
+			}, "block time must be positive"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				input := newInput()
+				tt.modify(&input)
+				_, err := BuildGenesis(input)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			})
+		}
+	})
+}
+
+func TestVestingTranches(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   uint64
+		cliff    int
+		duration int
+		expected []vestingTranche
+	}{
+		{"cliff then monthly", 1200, 30, 90, []vestingTranche{{30, 400}, {60, 400}, {90, 400}}},
+		{"no cliff", 1000, 0, 60, []vestingTranche{{30, 500}, {60, 500}}},
+		{"cliff equals duration", 1000, 365, 365, []vestingTranche{{365, 1000}}},
+		{"short duration", 1000, 0, 10, []vestingTranche{{10, 1000}}},
+		{"last tranche is partial", 1000, 0, 45, []vestingTranche{{30, 666}, {45, 334}}},
+		{"rounding never loses tokens", 7, 0, 90, []vestingTranche{{30, 2}, {60, 2}, {90, 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tranches := vestingTranches(tt.amount, tt.cliff, tt.duration)
+			assert.Equal(t, tt.expected, tranches)
+
+			var total uint64
+			for _, tranche := range tranches {
+				total += tranche.amount
+			}
+			assert.Equal(t, tt.amount, total)
+		})
+	}
+}
+
+func TestStakeUnits(t *testing.T) {
+	assert.Equal(t, uint64(1000), stakeUnits(1000, 1))
+	assert.Equal(t, uint64(1001), stakeUnits(1000.5, 1))
+	assert.Equal(t, uint64(100000), stakeUnits(0.1, 1000000))
+	assert.Equal(t, uint64(1000000001), stakeUnits(1000.0000001, 1000000))
+}
+
+func TestCanonicalizeGenesis(t *testing.T) {
+	t.Run("formatting and key order do not change the canonical bytes", func(t *testing.T) {
+		a, err := CanonicalizeGenesis([]byte(`{"b": 1, "a": {"y": [1, 2], "x": "<tcp>"}}`))
+		require.NoError(t, err)
+		b, err := CanonicalizeGenesis([]byte("{\n  \"a\": {\"x\": \"<tcp>\", \"y\": [1,2]},\n  \"b\": 1\n}\n"))
+		require.NoError(t, err)
+
+		assert.Equal(t, `{"a":{"x":"<tcp>","y":[1,2]},"b":1}`, string(a))
+		assert.Equal(t, a, b)
+		assert.Equal(t, HashGenesis(a), HashGenesis(b))
+	})
+
+	t.Run("large numbers keep their precision", func(t *testing.T) {
+		out, err := CanonicalizeGenesis([]byte(`{"amount": 18446744073709551615}`))
+		require.NoError(t, err)
+		assert.Equal(t, `{"amount":18446744073709551615}`, string(out))
+	})
+
+	t.Run("canonical form is stable", func(t *testing.T) {
+		once, err := CanonicalizeGenesis([]byte(`{"z": null, "a": true}`))
+		require.NoError(t, err)
+		twice, err := CanonicalizeGenesis(once)
+		require.NoError(t, err)
+		assert.Equal(t, once, twice)
+	})
+
+	t.Run("malformed JSON is rejected", func(t *testing.T) {
+		_, err := CanonicalizeGenesis([]byte(`{"accounts": [}`))
+		assert.Error(t, err)
+		_, err = CanonicalizeGenesis([]byte(`{} {}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestChainIDFromGenesisHash(t *testing.T) {
+	hash := HashGenesis([]byte(`{}`))
+
+	id, err := ChainIDFromGenesisHash(hash)
+	require.NoError(t, err)
+	again, err := ChainIDFromGenesisHash(hash)
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	parsed, err := strconv.ParseInt(id, 10, 64)
+	require.NoError(t, err, "chain ID must fit a signed 64-bit integer")
+	assert.Positive(t, parsed)
+
+	_, err = ChainIDFromGenesisHash("not-a-hash")
+	assert.Error(t, err)
+}
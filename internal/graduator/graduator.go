@@ -4,53 +4,67 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"text/template"
+	"strconv"
 	"time"
 
+	"github.com/canopy-network/canopy/fsm"
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/interfaces"
 	"github.com/google/uuid"
 )
 
+const (
+	// RetryBaseDelay is the delay before the first retry of a failed graduation step.
+	// Subsequent retries double the delay up to RetryMaxDelay.
+	RetryBaseDelay = 30 * time.Second
+
+	// RetryMaxDelay caps the backoff between graduation step retries
+	RetryMaxDelay = 30 * time.Minute
+)
+
+// ErrRetryNotDue is returned when a failed graduation step is still backing off
+var ErrRetryNotDue = errors.New("graduation retry not yet due")
+
+// ErrAwaitingDeployment is returned while the deployer has not yet reported the
+// new chain as running. Graduation resumes once the deployer callback arrives.
+var ErrAwaitingDeployment = errors.New("awaiting deployment confirmation")
+
 // Graduator handles the virtual chain graduation process
 type Graduator struct {
-	chainRepo       interfaces.ChainRepository
-	virtualPoolRepo interfaces.VirtualPoolRepository
-	userRepo        interfaces.UserRepository
-	templatePath    string
-	rpcEndpoint     string
-	httpClient      *http.Client
+	chainRepo         interfaces.ChainRepository
+	virtualPoolRepo   interfaces.VirtualPoolRepository
+	graduatedPoolRepo interfaces.GraduatedPoolRepository
+	userRepo          interfaces.UserRepository
+	graduationRepo    interfaces.ChainGraduationRepository
+	rootChainID       uint64 // root chain of chains that do not record their own
+	rpcEndpoint       string
+	rpcSecret         string
+	httpClient        *http.Client
 }
 
 // New creates a new Graduator instance
-func New(chainRepo interfaces.ChainRepository, virtualPoolRepo interfaces.VirtualPoolRepository, userRepo interfaces.UserRepository, templatePath string, rpcEndpoint string) *Graduator {
+func New(chainRepo interfaces.ChainRepository, virtualPoolRepo interfaces.VirtualPoolRepository, graduatedPoolRepo interfaces.GraduatedPoolRepository, userRepo interfaces.UserRepository, graduationRepo interfaces.ChainGraduationRepository, rootChainID uint64, rpcEndpoint, rpcSecret string) *Graduator {
 	return &Graduator{
-		chainRepo:       chainRepo,
-		virtualPoolRepo: virtualPoolRepo,
-		userRepo:        userRepo,
-		templatePath:    templatePath,
-		rpcEndpoint:     rpcEndpoint,
+		chainRepo:         chainRepo,
+		virtualPoolRepo:   virtualPoolRepo,
+		graduatedPoolRepo: graduatedPoolRepo,
+		userRepo:          userRepo,
+		graduationRepo:    graduationRepo,
+		rootChainID:       rootChainID,
+		rpcEndpoint:       rpcEndpoint,
+		rpcSecret:         rpcSecret,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
 }
 
-// GenesisAccount represents an account in the genesis file
-type GenesisAccount struct {
-	Address string
-	Amount  int64
-}
-
-// GenesisData contains the template data for genesis file generation
-type GenesisData struct {
-	Accounts []GenesisAccount
-}
-
 // GraduationRPCPayload represents the data sent to the graduation RPC endpoint
 type GraduationRPCPayload struct {
+	ChainID     uuid.UUID              `json:"chain_id"`
 	Username    string                 `json:"username"`
 	ChainName   string                 `json:"chain_name"`
 	WalletOwner string                 `json:"wallet_owner"`
@@ -59,7 +73,34 @@ type GraduationRPCPayload struct {
 	GithubRepo  string                 `json:"github_repo"`
 }
 
-// MakeGraduationRPCCall sends graduation data to the configured RPC endpoint
+// NewGraduationRPCPayload builds the payload sent to the graduation RPC endpoint.
+// Missing relationships leave the corresponding fields empty.
+func NewGraduationRPCPayload(chain *models.Chain, genesisFile string) *GraduationRPCPayload {
+	payload := &GraduationRPCPayload{
+		ChainID:     chain.ID,
+		ChainName:   chain.ChainName,
+		GenesisFile: genesisFile,
+		Tokenomics: map[string]interface{}{
+			"token_name":          chain.TokenName,
+			"token_symbol":        chain.TokenSymbol,
+			"token_total_supply":  chain.TokenTotalSupply,
+			"block_time_seconds":  chain.BlockTimeSeconds,
+			"block_reward_amount": chain.BlockRewardAmount,
+		},
+	}
+	if chain.Creator != nil {
+		payload.Username = getStringValue(chain.Creator.Username)
+		payload.WalletOwner = chain.Creator.WalletAddress
+	}
+	if chain.Repository != nil {
+		payload.GithubRepo = chain.Repository.GithubURL
+	}
+	return payload
+}
+
+// MakeGraduationRPCCall sends graduation data to the configured RPC endpoint.
+// The request is signed with the shared secret and carries the chain's
+// idempotency key, so a retried request never provisions a second chain.
 func (g *Graduator) MakeGraduationRPCCall(ctx context.Context, chain *models.Chain, genesisFile string) error {
 	// Validate required relationships are loaded
 	if chain.Creator == nil {
@@ -68,25 +109,11 @@ func (g *Graduator) MakeGraduationRPCCall(ctx context.Context, chain *models.Cha
 	if chain.Repository == nil {
 		return fmt.Errorf("chain repository not loaded")
 	}
-
-	// Prepare tokenomics data
-	tokenomics := map[string]interface{}{
-		"token_name":          chain.TokenName,
-		"token_symbol":        chain.TokenSymbol,
-		"token_total_supply":  chain.TokenTotalSupply,
-		"block_time_seconds":  chain.BlockTimeSeconds,
-		"block_reward_amount": chain.BlockRewardAmount,
+	if getStringValue(chain.Creator.Username) == "" {
+		return fmt.Errorf("chain creator has no username")
 	}
 
-	// Prepare RPC payload
-	payload := GraduationRPCPayload{
-		Username:    getStringValue(chain.Creator.Username),
-		ChainName:   chain.ChainName,
-		WalletOwner: chain.Creator.WalletAddress,
-		GenesisFile: genesisFile,
-		Tokenomics:  tokenomics,
-		GithubRepo:  chain.Repository.GithubURL,
-	}
+	payload := NewGraduationRPCPayload(chain, genesisFile)
 
 	// Marshal payload to JSON
 	jsonData, err := json.Marshal(payload)
@@ -99,7 +126,11 @@ func (g *Graduator) MakeGraduationRPCCall(ctx context.Context, chain *models.Cha
 	if err != nil {
 		return fmt.Errorf("failed to create RPC request: %w", err)
 	}
+	timestamp := time.Now().Unix()
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, SignRequest(g.rpcSecret, timestamp, jsonData))
+	req.Header.Set(IdempotencyKeyHeader, IdempotencyKey(chain.ID))
 
 	// Execute request
 	resp, err := g.httpClient.Do(req)
@@ -124,7 +155,9 @@ func getStringValue(s *string) string {
 	return *s
 }
 
-// CheckAndGraduate checks if a chain is eligible for graduation and performs the graduation process
+// CheckAndGraduate checks if a chain is eligible for graduation and drives it through
+// the graduation steps. Progress is persisted after every step, so calling it again
+// after a failure resumes from the step that failed rather than starting over.
 func (g *Graduator) CheckAndGraduate(ctx context.Context, chainID uuid.UUID) error {
 	// Get the chain with relationships
 	chain, err := g.chainRepo.GetByID(ctx, chainID, []string{"creator", "repository"})
@@ -132,79 +165,263 @@ func (g *Graduator) CheckAndGraduate(ctx context.Context, chainID uuid.UUID) err
 		return fmt.Errorf("failed to get chain: %w", err)
 	}
 
+	graduation, err := g.graduationRepo.GetByChainID(ctx, chainID)
+	if err != nil {
+		return fmt.Errorf("failed to get graduation state: %w", err)
+	}
+
+	if graduation == nil {
+		graduation, err = g.startGraduation(ctx, chain)
+		if err != nil {
+			return err
+		}
+	}
+
+	if graduation.IsComplete() {
+		return fmt.Errorf("chain already graduated")
+	}
+
+	if graduation.NextRetryAt != nil && time.Now().Before(*graduation.NextRetryAt) {
+		return ErrRetryNotDue
+	}
+
+	for step := graduation.NextStep(); step != ""; step = graduation.NextStep() {
+		if err := g.runStep(ctx, step, chain, graduation); err != nil {
+			// Waiting on the deployer is not a failure and does not back off
+			if errors.Is(err, ErrAwaitingDeployment) {
+				return err
+			}
+			return g.recordFailure(ctx, graduation, step, err)
+		}
+
+		now := time.Now()
+		graduation.CurrentStep = step
+		graduation.Attempts = 0
+		graduation.LastError = nil
+		graduation.LastAttemptAt = &now
+		graduation.NextRetryAt = nil
+		if graduation.NextStep() == "" {
+			graduation.CompletedAt = &now
+		}
+
+		if _, err := g.graduationRepo.Update(ctx, graduation); err != nil {
+			return fmt.Errorf("failed to record graduation step %s: %w", step, err)
+		}
+	}
+
+	return nil
+}
+
+// startGraduation verifies the chain has reached its graduation threshold and
+// records the first graduation step, which freezes the virtual pool so the
+// genesis and the migrated positions see the same holder balances
+func (g *Graduator) startGraduation(ctx context.Context, chain *models.Chain) (*models.ChainGraduation, error) {
 	// Check if already graduated
 	if chain.IsGraduated {
-		return fmt.Errorf("chain already graduated")
+		return nil, fmt.Errorf("chain already graduated")
 	}
 
 	// Get virtual pool
-	pool, err := g.virtualPoolRepo.GetPoolByChainID(ctx, chainID)
+	pool, err := g.virtualPoolRepo.GetPoolByChainID(ctx, chain.ID)
 	if err != nil {
-		return fmt.Errorf("failed to get virtual pool: %w", err)
+		return nil, fmt.Errorf("failed to get virtual pool: %w", err)
 	}
 
 	// Check if pool value meets graduation threshold
-	if pool.CNPYReserve < chain.GraduationThreshold {
-		return fmt.Errorf("graduation threshold not met: current %v, required %v", pool.CNPYReserve, chain.GraduationThreshold)
+	if !pool.ReachesThreshold(chain.GraduationThreshold) {
+		return nil, fmt.Errorf("graduation threshold not met: current %v, required %v", pool.CNPYReserve, chain.GraduationThreshold)
 	}
 
-	// Generate genesis file
-	genesisFile, err := g.GenerateGenesisFile(ctx, chainID)
+	now := time.Now()
+	graduation, err := g.graduationRepo.Create(ctx, &models.ChainGraduation{
+		ChainID:       chain.ID,
+		CurrentStep:   models.GraduationStepThresholdReached,
+		LastAttemptAt: &now,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to generate genesis file: %w", err)
+		return nil, fmt.Errorf("failed to record graduation start: %w", err)
 	}
 
-	// Make graduation RPC call
-	if err := g.MakeGraduationRPCCall(ctx, chain, genesisFile); err != nil {
-		return fmt.Errorf("failed to make graduation RPC call: %w", err)
+	return graduation, nil
+}
+
+// runStep executes a single graduation step. Every step can safely be re-run
+// if a previous attempt failed part way through.
+func (g *Graduator) runStep(ctx context.Context, step string, chain *models.Chain, graduation *models.ChainGraduation) error {
+	switch step {
+	case models.GraduationStepGenesisGenerated:
+		genesisFile, err := g.GenerateGenesisFile(ctx, chain, graduation.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to generate genesis file: %w", err)
+		}
+		graduation.GenesisFile = &genesisFile
+
+	case models.GraduationStepGenesisHashRecorded:
+		if graduation.GenesisFile == nil {
+			return fmt.Errorf("genesis file missing from graduation state")
+		}
+		// Store the canonical bytes so the published file always matches the hash
+		canonical, err := CanonicalizeGenesis([]byte(*graduation.GenesisFile))
+		if err != nil {
+			return fmt.Errorf("failed to canonicalise genesis: %w", err)
+		}
+		genesisFile := string(canonical)
+		hash := HashGenesis(canonical)
+		networkChainID, err := ChainIDFromGenesisHash(hash)
+		if err != nil {
+			return err
+		}
+		graduation.GenesisFile = &genesisFile
+		graduation.GenesisHash = &hash
+		chain.GenesisHash = &hash
+		chain.ChainID = &networkChainID
+		if _, err := g.chainRepo.Update(ctx, chain); err != nil {
+			return fmt.Errorf("failed to record genesis hash: %w", err)
+		}
+
+	case models.GraduationStepDeploymentRequested:
+		if graduation.GenesisFile == nil {
+			return fmt.Errorf("genesis file missing from graduation state")
+		}
+		if err := g.MakeGraduationRPCCall(ctx, chain, *graduation.GenesisFile); err != nil {
+			return fmt.Errorf("failed to make graduation RPC call: %w", err)
+		}
+
+	case models.GraduationStepDeploymentConfirmed:
+		// The deployer reports progress through the deployment callback; the
+		// step completes once it reports the new chain as running
+		switch getStringValue(graduation.DeploymentStatus) {
+		case models.DeploymentStatusRunning:
+		case models.DeploymentStatusFailed:
+			return fmt.Errorf("deployer reported failure: %s", getStringValue(graduation.DeploymentMessage))
+		default:
+			return ErrAwaitingDeployment
+		}
+
+		// The callback normally marks the chain graduated already
+		if !chain.IsGraduated {
+			MarkGraduated(chain, time.Now())
+			if _, err := g.chainRepo.Update(ctx, chain); err != nil {
+				return fmt.Errorf("failed to update chain graduation status: %w", err)
+			}
+		}
+
+	case models.GraduationStepPoolMigrated:
+		// Holders move to the graduated pool. The virtual pool stopped trading
+		// when the graduation started.
+		if _, err := g.graduatedPoolRepo.MigrateFromVirtualPool(ctx, chain.ID); err != nil {
+			return fmt.Errorf("failed to migrate virtual pool: %w", err)
+		}
+
+	default:
+		return fmt.Errorf("unknown graduation step: %s", step)
 	}
 
-	// Update chain status to graduated
-	now := time.Now()
+	return nil
+}
+
+// MarkGraduated sets the chain fields that record a completed deployment
+func MarkGraduated(chain *models.Chain, now time.Time) {
 	chain.IsGraduated = true
-	chain.GraduationTime = &now
 	chain.Status = models.ChainStatusGraduated
+	if chain.GraduationTime == nil {
+		chain.GraduationTime = &now
+	}
+}
 
-	if _, err := g.chainRepo.Update(ctx, chain); err != nil {
-		return fmt.Errorf("failed to update chain graduation status: %w", err)
+// recordFailure stores the error for a failed step and schedules the next retry
+func (g *Graduator) recordFailure(ctx context.Context, graduation *models.ChainGraduation, step string, stepErr error) error {
+	now := time.Now()
+	nextRetry := now.Add(retryBackoff(graduation.Attempts + 1))
+	errMsg := stepErr.Error()
+
+	graduation.Attempts++
+	graduation.LastError = &errMsg
+	graduation.LastAttemptAt = &now
+	graduation.NextRetryAt = &nextRetry
+
+	if _, err := g.graduationRepo.Update(ctx, graduation); err != nil {
+		return fmt.Errorf("graduation step %s failed: %w (and failed to record failure: %v)", step, stepErr, err)
 	}
 
-	return nil
+	return fmt.Errorf("graduation step %s failed: %w", step, stepErr)
+}
+
+// retryBackoff returns the delay before the given retry attempt
+func retryBackoff(attempt int) time.Duration {
+	delay := RetryBaseDelay
+	for i := 1; i < attempt && delay < RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > RetryMaxDelay {
+		delay = RetryMaxDelay
+	}
+	return delay
+}
+
+// GenerateGenesisFile builds the genesis.json for a chain from its current holder
+// positions and returns it as a string. genesisTime is written as the genesis
+// time, so regenerating with the same time and positions yields identical bytes.
+func (g *Graduator) GenerateGenesisFile(ctx context.Context, chain *models.Chain, genesisTime time.Time) (string, error) {
+	genesis, err := g.buildChainGenesis(ctx, chain, genesisTime)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := MarshalGenesis(genesis)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
 }
 
-// GenerateGenesisFile generates the genesis.json file for a chain and returns it as a string
-func (g *Graduator) GenerateGenesisFile(ctx context.Context, chainID uuid.UUID) (string, error) {
+// buildChainGenesis loads the chain's current holder positions and validator
+// key and builds its genesis state
+func (g *Graduator) buildChainGenesis(ctx context.Context, chain *models.Chain, genesisTime time.Time) (*fsm.GenesisState, error) {
 	// Get positions with user addresses
-	positions, err := g.virtualPoolRepo.GetPositionsWithUsersByChainID(ctx, chainID)
+	positions, err := g.virtualPoolRepo.GetPositionsWithUsersByChainID(ctx, chain.ID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get positions: %w", err)
+		return nil, fmt.Errorf("failed to get positions: %w", err)
 	}
 
-	// Convert to genesis accounts
-	accounts := make([]GenesisAccount, len(positions))
-	for i, pos := range positions {
-		accounts[i] = GenesisAccount{
-			Address: pos.WalletAddress,
-			Amount:  pos.TokenBalance,
-		}
+	// The chain operation key becomes the genesis validator
+	validatorKey, err := g.chainRepo.GetChainKeyByChainID(ctx, chain.ID, models.KeyPurposeChainOperation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get validator key: %w", err)
 	}
 
-	// Create template data
-	data := GenesisData{
-		Accounts: accounts,
+	// The treasury bucket is paid to the chain's treasury key
+	var treasuryKey *models.ChainKey
+	if chain.TreasuryBps > 0 {
+		treasuryKey, err = g.chainRepo.GetChainKeyByChainID(ctx, chain.ID, models.KeyPurposeTreasury)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get treasury key: %w", err)
+		}
 	}
 
-	// Parse and execute template
-	tmpl, err := template.ParseFiles(g.templatePath)
+	vesting, err := g.chainRepo.GetVestingSchedulesByChainID(ctx, chain.ID)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+		return nil, fmt.Errorf("failed to get vesting schedules: %w", err)
 	}
 
-	// Execute template to buffer
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+	rootChainID := chain.RootChainID
+	if rootChainID == 0 {
+		rootChainID = g.rootChainID
+	}
+
+	genesis, err := BuildGenesis(GenesisInput{
+		Chain:       chain,
+		RootChainID: rootChainID,
+		Validator:   validatorKey,
+		Treasury:    treasuryKey,
+		Vesting:     vesting,
+		Holders:     positions,
+		Time:        genesisTime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build genesis: %w", err)
 	}
 
-	return buf.String(), nil
+	return genesis, nil
 }
@@ -1,13 +1,18 @@
 package graduator
 
 import (
-	"bytes"
 	"context"
-	"os"
-	"path/filepath"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
-	"text/template"
+	"time"
 
+	"github.com/canopy-network/canopy/fsm"
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/interfaces"
 	"github.com/enielson/launchpad/internal/testutil/mocks"
@@ -16,211 +21,109 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-func TestGenesisAccountStructure(t *testing.T) {
-	account := GenesisAccount{
-		Address: "0x1234567890abcdef",
-		Amount:  1000000,
-	}
-
-	if account.Address == "" {
-		t.Error("Address should not be empty")
-	}
-	if account.Amount <= 0 {
-		t.Error("Amount should be positive")
-	}
-}
-
-func TestGenesisDataStructure(t *testing.T) {
-	data := GenesisData{
-		Accounts: []GenesisAccount{
-			{Address: "0xabc", Amount: 100},
-			{Address: "0xdef", Amount: 200},
-		},
-	}
-
-	if len(data.Accounts) != 2 {
-		t.Errorf("Expected 2 accounts, got %d", len(data.Accounts))
-	}
-}
-
-func TestTemplateProcessing(t *testing.T) {
-	t.Run("simple template with two accounts", func(t *testing.T) {
-		tmplContent := `{
-    "accounts": [
-        {{- range $index, $account := .Accounts }}
-        {{- if $index }},{{ end }}
-        {
-            "address": "{{ $account.Address }}",
-            "amount": {{ $account.Amount }}
-        }
-        {{- end }}
-    ]
-}`
-
-		tmpl, err := template.New("test").Parse(tmplContent)
-		assert.NoError(t, err)
-
-		data := GenesisData{
-			Accounts: []GenesisAccount{
-				{Address: "0x1234567890abcdef", Amount: 1000000},
-				{Address: "0xfedcba0987654321", Amount: 2000000},
-			},
-		}
-
-		var buf bytes.Buffer
-		err = tmpl.Execute(&buf, data)
-		assert.NoError(t, err)
-
-		output := buf.String()
-		assert.Contains(t, output, `"address": "0x1234567890abcdef"`)
-		assert.Contains(t, output, `"amount": 1000000`)
-		assert.Contains(t, output, `"address": "0xfedcba0987654321"`)
-		assert.Contains(t, output, `"amount": 2000000`)
-	})
+func TestGenerateGenesisFile(t *testing.T) {
+	genesisTime := time.Date(2025, 10, 21, 14, 30, 12, 0, time.UTC)
 
-	t.Run("template with no accounts", func(t *testing.T) {
-		tmplContent := `{
-    "accounts": [
-        {{- range $index, $account := .Accounts }}
-        {{- if $index }},{{ end }}
-        {
-            "address": "{{ $account.Address }}",
-            "amount": {{ $account.Amount }}
-        }
-        {{- end }}
-    ]
-}`
-
-		tmpl, err := template.New("test").Parse(tmplContent)
-		assert.NoError(t, err)
+	t.Run("successful generation with mock data", func(t *testing.T) {
+		chainID := uuid.New()
+		chain := newGenesisChain(chainID)
+		chainRepo := new(mocks.MockChainRepository)
+		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
 
-		data := GenesisData{
-			Accounts: []GenesisAccount{},
+		positions := []interfaces.UserPositionWithAddress{
+			{WalletAddress: "0x" + strings.Repeat("cc", 20), TokenBalanceUnits: 500000},
+			{WalletAddress: strings.Repeat("aa", 20), TokenBalanceUnits: 1000000},
+			{WalletAddress: strings.Repeat("bb", 20), TokenBalanceUnits: 2000000},
 		}
 
-		var buf bytes.Buffer
-		err = tmpl.Execute(&buf, data)
-		assert.NoError(t, err)
-
-		output := buf.String()
-		assert.Contains(t, output, `"accounts": [`)
-		assert.Contains(t, output, `]`)
-	})
+		virtualPoolRepo.On("GetPositionsWithUsersByChainID", mock.Anything, chainID).Return(positions, nil)
+		chainRepo.On("GetChainKeyByChainID", mock.Anything, chainID, models.KeyPurposeChainOperation).Return(newValidatorKey(t, chainID), nil)
+		chainRepo.On("GetVestingSchedulesByChainID", mock.Anything, chainID).Return([]models.ChainVestingSchedule{}, nil)
 
-	t.Run("template with single account", func(t *testing.T) {
-		tmplContent := `{
-    "accounts": [
-        {{- range $index, $account := .Accounts }}
-        {{- if $index }},{{ end }}
-        {
-            "address": "{{ $account.Address }}",
-            "amount": {{ $account.Amount }}
-        }
-        {{- end }}
-    ]
-}`
-
-		tmpl, err := template.New("test").Parse(tmplContent)
+		userRepo := new(mocks.MockUserRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
+		output, err := grad.GenerateGenesisFile(context.Background(), chain, genesisTime)
 		assert.NoError(t, err)
 
-		data := GenesisData{
-			Accounts: []GenesisAccount{
-				{Address: "0xsingleaddress", Amount: 5000000},
-			},
+		var genesis fsm.GenesisState
+		assert.NoError(t, json.Unmarshal([]byte(output), &genesis))
+		// Three holders plus the validator's share of the liquidity reserve
+		assert.Len(t, genesis.Accounts, 4)
+		assert.Len(t, genesis.Validators, 1)
+		assert.Contains(t, output, `"time":"2025-10-21 14:30:12"`)
+		assert.NoError(t, ValidateGenesis(&genesis, chain.TokenTotalSupplyUnits()))
+
+		// Accounts are sorted by address regardless of query order
+		balances := genesisBalances(&genesis)
+		assert.Equal(t, uint64(1000000), balances[strings.Repeat("aa", 20)])
+		assert.Equal(t, uint64(500000), balances[strings.Repeat("cc", 20)])
+		for i := 1; i < len(genesis.Accounts); i++ {
+			assert.Less(t, hex.EncodeToString(genesis.Accounts[i-1].Address), hex.EncodeToString(genesis.Accounts[i].Address))
 		}
 
-		var buf bytes.Buffer
-		err = tmpl.Execute(&buf, data)
+		// Same inputs produce the same bytes
+		again, err := grad.GenerateGenesisFile(context.Background(), chain, genesisTime)
 		assert.NoError(t, err)
+		assert.Equal(t, output, again)
 
-		output := buf.String()
-		assert.Contains(t, output, `"address": "0xsingleaddress"`)
-		assert.Contains(t, output, `"amount": 5000000`)
-		assert.NotContains(t, output, "},\n    ]")
+		virtualPoolRepo.AssertExpectations(t)
+		chainRepo.AssertExpectations(t)
 	})
-}
-
-func TestGenerateGenesisFile(t *testing.T) {
-	t.Run("successful generation with mock data", func(t *testing.T) {
-		tmpDir := t.TempDir()
-		templatePath := filepath.Join(tmpDir, "genesis.json.template")
-
-		templateContent := `{
-    "accounts": [
-        {{- range $index, $account := .Accounts }}
-        {{- if $index }},{{ end }}
-        {
-            "address": "{{ $account.Address }}",
-            "amount": {{ $account.Amount }}
-        }
-        {{- end }}
-    ]
-}`
-		err := os.WriteFile(templatePath, []byte(templateContent), 0644)
-		assert.NoError(t, err)
 
+	t.Run("validator key not found", func(t *testing.T) {
 		chainID := uuid.New()
 		chainRepo := new(mocks.MockChainRepository)
 		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
 
-		positions := []interfaces.UserPositionWithAddress{
-			{WalletAddress: "0xalice123", TokenBalance: 1000000},
-			{WalletAddress: "0xbob456", TokenBalance: 2000000},
-			{WalletAddress: "0xcharlie789", TokenBalance: 500000},
-		}
-
-		virtualPoolRepo.On("GetPositionsWithUsersByChainID", mock.Anything, chainID).Return(positions, nil)
+		virtualPoolRepo.On("GetPositionsWithUsersByChainID", mock.Anything, chainID).Return([]interfaces.UserPositionWithAddress{}, nil)
+		chainRepo.On("GetChainKeyByChainID", mock.Anything, chainID, models.KeyPurposeChainOperation).Return(nil, assert.AnError)
 
 		userRepo := new(mocks.MockUserRepository)
-		grad := New(chainRepo, virtualPoolRepo, userRepo, templatePath, "http://localhost:8082/graduate")
-		output, err := grad.GenerateGenesisFile(context.Background(), chainID)
-		assert.NoError(t, err)
-
-		assert.Contains(t, output, `"address": "0xalice123"`)
-		assert.Contains(t, output, `"amount": 1000000`)
-		assert.Contains(t, output, `"address": "0xbob456"`)
-		assert.Contains(t, output, `"amount": 2000000`)
-		assert.Contains(t, output, `"address": "0xcharlie789"`)
-		assert.Contains(t, output, `"amount": 500000`)
-
-		virtualPoolRepo.AssertExpectations(t)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
+		_, err := grad.GenerateGenesisFile(context.Background(), newGenesisChain(chainID), genesisTime)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get validator key")
 	})
 
-	t.Run("template file not found", func(t *testing.T) {
+	t.Run("invalid holder address", func(t *testing.T) {
 		chainID := uuid.New()
 		chainRepo := new(mocks.MockChainRepository)
 		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
 
 		positions := []interfaces.UserPositionWithAddress{
-			{WalletAddress: "0xtest", TokenBalance: 1000},
+			{WalletAddress: "0xtest", TokenBalanceUnits: 1000},
 		}
 
 		virtualPoolRepo.On("GetPositionsWithUsersByChainID", mock.Anything, chainID).Return(positions, nil)
+		chainRepo.On("GetChainKeyByChainID", mock.Anything, chainID, models.KeyPurposeChainOperation).Return(newValidatorKey(t, chainID), nil)
+		chainRepo.On("GetVestingSchedulesByChainID", mock.Anything, chainID).Return([]models.ChainVestingSchedule{}, nil)
 
 		userRepo := new(mocks.MockUserRepository)
-		grad := New(chainRepo, virtualPoolRepo, userRepo, "/nonexistent/template.json", "http://localhost:8082/graduate")
-		_, err := grad.GenerateGenesisFile(context.Background(), chainID)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
+		_, err := grad.GenerateGenesisFile(context.Background(), newGenesisChain(chainID), genesisTime)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "failed to parse template")
+		assert.Contains(t, err.Error(), "failed to build genesis")
+		assert.Contains(t, err.Error(), "invalid holder address")
 	})
 
 	t.Run("repository error", func(t *testing.T) {
-		tmpDir := t.TempDir()
-		templatePath := filepath.Join(tmpDir, "genesis.json.template")
-
-		templateContent := `{"accounts": []}`
-		err := os.WriteFile(templatePath, []byte(templateContent), 0644)
-		assert.NoError(t, err)
-
 		chainID := uuid.New()
 		chainRepo := new(mocks.MockChainRepository)
 		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
 
 		virtualPoolRepo.On("GetPositionsWithUsersByChainID", mock.Anything, chainID).Return(nil, assert.AnError)
 
 		userRepo := new(mocks.MockUserRepository)
-		grad := New(chainRepo, virtualPoolRepo, userRepo, templatePath, "http://localhost:8082/graduate")
-		_, err = grad.GenerateGenesisFile(context.Background(), chainID)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
+		_, err := grad.GenerateGenesisFile(context.Background(), newGenesisChain(chainID), genesisTime)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to get positions")
 
@@ -229,27 +132,18 @@ func TestGenerateGenesisFile(t *testing.T) {
 }
 
 func TestCheckAndGraduate(t *testing.T) {
-	t.Skip("CheckAndGraduate requires HTTP client mocking - implement with httptest server")
-	t.Run("successful graduation", func(t *testing.T) {
-		tmpDir := t.TempDir()
-		templatePath := filepath.Join(tmpDir, "genesis.json.template")
-
-		templateContent := `{"accounts": []}`
-		err := os.WriteFile(templatePath, []byte(templateContent), 0644)
-		assert.NoError(t, err)
-
-		chainID := uuid.New()
+	newChain := func(chainID uuid.UUID) *models.Chain {
 		creatorID := uuid.New()
-		chainRepo := new(mocks.MockChainRepository)
-		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
-		userRepo := new(mocks.MockUserRepository)
-
 		username := "testuser"
-		chain := &models.Chain{
+		return &models.Chain{
 			ID:                  chainID,
 			ChainName:           "TestChain",
+			TokenTotalSupply:    1000000000,
+			ValidatorMinStake:   1000,
+			TokenAllocation:     models.TokenAllocation{LiquidityBps: 2000, HoldersBps: 8000},
 			GraduationThreshold: 50000.0,
 			IsGraduated:         false,
+			Status:              models.ChainStatusVirtualActive,
 			CreatedBy:           creatorID,
 			Creator: &models.User{
 				ID:            creatorID,
@@ -260,44 +154,226 @@ func TestCheckAndGraduate(t *testing.T) {
 				GithubURL: "https://github.com/test/repo",
 			},
 		}
+	}
+
+	t.Run("successful graduation", func(t *testing.T) {
+		chainID := uuid.New()
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			body, _ := io.ReadAll(r.Body)
+			assert.NoError(t, VerifyRequest(testRPCSecret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, time.Now()))
+			assert.Equal(t, IdempotencyKey(chainID), r.Header.Get(IdempotencyKeyHeader))
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		chain := newChain(chainID)
+		chainRepo := new(mocks.MockChainRepository)
+		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
+		userRepo := new(mocks.MockUserRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
 
 		pool := &models.VirtualPool{
-			ID:          uuid.New(),
-			ChainID:     chainID,
-			CNPYReserve: 55000.0,
+			ID:               uuid.New(),
+			ChainID:          chainID,
+			CNPYReserve:      55000.0,
+			CNPYReserveMicro: 55000000000,
 		}
 
 		positions := []interfaces.UserPositionWithAddress{
-			{WalletAddress: "0xtest", TokenBalance: 1000},
+			{WalletAddress: strings.Repeat("ab", 20), TokenBalanceUnits: 1000},
 		}
 
+		graduation := &models.ChainGraduation{ChainID: chainID, CurrentStep: models.GraduationStepThresholdReached, CreatedAt: time.Now()}
 		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(chain, nil)
 		virtualPoolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
 		virtualPoolRepo.On("GetPositionsWithUsersByChainID", mock.Anything, chainID).Return(positions, nil)
-		chainRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Chain")).Return(chain, nil)
-
-		grad := New(chainRepo, virtualPoolRepo, userRepo, templatePath, "http://localhost:8082/graduate")
-		err = grad.CheckAndGraduate(context.Background(), chainID)
+		chainRepo.On("GetChainKeyByChainID", mock.Anything, chainID, models.KeyPurposeChainOperation).Return(newValidatorKey(t, chainID), nil)
+		chainRepo.On("GetVestingSchedulesByChainID", mock.Anything, chainID).Return([]models.ChainVestingSchedule{}, nil)
+		chainRepo.On("Update", mock.Anything, chain).Return(chain, nil)
+		graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(nil, nil).Once()
+		graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(graduation, nil)
+		graduationRepo.On("Create", mock.Anything, mock.MatchedBy(func(g *models.ChainGraduation) bool {
+			return g.CurrentStep == models.GraduationStepThresholdReached
+		})).Return(graduation, nil)
+		graduationRepo.On("Update", mock.Anything, graduation).Return(graduation, nil)
+		graduatedPoolRepo.On("MigrateFromVirtualPool", mock.Anything, chainID).Return(&models.GraduatedPool{ChainID: chainID}, nil)
+
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, server.URL, testRPCSecret)
+
+		// The signed deployment request is sent, then graduation waits for the deployer
+		err := grad.CheckAndGraduate(context.Background(), chainID)
+		assert.ErrorIs(t, err, ErrAwaitingDeployment)
+		assert.Equal(t, 1, requests)
+		assert.Equal(t, models.GraduationStepDeploymentRequested, graduation.CurrentStep)
+		assert.Equal(t, 0, graduation.Attempts)
+		assert.Nil(t, graduation.NextRetryAt)
+		assert.False(t, chain.IsGraduated)
 
-		// This will fail due to RPC call - proper test requires httptest server
-		assert.Error(t, err)
+		// The deployer callback reports the chain running
+		running := models.DeploymentStatusRunning
+		graduation.DeploymentStatus = &running
 
+		err = grad.CheckAndGraduate(context.Background(), chainID)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, requests)
+		assert.True(t, graduation.IsComplete())
+		assert.True(t, chain.IsGraduated)
+		assert.Equal(t, models.ChainStatusGraduated, chain.Status)
+		assert.NotNil(t, chain.GraduationTime)
+		assert.NotNil(t, chain.GenesisHash)
+		assert.NotNil(t, chain.ChainID)
+		assert.Equal(t, *chain.GenesisHash, HashGenesis([]byte(*graduation.GenesisFile)))
+
+		// One update per step after threshold_reached
+		graduationRepo.AssertNumberOfCalls(t, "Update", len(models.GraduationSteps)-1)
 		chainRepo.AssertExpectations(t)
 		virtualPoolRepo.AssertExpectations(t)
+		graduatedPoolRepo.AssertExpectations(t)
 	})
 
-	t.Run("threshold not met", func(t *testing.T) {
-		tmpDir := t.TempDir()
-		templatePath := filepath.Join(tmpDir, "genesis.json.template")
+	t.Run("failed step is recorded and resumed without redoing earlier steps", func(t *testing.T) {
+		status := http.StatusInternalServerError
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		chainID := uuid.New()
+		chain := newChain(chainID)
+		genesisFile := `{"accounts": []}`
+		graduation := &models.ChainGraduation{
+			ID:          uuid.New(),
+			ChainID:     chainID,
+			CurrentStep: models.GraduationStepGenesisHashRecorded,
+			GenesisFile: &genesisFile,
+		}
 
-		templateContent := `{"accounts": []}`
-		err := os.WriteFile(templatePath, []byte(templateContent), 0644)
+		chainRepo := new(mocks.MockChainRepository)
+		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
+		userRepo := new(mocks.MockUserRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
+
+		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(chain, nil)
+		chainRepo.On("Update", mock.Anything, chain).Return(chain, nil)
+		graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(graduation, nil)
+		graduationRepo.On("Update", mock.Anything, graduation).Return(graduation, nil)
+		graduatedPoolRepo.On("MigrateFromVirtualPool", mock.Anything, chainID).Return(&models.GraduatedPool{ChainID: chainID}, nil).Once()
+
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, server.URL, testRPCSecret)
+
+		// Deployment request fails: error and retry schedule are persisted
+		err := grad.CheckAndGraduate(context.Background(), chainID)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "graduation step deployment_requested failed")
+		assert.Equal(t, models.GraduationStepGenesisHashRecorded, graduation.CurrentStep)
+		assert.Equal(t, 1, graduation.Attempts)
+		assert.NotNil(t, graduation.LastError)
+		assert.NotNil(t, graduation.NextRetryAt)
+		assert.False(t, chain.IsGraduated)
+
+		// Retrying before the backoff elapses does nothing
+		err = grad.CheckAndGraduate(context.Background(), chainID)
+		assert.ErrorIs(t, err, ErrRetryNotDue)
+		assert.Equal(t, 1, requests)
+
+		// Once due, graduation resumes from the failed step
+		past := time.Now().Add(-time.Second)
+		graduation.NextRetryAt = &past
+		status = http.StatusOK
+
+		err = grad.CheckAndGraduate(context.Background(), chainID)
+		assert.ErrorIs(t, err, ErrAwaitingDeployment)
+		assert.Equal(t, 2, requests)
+		assert.Equal(t, models.GraduationStepDeploymentRequested, graduation.CurrentStep)
+
+		running := models.DeploymentStatusRunning
+		graduation.DeploymentStatus = &running
+
+		err = grad.CheckAndGraduate(context.Background(), chainID)
 		assert.NoError(t, err)
+		assert.Equal(t, 2, requests)
+		assert.True(t, graduation.IsComplete())
+		assert.Equal(t, models.GraduationStepPoolMigrated, graduation.CurrentStep)
+		assert.Equal(t, 0, graduation.Attempts)
+		assert.Nil(t, graduation.LastError)
+		assert.True(t, chain.IsGraduated)
+
+		// Genesis was not regenerated
+		virtualPoolRepo.AssertNotCalled(t, "GetPositionsWithUsersByChainID", mock.Anything, mock.Anything)
+	})
 
+	t.Run("deployer failure is recorded for retry", func(t *testing.T) {
+		chainID := uuid.New()
+		chain := newChain(chainID)
+		failed := models.DeploymentStatusFailed
+		message := "validator node failed to start"
+		graduation := &models.ChainGraduation{
+			ID:                uuid.New(),
+			ChainID:           chainID,
+			CurrentStep:       models.GraduationStepDeploymentRequested,
+			DeploymentStatus:  &failed,
+			DeploymentMessage: &message,
+		}
+
+		chainRepo := new(mocks.MockChainRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
+		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(chain, nil)
+		graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(graduation, nil)
+		graduationRepo.On("Update", mock.Anything, graduation).Return(graduation, nil)
+
+		grad := New(chainRepo, nil, nil, nil, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
+		err := grad.CheckAndGraduate(context.Background(), chainID)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "deployer reported failure: validator node failed to start")
+		assert.Equal(t, models.GraduationStepDeploymentRequested, graduation.CurrentStep)
+		assert.Equal(t, 1, graduation.Attempts)
+		assert.NotNil(t, graduation.NextRetryAt)
+		assert.False(t, chain.IsGraduated)
+		chainRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("pool migration failure leaves graduation incomplete", func(t *testing.T) {
+		chainID := uuid.New()
+		chain := newChain(chainID)
+		graduation := &models.ChainGraduation{
+			ID:          uuid.New(),
+			ChainID:     chainID,
+			CurrentStep: models.GraduationStepDeploymentConfirmed,
+		}
+
+		chainRepo := new(mocks.MockChainRepository)
+		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
+		userRepo := new(mocks.MockUserRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
+
+		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(chain, nil)
+		graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(graduation, nil)
+		graduationRepo.On("Update", mock.Anything, graduation).Return(graduation, nil)
+		graduatedPoolRepo.On("MigrateFromVirtualPool", mock.Anything, chainID).Return(nil, errors.New("deadlock detected"))
+
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
+		err := grad.CheckAndGraduate(context.Background(), chainID)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "graduation step pool_migrated failed")
+		assert.False(t, graduation.IsComplete())
+		assert.Equal(t, models.GraduationStepDeploymentConfirmed, graduation.CurrentStep)
+		assert.Equal(t, 1, graduation.Attempts)
+	})
+
+	t.Run("threshold not met", func(t *testing.T) {
 		chainID := uuid.New()
 		chainRepo := new(mocks.MockChainRepository)
 		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
 		userRepo := new(mocks.MockUserRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
 
 		chain := &models.Chain{
 			ID:                  chainID,
@@ -307,16 +383,18 @@ func TestCheckAndGraduate(t *testing.T) {
 		}
 
 		pool := &models.VirtualPool{
-			ID:          uuid.New(),
-			ChainID:     chainID,
-			CNPYReserve: 30000.0,
+			ID:               uuid.New(),
+			ChainID:          chainID,
+			CNPYReserve:      30000.0,
+			CNPYReserveMicro: 30000000000,
 		}
 
 		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(chain, nil)
+		graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(nil, nil)
 		virtualPoolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
 
-		grad := New(chainRepo, virtualPoolRepo, userRepo, templatePath, "http://localhost:8082/graduate")
-		err = grad.CheckAndGraduate(context.Background(), chainID)
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
+		err := grad.CheckAndGraduate(context.Background(), chainID)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "graduation threshold not met")
 		assert.Contains(t, err.Error(), "current 30000")
@@ -324,16 +402,16 @@ func TestCheckAndGraduate(t *testing.T) {
 
 		chainRepo.AssertExpectations(t)
 		virtualPoolRepo.AssertExpectations(t)
+		graduationRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
 	})
 
 	t.Run("already graduated", func(t *testing.T) {
-		tmpDir := t.TempDir()
-		templatePath := filepath.Join(tmpDir, "genesis.json.template")
-
 		chainID := uuid.New()
 		chainRepo := new(mocks.MockChainRepository)
 		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
 		userRepo := new(mocks.MockUserRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
 
 		chain := &models.Chain{
 			ID:          chainID,
@@ -342,8 +420,9 @@ func TestCheckAndGraduate(t *testing.T) {
 		}
 
 		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(chain, nil)
+		graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(nil, nil)
 
-		grad := New(chainRepo, virtualPoolRepo, userRepo, templatePath, "http://localhost:8082/graduate")
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
 		err := grad.CheckAndGraduate(context.Background(), chainID)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "already graduated")
@@ -351,18 +430,39 @@ func TestCheckAndGraduate(t *testing.T) {
 		chainRepo.AssertExpectations(t)
 	})
 
-	t.Run("chain not found", func(t *testing.T) {
-		tmpDir := t.TempDir()
-		templatePath := filepath.Join(tmpDir, "genesis.json.template")
+	t.Run("graduation already complete", func(t *testing.T) {
+		chainID := uuid.New()
+		chainRepo := new(mocks.MockChainRepository)
+		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
+		userRepo := new(mocks.MockUserRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
+
+		completedAt := time.Now()
+		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(newChain(chainID), nil)
+		graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(&models.ChainGraduation{
+			ChainID:     chainID,
+			CurrentStep: models.GraduationStepPoolMigrated,
+			CompletedAt: &completedAt,
+		}, nil)
 
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
+		err := grad.CheckAndGraduate(context.Background(), chainID)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "already graduated")
+	})
+
+	t.Run("chain not found", func(t *testing.T) {
 		chainID := uuid.New()
 		chainRepo := new(mocks.MockChainRepository)
 		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
 		userRepo := new(mocks.MockUserRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
 
 		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(nil, assert.AnError)
 
-		grad := New(chainRepo, virtualPoolRepo, userRepo, templatePath, "http://localhost:8082/graduate")
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
 		err := grad.CheckAndGraduate(context.Background(), chainID)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to get chain")
@@ -371,13 +471,12 @@ func TestCheckAndGraduate(t *testing.T) {
 	})
 
 	t.Run("pool not found", func(t *testing.T) {
-		tmpDir := t.TempDir()
-		templatePath := filepath.Join(tmpDir, "genesis.json.template")
-
 		chainID := uuid.New()
 		chainRepo := new(mocks.MockChainRepository)
 		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
 		userRepo := new(mocks.MockUserRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
 
 		chain := &models.Chain{
 			ID:                  chainID,
@@ -387,9 +486,10 @@ func TestCheckAndGraduate(t *testing.T) {
 		}
 
 		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(chain, nil)
+		graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(nil, nil)
 		virtualPoolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(nil, assert.AnError)
 
-		grad := New(chainRepo, virtualPoolRepo, userRepo, templatePath, "http://localhost:8082/graduate")
+		grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, 1, "http://localhost:8082/graduate", testRPCSecret)
 		err := grad.CheckAndGraduate(context.Background(), chainID)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to get virtual pool")
@@ -399,20 +499,32 @@ func TestCheckAndGraduate(t *testing.T) {
 	})
 }
 
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, RetryBaseDelay, retryBackoff(1))
+	assert.Equal(t, 2*RetryBaseDelay, retryBackoff(2))
+	assert.Equal(t, 4*RetryBaseDelay, retryBackoff(3))
+	assert.Equal(t, RetryMaxDelay, retryBackoff(50))
+}
+
 func TestNew(t *testing.T) {
 	chainRepo := new(mocks.MockChainRepository)
 	virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+	graduatedPoolRepo := new(mocks.MockGraduatedPoolRepository)
 	userRepo := new(mocks.MockUserRepository)
-	templatePath := "/path/to/template"
+	graduationRepo := new(mocks.MockChainGraduationRepository)
+	rootChainID := uint64(1)
 	rpcEndpoint := "http://localhost:8082/graduate"
 
-	grad := New(chainRepo, virtualPoolRepo, userRepo, templatePath, rpcEndpoint)
+	grad := New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, rootChainID, rpcEndpoint, testRPCSecret)
 
 	assert.NotNil(t, grad)
 	assert.Equal(t, chainRepo, grad.chainRepo)
 	assert.Equal(t, virtualPoolRepo, grad.virtualPoolRepo)
+	assert.Equal(t, graduatedPoolRepo, grad.graduatedPoolRepo)
 	assert.Equal(t, userRepo, grad.userRepo)
-	assert.Equal(t, templatePath, grad.templatePath)
+	assert.Equal(t, graduationRepo, grad.graduationRepo)
+	assert.Equal(t, rootChainID, grad.rootChainID)
 	assert.Equal(t, rpcEndpoint, grad.rpcEndpoint)
+	assert.Equal(t, testRPCSecret, grad.rpcSecret)
 	assert.NotNil(t, grad.httpClient)
 }
@@ -0,0 +1,153 @@
+package graduator
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/canopy-network/canopy/fsm"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/google/uuid"
+)
+
+// Precondition names reported by Preview
+const (
+	PreconditionStatus          = "status"
+	PreconditionThreshold       = "threshold"
+	PreconditionRepository      = "repository"
+	PreconditionCreatorUsername = "creator_username"
+	PreconditionGenesis         = "genesis"
+)
+
+// Genesis allocation types reported by Preview
+const (
+	AllocationTypeAccount   = "account"
+	AllocationTypeValidator = "validator_stake"
+	AllocationTypeVesting   = "vesting"
+)
+
+// PreconditionFailure describes a requirement the chain does not yet meet for graduation
+type PreconditionFailure struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// GenesisAllocation is a single balance or stake in the previewed genesis, in
+// token base units. Vesting tranches are reported against their beneficiary
+// with the height they unlock at.
+type GenesisAllocation struct {
+	Address       string `json:"address"`
+	Amount        uint64 `json:"amount"`
+	Type          string `json:"type"`
+	ReleaseHeight uint64 `json:"release_height,omitempty"`
+}
+
+// GraduationPreview is the result of a graduation dry run: the genesis and RPC
+// payload graduation would produce right now, and whatever is still blocking it
+type GraduationPreview struct {
+	ChainID             uuid.UUID             `json:"chain_id"`
+	Ready               bool                  `json:"ready"`
+	FailedPreconditions []PreconditionFailure `json:"failed_preconditions"`
+	CNPYReserve         float64               `json:"cnpy_reserve"`
+	GraduationThreshold float64               `json:"graduation_threshold"`
+	Genesis             json.RawMessage       `json:"genesis,omitempty"`
+	GenesisHash         string                `json:"genesis_hash,omitempty"`
+	Allocations         []GenesisAllocation   `json:"allocations"`
+	Payload             *GraduationRPCPayload `json:"rpc_payload"`
+}
+
+// Preview performs a graduation dry run for a chain. It builds the genesis from
+// the current holder positions and the RPC payload that would be sent, without
+// calling the RPC or changing any state. Unmet requirements are reported in the
+// preview rather than returned as errors.
+func (g *Graduator) Preview(ctx context.Context, chainID uuid.UUID) (*GraduationPreview, error) {
+	chain, err := g.chainRepo.GetByID(ctx, chainID, []string{"creator", "repository"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain: %w", err)
+	}
+
+	pool, err := g.virtualPoolRepo.GetPoolByChainID(ctx, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get virtual pool: %w", err)
+	}
+
+	preview := &GraduationPreview{
+		ChainID:             chain.ID,
+		FailedPreconditions: []PreconditionFailure{},
+		CNPYReserve:         pool.CNPYReserve,
+		GraduationThreshold: chain.GraduationThreshold,
+		Allocations:         []GenesisAllocation{},
+	}
+	fail := func(name, format string, args ...interface{}) {
+		preview.FailedPreconditions = append(preview.FailedPreconditions, PreconditionFailure{
+			Name:    name,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if chain.IsGraduated || chain.Status != models.ChainStatusVirtualActive {
+		fail(PreconditionStatus, "chain status is %s, expected %s", chain.Status, models.ChainStatusVirtualActive)
+	}
+	if !pool.ReachesThreshold(chain.GraduationThreshold) {
+		fail(PreconditionThreshold, "virtual pool holds %v CNPY of the %v required", pool.CNPYReserve, chain.GraduationThreshold)
+	}
+	if chain.Repository == nil {
+		fail(PreconditionRepository, "chain has no repository")
+	}
+	if chain.Creator == nil || getStringValue(chain.Creator.Username) == "" {
+		fail(PreconditionCreatorUsername, "chain creator has no username")
+	}
+
+	var genesisFile string
+	genesis, err := g.buildChainGenesis(ctx, chain, time.Now())
+	if err == nil {
+		var data []byte
+		data, err = MarshalGenesis(genesis)
+		if err == nil {
+			genesisFile = string(data)
+			preview.Genesis = data
+			preview.GenesisHash = HashGenesis(data)
+			preview.Allocations = genesisAllocations(genesis)
+		}
+	}
+	if err != nil {
+		fail(PreconditionGenesis, "%v", err)
+	}
+
+	preview.Payload = NewGraduationRPCPayload(chain, genesisFile)
+	preview.Ready = len(preview.FailedPreconditions) == 0
+
+	return preview, nil
+}
+
+// genesisAllocations lists the account balances, validator stakes and vesting
+// tranches in a genesis
+func genesisAllocations(genesis *fsm.GenesisState) []GenesisAllocation {
+	allocations := make([]GenesisAllocation, 0, len(genesis.Accounts)+len(genesis.Validators))
+	for _, account := range genesis.Accounts {
+		allocations = append(allocations, GenesisAllocation{
+			Address: hex.EncodeToString(account.Address),
+			Amount:  account.Amount,
+			Type:    AllocationTypeAccount,
+		})
+	}
+	for _, validator := range genesis.Validators {
+		if validator.Delegate && validator.UnstakingHeight != 0 {
+			allocations = append(allocations, GenesisAllocation{
+				Address:       hex.EncodeToString(validator.Output),
+				Amount:        validator.StakedAmount,
+				Type:          AllocationTypeVesting,
+				ReleaseHeight: validator.UnstakingHeight,
+			})
+			continue
+		}
+		allocations = append(allocations, GenesisAllocation{
+			Address: hex.EncodeToString(validator.Address),
+			Amount:  validator.StakedAmount,
+			Type:    AllocationTypeValidator,
+		})
+	}
+	return allocations
+}
@@ -0,0 +1,120 @@
+package graduator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/internal/testutil/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreview(t *testing.T) {
+	// The preview must never reach the deployer
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	holder := strings.Repeat("ab", 20)
+	positions := []interfaces.UserPositionWithAddress{{WalletAddress: holder, TokenBalanceUnits: 2500}}
+
+	t.Run("ready chain", func(t *testing.T) {
+		chainID := uuid.New()
+		username := "creator"
+		chain := newGenesisChain(chainID)
+		chain.Status = models.ChainStatusVirtualActive
+		chain.GraduationThreshold = 50000
+		chain.Creator = &models.User{Username: &username, WalletAddress: "0xcreator"}
+		chain.Repository = &models.ChainRepository{GithubURL: "https://github.com/test/repo"}
+		validatorKey := newValidatorKey(t, chainID)
+
+		chainRepo := new(mocks.MockChainRepository)
+		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		graduationRepo := new(mocks.MockChainGraduationRepository)
+		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(chain, nil)
+		chainRepo.On("GetChainKeyByChainID", mock.Anything, chainID, models.KeyPurposeChainOperation).Return(validatorKey, nil)
+		chainRepo.On("GetVestingSchedulesByChainID", mock.Anything, chainID).Return([]models.ChainVestingSchedule{}, nil)
+		virtualPoolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(&models.VirtualPool{CNPYReserve: 50000, CNPYReserveMicro: 50000000000}, nil)
+		virtualPoolRepo.On("GetPositionsWithUsersByChainID", mock.Anything, chainID).Return(positions, nil)
+
+		grad := New(chainRepo, virtualPoolRepo, nil, nil, graduationRepo, 1, server.URL, testRPCSecret)
+		preview, err := grad.Preview(context.Background(), chainID)
+		require.NoError(t, err)
+
+		assert.True(t, preview.Ready)
+		assert.Empty(t, preview.FailedPreconditions)
+		assert.NotEmpty(t, preview.Genesis)
+		assert.Equal(t, HashGenesis(preview.Genesis), preview.GenesisHash)
+		assert.ElementsMatch(t, []GenesisAllocation{
+			{Address: holder, Amount: 2500, Type: AllocationTypeAccount},
+			{Address: validatorKey.Address, Amount: 1000000000 - 2500 - 1000, Type: AllocationTypeAccount},
+			{Address: validatorKey.Address, Amount: 1000, Type: AllocationTypeValidator},
+		}, preview.Allocations)
+
+		require.NotNil(t, preview.Payload)
+		assert.Equal(t, "creator", preview.Payload.Username)
+		assert.Equal(t, "https://github.com/test/repo", preview.Payload.GithubRepo)
+		assert.Equal(t, string(preview.Genesis), preview.Payload.GenesisFile)
+
+		assert.Equal(t, 0, requests)
+		chainRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		graduationRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failing preconditions are reported", func(t *testing.T) {
+		chainID := uuid.New()
+		chain := newGenesisChain(chainID)
+		chain.Status = models.ChainStatusVirtualActive
+		chain.GraduationThreshold = 50000
+		chain.Creator = &models.User{WalletAddress: "0xcreator"}
+
+		chainRepo := new(mocks.MockChainRepository)
+		virtualPoolRepo := new(mocks.MockVirtualPoolRepository)
+		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(chain, nil)
+		chainRepo.On("GetChainKeyByChainID", mock.Anything, chainID, models.KeyPurposeChainOperation).Return(nil, errors.New("chain key not found"))
+		virtualPoolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(&models.VirtualPool{CNPYReserve: 1200, CNPYReserveMicro: 1200000000}, nil)
+		virtualPoolRepo.On("GetPositionsWithUsersByChainID", mock.Anything, chainID).Return(positions, nil)
+
+		grad := New(chainRepo, virtualPoolRepo, nil, nil, nil, 1, server.URL, testRPCSecret)
+		preview, err := grad.Preview(context.Background(), chainID)
+		require.NoError(t, err)
+
+		assert.False(t, preview.Ready)
+		var names []string
+		for _, failure := range preview.FailedPreconditions {
+			names = append(names, failure.Name)
+		}
+		assert.Equal(t, []string{
+			PreconditionThreshold,
+			PreconditionRepository,
+			PreconditionCreatorUsername,
+			PreconditionGenesis,
+		}, names)
+		assert.Empty(t, preview.Genesis)
+		assert.Empty(t, preview.Allocations)
+		require.NotNil(t, preview.Payload)
+		assert.Empty(t, preview.Payload.GithubRepo)
+		assert.Equal(t, 0, requests)
+	})
+
+	t.Run("chain not found", func(t *testing.T) {
+		chainID := uuid.New()
+		chainRepo := new(mocks.MockChainRepository)
+		chainRepo.On("GetByID", mock.Anything, chainID, []string{"creator", "repository"}).Return(nil, errors.New("chain not found"))
+
+		grad := New(chainRepo, nil, nil, nil, nil, 1, server.URL, testRPCSecret)
+		preview, err := grad.Preview(context.Background(), chainID)
+		assert.Nil(t, preview)
+		assert.ErrorContains(t, err, "chain not found")
+	})
+}
@@ -0,0 +1,72 @@
+package graduator
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers carried by requests exchanged with the deployer
+const (
+	SignatureHeader      = "X-Launchpad-Signature"
+	TimestampHeader      = "X-Launchpad-Timestamp"
+	IdempotencyKeyHeader = "Idempotency-Key"
+)
+
+// SignatureMaxSkew is how far a signed request's timestamp may drift from the
+// receiver's clock before it is rejected as a replay
+const SignatureMaxSkew = 5 * time.Minute
+
+var (
+	ErrMissingSignature = errors.New("missing request signature")
+	ErrInvalidSignature = errors.New("invalid request signature")
+	ErrStaleSignature   = errors.New("request timestamp outside allowed window")
+)
+
+// SignRequest returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>" under
+// the shared secret. Both the graduation request and the deployer callback are
+// signed this way.
+func SignRequest(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRequest checks a signature and unix timestamp header pair against the
+// request body. Timestamps more than SignatureMaxSkew away from now are rejected.
+func VerifyRequest(secret, timestampHeader, signature string, body []byte, now time.Time) error {
+	if secret == "" || timestampHeader == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+
+	skew := now.Sub(time.Unix(timestamp, 0))
+	if skew > SignatureMaxSkew || skew < -SignatureMaxSkew {
+		return ErrStaleSignature
+	}
+
+	expected := SignRequest(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// IdempotencyKey is sent with every graduation request for a chain. It stays the
+// same across retries so the deployer provisions the chain at most once.
+func IdempotencyKey(chainID uuid.UUID) string {
+	return "graduation-" + chainID.String()
+}
@@ -0,0 +1,56 @@
+package graduator
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRPCSecret = "test-graduation-secret"
+
+func TestVerifyRequest(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"status":"running","rpc_url":"http://node:50002"}`)
+	timestamp := now.Unix()
+	signature := SignRequest(testRPCSecret, timestamp, body)
+	header := strconv.FormatInt(timestamp, 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		expectErr error
+	}{
+		{name: "valid signature", secret: testRPCSecret, timestamp: header, signature: signature, body: body, now: now},
+		{name: "tampered body", secret: testRPCSecret, timestamp: header, signature: signature, body: []byte(`{"status":"failed"}`), now: now, expectErr: ErrInvalidSignature},
+		{name: "wrong secret", secret: "another-secret", timestamp: header, signature: signature, body: body, now: now, expectErr: ErrInvalidSignature},
+		{name: "timestamp not covered by signature", secret: testRPCSecret, timestamp: strconv.FormatInt(timestamp+1, 10), signature: signature, body: body, now: now, expectErr: ErrInvalidSignature},
+		{name: "malformed timestamp", secret: testRPCSecret, timestamp: "yesterday", signature: signature, body: body, now: now, expectErr: ErrInvalidSignature},
+		{name: "replayed outside window", secret: testRPCSecret, timestamp: header, signature: signature, body: body, now: now.Add(SignatureMaxSkew + time.Second), expectErr: ErrStaleSignature},
+		{name: "missing signature", secret: testRPCSecret, timestamp: header, body: body, now: now, expectErr: ErrMissingSignature},
+		{name: "no secret configured", timestamp: header, signature: SignRequest("", timestamp, body), body: body, now: now, expectErr: ErrMissingSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyRequest(tt.secret, tt.timestamp, tt.signature, tt.body, tt.now)
+			if tt.expectErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	chainID := uuid.New()
+	assert.Equal(t, IdempotencyKey(chainID), IdempotencyKey(chainID))
+	assert.NotEqual(t, IdempotencyKey(chainID), IdempotencyKey(uuid.New()))
+}
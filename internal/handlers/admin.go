@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/services"
+	"github.com/enielson/launchpad/internal/validators"
+	"github.com/enielson/launchpad/pkg/response"
+	"github.com/go-chi/chi/v5"
+)
+
+type AdminHandler struct {
+	deadLetterService *services.DeadLetterService
+	validator         *validators.Validator
+}
+
+func NewAdminHandler(deadLetterService *services.DeadLetterService, validator *validators.Validator) *AdminHandler {
+	return &AdminHandler{
+		deadLetterService: deadLetterService,
+		validator:         validator,
+	}
+}
+
+// GetFailedEvents handles GET /api/v1/admin/failed-events
+// Lists the dead-letter queue, optionally filtered by status and event type.
+func (h *AdminHandler) GetFailedEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Parse query parameters
+	query := r.URL.Query()
+	params := models.FailedEventsQueryParams{
+		Status:    query.Get("status"),
+		EventType: query.Get("event_type"),
+	}
+	if pageStr := query.Get("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil {
+			params.Page = page
+		}
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			params.Limit = limit
+		}
+	}
+
+	// Validate query parameters
+	if err := h.validator.Validate(&params); err != nil {
+		validationErrors := h.validator.FormatErrors(err)
+		response.ValidationError(w, validationErrors)
+		return
+	}
+
+	// Set defaults
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+
+	events, pagination, err := h.deadLetterService.GetFailedEvents(ctx, &params)
+	if err != nil {
+		log.Printf("Failed to retrieve failed events: %v", err)
+		response.InternalServerError(w, "Failed to retrieve failed events")
+		return
+	}
+
+	response.SuccessWithPagination(w, http.StatusOK, events, pagination)
+}
+
+// RetryFailedEvent handles POST /api/v1/admin/failed-events/{id}/retry
+// Replays the event immediately and returns it with the outcome recorded. A
+// retry that fails again is not an error; the event's last_error says why.
+func (h *AdminHandler) RetryFailedEvent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	eventID := chi.URLParam(r, "id")
+
+	event, err := h.deadLetterService.RetryFailedEvent(ctx, eventID)
+	if err != nil {
+		h.writeFailedEventError(w, eventID, "retry", err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, event)
+}
+
+// DiscardFailedEvent handles POST /api/v1/admin/failed-events/{id}/discard
+// Stops an event from being retried.
+func (h *AdminHandler) DiscardFailedEvent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	eventID := chi.URLParam(r, "id")
+
+	event, err := h.deadLetterService.DiscardFailedEvent(ctx, eventID)
+	if err != nil {
+		h.writeFailedEventError(w, eventID, "discard", err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, event)
+}
+
+// writeFailedEventError maps a retry or discard error to a response
+func (h *AdminHandler) writeFailedEventError(w http.ResponseWriter, eventID, action string, err error) {
+	switch {
+	case strings.Contains(err.Error(), "invalid event ID"):
+		response.BadRequest(w, "Invalid event ID", nil)
+	case errors.Is(err, services.ErrFailedEventNotFound):
+		response.NotFound(w, "Failed event not found")
+	case errors.Is(err, services.ErrFailedEventClosed):
+		response.Conflict(w, "Failed event already resolved or discarded", nil)
+	case errors.Is(err, services.ErrFailedEventChanged):
+		response.Conflict(w, "Failed event was updated concurrently, try again", nil)
+	default:
+		log.Printf("Failed to %s failed event %s: %v", action, eventID, err)
+		response.InternalServerError(w, "Failed to "+action+" failed event")
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -124,6 +125,22 @@ func (h *ChainHandler) CreateChain(w http.ResponseWriter, r *http.Request) {
 			response.Conflict(w, "Chain name already exists", nil)
 			return
 		}
+		if errors.Is(err, services.ErrInvalidAllocation) {
+			response.UnprocessableEntity(w, "Invalid token allocation", err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidVesting) {
+			response.UnprocessableEntity(w, "Invalid vesting schedule", err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrUnknownRootChain) {
+			response.UnprocessableEntity(w, "Unknown root chain", err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCurve) {
+			response.UnprocessableEntity(w, "Invalid bonding curve", err.Error())
+			return
+		}
 		log.Printf("Create chain failed for user %s: %v", userID, err)
 		response.InternalServerError(w, "Failed to create chain")
 		return
@@ -178,6 +195,64 @@ func (h *ChainHandler) UpdateChainDescription(w http.ResponseWriter, r *http.Req
 	response.Success(w, http.StatusOK, chain)
 }
 
+// UpdateChainAllocation handles PUT /api/v1/chains/{id}/allocation
+func (h *ChainHandler) UpdateChainAllocation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+	userID := h.getUserIDFromContext(ctx)
+
+	var req models.TokenAllocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid JSON payload", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(&req); err != nil {
+		validationErrors := h.validator.FormatErrors(err)
+		response.ValidationError(w, validationErrors)
+		return
+	}
+
+	// Update chain allocation
+	chain, err := h.chainService.UpdateChainAllocation(ctx, chainID, userID, &req)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, chain)
+}
+
+// UpdateVestingSchedules handles PUT /api/v1/chains/{id}/vesting
+func (h *ChainHandler) UpdateVestingSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+	userID := h.getUserIDFromContext(ctx)
+
+	var req models.UpdateVestingSchedulesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid JSON payload", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(&req); err != nil {
+		validationErrors := h.validator.FormatErrors(err)
+		response.ValidationError(w, validationErrors)
+		return
+	}
+
+	// Replace vesting schedules
+	chain, err := h.chainService.UpdateVestingSchedules(ctx, chainID, userID, &req)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, chain)
+}
+
 // GetRepository handles GET /api/v1/chains/{id}/repository
 func (h *ChainHandler) GetRepository(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -454,6 +529,16 @@ func parseTime(timeStr string) (time.Time, error) {
 }
 
 func (h *ChainHandler) handleServiceError(w http.ResponseWriter, err error) {
+	// Allocation and vesting errors are wrapped with the failing rule
+	if errors.Is(err, services.ErrInvalidAllocation) {
+		response.UnprocessableEntity(w, "Invalid token allocation", err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrInvalidVesting) {
+		response.UnprocessableEntity(w, "Invalid vesting schedule", err.Error())
+		return
+	}
+
 	switch err {
 	case services.ErrChainNotFound:
 		response.NotFound(w, "Chain not found")
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/services"
+	"github.com/enielson/launchpad/internal/validators"
+	"github.com/enielson/launchpad/pkg/response"
+)
+
+type FeeHandler struct {
+	feeService *services.FeeService
+	validator  *validators.Validator
+}
+
+func NewFeeHandler(feeService *services.FeeService, validator *validators.Validator) *FeeHandler {
+	return &FeeHandler{
+		feeService: feeService,
+		validator:  validator,
+	}
+}
+
+// GetUserFees handles GET /api/v1/users/fees
+// Lists the trading fees the authenticated user has accrued as a chain creator
+// or referrer, with the part still claimable.
+func (h *FeeHandler) GetUserFees(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := ctx.Value("userID").(string)
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	params, ok := h.parseBalancesQuery(w, r)
+	if !ok {
+		return
+	}
+
+	balances, pagination, err := h.feeService.GetUserBalances(ctx, userID, params)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid user ID") || strings.Contains(err.Error(), "invalid chain ID") {
+			response.BadRequest(w, err.Error(), nil)
+			return
+		}
+		log.Printf("Failed to retrieve fee balances for user %s: %v", userID, err)
+		response.InternalServerError(w, "Failed to retrieve fee balances")
+		return
+	}
+
+	response.SuccessWithPagination(w, http.StatusOK, balances, pagination)
+}
+
+// GetFeeBalances handles GET /api/v1/admin/fees
+// Lists the trading fees accrued to every recipient, including the protocol
+// treasury, optionally filtered by recipient type and chain.
+func (h *FeeHandler) GetFeeBalances(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	params, ok := h.parseBalancesQuery(w, r)
+	if !ok {
+		return
+	}
+
+	balances, pagination, err := h.feeService.GetBalances(ctx, params)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid chain ID") {
+			response.BadRequest(w, err.Error(), nil)
+			return
+		}
+		log.Printf("Failed to retrieve fee balances: %v", err)
+		response.InternalServerError(w, "Failed to retrieve fee balances")
+		return
+	}
+
+	response.SuccessWithPagination(w, http.StatusOK, balances, pagination)
+}
+
+// parseBalancesQuery reads and validates the fee balance query parameters,
+// writing the validation error response itself when they are invalid
+func (h *FeeHandler) parseBalancesQuery(w http.ResponseWriter, r *http.Request) (*models.FeeBalancesQueryParams, bool) {
+	// Parse query parameters
+	query := r.URL.Query()
+	params := models.FeeBalancesQueryParams{
+		RecipientType: query.Get("recipient_type"),
+		ChainID:       query.Get("chain_id"),
+	}
+	if pageStr := query.Get("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil {
+			params.Page = page
+		}
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			params.Limit = limit
+		}
+	}
+
+	// Validate query parameters
+	if err := h.validator.Validate(&params); err != nil {
+		validationErrors := h.validator.FormatErrors(err)
+		response.ValidationError(w, validationErrors)
+		return nil, false
+	}
+
+	// Set defaults
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+
+	return &params, true
+}
@@ -1 +1,173 @@
 package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/services"
+	"github.com/enielson/launchpad/internal/validators"
+	"github.com/enielson/launchpad/pkg/response"
+	"github.com/go-chi/chi/v5"
+)
+
+type GraduatedPoolHandler struct {
+	graduatedPoolService *services.GraduatedPoolService
+	validator            *validators.Validator
+}
+
+func NewGraduatedPoolHandler(graduatedPoolService *services.GraduatedPoolService, validator *validators.Validator) *GraduatedPoolHandler {
+	return &GraduatedPoolHandler{
+		graduatedPoolService: graduatedPoolService,
+		validator:            validator,
+	}
+}
+
+// GetGraduatedPools handles GET /api/v1/graduated-pools
+func (h *GraduatedPoolHandler) GetGraduatedPools(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Parse query parameters
+	var params models.GraduatedPoolsQueryParams
+	params.Page, params.Limit = h.parsePagination(r)
+
+	// Validate query parameters
+	if err := h.validator.Validate(&params); err != nil {
+		validationErrors := h.validator.FormatErrors(err)
+		response.ValidationError(w, validationErrors)
+		return
+	}
+
+	// Set defaults
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+
+	pools, pagination, err := h.graduatedPoolService.GetAllPools(ctx, params.Page, params.Limit)
+	if err != nil {
+		log.Printf("Failed to retrieve graduated pools: %v", err)
+		response.InternalServerError(w, "Failed to retrieve graduated pools")
+		return
+	}
+
+	response.SuccessWithPagination(w, http.StatusOK, pools, pagination)
+}
+
+// GetGraduatedPool handles GET /api/v1/graduated-pools/{id}
+func (h *GraduatedPoolHandler) GetGraduatedPool(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+
+	pool, err := h.graduatedPoolService.GetPool(ctx, chainID)
+	if err != nil {
+		h.handleServiceError(w, chainID, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, pool)
+}
+
+// GetGraduatedPoolTransactions handles GET /api/v1/graduated-pools/{id}/transactions
+func (h *GraduatedPoolHandler) GetGraduatedPoolTransactions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+
+	// Parse query parameters
+	var params models.GraduatedTransactionsQueryParams
+	params.UserID = r.URL.Query().Get("user_id")
+	params.TransactionType = r.URL.Query().Get("transaction_type")
+	params.Page, params.Limit = h.parsePagination(r)
+
+	// Validate query parameters
+	if err := h.validator.Validate(&params); err != nil {
+		validationErrors := h.validator.FormatErrors(err)
+		response.ValidationError(w, validationErrors)
+		return
+	}
+
+	// Set defaults
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+
+	transactions, pagination, err := h.graduatedPoolService.GetTransactions(
+		ctx,
+		chainID,
+		params.UserID,
+		params.TransactionType,
+		params.Page,
+		params.Limit,
+	)
+	if err != nil {
+		h.handleServiceError(w, chainID, err)
+		return
+	}
+
+	response.SuccessWithPagination(w, http.StatusOK, transactions, pagination)
+}
+
+// GetGraduatedPoolPositions handles GET /api/v1/graduated-pools/{id}/positions
+func (h *GraduatedPoolHandler) GetGraduatedPoolPositions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+
+	// Parse query parameters
+	var params models.GraduatedPoolsQueryParams
+	params.Page, params.Limit = h.parsePagination(r)
+
+	// Validate query parameters
+	if err := h.validator.Validate(&params); err != nil {
+		validationErrors := h.validator.FormatErrors(err)
+		response.ValidationError(w, validationErrors)
+		return
+	}
+
+	// Set defaults
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+
+	positions, pagination, err := h.graduatedPoolService.GetPositions(ctx, chainID, params.Page, params.Limit)
+	if err != nil {
+		h.handleServiceError(w, chainID, err)
+		return
+	}
+
+	response.SuccessWithPagination(w, http.StatusOK, positions, pagination)
+}
+
+// Helper methods
+func (h *GraduatedPoolHandler) parsePagination(r *http.Request) (page, limit int) {
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil {
+			page = p
+		}
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			limit = l
+		}
+	}
+
+	return page, limit
+}
+
+func (h *GraduatedPoolHandler) handleServiceError(w http.ResponseWriter, chainID string, err error) {
+	if err == services.ErrGraduatedPoolNotFound {
+		response.NotFound(w, "Graduated pool not found")
+		return
+	}
+	log.Printf("Failed to retrieve graduated pool for chain %s: %v", chainID, err)
+	response.InternalServerError(w, "Failed to retrieve graduated pool")
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/services"
+	"github.com/enielson/launchpad/internal/validators"
+	"github.com/enielson/launchpad/pkg/response"
+	"github.com/go-chi/chi/v5"
+)
+
+// GenesisHashHeader carries the SHA-256 of the genesis file returned by GetChainGenesis
+const GenesisHashHeader = "X-Genesis-Hash"
+
+type GraduationHandler struct {
+	graduationService *services.GraduationService
+	validator         *validators.Validator
+}
+
+func NewGraduationHandler(graduationService *services.GraduationService, validator *validators.Validator) *GraduationHandler {
+	return &GraduationHandler{
+		graduationService: graduationService,
+		validator:         validator,
+	}
+}
+
+// GetGraduations handles GET /api/v1/graduations
+func (h *GraduationHandler) GetGraduations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Parse query parameters
+	var params models.GraduationsQueryParams
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil {
+			params.Page = page
+		}
+	}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			params.Limit = limit
+		}
+	}
+
+	// Validate query parameters
+	if err := h.validator.Validate(&params); err != nil {
+		validationErrors := h.validator.FormatErrors(err)
+		response.ValidationError(w, validationErrors)
+		return
+	}
+
+	// Set defaults
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+
+	graduations, pagination, err := h.graduationService.GetGraduations(ctx, params.Page, params.Limit)
+	if err != nil {
+		log.Printf("Failed to retrieve graduations: %v", err)
+		response.InternalServerError(w, "Failed to retrieve graduations")
+		return
+	}
+
+	response.SuccessWithPagination(w, http.StatusOK, graduations, pagination)
+}
+
+// GetChainGraduation handles GET /api/v1/chains/{id}/graduation
+func (h *GraduationHandler) GetChainGraduation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+
+	graduation, err := h.graduationService.GetGraduationByChainID(ctx, chainID)
+	if err != nil {
+		if err == services.ErrGraduationNotFound {
+			response.NotFound(w, "Graduation not started for chain")
+			return
+		}
+		log.Printf("Failed to retrieve graduation for chain %s: %v", chainID, err)
+		response.InternalServerError(w, "Failed to retrieve graduation")
+		return
+	}
+
+	response.Success(w, http.StatusOK, graduation)
+}
+
+// GetGraduationPreview handles GET /api/v1/chains/{id}/graduation/preview
+func (h *GraduationHandler) GetGraduationPreview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+
+	preview, err := h.graduationService.PreviewGraduation(ctx, chainID)
+	if err != nil {
+		switch err {
+		case services.ErrChainNotFound:
+			response.NotFound(w, "Chain not found")
+		case services.ErrPoolNotFound:
+			response.NotFound(w, "Virtual pool not found for chain")
+		default:
+			log.Printf("Failed to preview graduation for chain %s: %v", chainID, err)
+			response.InternalServerError(w, "Failed to preview graduation")
+		}
+		return
+	}
+
+	response.Success(w, http.StatusOK, preview)
+}
+
+// RecordDeployment handles POST /api/v1/chains/{id}/graduation/deployment
+// It is called by the deployer, authenticated by request signature, to report
+// provisioning progress and the new chain's RPC URL.
+func (h *GraduationHandler) RecordDeployment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+
+	var req models.DeploymentCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid JSON payload", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(&req); err != nil {
+		validationErrors := h.validator.FormatErrors(err)
+		response.ValidationError(w, validationErrors)
+		return
+	}
+
+	graduation, err := h.graduationService.RecordDeployment(ctx, chainID, &req)
+	if err != nil {
+		switch err {
+		case services.ErrGraduationNotFound:
+			response.NotFound(w, "Graduation not started for chain")
+		case services.ErrGraduationComplete:
+			response.Conflict(w, "Graduation already complete", nil)
+		default:
+			log.Printf("Failed to record deployment for chain %s: %v", chainID, err)
+			response.InternalServerError(w, "Failed to record deployment")
+		}
+		return
+	}
+
+	response.Success(w, http.StatusOK, graduation)
+}
+
+// GetChainGenesis handles GET /api/v1/chains/{id}/genesis
+// It returns the exact genesis bytes that were hashed at graduation, so clients can
+// verify the body against the hash header before comparing it with the deployed network.
+func (h *GraduationHandler) GetChainGenesis(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+
+	genesis, hash, err := h.graduationService.GetGenesisByChainID(ctx, chainID)
+	if err != nil {
+		if err == services.ErrGenesisNotFound {
+			response.NotFound(w, "Genesis not available for chain")
+			return
+		}
+		log.Printf("Failed to retrieve genesis for chain %s: %v", chainID, err)
+		response.InternalServerError(w, "Failed to retrieve genesis")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(GenesisHashHeader, hash)
+	w.Header().Set("ETag", `"`+hash+`"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(genesis); err != nil {
+		log.Printf("Failed to write genesis for chain %s: %v", chainID, err)
+	}
+}
@@ -8,13 +8,25 @@ import (
 	"github.com/enielson/launchpad/pkg/response"
 )
 
-// HealthCheck handles GET /health
-func HealthCheck(w http.ResponseWriter, r *http.Request) {
-	healthResponse := models.HealthResponse{
-		Status:    "healthy",
-		Timestamp: time.Now().Format(time.RFC3339),
-		Version:   "1.0.0", // This could come from build info
-	}
+// RootChainStatusProvider reports the ingestion progress of every root chain
+// served for the health check
+type RootChainStatusProvider interface {
+	Status() []*models.RootChainStatus
+}
 
-	response.Success(w, http.StatusOK, healthResponse)
+// HealthCheck handles GET /health. Per-root-chain ingestion status is included
+// when a provider is given.
+func HealthCheck(rootChain RootChainStatusProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		healthResponse := models.HealthResponse{
+			Status:    "healthy",
+			Timestamp: time.Now().Format(time.RFC3339),
+			Version:   "1.0.0", // This could come from build info
+		}
+		if rootChain != nil {
+			healthResponse.RootChains = rootChain.Status()
+		}
+
+		response.Success(w, http.StatusOK, healthResponse)
+	}
 }
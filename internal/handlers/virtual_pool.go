@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/services"
@@ -39,6 +42,126 @@ func (h *VirtualPoolHandler) GetVirtualPool(w http.ResponseWriter, r *http.Reque
 	response.Success(w, http.StatusOK, pool)
 }
 
+// GetPendingDeposits handles GET /api/v1/virtual-pools/{id}/pending-deposits
+// Lists deposits seen on the root chain that are not final yet. The optional
+// sender query parameter limits the list to one wallet address.
+func (h *VirtualPoolHandler) GetPendingDeposits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+	sender := r.URL.Query().Get("sender")
+
+	deposits, err := h.virtualPoolService.GetPendingDeposits(ctx, chainID, sender)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid chain ID") {
+			response.BadRequest(w, "Invalid chain ID", nil)
+			return
+		}
+		log.Printf("Failed to retrieve pending deposits: %v", err)
+		response.InternalServerError(w, "Failed to retrieve pending deposits")
+		return
+	}
+
+	response.Success(w, http.StatusOK, deposits)
+}
+
+// GetQuote handles GET /api/v1/virtual-pools/{id}/quote
+// Prices a buy of amount CNPY or a sell of amount tokens at the pool's current
+// reserves. The response carries a short-lived signed quote whose minimum,
+// the amount out less slippage_bps, the deposit or sell must fill for, and
+// which credits the optional referrer with part of the trade's fee.
+func (h *VirtualPoolHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+	query := r.URL.Query()
+
+	slippage := uint64(services.DefaultQuoteSlippageBasisPoints)
+	if slippageStr := query.Get("slippage_bps"); slippageStr != "" {
+		parsed, err := strconv.ParseUint(slippageStr, 10, 64)
+		if err != nil {
+			response.BadRequest(w, "Invalid query parameters", "slippage_bps must be a whole number of basis points")
+			return
+		}
+		slippage = parsed
+	}
+
+	quote, err := h.virtualPoolService.Quote(ctx, chainID, query.Get("side"), query.Get("amount"), slippage, query.Get("referrer"))
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "invalid chain ID"):
+			response.BadRequest(w, "Invalid chain ID", nil)
+		case errors.Is(err, services.ErrInvalidQuoteRequest), errors.Is(err, services.ErrZeroAmount):
+			response.BadRequest(w, "Invalid quote request", err.Error())
+		case errors.Is(err, services.ErrPoolNotFound):
+			response.NotFound(w, "Virtual pool not found")
+		case errors.Is(err, services.ErrPoolInactive):
+			response.Conflict(w, "Virtual pool is no longer trading", nil)
+		case errors.Is(err, services.ErrInsufficientReserves):
+			response.UnprocessableEntity(w, "Trade exceeds the pool's reserves", nil)
+		default:
+			log.Printf("Failed to quote trade for chain %s: %v", chainID, err)
+			response.InternalServerError(w, "Failed to quote trade")
+		}
+		return
+	}
+
+	response.Success(w, http.StatusOK, quote)
+}
+
+// Sell handles POST /api/v1/virtual-pools/{id}/sell
+// Executes a sell intent signed by the wallet holding the tokens. The CNPY
+// proceeds are queued as a payout to that wallet.
+func (h *VirtualPoolHandler) Sell(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	chainID := chi.URLParam(r, "id")
+
+	var req models.SellIntentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid JSON payload", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(&req); err != nil {
+		validationErrors := h.validator.FormatErrors(err)
+		response.ValidationError(w, validationErrors)
+		return
+	}
+
+	result, err := h.virtualPoolService.Sell(ctx, chainID, &req)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "invalid chain ID"):
+			response.BadRequest(w, "Invalid chain ID", nil)
+		case errors.Is(err, services.ErrInvalidSellIntent), errors.Is(err, services.ErrZeroAmount):
+			response.BadRequest(w, "Invalid sell intent", err.Error())
+		case errors.Is(err, services.ErrSellIntentExpired):
+			response.BadRequest(w, "Sell intent expired", nil)
+		case errors.Is(err, services.ErrInvalidSellSignature):
+			response.Unauthorized(w, "Invalid sell intent signature")
+		case errors.Is(err, services.ErrInvalidQuote):
+			response.BadRequest(w, "Invalid quote", err.Error())
+		case errors.Is(err, services.ErrQuoteExpired):
+			response.BadRequest(w, "Quote expired", nil)
+		case errors.Is(err, services.ErrPayoutAlreadyQueued):
+			response.Conflict(w, "Sell intent already executed", nil)
+		case errors.Is(err, services.ErrPoolNotFound):
+			response.NotFound(w, "Virtual pool not found")
+		case errors.Is(err, services.ErrPoolInactive):
+			response.Conflict(w, "Virtual pool is no longer trading", nil)
+		case errors.Is(err, services.ErrInsufficientBalance):
+			response.UnprocessableEntity(w, "Insufficient token balance", nil)
+		case errors.Is(err, services.ErrSlippageExceeded):
+			response.UnprocessableEntity(w, "Sale proceeds below min_cnpy_out or the quoted minimum", nil)
+		default:
+			log.Printf("Failed to execute sell for chain %s: %v", chainID, err)
+			response.InternalServerError(w, "Failed to execute sell")
+		}
+		return
+	}
+
+	response.Success(w, http.StatusOK, result)
+}
+
 // GetVirtualPools handles GET /api/v1/virtual-pools
 func (h *VirtualPoolHandler) GetVirtualPools(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/enielson/launchpad/pkg/response"
+)
+
+// AdminAuthMiddleware admits requests that carry the admin API key as a bearer
+// token. Every request is rejected when no key is configured.
+func AdminAuthMiddleware(apiKey string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apiKey == "" {
+				response.Forbidden(w, "Admin API is disabled")
+				return
+			}
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
+				response.Unauthorized(w, "Invalid admin API key")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
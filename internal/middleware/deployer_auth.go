@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/enielson/launchpad/internal/graduator"
+	"github.com/enielson/launchpad/pkg/response"
+)
+
+// maxDeployerBodySize bounds the callback body read for signature verification
+const maxDeployerBodySize = 1 << 20
+
+// DeployerAuthMiddleware verifies that a request was signed by the deployer with
+// the shared graduation secret. Requests are rejected when no secret is configured.
+func DeployerAuthMiddleware(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxDeployerBodySize))
+			if err != nil {
+				response.BadRequest(w, "Failed to read request body", nil)
+				return
+			}
+
+			err = graduator.VerifyRequest(
+				secret,
+				r.Header.Get(graduator.TimestampHeader),
+				r.Header.Get(graduator.SignatureHeader),
+				body,
+				time.Now(),
+			)
+			if err != nil {
+				switch {
+				case errors.Is(err, graduator.ErrMissingSignature):
+					response.Unauthorized(w, "Missing request signature")
+				case errors.Is(err, graduator.ErrStaleSignature):
+					response.Unauthorized(w, "Request timestamp outside allowed window")
+				default:
+					response.Unauthorized(w, "Invalid request signature")
+				}
+				return
+			}
+
+			// Restore the body for the handler
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -16,6 +16,7 @@ type Chain struct {
 	TemplateID                 *uuid.UUID `json:"template_id" db:"template_id"`
 	ConsensusMechanism         string     `json:"consensus_mechanism" db:"consensus_mechanism"`
 	TokenTotalSupply           int64      `json:"token_total_supply" db:"token_total_supply"`
+	TokenDecimals              int        `json:"token_decimals" db:"token_decimals"`
 	BlockTimeSeconds           *int       `json:"block_time_seconds" db:"block_time_seconds"`
 	UpgradeBlockHeight         *int64     `json:"upgrade_block_height" db:"upgrade_block_height"`
 	BlockRewardAmount          *float64   `json:"block_reward_amount" db:"block_reward_amount"`
@@ -24,6 +25,9 @@ type Chain struct {
 	InitialCNPYReserve         float64    `json:"initial_cnpy_reserve" db:"initial_cnpy_reserve"`
 	InitialTokenSupply         int64      `json:"initial_token_supply" db:"initial_token_supply"`
 	BondingCurveSlope          float64    `json:"bonding_curve_slope" db:"bonding_curve_slope"`
+	CurveType                  string     `json:"curve_type" db:"curve_type"`
+	CurveMaxPrice              *float64   `json:"curve_max_price" db:"curve_max_price"`
+	CurveMidpointSupply        *int64     `json:"curve_midpoint_supply" db:"curve_midpoint_supply"`
 	ScheduledLaunchTime        *time.Time `json:"scheduled_launch_time" db:"scheduled_launch_time"`
 	ActualLaunchTime           *time.Time `json:"actual_launch_time" db:"actual_launch_time"`
 	CreatorInitialPurchaseCNPY float64    `json:"creator_initial_purchase_cnpy" db:"creator_initial_purchase_cnpy"`
@@ -32,19 +36,109 @@ type Chain struct {
 	GraduationTime             *time.Time `json:"graduation_time" db:"graduation_time"`
 	ChainID                    *string    `json:"chain_id" db:"chain_id"`
 	GenesisHash                *string    `json:"genesis_hash" db:"genesis_hash"`
+	RootChainID                uint64     `json:"root_chain_id" db:"root_chain_id"`
 	ValidatorMinStake          float64    `json:"validator_min_stake" db:"validator_min_stake"`
 	CreatedBy                  uuid.UUID  `json:"created_by" db:"created_by"`
 	CreatedAt                  time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt                  time.Time  `json:"updated_at" db:"updated_at"`
 
+	// Genesis token allocation
+	TokenAllocation `json:"allocation"`
+
 	// Relationships (populated when requested)
-	Template      *ChainTemplate    `json:"template,omitempty"`
-	Creator       *User             `json:"creator,omitempty"`
-	Repository    *ChainRepository  `json:"repository,omitempty"`
-	SocialLinks   []ChainSocialLink `json:"social_links,omitempty"`
-	Assets        []ChainAsset      `json:"assets,omitempty"`
-	VirtualPool   *VirtualPool      `json:"virtual_pool,omitempty"`
-	GraduatedPool *GraduatedPool    `json:"graduated_pool,omitempty"`
+	Template         *ChainTemplate         `json:"template,omitempty"`
+	Creator          *User                  `json:"creator,omitempty"`
+	Repository       *ChainRepository       `json:"repository,omitempty"`
+	SocialLinks      []ChainSocialLink      `json:"social_links,omitempty"`
+	Assets           []ChainAsset           `json:"assets,omitempty"`
+	VestingSchedules []ChainVestingSchedule `json:"vesting_schedules,omitempty"`
+	VirtualPool      *VirtualPool           `json:"virtual_pool,omitempty"`
+	GraduatedPool    *GraduatedPool         `json:"graduated_pool,omitempty"`
+}
+
+// DefaultRootChainID is the root chain of chains created before the launchpad
+// served more than one
+const DefaultRootChainID uint64 = 1
+
+// DefaultTokenDecimals is the number of decimal places a chain's token is
+// divided into, so one token is 10^6 base units, as one CNPY is 10^6 uCNPY
+const DefaultTokenDecimals = 6
+
+// TokenUnit returns the number of base units in one of the chain's tokens
+func (c *Chain) TokenUnit() int64 {
+	unit := int64(1)
+	for i := 0; i < c.TokenDecimals; i++ {
+		unit *= 10
+	}
+	return unit
+}
+
+// TokenTotalSupplyUnits returns the chain's total token supply in base units
+func (c *Chain) TokenTotalSupplyUnits() int64 {
+	return c.TokenTotalSupply * c.TokenUnit()
+}
+
+// AllocationTotalBps is the basis point total every token allocation must sum to
+const AllocationTotalBps = 10000
+
+// TokenAllocation splits a chain's total token supply into genesis buckets, each
+// in basis points of TokenTotalSupply. The liquidity reserve funds the genesis
+// validator stake and the graduated pool; the holders bucket covers virtual pool
+// balances, with any unsold remainder returned to the liquidity reserve.
+type TokenAllocation struct {
+	CreatorBps   int `json:"creator_bps" db:"allocation_creator_bps"`
+	TreasuryBps  int `json:"treasury_bps" db:"allocation_treasury_bps"`
+	LiquidityBps int `json:"liquidity_bps" db:"allocation_liquidity_bps"`
+	HoldersBps   int `json:"holders_bps" db:"allocation_holders_bps"`
+}
+
+// TokenAllocationAmounts holds the token amounts of each allocation bucket
+type TokenAllocationAmounts struct {
+	Creator   int64
+	Treasury  int64
+	Liquidity int64
+	Holders   int64
+}
+
+// DefaultTokenAllocation gives the holders bucket enough of the total supply to
+// cover the virtual pool's initial token supply and the rest to the liquidity reserve
+func DefaultTokenAllocation(totalSupply, initialTokenSupply int64) TokenAllocation {
+	holders := AllocationTotalBps
+	if totalSupply > 0 && initialTokenSupply < totalSupply {
+		holders = int((initialTokenSupply*AllocationTotalBps + totalSupply - 1) / totalSupply)
+	}
+	return TokenAllocation{
+		LiquidityBps: AllocationTotalBps - holders,
+		HoldersBps:   holders,
+	}
+}
+
+// TotalBps returns the sum of every bucket
+func (a TokenAllocation) TotalBps() int {
+	return a.CreatorBps + a.TreasuryBps + a.LiquidityBps + a.HoldersBps
+}
+
+// Amounts splits totalSupply across the buckets. Each bucket is rounded down and
+// the rounding remainder goes to the liquidity reserve, so the amounts always sum
+// to totalSupply when the buckets sum to AllocationTotalBps. The supply may be in
+// base units, so shares are computed without multiplying it out in full.
+func (a TokenAllocation) Amounts(totalSupply int64) TokenAllocationAmounts {
+	share := func(bps int) int64 {
+		return BpsShare(totalSupply, bps)
+	}
+	amounts := TokenAllocationAmounts{
+		Creator:  share(a.CreatorBps),
+		Treasury: share(a.TreasuryBps),
+		Holders:  share(a.HoldersBps),
+	}
+	amounts.Liquidity = share(a.TotalBps()) - amounts.Creator - amounts.Treasury - amounts.Holders
+	return amounts
+}
+
+// BpsShare returns bps basis points of amount, rounded down. It doesn't
+// overflow for any amount, including a total supply in base units.
+func BpsShare(amount int64, bps int) int64 {
+	return amount/AllocationTotalBps*int64(bps) + amount%AllocationTotalBps*int64(bps)/AllocationTotalBps
 }
 
 // ChainTemplate represents pre-built blockchain templates
@@ -124,6 +218,22 @@ type ChainAsset struct {
 	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// ChainVestingSchedule locks part of the creator allocation for a beneficiary.
+// Nothing vests before CliffDays; after that the schedule vests linearly until
+// DurationDays after genesis.
+type ChainVestingSchedule struct {
+	ID                 uuid.UUID `json:"id" db:"id"`
+	ChainID            uuid.UUID `json:"chain_id" db:"chain_id"`
+	BeneficiaryAddress string    `json:"beneficiary_address" db:"beneficiary_address"`
+	AllocationBps      int       `json:"allocation_bps" db:"allocation_bps"`
+	CliffDays          int       `json:"cliff_days" db:"cliff_days"`
+	DurationDays       int       `json:"duration_days" db:"duration_days"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+}
+
+// VestingTrancheDays is how often vested tokens are released after the cliff
+const VestingTrancheDays = 30
+
 // Chain status constants
 const (
 	ChainStatusDraft         = "draft"
@@ -133,6 +243,17 @@ const (
 	ChainStatusFailed        = "failed"
 )
 
+// Bonding curve type constants. BondingCurveSlope is the price increase per
+// token of a linear curve and the growth rate of the exponential and capped
+// sigmoid curves; the capped sigmoid also needs CurveMaxPrice and
+// CurveMidpointSupply.
+const (
+	CurveTypeConstantProduct = "constant_product"
+	CurveTypeLinear          = "linear"
+	CurveTypeExponential     = "exponential"
+	CurveTypeCappedSigmoid   = "capped_sigmoid"
+)
+
 // Asset type constants
 const (
 	AssetTypeLogo          = "logo"
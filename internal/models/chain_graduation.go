@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChainGraduation tracks the progress of a chain through the graduation process.
+// CurrentStep is the last step that completed successfully.
+type ChainGraduation struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	ChainID       uuid.UUID  `json:"chain_id" db:"chain_id"`
+	CurrentStep   string     `json:"current_step" db:"current_step"`
+	GenesisFile   *string    `json:"-" db:"genesis_file"`
+	GenesisHash   *string    `json:"genesis_hash" db:"genesis_hash"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	LastAttemptAt *time.Time `json:"last_attempt_at" db:"last_attempt_at"`
+	NextRetryAt   *time.Time `json:"next_retry_at" db:"next_retry_at"`
+	CompletedAt   *time.Time `json:"completed_at" db:"completed_at"`
+
+	// Deployment progress as last reported by the deployer callback
+	DeploymentStatus    *string    `json:"deployment_status" db:"deployment_status"`
+	DeploymentRPCURL    *string    `json:"deployment_rpc_url" db:"deployment_rpc_url"`
+	DeploymentMessage   *string    `json:"deployment_message" db:"deployment_message"`
+	DeploymentUpdatedAt *time.Time `json:"deployment_updated_at" db:"deployment_updated_at"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Graduation step constants, in the order they are executed
+const (
+	GraduationStepThresholdReached    = "threshold_reached"
+	GraduationStepGenesisGenerated    = "genesis_generated"
+	GraduationStepGenesisHashRecorded = "genesis_hash_recorded"
+	GraduationStepDeploymentRequested = "deployment_requested"
+	GraduationStepDeploymentConfirmed = "deployment_confirmed"
+	GraduationStepPoolMigrated        = "pool_migrated"
+)
+
+// Deployment status constants reported by the deployer
+const (
+	DeploymentStatusProvisioning = "provisioning"
+	DeploymentStatusRunning      = "running"
+	DeploymentStatusFailed       = "failed"
+)
+
+// GraduationSteps lists every graduation step in execution order
+var GraduationSteps = []string{
+	GraduationStepThresholdReached,
+	GraduationStepGenesisGenerated,
+	GraduationStepGenesisHashRecorded,
+	GraduationStepDeploymentRequested,
+	GraduationStepDeploymentConfirmed,
+	GraduationStepPoolMigrated,
+}
+
+// NextStep returns the step that follows CurrentStep, or an empty string once
+// every step has completed
+func (g *ChainGraduation) NextStep() string {
+	for i, step := range GraduationSteps {
+		if step == g.CurrentStep && i+1 < len(GraduationSteps) {
+			return GraduationSteps[i+1]
+		}
+	}
+	return ""
+}
+
+// IsComplete reports whether every graduation step has completed
+func (g *ChainGraduation) IsComplete() bool {
+	return g.CompletedAt != nil
+}
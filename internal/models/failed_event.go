@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FailedEvent is an on-chain event that could not be processed, held in the
+// dead-letter queue until a retry succeeds or an admin discards it. RawEvent is
+// the event as received, in JSON: the root chain transaction for a deposit, the
+// sell order for an order.
+//
+// Pending events are retried automatically at NextRetryAt. Once the retries are
+// exhausted the event is failed and is only retried on request.
+type FailedEvent struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	EventType   string     `json:"event_type" db:"event_type"`
+	Reference   string     `json:"reference" db:"reference"`
+	ChainID     *uuid.UUID `json:"chain_id" db:"chain_id"`
+	BlockHeight *uint64    `json:"block_height" db:"block_height"`
+	RawEvent    string     `json:"raw_event" db:"raw_event"`
+	Status      string     `json:"status" db:"status"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	LastError   string     `json:"last_error" db:"last_error"`
+	NextRetryAt time.Time  `json:"next_retry_at" db:"next_retry_at"`
+	ResolvedAt  *time.Time `json:"resolved_at" db:"resolved_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Failed event type constants
+const (
+	FailedEventTypeDeposit = "deposit"
+	FailedEventTypeOrder   = "order"
+)
+
+// Failed event status constants
+const (
+	FailedEventStatusPending   = "pending"
+	FailedEventStatusFailed    = "failed"
+	FailedEventStatusResolved  = "resolved"
+	FailedEventStatusDiscarded = "discarded"
+)
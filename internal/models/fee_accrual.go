@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FeeAccrual is one recipient's share of the trading fee paid on a virtual pool
+// trade. Amount is in uCNPY. Protocol accruals have no recipient user; creator
+// and referrer accruals are owed to RecipientUserID. An accrual stays claimable
+// until a payout settles it.
+type FeeAccrual struct {
+	ID                       uuid.UUID  `json:"id" db:"id"`
+	ChainID                  uuid.UUID  `json:"chain_id" db:"chain_id"`
+	VirtualPoolTransactionID uuid.UUID  `json:"virtual_pool_transaction_id" db:"virtual_pool_transaction_id"`
+	RecipientType            string     `json:"recipient_type" db:"recipient_type"`
+	RecipientUserID          *uuid.UUID `json:"recipient_user_id" db:"recipient_user_id"`
+	Amount                   uint64     `json:"amount" db:"amount"`
+	PayoutID                 *uuid.UUID `json:"payout_id" db:"payout_id"`
+	CreatedAt                time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Fee recipient type constants
+const (
+	FeeRecipientProtocol = "protocol"
+	FeeRecipientCreator  = "creator"
+	FeeRecipientReferrer = "referrer"
+)
+
+// FeeBalance totals the fees accrued to one recipient on one chain, in uCNPY.
+// Claimable is the part no payout has settled yet.
+type FeeBalance struct {
+	ChainID         uuid.UUID  `json:"chain_id" db:"chain_id"`
+	ChainName       string     `json:"chain_name" db:"chain_name"`
+	RecipientType   string     `json:"recipient_type" db:"recipient_type"`
+	RecipientUserID *uuid.UUID `json:"recipient_user_id" db:"recipient_user_id"`
+	Accrued         uint64     `json:"accrued" db:"accrued"`
+	Claimable       uint64     `json:"claimable" db:"claimable"`
+	Trades          int        `json:"trades" db:"trades"`
+	LastAccruedAt   time.Time  `json:"last_accrued_at" db:"last_accrued_at"`
+}
@@ -46,12 +46,20 @@ type GraduatedPoolTransaction struct {
 	CreatedAt             time.Time `json:"created_at" db:"created_at"`
 }
 
+// Graduated pool transaction type constants
+const (
+	GraduatedTransactionTypeBuy                 = "buy"
+	GraduatedTransactionTypeSell                = "sell"
+	GraduatedTransactionTypeLiquidityDeposit    = "liquidity_deposit"
+	GraduatedTransactionTypeLiquidityWithdrawal = "liquidity_withdrawal"
+)
+
 // UserGraduatedLPPosition represents user liquidity position in a graduated pools
 type UserGraduatedLPPosition struct {
 	ID                    uuid.UUID  `json:"id" db:"id"`
 	UserID                uuid.UUID  `json:"user_id" db:"user_id"`
 	ChainID               uuid.UUID  `json:"chain_id" db:"chain_id"`
-	VirtualPoolID         uuid.UUID  `json:"virtual_pool_id" db:"virtual_pool_id"`
+	GraduatedPoolID       uuid.UUID  `json:"graduated_pool_id" db:"graduated_pool_id"`
 	TokenBalance          int64      `json:"token_balance" db:"token_balance"`
 	TotalCNPYInvested     float64    `json:"total_cnpy_invested" db:"total_cnpy_invested"`
 	TotalCNPYWithdrawn    float64    `json:"total_cnpy_withdrawn" db:"total_cnpy_withdrawn"`
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payout is CNPY owed to a user, sent on the root chain from the chain's
+// operation key. Amount is in uCNPY.
+//
+// Once signed, the send is stored with its hash and the root chain height it
+// was created at. Retries rebroadcast that same transaction, so a payout is
+// only ever paid once.
+type Payout struct {
+	ID                       uuid.UUID  `json:"id" db:"id"`
+	ChainID                  uuid.UUID  `json:"chain_id" db:"chain_id"`
+	UserID                   uuid.UUID  `json:"user_id" db:"user_id"`
+	VirtualPoolTransactionID *uuid.UUID `json:"virtual_pool_transaction_id" db:"virtual_pool_transaction_id"`
+	PayoutType               string     `json:"payout_type" db:"payout_type"`
+	RecipientAddress         string     `json:"recipient_address" db:"recipient_address"`
+	Amount                   uint64     `json:"amount" db:"amount"`
+	Reference                string     `json:"reference" db:"reference"`
+	Status                   string     `json:"status" db:"status"`
+	TxHash                   *string    `json:"tx_hash" db:"tx_hash"`
+	SignedTx                 *string    `json:"-" db:"signed_tx"`
+	TxCreatedHeight          *uint64    `json:"tx_created_height" db:"tx_created_height"`
+	ConfirmedHeight          *uint64    `json:"confirmed_height" db:"confirmed_height"`
+	Attempts                 int        `json:"attempts" db:"attempts"`
+	LastError                *string    `json:"last_error" db:"last_error"`
+	NextAttemptAt            time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt                time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt                time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Payout type constants
+const (
+	PayoutTypeSellProceeds   = "sell_proceeds"
+	PayoutTypeInactiveRefund = "inactive_refund" // deposit to a chain that is not virtual_active
+	PayoutTypeCapRefund      = "cap_refund"      // part of a deposit beyond the graduation threshold
+	PayoutTypeSlippageRefund = "slippage_refund" // deposit that could not be filled as quoted
+)
+
+// Payout status constants
+const (
+	PayoutStatusPending   = "pending"
+	PayoutStatusSubmitted = "submitted"
+	PayoutStatusConfirmed = "confirmed"
+	PayoutStatusFailed    = "failed"
+)
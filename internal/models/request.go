@@ -11,9 +11,11 @@ type CreateChainRequest struct {
 	// Template and configuration
 	TemplateID         *string `json:"template_id" validate:"omitempty,uuid"`
 	ConsensusMechanism string  `json:"consensus_mechanism" validate:"omitempty,max=50"`
+	RootChainID        *uint64 `json:"root_chain_id" validate:"omitempty,min=1"` // Defaults to the first configured root chain
 
 	// Economic parameters
 	TokenTotalSupply    *int64   `json:"token_total_supply" validate:"omitempty,min=1000000,max=1000000000000"`
+	TokenDecimals       *int     `json:"token_decimals" validate:"omitempty,min=0,max=6"` // Defaults to 6; at most bondingcurve.MaxTokenDecimals
 	BlockTimeSeconds    *int     `json:"block_time_seconds" validate:"omitempty,oneof=5 10 20 30 60 120 300 600 1800"`
 	UpgradeBlockHeight  *int64   `json:"upgrade_block_height" validate:"omitempty,min=1"`
 	BlockRewardAmount   *float64 `json:"block_reward_amount" validate:"omitempty,min=0"`
@@ -24,9 +26,18 @@ type CreateChainRequest struct {
 	InitialCNPYReserve         *float64 `json:"initial_cnpy_reserve" validate:"omitempty,min=1000"`
 	InitialTokenSupply         *int64   `json:"initial_token_supply" validate:"omitempty,min=100000"`
 	BondingCurveSlope          *float64 `json:"bonding_curve_slope" validate:"omitempty,min=0.000000001"`
+	CurveType                  *string  `json:"curve_type" validate:"omitempty,oneof=constant_product linear exponential capped_sigmoid"`
+	CurveMaxPrice              *float64 `json:"curve_max_price" validate:"omitempty,gt=0"`        // Required for capped_sigmoid
+	CurveMidpointSupply        *int64   `json:"curve_midpoint_supply" validate:"omitempty,min=1"` // Used by capped_sigmoid
 	ValidatorMinStake          *float64 `json:"validator_min_stake" validate:"omitempty,min=100"`
 	CreatorInitialPurchaseCNPY *float64 `json:"creator_initial_purchase_cnpy" validate:"omitempty,min=0"`
 
+	// Genesis token allocation (defaults to holders and liquidity reserve only)
+	Allocation *TokenAllocationRequest `json:"allocation" validate:"omitempty"`
+
+	// Vesting schedules locking part of the creator allocation
+	VestingSchedules []VestingScheduleRequest `json:"vesting_schedules" validate:"omitempty,max=10,dive"`
+
 	// GitHub repository
 	GithubURL *string `json:"github_url" validate:"omitempty,url"`
 
@@ -71,6 +82,65 @@ type VirtualPoolsQueryParams struct {
 	Limit int `form:"limit" validate:"omitempty,min=1,max=100"`
 }
 
+// GraduatedPoolsQueryParams represents query parameters for graduated pool listing
+type GraduatedPoolsQueryParams struct {
+	Page  int `form:"page" validate:"omitempty,min=1"`
+	Limit int `form:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// GraduatedTransactionsQueryParams represents query parameters for graduated pool transactions
+type GraduatedTransactionsQueryParams struct {
+	UserID          string `form:"user_id" validate:"omitempty,uuid"`
+	TransactionType string `form:"transaction_type" validate:"omitempty,oneof=buy sell liquidity_deposit liquidity_withdrawal"`
+	Page            int    `form:"page" validate:"omitempty,min=1"`
+	Limit           int    `form:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// GraduationsQueryParams represents query parameters for graduation listing
+type GraduationsQueryParams struct {
+	Page  int `form:"page" validate:"omitempty,min=1"`
+	Limit int `form:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// FailedEventsQueryParams represents query parameters for dead-letter queue listing
+type FailedEventsQueryParams struct {
+	Page      int    `form:"page" validate:"omitempty,min=1"`
+	Limit     int    `form:"limit" validate:"omitempty,min=1,max=100"`
+	Status    string `form:"status" validate:"omitempty,oneof=pending failed resolved discarded"`
+	EventType string `form:"event_type" validate:"omitempty,oneof=deposit order"`
+}
+
+// FeeBalancesQueryParams represents query parameters for fee balance listing
+type FeeBalancesQueryParams struct {
+	Page          int    `form:"page" validate:"omitempty,min=1"`
+	Limit         int    `form:"limit" validate:"omitempty,min=1,max=100"`
+	RecipientType string `form:"recipient_type" validate:"omitempty,oneof=protocol creator referrer"`
+	ChainID       string `form:"chain_id" validate:"omitempty,uuid"`
+}
+
+// DeploymentCallbackRequest represents the deployer's report on a graduating chain's deployment
+// The RPC URL is required once the chain is running
+type DeploymentCallbackRequest struct {
+	Status  string `json:"status" validate:"required,oneof=provisioning running failed"`
+	RPCURL  string `json:"rpc_url" validate:"required_if=Status running,omitempty,url"`
+	Message string `json:"message" validate:"omitempty,max=1000"`
+}
+
+// SellIntentRequest is a holder's signed request to sell tokens back into a
+// chain's virtual pool. PublicKey and Signature are hex encoded; TokenAmountUnits
+// is in token base units and MinCNPYOut in uCNPY.
+// Quote is an optional sell quote from the quote endpoint, whose minimum the
+// sale must also meet.
+type SellIntentRequest struct {
+	TokenAmountUnits uint64 `json:"token_amount_units" validate:"required,min=1"`
+	MinCNPYOut       uint64 `json:"min_cnpy_out"`
+	Nonce            string `json:"nonce" validate:"required,alphanum,max=64"`
+	ExpiresAt        int64  `json:"expires_at" validate:"required"`
+	PublicKey        string `json:"public_key" validate:"required,hexadecimal"`
+	Signature        string `json:"signature" validate:"required,hexadecimal"`
+	Quote            string `json:"quote,omitempty" validate:"omitempty,max=128"`
+}
+
 // EmailAuthRequest represents the request payload for email authentication
 type EmailAuthRequest struct {
 	Email string `json:"email" validate:"required,email"`
@@ -156,6 +226,28 @@ type UpdateChainAssetRequest struct {
 	ModerationNotes  *string `json:"moderation_notes" validate:"omitempty,max=1000"`
 }
 
+// TokenAllocationRequest represents genesis allocation buckets in basis points of total supply
+// The buckets must sum to 10000; used on chain creation and for draft updates
+type TokenAllocationRequest struct {
+	CreatorBps   int `json:"creator_bps" validate:"min=0,max=10000"`
+	TreasuryBps  int `json:"treasury_bps" validate:"min=0,max=10000"`
+	LiquidityBps int `json:"liquidity_bps" validate:"min=0,max=10000"`
+	HoldersBps   int `json:"holders_bps" validate:"min=1,max=10000"`
+}
+
+// VestingScheduleRequest represents a vesting schedule carved out of the creator allocation
+type VestingScheduleRequest struct {
+	BeneficiaryAddress string `json:"beneficiary_address" validate:"required,canopy_address"`
+	AllocationBps      int    `json:"allocation_bps" validate:"min=1,max=10000"`
+	CliffDays          int    `json:"cliff_days" validate:"min=0,max=3650"`
+	DurationDays       int    `json:"duration_days" validate:"min=1,max=3650,gtefield=CliffDays"`
+}
+
+// UpdateVestingSchedulesRequest replaces every vesting schedule of a draft chain
+type UpdateVestingSchedulesRequest struct {
+	Schedules []VestingScheduleRequest `json:"schedules" validate:"max=10,dive"`
+}
+
 // UpdateChainDescriptionRequest represents the request payload for updating a chain's description
 type UpdateChainDescriptionRequest struct {
 	ChainDescription string `json:"chain_description" validate:"required,max=5000"`
@@ -27,9 +27,21 @@ type Pagination struct {
 
 // HealthResponse represents health check response
 type HealthResponse struct {
-	Status    string `json:"status"`
-	Timestamp string `json:"timestamp"`
-	Version   string `json:"version"`
+	Status     string             `json:"status"`
+	Timestamp  string             `json:"timestamp"`
+	Version    string             `json:"version"`
+	RootChains []*RootChainStatus `json:"root_chains,omitempty"`
+}
+
+// RootChainStatus reports the connection state and ingestion progress of one
+// root chain. Lag is the number of root chain blocks seen but not yet processed.
+type RootChainStatus struct {
+	RootChainID     uint64 `json:"root_chain_id"`
+	Connected       bool   `json:"connected"`
+	ProcessedHeight uint64 `json:"processed_height"`
+	ChainHeight     uint64 `json:"chain_height"`
+	Confirmations   uint64 `json:"confirmations"`
+	Lag             uint64 `json:"lag"`
 }
 
 // ValidationErrorDetail represents validation error details
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RootChainCheckpoint is the last root chain height whose transactions have all
+// been processed by the newblock worker
+type RootChainCheckpoint struct {
+	RootChainID uint64    `json:"root_chain_id" db:"root_chain_id"`
+	Height      uint64    `json:"height" db:"height"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PendingDeposit is a root chain send to a chain's virtual pool that has been
+// observed but not yet applied, because its block is not final. Amount is in uCNPY.
+type PendingDeposit struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	RootChainID     uint64    `json:"root_chain_id" db:"root_chain_id"`
+	ChainID         uuid.UUID `json:"chain_id" db:"chain_id"`
+	SenderAddress   string    `json:"sender_address" db:"sender_address"`
+	Amount          uint64    `json:"amount" db:"amount"`
+	TransactionHash string    `json:"transaction_hash" db:"transaction_hash"`
+	BlockHeight     uint64    `json:"block_height" db:"block_height"`
+	FinalHeight     uint64    `json:"final_height" db:"final_height"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
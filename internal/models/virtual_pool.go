@@ -1,16 +1,23 @@
 package models
 
 import (
-	"github.com/google/uuid"
+	"math"
 	"time"
+
+	"github.com/enielson/launchpad/pkg/bondingcurve"
+	"github.com/google/uuid"
 )
 
-// VirtualPool represents virtual liquidity pool state
+// VirtualPool represents virtual liquidity pool state. CNPYReserveMicro and
+// TokenReserveUnits are the exact reserves; CNPYReserve and TokenReserve are
+// derived from them for display, TokenReserve rounded down to whole tokens.
 type VirtualPool struct {
 	ID                    uuid.UUID `json:"id" db:"id"`
 	ChainID               uuid.UUID `json:"chain_id" db:"chain_id"`
 	CNPYReserve           float64   `json:"cnpy_reserve" db:"cnpy_reserve"`
 	TokenReserve          int64     `json:"token_reserve" db:"token_reserve"`
+	CNPYReserveMicro      uint64    `json:"cnpy_reserve_ucnpy" db:"cnpy_reserve_ucnpy"`   // Exact CNPYReserve
+	TokenReserveUnits     uint64    `json:"token_reserve_units" db:"token_reserve_units"` // Exact TokenReserve in base units
 	CurrentPriceCNPY      float64   `json:"current_price_cnpy" db:"current_price_cnpy"`
 	MarketCapUSD          float64   `json:"market_cap_usd" db:"market_cap_usd"`
 	TotalVolumeCNPY       float64   `json:"total_volume_cnpy" db:"total_volume_cnpy"`
@@ -23,49 +30,89 @@ type VirtualPool struct {
 	Low24hCNPY            float64   `json:"low_24h_cnpy" db:"low_24h_cnpy"`
 	CreatedAt             time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at" db:"updated_at"`
+
+	// Bonding curve and token settings, the creator and status of the pool's
+	// chain and whether its graduation has started, loaded with the pool where
+	// trades are priced. Pools read elsewhere leave them empty.
+	TokenDecimals       int       `json:"-" db:"token_decimals"`
+	CurveType           string    `json:"-" db:"curve_type"`
+	BondingCurveSlope   float64   `json:"-" db:"bonding_curve_slope"`
+	CurveMaxPrice       *float64  `json:"-" db:"curve_max_price"`
+	CurveMidpointSupply *int64    `json:"-" db:"curve_midpoint_supply"`
+	CreatorID           uuid.UUID `json:"-" db:"created_by"`
+	ChainStatus         string    `json:"-" db:"chain_status"`
+	GraduationStarted   bool      `json:"-" db:"graduation_started"`
 }
 
-// VirtualPoolTransaction represents individual trading transactions
+// AcceptsTrades reports whether the pool can still be traded: it is active,
+// its chain is virtual_active and no graduation has started for the chain
+func (p *VirtualPool) AcceptsTrades() bool {
+	return p.IsActive && p.ChainStatus == ChainStatusVirtualActive && !p.GraduationStarted
+}
+
+// ReachesThreshold reports whether the pool's exact CNPY reserve is at least
+// threshold CNPY
+func (p *VirtualPool) ReachesThreshold(threshold float64) bool {
+	return p.CNPYReserveMicro >= uint64(math.Round(threshold*bondingcurve.MicroCNPYPerCNPY))
+}
+
+// VirtualPoolTransaction represents individual trading transactions. The
+// *Micro and *Units amounts are exact; the others are derived from them for display.
 type VirtualPoolTransaction struct {
-	ID                    uuid.UUID `json:"id" db:"id"`
-	VirtualPoolID         uuid.UUID `json:"virtual_pool_id" db:"virtual_pool_id"`
-	ChainID               uuid.UUID `json:"chain_id" db:"chain_id"`
-	UserID                uuid.UUID `json:"user_id" db:"user_id"`
-	TransactionType       string    `json:"transaction_type" db:"transaction_type"`
-	CNPYAmount            float64   `json:"cnpy_amount" db:"cnpy_amount"`
-	TokenAmount           int64     `json:"token_amount" db:"token_amount"`
-	PricePerTokenCNPY     float64   `json:"price_per_token_cnpy" db:"price_per_token_cnpy"`
-	TradingFeeCNPY        float64   `json:"trading_fee_cnpy" db:"trading_fee_cnpy"`
-	SlippagePercent       float64   `json:"slippage_percent" db:"slippage_percent"`
-	TransactionHash       *string   `json:"transaction_hash" db:"transaction_hash"`
-	BlockHeight           *int64    `json:"block_height" db:"block_height"`
-	GasUsed               *int      `json:"gas_used" db:"gas_used"` // TODO 'gas' is wrong - this isn't ethereum
-	PoolCNPYReserveAfter  float64   `json:"pool_cnpy_reserve_after" db:"pool_cnpy_reserve_after"`
-	PoolTokenReserveAfter int64     `json:"pool_token_reserve_after" db:"pool_token_reserve_after"`
-	MarketCapAfterUSD     float64   `json:"market_cap_after_usd" db:"market_cap_after_usd"`
-	CreatedAt             time.Time `json:"created_at" db:"created_at"`
+	ID                         uuid.UUID `json:"id" db:"id"`
+	VirtualPoolID              uuid.UUID `json:"virtual_pool_id" db:"virtual_pool_id"`
+	ChainID                    uuid.UUID `json:"chain_id" db:"chain_id"`
+	UserID                     uuid.UUID `json:"user_id" db:"user_id"`
+	TransactionType            string    `json:"transaction_type" db:"transaction_type"`
+	CNPYAmount                 float64   `json:"cnpy_amount" db:"cnpy_amount"`
+	TokenAmount                int64     `json:"token_amount" db:"token_amount"`
+	CNPYAmountMicro            uint64    `json:"cnpy_amount_ucnpy" db:"cnpy_amount_ucnpy"`   // Exact CNPYAmount
+	TokenAmountUnits           uint64    `json:"token_amount_units" db:"token_amount_units"` // Exact TokenAmount in base units
+	PricePerTokenCNPY          float64   `json:"price_per_token_cnpy" db:"price_per_token_cnpy"`
+	TradingFeeCNPY             float64   `json:"trading_fee_cnpy" db:"trading_fee_cnpy"`
+	SlippagePercent            float64   `json:"slippage_percent" db:"slippage_percent"`
+	TransactionHash            *string   `json:"transaction_hash" db:"transaction_hash"`
+	BlockHeight                *int64    `json:"block_height" db:"block_height"`
+	GasUsed                    *int      `json:"gas_used" db:"gas_used"` // TODO 'gas' is wrong - this isn't ethereum
+	PoolCNPYReserveAfter       float64   `json:"pool_cnpy_reserve_after" db:"pool_cnpy_reserve_after"`
+	PoolTokenReserveAfter      int64     `json:"pool_token_reserve_after" db:"pool_token_reserve_after"`
+	PoolCNPYReserveAfterMicro  uint64    `json:"pool_cnpy_reserve_after_ucnpy" db:"pool_cnpy_reserve_after_ucnpy"`
+	PoolTokenReserveAfterUnits uint64    `json:"pool_token_reserve_after_units" db:"pool_token_reserve_after_units"`
+	MarketCapAfterUSD          float64   `json:"market_cap_after_usd" db:"market_cap_after_usd"`
+	CreatedAt                  time.Time `json:"created_at" db:"created_at"`
 }
 
 // TODO THERE'S NO SUCH THING AS A VIRTUAL LIQUIDITY PROVIDER
 // - Remove Pnl stuff and other unnecessary data...
-// UserVirtualLPPosition represents user liquidity positions in virtual pools
+// Virtual pool transaction type constants
+const (
+	VirtualTransactionTypeBuy  = "buy"
+	VirtualTransactionTypeSell = "sell"
+)
+
+// UserVirtualLPPosition represents user liquidity positions in virtual pools.
+// TokenBalanceUnits and the *Micro totals are exact; TokenBalance and the CNPY
+// totals are derived from them for display, TokenBalance rounded down.
 type UserVirtualLPPosition struct {
-	ID                    uuid.UUID  `json:"id" db:"id"`
-	UserID                uuid.UUID  `json:"user_id" db:"user_id"`
-	ChainID               uuid.UUID  `json:"chain_id" db:"chain_id"`
-	VirtualPoolID         uuid.UUID  `json:"virtual_pool_id" db:"virtual_pool_id"`
-	TokenBalance          int64      `json:"token_balance" db:"token_balance"`
-	TotalCNPYInvested     float64    `json:"total_cnpy_invested" db:"total_cnpy_invested"`
-	TotalCNPYWithdrawn    float64    `json:"total_cnpy_withdrawn" db:"total_cnpy_withdrawn"`
-	AverageEntryPriceCNPY float64    `json:"average_entry_price_cnpy" db:"average_entry_price_cnpy"`
-	UnrealizedPnlCNPY     float64    `json:"unrealized_pnl_cnpy" db:"unrealized_pnl_cnpy"`
-	RealizedPnlCNPY       float64    `json:"realized_pnl_cnpy" db:"realized_pnl_cnpy"`
-	TotalReturnPercent    float64    `json:"total_return_percent" db:"total_return_percent"`
-	IsActive              bool       `json:"is_active" db:"is_active"`
-	FirstPurchaseAt       *time.Time `json:"first_purchase_at" db:"first_purchase_at"`
-	LastActivityAt        *time.Time `json:"last_activity_at" db:"last_activity_at"`
-	CreatedAt             time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt             time.Time  `json:"updated_at" db:"updated_at"`
+	ID                      uuid.UUID  `json:"id" db:"id"`
+	UserID                  uuid.UUID  `json:"user_id" db:"user_id"`
+	ChainID                 uuid.UUID  `json:"chain_id" db:"chain_id"`
+	VirtualPoolID           uuid.UUID  `json:"virtual_pool_id" db:"virtual_pool_id"`
+	TokenBalance            int64      `json:"token_balance" db:"token_balance"`
+	TotalCNPYInvested       float64    `json:"total_cnpy_invested" db:"total_cnpy_invested"`
+	TotalCNPYWithdrawn      float64    `json:"total_cnpy_withdrawn" db:"total_cnpy_withdrawn"`
+	TokenBalanceUnits       uint64     `json:"token_balance_units" db:"token_balance_units"`               // Exact TokenBalance in base units
+	TotalCNPYInvestedMicro  uint64     `json:"total_cnpy_invested_ucnpy" db:"total_cnpy_invested_ucnpy"`   // Exact TotalCNPYInvested
+	TotalCNPYWithdrawnMicro uint64     `json:"total_cnpy_withdrawn_ucnpy" db:"total_cnpy_withdrawn_ucnpy"` // Exact TotalCNPYWithdrawn
+	AverageEntryPriceCNPY   float64    `json:"average_entry_price_cnpy" db:"average_entry_price_cnpy"`
+	UnrealizedPnlCNPY       float64    `json:"unrealized_pnl_cnpy" db:"unrealized_pnl_cnpy"`
+	RealizedPnlCNPY         float64    `json:"realized_pnl_cnpy" db:"realized_pnl_cnpy"`
+	TotalReturnPercent      float64    `json:"total_return_percent" db:"total_return_percent"`
+	IsActive                bool       `json:"is_active" db:"is_active"`
+	FirstPurchaseAt         *time.Time `json:"first_purchase_at" db:"first_purchase_at"`
+	LastActivityAt          *time.Time `json:"last_activity_at" db:"last_activity_at"`
+	CreatedAt               time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 // PriceHistoryCandle represents OHLC data for a time interval
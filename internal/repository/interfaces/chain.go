@@ -14,8 +14,10 @@ type ChainRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID, includeRelations []string) (*models.Chain, error)
 	GetByName(ctx context.Context, name string) (*models.Chain, error)
 	GetByAddress(ctx context.Context, address string) (*models.Chain, error)
+	GetByAddresses(ctx context.Context, addresses []string) (map[string]*models.Chain, error) // Keyed by address; unknown addresses are left out
 	Update(ctx context.Context, chain *models.Chain) (*models.Chain, error)
 	UpdateDescription(ctx context.Context, id uuid.UUID, description string) error
+	UpdateAllocation(ctx context.Context, id uuid.UUID, allocation models.TokenAllocation) error
 	Delete(ctx context.Context, id uuid.UUID) error
 
 	// Chain listing and filtering
@@ -44,6 +46,10 @@ type ChainRepository interface {
 	UpdateAsset(ctx context.Context, asset *models.ChainAsset) error
 	DeleteAssetsByChainID(ctx context.Context, chainID uuid.UUID) error
 
+	// Vesting schedule operations
+	GetVestingSchedulesByChainID(ctx context.Context, chainID uuid.UUID) ([]models.ChainVestingSchedule, error)
+	ReplaceVestingSchedules(ctx context.Context, chainID uuid.UUID, schedules []models.ChainVestingSchedule) ([]models.ChainVestingSchedule, error)
+
 	// Chain key operations
 	CreateChainKey(ctx context.Context, key *models.ChainKey) (*models.ChainKey, error)
 	GetChainKeyByChainID(ctx context.Context, chainID uuid.UUID, purpose string) (*models.ChainKey, error)
@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/google/uuid"
+)
+
+// ChainGraduationRepository defines the interface for graduation state persistence
+type ChainGraduationRepository interface {
+	// Create starts tracking a graduation and stops the chain's virtual pool
+	// trading. Fails if the chain already has one.
+	Create(ctx context.Context, graduation *models.ChainGraduation) (*models.ChainGraduation, error)
+
+	// GetByChainID retrieves the graduation for a chain, returning nil if graduation has not started
+	GetByChainID(ctx context.Context, chainID uuid.UUID) (*models.ChainGraduation, error)
+
+	// Update persists the step, error and retry state of a graduation
+	Update(ctx context.Context, graduation *models.ChainGraduation) (*models.ChainGraduation, error)
+
+	// RecordDeployment stores the deployer's latest report for a chain and makes a
+	// graduation waiting on the deployment due for an immediate retry
+	RecordDeployment(ctx context.Context, chainID uuid.UUID, status, rpcURL, message string) (*models.ChainGraduation, error)
+
+	// List retrieves all graduations, most recently updated first
+	List(ctx context.Context, pagination Pagination) ([]models.ChainGraduation, int, error)
+
+	// ListIncomplete retrieves unfinished graduations whose next retry is due
+	ListIncomplete(ctx context.Context, limit int) ([]models.ChainGraduation, error)
+}
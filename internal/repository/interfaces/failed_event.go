@@ -0,0 +1,35 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/google/uuid"
+)
+
+// FailedEventRepository defines the interface for the dead-letter queue of
+// on-chain events that could not be processed
+type FailedEventRepository interface {
+	// Record queues a failed event. When the event is already queued its error
+	// and retry time are replaced and its attempts incremented, unless it has
+	// been resolved or discarded.
+	Record(ctx context.Context, event *models.FailedEvent) error
+
+	// GetByID retrieves a failed event, returning nil if it does not exist
+	GetByID(ctx context.Context, id uuid.UUID) (*models.FailedEvent, error)
+
+	// List retrieves failed events, most recently updated first
+	List(ctx context.Context, filters FailedEventFilters, pagination Pagination) ([]models.FailedEvent, int, error)
+
+	// ListDue retrieves pending events whose next retry is due, oldest first
+	ListDue(ctx context.Context, limit int) ([]models.FailedEvent, error)
+
+	// Update persists the status and retry state of an event. It only applies
+	// when the event is unchanged since it was read, reporting whether it did.
+	Update(ctx context.Context, event *models.FailedEvent) (bool, error)
+}
+
+type FailedEventFilters struct {
+	Status    string
+	EventType string
+}
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/google/uuid"
+)
+
+// FeeAccrualRepository defines the interface for reading the trading fee
+// ledger. Accruals are written by the trade engine with the trades they split.
+type FeeAccrualRepository interface {
+	// ListBalances totals accruals per chain and recipient, largest claimable
+	// balance first
+	ListBalances(ctx context.Context, filters FeeBalanceFilters, pagination Pagination) ([]models.FeeBalance, int, error)
+}
+
+type FeeBalanceFilters struct {
+	RecipientType   string
+	RecipientUserID *uuid.UUID
+	ChainID         *uuid.UUID
+}
@@ -25,6 +25,15 @@ type GraduatedPoolRepository interface {
 	UpsertUserPosition(ctx context.Context, position *models.UserGraduatedLPPosition) error
 	GetPositionsByChainID(ctx context.Context, chainID uuid.UUID, pagination Pagination) ([]models.UserGraduatedLPPosition, int, error)
 	GetPositionsWithUsersByChainID(ctx context.Context, chainID uuid.UUID) ([]UserPositionWithAddress, error)
+
+	// Graduation operations
+
+	// MigrateFromVirtualPool creates the chain's graduated pool from the final
+	// virtual pool reserves, copies every virtual position (balance and cost
+	// basis) into a graduated position and deactivates the virtual pool, all in
+	// one database transaction. Running it again for a migrated chain returns the
+	// existing graduated pool without changing anything.
+	MigrateFromVirtualPool(ctx context.Context, chainID uuid.UUID) (*models.GraduatedPool, error)
 }
 
 /*
@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/enielson/launchpad/internal/models"
+)
+
+// PayoutRepository defines the interface for the outbound payout queue. Payouts
+// are queued by the trade engine; this repository drives their delivery.
+type PayoutRepository interface {
+	// ListDue retrieves pending and submitted payouts of chains on a root chain
+	// whose next attempt is due, oldest first
+	ListDue(ctx context.Context, rootChainID uint64, limit int) ([]models.Payout, error)
+
+	// Update persists a payout's status, transaction and retry state. It only
+	// applies while the row is unchanged since the payout was read, i.e. still in
+	// fromStatus with the same updated_at, and reports whether it was applied.
+	Update(ctx context.Context, payout *models.Payout, fromStatus string) (bool, error)
+}
@@ -0,0 +1,24 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/google/uuid"
+)
+
+// PendingDepositRepository defines the interface for deposits awaiting root chain finality
+type PendingDepositRepository interface {
+	// Create records a pending deposit. Recording the same transaction hash and height again is a no-op.
+	Create(ctx context.Context, deposit *models.PendingDeposit) error
+
+	// ListByChainID lists a chain's deposits whose height has not been processed yet, newest first
+	ListByChainID(ctx context.Context, chainID uuid.UUID, filters PendingDepositFilters) ([]models.PendingDeposit, error)
+
+	// DeleteThroughHeight removes a root chain's pending deposits at or below height
+	DeleteThroughHeight(ctx context.Context, rootChainID uint64, height uint64) error
+}
+
+type PendingDepositFilters struct {
+	SenderAddress string
+}
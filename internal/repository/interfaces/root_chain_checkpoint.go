@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/enielson/launchpad/internal/models"
+)
+
+// RootChainCheckpointRepository defines the interface for root chain ingestion checkpoints
+type RootChainCheckpointRepository interface {
+	// Get retrieves the checkpoint for a root chain, returning nil if nothing has been processed yet
+	Get(ctx context.Context, rootChainID uint64) (*models.RootChainCheckpoint, error)
+
+	// Save records height as the last processed height. The checkpoint never moves backwards.
+	Save(ctx context.Context, rootChainID uint64, height uint64) error
+}
@@ -13,6 +13,7 @@ type UserRepository interface {
 	Create(ctx context.Context, user *models.User) (*models.User, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
 	GetByWalletAddress(ctx context.Context, walletAddress string) (*models.User, error)
+	GetOrCreateByWalletAddresses(ctx context.Context, walletAddresses []string) ([]models.User, error) // Creates users for addresses not seen before
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
 	Update(ctx context.Context, user *models.User) (*models.User, error)
@@ -51,6 +52,6 @@ type UserFilters struct {
 
 // UserPositionWithAddress contains position data with user's wallet address
 type UserPositionWithAddress struct {
-	WalletAddress string
-	TokenBalance  int64
+	WalletAddress     string `db:"wallet_address"`
+	TokenBalanceUnits uint64 `db:"token_balance_units"` // Token balance in base units
 }
@@ -37,6 +37,8 @@ type VirtualPoolRepository interface {
 type PoolStateUpdate struct {
 	CNPYReserve        *big.Float
 	TokenReserve       *big.Float
+	CNPYReserveMicro   *uint64 // Virtual pools only
+	TokenReserveUnits  *uint64 // Virtual pools only
 	CurrentPriceCNPY   *big.Float
 	MarketCapUSD       *big.Float
 	TotalVolumeCNPY    *big.Float
@@ -29,16 +29,27 @@ func NewChainRepository(db *sqlx.DB, userRepo interfaces.UserRepository, templat
 	}
 }
 
-// Create creates a new chain
+// Create creates a new chain. A chain without a root chain is created on the
+// default root chain, and one without a curve type on a constant-product curve.
 func (r *chainRepository) Create(ctx context.Context, chain *models.Chain) (*models.Chain, error) {
+	if chain.RootChainID == 0 {
+		chain.RootChainID = models.DefaultRootChainID
+	}
+	if chain.CurveType == "" {
+		chain.CurveType = models.CurveTypeConstantProduct
+	}
+
 	query := `
 		INSERT INTO chains (
 			chain_name, token_symbol, chain_description, template_id, consensus_mechanism,
 			token_total_supply, graduation_threshold, creation_fee_cnpy, initial_cnpy_reserve,
 			initial_token_supply, bonding_curve_slope, creator_initial_purchase_cnpy,
-			validator_min_stake, created_by
+			validator_min_stake, allocation_creator_bps, allocation_treasury_bps,
+			allocation_liquidity_bps, allocation_holders_bps, root_chain_id, curve_type,
+			curve_max_price, curve_midpoint_supply, token_decimals, created_by
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+			$20, $21, $22, $23
 		) RETURNING id, status, is_graduated, created_at, updated_at`
 
 	err := r.db.QueryRowxContext(ctx, query,
@@ -55,6 +66,15 @@ func (r *chainRepository) Create(ctx context.Context, chain *models.Chain) (*mod
 		chain.BondingCurveSlope,
 		chain.CreatorInitialPurchaseCNPY,
 		chain.ValidatorMinStake,
+		chain.CreatorBps,
+		chain.TreasuryBps,
+		chain.LiquidityBps,
+		chain.HoldersBps,
+		chain.RootChainID,
+		chain.CurveType,
+		database.NullFloat64(chain.CurveMaxPrice),
+		database.NullInt64(chain.CurveMidpointSupply),
+		chain.TokenDecimals,
 		chain.CreatedBy,
 	).Scan(&chain.ID, &chain.Status, &chain.IsGraduated, &chain.CreatedAt, &chain.UpdatedAt)
 
@@ -96,8 +116,10 @@ func (r *chainRepository) GetByAddress(ctx context.Context, address string) (*mo
 			c.block_reward_amount, c.graduation_threshold, c.creation_fee_cnpy, c.initial_cnpy_reserve,
 			c.initial_token_supply, c.bonding_curve_slope, c.scheduled_launch_time, c.actual_launch_time,
 			c.creator_initial_purchase_cnpy, c.status, c.is_graduated, c.graduation_time,
-			c.chain_id, c.genesis_hash, c.validator_min_stake, c.created_by,
-			c.created_at, c.updated_at
+			c.chain_id, c.genesis_hash, c.validator_min_stake, c.allocation_creator_bps,
+			c.allocation_treasury_bps, c.allocation_liquidity_bps, c.allocation_holders_bps,
+			c.root_chain_id, c.curve_type, c.curve_max_price, c.curve_midpoint_supply, c.token_decimals,
+			c.created_by, c.created_at, c.updated_at
 		FROM chains c
 		INNER JOIN chain_keys ck ON c.id = ck.chain_id
 		WHERE ck.address = $1 AND ck.is_active = true`
@@ -114,6 +136,39 @@ func (r *chainRepository) GetByAddress(ctx context.Context, address string) (*mo
 	return &chain, nil
 }
 
+// GetByAddresses retrieves the chains whose active keys hold any of the given
+// addresses, keyed by address. Addresses without a chain are left out.
+func (r *chainRepository) GetByAddresses(ctx context.Context, addresses []string) (map[string]*models.Chain, error) {
+	query := `
+		SELECT ck.address, c.id, c.chain_name, c.token_name, c.token_symbol, c.chain_description, c.template_id,
+			c.consensus_mechanism, c.token_total_supply, c.block_time_seconds, c.upgrade_block_height,
+			c.block_reward_amount, c.graduation_threshold, c.creation_fee_cnpy, c.initial_cnpy_reserve,
+			c.initial_token_supply, c.bonding_curve_slope, c.scheduled_launch_time, c.actual_launch_time,
+			c.creator_initial_purchase_cnpy, c.status, c.is_graduated, c.graduation_time,
+			c.chain_id, c.genesis_hash, c.validator_min_stake, c.allocation_creator_bps,
+			c.allocation_treasury_bps, c.allocation_liquidity_bps, c.allocation_holders_bps,
+			c.root_chain_id, c.curve_type, c.curve_max_price, c.curve_midpoint_supply, c.token_decimals,
+			c.created_by, c.created_at, c.updated_at
+		FROM chains c
+		INNER JOIN chain_keys ck ON c.id = ck.chain_id
+		WHERE ck.address = ANY($1) AND ck.is_active = true`
+
+	var rows []struct {
+		Address string `db:"address"`
+		models.Chain
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, pq.Array(addresses)); err != nil {
+		return nil, fmt.Errorf("failed to get chains by address: %w", err)
+	}
+
+	chains := make(map[string]*models.Chain, len(rows))
+	for i := range rows {
+		chains[rows[i].Address] = &rows[i].Chain
+	}
+
+	return chains, nil
+}
+
 // Update updates a chain
 func (r *chainRepository) Update(ctx context.Context, chain *models.Chain) (*models.Chain, error) {
 	query := `
@@ -187,6 +242,39 @@ func (r *chainRepository) UpdateDescription(ctx context.Context, id uuid.UUID, d
 	return nil
 }
 
+// UpdateAllocation updates only the chain's genesis token allocation
+func (r *chainRepository) UpdateAllocation(ctx context.Context, id uuid.UUID, allocation models.TokenAllocation) error {
+	query := `
+		UPDATE chains SET
+			allocation_creator_bps = $2,
+			allocation_treasury_bps = $3,
+			allocation_liquidity_bps = $4,
+			allocation_holders_bps = $5,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id,
+		allocation.CreatorBps,
+		allocation.TreasuryBps,
+		allocation.LiquidityBps,
+		allocation.HoldersBps,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update chain allocation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("chain not found")
+	}
+
+	return nil
+}
+
 // Delete deletes a chain
 func (r *chainRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM chains WHERE id = $1`
@@ -227,8 +315,10 @@ func (r *chainRepository) List(ctx context.Context, filters interfaces.ChainFilt
 			c.initial_cnpy_reserve, c.initial_token_supply, c.bonding_curve_slope,
 			c.scheduled_launch_time, c.actual_launch_time, c.creator_initial_purchase_cnpy,
 			c.status, c.is_graduated, c.graduation_time, c.chain_id, c.genesis_hash,
-			c.validator_min_stake, c.created_by, c.created_at, c.updated_at,
-			ct.template_name, ct.template_description, u.wallet_address, u.display_name
+			c.validator_min_stake, c.allocation_creator_bps, c.allocation_treasury_bps,
+			c.allocation_liquidity_bps, c.allocation_holders_bps, c.root_chain_id, c.curve_type,
+			c.curve_max_price, c.curve_midpoint_supply, c.token_decimals, c.created_by,
+			c.created_at, c.updated_at, ct.template_name, ct.template_description, u.wallet_address, u.display_name
 		FROM chains c
 		LEFT JOIN chain_templates ct ON c.template_id = ct.id
 		LEFT JOIN users u ON c.created_by = u.id
@@ -269,7 +359,10 @@ func (r *chainRepository) List(ctx context.Context, filters interfaces.ChainFilt
 			&chain.InitialTokenSupply, &chain.BondingCurveSlope, &scheduledLaunchTime,
 			&actualLaunchTime, &chain.CreatorInitialPurchaseCNPY, &chain.Status,
 			&chain.IsGraduated, &graduationTime, &chainID, &genesisHash,
-			&chain.ValidatorMinStake, &chain.CreatedBy, &chain.CreatedAt, &chain.UpdatedAt,
+			&chain.ValidatorMinStake, &chain.CreatorBps, &chain.TreasuryBps,
+			&chain.LiquidityBps, &chain.HoldersBps, &chain.RootChainID, &chain.CurveType,
+			&chain.CurveMaxPrice, &chain.CurveMidpointSupply, &chain.TokenDecimals, &chain.CreatedBy,
+			&chain.CreatedAt, &chain.UpdatedAt,
 			&templateName, &templateDescription,
 			&walletAddress, &displayName,
 		)
@@ -352,7 +445,9 @@ func (r *chainRepository) getChainByField(ctx context.Context, field, value stri
 			   graduation_threshold, creation_fee_cnpy, initial_cnpy_reserve,
 			   initial_token_supply, bonding_curve_slope, scheduled_launch_time, actual_launch_time,
 			   creator_initial_purchase_cnpy, status, is_graduated, graduation_time, chain_id,
-			   genesis_hash, validator_min_stake, created_by, created_at, updated_at
+			   genesis_hash, validator_min_stake, allocation_creator_bps, allocation_treasury_bps,
+			   allocation_liquidity_bps, allocation_holders_bps, root_chain_id, curve_type,
+			   curve_max_price, curve_midpoint_supply, token_decimals, created_by, created_at, updated_at
 		FROM chains WHERE %s = $1`, field)
 
 	var chain models.Chain
@@ -372,7 +467,10 @@ func (r *chainRepository) getChainByField(ctx context.Context, field, value stri
 		&chain.InitialTokenSupply, &chain.BondingCurveSlope, &scheduledLaunchTime,
 		&actualLaunchTime, &chain.CreatorInitialPurchaseCNPY, &chain.Status,
 		&chain.IsGraduated, &graduationTime, &chainID, &genesisHash,
-		&chain.ValidatorMinStake, &chain.CreatedBy, &chain.CreatedAt, &chain.UpdatedAt,
+		&chain.ValidatorMinStake, &chain.CreatorBps, &chain.TreasuryBps,
+		&chain.LiquidityBps, &chain.HoldersBps, &chain.RootChainID, &chain.CurveType,
+		&chain.CurveMaxPrice, &chain.CurveMidpointSupply, &chain.TokenDecimals, &chain.CreatedBy,
+		&chain.CreatedAt, &chain.UpdatedAt,
 	)
 
 	if err != nil {
@@ -495,6 +593,15 @@ func (r *chainRepository) loadChainRelations(ctx context.Context, chain *models.
 		chain.Assets = assets
 	}
 
+	// Load vesting schedules
+	if includeMap["vesting"] || includeMap["vesting_schedules"] {
+		schedules, err := r.GetVestingSchedulesByChainID(ctx, chain.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load vesting schedules: %w", err)
+		}
+		chain.VestingSchedules = schedules
+	}
+
 	// Note: Virtual pool loading removed - use VirtualPoolRepository directly instead
 	// Virtual pools should be loaded separately through the VirtualPoolRepository
 
@@ -798,6 +905,68 @@ func (r *chainRepository) DeleteAssetsByChainID(ctx context.Context, chainID uui
 	return fmt.Errorf("not implemented")
 }
 
+// Vesting schedule operations
+func (r *chainRepository) GetVestingSchedulesByChainID(ctx context.Context, chainID uuid.UUID) ([]models.ChainVestingSchedule, error) {
+	query := `
+		SELECT id, chain_id, beneficiary_address, allocation_bps, cliff_days, duration_days, created_at
+		FROM chain_vesting_schedules
+		WHERE chain_id = $1
+		ORDER BY created_at ASC, id ASC`
+
+	var schedules []models.ChainVestingSchedule
+	err := r.db.SelectContext(ctx, &schedules, query, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vesting schedules: %w", err)
+	}
+
+	// Return empty slice if no schedules found (not an error)
+	if schedules == nil {
+		schedules = []models.ChainVestingSchedule{}
+	}
+
+	return schedules, nil
+}
+
+// ReplaceVestingSchedules swaps all of a chain's vesting schedules for the given
+// ones in a single transaction and returns the stored schedules
+func (r *chainRepository) ReplaceVestingSchedules(ctx context.Context, chainID uuid.UUID, schedules []models.ChainVestingSchedule) ([]models.ChainVestingSchedule, error) {
+	stored := make([]models.ChainVestingSchedule, 0, len(schedules))
+	err := database.Transaction(r.db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM chain_vesting_schedules WHERE chain_id = $1`, chainID); err != nil {
+			return fmt.Errorf("failed to delete vesting schedules: %w", err)
+		}
+
+		query := `
+			INSERT INTO chain_vesting_schedules (
+				chain_id, beneficiary_address, allocation_bps, cliff_days, duration_days
+			) VALUES (
+				$1, $2, $3, $4, $5
+			) RETURNING id, chain_id, beneficiary_address, allocation_bps, cliff_days, duration_days, created_at`
+
+		for _, schedule := range schedules {
+			var created models.ChainVestingSchedule
+			err := tx.QueryRowxContext(ctx, query,
+				chainID,
+				schedule.BeneficiaryAddress,
+				schedule.AllocationBps,
+				schedule.CliffDays,
+				schedule.DurationDays,
+			).StructScan(&created)
+			if err != nil {
+				return fmt.Errorf("failed to create vesting schedule: %w", err)
+			}
+			stored = append(stored, created)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
 // CreateChainKey creates a new encrypted key for a chain
 func (r *chainRepository) CreateChainKey(ctx context.Context, key *models.ChainKey) (*models.ChainKey, error) {
 	query := `
@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const chainGraduationColumns = `id, chain_id, current_step, genesis_file, genesis_hash, attempts,
+			   last_error, last_attempt_at, next_retry_at, completed_at, deployment_status,
+			   deployment_rpc_url, deployment_message, deployment_updated_at, created_at, updated_at`
+
+type chainGraduationRepository struct {
+	db *sqlx.DB
+}
+
+// NewChainGraduationRepository creates a new PostgreSQL chain graduation repository
+func NewChainGraduationRepository(db *sqlx.DB) interfaces.ChainGraduationRepository {
+	return &chainGraduationRepository{db: db}
+}
+
+// Create starts tracking a graduation for a chain and stops its virtual pool
+// trading in the same transaction, so the holder balances the genesis is built
+// from can no longer change
+func (r *chainGraduationRepository) Create(ctx context.Context, graduation *models.ChainGraduation) (*models.ChainGraduation, error) {
+	err := database.Transaction(r.db, func(tx *sqlx.Tx) error {
+		// Trades lock the pool row, so this waits for any trade in flight
+		_, err := tx.ExecContext(ctx, `
+			UPDATE virtual_pools
+			SET is_active = false, updated_at = CURRENT_TIMESTAMP
+			WHERE chain_id = $1`, graduation.ChainID)
+		if err != nil {
+			return fmt.Errorf("failed to freeze virtual pool: %w", err)
+		}
+
+		query := `
+			INSERT INTO chain_graduations (
+				chain_id, current_step, genesis_file, genesis_hash, attempts,
+				last_error, last_attempt_at, next_retry_at, completed_at
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9
+			) RETURNING id, created_at, updated_at`
+
+		err = tx.QueryRowxContext(ctx, query,
+			graduation.ChainID,
+			graduation.CurrentStep,
+			graduation.GenesisFile,
+			graduation.GenesisHash,
+			graduation.Attempts,
+			graduation.LastError,
+			graduation.LastAttemptAt,
+			graduation.NextRetryAt,
+			graduation.CompletedAt,
+		).Scan(&graduation.ID, &graduation.CreatedAt, &graduation.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create chain graduation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return graduation, nil
+}
+
+// GetByChainID retrieves the graduation for a chain
+func (r *chainGraduationRepository) GetByChainID(ctx context.Context, chainID uuid.UUID) (*models.ChainGraduation, error) {
+	query := `SELECT ` + chainGraduationColumns + ` FROM chain_graduations WHERE chain_id = $1`
+
+	var graduation models.ChainGraduation
+	err := r.db.GetContext(ctx, &graduation, query, chainID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Graduation hasn't started, not an error
+		}
+		return nil, fmt.Errorf("failed to get chain graduation: %w", err)
+	}
+
+	return &graduation, nil
+}
+
+// Update persists the step, error and retry state of a graduation
+func (r *chainGraduationRepository) Update(ctx context.Context, graduation *models.ChainGraduation) (*models.ChainGraduation, error) {
+	query := `
+		UPDATE chain_graduations SET
+			current_step = $2, genesis_file = $3, genesis_hash = $4, attempts = $5,
+			last_error = $6, last_attempt_at = $7, next_retry_at = $8, completed_at = $9,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		graduation.ID,
+		graduation.CurrentStep,
+		graduation.GenesisFile,
+		graduation.GenesisHash,
+		graduation.Attempts,
+		graduation.LastError,
+		graduation.LastAttemptAt,
+		graduation.NextRetryAt,
+		graduation.CompletedAt,
+	).Scan(&graduation.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("chain graduation not found")
+		}
+		return nil, fmt.Errorf("failed to update chain graduation: %w", err)
+	}
+
+	return graduation, nil
+}
+
+// RecordDeployment stores the deployer's latest report for a chain. Deployment
+// columns are written only here so that graduation step updates never overwrite
+// a report that arrived while a step was running.
+func (r *chainGraduationRepository) RecordDeployment(ctx context.Context, chainID uuid.UUID, status, rpcURL, message string) (*models.ChainGraduation, error) {
+	query := `
+		UPDATE chain_graduations SET
+			deployment_status = $2,
+			deployment_rpc_url = COALESCE(NULLIF($3, ''), deployment_rpc_url),
+			deployment_message = NULLIF($4, ''),
+			deployment_updated_at = NOW(),
+			next_retry_at = NULL,
+			updated_at = NOW()
+		WHERE chain_id = $1
+		RETURNING ` + chainGraduationColumns
+
+	var graduation models.ChainGraduation
+	err := r.db.GetContext(ctx, &graduation, query, chainID, status, rpcURL, message)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("chain graduation not found")
+		}
+		return nil, fmt.Errorf("failed to record chain deployment: %w", err)
+	}
+
+	return &graduation, nil
+}
+
+// List retrieves all graduations, most recently updated first
+func (r *chainGraduationRepository) List(ctx context.Context, pagination interfaces.Pagination) ([]models.ChainGraduation, int, error) {
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM chain_graduations`); err != nil {
+		return nil, 0, fmt.Errorf("failed to count chain graduations: %w", err)
+	}
+
+	query := `SELECT ` + chainGraduationColumns + `
+		FROM chain_graduations
+		ORDER BY updated_at DESC
+		LIMIT $1 OFFSET $2`
+
+	graduations := []models.ChainGraduation{}
+	if err := r.db.SelectContext(ctx, &graduations, query, pagination.Limit, pagination.Offset); err != nil {
+		return nil, 0, fmt.Errorf("failed to list chain graduations: %w", err)
+	}
+
+	return graduations, total, nil
+}
+
+// ListIncomplete retrieves unfinished graduations whose next retry is due
+func (r *chainGraduationRepository) ListIncomplete(ctx context.Context, limit int) ([]models.ChainGraduation, error) {
+	query := `SELECT ` + chainGraduationColumns + `
+		FROM chain_graduations
+		WHERE completed_at IS NULL
+		  AND (next_retry_at IS NULL OR next_retry_at <= NOW())
+		ORDER BY next_retry_at ASC NULLS FIRST
+		LIMIT $1`
+
+	graduations := []models.ChainGraduation{}
+	if err := r.db.SelectContext(ctx, &graduations, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to list incomplete chain graduations: %w", err)
+	}
+
+	return graduations, nil
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainGraduationCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewChainGraduationRepository(sqlx.NewDb(db, "sqlmock"))
+	chainID := uuid.New()
+
+	t.Run("freezes the virtual pool with the graduation", func(t *testing.T) {
+		graduationID := uuid.New()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE virtual_pools\\s+SET is_active = false").
+			WithArgs(chainID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO chain_graduations").
+			WithArgs(chainID, models.GraduationStepThresholdReached, nil, nil, 0, nil, sqlmock.AnyArg(), nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
+				AddRow(graduationID, time.Now(), time.Now()))
+		mock.ExpectCommit()
+
+		now := time.Now()
+		graduation, err := repo.Create(context.Background(), &models.ChainGraduation{
+			ChainID:       chainID,
+			CurrentStep:   models.GraduationStepThresholdReached,
+			LastAttemptAt: &now,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, graduationID, graduation.ID)
+	})
+
+	t.Run("pool stays active when the graduation already exists", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE virtual_pools\\s+SET is_active = false").
+			WithArgs(chainID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO chain_graduations").
+			WillReturnError(errors.New("duplicate key value violates unique constraint"))
+		mock.ExpectRollback()
+
+		graduation, err := repo.Create(context.Background(), &models.ChainGraduation{
+			ChainID:     chainID,
+			CurrentStep: models.GraduationStepThresholdReached,
+		})
+		assert.Nil(t, graduation)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to create chain graduation")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const failedEventColumns = `id, event_type, reference, chain_id, block_height, raw_event, status,
+			   attempts, last_error, next_retry_at, resolved_at, created_at, updated_at`
+
+// FailedEventTxRepository extends FailedEventRepository with a record that runs
+// inside a database transaction, so an event that fails while its block is
+// being applied is queued only if the rest of the block commits.
+type FailedEventTxRepository interface {
+	interfaces.FailedEventRepository
+
+	// RecordInTx queues a failed event within a transaction
+	RecordInTx(ctx context.Context, tx *sqlx.Tx, event *models.FailedEvent) error
+}
+
+type failedEventRepository struct {
+	db *sqlx.DB
+}
+
+// NewFailedEventRepository creates a new PostgreSQL failed event repository
+func NewFailedEventRepository(db *sqlx.DB) interfaces.FailedEventRepository {
+	return &failedEventRepository{db: db}
+}
+
+// NewFailedEventTxRepository creates a new transaction-aware failed event repository
+func NewFailedEventTxRepository(db *sqlx.DB) FailedEventTxRepository {
+	return &failedEventRepository{db: db}
+}
+
+// Record queues a failed event. An event that is queued again, for example
+// when a block is reprocessed after a restart, counts as one more attempt.
+func (r *failedEventRepository) Record(ctx context.Context, event *models.FailedEvent) error {
+	return recordFailedEvent(ctx, r.db, event)
+}
+
+// RecordInTx queues a failed event within a transaction
+func (r *failedEventRepository) RecordInTx(ctx context.Context, tx *sqlx.Tx, event *models.FailedEvent) error {
+	return recordFailedEvent(ctx, tx, event)
+}
+
+func recordFailedEvent(ctx context.Context, db sqlx.QueryerContext, event *models.FailedEvent) error {
+	query := `
+		INSERT INTO failed_events (
+			event_type, reference, chain_id, block_height, raw_event, last_error, next_retry_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (event_type, reference) DO UPDATE SET
+			last_error = EXCLUDED.last_error,
+			next_retry_at = EXCLUDED.next_retry_at,
+			attempts = failed_events.attempts + 1,
+			updated_at = NOW()
+		WHERE failed_events.status IN ('pending', 'failed')
+		RETURNING ` + failedEventColumns
+
+	err := sqlx.GetContext(ctx, db, event, query,
+		event.EventType,
+		event.Reference,
+		event.ChainID,
+		event.BlockHeight,
+		event.RawEvent,
+		event.LastError,
+		event.NextRetryAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil // Already resolved or discarded
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record failed event: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a failed event
+func (r *failedEventRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.FailedEvent, error) {
+	query := `SELECT ` + failedEventColumns + ` FROM failed_events WHERE id = $1`
+
+	var event models.FailedEvent
+	err := r.db.GetContext(ctx, &event, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get failed event: %w", err)
+	}
+
+	return &event, nil
+}
+
+// List retrieves failed events, most recently updated first
+func (r *failedEventRepository) List(ctx context.Context, filters interfaces.FailedEventFilters, pagination interfaces.Pagination) ([]models.FailedEvent, int, error) {
+	where := ` WHERE ($1 = '' OR status = $1) AND ($2 = '' OR event_type = $2)`
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM failed_events`+where, filters.Status, filters.EventType); err != nil {
+		return nil, 0, fmt.Errorf("failed to count failed events: %w", err)
+	}
+
+	query := `SELECT ` + failedEventColumns + ` FROM failed_events` + where + `
+		ORDER BY updated_at DESC
+		LIMIT $3 OFFSET $4`
+
+	events := []models.FailedEvent{}
+	if err := r.db.SelectContext(ctx, &events, query, filters.Status, filters.EventType, pagination.Limit, pagination.Offset); err != nil {
+		return nil, 0, fmt.Errorf("failed to list failed events: %w", err)
+	}
+
+	return events, total, nil
+}
+
+// ListDue retrieves pending events whose next retry is due
+func (r *failedEventRepository) ListDue(ctx context.Context, limit int) ([]models.FailedEvent, error) {
+	query := `SELECT ` + failedEventColumns + ` FROM failed_events
+		WHERE status = 'pending' AND next_retry_at <= NOW()
+		ORDER BY next_retry_at ASC
+		LIMIT $1`
+
+	events := []models.FailedEvent{}
+	if err := r.db.SelectContext(ctx, &events, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to list due failed events: %w", err)
+	}
+
+	return events, nil
+}
+
+// Update persists the status and retry state of an event if no one else has
+// changed it since it was read, so a retry cannot reopen a discarded event
+func (r *failedEventRepository) Update(ctx context.Context, event *models.FailedEvent) (bool, error) {
+	query := `
+		UPDATE failed_events SET
+			status = $2, attempts = $3, last_error = $4, next_retry_at = $5, resolved_at = $6,
+			updated_at = NOW()
+		WHERE id = $1 AND updated_at = $7
+		RETURNING updated_at`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		event.ID,
+		event.Status,
+		event.Attempts,
+		event.LastError,
+		event.NextRetryAt,
+		event.ResolvedAt,
+		event.UpdatedAt,
+	).Scan(&event.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update failed event: %w", err)
+	}
+
+	return true, nil
+}
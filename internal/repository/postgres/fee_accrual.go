@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/jmoiron/sqlx"
+)
+
+type feeAccrualRepository struct {
+	db *sqlx.DB
+}
+
+// NewFeeAccrualRepository creates a new PostgreSQL fee accrual repository
+func NewFeeAccrualRepository(db *sqlx.DB) interfaces.FeeAccrualRepository {
+	return &feeAccrualRepository{db: db}
+}
+
+// ListBalances totals the fee ledger per chain and recipient. Accruals not yet
+// settled by a payout are claimable.
+func (r *feeAccrualRepository) ListBalances(ctx context.Context, filters interfaces.FeeBalanceFilters, pagination interfaces.Pagination) ([]models.FeeBalance, int, error) {
+	where := ` WHERE ($1 = '' OR fa.recipient_type = $1)
+		  AND ($2::uuid IS NULL OR fa.recipient_user_id = $2)
+		  AND ($3::uuid IS NULL OR fa.chain_id = $3)`
+	groupBy := ` GROUP BY fa.chain_id, fa.recipient_type, fa.recipient_user_id`
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM (SELECT 1 FROM fee_accruals fa` + where + groupBy + `) balances`
+	if err := r.db.GetContext(ctx, &total, countQuery, filters.RecipientType, filters.RecipientUserID, filters.ChainID); err != nil {
+		return nil, 0, fmt.Errorf("failed to count fee balances: %w", err)
+	}
+
+	query := `
+		SELECT fa.chain_id, MIN(c.chain_name) AS chain_name, fa.recipient_type, fa.recipient_user_id,
+			   SUM(fa.amount)::bigint AS accrued,
+			   COALESCE(SUM(fa.amount) FILTER (WHERE fa.payout_id IS NULL), 0)::bigint AS claimable,
+			   COUNT(DISTINCT fa.virtual_pool_transaction_id) AS trades,
+			   MAX(fa.created_at) AS last_accrued_at
+		FROM fee_accruals fa
+		JOIN chains c ON c.id = fa.chain_id` + where + groupBy + `
+		ORDER BY claimable DESC, fa.chain_id, fa.recipient_type
+		LIMIT $4 OFFSET $5`
+
+	balances := []models.FeeBalance{}
+	err := r.db.SelectContext(ctx, &balances, query,
+		filters.RecipientType, filters.RecipientUserID, filters.ChainID, pagination.Limit, pagination.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list fee balances: %w", err)
+	}
+
+	return balances, total, nil
+}
@@ -0,0 +1,453 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const graduatedPoolColumns = `id, chain_id, cnpy_reserve, token_reserve, current_price_cnpy, market_cap_usd,
+			   total_volume_cnpy, total_transactions, unique_traders, is_active,
+			   price_24h_change_percent, volume_24h_cnpy, high_24h_cnpy, low_24h_cnpy,
+			   created_at, updated_at`
+
+const graduatedPoolTransactionColumns = `id, graduated_pool_id, chain_id, user_id, transaction_type, cnpy_amount,
+			   token_amount, price_per_token_cnpy, trading_fee_cnpy, slippage_percent,
+			   pool_cnpy_reserve_after, pool_token_reserve_after, market_cap_after_usd,
+			   transaction_hash, block_height, gas_used, created_at`
+
+const graduatedPositionColumns = `id, user_id, chain_id, graduated_pool_id, token_balance, total_cnpy_invested,
+			   total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
+			   realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
+			   last_activity_at, created_at, updated_at`
+
+type graduatedPoolRepository struct {
+	db *sqlx.DB
+}
+
+// NewGraduatedPoolRepository creates a new PostgreSQL graduated pool repository
+func NewGraduatedPoolRepository(db *sqlx.DB) interfaces.GraduatedPoolRepository {
+	return &graduatedPoolRepository{db: db}
+}
+
+// Create creates a new graduated pool
+func (r *graduatedPoolRepository) Create(ctx context.Context, pool *models.GraduatedPool) (*models.GraduatedPool, error) {
+	query := `
+		INSERT INTO graduated_pools (
+			chain_id, cnpy_reserve, token_reserve, current_price_cnpy, market_cap_usd,
+			total_volume_cnpy, total_transactions, unique_traders, is_active
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING ` + graduatedPoolColumns
+
+	var created models.GraduatedPool
+	err := r.db.QueryRowxContext(ctx, query,
+		pool.ChainID,
+		pool.CNPYReserve,
+		pool.TokenReserve,
+		pool.CurrentPriceCNPY,
+		pool.MarketCapUSD,
+		pool.TotalVolumeCNPY,
+		pool.TotalTransactions,
+		pool.UniqueTraders,
+		pool.IsActive,
+	).StructScan(&created)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create graduated pool: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetPoolByChainID retrieves a graduated pool by chain ID
+func (r *graduatedPoolRepository) GetPoolByChainID(ctx context.Context, chainID uuid.UUID) (*models.GraduatedPool, error) {
+	query := `SELECT ` + graduatedPoolColumns + ` FROM graduated_pools WHERE chain_id = $1`
+
+	var pool models.GraduatedPool
+	err := r.db.GetContext(ctx, &pool, query, chainID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("graduated pool not found for chain_id: %s", chainID)
+		}
+		return nil, fmt.Errorf("failed to get graduated pool: %w", err)
+	}
+
+	return &pool, nil
+}
+
+// GetAllPools retrieves all graduated pools with pagination
+func (r *graduatedPoolRepository) GetAllPools(ctx context.Context, pagination interfaces.Pagination) ([]models.GraduatedPool, int, error) {
+	var total int
+	err := r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM graduated_pools")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count graduated pools: %w", err)
+	}
+
+	dataQuery := `SELECT ` + graduatedPoolColumns + `
+		FROM graduated_pools
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	pools := []models.GraduatedPool{}
+	err = r.db.SelectContext(ctx, &pools, dataQuery, pagination.Limit, pagination.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query graduated pools: %w", err)
+	}
+
+	return pools, total, nil
+}
+
+// UpdatePoolState updates the graduated pool state with the non-nil fields of update
+func (r *graduatedPoolRepository) UpdatePoolState(ctx context.Context, chainID uuid.UUID, update *interfaces.PoolStateUpdate) error {
+	query := "UPDATE graduated_pools SET updated_at = CURRENT_TIMESTAMP"
+	args := []interface{}{chainID}
+
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		query += fmt.Sprintf(", %s = $%d", column, len(args))
+	}
+	setFloat := func(column string, value *big.Float) {
+		if value != nil {
+			set(column, bigFloatToFloat64(value))
+		}
+	}
+
+	setFloat("cnpy_reserve", update.CNPYReserve)
+	if update.TokenReserve != nil {
+		set("token_reserve", int64(bigFloatToFloat64(update.TokenReserve)))
+	}
+	setFloat("current_price_cnpy", update.CurrentPriceCNPY)
+	setFloat("market_cap_usd", update.MarketCapUSD)
+	setFloat("total_volume_cnpy", update.TotalVolumeCNPY)
+	if update.TotalTransactions != nil {
+		set("total_transactions", *update.TotalTransactions)
+	}
+	if update.UniqueTraders != nil {
+		set("unique_traders", *update.UniqueTraders)
+	}
+	setFloat("volume_24h_cnpy", update.Volume24hCNPY)
+	setFloat("high_24h_cnpy", update.High24hCNPY)
+	setFloat("low_24h_cnpy", update.Low24hCNPY)
+	setFloat("price_24h_change_percent", update.Price24hChangePerc)
+
+	query += " WHERE chain_id = $1"
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update pool state: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("graduated pool not found for chain_id: %s", chainID)
+	}
+
+	return nil
+}
+
+// CreateTransaction creates a new graduated pool transaction record
+func (r *graduatedPoolRepository) CreateTransaction(ctx context.Context, transaction *models.GraduatedPoolTransaction) error {
+	query := `
+		INSERT INTO graduated_pool_transactions (
+			graduated_pool_id, chain_id, user_id, transaction_type, cnpy_amount,
+			token_amount, price_per_token_cnpy, trading_fee_cnpy, slippage_percent,
+			pool_cnpy_reserve_after, pool_token_reserve_after, market_cap_after_usd,
+			transaction_hash, block_height, gas_used
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+		) RETURNING id, created_at`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		transaction.GraduatedPoolID,
+		transaction.ChainID,
+		transaction.UserID,
+		transaction.TransactionType,
+		transaction.CNPYAmount,
+		transaction.TokenAmount,
+		transaction.PricePerTokenCNPY,
+		transaction.TradingFeeCNPY,
+		transaction.SlippagePercent,
+		transaction.PoolCNPYReserveAfter,
+		transaction.PoolTokenReserveAfter,
+		transaction.MarketCapAfterUSD,
+		transaction.TransactionHash,
+		transaction.BlockHeight,
+		transaction.GasUsed,
+	).Scan(&transaction.ID, &transaction.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetTransactionsByPoolID retrieves transactions for a graduated pool with pagination
+func (r *graduatedPoolRepository) GetTransactionsByPoolID(ctx context.Context, poolID uuid.UUID, pagination interfaces.Pagination) ([]models.GraduatedPoolTransaction, int, error) {
+	return r.queryTransactions(ctx, "graduated_pool_id = $1", []interface{}{poolID}, pagination)
+}
+
+// GetTransactionsByUserID retrieves transactions for a user with pagination
+func (r *graduatedPoolRepository) GetTransactionsByUserID(ctx context.Context, userID uuid.UUID, pagination interfaces.Pagination) ([]models.GraduatedPoolTransaction, int, error) {
+	return r.queryTransactions(ctx, "user_id = $1", []interface{}{userID}, pagination)
+}
+
+// GetTransactionsByChainID retrieves transactions for a chain with filters and pagination
+func (r *graduatedPoolRepository) GetTransactionsByChainID(ctx context.Context, chainID uuid.UUID, filters interfaces.TransactionFilters, pagination interfaces.Pagination) ([]models.GraduatedPoolTransaction, int, error) {
+	whereConditions := []string{"chain_id = $1"}
+	args := []interface{}{chainID}
+
+	if filters.UserID != nil {
+		args = append(args, *filters.UserID)
+		whereConditions = append(whereConditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	if filters.TransactionType != "" {
+		args = append(args, filters.TransactionType)
+		whereConditions = append(whereConditions, fmt.Sprintf("transaction_type = $%d", len(args)))
+	}
+
+	return r.queryTransactions(ctx, strings.Join(whereConditions, " AND "), args, pagination)
+}
+
+// queryTransactions counts and pages graduated pool transactions matching whereClause
+func (r *graduatedPoolRepository) queryTransactions(ctx context.Context, whereClause string, args []interface{}, pagination interfaces.Pagination) ([]models.GraduatedPoolTransaction, int, error) {
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM graduated_pool_transactions WHERE %s", whereClause)
+	var total int
+	err := r.db.GetContext(ctx, &total, countQuery, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+
+	dataQuery := fmt.Sprintf(`SELECT %s
+		FROM graduated_pool_transactions
+		WHERE %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d`, graduatedPoolTransactionColumns, whereClause, len(args)+1, len(args)+2)
+
+	args = append(args, pagination.Limit, pagination.Offset)
+
+	transactions := []models.GraduatedPoolTransaction{}
+	err = r.db.SelectContext(ctx, &transactions, dataQuery, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query transactions: %w", err)
+	}
+
+	return transactions, total, nil
+}
+
+// GetUserPosition retrieves a user's position for a specific chain
+func (r *graduatedPoolRepository) GetUserPosition(ctx context.Context, userID, chainID uuid.UUID) (*models.UserGraduatedLPPosition, error) {
+	query := `SELECT ` + graduatedPositionColumns + `
+		FROM user_graduated_positions
+		WHERE user_id = $1 AND chain_id = $2`
+
+	var position models.UserGraduatedLPPosition
+	err := r.db.GetContext(ctx, &position, query, userID, chainID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Position doesn't exist yet, not an error
+		}
+		return nil, fmt.Errorf("failed to get user position: %w", err)
+	}
+
+	return &position, nil
+}
+
+// UpsertUserPosition inserts or updates a user position
+func (r *graduatedPoolRepository) UpsertUserPosition(ctx context.Context, position *models.UserGraduatedLPPosition) error {
+	query := `
+		INSERT INTO user_graduated_positions (
+			user_id, chain_id, graduated_pool_id, token_balance, total_cnpy_invested,
+			total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
+			realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
+			last_activity_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+		)
+		ON CONFLICT (user_id, chain_id)
+		DO UPDATE SET
+			token_balance = EXCLUDED.token_balance,
+			total_cnpy_invested = EXCLUDED.total_cnpy_invested,
+			total_cnpy_withdrawn = EXCLUDED.total_cnpy_withdrawn,
+			average_entry_price_cnpy = EXCLUDED.average_entry_price_cnpy,
+			unrealized_pnl_cnpy = EXCLUDED.unrealized_pnl_cnpy,
+			realized_pnl_cnpy = EXCLUDED.realized_pnl_cnpy,
+			total_return_percent = EXCLUDED.total_return_percent,
+			is_active = EXCLUDED.is_active,
+			last_activity_at = EXCLUDED.last_activity_at,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		position.UserID,
+		position.ChainID,
+		position.GraduatedPoolID,
+		position.TokenBalance,
+		position.TotalCNPYInvested,
+		position.TotalCNPYWithdrawn,
+		position.AverageEntryPriceCNPY,
+		position.UnrealizedPnlCNPY,
+		position.RealizedPnlCNPY,
+		position.TotalReturnPercent,
+		position.IsActive,
+		position.FirstPurchaseAt,
+		position.LastActivityAt,
+	).Scan(&position.ID, &position.CreatedAt, &position.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to upsert user position: %w", err)
+	}
+
+	return nil
+}
+
+// GetPositionsByChainID retrieves all user positions for a specific chain with pagination
+func (r *graduatedPoolRepository) GetPositionsByChainID(ctx context.Context, chainID uuid.UUID, pagination interfaces.Pagination) ([]models.UserGraduatedLPPosition, int, error) {
+	var total int
+	err := r.db.GetContext(ctx, &total, "SELECT COUNT(*) FROM user_graduated_positions WHERE chain_id = $1", chainID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count positions: %w", err)
+	}
+
+	dataQuery := `SELECT ` + graduatedPositionColumns + `
+		FROM user_graduated_positions
+		WHERE chain_id = $1
+		ORDER BY token_balance DESC
+		LIMIT $2 OFFSET $3`
+
+	positions := []models.UserGraduatedLPPosition{}
+	err = r.db.SelectContext(ctx, &positions, dataQuery, chainID, pagination.Limit, pagination.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query positions: %w", err)
+	}
+
+	return positions, total, nil
+}
+
+// GetPositionsWithUsersByChainID retrieves all positions with user wallet addresses for a chain
+func (r *graduatedPoolRepository) GetPositionsWithUsersByChainID(ctx context.Context, chainID uuid.UUID) ([]interfaces.UserPositionWithAddress, error) {
+	query := `
+		SELECT u.wallet_address, (ugp.token_balance * power(10::numeric, c.token_decimals))::numeric(30,0) AS token_balance_units
+		FROM user_graduated_positions ugp
+		INNER JOIN users u ON ugp.user_id = u.id
+		INNER JOIN chains c ON ugp.chain_id = c.id
+		WHERE ugp.chain_id = $1 AND ugp.token_balance > 0
+		ORDER BY ugp.token_balance DESC`
+
+	var results []interfaces.UserPositionWithAddress
+	err := r.db.SelectContext(ctx, &results, query, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query positions with users: %w", err)
+	}
+
+	return results, nil
+}
+
+// MigrateFromVirtualPool moves a chain's final virtual pool state and holder
+// positions into graduated pool records within a single transaction
+func (r *graduatedPoolRepository) MigrateFromVirtualPool(ctx context.Context, chainID uuid.UUID) (*models.GraduatedPool, error) {
+	var pool models.GraduatedPool
+	err := database.Transaction(r.db, func(tx *sqlx.Tx) error {
+		// Lock the virtual pool first so no trade can land between copying the
+		// reserves and deactivating it. Graduated pools keep whole tokens, so the
+		// exact reserves are converted here.
+		var virtualPool models.VirtualPool
+		err := tx.GetContext(ctx, &virtualPool, `
+			SELECT vp.id, vp.chain_id, vp.cnpy_reserve_ucnpy / 1000000 AS cnpy_reserve,
+				   floor(vp.token_reserve_units / power(10::numeric, c.token_decimals)) AS token_reserve,
+				   vp.current_price_cnpy, vp.market_cap_usd, vp.is_active
+			FROM virtual_pools vp
+			JOIN chains c ON c.id = vp.chain_id
+			WHERE vp.chain_id = $1
+			FOR UPDATE OF vp`, chainID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("virtual pool not found for chain_id: %s", chainID)
+			}
+			return fmt.Errorf("failed to lock virtual pool: %w", err)
+		}
+
+		// A previous run already committed the migration
+		err = tx.GetContext(ctx, &pool, `SELECT `+graduatedPoolColumns+` FROM graduated_pools WHERE chain_id = $1`, chainID)
+		if err == nil {
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get graduated pool: %w", err)
+		}
+
+		err = tx.QueryRowxContext(ctx, `
+			INSERT INTO graduated_pools (
+				chain_id, cnpy_reserve, token_reserve, current_price_cnpy, market_cap_usd, is_active
+			)
+			VALUES ($1, $2, $3, $4, $5, true)
+			RETURNING `+graduatedPoolColumns,
+			chainID,
+			virtualPool.CNPYReserve,
+			virtualPool.TokenReserve,
+			virtualPool.CurrentPriceCNPY,
+			virtualPool.MarketCapUSD,
+		).StructScan(&pool)
+		if err != nil {
+			return fmt.Errorf("failed to create graduated pool: %w", err)
+		}
+
+		// Positions keep their cost basis and realised PnL so holder history
+		// carries across the graduation
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO user_graduated_positions (
+				user_id, chain_id, graduated_pool_id, token_balance, total_cnpy_invested,
+				total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
+				realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
+				last_activity_at
+			)
+			SELECT uvp.user_id, uvp.chain_id, $2,
+				   floor(uvp.token_balance_units / power(10::numeric, c.token_decimals)),
+				   uvp.total_cnpy_invested_ucnpy / 1000000, uvp.total_cnpy_withdrawn_ucnpy / 1000000,
+				   uvp.average_entry_price_cnpy, uvp.unrealized_pnl_cnpy, uvp.realized_pnl_cnpy,
+				   uvp.total_return_percent, uvp.is_active, uvp.first_purchase_at, uvp.last_activity_at
+			FROM user_virtual_positions uvp
+			JOIN chains c ON c.id = uvp.chain_id
+			WHERE uvp.chain_id = $1`, chainID, pool.ID)
+		if err != nil {
+			return fmt.Errorf("failed to migrate user positions: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE user_virtual_positions
+			SET is_active = false, updated_at = CURRENT_TIMESTAMP
+			WHERE chain_id = $1`, chainID)
+		if err != nil {
+			return fmt.Errorf("failed to close virtual positions: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE virtual_pools
+			SET is_active = false, updated_at = CURRENT_TIMESTAMP
+			WHERE chain_id = $1`, chainID)
+		if err != nil {
+			return fmt.Errorf("failed to deactivate virtual pool: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pool, nil
+}
@@ -0,0 +1,268 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var graduatedPoolRowColumns = []string{
+	"id", "chain_id", "cnpy_reserve", "token_reserve", "current_price_cnpy",
+	"market_cap_usd", "total_volume_cnpy", "total_transactions", "unique_traders",
+	"is_active", "price_24h_change_percent", "volume_24h_cnpy", "high_24h_cnpy",
+	"low_24h_cnpy", "created_at", "updated_at",
+}
+
+func TestGraduatedPoolGetPoolByChainID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewGraduatedPoolRepository(sqlx.NewDb(db, "sqlmock"))
+	chainID := uuid.New()
+	poolID := uuid.New()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows(graduatedPoolRowColumns).AddRow(
+			poolID, chainID, 50000.0, 200000000, 0.00025, 250000.0,
+			0.0, 0, 0, true, 0.0, 0.0, 0.0, 0.0,
+			time.Now(), time.Now(),
+		)
+
+		mock.ExpectQuery("SELECT (.+) FROM graduated_pools WHERE chain_id").
+			WithArgs(chainID).
+			WillReturnRows(rows)
+
+		pool, err := repo.GetPoolByChainID(context.Background(), chainID)
+		require.NoError(t, err)
+		assert.Equal(t, poolID, pool.ID)
+		assert.Equal(t, int64(200000000), pool.TokenReserve)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM graduated_pools WHERE chain_id").
+			WithArgs(chainID).
+			WillReturnError(sql.ErrNoRows)
+
+		pool, err := repo.GetPoolByChainID(context.Background(), chainID)
+		assert.Nil(t, pool)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "graduated pool not found")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraduatedPoolUpdatePoolState(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewGraduatedPoolRepository(sqlx.NewDb(db, "sqlmock"))
+	chainID := uuid.New()
+
+	t.Run("only set fields are updated", func(t *testing.T) {
+		totalTx := 3
+		update := &interfaces.PoolStateUpdate{
+			CNPYReserve:       big.NewFloat(50100),
+			TokenReserve:      big.NewFloat(199602000),
+			TotalTransactions: &totalTx,
+		}
+
+		mock.ExpectExec(`UPDATE graduated_pools SET updated_at = CURRENT_TIMESTAMP, cnpy_reserve = \$2, token_reserve = \$3, total_transactions = \$4 WHERE chain_id = \$1`).
+			WithArgs(chainID, 50100.0, int64(199602000), 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.UpdatePoolState(context.Background(), chainID, update)
+		require.NoError(t, err)
+	})
+
+	t.Run("pool not found", func(t *testing.T) {
+		mock.ExpectExec("UPDATE graduated_pools SET").
+			WithArgs(chainID, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.UpdatePoolState(context.Background(), chainID, &interfaces.PoolStateUpdate{CNPYReserve: big.NewFloat(1)})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not found")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraduatedPoolGetTransactionsByChainID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewGraduatedPoolRepository(sqlx.NewDb(db, "sqlmock"))
+	chainID := uuid.New()
+	userID := uuid.New()
+	pagination := interfaces.Pagination{Limit: 10, Offset: 20}
+	filters := interfaces.TransactionFilters{
+		UserID:          &userID,
+		TransactionType: models.GraduatedTransactionTypeLiquidityDeposit,
+	}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM graduated_pool_transactions WHERE chain_id = \$1 AND user_id = \$2 AND transaction_type = \$3`).
+		WithArgs(chainID, userID, models.GraduatedTransactionTypeLiquidityDeposit).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+
+	dataRows := sqlmock.NewRows([]string{
+		"id", "graduated_pool_id", "chain_id", "user_id", "transaction_type", "cnpy_amount",
+		"token_amount", "price_per_token_cnpy", "trading_fee_cnpy", "slippage_percent",
+		"pool_cnpy_reserve_after", "pool_token_reserve_after", "market_cap_after_usd",
+		"transaction_hash", "block_height", "gas_used", "created_at",
+	}).AddRow(
+		uuid.New(), uuid.New(), chainID, userID, models.GraduatedTransactionTypeLiquidityDeposit, 10.0,
+		40000, 0.00025, 0.0, 0.0, 50010.0, 200040000, 250050.0,
+		nil, nil, nil, time.Now(),
+	)
+
+	mock.ExpectQuery(`FROM graduated_pool_transactions\s+WHERE chain_id = \$1 AND user_id = \$2 AND transaction_type = \$3\s+ORDER BY created_at DESC\s+LIMIT \$4 OFFSET \$5`).
+		WithArgs(chainID, userID, models.GraduatedTransactionTypeLiquidityDeposit, 10, 20).
+		WillReturnRows(dataRows)
+
+	transactions, total, err := repo.GetTransactionsByChainID(context.Background(), chainID, filters, pagination)
+	require.NoError(t, err)
+	assert.Equal(t, 21, total)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, models.GraduatedTransactionTypeLiquidityDeposit, transactions[0].TransactionType)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraduatedPoolUpsertUserPosition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewGraduatedPoolRepository(sqlx.NewDb(db, "sqlmock"))
+
+	now := time.Now()
+	position := &models.UserGraduatedLPPosition{
+		UserID:          uuid.New(),
+		ChainID:         uuid.New(),
+		GraduatedPoolID: uuid.New(),
+		TokenBalance:    1500000,
+		IsActive:        true,
+		LastActivityAt:  &now,
+	}
+	positionID := uuid.New()
+
+	mock.ExpectQuery("INSERT INTO user_graduated_positions (.+) ON CONFLICT \\(user_id, chain_id\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(positionID, now, now))
+
+	err = repo.UpsertUserPosition(context.Background(), position)
+	require.NoError(t, err)
+	assert.Equal(t, positionID, position.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraduatedPoolMigrateFromVirtualPool(t *testing.T) {
+	chainID := uuid.New()
+	poolID := uuid.New()
+
+	virtualPoolRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"id", "chain_id", "cnpy_reserve", "token_reserve", "current_price_cnpy", "market_cap_usd", "is_active",
+		}).AddRow(uuid.New(), chainID, 50000.0, 200000000, 0.00025, 250000.0, true)
+	}
+	graduatedPoolRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(graduatedPoolRowColumns).AddRow(
+			poolID, chainID, 50000.0, 200000000, 0.00025, 250000.0,
+			0.0, 0, 0, true, 0.0, 0.0, 0.0, 0.0,
+			time.Now(), time.Now(),
+		)
+	}
+
+	t.Run("copies reserves and positions then deactivates the virtual pool", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := NewGraduatedPoolRepository(sqlx.NewDb(db, "sqlmock"))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM virtual_pools vp JOIN chains c ON c.id = vp.chain_id WHERE vp.chain_id = \\$1 FOR UPDATE OF vp").
+			WithArgs(chainID).
+			WillReturnRows(virtualPoolRows())
+		mock.ExpectQuery("SELECT (.+) FROM graduated_pools WHERE chain_id").
+			WithArgs(chainID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("INSERT INTO graduated_pools").
+			WithArgs(chainID, 50000.0, int64(200000000), 0.00025, 250000.0).
+			WillReturnRows(graduatedPoolRows())
+		mock.ExpectExec("INSERT INTO user_graduated_positions (.+) SELECT (.+) FROM user_virtual_positions uvp JOIN chains c").
+			WithArgs(chainID, poolID).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("UPDATE user_virtual_positions SET is_active = false").
+			WithArgs(chainID).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("UPDATE virtual_pools SET is_active = false").
+			WithArgs(chainID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		pool, err := repo.MigrateFromVirtualPool(context.Background(), chainID)
+		require.NoError(t, err)
+		assert.Equal(t, poolID, pool.ID)
+		assert.Equal(t, 50000.0, pool.CNPYReserve)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already migrated returns existing pool", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := NewGraduatedPoolRepository(sqlx.NewDb(db, "sqlmock"))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM virtual_pools").
+			WithArgs(chainID).
+			WillReturnRows(virtualPoolRows())
+		mock.ExpectQuery("SELECT (.+) FROM graduated_pools WHERE chain_id").
+			WithArgs(chainID).
+			WillReturnRows(graduatedPoolRows())
+		mock.ExpectCommit()
+
+		pool, err := repo.MigrateFromVirtualPool(context.Background(), chainID)
+		require.NoError(t, err)
+		assert.Equal(t, poolID, pool.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failure rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := NewGraduatedPoolRepository(sqlx.NewDb(db, "sqlmock"))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM virtual_pools").
+			WithArgs(chainID).
+			WillReturnRows(virtualPoolRows())
+		mock.ExpectQuery("SELECT (.+) FROM graduated_pools WHERE chain_id").
+			WithArgs(chainID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("INSERT INTO graduated_pools").
+			WillReturnRows(graduatedPoolRows())
+		mock.ExpectExec("INSERT INTO user_graduated_positions").
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		pool, err := repo.MigrateFromVirtualPool(context.Background(), chainID)
+		assert.Nil(t, pool)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to migrate user positions")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
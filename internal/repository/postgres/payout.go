@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/jmoiron/sqlx"
+)
+
+const payoutColumns = `id, chain_id, user_id, virtual_pool_transaction_id, payout_type,
+			   recipient_address, amount, reference, status, tx_hash, signed_tx,
+			   tx_created_height, confirmed_height, attempts, last_error, next_attempt_at,
+			   created_at, updated_at`
+
+type payoutRepository struct {
+	db *sqlx.DB
+}
+
+// NewPayoutRepository creates a new PostgreSQL payout repository
+func NewPayoutRepository(db *sqlx.DB) interfaces.PayoutRepository {
+	return &payoutRepository{db: db}
+}
+
+// ListDue retrieves payouts of chains on a root chain that are waiting to be
+// sent or confirmed
+func (r *payoutRepository) ListDue(ctx context.Context, rootChainID uint64, limit int) ([]models.Payout, error) {
+	query := `SELECT ` + payoutColumns + ` FROM payouts
+		WHERE status IN ('pending', 'submitted') AND next_attempt_at <= NOW()
+		  AND chain_id IN (SELECT id FROM chains WHERE root_chain_id = $1)
+		ORDER BY created_at ASC
+		LIMIT $2`
+
+	payouts := []models.Payout{}
+	if err := r.db.SelectContext(ctx, &payouts, query, rootChainID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list due payouts: %w", err)
+	}
+
+	return payouts, nil
+}
+
+// Update persists a payout if no one else has changed it since it was read.
+// The updated_at check makes signing a payout a claim: of two workers that read
+// the same pending payout, only one can store its signed transaction.
+func (r *payoutRepository) Update(ctx context.Context, payout *models.Payout, fromStatus string) (bool, error) {
+	query := `
+		UPDATE payouts SET
+			status = $3, tx_hash = $4, signed_tx = $5, tx_created_height = $6,
+			confirmed_height = $7, attempts = $8, last_error = $9, next_attempt_at = $10,
+			updated_at = NOW()
+		WHERE id = $1 AND status = $2 AND updated_at = $11
+		RETURNING updated_at`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		payout.ID,
+		fromStatus,
+		payout.Status,
+		payout.TxHash,
+		payout.SignedTx,
+		payout.TxCreatedHeight,
+		payout.ConfirmedHeight,
+		payout.Attempts,
+		payout.LastError,
+		payout.NextAttemptAt,
+		payout.UpdatedAt,
+	).Scan(&payout.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update payout: %w", err)
+	}
+
+	return true, nil
+}
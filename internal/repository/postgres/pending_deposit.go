@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type pendingDepositRepository struct {
+	db *sqlx.DB
+}
+
+// NewPendingDepositRepository creates a new PostgreSQL pending deposit repository
+func NewPendingDepositRepository(db *sqlx.DB) interfaces.PendingDepositRepository {
+	return &pendingDepositRepository{db: db}
+}
+
+// Create records a pending deposit, ignoring one that is already recorded
+func (r *pendingDepositRepository) Create(ctx context.Context, deposit *models.PendingDeposit) error {
+	query := `
+		INSERT INTO pending_deposits (
+			root_chain_id, chain_id, sender_address, amount, transaction_hash, block_height, final_height
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (transaction_hash, block_height) DO NOTHING`
+
+	_, err := r.db.ExecContext(ctx, query,
+		deposit.RootChainID, deposit.ChainID, deposit.SenderAddress, deposit.Amount,
+		deposit.TransactionHash, deposit.BlockHeight, deposit.FinalHeight,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create pending deposit: %w", err)
+	}
+
+	return nil
+}
+
+// ListByChainID lists a chain's pending deposits. Rows at or below the root
+// chain checkpoint have already been applied and are left out even if they
+// have not been deleted yet.
+func (r *pendingDepositRepository) ListByChainID(ctx context.Context, chainID uuid.UUID, filters interfaces.PendingDepositFilters) ([]models.PendingDeposit, error) {
+	query := `
+		SELECT pd.id, pd.root_chain_id, pd.chain_id, pd.sender_address, pd.amount,
+			   pd.transaction_hash, pd.block_height, pd.final_height, pd.created_at
+		FROM pending_deposits pd
+		LEFT JOIN root_chain_checkpoints c ON c.root_chain_id = pd.root_chain_id
+		WHERE pd.chain_id = $1 AND (c.height IS NULL OR pd.block_height > c.height)`
+	args := []interface{}{chainID}
+
+	if filters.SenderAddress != "" {
+		query += " AND pd.sender_address = $2"
+		args = append(args, filters.SenderAddress)
+	}
+	query += " ORDER BY pd.block_height DESC, pd.created_at DESC"
+
+	deposits := []models.PendingDeposit{}
+	if err := r.db.SelectContext(ctx, &deposits, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list pending deposits: %w", err)
+	}
+
+	return deposits, nil
+}
+
+// DeleteThroughHeight removes pending deposits that have been applied
+func (r *pendingDepositRepository) DeleteThroughHeight(ctx context.Context, rootChainID uint64, height uint64) error {
+	query := `DELETE FROM pending_deposits WHERE root_chain_id = $1 AND block_height <= $2`
+
+	if _, err := r.db.ExecContext(ctx, query, rootChainID, height); err != nil {
+		return fmt.Errorf("failed to delete pending deposits: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/jmoiron/sqlx"
+)
+
+// RootChainCheckpointTxRepository extends RootChainCheckpointRepository with a
+// save that runs inside a database transaction, so a block's writes and the
+// checkpoint that marks it processed commit together.
+type RootChainCheckpointTxRepository interface {
+	interfaces.RootChainCheckpointRepository
+
+	// SaveInTx records height as the last processed height within a transaction
+	SaveInTx(ctx context.Context, tx *sqlx.Tx, rootChainID uint64, height uint64) error
+}
+
+type rootChainCheckpointRepository struct {
+	db *sqlx.DB
+}
+
+// NewRootChainCheckpointRepository creates a new PostgreSQL root chain checkpoint repository
+func NewRootChainCheckpointRepository(db *sqlx.DB) interfaces.RootChainCheckpointRepository {
+	return &rootChainCheckpointRepository{db: db}
+}
+
+// NewRootChainCheckpointTxRepository creates a new transaction-aware root chain checkpoint repository
+func NewRootChainCheckpointTxRepository(db *sqlx.DB) RootChainCheckpointTxRepository {
+	return &rootChainCheckpointRepository{db: db}
+}
+
+// Get retrieves the checkpoint for a root chain
+func (r *rootChainCheckpointRepository) Get(ctx context.Context, rootChainID uint64) (*models.RootChainCheckpoint, error) {
+	query := `SELECT root_chain_id, height, updated_at FROM root_chain_checkpoints WHERE root_chain_id = $1`
+
+	var checkpoint models.RootChainCheckpoint
+	err := r.db.QueryRowxContext(ctx, query, rootChainID).Scan(
+		&checkpoint.RootChainID, &checkpoint.Height, &checkpoint.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get root chain checkpoint: %w", err)
+	}
+
+	return &checkpoint, nil
+}
+
+// Save records the last processed height for a root chain
+func (r *rootChainCheckpointRepository) Save(ctx context.Context, rootChainID uint64, height uint64) error {
+	return saveCheckpoint(ctx, r.db, rootChainID, height)
+}
+
+// SaveInTx records the last processed height for a root chain within a transaction
+func (r *rootChainCheckpointRepository) SaveInTx(ctx context.Context, tx *sqlx.Tx, rootChainID uint64, height uint64) error {
+	return saveCheckpoint(ctx, tx, rootChainID, height)
+}
+
+func saveCheckpoint(ctx context.Context, db sqlx.ExecerContext, rootChainID uint64, height uint64) error {
+	query := `
+		INSERT INTO root_chain_checkpoints (root_chain_id, height)
+		VALUES ($1, $2)
+		ON CONFLICT (root_chain_id) DO UPDATE SET
+			height = GREATEST(root_chain_checkpoints.height, EXCLUDED.height),
+			updated_at = NOW()`
+
+	if _, err := db.ExecContext(ctx, query, rootChainID, height); err != nil {
+		return fmt.Errorf("failed to save root chain checkpoint: %w", err)
+	}
+
+	return nil
+}
@@ -75,6 +75,35 @@ func (r *userRepository) GetByWalletAddress(ctx context.Context, walletAddress s
 	return r.getUserByField(ctx, "wallet_address", walletAddress)
 }
 
+// GetOrCreateByWalletAddresses retrieves the users for a set of wallet
+// addresses in one round trip, creating a user for each address not seen before
+func (r *userRepository) GetOrCreateByWalletAddresses(ctx context.Context, walletAddresses []string) ([]models.User, error) {
+	sanitized := make([]string, len(walletAddresses))
+	for i, address := range walletAddresses {
+		sanitized[i] = sanitizeString(address)
+	}
+
+	// Rows inserted by the CTE are not visible to the second SELECT, so each
+	// address is returned exactly once
+	query := `
+		WITH created AS (
+			INSERT INTO users (wallet_address)
+			SELECT DISTINCT unnest($1::text[])
+			ON CONFLICT (wallet_address) DO NOTHING
+			RETURNING *
+		)
+		SELECT * FROM created
+		UNION ALL
+		SELECT * FROM users WHERE wallet_address = ANY($1)`
+
+	users := []models.User{}
+	if err := r.db.SelectContext(ctx, &users, query, pq.Array(sanitized)); err != nil {
+		return nil, fmt.Errorf("failed to get or create users: %w", err)
+	}
+
+	return users, nil
+}
+
 // GetByEmail retrieves a user by email
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	return r.getUserByField(ctx, "email", email)
@@ -28,13 +28,13 @@ func (r *virtualPoolRepository) Create(ctx context.Context, pool *models.Virtual
 	query := `
 		INSERT INTO virtual_pools (
 			chain_id, cnpy_reserve, token_reserve, current_price_cnpy,
-			total_transactions, is_active
+			total_transactions, is_active, cnpy_reserve_ucnpy, token_reserve_units
 		)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, chain_id, cnpy_reserve, token_reserve, current_price_cnpy,
 				  market_cap_usd, total_volume_cnpy, total_transactions, unique_traders,
 				  is_active, price_24h_change_percent, volume_24h_cnpy, high_24h_cnpy,
-				  low_24h_cnpy, created_at, updated_at`
+				  low_24h_cnpy, created_at, updated_at, cnpy_reserve_ucnpy, token_reserve_units`
 
 	var created models.VirtualPool
 	err := r.db.QueryRowxContext(ctx, query,
@@ -44,12 +44,15 @@ func (r *virtualPoolRepository) Create(ctx context.Context, pool *models.Virtual
 		pool.CurrentPriceCNPY,
 		pool.TotalTransactions,
 		pool.IsActive,
+		pool.CNPYReserveMicro,
+		pool.TokenReserveUnits,
 	).Scan(
 		&created.ID, &created.ChainID, &created.CNPYReserve, &created.TokenReserve,
 		&created.CurrentPriceCNPY, &created.MarketCapUSD, &created.TotalVolumeCNPY,
 		&created.TotalTransactions, &created.UniqueTraders, &created.IsActive,
 		&created.Price24hChangePercent, &created.Volume24hCNPY, &created.High24hCNPY,
-		&created.Low24hCNPY, &created.CreatedAt, &created.UpdatedAt,
+		&created.Low24hCNPY, &created.CreatedAt, &created.UpdatedAt, &created.CNPYReserveMicro,
+		&created.TokenReserveUnits,
 	)
 
 	if err != nil {
@@ -62,12 +65,16 @@ func (r *virtualPoolRepository) Create(ctx context.Context, pool *models.Virtual
 // GetPoolByChainID retrieves a virtual pool by chain ID
 func (r *virtualPoolRepository) GetPoolByChainID(ctx context.Context, chainID uuid.UUID) (*models.VirtualPool, error) {
 	query := `
-		SELECT id, chain_id, cnpy_reserve, token_reserve, current_price_cnpy, market_cap_usd,
-			   total_volume_cnpy, total_transactions, unique_traders, is_active,
-			   price_24h_change_percent, volume_24h_cnpy, high_24h_cnpy, low_24h_cnpy,
-			   created_at, updated_at
-		FROM virtual_pools
-		WHERE chain_id = $1`
+		SELECT vp.id, vp.chain_id, vp.cnpy_reserve, vp.token_reserve, vp.current_price_cnpy,
+			   vp.market_cap_usd, vp.total_volume_cnpy, vp.total_transactions, vp.unique_traders,
+			   vp.is_active, vp.price_24h_change_percent, vp.volume_24h_cnpy, vp.high_24h_cnpy,
+			   vp.low_24h_cnpy, vp.created_at, vp.updated_at, vp.cnpy_reserve_ucnpy,
+			   vp.token_reserve_units, c.token_decimals, c.curve_type, c.bonding_curve_slope,
+			   c.curve_max_price, c.curve_midpoint_supply, c.created_by, c.status AS chain_status,
+			   EXISTS (SELECT 1 FROM chain_graduations g WHERE g.chain_id = vp.chain_id) AS graduation_started
+		FROM virtual_pools vp
+		JOIN chains c ON c.id = vp.chain_id
+		WHERE vp.chain_id = $1`
 
 	var pool models.VirtualPool
 	err := r.db.QueryRowxContext(ctx, query, chainID).Scan(
@@ -75,7 +82,10 @@ func (r *virtualPoolRepository) GetPoolByChainID(ctx context.Context, chainID uu
 		&pool.CurrentPriceCNPY, &pool.MarketCapUSD, &pool.TotalVolumeCNPY,
 		&pool.TotalTransactions, &pool.UniqueTraders, &pool.IsActive,
 		&pool.Price24hChangePercent, &pool.Volume24hCNPY, &pool.High24hCNPY,
-		&pool.Low24hCNPY, &pool.CreatedAt, &pool.UpdatedAt,
+		&pool.Low24hCNPY, &pool.CreatedAt, &pool.UpdatedAt, &pool.CNPYReserveMicro,
+		&pool.TokenReserveUnits, &pool.TokenDecimals, &pool.CurveType,
+		&pool.BondingCurveSlope, &pool.CurveMaxPrice, &pool.CurveMidpointSupply,
+		&pool.CreatorID, &pool.ChainStatus, &pool.GraduationStarted,
 	)
 
 	if err != nil {
@@ -103,7 +113,7 @@ func (r *virtualPoolRepository) GetAllPools(ctx context.Context, pagination inte
 		SELECT id, chain_id, cnpy_reserve, token_reserve, current_price_cnpy, market_cap_usd,
 			   total_volume_cnpy, total_transactions, unique_traders, is_active,
 			   price_24h_change_percent, volume_24h_cnpy, high_24h_cnpy, low_24h_cnpy,
-			   created_at, updated_at
+			   created_at, updated_at, cnpy_reserve_ucnpy, token_reserve_units
 		FROM virtual_pools
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2`
@@ -138,6 +148,18 @@ func (r *virtualPoolRepository) UpdatePoolState(ctx context.Context, chainID uui
 		args = append(args, int64(tokenFloat))
 	}
 
+	if update.CNPYReserveMicro != nil {
+		argCount++
+		query += fmt.Sprintf(", cnpy_reserve_ucnpy = $%d", argCount)
+		args = append(args, *update.CNPYReserveMicro)
+	}
+
+	if update.TokenReserveUnits != nil {
+		argCount++
+		query += fmt.Sprintf(", token_reserve_units = $%d", argCount)
+		args = append(args, *update.TokenReserveUnits)
+	}
+
 	if update.CurrentPriceCNPY != nil {
 		argCount++
 		priceFloat, _ := update.CurrentPriceCNPY.Float64()
@@ -225,9 +247,10 @@ func (r *virtualPoolRepository) CreateTransaction(ctx context.Context, transacti
 			virtual_pool_id, chain_id, user_id, transaction_type, cnpy_amount,
 			token_amount, price_per_token_cnpy, trading_fee_cnpy, slippage_percent,
 			pool_cnpy_reserve_after, pool_token_reserve_after, market_cap_after_usd,
-			transaction_hash, block_height, gas_used
+			transaction_hash, block_height, gas_used, cnpy_amount_ucnpy, token_amount_units,
+			pool_cnpy_reserve_after_ucnpy, pool_token_reserve_after_units
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
 		) RETURNING id, created_at`
 
 	err := r.db.QueryRowxContext(ctx, query,
@@ -246,6 +269,10 @@ func (r *virtualPoolRepository) CreateTransaction(ctx context.Context, transacti
 		transaction.TransactionHash,
 		transaction.BlockHeight,
 		transaction.GasUsed,
+		transaction.CNPYAmountMicro,
+		transaction.TokenAmountUnits,
+		transaction.PoolCNPYReserveAfterMicro,
+		transaction.PoolTokenReserveAfterUnits,
 	).Scan(&transaction.ID, &transaction.CreatedAt)
 
 	if err != nil {
@@ -270,7 +297,8 @@ func (r *virtualPoolRepository) GetTransactionsByPoolID(ctx context.Context, poo
 		SELECT id, virtual_pool_id, chain_id, user_id, transaction_type, cnpy_amount,
 			   token_amount, price_per_token_cnpy, trading_fee_cnpy, slippage_percent,
 			   pool_cnpy_reserve_after, pool_token_reserve_after, market_cap_after_usd,
-			   transaction_hash, block_height, gas_used, created_at
+			   transaction_hash, block_height, gas_used, created_at, cnpy_amount_ucnpy,
+			   token_amount_units, pool_cnpy_reserve_after_ucnpy, pool_token_reserve_after_units
 		FROM virtual_pool_transactions
 		WHERE virtual_pool_id = $1
 		ORDER BY created_at DESC
@@ -300,7 +328,8 @@ func (r *virtualPoolRepository) GetTransactionsByUserID(ctx context.Context, use
 		SELECT id, virtual_pool_id, chain_id, user_id, transaction_type, cnpy_amount,
 			   token_amount, price_per_token_cnpy, trading_fee_cnpy, slippage_percent,
 			   pool_cnpy_reserve_after, pool_token_reserve_after, market_cap_after_usd,
-			   transaction_hash, block_height, gas_used, created_at
+			   transaction_hash, block_height, gas_used, created_at, cnpy_amount_ucnpy,
+			   token_amount_units, pool_cnpy_reserve_after_ucnpy, pool_token_reserve_after_units
 		FROM virtual_pool_transactions
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -349,7 +378,8 @@ func (r *virtualPoolRepository) GetTransactionsByChainID(ctx context.Context, ch
 		SELECT id, virtual_pool_id, chain_id, user_id, transaction_type, cnpy_amount,
 			   token_amount, price_per_token_cnpy, trading_fee_cnpy, slippage_percent,
 			   pool_cnpy_reserve_after, pool_token_reserve_after, market_cap_after_usd,
-			   transaction_hash, block_height, gas_used, created_at
+			   transaction_hash, block_height, gas_used, created_at, cnpy_amount_ucnpy,
+			   token_amount_units, pool_cnpy_reserve_after_ucnpy, pool_token_reserve_after_units
 		FROM virtual_pool_transactions
 		WHERE %s
 		ORDER BY created_at DESC
@@ -372,7 +402,8 @@ func (r *virtualPoolRepository) GetUserPosition(ctx context.Context, userID, cha
 		SELECT id, user_id, chain_id, virtual_pool_id, token_balance, total_cnpy_invested,
 			   total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
 			   realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
-			   last_activity_at, created_at, updated_at
+			   last_activity_at, created_at, updated_at, token_balance_units,
+			   total_cnpy_invested_ucnpy, total_cnpy_withdrawn_ucnpy
 		FROM user_virtual_positions
 		WHERE user_id = $1 AND chain_id = $2`
 
@@ -384,7 +415,8 @@ func (r *virtualPoolRepository) GetUserPosition(ctx context.Context, userID, cha
 		&position.TokenBalance, &position.TotalCNPYInvested, &position.TotalCNPYWithdrawn,
 		&position.AverageEntryPriceCNPY, &position.UnrealizedPnlCNPY, &position.RealizedPnlCNPY,
 		&position.TotalReturnPercent, &position.IsActive, &firstPurchaseAt,
-		&lastActivityAt, &position.CreatedAt, &position.UpdatedAt,
+		&lastActivityAt, &position.CreatedAt, &position.UpdatedAt, &position.TokenBalanceUnits,
+		&position.TotalCNPYInvestedMicro, &position.TotalCNPYWithdrawnMicro,
 	)
 
 	if err != nil {
@@ -411,9 +443,10 @@ func (r *virtualPoolRepository) UpsertUserPosition(ctx context.Context, position
 			user_id, chain_id, virtual_pool_id, token_balance, total_cnpy_invested,
 			total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
 			realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
-			last_activity_at
+			last_activity_at, token_balance_units, total_cnpy_invested_ucnpy,
+			total_cnpy_withdrawn_ucnpy
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 		)
 		ON CONFLICT (user_id, chain_id)
 		DO UPDATE SET
@@ -426,6 +459,9 @@ func (r *virtualPoolRepository) UpsertUserPosition(ctx context.Context, position
 			total_return_percent = EXCLUDED.total_return_percent,
 			is_active = EXCLUDED.is_active,
 			last_activity_at = EXCLUDED.last_activity_at,
+			token_balance_units = EXCLUDED.token_balance_units,
+			total_cnpy_invested_ucnpy = EXCLUDED.total_cnpy_invested_ucnpy,
+			total_cnpy_withdrawn_ucnpy = EXCLUDED.total_cnpy_withdrawn_ucnpy,
 			updated_at = CURRENT_TIMESTAMP
 		RETURNING id, created_at, updated_at`
 
@@ -443,6 +479,9 @@ func (r *virtualPoolRepository) UpsertUserPosition(ctx context.Context, position
 		position.IsActive,
 		position.FirstPurchaseAt,
 		position.LastActivityAt,
+		position.TokenBalanceUnits,
+		position.TotalCNPYInvestedMicro,
+		position.TotalCNPYWithdrawnMicro,
 	).Scan(&position.ID, &position.CreatedAt, &position.UpdatedAt)
 
 	if err != nil {
@@ -467,10 +506,11 @@ func (r *virtualPoolRepository) GetPositionsByChainID(ctx context.Context, chain
 		SELECT id, user_id, chain_id, virtual_pool_id, token_balance, total_cnpy_invested,
 			   total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
 			   realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
-			   last_activity_at, created_at, updated_at
+			   last_activity_at, created_at, updated_at, token_balance_units,
+			   total_cnpy_invested_ucnpy, total_cnpy_withdrawn_ucnpy
 		FROM user_virtual_positions
 		WHERE chain_id = $1
-		ORDER BY token_balance DESC
+		ORDER BY token_balance_units DESC
 		LIMIT $2 OFFSET $3`
 
 	positions := []models.UserVirtualLPPosition{}
@@ -485,11 +525,11 @@ func (r *virtualPoolRepository) GetPositionsByChainID(ctx context.Context, chain
 // GetPositionsWithUsersByChainID retrieves all positions with user wallet addresses for a chain
 func (r *virtualPoolRepository) GetPositionsWithUsersByChainID(ctx context.Context, chainID uuid.UUID) ([]interfaces.UserPositionWithAddress, error) {
 	query := `
-		SELECT u.wallet_address, uvp.token_balance
+		SELECT u.wallet_address, uvp.token_balance_units
 		FROM user_virtual_positions uvp
 		INNER JOIN users u ON uvp.user_id = u.id
-		WHERE uvp.chain_id = $1 AND uvp.token_balance > 0
-		ORDER BY uvp.token_balance DESC`
+		WHERE uvp.chain_id = $1 AND uvp.token_balance_units > 0
+		ORDER BY uvp.token_balance_units DESC`
 
 	var results []interfaces.UserPositionWithAddress
 	err := r.db.SelectContext(ctx, &results, query, chainID)
@@ -509,7 +549,7 @@ func (r *virtualPoolRepository) GetPriceHistory(ctx context.Context, chainID uui
 			MAX(price_per_token_cnpy) as high,
 			MIN(price_per_token_cnpy) as low,
 			(array_agg(price_per_token_cnpy ORDER BY created_at DESC))[1] as close,
-			COALESCE(SUM(cnpy_amount), 0) as volume,
+			COALESCE(SUM(cnpy_amount_ucnpy), 0) / 1000000 as volume,
 			COUNT(*) as trade_count
 		FROM virtual_pool_transactions
 		WHERE chain_id = $1
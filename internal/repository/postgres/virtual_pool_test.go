@@ -25,20 +25,25 @@ func TestGetPoolByChainID(t *testing.T) {
 
 	chainID := uuid.New()
 	poolID := uuid.New()
+	creatorID := uuid.New()
 
 	t.Run("success", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{
 			"id", "chain_id", "cnpy_reserve", "token_reserve", "current_price_cnpy",
 			"market_cap_usd", "total_volume_cnpy", "total_transactions", "unique_traders",
 			"is_active", "price_24h_change_percent", "volume_24h_cnpy", "high_24h_cnpy",
-			"low_24h_cnpy", "created_at", "updated_at",
+			"low_24h_cnpy", "created_at", "updated_at", "cnpy_reserve_ucnpy", "token_reserve_units",
+			"token_decimals", "curve_type", "bonding_curve_slope", "curve_max_price",
+			"curve_midpoint_supply", "created_by", "chain_status", "graduation_started",
 		}).AddRow(
 			poolID, chainID, 10000.0, 800000000, 0.0000125, 10000.0,
 			5000.0, 10, 5, true, 2.5, 1000.0, 0.000015, 0.00001,
-			time.Now(), time.Now(),
+			time.Now(), time.Now(), "10000000000", "800000000000000", 6,
+			"capped_sigmoid", 0.00000001, 1.0, 400000000, creatorID,
+			models.ChainStatusVirtualActive, false,
 		)
 
-		mock.ExpectQuery("SELECT (.+) FROM virtual_pools WHERE chain_id").
+		mock.ExpectQuery("SELECT (.+) FROM virtual_pools vp JOIN chains c ON (.+) WHERE vp.chain_id").
 			WithArgs(chainID).
 			WillReturnRows(rows)
 
@@ -47,10 +52,19 @@ func TestGetPoolByChainID(t *testing.T) {
 		assert.NotNil(t, pool)
 		assert.Equal(t, poolID, pool.ID)
 		assert.Equal(t, chainID, pool.ChainID)
+		assert.Equal(t, uint64(10000000000), pool.CNPYReserveMicro)
+		assert.Equal(t, uint64(800000000000000), pool.TokenReserveUnits)
+		assert.Equal(t, 6, pool.TokenDecimals)
+		assert.Equal(t, models.CurveTypeCappedSigmoid, pool.CurveType)
+		require.NotNil(t, pool.CurveMidpointSupply)
+		assert.Equal(t, int64(400000000), *pool.CurveMidpointSupply)
+		assert.Equal(t, creatorID, pool.CreatorID)
+		assert.Equal(t, models.ChainStatusVirtualActive, pool.ChainStatus)
+		assert.False(t, pool.GraduationStarted)
 	})
 
 	t.Run("not found", func(t *testing.T) {
-		mock.ExpectQuery("SELECT (.+) FROM virtual_pools WHERE chain_id").
+		mock.ExpectQuery("SELECT (.+) FROM virtual_pools vp JOIN chains c ON (.+) WHERE vp.chain_id").
 			WithArgs(chainID).
 			WillReturnError(sqlmock.ErrCancelled)
 
@@ -128,6 +142,11 @@ func TestCreateTransaction(t *testing.T) {
 		PoolCNPYReserveAfter:  10100.0,
 		PoolTokenReserveAfter: 792000,
 		MarketCapAfterUSD:     10100.0,
+
+		CNPYAmountMicro:            100000000,
+		TokenAmountUnits:           8000000000,
+		PoolCNPYReserveAfterMicro:  10100000000,
+		PoolTokenReserveAfterUnits: 792000000000,
 	}
 
 	t.Run("success", func(t *testing.T) {
@@ -151,6 +170,10 @@ func TestCreateTransaction(t *testing.T) {
 				transaction.TransactionHash,
 				transaction.BlockHeight,
 				transaction.GasUsed,
+				transaction.CNPYAmountMicro,
+				transaction.TokenAmountUnits,
+				transaction.PoolCNPYReserveAfterMicro,
+				transaction.PoolTokenReserveAfterUnits,
 			).
 			WillReturnRows(rows)
 
@@ -180,9 +203,10 @@ func TestGetUserPosition(t *testing.T) {
 			"total_cnpy_invested", "total_cnpy_withdrawn", "average_entry_price_cnpy",
 			"unrealized_pnl_cnpy", "realized_pnl_cnpy", "total_return_percent",
 			"is_active", "first_purchase_at", "last_activity_at", "created_at", "updated_at",
+			"token_balance_units", "total_cnpy_invested_ucnpy", "total_cnpy_withdrawn_ucnpy",
 		}).AddRow(
 			positionID, userID, chainID, poolID, 8000, 100.0, 0.0, 0.0125,
-			5.0, 0.0, 5.0, true, now, now, now, now,
+			5.0, 0.0, 5.0, true, now, now, now, now, "8000123456", "100000000", "0",
 		)
 
 		mock.ExpectQuery("SELECT (.+) FROM user_virtual_positions WHERE user_id").
@@ -195,6 +219,8 @@ func TestGetUserPosition(t *testing.T) {
 		assert.Equal(t, positionID, position.ID)
 		assert.Equal(t, userID, position.UserID)
 		assert.Equal(t, int64(8000), position.TokenBalance)
+		assert.Equal(t, uint64(8000123456), position.TokenBalanceUnits)
+		assert.Equal(t, uint64(100000000), position.TotalCNPYInvestedMicro)
 	})
 
 	t.Run("not found returns nil", func(t *testing.T) {
@@ -231,6 +257,9 @@ func TestUpsertUserPosition(t *testing.T) {
 		IsActive:              true,
 		FirstPurchaseAt:       &now,
 		LastActivityAt:        &now,
+
+		TokenBalanceUnits:      8000000000,
+		TotalCNPYInvestedMicro: 100000000,
 	}
 
 	t.Run("success insert", func(t *testing.T) {
@@ -252,6 +281,9 @@ func TestUpsertUserPosition(t *testing.T) {
 				position.IsActive,
 				position.FirstPurchaseAt,
 				position.LastActivityAt,
+				position.TokenBalanceUnits,
+				position.TotalCNPYInvestedMicro,
+				position.TotalCNPYWithdrawnMicro,
 			).
 			WillReturnRows(rows)
 
@@ -342,3 +374,29 @@ func TestGetPositionsByChainID(t *testing.T) {
 		assert.Len(t, positions, 1)
 	})
 }
+
+func TestTransactionExistsInTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewVirtualPoolTxRepository(sqlxDB)
+
+	for _, exists := range []bool{true, false} {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS \\(\\s*SELECT 1 FROM virtual_pool_transactions\\s+WHERE transaction_hash = \\$1 AND block_height = \\$2").
+			WithArgs("0xabc123", int64(1000)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+		mock.ExpectRollback()
+
+		tx, err := sqlxDB.Beginx()
+		require.NoError(t, err)
+		found, err := repo.TransactionExistsInTx(context.Background(), tx, "0xabc123", 1000)
+		require.NoError(t, err)
+		assert.Equal(t, exists, found)
+		require.NoError(t, tx.Rollback())
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
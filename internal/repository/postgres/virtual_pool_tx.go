@@ -4,11 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/interfaces"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // VirtualPoolTxRepository extends VirtualPoolRepository with transaction-aware methods
@@ -23,6 +25,7 @@ type VirtualPoolTxRepository interface {
 	// GetPoolByChainIDForUpdate retrieves a virtual pool with an exclusive row lock.
 	// Uses SELECT ... FOR UPDATE to prevent concurrent modifications.
 	// Other transactions attempting to read this row will wait until the lock is released.
+	// The pool carries its chain's bonding curve settings.
 	GetPoolByChainIDForUpdate(ctx context.Context, tx *sqlx.Tx, chainID uuid.UUID) (*models.VirtualPool, error)
 
 	// UpdatePoolStateInTx updates virtual pool state within a transaction.
@@ -33,6 +36,11 @@ type VirtualPoolTxRepository interface {
 	// Records the order details and resulting pool state for audit trail.
 	CreateTransactionInTx(ctx context.Context, tx *sqlx.Tx, transaction *models.VirtualPoolTransaction) error
 
+	// TransactionExistsInTx reports whether a transaction with the given root chain
+	// hash and block height has already been recorded. Deposits check this after
+	// locking the pool so a replayed block is never applied twice.
+	TransactionExistsInTx(ctx context.Context, tx *sqlx.Tx, txHash string, blockHeight int64) (bool, error)
+
 	// GetUserPositionForUpdate retrieves a user position with an exclusive row lock.
 	// Returns nil if position doesn't exist (not an error - user hasn't traded yet).
 	GetUserPositionForUpdate(ctx context.Context, tx *sqlx.Tx, userID, chainID uuid.UUID) (*models.UserVirtualLPPosition, error)
@@ -40,6 +48,49 @@ type VirtualPoolTxRepository interface {
 	// UpsertUserPositionInTx inserts or updates a user position within a transaction.
 	// Uses ON CONFLICT to handle both new and existing positions atomically.
 	UpsertUserPositionInTx(ctx context.Context, tx *sqlx.Tx, position *models.UserVirtualLPPosition) error
+
+	// PayoutExistsInTx reports whether a payout with the given reference has
+	// already been queued. Sells check this after locking the pool so a signed
+	// request is honoured at most once.
+	PayoutExistsInTx(ctx context.Context, tx *sqlx.Tx, reference string) (bool, error)
+
+	// CreatePayoutInTx queues a payout within a transaction, so the CNPY owed for
+	// a sell is recorded together with the trade that produced it.
+	CreatePayoutInTx(ctx context.Context, tx *sqlx.Tx, payout *models.Payout) error
+
+	// The batch methods below let a whole root chain block be applied in a
+	// handful of statements instead of several per deposit.
+
+	// GetPoolsByChainIDsForUpdate retrieves the virtual pools of several chains
+	// with exclusive row locks, taken in chain ID order, along with each chain's
+	// bonding curve settings. Chains without a pool are left out.
+	GetPoolsByChainIDsForUpdate(ctx context.Context, tx *sqlx.Tx, chainIDs []uuid.UUID) ([]models.VirtualPool, error)
+
+	// AppliedDepositsInTx reports which of the given root chain transaction
+	// hashes have already been applied at blockHeight, as a recorded transaction
+	// or as a queued refund.
+	AppliedDepositsInTx(ctx context.Context, tx *sqlx.Tx, txHashes []string, blockHeight int64) (map[string]bool, error)
+
+	// GetUserPositionsForUpdate retrieves user positions with exclusive row
+	// locks for pairs of user and chain: userIDs[i] with chainIDs[i]. Pairs
+	// without a position are left out.
+	GetUserPositionsForUpdate(ctx context.Context, tx *sqlx.Tx, userIDs, chainIDs []uuid.UUID) ([]models.UserVirtualLPPosition, error)
+
+	// CreateTransactionsInTx records several transactions in one statement.
+	// Transactions without an ID are given one, so callers can reference them
+	// before they are written.
+	CreateTransactionsInTx(ctx context.Context, tx *sqlx.Tx, transactions []*models.VirtualPoolTransaction) error
+
+	// UpsertUserPositionsInTx inserts or updates several positions in one
+	// statement. Each user and chain pair may appear only once.
+	UpsertUserPositionsInTx(ctx context.Context, tx *sqlx.Tx, positions []*models.UserVirtualLPPosition) error
+
+	// CreatePayoutsInTx queues several payouts in one statement
+	CreatePayoutsInTx(ctx context.Context, tx *sqlx.Tx, payouts []*models.Payout) error
+
+	// CreateFeeAccrualsInTx records the fee splits of one or more trades in one
+	// statement. The trades' transactions must be written first.
+	CreateFeeAccrualsInTx(ctx context.Context, tx *sqlx.Tx, accruals []*models.FeeAccrual) error
 }
 
 // virtualPoolTxRepository implements transaction-aware virtual pool operations
@@ -57,13 +108,17 @@ func NewVirtualPoolTxRepository(db *sqlx.DB) VirtualPoolTxRepository {
 // GetPoolByChainIDForUpdate retrieves a virtual pool with FOR UPDATE lock
 func (r *virtualPoolTxRepository) GetPoolByChainIDForUpdate(ctx context.Context, tx *sqlx.Tx, chainID uuid.UUID) (*models.VirtualPool, error) {
 	query := `
-		SELECT id, chain_id, cnpy_reserve, token_reserve, current_price_cnpy, market_cap_usd,
-			   total_volume_cnpy, total_transactions, unique_traders, is_active,
-			   price_24h_change_percent, volume_24h_cnpy, high_24h_cnpy, low_24h_cnpy,
-			   created_at, updated_at
-		FROM virtual_pools
-		WHERE chain_id = $1
-		FOR UPDATE`
+		SELECT vp.id, vp.chain_id, vp.cnpy_reserve, vp.token_reserve, vp.current_price_cnpy,
+			   vp.market_cap_usd, vp.total_volume_cnpy, vp.total_transactions, vp.unique_traders,
+			   vp.is_active, vp.price_24h_change_percent, vp.volume_24h_cnpy, vp.high_24h_cnpy,
+			   vp.low_24h_cnpy, vp.created_at, vp.updated_at, vp.cnpy_reserve_ucnpy,
+			   vp.token_reserve_units, c.token_decimals, c.curve_type, c.bonding_curve_slope,
+			   c.curve_max_price, c.curve_midpoint_supply, c.created_by, c.status AS chain_status,
+			   EXISTS (SELECT 1 FROM chain_graduations g WHERE g.chain_id = vp.chain_id) AS graduation_started
+		FROM virtual_pools vp
+		JOIN chains c ON c.id = vp.chain_id
+		WHERE vp.chain_id = $1
+		FOR UPDATE OF vp`
 
 	var pool models.VirtualPool
 	err := tx.QueryRowxContext(ctx, query, chainID).Scan(
@@ -71,7 +126,10 @@ func (r *virtualPoolTxRepository) GetPoolByChainIDForUpdate(ctx context.Context,
 		&pool.CurrentPriceCNPY, &pool.MarketCapUSD, &pool.TotalVolumeCNPY,
 		&pool.TotalTransactions, &pool.UniqueTraders, &pool.IsActive,
 		&pool.Price24hChangePercent, &pool.Volume24hCNPY, &pool.High24hCNPY,
-		&pool.Low24hCNPY, &pool.CreatedAt, &pool.UpdatedAt,
+		&pool.Low24hCNPY, &pool.CreatedAt, &pool.UpdatedAt, &pool.CNPYReserveMicro,
+		&pool.TokenReserveUnits, &pool.TokenDecimals, &pool.CurveType,
+		&pool.BondingCurveSlope, &pool.CurveMaxPrice, &pool.CurveMidpointSupply,
+		&pool.CreatorID, &pool.ChainStatus, &pool.GraduationStarted,
 	)
 
 	if err != nil {
@@ -105,6 +163,18 @@ func (r *virtualPoolTxRepository) UpdatePoolStateInTx(ctx context.Context, tx *s
 		args = append(args, int64(tokenFloat))
 	}
 
+	if update.CNPYReserveMicro != nil {
+		argCount++
+		query += fmt.Sprintf(", cnpy_reserve_ucnpy = $%d", argCount)
+		args = append(args, *update.CNPYReserveMicro)
+	}
+
+	if update.TokenReserveUnits != nil {
+		argCount++
+		query += fmt.Sprintf(", token_reserve_units = $%d", argCount)
+		args = append(args, *update.TokenReserveUnits)
+	}
+
 	if update.CurrentPriceCNPY != nil {
 		argCount++
 		priceFloat, _ := update.CurrentPriceCNPY.Float64()
@@ -192,9 +262,10 @@ func (r *virtualPoolTxRepository) CreateTransactionInTx(ctx context.Context, tx
 			virtual_pool_id, chain_id, user_id, transaction_type, cnpy_amount,
 			token_amount, price_per_token_cnpy, trading_fee_cnpy, slippage_percent,
 			pool_cnpy_reserve_after, pool_token_reserve_after, market_cap_after_usd,
-			transaction_hash, block_height, gas_used
+			transaction_hash, block_height, gas_used, cnpy_amount_ucnpy, token_amount_units,
+			pool_cnpy_reserve_after_ucnpy, pool_token_reserve_after_units
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
 		) RETURNING id, created_at`
 
 	err := tx.QueryRowxContext(ctx, query,
@@ -213,6 +284,10 @@ func (r *virtualPoolTxRepository) CreateTransactionInTx(ctx context.Context, tx
 		transaction.TransactionHash,
 		transaction.BlockHeight,
 		transaction.GasUsed,
+		transaction.CNPYAmountMicro,
+		transaction.TokenAmountUnits,
+		transaction.PoolCNPYReserveAfterMicro,
+		transaction.PoolTokenReserveAfterUnits,
 	).Scan(&transaction.ID, &transaction.CreatedAt)
 
 	if err != nil {
@@ -222,13 +297,30 @@ func (r *virtualPoolTxRepository) CreateTransactionInTx(ctx context.Context, tx
 	return nil
 }
 
+// TransactionExistsInTx checks for a recorded transaction by hash and block height
+func (r *virtualPoolTxRepository) TransactionExistsInTx(ctx context.Context, tx *sqlx.Tx, txHash string, blockHeight int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM virtual_pool_transactions
+			WHERE transaction_hash = $1 AND block_height = $2
+		)`
+
+	var exists bool
+	if err := tx.QueryRowxContext(ctx, query, txHash, blockHeight).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check transaction in tx: %w", err)
+	}
+
+	return exists, nil
+}
+
 // GetUserPositionForUpdate retrieves a user position with FOR UPDATE lock
 func (r *virtualPoolTxRepository) GetUserPositionForUpdate(ctx context.Context, tx *sqlx.Tx, userID, chainID uuid.UUID) (*models.UserVirtualLPPosition, error) {
 	query := `
 		SELECT id, user_id, chain_id, virtual_pool_id, token_balance, total_cnpy_invested,
 			   total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
 			   realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
-			   last_activity_at, created_at, updated_at
+			   last_activity_at, created_at, updated_at, token_balance_units,
+			   total_cnpy_invested_ucnpy, total_cnpy_withdrawn_ucnpy
 		FROM user_virtual_positions
 		WHERE user_id = $1 AND chain_id = $2
 		FOR UPDATE`
@@ -241,7 +333,8 @@ func (r *virtualPoolTxRepository) GetUserPositionForUpdate(ctx context.Context,
 		&position.TokenBalance, &position.TotalCNPYInvested, &position.TotalCNPYWithdrawn,
 		&position.AverageEntryPriceCNPY, &position.UnrealizedPnlCNPY, &position.RealizedPnlCNPY,
 		&position.TotalReturnPercent, &position.IsActive, &firstPurchaseAt,
-		&lastActivityAt, &position.CreatedAt, &position.UpdatedAt,
+		&lastActivityAt, &position.CreatedAt, &position.UpdatedAt, &position.TokenBalanceUnits,
+		&position.TotalCNPYInvestedMicro, &position.TotalCNPYWithdrawnMicro,
 	)
 
 	if err != nil {
@@ -268,9 +361,10 @@ func (r *virtualPoolTxRepository) UpsertUserPositionInTx(ctx context.Context, tx
 			user_id, chain_id, virtual_pool_id, token_balance, total_cnpy_invested,
 			total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
 			realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
-			last_activity_at
+			last_activity_at, token_balance_units, total_cnpy_invested_ucnpy,
+			total_cnpy_withdrawn_ucnpy
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 		)
 		ON CONFLICT (user_id, chain_id)
 		DO UPDATE SET
@@ -283,6 +377,9 @@ func (r *virtualPoolTxRepository) UpsertUserPositionInTx(ctx context.Context, tx
 			total_return_percent = EXCLUDED.total_return_percent,
 			is_active = EXCLUDED.is_active,
 			last_activity_at = EXCLUDED.last_activity_at,
+			token_balance_units = EXCLUDED.token_balance_units,
+			total_cnpy_invested_ucnpy = EXCLUDED.total_cnpy_invested_ucnpy,
+			total_cnpy_withdrawn_ucnpy = EXCLUDED.total_cnpy_withdrawn_ucnpy,
 			updated_at = CURRENT_TIMESTAMP
 		RETURNING id, created_at, updated_at`
 
@@ -300,6 +397,9 @@ func (r *virtualPoolTxRepository) UpsertUserPositionInTx(ctx context.Context, tx
 		position.IsActive,
 		position.FirstPurchaseAt,
 		position.LastActivityAt,
+		position.TokenBalanceUnits,
+		position.TotalCNPYInvestedMicro,
+		position.TotalCNPYWithdrawnMicro,
 	).Scan(&position.ID, &position.CreatedAt, &position.UpdatedAt)
 
 	if err != nil {
@@ -308,3 +408,433 @@ func (r *virtualPoolTxRepository) UpsertUserPositionInTx(ctx context.Context, tx
 
 	return nil
 }
+
+// PayoutExistsInTx checks for a queued payout by reference
+func (r *virtualPoolTxRepository) PayoutExistsInTx(ctx context.Context, tx *sqlx.Tx, reference string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM payouts WHERE reference = $1)`
+
+	var exists bool
+	if err := tx.QueryRowxContext(ctx, query, reference).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check payout in tx: %w", err)
+	}
+
+	return exists, nil
+}
+
+// CreatePayoutInTx queues a payout within a transaction
+func (r *virtualPoolTxRepository) CreatePayoutInTx(ctx context.Context, tx *sqlx.Tx, payout *models.Payout) error {
+	query := `
+		INSERT INTO payouts (
+			chain_id, user_id, virtual_pool_transaction_id, payout_type,
+			recipient_address, amount, reference, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, next_attempt_at, created_at, updated_at`
+
+	err := tx.QueryRowxContext(ctx, query,
+		payout.ChainID,
+		payout.UserID,
+		payout.VirtualPoolTransactionID,
+		payout.PayoutType,
+		payout.RecipientAddress,
+		payout.Amount,
+		payout.Reference,
+		payout.Status,
+	).Scan(&payout.ID, &payout.NextAttemptAt, &payout.CreatedAt, &payout.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create payout in tx: %w", err)
+	}
+
+	return nil
+}
+
+// GetPoolsByChainIDsForUpdate retrieves several virtual pools with FOR UPDATE locks
+func (r *virtualPoolTxRepository) GetPoolsByChainIDsForUpdate(ctx context.Context, tx *sqlx.Tx, chainIDs []uuid.UUID) ([]models.VirtualPool, error) {
+	query := `
+		SELECT vp.id, vp.chain_id, vp.cnpy_reserve, vp.token_reserve, vp.current_price_cnpy,
+			   vp.market_cap_usd, vp.total_volume_cnpy, vp.total_transactions, vp.unique_traders,
+			   vp.is_active, vp.price_24h_change_percent, vp.volume_24h_cnpy, vp.high_24h_cnpy,
+			   vp.low_24h_cnpy, vp.created_at, vp.updated_at, vp.cnpy_reserve_ucnpy,
+			   vp.token_reserve_units, c.token_decimals, c.curve_type, c.bonding_curve_slope,
+			   c.curve_max_price, c.curve_midpoint_supply, c.created_by, c.status AS chain_status,
+			   EXISTS (SELECT 1 FROM chain_graduations g WHERE g.chain_id = vp.chain_id) AS graduation_started
+		FROM virtual_pools vp
+		JOIN chains c ON c.id = vp.chain_id
+		WHERE vp.chain_id = ANY($1::uuid[])
+		ORDER BY vp.chain_id
+		FOR UPDATE OF vp`
+
+	pools := []models.VirtualPool{}
+	if err := tx.SelectContext(ctx, &pools, query, pq.Array(uuidStrings(chainIDs))); err != nil {
+		return nil, fmt.Errorf("failed to get virtual pools with lock: %w", err)
+	}
+
+	return pools, nil
+}
+
+// AppliedDepositsInTx checks for recorded transactions and refunds by hash
+func (r *virtualPoolTxRepository) AppliedDepositsInTx(ctx context.Context, tx *sqlx.Tx, txHashes []string, blockHeight int64) (map[string]bool, error) {
+	query := `
+		SELECT transaction_hash FROM virtual_pool_transactions
+		WHERE transaction_hash = ANY($1) AND block_height = $2
+		UNION
+		SELECT reference FROM payouts WHERE reference = ANY($1)`
+
+	var hashes []string
+	if err := tx.SelectContext(ctx, &hashes, query, pq.Array(txHashes), blockHeight); err != nil {
+		return nil, fmt.Errorf("failed to check deposits in tx: %w", err)
+	}
+
+	applied := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		applied[hash] = true
+	}
+
+	return applied, nil
+}
+
+// GetUserPositionsForUpdate retrieves several user positions with FOR UPDATE locks
+func (r *virtualPoolTxRepository) GetUserPositionsForUpdate(ctx context.Context, tx *sqlx.Tx, userIDs, chainIDs []uuid.UUID) ([]models.UserVirtualLPPosition, error) {
+	query := `
+		SELECT id, user_id, chain_id, virtual_pool_id, token_balance, total_cnpy_invested,
+			   total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
+			   realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
+			   last_activity_at, created_at, updated_at, token_balance_units,
+			   total_cnpy_invested_ucnpy, total_cnpy_withdrawn_ucnpy
+		FROM user_virtual_positions
+		WHERE (user_id, chain_id) IN (SELECT * FROM unnest($1::uuid[], $2::uuid[]))
+		ORDER BY chain_id, user_id
+		FOR UPDATE`
+
+	positions := []models.UserVirtualLPPosition{}
+	err := tx.SelectContext(ctx, &positions, query, pq.Array(uuidStrings(userIDs)), pq.Array(uuidStrings(chainIDs)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user positions with lock: %w", err)
+	}
+
+	return positions, nil
+}
+
+// CreateTransactionsInTx creates several transaction records within a database transaction
+func (r *virtualPoolTxRepository) CreateTransactionsInTx(ctx context.Context, tx *sqlx.Tx, transactions []*models.VirtualPoolTransaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	n := len(transactions)
+	ids, poolIDs, chainIDs, userIDs := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	types, hashes := make([]string, n), make([]string, n)
+	cnpyAmounts, prices, fees, slippages := make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)
+	reservesCNPY, marketCaps := make([]float64, n), make([]float64, n)
+	tokenAmounts, reservesToken, heights := make([]int64, n), make([]int64, n), make([]int64, n)
+	cnpyMicros, tokenUnits := make([]int64, n), make([]int64, n)
+	reservesCNPYMicro, reservesTokenUnits := make([]int64, n), make([]int64, n)
+	byID := make(map[uuid.UUID]*models.VirtualPoolTransaction, n)
+
+	for i, transaction := range transactions {
+		if transaction.ID == uuid.Nil {
+			transaction.ID = uuid.New()
+		}
+		byID[transaction.ID] = transaction
+
+		ids[i] = transaction.ID.String()
+		poolIDs[i] = transaction.VirtualPoolID.String()
+		chainIDs[i] = transaction.ChainID.String()
+		userIDs[i] = transaction.UserID.String()
+		types[i] = transaction.TransactionType
+		cnpyAmounts[i] = transaction.CNPYAmount
+		tokenAmounts[i] = transaction.TokenAmount
+		prices[i] = transaction.PricePerTokenCNPY
+		fees[i] = transaction.TradingFeeCNPY
+		slippages[i] = transaction.SlippagePercent
+		reservesCNPY[i] = transaction.PoolCNPYReserveAfter
+		reservesToken[i] = transaction.PoolTokenReserveAfter
+		marketCaps[i] = transaction.MarketCapAfterUSD
+		cnpyMicros[i] = int64(transaction.CNPYAmountMicro)
+		tokenUnits[i] = int64(transaction.TokenAmountUnits)
+		reservesCNPYMicro[i] = int64(transaction.PoolCNPYReserveAfterMicro)
+		reservesTokenUnits[i] = int64(transaction.PoolTokenReserveAfterUnits)
+		if transaction.TransactionHash != nil {
+			hashes[i] = *transaction.TransactionHash
+		}
+		if transaction.BlockHeight != nil {
+			heights[i] = *transaction.BlockHeight
+		}
+	}
+
+	query := `
+		INSERT INTO virtual_pool_transactions (
+			id, virtual_pool_id, chain_id, user_id, transaction_type, cnpy_amount,
+			token_amount, price_per_token_cnpy, trading_fee_cnpy, slippage_percent,
+			pool_cnpy_reserve_after, pool_token_reserve_after, market_cap_after_usd,
+			transaction_hash, block_height, cnpy_amount_ucnpy, token_amount_units,
+			pool_cnpy_reserve_after_ucnpy, pool_token_reserve_after_units
+		)
+		SELECT id, virtual_pool_id, chain_id, user_id, transaction_type, cnpy_amount,
+			   token_amount, price_per_token_cnpy, trading_fee_cnpy, slippage_percent,
+			   pool_cnpy_reserve_after, pool_token_reserve_after, market_cap_after_usd,
+			   NULLIF(transaction_hash, ''), NULLIF(block_height, 0), cnpy_amount_ucnpy,
+			   token_amount_units, pool_cnpy_reserve_after_ucnpy, pool_token_reserve_after_units
+		FROM unnest(
+			$1::uuid[], $2::uuid[], $3::uuid[], $4::uuid[], $5::text[], $6::float8[],
+			$7::bigint[], $8::float8[], $9::float8[], $10::float8[],
+			$11::float8[], $12::bigint[], $13::float8[], $14::text[], $15::bigint[],
+			$16::numeric[], $17::numeric[], $18::numeric[], $19::numeric[]
+		) AS t(
+			id, virtual_pool_id, chain_id, user_id, transaction_type, cnpy_amount,
+			token_amount, price_per_token_cnpy, trading_fee_cnpy, slippage_percent,
+			pool_cnpy_reserve_after, pool_token_reserve_after, market_cap_after_usd,
+			transaction_hash, block_height, cnpy_amount_ucnpy, token_amount_units,
+			pool_cnpy_reserve_after_ucnpy, pool_token_reserve_after_units
+		)
+		RETURNING id, created_at`
+
+	rows, err := tx.QueryxContext(ctx, query,
+		pq.Array(ids), pq.Array(poolIDs), pq.Array(chainIDs), pq.Array(userIDs), pq.Array(types),
+		pq.Array(cnpyAmounts), pq.Array(tokenAmounts), pq.Array(prices), pq.Array(fees),
+		pq.Array(slippages), pq.Array(reservesCNPY), pq.Array(reservesToken), pq.Array(marketCaps),
+		pq.Array(hashes), pq.Array(heights), pq.Array(cnpyMicros), pq.Array(tokenUnits),
+		pq.Array(reservesCNPYMicro), pq.Array(reservesTokenUnits),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create transactions in tx: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var createdAt time.Time
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			return fmt.Errorf("failed to scan created transaction: %w", err)
+		}
+		if transaction, ok := byID[id]; ok {
+			transaction.CreatedAt = createdAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to create transactions in tx: %w", err)
+	}
+
+	return nil
+}
+
+// UpsertUserPositionsInTx inserts or updates several user positions within a transaction
+func (r *virtualPoolTxRepository) UpsertUserPositionsInTx(ctx context.Context, tx *sqlx.Tx, positions []*models.UserVirtualLPPosition) error {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	n := len(positions)
+	userIDs, chainIDs, poolIDs := make([]string, n), make([]string, n), make([]string, n)
+	balances, balanceUnits := make([]int64, n), make([]int64, n)
+	investedMicro, withdrawnMicro := make([]int64, n), make([]int64, n)
+	invested, withdrawn, entryPrices := make([]float64, n), make([]float64, n), make([]float64, n)
+	unrealized, realized, returns := make([]float64, n), make([]float64, n), make([]float64, n)
+	active := make([]bool, n)
+	firstPurchases, lastActivities := make([]string, n), make([]string, n)
+
+	for i, position := range positions {
+		userIDs[i] = position.UserID.String()
+		chainIDs[i] = position.ChainID.String()
+		poolIDs[i] = position.VirtualPoolID.String()
+		balances[i] = position.TokenBalance
+		balanceUnits[i] = int64(position.TokenBalanceUnits)
+		investedMicro[i] = int64(position.TotalCNPYInvestedMicro)
+		withdrawnMicro[i] = int64(position.TotalCNPYWithdrawnMicro)
+		invested[i] = position.TotalCNPYInvested
+		withdrawn[i] = position.TotalCNPYWithdrawn
+		entryPrices[i] = position.AverageEntryPriceCNPY
+		unrealized[i] = position.UnrealizedPnlCNPY
+		realized[i] = position.RealizedPnlCNPY
+		returns[i] = position.TotalReturnPercent
+		active[i] = position.IsActive
+		if position.FirstPurchaseAt != nil {
+			firstPurchases[i] = position.FirstPurchaseAt.Format(time.RFC3339Nano)
+		}
+		if position.LastActivityAt != nil {
+			lastActivities[i] = position.LastActivityAt.Format(time.RFC3339Nano)
+		}
+	}
+
+	query := `
+		INSERT INTO user_virtual_positions (
+			user_id, chain_id, virtual_pool_id, token_balance, total_cnpy_invested,
+			total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
+			realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
+			last_activity_at, token_balance_units, total_cnpy_invested_ucnpy,
+			total_cnpy_withdrawn_ucnpy
+		)
+		SELECT user_id, chain_id, virtual_pool_id, token_balance, total_cnpy_invested,
+			   total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
+			   realized_pnl_cnpy, total_return_percent, is_active,
+			   NULLIF(first_purchase_at, '')::timestamptz, NULLIF(last_activity_at, '')::timestamptz,
+			   token_balance_units, total_cnpy_invested_ucnpy, total_cnpy_withdrawn_ucnpy
+		FROM unnest(
+			$1::uuid[], $2::uuid[], $3::uuid[], $4::bigint[], $5::float8[],
+			$6::float8[], $7::float8[], $8::float8[], $9::float8[], $10::float8[],
+			$11::boolean[], $12::text[], $13::text[], $14::numeric[], $15::numeric[],
+			$16::numeric[]
+		) AS p(
+			user_id, chain_id, virtual_pool_id, token_balance, total_cnpy_invested,
+			total_cnpy_withdrawn, average_entry_price_cnpy, unrealized_pnl_cnpy,
+			realized_pnl_cnpy, total_return_percent, is_active, first_purchase_at,
+			last_activity_at, token_balance_units, total_cnpy_invested_ucnpy,
+			total_cnpy_withdrawn_ucnpy
+		)
+		ON CONFLICT (user_id, chain_id)
+		DO UPDATE SET
+			token_balance = EXCLUDED.token_balance,
+			total_cnpy_invested = EXCLUDED.total_cnpy_invested,
+			total_cnpy_withdrawn = EXCLUDED.total_cnpy_withdrawn,
+			average_entry_price_cnpy = EXCLUDED.average_entry_price_cnpy,
+			unrealized_pnl_cnpy = EXCLUDED.unrealized_pnl_cnpy,
+			realized_pnl_cnpy = EXCLUDED.realized_pnl_cnpy,
+			total_return_percent = EXCLUDED.total_return_percent,
+			is_active = EXCLUDED.is_active,
+			last_activity_at = EXCLUDED.last_activity_at,
+			token_balance_units = EXCLUDED.token_balance_units,
+			total_cnpy_invested_ucnpy = EXCLUDED.total_cnpy_invested_ucnpy,
+			total_cnpy_withdrawn_ucnpy = EXCLUDED.total_cnpy_withdrawn_ucnpy,
+			updated_at = CURRENT_TIMESTAMP`
+
+	_, err := tx.ExecContext(ctx, query,
+		pq.Array(userIDs), pq.Array(chainIDs), pq.Array(poolIDs), pq.Array(balances),
+		pq.Array(invested), pq.Array(withdrawn), pq.Array(entryPrices), pq.Array(unrealized),
+		pq.Array(realized), pq.Array(returns), pq.Array(active),
+		pq.Array(firstPurchases), pq.Array(lastActivities), pq.Array(balanceUnits),
+		pq.Array(investedMicro), pq.Array(withdrawnMicro),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert user positions in tx: %w", err)
+	}
+
+	return nil
+}
+
+// CreatePayoutsInTx queues several payouts within a transaction
+func (r *virtualPoolTxRepository) CreatePayoutsInTx(ctx context.Context, tx *sqlx.Tx, payouts []*models.Payout) error {
+	if len(payouts) == 0 {
+		return nil
+	}
+
+	n := len(payouts)
+	chainIDs, userIDs, transactionIDs := make([]string, n), make([]string, n), make([]string, n)
+	types, recipients, references, statuses := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	amounts := make([]int64, n)
+	byReference := make(map[string]*models.Payout, n)
+
+	for i, payout := range payouts {
+		byReference[payout.Reference] = payout
+
+		chainIDs[i] = payout.ChainID.String()
+		userIDs[i] = payout.UserID.String()
+		if payout.VirtualPoolTransactionID != nil {
+			transactionIDs[i] = payout.VirtualPoolTransactionID.String()
+		}
+		types[i] = payout.PayoutType
+		recipients[i] = payout.RecipientAddress
+		amounts[i] = int64(payout.Amount)
+		references[i] = payout.Reference
+		statuses[i] = payout.Status
+	}
+
+	query := `
+		INSERT INTO payouts (
+			chain_id, user_id, virtual_pool_transaction_id, payout_type,
+			recipient_address, amount, reference, status
+		)
+		SELECT chain_id, user_id, NULLIF(virtual_pool_transaction_id, '')::uuid, payout_type,
+			   recipient_address, amount, reference, status
+		FROM unnest(
+			$1::uuid[], $2::uuid[], $3::text[], $4::text[],
+			$5::text[], $6::bigint[], $7::text[], $8::text[]
+		) AS p(
+			chain_id, user_id, virtual_pool_transaction_id, payout_type,
+			recipient_address, amount, reference, status
+		)
+		RETURNING id, reference, next_attempt_at, created_at, updated_at`
+
+	rows, err := tx.QueryxContext(ctx, query,
+		pq.Array(chainIDs), pq.Array(userIDs), pq.Array(transactionIDs), pq.Array(types),
+		pq.Array(recipients), pq.Array(amounts), pq.Array(references), pq.Array(statuses),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create payouts in tx: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var created models.Payout
+		if err := rows.Scan(&created.ID, &created.Reference, &created.NextAttemptAt, &created.CreatedAt, &created.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to scan created payout: %w", err)
+		}
+		if payout, ok := byReference[created.Reference]; ok {
+			payout.ID = created.ID
+			payout.NextAttemptAt = created.NextAttemptAt
+			payout.CreatedAt = created.CreatedAt
+			payout.UpdatedAt = created.UpdatedAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to create payouts in tx: %w", err)
+	}
+
+	return nil
+}
+
+// CreateFeeAccrualsInTx records several fee accruals within a transaction.
+// Accruals without an ID are given one.
+func (r *virtualPoolTxRepository) CreateFeeAccrualsInTx(ctx context.Context, tx *sqlx.Tx, accruals []*models.FeeAccrual) error {
+	if len(accruals) == 0 {
+		return nil
+	}
+
+	n := len(accruals)
+	ids, chainIDs, transactionIDs := make([]string, n), make([]string, n), make([]string, n)
+	types, recipients := make([]string, n), make([]string, n)
+	amounts := make([]int64, n)
+
+	for i, accrual := range accruals {
+		if accrual.ID == uuid.Nil {
+			accrual.ID = uuid.New()
+		}
+
+		ids[i] = accrual.ID.String()
+		chainIDs[i] = accrual.ChainID.String()
+		transactionIDs[i] = accrual.VirtualPoolTransactionID.String()
+		types[i] = accrual.RecipientType
+		if accrual.RecipientUserID != nil {
+			recipients[i] = accrual.RecipientUserID.String()
+		}
+		amounts[i] = int64(accrual.Amount)
+	}
+
+	query := `
+		INSERT INTO fee_accruals (
+			id, chain_id, virtual_pool_transaction_id, recipient_type, recipient_user_id, amount
+		)
+		SELECT id, chain_id, virtual_pool_transaction_id, recipient_type,
+			   NULLIF(recipient_user_id, '')::uuid, amount
+		FROM unnest(
+			$1::uuid[], $2::uuid[], $3::uuid[], $4::text[], $5::text[], $6::bigint[]
+		) AS f(id, chain_id, virtual_pool_transaction_id, recipient_type, recipient_user_id, amount)`
+
+	_, err := tx.ExecContext(ctx, query,
+		pq.Array(ids), pq.Array(chainIDs), pq.Array(transactionIDs),
+		pq.Array(types), pq.Array(recipients), pq.Array(amounts),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create fee accruals in tx: %w", err)
+	}
+
+	return nil
+}
+
+// uuidStrings converts IDs to strings for use as a PostgreSQL uuid[] parameter
+func uuidStrings(ids []uuid.UUID) []string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+	return strs
+}
@@ -28,21 +28,32 @@ type Server struct {
 }
 
 type Services struct {
-	ChainService       *services.ChainService
-	TemplateService    *services.TemplateService
-	AuthService        *services.AuthService
-	VirtualPoolService *services.VirtualPoolService
-	WalletService      *services.WalletService
-	UserService        *services.UserService
+	ChainService         *services.ChainService
+	TemplateService      *services.TemplateService
+	AuthService          *services.AuthService
+	VirtualPoolService   *services.VirtualPoolService
+	WalletService        *services.WalletService
+	UserService          *services.UserService
+	GraduationService    *services.GraduationService
+	GraduatedPoolService *services.GraduatedPoolService
+	DeadLetterService    *services.DeadLetterService
+	FeeService           *services.FeeService
+
+	// RootChainStatus reports each root chain's ingestion progress on /health (optional)
+	RootChainStatus handlers.RootChainStatusProvider
 }
 
 type Handlers struct {
-	ChainHandler       *handlers.ChainHandler
-	TemplateHandler    *handlers.TemplateHandler
-	AuthHandler        *handlers.AuthHandler
-	VirtualPoolHandler *handlers.VirtualPoolHandler
-	WalletHandler      *handlers.WalletHandler
-	UserHandler        *handlers.UserHandler
+	ChainHandler         *handlers.ChainHandler
+	TemplateHandler      *handlers.TemplateHandler
+	AuthHandler          *handlers.AuthHandler
+	VirtualPoolHandler   *handlers.VirtualPoolHandler
+	WalletHandler        *handlers.WalletHandler
+	UserHandler          *handlers.UserHandler
+	GraduationHandler    *handlers.GraduationHandler
+	GraduatedPoolHandler *handlers.GraduatedPoolHandler
+	AdminHandler         *handlers.AdminHandler
+	FeeHandler           *handlers.FeeHandler
 }
 
 func NewServer(cfg *config.Config, services *Services) *Server {
@@ -51,12 +62,16 @@ func NewServer(cfg *config.Config, services *Services) *Server {
 
 	// Create handlers
 	handlers := &Handlers{
-		ChainHandler:       handlers.NewChainHandler(services.ChainService, validator),
-		TemplateHandler:    handlers.NewTemplateHandler(services.TemplateService, validator),
-		AuthHandler:        handlers.NewAuthHandler(services.AuthService, validator),
-		VirtualPoolHandler: handlers.NewVirtualPoolHandler(services.VirtualPoolService, validator),
-		WalletHandler:      handlers.NewWalletHandler(services.WalletService, validator),
-		UserHandler:        handlers.NewUserHandler(services.UserService, validator),
+		ChainHandler:         handlers.NewChainHandler(services.ChainService, validator),
+		TemplateHandler:      handlers.NewTemplateHandler(services.TemplateService, validator),
+		AuthHandler:          handlers.NewAuthHandler(services.AuthService, validator),
+		VirtualPoolHandler:   handlers.NewVirtualPoolHandler(services.VirtualPoolService, validator),
+		WalletHandler:        handlers.NewWalletHandler(services.WalletService, validator),
+		UserHandler:          handlers.NewUserHandler(services.UserService, validator),
+		GraduationHandler:    handlers.NewGraduationHandler(services.GraduationService, validator),
+		GraduatedPoolHandler: handlers.NewGraduatedPoolHandler(services.GraduatedPoolService, validator),
+		AdminHandler:         handlers.NewAdminHandler(services.DeadLetterService, validator),
+		FeeHandler:           handlers.NewFeeHandler(services.FeeService, validator),
 	}
 
 	// Configure rate limiting based on environment
@@ -110,7 +125,7 @@ func (s *Server) setupMiddleware() {
 
 func (s *Server) setupRoutes() {
 	// Health check endpoint
-	s.Router.Get("/health", handlers.HealthCheck)
+	s.Router.Get("/health", handlers.HealthCheck(s.Services.RootChainStatus))
 
 	// Debug/development routes
 	if s.Config.IsDevelopment() {
@@ -133,6 +148,26 @@ func (s *Server) setupRoutes() {
 			// Public read-only endpoints
 			r.Get("/templates", s.Handlers.TemplateHandler.GetTemplates)
 			r.Get("/chains", s.Handlers.ChainHandler.GetChains)
+			r.Get("/chains/{id}/genesis", s.Handlers.GraduationHandler.GetChainGenesis)
+		})
+
+		// Deployer callbacks (authenticated by request signature)
+		r.Group(func(r chi.Router) {
+			r.Use(custommiddleware.DeployerAuthMiddleware(s.Config.GraduationRPCSecret))
+			r.Post("/chains/{id}/graduation/deployment", s.Handlers.GraduationHandler.RecordDeployment)
+		})
+
+		// Admin routes (authenticated by the admin API key)
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(custommiddleware.AdminAuthMiddleware(s.Config.AdminAPIKey))
+
+			// Dead-letter queue of on-chain events that failed to process
+			r.Get("/failed-events", s.Handlers.AdminHandler.GetFailedEvents)
+			r.Post("/failed-events/{id}/retry", s.Handlers.AdminHandler.RetryFailedEvent)
+			r.Post("/failed-events/{id}/discard", s.Handlers.AdminHandler.DiscardFailedEvent)
+
+			// Trading fee balances of the protocol, chain creators and referrers
+			r.Get("/fees", s.Handlers.FeeHandler.GetFeeBalances)
 		})
 
 		// Protected routes (authentication required)
@@ -155,6 +190,7 @@ func (s *Server) setupRoutes() {
 			// User routes
 			r.Route("/users", func(r chi.Router) {
 				r.Put("/profile", s.Handlers.UserHandler.UpdateProfile)
+				r.Get("/fees", s.Handlers.FeeHandler.GetUserFees)
 			})
 
 			// Virtual pool routes
@@ -162,6 +198,19 @@ func (s *Server) setupRoutes() {
 				r.Get("/", s.Handlers.VirtualPoolHandler.GetVirtualPools)
 				r.Route("/{id}", func(r chi.Router) {
 					r.Get("/", s.Handlers.VirtualPoolHandler.GetVirtualPool)
+					r.Get("/pending-deposits", s.Handlers.VirtualPoolHandler.GetPendingDeposits)
+					r.Get("/quote", s.Handlers.VirtualPoolHandler.GetQuote)
+					r.Post("/sell", s.Handlers.VirtualPoolHandler.Sell)
+				})
+			})
+
+			// Graduated pool routes
+			r.Route("/graduated-pools", func(r chi.Router) {
+				r.Get("/", s.Handlers.GraduatedPoolHandler.GetGraduatedPools)
+				r.Route("/{id}", func(r chi.Router) {
+					r.Get("/", s.Handlers.GraduatedPoolHandler.GetGraduatedPool)
+					r.Get("/transactions", s.Handlers.GraduatedPoolHandler.GetGraduatedPoolTransactions)
+					r.Get("/positions", s.Handlers.GraduatedPoolHandler.GetGraduatedPoolPositions)
 				})
 			})
 
@@ -171,6 +220,8 @@ func (s *Server) setupRoutes() {
 				r.Get("/", s.Handlers.ChainHandler.GetChain)
 				r.Delete("/", s.Handlers.ChainHandler.DeleteChain)
 				r.Put("/description", s.Handlers.ChainHandler.UpdateChainDescription)
+				r.Put("/allocation", s.Handlers.ChainHandler.UpdateChainAllocation)
+				r.Put("/vesting", s.Handlers.ChainHandler.UpdateVestingSchedules)
 
 				// Repository endpoints
 				r.Get("/repository", s.Handlers.ChainHandler.GetRepository)
@@ -184,8 +235,15 @@ func (s *Server) setupRoutes() {
 				// Virtual pool endpoints
 				r.Get("/transactions", s.Handlers.ChainHandler.GetTransactions)
 				r.Get("/price-history", s.Handlers.ChainHandler.GetPriceHistory)
+
+				// Graduation progress
+				r.Get("/graduation", s.Handlers.GraduationHandler.GetChainGraduation)
+				r.Get("/graduation/preview", s.Handlers.GraduationHandler.GetGraduationPreview)
 			})
 
+			// Graduation routes
+			r.Get("/graduations", s.Handlers.GraduationHandler.GetGraduations)
+
 			// Wallet routes
 			r.Route("/wallets", func(r chi.Router) {
 				r.Get("/", s.Handlers.WalletHandler.GetWallets)
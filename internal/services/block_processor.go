@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/internal/repository/postgres"
+	"github.com/enielson/launchpad/pkg/bondingcurve"
+	"github.com/enielson/launchpad/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// DepositBlock is every deposit found at one final root chain height
+type DepositBlock struct {
+	RootChainID uint64
+	Height      uint64
+	Deposits    []*BlockDeposit // In block order
+}
+
+// BlockDeposit is a deposit within a block. Refund marks a deposit to a chain
+// that is not virtual_active, which is refunded in full without touching the
+// pool. RawEvent is the root chain transaction in JSON, kept for the
+// dead-letter queue should the deposit fail.
+type BlockDeposit struct {
+	Deposit
+	Refund   bool
+	RawEvent string
+}
+
+// DepositOutcome is what happened to one deposit of a block. Result is set
+// when the deposit bought tokens. Otherwise Err is ErrDepositAlreadyApplied,
+// ErrDepositRefunded, or the reason the deposit was queued for retry.
+type DepositOutcome struct {
+	Deposit *BlockDeposit
+	Result  *bondingcurve.TradeResult
+	Err     error
+}
+
+// BlockProcessor applies a whole root chain block in one database transaction.
+//
+// The pools, positions and already-applied hashes for every deposit in the
+// block are loaded with a few batched queries, the deposits are priced in
+// block order against the in-memory pool state, and the resulting rows are
+// written back in batches together with the root chain checkpoint. A crash
+// therefore never leaves a block half applied: either the block and its
+// checkpoint commit, or nothing does and the height is processed again.
+//
+// Deposits that cannot be applied are recorded in the dead-letter queue inside
+// the same transaction, so a block never fails because of a single deposit.
+type BlockProcessor struct {
+	db           *sqlx.DB
+	processor    *OrderProcessorTx
+	poolRepo     postgres.VirtualPoolTxRepository
+	checkpoints  postgres.RootChainCheckpointTxRepository
+	failedEvents postgres.FailedEventTxRepository
+}
+
+// NewBlockProcessor creates a block processor that prices deposits on each
+// chain's bonding curve with the given order processor's fee config and
+// repository
+func NewBlockProcessor(
+	db *sqlx.DB,
+	processor *OrderProcessorTx,
+	checkpoints postgres.RootChainCheckpointTxRepository,
+	failedEvents postgres.FailedEventTxRepository,
+) *BlockProcessor {
+	return &BlockProcessor{
+		db:           db,
+		processor:    processor,
+		poolRepo:     processor.poolRepo,
+		checkpoints:  checkpoints,
+		failedEvents: failedEvents,
+	}
+}
+
+// ApplyBlockWithRetry applies a block with automatic retry on
+// deadlock/serialization failure. See ApplyBlock.
+func (bp *BlockProcessor) ApplyBlockWithRetry(ctx context.Context, block *DepositBlock) ([]DepositOutcome, error) {
+	var outcomes []DepositOutcome
+	err := withRetry(func() error {
+		var err error
+		outcomes, err = bp.ApplyBlock(ctx, block)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// ApplyBlock applies every deposit in a block and saves the block's height as
+// the root chain checkpoint, all in one transaction. It returns one outcome per
+// deposit, in block order. An error means nothing was written.
+func (bp *BlockProcessor) ApplyBlock(ctx context.Context, block *DepositBlock) ([]DepositOutcome, error) {
+	if block == nil {
+		return nil, fmt.Errorf("%w: no block", ErrInvalidOrder)
+	}
+
+	var outcomes []DepositOutcome
+	err := database.Transaction(bp.db, func(tx *sqlx.Tx) error {
+		var err error
+		outcomes, err = bp.applyBlockInTx(ctx, tx, block)
+		if err != nil {
+			return err
+		}
+		if err := bp.checkpoints.SaveInTx(ctx, tx, block.RootChainID, block.Height); err != nil {
+			return fmt.Errorf("failed to save checkpoint at height %d: %w", block.Height, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return outcomes, nil
+}
+
+// blockState is the in-memory view of the rows a block touches
+type blockState struct {
+	applied   map[string]bool
+	pools     map[uuid.UUID]*models.VirtualPool
+	positions map[positionKey]*models.UserVirtualLPPosition
+
+	transactions []*models.VirtualPoolTransaction
+	accruals     []*models.FeeAccrual
+	changed      []*models.UserVirtualLPPosition
+	payouts      []*models.Payout
+	traded       []*models.VirtualPool
+}
+
+// positionKey identifies a user's position on a chain's pool
+type positionKey struct {
+	userID  uuid.UUID
+	chainID uuid.UUID
+}
+
+// applyBlockInTx loads, applies and writes back every deposit in a block
+func (bp *BlockProcessor) applyBlockInTx(ctx context.Context, tx *sqlx.Tx, block *DepositBlock) ([]DepositOutcome, error) {
+	state, err := bp.loadBlockInTx(ctx, tx, block)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	outcomes := make([]DepositOutcome, 0, len(block.Deposits))
+	for _, deposit := range block.Deposits {
+		result, err := bp.applyDeposit(state, block, deposit, now)
+		outcomes = append(outcomes, DepositOutcome{Deposit: deposit, Result: result, Err: err})
+	}
+
+	if err := bp.writeBlockInTx(ctx, tx, state); err != nil {
+		return nil, err
+	}
+
+	for _, outcome := range outcomes {
+		if outcome.Err == nil || errors.Is(outcome.Err, ErrDepositAlreadyApplied) || errors.Is(outcome.Err, ErrDepositRefunded) {
+			continue
+		}
+		deposit := outcome.Deposit
+		event := NewDepositFailedEvent(deposit.TxHash, &deposit.ChainID, block.Height, deposit.RawEvent, outcome.Err)
+		if err := bp.failedEvents.RecordInTx(ctx, tx, event); err != nil {
+			return nil, fmt.Errorf("failed to queue deposit %s for retry: %w", deposit.TxHash, err)
+		}
+	}
+
+	return outcomes, nil
+}
+
+// loadBlockInTx locks the pools and positions the block trades against and
+// looks up which of its deposits were applied before
+func (bp *BlockProcessor) loadBlockInTx(ctx context.Context, tx *sqlx.Tx, block *DepositBlock) (*blockState, error) {
+	state := &blockState{
+		applied:   make(map[string]bool),
+		pools:     make(map[uuid.UUID]*models.VirtualPool),
+		positions: make(map[positionKey]*models.UserVirtualLPPosition),
+	}
+
+	var hashes []string
+	var chainIDs []uuid.UUID
+	var userIDs, positionChainIDs []uuid.UUID
+	seenChains := make(map[uuid.UUID]bool)
+	seenPositions := make(map[positionKey]bool)
+	for _, deposit := range block.Deposits {
+		if deposit.TxHash != "" {
+			hashes = append(hashes, deposit.TxHash)
+		}
+		if deposit.Refund {
+			continue
+		}
+		if !seenChains[deposit.ChainID] {
+			seenChains[deposit.ChainID] = true
+			chainIDs = append(chainIDs, deposit.ChainID)
+		}
+		key := positionKey{userID: deposit.UserID, chainID: deposit.ChainID}
+		if !seenPositions[key] {
+			seenPositions[key] = true
+			userIDs = append(userIDs, deposit.UserID)
+			positionChainIDs = append(positionChainIDs, deposit.ChainID)
+		}
+	}
+
+	if len(hashes) > 0 {
+		applied, err := bp.poolRepo.AppliedDepositsInTx(ctx, tx, hashes, int64(block.Height))
+		if err != nil {
+			return nil, err
+		}
+		for hash := range applied {
+			state.applied[hash] = applied[hash]
+		}
+	}
+
+	// Pools are locked before positions, in the same order as single trades
+	if len(chainIDs) > 0 {
+		pools, err := bp.poolRepo.GetPoolsByChainIDsForUpdate(ctx, tx, chainIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range pools {
+			state.pools[pools[i].ChainID] = &pools[i]
+		}
+
+		positions, err := bp.poolRepo.GetUserPositionsForUpdate(ctx, tx, userIDs, positionChainIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user positions: %w", err)
+		}
+		for i := range positions {
+			key := positionKey{userID: positions[i].UserID, chainID: positions[i].ChainID}
+			state.positions[key] = &positions[i]
+		}
+	}
+
+	return state, nil
+}
+
+// applyDeposit applies one deposit to the in-memory block state, following the
+// same rules as ProcessDeposit and RefundDeposit. Nothing is written.
+func (bp *BlockProcessor) applyDeposit(state *blockState, block *DepositBlock, deposit *BlockDeposit, now time.Time) (*bondingcurve.TradeResult, error) {
+	if err := validateDeposit(&deposit.Deposit); err != nil {
+		return nil, err
+	}
+	if deposit.BlockHeight != block.Height {
+		return nil, fmt.Errorf("%w: deposit at height %d in block %d", ErrInvalidOrder, deposit.BlockHeight, block.Height)
+	}
+	if state.applied[deposit.TxHash] {
+		return nil, ErrDepositAlreadyApplied
+	}
+	refundable := deposit.Sender != ""
+
+	if deposit.Refund {
+		if !refundable {
+			return nil, fmt.Errorf("%w: deposit has no sender to refund", ErrInvalidOrder)
+		}
+		state.refund(&deposit.Deposit, models.PayoutTypeInactiveRefund, deposit.Amount, nil)
+		return nil, ErrDepositRefunded
+	}
+
+	pool := state.pools[deposit.ChainID]
+	if pool == nil {
+		return nil, fmt.Errorf("%w: no pool for chain %s", ErrPoolNotFound, deposit.ChainID)
+	}
+	if !pool.AcceptsTrades() {
+		if !refundable {
+			return nil, ErrPoolInactive
+		}
+		state.refund(&deposit.Deposit, models.PayoutTypeInactiveRefund, deposit.Amount, nil)
+		return nil, ErrDepositRefunded
+	}
+
+	buyAmount := bp.processor.depositBuyAmount(pool, &deposit.Deposit)
+	if buyAmount == 0 {
+		state.refund(&deposit.Deposit, models.PayoutTypeCapRefund, deposit.Amount, nil)
+		return nil, ErrDepositRefunded
+	}
+
+	trade := depositTrade(pool, &deposit.Deposit, buyAmount)
+	var result *bondingcurve.TradeResult
+	var units *bondingcurve.IntTradeResult
+	err := deposit.checkQuote()
+	if err == nil {
+		result, units, err = bp.processor.priceBuy(pool, trade)
+	}
+	if refundable && missedQuote(err) {
+		state.refund(&deposit.Deposit, models.PayoutTypeSlippageRefund, deposit.Amount, nil)
+		return nil, ErrDepositRefunded
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := positionKey{userID: deposit.UserID, chainID: deposit.ChainID}
+	position, ok := state.positions[key]
+	if !ok || position == nil {
+		position = newPosition(pool, trade, now)
+		state.positions[key] = position
+	}
+	if !state.hasChanged(position) {
+		state.changed = append(state.changed, position)
+	}
+	if !state.hasTraded(pool) {
+		state.traded = append(state.traded, pool)
+	}
+
+	transaction := bp.processor.applyBuy(pool, position, trade, result, units, now)
+	transaction.ID = uuid.New()
+	state.transactions = append(state.transactions, transaction)
+	state.accruals = append(state.accruals, bp.processor.fees.accruals(pool, trade, transaction.ID, units.Fee.Uint64())...)
+	advancePool(pool, units, transaction.CNPYAmount)
+
+	if excess := deposit.Amount - buyAmount; excess > 0 {
+		state.refund(&deposit.Deposit, models.PayoutTypeCapRefund, excess, &transaction.ID)
+	}
+	state.applied[deposit.TxHash] = true
+
+	return result, nil
+}
+
+// refund queues amount uCNPY of a deposit for refund and marks the deposit applied
+func (s *blockState) refund(deposit *Deposit, payoutType string, amount uint64, transactionID *uuid.UUID) {
+	s.payouts = append(s.payouts, newRefund(deposit, payoutType, amount, transactionID))
+	s.applied[deposit.TxHash] = true
+}
+
+// hasChanged reports whether a position is already due to be written
+func (s *blockState) hasChanged(position *models.UserVirtualLPPosition) bool {
+	for _, changed := range s.changed {
+		if changed == position {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTraded reports whether a pool is already due to be written
+func (s *blockState) hasTraded(pool *models.VirtualPool) bool {
+	for _, traded := range s.traded {
+		if traded == pool {
+			return true
+		}
+	}
+	return false
+}
+
+// advancePool moves a pool's in-memory state past a priced trade, so the next
+// deposit in the block is priced against it
+func advancePool(pool *models.VirtualPool, units *bondingcurve.IntTradeResult, cnpyAmount float64) {
+	after := poolUnits(pool).After(units)
+	pool.CNPYReserveMicro = after.CNPYReserve.Uint64()
+	pool.TokenReserveUnits = after.TokenReserve.Uint64()
+	pool.CNPYReserve = cnpy(pool.CNPYReserveMicro)
+	pool.TokenReserve = wholeTokens(pool.TokenReserveUnits, pool.TokenDecimals)
+	pool.CurrentPriceCNPY, _ = units.Price.Float64()
+	pool.MarketCapUSD = pool.CNPYReserve
+	pool.TotalVolumeCNPY += cnpyAmount
+	pool.TotalTransactions++
+}
+
+// poolStateUpdate is the update that writes a pool's in-memory trading state
+func poolStateUpdate(pool *models.VirtualPool) *interfaces.PoolStateUpdate {
+	cnpyReserve := pool.CNPYReserveMicro
+	tokenReserve := pool.TokenReserveUnits
+	totalTransactions := pool.TotalTransactions
+	return &interfaces.PoolStateUpdate{
+		CNPYReserve:       big.NewFloat(pool.CNPYReserve),
+		TokenReserve:      new(big.Float).SetInt64(pool.TokenReserve),
+		CNPYReserveMicro:  &cnpyReserve,
+		TokenReserveUnits: &tokenReserve,
+		CurrentPriceCNPY:  big.NewFloat(pool.CurrentPriceCNPY),
+		MarketCapUSD:      big.NewFloat(pool.MarketCapUSD),
+		TotalVolumeCNPY:   big.NewFloat(pool.TotalVolumeCNPY),
+		TotalTransactions: &totalTransactions,
+	}
+}
+
+// writeBlockInTx writes the rows produced by a block's deposits
+func (bp *BlockProcessor) writeBlockInTx(ctx context.Context, tx *sqlx.Tx, state *blockState) error {
+	if len(state.transactions) > 0 {
+		if err := bp.poolRepo.CreateTransactionsInTx(ctx, tx, state.transactions); err != nil {
+			return fmt.Errorf("failed to create transactions: %w", err)
+		}
+	}
+	if len(state.accruals) > 0 {
+		if err := bp.poolRepo.CreateFeeAccrualsInTx(ctx, tx, state.accruals); err != nil {
+			return fmt.Errorf("failed to record fee accruals: %w", err)
+		}
+	}
+	if len(state.changed) > 0 {
+		if err := bp.poolRepo.UpsertUserPositionsInTx(ctx, tx, state.changed); err != nil {
+			return fmt.Errorf("failed to update user positions: %w", err)
+		}
+	}
+	if len(state.payouts) > 0 {
+		if err := bp.poolRepo.CreatePayoutsInTx(ctx, tx, state.payouts); err != nil {
+			return fmt.Errorf("failed to queue refunds: %w", err)
+		}
+	}
+
+	for _, pool := range state.traded {
+		if err := bp.poolRepo.UpdatePoolStateInTx(ctx, tx, pool.ChainID, poolStateUpdate(pool)); err != nil {
+			return fmt.Errorf("failed to update pool state: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/internal/testutil/mocks"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockCheckpointTxRepository mocks the RootChainCheckpointTxRepository
+type MockCheckpointTxRepository struct {
+	mock.Mock
+}
+
+func (m *MockCheckpointTxRepository) Get(ctx context.Context, rootChainID uint64) (*models.RootChainCheckpoint, error) {
+	args := m.Called(ctx, rootChainID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RootChainCheckpoint), args.Error(1)
+}
+
+func (m *MockCheckpointTxRepository) Save(ctx context.Context, rootChainID uint64, height uint64) error {
+	args := m.Called(ctx, rootChainID, height)
+	return args.Error(0)
+}
+
+func (m *MockCheckpointTxRepository) SaveInTx(ctx context.Context, tx *sqlx.Tx, rootChainID uint64, height uint64) error {
+	args := m.Called(ctx, tx, rootChainID, height)
+	return args.Error(0)
+}
+
+// MockFailedEventTxRepository mocks the FailedEventTxRepository
+type MockFailedEventTxRepository struct {
+	mocks.MockFailedEventRepository // Embed to get base methods
+}
+
+func (m *MockFailedEventTxRepository) RecordInTx(ctx context.Context, tx *sqlx.Tx, event *models.FailedEvent) error {
+	args := m.Called(ctx, tx, event)
+	return args.Error(0)
+}
+
+func TestBlockProcessor_ApplyBlock(t *testing.T) {
+	const rootChainID = 1
+	const height = 1000
+	chainID := uuid.New()
+	alice := uuid.New()
+	bob := uuid.New()
+	sender := "0x" + strings.Repeat("ab", 20)
+
+	newPool := func() *models.VirtualPool {
+		return &models.VirtualPool{
+			ID:                uuid.New(),
+			ChainID:           chainID,
+			CNPYReserve:       30,
+			TokenReserve:      800000000,
+			CNPYReserveMicro:  30000000,
+			TokenReserveUnits: 800000000000000,
+			TokenDecimals:     6,
+			TotalVolumeCNPY:   10,
+			TotalTransactions: 4,
+			IsActive:          true,
+			ChainStatus:       models.ChainStatusVirtualActive,
+		}
+	}
+	deposit := func(userID uuid.UUID, hash string, amount uint64) *BlockDeposit {
+		return &BlockDeposit{
+			Deposit: Deposit{
+				ChainID:     chainID,
+				UserID:      userID,
+				Amount:      amount,
+				TxHash:      hash,
+				BlockHeight: height,
+				Sender:      sender,
+			},
+			RawEvent: `{"txHash":"` + hash + `"}`,
+		}
+	}
+
+	newProcessor := func(t *testing.T) (*BlockProcessor, *MockVirtualPoolTxRepository, *MockCheckpointTxRepository, *MockFailedEventTxRepository, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		poolRepo := new(MockVirtualPoolTxRepository)
+		checkpoints := new(MockCheckpointTxRepository)
+		failedEvents := new(MockFailedEventTxRepository)
+		sqlxDB := sqlx.NewDb(db, "sqlmock")
+		processor := NewOrderProcessorTx(sqlxDB, poolRepo, new(MockUserRepository), nil)
+		return NewBlockProcessor(sqlxDB, processor, checkpoints, failedEvents), poolRepo, checkpoints, failedEvents, mock
+	}
+
+	t.Run("deposits are priced in block order and written together", func(t *testing.T) {
+		processor, poolRepo, checkpoints, _, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		pool := newPool()
+		pool.CreatorID = uuid.New()
+		block := &DepositBlock{RootChainID: rootChainID, Height: height, Deposits: []*BlockDeposit{
+			deposit(alice, "0x01", 1000000),
+			deposit(bob, "0x02", 1000000),
+			deposit(alice, "0x03", 2000000),
+		}}
+
+		existing := models.UserVirtualLPPosition{UserID: bob, ChainID: chainID, VirtualPoolID: pool.ID, TokenBalance: 500, TotalCNPYInvested: 0.5, TokenBalanceUnits: 500000000, TotalCNPYInvestedMicro: 500000, IsActive: true}
+		poolRepo.On("AppliedDepositsInTx", mock.Anything, mock.Anything, []string{"0x01", "0x02", "0x03"}, int64(height)).Return(map[string]bool{}, nil).Once()
+		poolRepo.On("GetPoolsByChainIDsForUpdate", mock.Anything, mock.Anything, []uuid.UUID{chainID}).Return([]models.VirtualPool{*pool}, nil).Once()
+		poolRepo.On("GetUserPositionsForUpdate", mock.Anything, mock.Anything, []uuid.UUID{alice, bob}, []uuid.UUID{chainID, chainID}).
+			Return([]models.UserVirtualLPPosition{existing}, nil).Once()
+
+		var transactions []*models.VirtualPoolTransaction
+		poolRepo.On("CreateTransactionsInTx", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			transactions = args.Get(2).([]*models.VirtualPoolTransaction)
+		}).Return(nil).Once()
+		var accruals []*models.FeeAccrual
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			accruals = args.Get(2).([]*models.FeeAccrual)
+		}).Return(nil).Once()
+		var positions []*models.UserVirtualLPPosition
+		poolRepo.On("UpsertUserPositionsInTx", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			positions = args.Get(2).([]*models.UserVirtualLPPosition)
+		}).Return(nil).Once()
+		var update *interfaces.PoolStateUpdate
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.Anything).Run(func(args mock.Arguments) {
+			update = args.Get(3).(*interfaces.PoolStateUpdate)
+		}).Return(nil).Once()
+		checkpoints.On("SaveInTx", mock.Anything, mock.Anything, uint64(rootChainID), uint64(height)).Return(nil).Once()
+
+		outcomes, err := processor.ApplyBlock(context.Background(), block)
+		require.NoError(t, err)
+		require.Len(t, outcomes, 3)
+
+		// Each deposit is priced against the pool the previous one left behind,
+		// exactly as if they had been applied one at a time
+		reserve := big.NewFloat(30)
+		for i, outcome := range outcomes {
+			require.NoError(t, outcome.Err)
+			assert.Same(t, block.Deposits[i], outcome.Deposit)
+			assert.Equal(t, 1, outcome.Result.NewCNPYReserve.Cmp(reserve), "deposit %d", i)
+			reserve = outcome.Result.NewCNPYReserve
+		}
+		// The 1% fee leaves the pool with the fee split
+		finalReserve, _ := reserve.Float64()
+		assert.InDelta(t, 33.96, finalReserve, 1e-9)
+
+		require.Len(t, transactions, 3)
+		for i, transaction := range transactions {
+			assert.NotEqual(t, uuid.Nil, transaction.ID)
+			assert.Equal(t, block.Deposits[i].TxHash, *transaction.TransactionHash)
+			assert.Equal(t, int64(height), *transaction.BlockHeight)
+		}
+		assert.InDelta(t, finalReserve, transactions[2].PoolCNPYReserveAfter, 1e-9)
+		assert.InDelta(t, 0.02, transactions[2].TradingFeeCNPY, 1e-9)
+
+		// Each fee is split between the chain's creator and the protocol
+		require.Len(t, accruals, 6)
+		for i, transaction := range transactions {
+			creator, protocol := accruals[2*i], accruals[2*i+1]
+			fee := uint64(transaction.TradingFeeCNPY * 1e6)
+			assert.Equal(t, transaction.ID, creator.VirtualPoolTransactionID)
+			assert.Equal(t, models.FeeRecipientCreator, creator.RecipientType)
+			assert.Equal(t, pool.CreatorID, *creator.RecipientUserID)
+			assert.Equal(t, fee*4/10, creator.Amount)
+			assert.Equal(t, transaction.ID, protocol.VirtualPoolTransactionID)
+			assert.Equal(t, models.FeeRecipientProtocol, protocol.RecipientType)
+			assert.Nil(t, protocol.RecipientUserID)
+			assert.Equal(t, fee*6/10, protocol.Amount)
+		}
+
+		// A user with several deposits has one position, written once
+		require.Len(t, positions, 2)
+		assert.Equal(t, alice, positions[0].UserID)
+		assert.InDelta(t, 3, positions[0].TotalCNPYInvested, 1e-9)
+		assert.Equal(t, bob, positions[1].UserID)
+		assert.InDelta(t, 1.5, positions[1].TotalCNPYInvested, 1e-9)
+		assert.Greater(t, positions[1].TokenBalance, int64(500))
+
+		require.NotNil(t, update)
+		assert.Equal(t, 7, *update.TotalTransactions)
+		totalVolume, _ := update.TotalVolumeCNPY.Float64()
+		assert.InDelta(t, 14, totalVolume, 1e-9)
+		updatedReserve, _ := update.CNPYReserve.Float64()
+		assert.InDelta(t, finalReserve, updatedReserve, 1e-9)
+
+		poolRepo.AssertNotCalled(t, "CreatePayoutsInTx", mock.Anything, mock.Anything, mock.Anything)
+		poolRepo.AssertExpectations(t)
+		checkpoints.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("applied, refunded and failed deposits", func(t *testing.T) {
+		processor, poolRepo, checkpoints, failedEvents, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		otherChain := uuid.New()
+		pool := newPool()
+		pool.CNPYReserve, pool.CNPYReserveMicro = 49.5, 49500000
+
+		applied := deposit(alice, "0x01", 1000000)
+		inactive := deposit(alice, "0x02", 1000000)
+		inactive.Refund = true
+		capped := deposit(alice, "0x03", 2000000) // 0.5 CNPY of room below the threshold, before the fee
+		capped.GraduationThreshold = 50
+		full := deposit(bob, "0x04", 1000000) // Arrives after the pool reached the threshold
+		full.GraduationThreshold = 50
+		unknown := deposit(bob, "0x05", 1000000)
+		unknown.ChainID = otherChain
+		repeat := deposit(alice, "0x03", 2000000) // Same send delivered twice in one block
+		block := &DepositBlock{RootChainID: rootChainID, Height: height, Deposits: []*BlockDeposit{applied, inactive, capped, full, unknown, repeat}}
+
+		poolRepo.On("AppliedDepositsInTx", mock.Anything, mock.Anything, mock.Anything, int64(height)).Return(map[string]bool{"0x01": true}, nil).Once()
+		poolRepo.On("GetPoolsByChainIDsForUpdate", mock.Anything, mock.Anything, []uuid.UUID{chainID, otherChain}).Return([]models.VirtualPool{*pool}, nil).Once()
+		poolRepo.On("GetUserPositionsForUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+		poolRepo.On("CreateTransactionsInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(transactions []*models.VirtualPoolTransaction) bool {
+			return len(transactions) == 1 && *transactions[0].TransactionHash == "0x03" && transactions[0].CNPYAmount == 0.505051
+		})).Return(nil).Once()
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		poolRepo.On("UpsertUserPositionsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		var payouts []*models.Payout
+		poolRepo.On("CreatePayoutsInTx", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			payouts = args.Get(2).([]*models.Payout)
+		}).Return(nil).Once()
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.Anything).Return(nil).Once()
+		failedEvents.On("RecordInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(event *models.FailedEvent) bool {
+			return event.EventType == models.FailedEventTypeDeposit &&
+				event.Reference == "0x05" &&
+				*event.ChainID == otherChain &&
+				*event.BlockHeight == height &&
+				event.RawEvent == unknown.RawEvent &&
+				strings.Contains(event.LastError, ErrPoolNotFound.Error())
+		})).Return(nil).Once()
+		checkpoints.On("SaveInTx", mock.Anything, mock.Anything, uint64(rootChainID), uint64(height)).Return(nil).Once()
+
+		outcomes, err := processor.ApplyBlock(context.Background(), block)
+		require.NoError(t, err)
+		require.Len(t, outcomes, 6)
+		assert.ErrorIs(t, outcomes[0].Err, ErrDepositAlreadyApplied)
+		assert.ErrorIs(t, outcomes[1].Err, ErrDepositRefunded)
+		require.NoError(t, outcomes[2].Err)
+		assert.NotNil(t, outcomes[2].Result)
+		assert.ErrorIs(t, outcomes[3].Err, ErrDepositRefunded)
+		assert.ErrorIs(t, outcomes[4].Err, ErrPoolNotFound)
+		assert.ErrorIs(t, outcomes[5].Err, ErrDepositAlreadyApplied)
+
+		require.Len(t, payouts, 3)
+		assert.Equal(t, models.PayoutTypeInactiveRefund, payouts[0].PayoutType)
+		assert.Equal(t, "0x02", payouts[0].Reference)
+		assert.Equal(t, uint64(1000000), payouts[0].Amount)
+		assert.Nil(t, payouts[0].VirtualPoolTransactionID)
+		// The excess over the threshold is refunded against the trade that took the rest
+		assert.Equal(t, models.PayoutTypeCapRefund, payouts[1].PayoutType)
+		assert.Equal(t, "0x03", payouts[1].Reference)
+		assert.Equal(t, uint64(1494949), payouts[1].Amount)
+		require.NotNil(t, payouts[1].VirtualPoolTransactionID)
+		assert.Equal(t, models.PayoutTypeCapRefund, payouts[2].PayoutType)
+		assert.Equal(t, "0x04", payouts[2].Reference)
+		assert.Equal(t, uint64(1000000), payouts[2].Amount)
+		assert.Equal(t, sender, payouts[2].RecipientAddress)
+
+		poolRepo.AssertExpectations(t)
+		failedEvents.AssertExpectations(t)
+		checkpoints.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("deposits that cannot be filled as quoted are refunded in full", func(t *testing.T) {
+		processor, poolRepo, checkpoints, _, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		blockTime := time.Unix(1700000000, 0)
+		quoted := func(userID uuid.UUID, hash string, minTokens uint64, expiresAt time.Time) *BlockDeposit {
+			d := deposit(userID, hash, 1000000)
+			d.Quote = &Quote{ChainID: chainID, Side: models.VirtualTransactionTypeBuy, AmountIn: 1000000, MinAmountOut: minTokens * 1000000, ExpiresAt: expiresAt.Unix()}
+			d.BlockTime = blockTime
+			return d
+		}
+		// 1 CNPY buys about 25.5M tokens from the pool
+		filled := quoted(alice, "0x01", 25000000, blockTime.Add(time.Minute))
+		short := quoted(bob, "0x02", 26000000, blockTime.Add(time.Minute))
+		late := quoted(bob, "0x03", 1, blockTime)
+		block := &DepositBlock{RootChainID: rootChainID, Height: height, Deposits: []*BlockDeposit{filled, short, late}}
+
+		poolRepo.On("AppliedDepositsInTx", mock.Anything, mock.Anything, mock.Anything, int64(height)).Return(map[string]bool{}, nil).Once()
+		poolRepo.On("GetPoolsByChainIDsForUpdate", mock.Anything, mock.Anything, []uuid.UUID{chainID}).Return([]models.VirtualPool{*newPool()}, nil).Once()
+		poolRepo.On("GetUserPositionsForUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+		poolRepo.On("CreateTransactionsInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(transactions []*models.VirtualPoolTransaction) bool {
+			return len(transactions) == 1 && *transactions[0].TransactionHash == "0x01"
+		})).Return(nil).Once()
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		poolRepo.On("UpsertUserPositionsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		var payouts []*models.Payout
+		poolRepo.On("CreatePayoutsInTx", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			payouts = args.Get(2).([]*models.Payout)
+		}).Return(nil).Once()
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.Anything).Return(nil).Once()
+		checkpoints.On("SaveInTx", mock.Anything, mock.Anything, uint64(rootChainID), uint64(height)).Return(nil).Once()
+
+		outcomes, err := processor.ApplyBlock(context.Background(), block)
+		require.NoError(t, err)
+		require.Len(t, outcomes, 3)
+		require.NoError(t, outcomes[0].Err)
+		assert.ErrorIs(t, outcomes[1].Err, ErrDepositRefunded)
+		assert.ErrorIs(t, outcomes[2].Err, ErrDepositRefunded)
+
+		require.Len(t, payouts, 2)
+		for i, hash := range []string{"0x02", "0x03"} {
+			assert.Equal(t, models.PayoutTypeSlippageRefund, payouts[i].PayoutType)
+			assert.Equal(t, hash, payouts[i].Reference)
+			assert.Equal(t, uint64(1000000), payouts[i].Amount)
+			assert.Nil(t, payouts[i].VirtualPoolTransactionID)
+		}
+
+		poolRepo.AssertExpectations(t)
+		checkpoints.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("write failure rolls back the block and its checkpoint", func(t *testing.T) {
+		processor, poolRepo, checkpoints, _, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		block := &DepositBlock{RootChainID: rootChainID, Height: height, Deposits: []*BlockDeposit{deposit(alice, "0x01", 1000000)}}
+		poolRepo.On("AppliedDepositsInTx", mock.Anything, mock.Anything, mock.Anything, int64(height)).Return(map[string]bool{}, nil)
+		poolRepo.On("GetPoolsByChainIDsForUpdate", mock.Anything, mock.Anything, mock.Anything).Return([]models.VirtualPool{*newPool()}, nil)
+		poolRepo.On("GetUserPositionsForUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+		poolRepo.On("CreateTransactionsInTx", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("duplicate key value"))
+
+		outcomes, err := processor.ApplyBlock(context.Background(), block)
+		assert.Nil(t, outcomes)
+		assert.ErrorContains(t, err, "failed to create transactions")
+		poolRepo.AssertNotCalled(t, "UpdatePoolStateInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		checkpoints.AssertNotCalled(t, "SaveInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("dead-letter failure rolls back the block", func(t *testing.T) {
+		processor, poolRepo, checkpoints, failedEvents, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		inactive := newPool()
+		inactive.IsActive = false
+		orphan := deposit(alice, "0x01", 1000000)
+		orphan.Sender = "" // Nowhere to refund to
+		block := &DepositBlock{RootChainID: rootChainID, Height: height, Deposits: []*BlockDeposit{orphan}}
+		poolRepo.On("AppliedDepositsInTx", mock.Anything, mock.Anything, mock.Anything, int64(height)).Return(map[string]bool{}, nil)
+		poolRepo.On("GetPoolsByChainIDsForUpdate", mock.Anything, mock.Anything, mock.Anything).Return([]models.VirtualPool{*inactive}, nil)
+		poolRepo.On("GetUserPositionsForUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+		failedEvents.On("RecordInTx", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("connection refused"))
+
+		_, err := processor.ApplyBlock(context.Background(), block)
+		assert.ErrorContains(t, err, "failed to queue deposit 0x01 for retry")
+		checkpoints.AssertNotCalled(t, "SaveInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("empty block only saves the checkpoint", func(t *testing.T) {
+		processor, poolRepo, checkpoints, _, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		checkpoints.On("SaveInTx", mock.Anything, mock.Anything, uint64(rootChainID), uint64(height)).Return(nil).Once()
+
+		outcomes, err := processor.ApplyBlock(context.Background(), &DepositBlock{RootChainID: rootChainID, Height: height})
+		require.NoError(t, err)
+		assert.Empty(t, outcomes)
+		assert.Empty(t, poolRepo.Calls)
+		checkpoints.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/canopy-network/canopy/lib/crypto"
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/pkg/bondingcurve"
 	"github.com/enielson/launchpad/pkg/keygen"
 	"github.com/google/uuid"
 )
@@ -21,6 +24,11 @@ var (
 	ErrUnauthorized          = errors.New("unauthorized")
 	ErrRepositoryNotFound    = errors.New("repository not found")
 	ErrAssetNotFound         = errors.New("asset not found")
+	ErrInvalidAllocation     = errors.New("invalid token allocation")
+	ErrInvalidVesting        = errors.New("invalid vesting schedule")
+	ErrUnknownRootChain      = errors.New("unknown root chain")
+	ErrInvalidCurve          = errors.New("invalid bonding curve")
+	ErrNoChainKeyPassword    = errors.New("chain key password not configured")
 )
 
 type ChainService struct {
@@ -28,6 +36,8 @@ type ChainService struct {
 	templateRepo    interfaces.ChainTemplateRepository
 	userRepo        interfaces.UserRepository
 	virtualPoolRepo interfaces.VirtualPoolRepository
+	rootChainIDs    []uint64 // root chains new chains may be created on; the first is the default
+	keyPassword     string   // encrypts the keys generated for chains
 }
 
 func NewChainService(chainRepo interfaces.ChainRepository, templateRepo interfaces.ChainTemplateRepository, userRepo interfaces.UserRepository, virtualPoolRepo interfaces.VirtualPoolRepository) *ChainService {
@@ -39,6 +49,20 @@ func NewChainService(chainRepo interfaces.ChainRepository, templateRepo interfac
 	}
 }
 
+// SetRootChains sets the root chains the launchpad serves. New chains are
+// created on the first one unless the request names another. Without root
+// chains, requests may name any root chain and default to
+// models.DefaultRootChainID.
+func (s *ChainService) SetRootChains(rootChainIDs ...uint64) {
+	s.rootChainIDs = rootChainIDs
+}
+
+// SetKeyPassword sets the password the keys generated for chains are encrypted
+// with. Chains cannot be created until it is set.
+func (s *ChainService) SetKeyPassword(password string) {
+	s.keyPassword = password
+}
+
 // CreateChain creates a new chain
 func (s *ChainService) CreateChain(ctx context.Context, req *models.CreateChainRequest, userID string) (*models.Chain, error) {
 	// Parse user ID
@@ -67,6 +91,11 @@ func (s *ChainService) CreateChain(ctx context.Context, req *models.CreateChainR
 		}
 	}
 
+	rootChainID, err := s.resolveRootChain(req.RootChainID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if chain name already exists
 	existingChain, err := s.chainRepo.GetByName(ctx, req.ChainName)
 	if err == nil && existingChain != nil {
@@ -89,6 +118,7 @@ func (s *ChainService) CreateChain(ctx context.Context, req *models.CreateChainR
 		TemplateID:                 templateID,
 		ConsensusMechanism:         s.getStringValueOrDefault(&req.ConsensusMechanism, defaultConsensus),
 		TokenTotalSupply:           s.getInt64ValueOrDefault(req.TokenTotalSupply, defaultTokenSupply),
+		TokenDecimals:              s.getIntValueOrDefault(req.TokenDecimals, models.DefaultTokenDecimals),
 		BlockTimeSeconds:           req.BlockTimeSeconds,
 		UpgradeBlockHeight:         req.UpgradeBlockHeight,
 		BlockRewardAmount:          req.BlockRewardAmount,
@@ -97,13 +127,34 @@ func (s *ChainService) CreateChain(ctx context.Context, req *models.CreateChainR
 		InitialCNPYReserve:         s.getFloat64ValueOrDefault(req.InitialCNPYReserve, 10000.00000000),
 		InitialTokenSupply:         s.getInt64ValueOrDefault(req.InitialTokenSupply, 800000000),
 		BondingCurveSlope:          s.getFloat64ValueOrDefault(req.BondingCurveSlope, 0.00000001),
+		CurveType:                  s.getStringValueOrDefault(req.CurveType, models.CurveTypeConstantProduct),
+		CurveMaxPrice:              req.CurveMaxPrice,
+		CurveMidpointSupply:        req.CurveMidpointSupply,
 		ValidatorMinStake:          s.getFloat64ValueOrDefault(req.ValidatorMinStake, 1000.00000000),
 		CreatorInitialPurchaseCNPY: s.getFloat64ValueOrDefault(req.CreatorInitialPurchaseCNPY, 0),
 		Status:                     models.ChainStatusDraft,
 		IsGraduated:                false,
+		RootChainID:                rootChainID,
 		CreatedBy:                  createdBy,
 	}
 
+	if err := validateCurve(chain); err != nil {
+		return nil, err
+	}
+
+	// Resolve the genesis token allocation
+	chain.TokenAllocation = models.DefaultTokenAllocation(chain.TokenTotalSupply, chain.InitialTokenSupply)
+	if req.Allocation != nil {
+		chain.TokenAllocation = models.TokenAllocation(*req.Allocation)
+	}
+	if err := validateAllocation(chain); err != nil {
+		return nil, err
+	}
+	vestingSchedules := vestingSchedulesFromRequest(req.VestingSchedules)
+	if err := validateVestingSchedules(chain, vestingSchedules); err != nil {
+		return nil, err
+	}
+
 	// Save to database
 	createdChain, err := s.chainRepo.Create(ctx, chain)
 	if err != nil {
@@ -111,53 +162,27 @@ func (s *ChainService) CreateChain(ctx context.Context, req *models.CreateChainR
 	}
 
 	// Generate and encrypt keypair for the chain
-	// Using a default password for now - in production, this should be configurable or derived from user secrets
-	defaultPassword := "changeme" // TODO: make this configurable or user-provided
-	_, encryptedKeyPair, err := keygen.GenerateEncryptedKeyPair(defaultPassword)
-	if err != nil {
-		// Rollback chain creation on key generation failure
+	if err := s.createChainKey(ctx, createdChain.ID, models.KeyPurposeChainOperation); err != nil {
+		// Rollback chain creation on key failure
 		// In production, this should use a transaction
 		_ = s.chainRepo.Delete(ctx, createdChain.ID)
-		return nil, fmt.Errorf("failed to generate chain keypair: %w", err)
-	}
-
-	// Convert encrypted keypair to ChainKey model
-	// addressBytes, err := hex.DecodeString(encryptedKeyPair.Address)
-	// if err != nil {
-	// 	_ = s.chainRepo.Delete(ctx, createdChain.ID)
-	// 	return nil, fmt.Errorf("failed to decode address: %w", err)
-	// }
-
-	publicKeyBytes, err := hex.DecodeString(encryptedKeyPair.PublicKey)
-	if err != nil {
-		_ = s.chainRepo.Delete(ctx, createdChain.ID)
-		return nil, fmt.Errorf("failed to decode public key: %w", err)
-	}
-
-	saltBytes, err := hex.DecodeString(encryptedKeyPair.Salt)
-	if err != nil {
-		_ = s.chainRepo.Delete(ctx, createdChain.ID)
-		return nil, fmt.Errorf("failed to decode salt: %w", err)
+		return nil, err
 	}
 
-	chainKey := &models.ChainKey{
-		ChainID:             createdChain.ID,
-		Address:             encryptedKeyPair.Address,
-		PublicKey:           publicKeyBytes,
-		EncryptedPrivateKey: encryptedKeyPair.EncryptedPrivateKey,
-		Salt:                saltBytes,
-		KeyNickname:         nil,
-		KeyPurpose:          models.KeyPurposeChainOperation,
-		IsActive:            true,
-		RotationCount:       0,
+	// The treasury bucket is paid to its own key at genesis
+	if createdChain.TreasuryBps > 0 {
+		if err := s.createChainKey(ctx, createdChain.ID, models.KeyPurposeTreasury); err != nil {
+			_ = s.chainRepo.Delete(ctx, createdChain.ID)
+			return nil, err
+		}
 	}
 
-	// Save chain key to database
-	_, err = s.chainRepo.CreateChainKey(ctx, chainKey)
-	if err != nil {
-		// Rollback chain creation on key storage failure
-		_ = s.chainRepo.Delete(ctx, createdChain.ID)
-		return nil, fmt.Errorf("failed to store chain key: %w", err)
+	// Store vesting schedules
+	if len(vestingSchedules) > 0 {
+		if _, err := s.chainRepo.ReplaceVestingSchedules(ctx, createdChain.ID, vestingSchedules); err != nil {
+			_ = s.chainRepo.Delete(ctx, createdChain.ID)
+			return nil, fmt.Errorf("failed to store vesting schedules: %w", err)
+		}
 	}
 
 	// Create GitHub repository if provided
@@ -296,7 +321,7 @@ func (s *ChainService) CreateChain(ctx context.Context, req *models.CreateChainR
 	}
 
 	// Load relations for response (only load what's available)
-	includeRelations := []string{}
+	includeRelations := []string{"vesting_schedules"}
 	if s.templateRepo != nil {
 		includeRelations = append(includeRelations, "template")
 	}
@@ -351,9 +376,10 @@ func (s *ChainService) GetChainByID(ctx context.Context, id string, include stri
 		return nil, fmt.Errorf("invalid chain ID: %w", err)
 	}
 
-	includeRelations := []string{}
+	// Vesting schedules are always part of the chain detail
+	includeRelations := []string{"vesting_schedules"}
 	if include != "" {
-		includeRelations = strings.Split(include, ",")
+		includeRelations = append(includeRelations, strings.Split(include, ",")...)
 	}
 
 	chain, err := s.chainRepo.GetByID(ctx, chainID, includeRelations)
@@ -419,6 +445,80 @@ func (s *ChainService) UpdateChainDescription(ctx context.Context, chainID strin
 	return s.chainRepo.GetByID(ctx, chain.ID, nil)
 }
 
+// UpdateChainAllocation replaces the genesis token allocation of a draft chain
+func (s *ChainService) UpdateChainAllocation(ctx context.Context, chainID string, userID string, req *models.TokenAllocationRequest) (*models.Chain, error) {
+	// Validate ownership
+	chain, err := s.getChainAndValidateOwnership(ctx, chainID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The allocation is fixed once the virtual pool opens
+	if chain.Status != models.ChainStatusDraft {
+		return nil, ErrChainNotInDraftStatus
+	}
+
+	chain.TokenAllocation = models.TokenAllocation(*req)
+	if err := validateAllocation(chain); err != nil {
+		return nil, err
+	}
+
+	// Existing vesting schedules must still fit the creator bucket
+	schedules, err := s.chainRepo.GetVestingSchedulesByChainID(ctx, chain.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vesting schedules: %w", err)
+	}
+	if err := validateVestingSchedules(chain, schedules); err != nil {
+		return nil, err
+	}
+
+	// Chains created without a treasury bucket have no treasury key yet
+	if chain.TreasuryBps > 0 {
+		if _, err := s.chainRepo.GetChainKeyByChainID(ctx, chain.ID, models.KeyPurposeTreasury); err != nil {
+			if err := s.createChainKey(ctx, chain.ID, models.KeyPurposeTreasury); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = s.chainRepo.UpdateAllocation(ctx, chain.ID, chain.TokenAllocation)
+	if err != nil {
+		if err.Error() == "chain not found" {
+			return nil, ErrChainNotFound
+		}
+		return nil, fmt.Errorf("failed to update chain allocation: %w", err)
+	}
+
+	// Return updated chain
+	return s.chainRepo.GetByID(ctx, chain.ID, []string{"vesting_schedules"})
+}
+
+// UpdateVestingSchedules replaces the vesting schedules of a draft chain
+func (s *ChainService) UpdateVestingSchedules(ctx context.Context, chainID string, userID string, req *models.UpdateVestingSchedulesRequest) (*models.Chain, error) {
+	// Validate ownership
+	chain, err := s.getChainAndValidateOwnership(ctx, chainID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Schedules are fixed once the virtual pool opens
+	if chain.Status != models.ChainStatusDraft {
+		return nil, ErrChainNotInDraftStatus
+	}
+
+	schedules := vestingSchedulesFromRequest(req.Schedules)
+	if err := validateVestingSchedules(chain, schedules); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.chainRepo.ReplaceVestingSchedules(ctx, chain.ID, schedules); err != nil {
+		return nil, fmt.Errorf("failed to update vesting schedules: %w", err)
+	}
+
+	// Return updated chain
+	return s.chainRepo.GetByID(ctx, chain.ID, []string{"vesting_schedules"})
+}
+
 // GetRepositoryByChainID retrieves a GitHub repository by chain ID
 func (s *ChainService) GetRepositoryByChainID(ctx context.Context, chainID string, userID string) (*models.ChainRepository, error) {
 	chain, err := s.getChainAndValidateOwnership(ctx, chainID, userID)
@@ -657,6 +757,153 @@ func (s *ChainService) getIntValueOrDefault(ptr *int, defaultValue int) int {
 	return defaultValue
 }
 
+// createChainKey generates, encrypts and stores a keypair for the chain
+func (s *ChainService) createChainKey(ctx context.Context, chainID uuid.UUID, purpose string) error {
+	if s.keyPassword == "" {
+		return ErrNoChainKeyPassword
+	}
+
+	_, encryptedKeyPair, err := keygen.GenerateEncryptedKeyPair(s.keyPassword)
+	if err != nil {
+		return fmt.Errorf("failed to generate chain keypair: %w", err)
+	}
+
+	publicKeyBytes, err := hex.DecodeString(encryptedKeyPair.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to decode public key: %w", err)
+	}
+
+	saltBytes, err := hex.DecodeString(encryptedKeyPair.Salt)
+	if err != nil {
+		return fmt.Errorf("failed to decode salt: %w", err)
+	}
+
+	chainKey := &models.ChainKey{
+		ChainID:             chainID,
+		Address:             encryptedKeyPair.Address,
+		PublicKey:           publicKeyBytes,
+		EncryptedPrivateKey: encryptedKeyPair.EncryptedPrivateKey,
+		Salt:                saltBytes,
+		KeyNickname:         nil,
+		KeyPurpose:          purpose,
+		IsActive:            true,
+		RotationCount:       0,
+	}
+
+	// Save chain key to database
+	if _, err := s.chainRepo.CreateChainKey(ctx, chainKey); err != nil {
+		return fmt.Errorf("failed to store chain key: %w", err)
+	}
+
+	return nil
+}
+
+// DecryptChainKey decrypts a chain key's private key with the password it was
+// encrypted with
+func DecryptChainKey(key *models.ChainKey, password string) (crypto.PrivateKeyI, error) {
+	privateKeyBytes, err := keygen.DecryptPrivateKey(&keygen.EncryptedKeyPair{
+		PublicKey:           hex.EncodeToString(key.PublicKey),
+		EncryptedPrivateKey: key.EncryptedPrivateKey,
+		Salt:                hex.EncodeToString(key.Salt),
+		Address:             key.Address,
+	}, []byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt chain key: %w", err)
+	}
+
+	privateKey, err := crypto.NewPrivateKeyFromBytes(privateKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chain key: %w", err)
+	}
+	if privateKey.PublicKey().Address().String() != key.Address {
+		return nil, fmt.Errorf("chain key does not match address %s", key.Address)
+	}
+
+	return privateKey, nil
+}
+
+// validateAllocation checks that a chain's allocation buckets cover exactly the
+// total supply, that the holders bucket can pay out everything the virtual pool
+// sells, and that the liquidity reserve can fund the genesis validator stake
+// resolveRootChain returns the root chain a new chain is created on, rejecting
+// root chains the launchpad does not serve
+func (s *ChainService) resolveRootChain(requested *uint64) (uint64, error) {
+	if requested == nil {
+		if len(s.rootChainIDs) == 0 {
+			return models.DefaultRootChainID, nil
+		}
+		return s.rootChainIDs[0], nil
+	}
+	if len(s.rootChainIDs) > 0 && !slices.Contains(s.rootChainIDs, *requested) {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownRootChain, *requested)
+	}
+	return *requested, nil
+}
+
+func validateAllocation(chain *models.Chain) error {
+	if total := chain.TotalBps(); total != models.AllocationTotalBps {
+		return fmt.Errorf("%w: buckets sum to %d basis points, expected %d", ErrInvalidAllocation, total, models.AllocationTotalBps)
+	}
+
+	amounts := chain.Amounts(chain.TokenTotalSupply)
+	if amounts.Holders < chain.InitialTokenSupply {
+		return fmt.Errorf("%w: holders bucket of %d tokens is below the virtual pool supply of %d",
+			ErrInvalidAllocation, amounts.Holders, chain.InitialTokenSupply)
+	}
+	if float64(amounts.Liquidity) < chain.ValidatorMinStake {
+		return fmt.Errorf("%w: liquidity reserve of %d tokens cannot fund the validator stake of %v",
+			ErrInvalidAllocation, amounts.Liquidity, chain.ValidatorMinStake)
+	}
+
+	return nil
+}
+
+// validateCurve checks that the chain's bonding curve settings and token
+// decimals describe a curve trades can be priced on
+func validateCurve(chain *models.Chain) error {
+	if chain.TokenDecimals < 0 || chain.TokenDecimals > bondingcurve.MaxTokenDecimals {
+		return fmt.Errorf("%w: token_decimals must be between 0 and %d", ErrInvalidCurve, bondingcurve.MaxTokenDecimals)
+	}
+	params := curveParams(chain.CurveType, chain.BondingCurveSlope, chain.CurveMaxPrice, chain.CurveMidpointSupply)
+	if _, err := bondingcurve.NewCurve(params, nil); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCurve, err)
+	}
+	return nil
+}
+
+// validateVestingSchedules checks that the vesting schedules only lock tokens
+// from the creator bucket of the chain's allocation
+func validateVestingSchedules(chain *models.Chain, schedules []models.ChainVestingSchedule) error {
+	locked := 0
+	for _, schedule := range schedules {
+		if schedule.CliffDays > schedule.DurationDays {
+			return fmt.Errorf("%w: cliff of %d days is longer than the %d day duration",
+				ErrInvalidVesting, schedule.CliffDays, schedule.DurationDays)
+		}
+		locked += schedule.AllocationBps
+	}
+	if locked > chain.CreatorBps {
+		return fmt.Errorf("%w: schedules lock %d basis points but the creator bucket is %d",
+			ErrInvalidVesting, locked, chain.CreatorBps)
+	}
+
+	return nil
+}
+
+// vestingSchedulesFromRequest converts requested vesting schedules to models
+func vestingSchedulesFromRequest(reqs []models.VestingScheduleRequest) []models.ChainVestingSchedule {
+	schedules := make([]models.ChainVestingSchedule, 0, len(reqs))
+	for _, req := range reqs {
+		schedules = append(schedules, models.ChainVestingSchedule{
+			BeneficiaryAddress: req.BeneficiaryAddress,
+			AllocationBps:      req.AllocationBps,
+			CliffDays:          req.CliffDays,
+			DurationDays:       req.DurationDays,
+		})
+	}
+	return schedules
+}
+
 // extractRepoName extracts repository name from GitHub URL
 // Example: https://github.com/owner/repo -> "repo"
 func extractRepoName(githubURL string) string {
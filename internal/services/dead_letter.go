@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/canopy-network/canopy/lib"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/google/uuid"
+)
+
+// Dead-letter retry schedule. Events are retried with exponential backoff and
+// stop being retried automatically after DeadLetterMaxAttempts.
+const (
+	DeadLetterMaxAttempts = 10
+	DeadLetterBaseDelay   = 30 * time.Second
+	DeadLetterMaxDelay    = 1 * time.Hour
+)
+
+var (
+	ErrFailedEventNotFound = errors.New("failed event not found")
+	ErrFailedEventClosed   = errors.New("failed event already resolved or discarded")
+	ErrFailedEventChanged  = errors.New("failed event changed while being updated")
+)
+
+// EventReplayer processes a failed event again. A nil error means the event
+// has now been processed, including when it turns out to have been applied
+// already.
+type EventReplayer interface {
+	Replay(ctx context.Context, event *models.FailedEvent) error
+}
+
+// DeadLetterService manages the queue of on-chain events that could not be
+// processed. Events are replayed by the replayer registered for their type.
+type DeadLetterService struct {
+	failedEventRepo interfaces.FailedEventRepository
+	replayers       map[string]EventReplayer
+}
+
+func NewDeadLetterService(failedEventRepo interfaces.FailedEventRepository) *DeadLetterService {
+	return &DeadLetterService{
+		failedEventRepo: failedEventRepo,
+		replayers:       make(map[string]EventReplayer),
+	}
+}
+
+// SetReplayer registers the replayer for an event type. It must be called
+// before the service is used.
+func (s *DeadLetterService) SetReplayer(eventType string, replayer EventReplayer) {
+	s.replayers[eventType] = replayer
+}
+
+// DeadLetterRetryDelay returns how long to wait before the next retry of an
+// event that has failed attempts times
+func DeadLetterRetryDelay(attempts int) time.Duration {
+	delay := DeadLetterBaseDelay
+	for i := 1; i < attempts && delay < DeadLetterMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, DeadLetterMaxDelay)
+}
+
+// GetFailedEvents lists failed events with pagination, optionally filtered by
+// status and event type
+func (s *DeadLetterService) GetFailedEvents(ctx context.Context, params *models.FailedEventsQueryParams) ([]models.FailedEvent, *models.Pagination, error) {
+	pagination := interfaces.Pagination{
+		Page:   params.Page,
+		Limit:  params.Limit,
+		Offset: (params.Page - 1) * params.Limit,
+	}
+	filters := interfaces.FailedEventFilters{
+		Status:    params.Status,
+		EventType: params.EventType,
+	}
+
+	events, total, err := s.failedEventRepo.List(ctx, filters, pagination)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get failed events: %w", err)
+	}
+
+	paginationResp := &models.Pagination{
+		Page:  params.Page,
+		Limit: params.Limit,
+		Total: total,
+		Pages: (total + params.Limit - 1) / params.Limit, // Ceiling division
+	}
+
+	return events, paginationResp, nil
+}
+
+// RetryFailedEvent replays an event now, whether or not its retry is due or its
+// automatic retries are exhausted, and returns it with the outcome recorded
+func (s *DeadLetterService) RetryFailedEvent(ctx context.Context, id string) (*models.FailedEvent, error) {
+	event, err := s.getOpenEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.replay(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return nil, ErrFailedEventChanged
+	}
+
+	return event, nil
+}
+
+// DiscardFailedEvent stops an event from being retried. Use it for events that
+// can never succeed or that have been settled by hand.
+func (s *DeadLetterService) DiscardFailedEvent(ctx context.Context, id string) (*models.FailedEvent, error) {
+	event, err := s.getOpenEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	event.Status = models.FailedEventStatusDiscarded
+	event.ResolvedAt = &now
+
+	stored, err := s.failedEventRepo.Update(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return nil, ErrFailedEventChanged
+	}
+
+	return event, nil
+}
+
+// RetryDue replays up to limit pending events whose retry is due. It returns
+// the events that were replayed, with their outcome recorded, and any errors
+// storing those outcomes.
+func (s *DeadLetterService) RetryDue(ctx context.Context, limit int) ([]models.FailedEvent, error) {
+	events, err := s.failedEventRepo.ListDue(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for i := range events {
+		if _, err := s.replay(ctx, &events[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s event %s: %w", events[i].EventType, events[i].Reference, err))
+		}
+	}
+
+	return events, errors.Join(errs...)
+}
+
+// getOpenEvent retrieves an event that can still be retried or discarded
+func (s *DeadLetterService) getOpenEvent(ctx context.Context, id string) (*models.FailedEvent, error) {
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event ID: %w", err)
+	}
+
+	event, err := s.failedEventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, ErrFailedEventNotFound
+	}
+	if event.Status == models.FailedEventStatusResolved || event.Status == models.FailedEventStatusDiscarded {
+		return nil, ErrFailedEventClosed
+	}
+
+	return event, nil
+}
+
+// replay processes an event again and records the outcome on it: resolved on
+// success, otherwise one more attempt with the next retry scheduled. An event
+// that has used up its attempts is marked failed and left for an admin.
+func (s *DeadLetterService) replay(ctx context.Context, event *models.FailedEvent) (bool, error) {
+	var replayErr error
+	replayer, ok := s.replayers[event.EventType]
+	if ok {
+		replayErr = replayer.Replay(ctx, event)
+	} else {
+		replayErr = fmt.Errorf("no replayer for %s events", event.EventType)
+	}
+
+	now := time.Now()
+	if replayErr == nil {
+		event.Status = models.FailedEventStatusResolved
+		event.ResolvedAt = &now
+	} else {
+		event.Attempts++
+		event.LastError = replayErr.Error()
+		event.NextRetryAt = now.Add(DeadLetterRetryDelay(event.Attempts))
+		if event.Attempts >= DeadLetterMaxAttempts {
+			event.Status = models.FailedEventStatusFailed
+		}
+	}
+
+	return s.failedEventRepo.Update(ctx, event)
+}
+
+// NewDepositFailedEvent builds the dead-letter entry for a root chain deposit
+// that could not be applied. raw is the root chain transaction as received, in
+// JSON; chainID is nil when the deposit's chain could not be looked up.
+func NewDepositFailedEvent(txHash string, chainID *uuid.UUID, height uint64, raw string, processErr error) *models.FailedEvent {
+	return &models.FailedEvent{
+		EventType:   models.FailedEventTypeDeposit,
+		Reference:   txHash,
+		ChainID:     chainID,
+		BlockHeight: &height,
+		RawEvent:    raw,
+		LastError:   processErr.Error(),
+		NextRetryAt: time.Now().Add(DeadLetterRetryDelay(1)),
+	}
+}
+
+// NewOrderFailedEvent builds the dead-letter entry for a sell order that could
+// not be processed for a chain
+func NewOrderFailedEvent(order *lib.SellOrder, chainID uuid.UUID, processErr error) (*models.FailedEvent, error) {
+	raw, err := json.Marshal(order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode order: %w", err)
+	}
+
+	return &models.FailedEvent{
+		EventType:   models.FailedEventTypeOrder,
+		Reference:   hex.EncodeToString(order.Id),
+		ChainID:     &chainID,
+		RawEvent:    string(raw),
+		LastError:   processErr.Error(),
+		NextRetryAt: time.Now().Add(DeadLetterRetryDelay(1)),
+	}, nil
+}
+
+// OrderReplayer replays failed sell orders through the order processor
+type OrderReplayer struct {
+	processor *OrderProcessorTx
+}
+
+func NewOrderReplayer(processor *OrderProcessorTx) *OrderReplayer {
+	return &OrderReplayer{processor: processor}
+}
+
+// Replay processes a failed sell order again
+func (r *OrderReplayer) Replay(ctx context.Context, event *models.FailedEvent) error {
+	if event.ChainID == nil {
+		return fmt.Errorf("order event %s has no chain", event.Reference)
+	}
+
+	order := new(lib.SellOrder)
+	if err := json.Unmarshal([]byte(event.RawEvent), order); err != nil {
+		return fmt.Errorf("failed to decode order: %w", err)
+	}
+
+	return r.processor.ProcessOrderWithRetry(ctx, order, *event.ChainID)
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/testutil/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReplayer is an EventReplayer that records the events it replays
+type fakeReplayer struct {
+	err      error
+	replayed []string
+}
+
+func (f *fakeReplayer) Replay(ctx context.Context, event *models.FailedEvent) error {
+	f.replayed = append(f.replayed, event.Reference)
+	return f.err
+}
+
+// openEvent builds a pending deposit event that has failed attempts times
+func openEvent(attempts int) *models.FailedEvent {
+	return &models.FailedEvent{
+		ID:          uuid.New(),
+		EventType:   models.FailedEventTypeDeposit,
+		Reference:   "0xabc123",
+		Status:      models.FailedEventStatusPending,
+		Attempts:    attempts,
+		LastError:   "connection refused",
+		NextRetryAt: time.Now().Add(time.Hour),
+	}
+}
+
+func TestDeadLetterRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, DeadLetterRetryDelay(1))
+	assert.Equal(t, 60*time.Second, DeadLetterRetryDelay(2))
+	assert.Equal(t, 4*time.Minute, DeadLetterRetryDelay(4))
+	assert.Equal(t, DeadLetterMaxDelay, DeadLetterRetryDelay(8))
+	assert.Equal(t, DeadLetterMaxDelay, DeadLetterRetryDelay(100))
+}
+
+func TestDeadLetterService_RetryFailedEvent(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("successful replay resolves the event", func(t *testing.T) {
+		repo := new(mocks.MockFailedEventRepository)
+		replayer := &fakeReplayer{}
+		service := NewDeadLetterService(repo)
+		service.SetReplayer(models.FailedEventTypeDeposit, replayer)
+
+		event := openEvent(3)
+		repo.On("GetByID", ctx, event.ID).Return(event, nil)
+		repo.On("Update", ctx, event).Return(true, nil)
+
+		result, err := service.RetryFailedEvent(ctx, event.ID.String())
+		require.NoError(t, err)
+		assert.Equal(t, models.FailedEventStatusResolved, result.Status)
+		assert.NotNil(t, result.ResolvedAt)
+		assert.Equal(t, 3, result.Attempts)
+		assert.Equal(t, []string{"0xabc123"}, replayer.replayed)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("failed replay schedules the next retry", func(t *testing.T) {
+		repo := new(mocks.MockFailedEventRepository)
+		service := NewDeadLetterService(repo)
+		service.SetReplayer(models.FailedEventTypeDeposit, &fakeReplayer{err: errors.New("deadlock detected")})
+
+		event := openEvent(2)
+		repo.On("GetByID", ctx, event.ID).Return(event, nil)
+		repo.On("Update", ctx, event).Return(true, nil)
+
+		before := time.Now()
+		result, err := service.RetryFailedEvent(ctx, event.ID.String())
+		require.NoError(t, err)
+		assert.Equal(t, models.FailedEventStatusPending, result.Status)
+		assert.Equal(t, 3, result.Attempts)
+		assert.Equal(t, "deadlock detected", result.LastError)
+		assert.WithinDuration(t, before.Add(DeadLetterRetryDelay(3)), result.NextRetryAt, time.Second)
+		assert.Nil(t, result.ResolvedAt)
+	})
+
+	t.Run("failed replay on the last attempt fails the event", func(t *testing.T) {
+		repo := new(mocks.MockFailedEventRepository)
+		service := NewDeadLetterService(repo)
+		service.SetReplayer(models.FailedEventTypeDeposit, &fakeReplayer{err: errors.New("deadlock detected")})
+
+		event := openEvent(DeadLetterMaxAttempts - 1)
+		repo.On("GetByID", ctx, event.ID).Return(event, nil)
+		repo.On("Update", ctx, event).Return(true, nil)
+
+		result, err := service.RetryFailedEvent(ctx, event.ID.String())
+		require.NoError(t, err)
+		assert.Equal(t, models.FailedEventStatusFailed, result.Status)
+		assert.Equal(t, DeadLetterMaxAttempts, result.Attempts)
+	})
+
+	t.Run("failed event can still be retried by hand", func(t *testing.T) {
+		repo := new(mocks.MockFailedEventRepository)
+		replayer := &fakeReplayer{}
+		service := NewDeadLetterService(repo)
+		service.SetReplayer(models.FailedEventTypeDeposit, replayer)
+
+		event := openEvent(DeadLetterMaxAttempts)
+		event.Status = models.FailedEventStatusFailed
+		repo.On("GetByID", ctx, event.ID).Return(event, nil)
+		repo.On("Update", ctx, event).Return(true, nil)
+
+		result, err := service.RetryFailedEvent(ctx, event.ID.String())
+		require.NoError(t, err)
+		assert.Equal(t, models.FailedEventStatusResolved, result.Status)
+		assert.Len(t, replayer.replayed, 1)
+	})
+
+	t.Run("event without a replayer stays queued", func(t *testing.T) {
+		repo := new(mocks.MockFailedEventRepository)
+		service := NewDeadLetterService(repo)
+
+		event := openEvent(1)
+		repo.On("GetByID", ctx, event.ID).Return(event, nil)
+		repo.On("Update", ctx, event).Return(true, nil)
+
+		result, err := service.RetryFailedEvent(ctx, event.ID.String())
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Attempts)
+		assert.Equal(t, "no replayer for deposit events", result.LastError)
+	})
+
+	t.Run("closed events are not replayed", func(t *testing.T) {
+		for _, status := range []string{models.FailedEventStatusResolved, models.FailedEventStatusDiscarded} {
+			repo := new(mocks.MockFailedEventRepository)
+			replayer := &fakeReplayer{}
+			service := NewDeadLetterService(repo)
+			service.SetReplayer(models.FailedEventTypeDeposit, replayer)
+
+			event := openEvent(1)
+			event.Status = status
+			repo.On("GetByID", ctx, event.ID).Return(event, nil)
+
+			_, err := service.RetryFailedEvent(ctx, event.ID.String())
+			assert.ErrorIs(t, err, ErrFailedEventClosed, status)
+			assert.Empty(t, replayer.replayed)
+			repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("event changed concurrently", func(t *testing.T) {
+		repo := new(mocks.MockFailedEventRepository)
+		service := NewDeadLetterService(repo)
+		service.SetReplayer(models.FailedEventTypeDeposit, &fakeReplayer{})
+
+		event := openEvent(1)
+		repo.On("GetByID", ctx, event.ID).Return(event, nil)
+		repo.On("Update", ctx, event).Return(false, nil)
+
+		_, err := service.RetryFailedEvent(ctx, event.ID.String())
+		assert.ErrorIs(t, err, ErrFailedEventChanged)
+	})
+
+	t.Run("unknown event", func(t *testing.T) {
+		repo := new(mocks.MockFailedEventRepository)
+		service := NewDeadLetterService(repo)
+
+		id := uuid.New()
+		repo.On("GetByID", ctx, id).Return(nil, nil)
+
+		_, err := service.RetryFailedEvent(ctx, id.String())
+		assert.ErrorIs(t, err, ErrFailedEventNotFound)
+	})
+
+	t.Run("invalid event ID", func(t *testing.T) {
+		service := NewDeadLetterService(new(mocks.MockFailedEventRepository))
+
+		_, err := service.RetryFailedEvent(ctx, "not-a-uuid")
+		assert.ErrorContains(t, err, "invalid event ID")
+	})
+}
+
+func TestDeadLetterService_DiscardFailedEvent(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(mocks.MockFailedEventRepository)
+	replayer := &fakeReplayer{}
+	service := NewDeadLetterService(repo)
+	service.SetReplayer(models.FailedEventTypeDeposit, replayer)
+
+	event := openEvent(4)
+	repo.On("GetByID", ctx, event.ID).Return(event, nil)
+	repo.On("Update", ctx, event).Return(true, nil)
+
+	result, err := service.DiscardFailedEvent(ctx, event.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.FailedEventStatusDiscarded, result.Status)
+	assert.NotNil(t, result.ResolvedAt)
+	assert.Equal(t, 4, result.Attempts)
+	assert.Empty(t, replayer.replayed)
+}
+
+func TestDeadLetterService_RetryDue(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(mocks.MockFailedEventRepository)
+	deposits := &fakeReplayer{}
+	orders := &fakeReplayer{err: errors.New("pool not found")}
+	service := NewDeadLetterService(repo)
+	service.SetReplayer(models.FailedEventTypeDeposit, deposits)
+	service.SetReplayer(models.FailedEventTypeOrder, orders)
+
+	deposit := *openEvent(1)
+	order := *openEvent(1)
+	order.EventType = models.FailedEventTypeOrder
+	order.Reference = "0a0b"
+	stale := *openEvent(1)
+	stale.Reference = "0xdef456"
+
+	repo.On("ListDue", ctx, 50).Return([]models.FailedEvent{deposit, order, stale}, nil)
+	repo.On("Update", ctx, mock.MatchedBy(func(event *models.FailedEvent) bool {
+		return event.Reference != "0xdef456"
+	})).Return(true, nil)
+	repo.On("Update", ctx, mock.Anything).Return(false, errors.New("connection reset"))
+
+	events, err := service.RetryDue(ctx, 50)
+	require.Len(t, events, 3)
+	assert.ErrorContains(t, err, "deposit event 0xdef456: connection reset")
+
+	assert.Equal(t, models.FailedEventStatusResolved, events[0].Status)
+	assert.Equal(t, models.FailedEventStatusPending, events[1].Status)
+	assert.Equal(t, 2, events[1].Attempts)
+	assert.Equal(t, "pool not found", events[1].LastError)
+	assert.Equal(t, []string{"0xabc123", "0xdef456"}, deposits.replayed)
+	assert.Equal(t, []string{"0a0b"}, orders.replayed)
+}
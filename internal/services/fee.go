@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/pkg/bondingcurve"
+	"github.com/google/uuid"
+)
+
+// Default shares of each trade's fee, in basis points of the fee
+const (
+	DefaultProtocolFeeBasisPoints = 5000
+	DefaultCreatorFeeBasisPoints  = 4000
+	DefaultReferrerFeeBasisPoints = 1000
+)
+
+var ErrInvalidFeeSplit = errors.New("invalid fee split")
+
+// FeeSplit divides the fee paid on every trade among the protocol treasury,
+// the creator of the traded chain and the trade's referrer, in basis points of
+// the fee. A trade with no referrer, or referred by the trader, pays the
+// referrer's share to the protocol, which also keeps the uCNPY that rounding
+// the other shares down leaves over.
+type FeeSplit struct {
+	ProtocolBasisPoints uint64
+	CreatorBasisPoints  uint64
+	ReferrerBasisPoints uint64
+}
+
+// DefaultFeeSplit returns the split used when none is configured
+func DefaultFeeSplit() FeeSplit {
+	return FeeSplit{
+		ProtocolBasisPoints: DefaultProtocolFeeBasisPoints,
+		CreatorBasisPoints:  DefaultCreatorFeeBasisPoints,
+		ReferrerBasisPoints: DefaultReferrerFeeBasisPoints,
+	}
+}
+
+// Validate checks that the shares add up to the whole fee
+func (s FeeSplit) Validate() error {
+	if total := s.ProtocolBasisPoints + s.CreatorBasisPoints + s.ReferrerBasisPoints; total != bondingcurve.BasisPointsDivisor {
+		return fmt.Errorf("%w: shares add up to %d basis points, not %d", ErrInvalidFeeSplit, total, bondingcurve.BasisPointsDivisor)
+	}
+	return nil
+}
+
+// accruals splits the fee uCNPY paid on a trade on a pool, recorded as
+// transactionID. Shares that round to zero are left out.
+func (s FeeSplit) accruals(pool *models.VirtualPool, trade *Trade, transactionID uuid.UUID, fee uint64) []*models.FeeAccrual {
+	var accruals []*models.FeeAccrual
+	add := func(recipientType string, recipient *uuid.UUID, amount uint64) {
+		if amount == 0 {
+			return
+		}
+		accruals = append(accruals, &models.FeeAccrual{
+			ChainID:                  trade.ChainID,
+			VirtualPoolTransactionID: transactionID,
+			RecipientType:            recipientType,
+			RecipientUserID:          recipient,
+			Amount:                   amount,
+		})
+	}
+
+	protocol := fee
+	if pool.CreatorID != uuid.Nil {
+		creator := pool.CreatorID
+		amount := share(fee, s.CreatorBasisPoints)
+		add(models.FeeRecipientCreator, &creator, amount)
+		protocol -= amount
+	}
+	if trade.ReferrerID != nil && *trade.ReferrerID != trade.UserID {
+		referrer := *trade.ReferrerID
+		amount := share(fee, s.ReferrerBasisPoints)
+		add(models.FeeRecipientReferrer, &referrer, amount)
+		protocol -= amount
+	}
+	add(models.FeeRecipientProtocol, nil, protocol)
+
+	return accruals
+}
+
+// share is basisPoints of amount, rounded down
+func share(amount, basisPoints uint64) uint64 {
+	split := new(big.Int).SetUint64(amount)
+	split.Mul(split, new(big.Int).SetUint64(basisPoints))
+	return split.Quo(split, big.NewInt(bondingcurve.BasisPointsDivisor)).Uint64()
+}
+
+// FeeService reports the trading fees accrued to the protocol, chain creators
+// and referrers
+type FeeService struct {
+	feeRepo interfaces.FeeAccrualRepository
+}
+
+func NewFeeService(feeRepo interfaces.FeeAccrualRepository) *FeeService {
+	return &FeeService{feeRepo: feeRepo}
+}
+
+// GetUserBalances lists the fee balances a user has accrued as a chain creator
+// or referrer, one per chain and role
+func (s *FeeService) GetUserBalances(ctx context.Context, userID string, params *models.FeeBalancesQueryParams) ([]models.FeeBalance, *models.Pagination, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	filters, err := feeBalanceFilters(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	filters.RecipientUserID = &userUUID
+
+	return s.listBalances(ctx, filters, params)
+}
+
+// GetBalances lists the fee balances of every recipient, optionally filtered by
+// recipient type and chain
+func (s *FeeService) GetBalances(ctx context.Context, params *models.FeeBalancesQueryParams) ([]models.FeeBalance, *models.Pagination, error) {
+	filters, err := feeBalanceFilters(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.listBalances(ctx, filters, params)
+}
+
+func (s *FeeService) listBalances(ctx context.Context, filters interfaces.FeeBalanceFilters, params *models.FeeBalancesQueryParams) ([]models.FeeBalance, *models.Pagination, error) {
+	pagination := interfaces.Pagination{
+		Page:   params.Page,
+		Limit:  params.Limit,
+		Offset: (params.Page - 1) * params.Limit,
+	}
+
+	balances, total, err := s.feeRepo.ListBalances(ctx, filters, pagination)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get fee balances: %w", err)
+	}
+
+	paginationResp := &models.Pagination{
+		Page:  params.Page,
+		Limit: params.Limit,
+		Total: total,
+		Pages: (total + params.Limit - 1) / params.Limit, // Ceiling division
+	}
+
+	return balances, paginationResp, nil
+}
+
+// feeBalanceFilters maps query parameters onto repository filters
+func feeBalanceFilters(params *models.FeeBalancesQueryParams) (interfaces.FeeBalanceFilters, error) {
+	filters := interfaces.FeeBalanceFilters{RecipientType: params.RecipientType}
+	if params.ChainID != "" {
+		chainID, err := uuid.Parse(params.ChainID)
+		if err != nil {
+			return filters, fmt.Errorf("invalid chain ID: %w", err)
+		}
+		filters.ChainID = &chainID
+	}
+	return filters, nil
+}
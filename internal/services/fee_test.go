@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeSplit(t *testing.T) {
+	split := DefaultFeeSplit()
+	require.NoError(t, split.Validate())
+
+	chainID := uuid.New()
+	creatorID := uuid.New()
+	traderID := uuid.New()
+	referrerID := uuid.New()
+	transactionID := uuid.New()
+	pool := &models.VirtualPool{ChainID: chainID, CreatorID: creatorID}
+
+	amounts := func(accruals []*models.FeeAccrual) map[string]uint64 {
+		byType := map[string]uint64{}
+		for _, accrual := range accruals {
+			assert.Equal(t, chainID, accrual.ChainID)
+			assert.Equal(t, transactionID, accrual.VirtualPoolTransactionID)
+			byType[accrual.RecipientType] += accrual.Amount
+		}
+		return byType
+	}
+
+	t.Run("referred trade pays every recipient", func(t *testing.T) {
+		trade := &Trade{ChainID: chainID, UserID: traderID, ReferrerID: &referrerID}
+		accruals := split.accruals(pool, trade, transactionID, 10000)
+		require.Len(t, accruals, 3)
+		assert.Equal(t, map[string]uint64{
+			models.FeeRecipientCreator:  4000,
+			models.FeeRecipientReferrer: 1000,
+			models.FeeRecipientProtocol: 5000,
+		}, amounts(accruals))
+		assert.Equal(t, creatorID, *accruals[0].RecipientUserID)
+		assert.Equal(t, referrerID, *accruals[1].RecipientUserID)
+		assert.Nil(t, accruals[2].RecipientUserID)
+	})
+
+	t.Run("unreferred and self-referred trades pay the referrer share to the protocol", func(t *testing.T) {
+		for _, trade := range []*Trade{
+			{ChainID: chainID, UserID: traderID},
+			{ChainID: chainID, UserID: traderID, ReferrerID: &traderID},
+		} {
+			assert.Equal(t, map[string]uint64{
+				models.FeeRecipientCreator:  4000,
+				models.FeeRecipientProtocol: 6000,
+			}, amounts(split.accruals(pool, trade, transactionID, 10000)))
+		}
+	})
+
+	t.Run("protocol keeps the rounding remainder", func(t *testing.T) {
+		trade := &Trade{ChainID: chainID, UserID: traderID, ReferrerID: &referrerID}
+		assert.Equal(t, map[string]uint64{
+			models.FeeRecipientCreator:  3,
+			models.FeeRecipientProtocol: 6,
+		}, amounts(split.accruals(pool, trade, transactionID, 9)))
+	})
+
+	t.Run("pool without a creator", func(t *testing.T) {
+		trade := &Trade{ChainID: chainID, UserID: traderID}
+		accruals := split.accruals(&models.VirtualPool{ChainID: chainID}, trade, transactionID, 10000)
+		require.Len(t, accruals, 1)
+		assert.Equal(t, models.FeeRecipientProtocol, accruals[0].RecipientType)
+		assert.Equal(t, uint64(10000), accruals[0].Amount)
+	})
+
+	t.Run("shares must cover the whole fee", func(t *testing.T) {
+		assert.ErrorIs(t, FeeSplit{ProtocolBasisPoints: 5000, CreatorBasisPoints: 4000}.Validate(), ErrInvalidFeeSplit)
+	})
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/google/uuid"
+)
+
+var ErrGraduatedPoolNotFound = errors.New("graduated pool not found")
+
+type GraduatedPoolService struct {
+	graduatedPoolRepo interfaces.GraduatedPoolRepository
+}
+
+func NewGraduatedPoolService(graduatedPoolRepo interfaces.GraduatedPoolRepository) *GraduatedPoolService {
+	return &GraduatedPoolService{
+		graduatedPoolRepo: graduatedPoolRepo,
+	}
+}
+
+// GetAllPools retrieves all graduated pools with pagination
+func (s *GraduatedPoolService) GetAllPools(ctx context.Context, page, limit int) ([]models.GraduatedPool, *models.Pagination, error) {
+	pagination := interfaces.Pagination{
+		Page:   page,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	}
+
+	pools, total, err := s.graduatedPoolRepo.GetAllPools(ctx, pagination)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get graduated pools: %w", err)
+	}
+
+	paginationResp := &models.Pagination{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+		Pages: (total + limit - 1) / limit,
+	}
+
+	return pools, paginationResp, nil
+}
+
+// GetPool retrieves graduated pool data for a chain
+func (s *GraduatedPoolService) GetPool(ctx context.Context, chainID string) (*models.GraduatedPool, error) {
+	chainUUID, err := uuid.Parse(chainID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain ID: %w", err)
+	}
+
+	pool, err := s.graduatedPoolRepo.GetPoolByChainID(ctx, chainUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "graduated pool not found") {
+			return nil, ErrGraduatedPoolNotFound
+		}
+		return nil, fmt.Errorf("failed to get graduated pool: %w", err)
+	}
+
+	return pool, nil
+}
+
+// GetTransactions retrieves graduated pool transactions for a chain with optional filters
+func (s *GraduatedPoolService) GetTransactions(ctx context.Context, chainID string, userID, transactionType string, page, limit int) ([]models.GraduatedPoolTransaction, *models.Pagination, error) {
+	pool, err := s.GetPool(ctx, chainID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filters := interfaces.TransactionFilters{
+		TransactionType: transactionType,
+	}
+
+	if userID != "" {
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid user ID: %w", err)
+		}
+		filters.UserID = &userUUID
+	}
+
+	pagination := interfaces.Pagination{
+		Page:   page,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	}
+
+	transactions, total, err := s.graduatedPoolRepo.GetTransactionsByChainID(ctx, pool.ChainID, filters, pagination)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	paginationResp := &models.Pagination{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+		Pages: (total + limit - 1) / limit,
+	}
+
+	return transactions, paginationResp, nil
+}
+
+// GetPositions retrieves holder positions in a chain's graduated pool, largest balance first
+func (s *GraduatedPoolService) GetPositions(ctx context.Context, chainID string, page, limit int) ([]models.UserGraduatedLPPosition, *models.Pagination, error) {
+	pool, err := s.GetPool(ctx, chainID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pagination := interfaces.Pagination{
+		Page:   page,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	}
+
+	positions, total, err := s.graduatedPoolRepo.GetPositionsByChainID(ctx, pool.ChainID, pagination)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	paginationResp := &models.Pagination{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+		Pages: (total + limit - 1) / limit,
+	}
+
+	return positions, paginationResp, nil
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/enielson/launchpad/internal/graduator"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrGraduationNotFound = errors.New("graduation not found")
+	ErrGenesisNotFound    = errors.New("genesis not found")
+	ErrGraduationComplete = errors.New("graduation already complete")
+)
+
+// GraduationPreviewer runs graduation dry runs
+type GraduationPreviewer interface {
+	Preview(ctx context.Context, chainID uuid.UUID) (*graduator.GraduationPreview, error)
+}
+
+// GraduationNotifier is told about chains whose graduation can make progress,
+// such as a virtual pool that may have reached the graduation threshold
+type GraduationNotifier interface {
+	Notify(chainID uuid.UUID)
+}
+
+type GraduationService struct {
+	graduationRepo interfaces.ChainGraduationRepository
+	chainRepo      interfaces.ChainRepository
+	previewer      GraduationPreviewer
+	notifier       GraduationNotifier
+}
+
+func NewGraduationService(graduationRepo interfaces.ChainGraduationRepository, chainRepo interfaces.ChainRepository, previewer GraduationPreviewer) *GraduationService {
+	return &GraduationService{
+		graduationRepo: graduationRepo,
+		chainRepo:      chainRepo,
+		previewer:      previewer,
+	}
+}
+
+// SetGraduationNotifier registers the notifier that is signalled when a deployer
+// report lets a waiting graduation continue
+func (s *GraduationService) SetGraduationNotifier(notifier GraduationNotifier) {
+	s.notifier = notifier
+}
+
+// GetGraduations retrieves the graduation state of every chain with pagination
+func (s *GraduationService) GetGraduations(ctx context.Context, page, limit int) ([]models.ChainGraduation, *models.Pagination, error) {
+	pagination := interfaces.Pagination{
+		Page:   page,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	}
+
+	graduations, total, err := s.graduationRepo.List(ctx, pagination)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get graduations: %w", err)
+	}
+
+	paginationResp := &models.Pagination{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+		Pages: (total + limit - 1) / limit, // Ceiling division
+	}
+
+	return graduations, paginationResp, nil
+}
+
+// GetGraduationByChainID retrieves the current graduation step and last error for a chain
+func (s *GraduationService) GetGraduationByChainID(ctx context.Context, id string) (*models.ChainGraduation, error) {
+	chainID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain ID: %w", err)
+	}
+
+	graduation, err := s.graduationRepo.GetByChainID(ctx, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get graduation: %w", err)
+	}
+	if graduation == nil {
+		return nil, ErrGraduationNotFound
+	}
+
+	return graduation, nil
+}
+
+// GetGenesisByChainID retrieves the canonical genesis file and its hash for a chain.
+// The genesis is only available once its hash has been recorded during graduation.
+func (s *GraduationService) GetGenesisByChainID(ctx context.Context, id string) ([]byte, string, error) {
+	graduation, err := s.GetGraduationByChainID(ctx, id)
+	if err != nil {
+		if err == ErrGraduationNotFound {
+			return nil, "", ErrGenesisNotFound
+		}
+		return nil, "", err
+	}
+
+	if graduation.GenesisFile == nil || graduation.GenesisHash == nil {
+		return nil, "", ErrGenesisNotFound
+	}
+
+	return []byte(*graduation.GenesisFile), *graduation.GenesisHash, nil
+}
+
+// PreviewGraduation returns the genesis, allocations and RPC payload graduation
+// would produce for the chain right now, along with any unmet preconditions
+func (s *GraduationService) PreviewGraduation(ctx context.Context, id string) (*graduator.GraduationPreview, error) {
+	chainID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain ID: %w", err)
+	}
+
+	preview, err := s.previewer.Preview(ctx, chainID)
+	if err != nil {
+		if strings.Contains(err.Error(), "chain not found") {
+			return nil, ErrChainNotFound
+		}
+		if strings.Contains(err.Error(), "virtual pool not found") {
+			return nil, ErrPoolNotFound
+		}
+		return nil, fmt.Errorf("failed to preview graduation: %w", err)
+	}
+
+	return preview, nil
+}
+
+// RecordDeployment stores a deployer progress report for a chain and updates the
+// chain's status to match: running marks it graduated and failed marks it failed.
+// The graduation worker is notified so a waiting graduation resumes promptly.
+func (s *GraduationService) RecordDeployment(ctx context.Context, id string, req *models.DeploymentCallbackRequest) (*models.ChainGraduation, error) {
+	graduation, err := s.GetGraduationByChainID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if graduation.IsComplete() {
+		return nil, ErrGraduationComplete
+	}
+
+	graduation, err = s.graduationRepo.RecordDeployment(ctx, graduation.ChainID, req.Status, req.RPCURL, req.Message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record deployment: %w", err)
+	}
+
+	if req.Status != models.DeploymentStatusProvisioning {
+		chain, err := s.chainRepo.GetByID(ctx, graduation.ChainID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chain: %w", err)
+		}
+
+		if req.Status == models.DeploymentStatusRunning {
+			graduator.MarkGraduated(chain, time.Now())
+		} else {
+			chain.Status = models.ChainStatusFailed
+		}
+
+		if _, err := s.chainRepo.Update(ctx, chain); err != nil {
+			return nil, fmt.Errorf("failed to update chain status: %w", err)
+		}
+	}
+
+	if s.notifier != nil {
+		s.notifier.Notify(graduation.ChainID)
+	}
+
+	return graduation, nil
+}
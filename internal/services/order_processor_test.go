@@ -6,7 +6,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/canopy-network/canopy/lib"
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/interfaces"
 	"github.com/enielson/launchpad/pkg/bondingcurve"
@@ -126,6 +125,14 @@ func (m *MockUserRepository) GetByWalletAddress(ctx context.Context, walletAddre
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetOrCreateByWalletAddresses(ctx context.Context, walletAddresses []string) ([]models.User, error) {
+	args := m.Called(ctx, walletAddresses)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
 func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(ctx, email)
 	return args.Get(0).(*models.User), args.Error(1)
@@ -217,250 +224,92 @@ func (m *MockUserRepository) UpdateProfile(ctx context.Context, userID uuid.UUID
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func TestNewOrderProcessor(t *testing.T) {
-	poolRepo := new(MockVirtualPoolRepository)
+func TestNewOrderProcessorTx(t *testing.T) {
+	poolRepo := new(MockVirtualPoolTxRepository)
 	userRepo := new(MockUserRepository)
 
 	t.Run("with custom config", func(t *testing.T) {
 		config := &bondingcurve.BondingCurveConfig{
 			FeeRateBasisPoints: 200, // 2%
 		}
-		processor := NewOrderProcessor(poolRepo, userRepo, config)
+		processor := NewOrderProcessorTx(nil, poolRepo, userRepo, config)
 		assert.NotNil(t, processor)
 		assert.Equal(t, uint64(200), processor.GetConfig().FeeRateBasisPoints)
 	})
 
 	t.Run("with nil config uses default", func(t *testing.T) {
-		processor := NewOrderProcessor(poolRepo, userRepo, nil)
+		processor := NewOrderProcessorTx(nil, poolRepo, userRepo, nil)
 		assert.NotNil(t, processor)
 		assert.Equal(t, uint64(100), processor.GetConfig().FeeRateBasisPoints) // Default 1%
 	})
 }
 
-func TestValidateOrder(t *testing.T) {
-	poolRepo := new(MockVirtualPoolRepository)
-	userRepo := new(MockUserRepository)
-	processor := NewOrderProcessor(poolRepo, userRepo, nil)
-
-	t.Run("valid buy order", func(t *testing.T) {
-		order := &lib.SellOrder{
-			AmountForSale:       1000,
-			RequestedAmount:     80000,
-			BuyerReceiveAddress: []byte(uuid.New().String()),
-		}
-		err := processor.validateOrder(order)
-		assert.NoError(t, err)
-	})
-
-	t.Run("nil order", func(t *testing.T) {
-		err := processor.validateOrder(nil)
-		assert.ErrorIs(t, err, ErrInvalidOrder)
-	})
-
-	t.Run("zero amounts", func(t *testing.T) {
-		order := &lib.SellOrder{
-			AmountForSale:   0,
-			RequestedAmount: 0,
-		}
-		err := processor.validateOrder(order)
-		assert.ErrorIs(t, err, ErrZeroAmount)
-	})
-
-	t.Run("missing addresses", func(t *testing.T) {
-		order := &lib.SellOrder{
-			AmountForSale:   1000,
-			RequestedAmount: 80000,
-		}
-		err := processor.validateOrder(order)
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "missing user address")
-	})
-}
-
-func TestProcessBuyOrder(t *testing.T) {
-	poolRepo := new(MockVirtualPoolRepository)
+func TestSimulateBuy(t *testing.T) {
+	poolRepo := new(MockVirtualPoolTxRepository)
 	userRepo := new(MockUserRepository)
-	processor := NewOrderProcessor(poolRepo, userRepo, nil)
+	processor := NewOrderProcessorTx(nil, poolRepo, userRepo, nil)
 
 	chainID := uuid.New()
-	userID := uuid.New()
-	poolID := uuid.New()
-
 	pool := &models.VirtualPool{
-		ID:                poolID,
+		ID:                uuid.New(),
 		ChainID:           chainID,
 		CNPYReserve:       10000.0,
 		TokenReserve:      800000000,
-		CurrentPriceCNPY:  0.0000125,
-		TotalVolumeCNPY:   5000.0,
-		TotalTransactions: 10,
+		CNPYReserveMicro:  10000000000,
+		TokenReserveUnits: 800000000000000,
+		TokenDecimals:     6,
 	}
 
-	order := &lib.SellOrder{
-		AmountForSale:       100,
-		RequestedAmount:     8000,
-		BuyerReceiveAddress: []byte(userID.String()),
-	}
-
-	t.Run("successful buy with new position", func(t *testing.T) {
+	t.Run("successful simulation", func(t *testing.T) {
 		poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil).Once()
-		poolRepo.On("GetUserPosition", mock.Anything, userID, chainID).Return(nil, nil).Once()
-		poolRepo.On("UpsertUserPosition", mock.Anything, mock.AnythingOfType("*models.UserVirtualLPPosition")).Return(nil).Once()
-		poolRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("*models.VirtualPoolTransaction")).Return(nil).Once()
-		poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.AnythingOfType("*interfaces.PoolStateUpdate")).Return(nil).Once()
 
-		err := processor.processBuyOrder(context.Background(), order, chainID)
+		result, err := processor.SimulateBuy(context.Background(), chainID, 100.0)
 		assert.NoError(t, err)
+		assert.NotNil(t, result)
+		assert.NotNil(t, result.AmountOut)
+		assert.True(t, result.AmountOut.Sign() > 0)
 		poolRepo.AssertExpectations(t)
 	})
 
-	t.Run("successful buy with existing position", func(t *testing.T) {
-		now := time.Now()
-		existingPosition := &models.UserVirtualLPPosition{
-			ID:                    uuid.New(),
-			UserID:                userID,
-			ChainID:               chainID,
-			VirtualPoolID:         poolID,
-			TokenBalance:          5000,
-			TotalCNPYInvested:     60.0,
-			AverageEntryPriceCNPY: 0.012,
-			FirstPurchaseAt:       &now,
-		}
-
-		poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil).Once()
-		poolRepo.On("GetUserPosition", mock.Anything, userID, chainID).Return(existingPosition, nil).Once()
-		poolRepo.On("UpsertUserPosition", mock.Anything, mock.AnythingOfType("*models.UserVirtualLPPosition")).Return(nil).Once()
-		poolRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("*models.VirtualPoolTransaction")).Return(nil).Once()
-		poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.AnythingOfType("*interfaces.PoolStateUpdate")).Return(nil).Once()
+	t.Run("priced on the chain's curve", func(t *testing.T) {
+		linear := *pool
+		linear.CurveType = models.CurveTypeLinear
+		poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(&linear, nil).Once()
 
-		err := processor.processBuyOrder(context.Background(), order, chainID)
+		// A flat linear curve sells at the initial price of 0.01 CNPY, less the
+		// 1% fee. As a float64 that price is a shade above 0.01, so the 9,900
+		// tokens round down to the base unit below.
+		result, err := processor.SimulateBuy(context.Background(), chainID, 100.0)
 		assert.NoError(t, err)
-		poolRepo.AssertExpectations(t)
+		assert.Equal(t, "9899.999999", result.AmountOut.Text('f', 6))
 	})
 
-	t.Run("pool not found", func(t *testing.T) {
-		poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(nil, errors.New("not found")).Once()
+	t.Run("invalid curve settings", func(t *testing.T) {
+		sigmoid := *pool
+		sigmoid.CurveType = models.CurveTypeCappedSigmoid
+		sigmoid.BondingCurveSlope = 0.00000001
+		poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(&sigmoid, nil).Once()
 
-		err := processor.processBuyOrder(context.Background(), order, chainID)
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, ErrPoolNotFound)
-		poolRepo.AssertExpectations(t)
+		result, err := processor.SimulateBuy(context.Background(), chainID, 100.0)
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, bondingcurve.ErrInvalidCurveParams)
 	})
 }
 
-func TestProcessSellOrder(t *testing.T) {
-	poolRepo := new(MockVirtualPoolRepository)
+func TestSimulateSell(t *testing.T) {
+	poolRepo := new(MockVirtualPoolTxRepository)
 	userRepo := new(MockUserRepository)
-	processor := NewOrderProcessor(poolRepo, userRepo, nil)
+	processor := NewOrderProcessorTx(nil, poolRepo, userRepo, nil)
 
 	chainID := uuid.New()
-	userID := uuid.New()
-	poolID := uuid.New()
-
 	pool := &models.VirtualPool{
-		ID:                poolID,
+		ID:                uuid.New(),
 		ChainID:           chainID,
 		CNPYReserve:       10000.0,
 		TokenReserve:      800000000,
-		CurrentPriceCNPY:  0.0000125,
-		TotalVolumeCNPY:   5000.0,
-		TotalTransactions: 10,
-	}
-
-	now := time.Now()
-	existingPosition := &models.UserVirtualLPPosition{
-		ID:                    uuid.New(),
-		UserID:                userID,
-		ChainID:               chainID,
-		VirtualPoolID:         poolID,
-		TokenBalance:          10000,
-		TotalCNPYInvested:     120.0,
-		AverageEntryPriceCNPY: 0.012,
-		FirstPurchaseAt:       &now,
-	}
-
-	order := &lib.SellOrder{
-		RequestedAmount:    5000, // Selling 5000 tokens
-		SellersSendAddress: []byte(userID.String()),
-	}
-
-	t.Run("successful sell", func(t *testing.T) {
-		poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil).Once()
-		poolRepo.On("GetUserPosition", mock.Anything, userID, chainID).Return(existingPosition, nil).Once()
-		poolRepo.On("UpsertUserPosition", mock.Anything, mock.AnythingOfType("*models.UserVirtualLPPosition")).Return(nil).Once()
-		poolRepo.On("CreateTransaction", mock.Anything, mock.AnythingOfType("*models.VirtualPoolTransaction")).Return(nil).Once()
-		poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.AnythingOfType("*interfaces.PoolStateUpdate")).Return(nil).Once()
-
-		err := processor.processSellOrder(context.Background(), order, chainID)
-		assert.NoError(t, err)
-		poolRepo.AssertExpectations(t)
-	})
-
-	t.Run("insufficient balance", func(t *testing.T) {
-		smallPosition := &models.UserVirtualLPPosition{
-			ID:            uuid.New(),
-			UserID:        userID,
-			ChainID:       chainID,
-			VirtualPoolID: poolID,
-			TokenBalance:  100, // Less than order amount
-		}
-
-		poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil).Once()
-		poolRepo.On("GetUserPosition", mock.Anything, userID, chainID).Return(smallPosition, nil).Once()
-
-		err := processor.processSellOrder(context.Background(), order, chainID)
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, ErrInsufficientBalance)
-		poolRepo.AssertExpectations(t)
-	})
-
-	t.Run("position not found", func(t *testing.T) {
-		poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil).Once()
-		poolRepo.On("GetUserPosition", mock.Anything, userID, chainID).Return(nil, nil).Once()
-
-		err := processor.processSellOrder(context.Background(), order, chainID)
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, ErrInsufficientBalance)
-		poolRepo.AssertExpectations(t)
-	})
-}
-
-func TestSimulateBuy(t *testing.T) {
-	poolRepo := new(MockVirtualPoolRepository)
-	userRepo := new(MockUserRepository)
-	processor := NewOrderProcessor(poolRepo, userRepo, nil)
-
-	chainID := uuid.New()
-	pool := &models.VirtualPool{
-		ID:           uuid.New(),
-		ChainID:      chainID,
-		CNPYReserve:  10000.0,
-		TokenReserve: 800000000,
-	}
-
-	t.Run("successful simulation", func(t *testing.T) {
-		poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil).Once()
-
-		result, err := processor.SimulateBuy(context.Background(), chainID, 100.0)
-		assert.NoError(t, err)
-		assert.NotNil(t, result)
-		assert.NotNil(t, result.AmountOut)
-		assert.True(t, result.AmountOut.Sign() > 0)
-		poolRepo.AssertExpectations(t)
-	})
-}
-
-func TestSimulateSell(t *testing.T) {
-	poolRepo := new(MockVirtualPoolRepository)
-	userRepo := new(MockUserRepository)
-	processor := NewOrderProcessor(poolRepo, userRepo, nil)
-
-	chainID := uuid.New()
-	pool := &models.VirtualPool{
-		ID:           uuid.New(),
-		ChainID:      chainID,
-		CNPYReserve:  10000.0,
-		TokenReserve: 800000000,
+		CNPYReserveMicro:  10000000000,
+		TokenReserveUnits: 800000000000000,
+		TokenDecimals:     6,
 	}
 
 	t.Run("successful simulation", func(t *testing.T) {
@@ -476,10 +325,9 @@ func TestSimulateSell(t *testing.T) {
 }
 
 func TestExtractUserID(t *testing.T) {
-	poolRepo := new(MockVirtualPoolRepository)
+	poolRepo := new(MockVirtualPoolTxRepository)
 	userRepo := new(MockUserRepository)
-	processor := NewOrderProcessor(poolRepo, userRepo, nil)
-
+	processor := NewOrderProcessorTx(nil, poolRepo, userRepo, nil)
 	t.Run("valid UUID address", func(t *testing.T) {
 		userID := uuid.New()
 		address := []byte(userID.String())
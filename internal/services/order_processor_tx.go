@@ -28,8 +28,73 @@ var (
 
 	// ErrSerialization indicates a transaction serialization failure
 	ErrSerialization = errors.New("serialization failure")
+
+	// ErrDepositAlreadyApplied indicates a root chain transaction with the same
+	// hash and height has already been applied to its pool
+	ErrDepositAlreadyApplied = errors.New("deposit already applied")
+
+	// ErrPayoutAlreadyQueued indicates a payout with the same reference has
+	// already been queued, so the request that produced it was already honoured
+	ErrPayoutAlreadyQueued = errors.New("payout already queued")
+
+	// ErrDepositRefunded indicates a deposit bought nothing and its whole amount
+	// was queued for refund to the sender instead
+	ErrDepositRefunded = errors.New("deposit refunded")
 )
 
+// Deposit is a CNPY send to a chain's address observed on the root chain. The
+// transaction hash and block height identify it for idempotent ingestion.
+//
+// A deposit with a Sender is refunded to that root chain address when its pool
+// no longer trades, and any part that would lift the pool's CNPY reserve past
+// GraduationThreshold is refunded rather than bought with. A threshold of zero
+// leaves the deposit uncapped.
+//
+// A deposit made against a Quote is refunded in full when the quote had expired
+// by BlockTime, the time of the root chain block that included the send, or
+// when it would buy fewer tokens than the quote's minimum.
+type Deposit struct {
+	ChainID     uuid.UUID
+	UserID      uuid.UUID
+	Amount      uint64 // uCNPY
+	TxHash      string
+	BlockHeight uint64
+
+	Sender              string
+	GraduationThreshold float64 // CNPY
+
+	Quote     *Quote
+	BlockTime time.Time
+}
+
+// Trade is a buy or sell on a chain's virtual pool. Amount is the CNPY spent on
+// a buy or the tokens sold on a sell, and is traded to the nearest uCNPY or
+// token base unit. A trade that already knows the exact amount sets AmountUnits
+// instead, in uCNPY or token base units. Trades settled on the root chain carry
+// the hash and height of their transaction; others leave them empty.
+//
+// A sell with a PayoutAddress queues its CNPY proceeds for payment to that root
+// chain address. PayoutReference identifies the request behind the sell and is
+// accepted only once.
+//
+// ReferrerID is the user credited with the referrer's share of the trade's fee,
+// if any.
+type Trade struct {
+	ChainID      uuid.UUID
+	UserID       uuid.UUID
+	Type         string // models.VirtualTransactionTypeBuy or models.VirtualTransactionTypeSell
+	Amount       *big.Float
+	AmountUnits  *big.Int   // Optional: takes the place of Amount when set
+	MinAmountOut *big.Float // Optional: tokens for a buy, CNPY for a sell
+	TxHash       string
+	BlockHeight  uint64
+
+	PayoutAddress   string
+	PayoutReference string
+
+	ReferrerID *uuid.UUID
+}
+
 const (
 	// MaxRetries is the maximum number of retry attempts for deadlock/serialization failures.
 	// After 3 attempts with exponential backoff (100ms, 200ms, 400ms), the transaction is abandoned.
@@ -48,6 +113,11 @@ const (
 // virtual_pool_transactions, and user_virtual_positions are performed atomically within
 // a single database transaction.
 //
+// Each trade is priced on the bonding curve its chain was created with, built
+// from the curve settings loaded with the pool and the processor's fee config.
+// The fee is paid in uCNPY and recorded in the fee_accruals ledger, split
+// according to the processor's FeeSplit, alongside the trade.
+//
 // Example usage:
 //
 //	processor := NewOrderProcessorTx(db, poolRepo, userRepo, nil)
@@ -59,7 +129,8 @@ type OrderProcessorTx struct {
 	db       *sqlx.DB
 	poolRepo postgres.VirtualPoolTxRepository
 	userRepo interfaces.UserRepository
-	curve    *bondingcurve.BondingCurve
+	config   *bondingcurve.BondingCurveConfig
+	fees     FeeSplit
 }
 
 // NewOrderProcessorTx creates a new transaction-aware order processor.
@@ -68,7 +139,8 @@ type OrderProcessorTx struct {
 //   - db: Database connection for transaction management
 //   - poolRepo: Transaction-aware virtual pool repository
 //   - userRepo: User repository for address-to-user mapping
-//   - curveConfig: Bonding curve configuration (nil uses default 1% fee)
+//   - curveConfig: Bonding curve fee and initial price, shared by every chain's
+//     curve (nil uses default 1% fee)
 //
 // The returned processor is safe for concurrent use by multiple goroutines.
 func NewOrderProcessorTx(
@@ -85,10 +157,17 @@ func NewOrderProcessorTx(
 		db:       db,
 		poolRepo: poolRepo,
 		userRepo: userRepo,
-		curve:    bondingcurve.NewBondingCurve(curveConfig),
+		config:   curveConfig,
+		fees:     DefaultFeeSplit(),
 	}
 }
 
+// SetFeeSplit sets how trading fees are divided among the protocol, chain
+// creators and referrers. It must be called before the processor is used.
+func (op *OrderProcessorTx) SetFeeSplit(split FeeSplit) {
+	op.fees = split
+}
+
 // ProcessOrderWithRetry processes an order with automatic retry on deadlock/serialization failure.
 //
 // This is the main entry point for order processing and should be used by background workers.
@@ -109,10 +188,20 @@ func NewOrderProcessorTx(
 //
 //	err := processor.ProcessOrderWithRetry(ctx, order, chainID)
 //	if errors.Is(err, services.ErrMaxRetries) {
-//	    // Persistent deadlock - move to dead letter queue
-//	    dlq.Enqueue(order)
+//	    // Persistent deadlock - move to dead letter queue for a later retry
+//	    if event, err := services.NewOrderFailedEvent(order, chainID, err); err == nil {
+//	        failedEventRepo.Record(ctx, event)
+//	    }
 //	}
 func (op *OrderProcessorTx) ProcessOrderWithRetry(ctx context.Context, order *lib.SellOrder, chainID uuid.UUID) error {
+	return withRetry(func() error {
+		return op.ProcessOrder(ctx, order, chainID)
+	})
+}
+
+// withRetry runs fn until it succeeds, fails with a non-retryable error, or
+// MaxRetries attempts have been made, backing off exponentially between attempts
+func withRetry(fn func() error) error {
 	var err error
 	for attempt := 0; attempt < MaxRetries; attempt++ {
 		if attempt > 0 {
@@ -121,7 +210,7 @@ func (op *OrderProcessorTx) ProcessOrderWithRetry(ctx context.Context, order *li
 			time.Sleep(delay)
 		}
 
-		err = op.ProcessOrder(ctx, order, chainID)
+		err = fn()
 		if err == nil {
 			return nil
 		}
@@ -132,7 +221,7 @@ func (op *OrderProcessorTx) ProcessOrderWithRetry(ctx context.Context, order *li
 		}
 
 		// Log retry attempt (in production, use proper logging)
-		fmt.Printf("Retrying order processing (attempt %d/%d) due to: %v\n", attempt+1, MaxRetries, err)
+		fmt.Printf("Retrying transaction (attempt %d/%d) due to: %v\n", attempt+1, MaxRetries, err)
 	}
 
 	return fmt.Errorf("%w: %v", ErrMaxRetries, err)
@@ -140,13 +229,8 @@ func (op *OrderProcessorTx) ProcessOrderWithRetry(ctx context.Context, order *li
 
 // ProcessOrder processes a single order within a database transaction.
 //
-// All operations are performed atomically:
-//  1. Acquire row locks on virtual pool and user position (FOR UPDATE)
-//  2. Calculate bonding curve result
-//  3. Update user position (token balance, PnL)
-//  4. Create transaction record
-//  5. Update pool state (reserves, price, volume)
-//  6. Commit transaction (or rollback on any error)
+// The order is converted to a Trade and executed with ExecuteTrade, so it is
+// applied with the same locking and atomicity as every other pool mutation.
 //
 // This method does NOT retry on deadlocks. Use ProcessOrderWithRetry instead.
 //
@@ -165,288 +249,654 @@ func (op *OrderProcessorTx) ProcessOrder(ctx context.Context, order *lib.SellOrd
 	// Determine if this is a buy or sell
 	isBuy := order.AmountForSale > 0 && order.RequestedAmount > 0
 
-	// Execute order processing within a transaction
-	err := database.Transaction(op.db, func(tx *sqlx.Tx) error {
-		if isBuy {
-			return op.processBuyOrderInTx(ctx, tx, order, chainID)
+	trade := &Trade{ChainID: chainID}
+	if isBuy {
+		// Parse user ID from buyer address
+		userID, err := op.extractUserID(order.BuyerReceiveAddress)
+		if err != nil {
+			return fmt.Errorf("invalid buyer address: %w", err)
+		}
+		trade.UserID = userID
+		trade.Type = models.VirtualTransactionTypeBuy
+		trade.Amount = big.NewFloat(float64(order.AmountForSale))
+	} else {
+		// For sell orders, RequestedAmount represents tokens to sell
+		userID, err := op.extractUserID(order.SellersSendAddress)
+		if err != nil {
+			return fmt.Errorf("invalid seller address: %w", err)
 		}
-		return op.processSellOrderInTx(ctx, tx, order, chainID)
+		trade.UserID = userID
+		trade.Type = models.VirtualTransactionTypeSell
+		trade.Amount = big.NewFloat(float64(order.RequestedAmount))
+	}
+
+	_, err := op.ExecuteTrade(ctx, trade)
+	return err
+}
+
+// ProcessDepositWithRetry applies a root chain deposit with automatic retry on
+// deadlock/serialization failure. See ProcessDeposit.
+func (op *OrderProcessorTx) ProcessDepositWithRetry(ctx context.Context, deposit *Deposit) (*bondingcurve.TradeResult, error) {
+	var result *bondingcurve.TradeResult
+	err := withRetry(func() error {
+		var err error
+		result, err = op.ProcessDeposit(ctx, deposit)
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ProcessDeposit buys tokens on a chain's virtual pool with a CNPY deposit made
+// on the root chain.
+//
+// The pool row is locked before the deposit's transaction hash and height are
+// checked, so the same send can only ever be applied once. When it has already
+// been recorded ErrDepositAlreadyApplied is returned and nothing is written;
+// otherwise the pool update, transaction record, position upsert and any
+// refund commit together.
+//
+// When the whole deposit is refunded, because the pool no longer trades, is
+// already at its graduation threshold or cannot fill the deposit as quoted,
+// ErrDepositRefunded is returned after the refund has been committed.
+func (op *OrderProcessorTx) ProcessDeposit(ctx context.Context, deposit *Deposit) (*bondingcurve.TradeResult, error) {
+	if err := validateDeposit(deposit); err != nil {
+		return nil, err
+	}
 
+	var result *bondingcurve.TradeResult
+	err := database.Transaction(op.db, func(tx *sqlx.Tx) error {
+		var err error
+		result, err = op.processDepositInTx(ctx, tx, deposit)
+		return err
+	})
 	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrDepositRefunded
+	}
+
+	return result, nil
+}
+
+// RefundDepositWithRetry refunds a root chain deposit with automatic retry on
+// deadlock/serialization failure. See RefundDeposit.
+func (op *OrderProcessorTx) RefundDepositWithRetry(ctx context.Context, deposit *Deposit) error {
+	return withRetry(func() error {
+		return op.RefundDeposit(ctx, deposit)
+	})
+}
+
+// RefundDeposit queues the whole of a deposit for refund to its sender without
+// touching the chain's pool. It is used for deposits to chains that are not
+// virtual_active. Like ProcessDeposit, a deposit is refunded at most once and
+// ErrDepositAlreadyApplied is returned for a repeat.
+func (op *OrderProcessorTx) RefundDeposit(ctx context.Context, deposit *Deposit) error {
+	if err := validateDeposit(deposit); err != nil {
 		return err
 	}
+	if deposit.Sender == "" {
+		return fmt.Errorf("%w: deposit has no sender to refund", ErrInvalidOrder)
+	}
 
-	return nil
+	return database.Transaction(op.db, func(tx *sqlx.Tx) error {
+		queued, err := op.poolRepo.PayoutExistsInTx(ctx, tx, deposit.TxHash)
+		if err != nil {
+			return err
+		}
+		if queued {
+			return ErrDepositAlreadyApplied
+		}
+		return op.refundDepositInTx(ctx, tx, deposit, models.PayoutTypeInactiveRefund, deposit.Amount, nil)
+	})
 }
 
-// processBuyOrderInTx handles a buy order within a transaction
-func (op *OrderProcessorTx) processBuyOrderInTx(ctx context.Context, tx *sqlx.Tx, order *lib.SellOrder, chainID uuid.UUID) error {
-	// Get the virtual pool with FOR UPDATE lock
-	pool, err := op.poolRepo.GetPoolByChainIDForUpdate(ctx, tx, chainID)
-	if err != nil {
-		return fmt.Errorf("%w: %v", ErrPoolNotFound, err)
+// validateDeposit checks the fields every deposit needs
+func validateDeposit(deposit *Deposit) error {
+	if deposit == nil || deposit.TxHash == "" {
+		return fmt.Errorf("%w: deposit has no transaction hash", ErrInvalidOrder)
+	}
+	if deposit.Amount == 0 {
+		return ErrZeroAmount
 	}
+	return nil
+}
 
-	// Convert order amounts to big.Float for bonding curve calculations
-	cnpyAmountIn := big.NewFloat(float64(order.AmountForSale))
+// processDepositInTx applies a deposit within a transaction. It returns a nil
+// result when the whole deposit was refunded.
+func (op *OrderProcessorTx) processDepositInTx(ctx context.Context, tx *sqlx.Tx, deposit *Deposit) (*bondingcurve.TradeResult, error) {
+	refundable := deposit.Sender != ""
 
-	// Create virtual pool for bonding curve
-	virtualPool := bondingcurve.NewVirtualPool(
-		big.NewFloat(pool.CNPYReserve),
-		big.NewFloat(float64(pool.TokenReserve)),
-		big.NewFloat(float64(pool.TokenReserve)),
-	)
+	pool, err := op.poolRepo.GetPoolByChainIDForUpdate(ctx, tx, deposit.ChainID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPoolNotFound, err)
+	}
+	if !pool.AcceptsTrades() && !refundable {
+		return nil, ErrPoolInactive
+	}
 
-	// Execute the buy on the bonding curve
-	result, err := op.curve.Buy(virtualPool, cnpyAmountIn)
+	applied, err := op.poolRepo.TransactionExistsInTx(ctx, tx, deposit.TxHash, int64(deposit.BlockHeight))
 	if err != nil {
-		if errors.Is(err, bondingcurve.ErrInsufficientReserve) {
-			return ErrInsufficientReserves
+		return nil, err
+	}
+	if !applied && refundable {
+		// A deposit refunded in full leaves no transaction row, only its refund
+		applied, err = op.poolRepo.PayoutExistsInTx(ctx, tx, deposit.TxHash)
+		if err != nil {
+			return nil, err
 		}
-		return fmt.Errorf("bonding curve buy failed: %w", err)
+	}
+	if applied {
+		return nil, ErrDepositAlreadyApplied
 	}
 
-	// Parse user ID from buyer address
-	userID, err := op.extractUserID(order.BuyerReceiveAddress)
-	if err != nil {
-		return fmt.Errorf("invalid buyer address: %w", err)
+	if !pool.AcceptsTrades() {
+		return nil, op.refundDepositInTx(ctx, tx, deposit, models.PayoutTypeInactiveRefund, deposit.Amount, nil)
 	}
 
-	// Get or create user position with FOR UPDATE lock
-	position, err := op.poolRepo.GetUserPositionForUpdate(ctx, tx, userID, chainID)
+	buyAmount := op.depositBuyAmount(pool, deposit)
+	if buyAmount == 0 {
+		return nil, op.refundDepositInTx(ctx, tx, deposit, models.PayoutTypeCapRefund, deposit.Amount, nil)
+	}
+
+	var result *bondingcurve.TradeResult
+	var transaction *models.VirtualPoolTransaction
+	err = deposit.checkQuote()
+	if err == nil {
+		result, transaction, err = op.buyInTx(ctx, tx, pool, depositTrade(pool, deposit, buyAmount))
+	}
+	if refundable && missedQuote(err) {
+		return nil, op.refundDepositInTx(ctx, tx, deposit, models.PayoutTypeSlippageRefund, deposit.Amount, nil)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get user position: %w", err)
+		return nil, err
 	}
 
-	now := time.Now()
-	if position == nil {
-		// Create new position
-		position = &models.UserVirtualLPPosition{
-			UserID:                userID,
-			ChainID:               chainID,
-			VirtualPoolID:         pool.ID,
-			TokenBalance:          0,
-			TotalCNPYInvested:     0,
-			TotalCNPYWithdrawn:    0,
-			AverageEntryPriceCNPY: 0,
-			UnrealizedPnlCNPY:     0,
-			RealizedPnlCNPY:       0,
-			TotalReturnPercent:    0,
-			IsActive:              true,
-			FirstPurchaseAt:       &now,
-			LastActivityAt:        &now,
+	if excess := deposit.Amount - buyAmount; excess > 0 {
+		if err := op.refundDepositInTx(ctx, tx, deposit, models.PayoutTypeCapRefund, excess, &transaction.ID); err != nil {
+			return nil, err
 		}
 	}
 
-	// Update user position with new purchase
-	tokensReceived, _ := result.AmountOut.Int64()
-	cnpySpent, _ := cnpyAmountIn.Float64()
+	return result, nil
+}
 
-	// Calculate new average entry price
-	totalTokensAfter := position.TokenBalance + tokensReceived
-	totalInvestedAfter := position.TotalCNPYInvested + cnpySpent
-	newAveragePrice := totalInvestedAfter / float64(totalTokensAfter)
+// depositBuyAmount returns how much of a deposit, in uCNPY, is spent on tokens:
+// all of it, or only as much as lifts the pool's CNPY reserve to the deposit's
+// graduation threshold once the fee is taken. The rest goes back to the sender.
+func (op *OrderProcessorTx) depositBuyAmount(pool *models.VirtualPool, deposit *Deposit) uint64 {
+	buyAmount := deposit.Amount
+	if deposit.Sender != "" && deposit.GraduationThreshold > 0 {
+		room := uint64(0)
+		if threshold := MicroCNPY(big.NewFloat(deposit.GraduationThreshold)); pool.CNPYReserveMicro < threshold {
+			room = threshold - pool.CNPYReserveMicro
+		}
+		if room == 0 {
+			return 0
+		}
+		withFee, err := op.config.AmountBeforeFeeUnits(new(big.Int).SetUint64(room))
+		if err == nil && withFee.IsUint64() {
+			room = withFee.Uint64()
+		}
+		if buyAmount > room {
+			buyAmount = room
+		}
+	}
+	return buyAmount
+}
 
-	// Calculate fees
-	feeAmount := op.curve.GetConfig().CalculateFee(cnpyAmountIn)
-	tradingFee, _ := feeAmount.Float64()
+// depositTrade is the buy made with buyAmount uCNPY of a deposit to a pool.
+// It must fill at the quoted price or better: the minimum of the deposit's
+// quote is scaled to buyAmount, which differs from the quoted amount when the
+// deposit was capped at the threshold or sent a different amount.
+func depositTrade(pool *models.VirtualPool, deposit *Deposit, buyAmount uint64) *Trade {
+	// Convert amount from micro-CNPY to CNPY (1 CNPY = 1,000,000 uCNPY)
+	cnpyAmountIn := new(big.Float).SetUint64(buyAmount)
+	cnpyAmountIn.Quo(cnpyAmountIn, big.NewFloat(1000000))
+
+	trade := &Trade{
+		ChainID:     deposit.ChainID,
+		UserID:      deposit.UserID,
+		Type:        models.VirtualTransactionTypeBuy,
+		Amount:      cnpyAmountIn,
+		TxHash:      deposit.TxHash,
+		BlockHeight: deposit.BlockHeight,
+	}
+	if deposit.Quote != nil {
+		trade.MinAmountOut = unitAmount(deposit.Quote.MinAmountOutFor(buyAmount), tokenUnit(pool.TokenDecimals))
+		trade.ReferrerID = deposit.Quote.Referrer()
+	}
+	return trade
+}
 
-	// Update position fields
-	position.TokenBalance = totalTokensAfter
-	position.TotalCNPYInvested = totalInvestedAfter
-	position.AverageEntryPriceCNPY = newAveragePrice
-	position.LastActivityAt = &now
-	if position.FirstPurchaseAt == nil {
-		position.FirstPurchaseAt = &now
+// checkQuote checks that the quote a deposit was made against, if any, is for a
+// buy on the deposit's chain and had not expired when the deposit's block was
+// produced
+func (d *Deposit) checkQuote() error {
+	if d.Quote == nil {
+		return nil
 	}
+	return d.Quote.Check(d.ChainID, models.VirtualTransactionTypeBuy, d.BlockTime)
+}
 
-	// Calculate unrealized PnL
-	currentPrice, _ := result.Price.Float64()
-	position.UnrealizedPnlCNPY = (currentPrice - position.AverageEntryPriceCNPY) * float64(position.TokenBalance)
+// missedQuote reports whether a deposit failed because it could not be filled
+// as quoted, in which case it is refunded rather than retried
+func missedQuote(err error) bool {
+	return errors.Is(err, ErrSlippageExceeded) || errors.Is(err, ErrQuoteExpired) || errors.Is(err, ErrInvalidQuote)
+}
 
-	// Calculate total return percentage
-	if position.TotalCNPYInvested > 0 {
-		position.TotalReturnPercent = (position.UnrealizedPnlCNPY / position.TotalCNPYInvested) * 100
+// refundDepositInTx queues amount uCNPY of a deposit for refund to its sender.
+// The deposit's transaction hash is the payout reference.
+func (op *OrderProcessorTx) refundDepositInTx(ctx context.Context, tx *sqlx.Tx, deposit *Deposit, payoutType string, amount uint64, transactionID *uuid.UUID) error {
+	if err := op.poolRepo.CreatePayoutInTx(ctx, tx, newRefund(deposit, payoutType, amount, transactionID)); err != nil {
+		return fmt.Errorf("failed to queue refund: %w", err)
 	}
+	return nil
+}
 
-	// Save user position within transaction
-	if err := op.poolRepo.UpsertUserPositionInTx(ctx, tx, position); err != nil {
-		return fmt.Errorf("failed to update user position: %w", err)
+// newRefund builds the payout returning amount uCNPY of a deposit to its sender
+func newRefund(deposit *Deposit, payoutType string, amount uint64, transactionID *uuid.UUID) *models.Payout {
+	return &models.Payout{
+		ChainID:                  deposit.ChainID,
+		UserID:                   deposit.UserID,
+		VirtualPoolTransactionID: transactionID,
+		PayoutType:               payoutType,
+		RecipientAddress:         deposit.Sender,
+		Amount:                   amount,
+		Reference:                deposit.TxHash,
+		Status:                   models.PayoutStatusPending,
 	}
+}
 
-	// Create transaction record
-	newReserveCNPY, _ := result.NewCNPYReserve.Float64()
-	newReserveToken, _ := result.NewTokenReserve.Int64()
-	priceImpact, _ := result.PriceImpact.Float64()
-	pricePerToken, _ := result.Price.Float64()
+// ExecuteTradeWithRetry executes a trade with automatic retry on
+// deadlock/serialization failure. See ExecuteTrade.
+func (op *OrderProcessorTx) ExecuteTradeWithRetry(ctx context.Context, trade *Trade) (*bondingcurve.TradeResult, error) {
+	var result *bondingcurve.TradeResult
+	err := withRetry(func() error {
+		var err error
+		result, err = op.ExecuteTrade(ctx, trade)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-	transaction := &models.VirtualPoolTransaction{
-		VirtualPoolID:         pool.ID,
-		ChainID:               chainID,
-		UserID:                userID,
-		TransactionType:       "buy",
-		CNPYAmount:            cnpySpent,
-		TokenAmount:           tokensReceived,
-		PricePerTokenCNPY:     pricePerToken,
-		TradingFeeCNPY:        tradingFee,
-		SlippagePercent:       priceImpact,
-		PoolCNPYReserveAfter:  newReserveCNPY,
-		PoolTokenReserveAfter: newReserveToken,
-		MarketCapAfterUSD:     newReserveCNPY,
+// ExecuteTrade applies a buy or sell to a chain's virtual pool in a single
+// database transaction. It is the only path that mutates virtual pools: the
+// pool row and the user's position are locked FOR UPDATE, and the position
+// upsert, transaction record and pool state update commit or roll back
+// together, so a pool is never updated without its transaction row.
+//
+// A trade carrying a root chain transaction hash is applied at most once;
+// ErrDepositAlreadyApplied is returned when its hash and height are already
+// recorded.
+func (op *OrderProcessorTx) ExecuteTrade(ctx context.Context, trade *Trade) (*bondingcurve.TradeResult, error) {
+	if trade == nil {
+		return nil, ErrInvalidOrder
+	}
+	if trade.Type != models.VirtualTransactionTypeBuy && trade.Type != models.VirtualTransactionTypeSell {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidOrderType, trade.Type)
+	}
+	if trade.AmountUnits == nil && (trade.Amount == nil || trade.Amount.Sign() <= 0) {
+		return nil, ErrZeroAmount
+	}
+	if trade.PayoutAddress != "" && (trade.Type != models.VirtualTransactionTypeSell || trade.PayoutReference == "") {
+		return nil, fmt.Errorf("%w: payouts need a sell with a reference", ErrInvalidOrder)
 	}
 
-	if err := op.poolRepo.CreateTransactionInTx(ctx, tx, transaction); err != nil {
-		return fmt.Errorf("failed to create transaction: %w", err)
+	var result *bondingcurve.TradeResult
+	err := database.Transaction(op.db, func(tx *sqlx.Tx) error {
+		var err error
+		result, err = op.executeTradeInTx(ctx, tx, trade)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Update pool state within transaction
-	poolUpdate := &interfaces.PoolStateUpdate{
-		CNPYReserve:      result.NewCNPYReserve,
-		TokenReserve:     result.NewTokenReserve,
-		CurrentPriceCNPY: result.Price,
-		MarketCapUSD:     result.NewCNPYReserve,
-		TotalVolumeCNPY:  big.NewFloat(pool.TotalVolumeCNPY + cnpySpent),
+	return result, nil
+}
+
+// executeTradeInTx applies a trade within a transaction
+func (op *OrderProcessorTx) executeTradeInTx(ctx context.Context, tx *sqlx.Tx, trade *Trade) (*bondingcurve.TradeResult, error) {
+	// Lock the pool first so concurrent trades, and replays of the same block, serialize here
+	pool, err := op.poolRepo.GetPoolByChainIDForUpdate(ctx, tx, trade.ChainID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPoolNotFound, err)
+	}
+	if !pool.AcceptsTrades() {
+		return nil, ErrPoolInactive
 	}
 
-	newTxCount := pool.TotalTransactions + 1
-	poolUpdate.TotalTransactions = &newTxCount
+	if trade.TxHash != "" {
+		applied, err := op.poolRepo.TransactionExistsInTx(ctx, tx, trade.TxHash, int64(trade.BlockHeight))
+		if err != nil {
+			return nil, err
+		}
+		if applied {
+			return nil, ErrDepositAlreadyApplied
+		}
+	}
 
-	if err := op.poolRepo.UpdatePoolStateInTx(ctx, tx, chainID, poolUpdate); err != nil {
-		return fmt.Errorf("failed to update pool state: %w", err)
+	if trade.PayoutReference != "" {
+		queued, err := op.poolRepo.PayoutExistsInTx(ctx, tx, trade.PayoutReference)
+		if err != nil {
+			return nil, err
+		}
+		if queued {
+			return nil, ErrPayoutAlreadyQueued
+		}
 	}
 
-	return nil
+	if trade.Type == models.VirtualTransactionTypeBuy {
+		result, _, err := op.buyInTx(ctx, tx, pool, trade)
+		return result, err
+	}
+	return op.sellInTx(ctx, tx, pool, trade)
 }
 
-// processSellOrderInTx handles a sell order within a transaction
-func (op *OrderProcessorTx) processSellOrderInTx(ctx context.Context, tx *sqlx.Tx, order *lib.SellOrder, chainID uuid.UUID) error {
-	// Get the virtual pool with FOR UPDATE lock
-	pool, err := op.poolRepo.GetPoolByChainIDForUpdate(ctx, tx, chainID)
+// buyInTx spends trade.Amount CNPY on tokens from a locked pool and returns the
+// transaction it recorded
+func (op *OrderProcessorTx) buyInTx(ctx context.Context, tx *sqlx.Tx, pool *models.VirtualPool, trade *Trade) (*bondingcurve.TradeResult, *models.VirtualPoolTransaction, error) {
+	result, units, err := op.priceBuy(pool, trade)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrPoolNotFound, err)
+		return nil, nil, err
 	}
 
-	// For sell orders, RequestedAmount represents tokens to sell
-	tokenAmountIn := big.NewFloat(float64(order.RequestedAmount))
+	// Get or create user position with FOR UPDATE lock
+	position, err := op.poolRepo.GetUserPositionForUpdate(ctx, tx, trade.UserID, trade.ChainID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user position: %w", err)
+	}
 
-	// Create virtual pool for bonding curve
-	virtualPool := bondingcurve.NewVirtualPool(
-		big.NewFloat(pool.CNPYReserve),
-		big.NewFloat(float64(pool.TokenReserve)),
-		big.NewFloat(float64(pool.TokenReserve)),
-	)
+	now := time.Now()
+	if position == nil {
+		position = newPosition(pool, trade, now)
+	}
+	transaction := op.applyBuy(pool, position, trade, result, units, now)
 
-	// Execute the sell on the bonding curve
-	result, err := op.curve.Sell(virtualPool, tokenAmountIn)
+	if err := op.poolRepo.UpsertUserPositionInTx(ctx, tx, position); err != nil {
+		return nil, nil, fmt.Errorf("failed to update user position: %w", err)
+	}
+
+	if err := op.recordTradeInTx(ctx, tx, pool, trade, units, transaction); err != nil {
+		return nil, nil, err
+	}
+
+	return result, transaction, nil
+}
+
+// curveFor builds the bonding curve of a pool's chain
+func (op *OrderProcessorTx) curveFor(pool *models.VirtualPool) (bondingcurve.Curve, error) {
+	params := curveParams(pool.CurveType, pool.BondingCurveSlope, pool.CurveMaxPrice, pool.CurveMidpointSupply)
+	curve, err := bondingcurve.NewCurve(params, op.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build bonding curve for chain %s: %w", pool.ChainID, err)
+	}
+	return curve, nil
+}
+
+// curveParams maps a chain's bonding curve settings onto curve parameters
+func curveParams(curveType string, slope float64, maxPrice *float64, midpointSupply *int64) bondingcurve.CurveParams {
+	params := bondingcurve.CurveParams{
+		Type:  bondingcurve.CurveType(curveType),
+		Slope: slope,
+	}
+	if maxPrice != nil {
+		params.MaxPrice = *maxPrice
+	}
+	if midpointSupply != nil {
+		params.MidpointSupply = float64(*midpointSupply)
+	}
+	return params
+}
+
+// priceBuy runs a buy of trade.Amount CNPY against a pool's current reserves
+// without changing anything
+func (op *OrderProcessorTx) priceBuy(pool *models.VirtualPool, trade *Trade) (*bondingcurve.TradeResult, *bondingcurve.IntTradeResult, error) {
+	cnpyIn := trade.amountUnits(big.NewInt(bondingcurve.MicroCNPYPerCNPY))
+	if cnpyIn.Sign() <= 0 {
+		return nil, nil, ErrZeroAmount
+	}
+
+	result, units, err := op.tradeUnits(pool, true, cnpyIn)
 	if err != nil {
 		if errors.Is(err, bondingcurve.ErrInsufficientReserve) {
-			return ErrInsufficientReserves
-		}
-		if errors.Is(err, bondingcurve.ErrInsufficientTokens) {
-			return ErrInsufficientBalance
+			return nil, nil, ErrInsufficientReserves
 		}
-		return fmt.Errorf("bonding curve sell failed: %w", err)
+		return nil, nil, fmt.Errorf("bonding curve buy failed: %w", err)
+	}
+	if trade.MinAmountOut != nil && result.AmountOut.Cmp(trade.MinAmountOut) < 0 {
+		return nil, nil, ErrSlippageExceeded
+	}
+
+	return result, units, nil
+}
+
+// tradeUnits prices a trade against a pool's exact reserves: a buy of amount
+// uCNPY or a sell of amount token base units. The pool the trade leaves behind
+// is checked against the curve's invariant before the trade is returned, both
+// in base units and converted to whole CNPY and tokens.
+func (op *OrderProcessorTx) tradeUnits(pool *models.VirtualPool, buy bool, amount *big.Int) (*bondingcurve.TradeResult, *bondingcurve.IntTradeResult, error) {
+	curve, err := op.curveFor(pool)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Parse user ID from seller address
-	userID, err := op.extractUserID(order.SellersSendAddress)
+	before := poolUnits(pool)
+	var units *bondingcurve.IntTradeResult
+	if buy {
+		units, err = curve.BuyUnits(before, amount)
+	} else {
+		units, err = curve.SellUnits(before, amount)
+	}
 	if err != nil {
-		return fmt.Errorf("invalid seller address: %w", err)
+		return nil, nil, err
+	}
+
+	if err := curve.CheckInvariant(before, before.After(units)); err != nil {
+		return nil, nil, fmt.Errorf("trade on chain %s: %w", pool.ChainID, err)
+	}
+
+	return units.TradeResult(before, buy), units, nil
+}
+
+// newPosition starts the position of a user who has not traded on a pool before
+func newPosition(pool *models.VirtualPool, trade *Trade, now time.Time) *models.UserVirtualLPPosition {
+	return &models.UserVirtualLPPosition{
+		UserID:          trade.UserID,
+		ChainID:         trade.ChainID,
+		VirtualPoolID:   pool.ID,
+		IsActive:        true,
+		FirstPurchaseAt: &now,
+	}
+}
+
+// applyBuy credits a priced buy to the buyer's position and returns the
+// transaction that records it. Nothing is written.
+func (op *OrderProcessorTx) applyBuy(pool *models.VirtualPool, position *models.UserVirtualLPPosition, trade *Trade, result *bondingcurve.TradeResult, units *bondingcurve.IntTradeResult, now time.Time) *models.VirtualPoolTransaction {
+	currentPrice, _ := result.Price.Float64()
+
+	// Update the position with the weighted average entry price
+	position.TokenBalanceUnits += units.AmountOut.Uint64()
+	position.TotalCNPYInvestedMicro += units.AmountIn.Uint64()
+	position.TokenBalance = wholeTokens(position.TokenBalanceUnits, pool.TokenDecimals)
+	position.TotalCNPYInvested = cnpy(position.TotalCNPYInvestedMicro)
+	tokensHeld := tokens(position.TokenBalanceUnits, pool.TokenDecimals)
+	if tokensHeld > 0 {
+		position.AverageEntryPriceCNPY = position.TotalCNPYInvested / tokensHeld
+	}
+	position.UnrealizedPnlCNPY = currentPrice*tokensHeld - position.TotalCNPYInvested
+	if position.TotalCNPYInvested > 0 {
+		position.TotalReturnPercent = (position.UnrealizedPnlCNPY / position.TotalCNPYInvested) * 100
+	}
+	position.IsActive = true
+	position.LastActivityAt = &now
+	if position.FirstPurchaseAt == nil {
+		position.FirstPurchaseAt = &now
 	}
 
+	return newTradeTransaction(pool, trade, result, units, units.AmountIn.Uint64(), units.AmountOut.Uint64())
+}
+
+// sellInTx sells trade.Amount tokens back to a locked pool for CNPY
+func (op *OrderProcessorTx) sellInTx(ctx context.Context, tx *sqlx.Tx, pool *models.VirtualPool, trade *Trade) (*bondingcurve.TradeResult, error) {
 	// Get user position with FOR UPDATE lock
-	position, err := op.poolRepo.GetUserPositionForUpdate(ctx, tx, userID, chainID)
+	position, err := op.poolRepo.GetUserPositionForUpdate(ctx, tx, trade.UserID, trade.ChainID)
 	if err != nil {
-		return fmt.Errorf("failed to get user position: %w", err)
+		return nil, fmt.Errorf("failed to get user position: %w", err)
 	}
 	if position == nil {
-		return ErrInsufficientBalance
+		return nil, ErrInsufficientBalance
 	}
 
 	// Verify user has enough tokens
-	tokensSold, _ := tokenAmountIn.Int64()
-	if position.TokenBalance < tokensSold {
-		return ErrInsufficientBalance
+	tokensSold := trade.amountUnits(tokenUnit(pool.TokenDecimals))
+	if tokensSold.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+	if !tokensSold.IsUint64() || position.TokenBalanceUnits < tokensSold.Uint64() {
+		return nil, ErrInsufficientBalance
+	}
+
+	// Execute the sell on the bonding curve
+	result, units, err := op.tradeUnits(pool, false, tokensSold)
+	if err != nil {
+		if errors.Is(err, bondingcurve.ErrInsufficientReserve) {
+			return nil, ErrInsufficientReserves
+		}
+		if errors.Is(err, bondingcurve.ErrInsufficientTokens) {
+			return nil, ErrInsufficientBalance
+		}
+		return nil, fmt.Errorf("bonding curve sell failed: %w", err)
+	}
+	if trade.MinAmountOut != nil && result.AmountOut.Cmp(trade.MinAmountOut) < 0 {
+		return nil, ErrSlippageExceeded
 	}
 
 	// Calculate proceeds from sale
 	cnpyReceived, _ := result.AmountOut.Float64()
 	pricePerToken, _ := result.Price.Float64()
 
-	// Calculate fees
-	feeAmount := op.curve.GetConfig().CalculateFee(result.AmountOut)
-	tradingFee, _ := feeAmount.Float64()
-
 	// Calculate realized PnL for this sale
-	costBasis := position.AverageEntryPriceCNPY * float64(tokensSold)
+	costBasis := position.AverageEntryPriceCNPY * tokens(tokensSold.Uint64(), pool.TokenDecimals)
 	realizedPnL := cnpyReceived - costBasis
 
 	now := time.Now()
 
 	// Update position fields
-	position.TokenBalance -= tokensSold
-	position.TotalCNPYWithdrawn += cnpyReceived
+	position.TokenBalanceUnits -= tokensSold.Uint64()
+	position.TotalCNPYWithdrawnMicro += units.AmountOut.Uint64()
+	position.TokenBalance = wholeTokens(position.TokenBalanceUnits, pool.TokenDecimals)
+	position.TotalCNPYWithdrawn = cnpy(position.TotalCNPYWithdrawnMicro)
 	position.RealizedPnlCNPY += realizedPnL
 	position.LastActivityAt = &now
 
 	// Recalculate unrealized PnL with remaining balance
-	if position.TokenBalance > 0 {
-		currentPrice, _ := result.Price.Float64()
-		position.UnrealizedPnlCNPY = (currentPrice - position.AverageEntryPriceCNPY) * float64(position.TokenBalance)
+	tokensHeld := tokens(position.TokenBalanceUnits, pool.TokenDecimals)
+	if position.TokenBalanceUnits > 0 {
+		position.UnrealizedPnlCNPY = (pricePerToken - position.AverageEntryPriceCNPY) * tokensHeld
 	} else {
 		position.UnrealizedPnlCNPY = 0
 		position.IsActive = false
 	}
 
 	// Calculate total return percentage
-	totalValue := position.TotalCNPYWithdrawn + (pricePerToken * float64(position.TokenBalance))
+	totalValue := position.TotalCNPYWithdrawn + (pricePerToken * tokensHeld)
 	if position.TotalCNPYInvested > 0 {
 		position.TotalReturnPercent = ((totalValue - position.TotalCNPYInvested) / position.TotalCNPYInvested) * 100
 	}
 
 	// Save user position within transaction
 	if err := op.poolRepo.UpsertUserPositionInTx(ctx, tx, position); err != nil {
-		return fmt.Errorf("failed to update user position: %w", err)
+		return nil, fmt.Errorf("failed to update user position: %w", err)
+	}
+
+	transaction := newTradeTransaction(pool, trade, result, units, units.AmountOut.Uint64(), tokensSold.Uint64())
+	if err := op.recordTradeInTx(ctx, tx, pool, trade, units, transaction); err != nil {
+		return nil, err
+	}
+
+	if trade.PayoutAddress != "" {
+		payout := &models.Payout{
+			ChainID:                  trade.ChainID,
+			UserID:                   trade.UserID,
+			VirtualPoolTransactionID: &transaction.ID,
+			PayoutType:               models.PayoutTypeSellProceeds,
+			RecipientAddress:         trade.PayoutAddress,
+			Amount:                   units.AmountOut.Uint64(),
+			Reference:                trade.PayoutReference,
+			Status:                   models.PayoutStatusPending,
+		}
+		if payout.Amount == 0 {
+			return nil, fmt.Errorf("%w: sale proceeds round to zero uCNPY", ErrZeroAmount)
+		}
+		if err := op.poolRepo.CreatePayoutInTx(ctx, tx, payout); err != nil {
+			return nil, fmt.Errorf("failed to queue payout: %w", err)
+		}
 	}
 
-	// Create transaction record
+	return result, nil
+}
+
+// newTradeTransaction builds the transaction row for a trade on a pool that
+// moved cnpyAmount uCNPY and tokenAmount token base units
+func newTradeTransaction(pool *models.VirtualPool, trade *Trade, result *bondingcurve.TradeResult, units *bondingcurve.IntTradeResult, cnpyAmount, tokenAmount uint64) *models.VirtualPoolTransaction {
 	newReserveCNPY, _ := result.NewCNPYReserve.Float64()
 	newReserveToken, _ := result.NewTokenReserve.Int64()
 	priceImpact, _ := result.PriceImpact.Float64()
+	pricePerToken, _ := result.Price.Float64()
 
 	transaction := &models.VirtualPoolTransaction{
-		VirtualPoolID:         pool.ID,
-		ChainID:               chainID,
-		UserID:                userID,
-		TransactionType:       "sell",
-		CNPYAmount:            cnpyReceived,
-		TokenAmount:           tokensSold,
-		PricePerTokenCNPY:     pricePerToken,
-		TradingFeeCNPY:        tradingFee,
-		SlippagePercent:       priceImpact,
-		PoolCNPYReserveAfter:  newReserveCNPY,
-		PoolTokenReserveAfter: newReserveToken,
-		MarketCapAfterUSD:     newReserveCNPY,
+		VirtualPoolID:              pool.ID,
+		ChainID:                    trade.ChainID,
+		UserID:                     trade.UserID,
+		TransactionType:            trade.Type,
+		CNPYAmount:                 cnpy(cnpyAmount),
+		TokenAmount:                wholeTokens(tokenAmount, pool.TokenDecimals),
+		CNPYAmountMicro:            cnpyAmount,
+		TokenAmountUnits:           tokenAmount,
+		PricePerTokenCNPY:          pricePerToken,
+		TradingFeeCNPY:             cnpy(units.Fee.Uint64()),
+		SlippagePercent:            priceImpact,
+		PoolCNPYReserveAfter:       newReserveCNPY,
+		PoolTokenReserveAfter:      newReserveToken,
+		PoolCNPYReserveAfterMicro:  units.NewCNPYReserve.Uint64(),
+		PoolTokenReserveAfterUnits: units.NewTokenReserve.Uint64(),
+		MarketCapAfterUSD:          newReserveCNPY,
 	}
+	if trade.TxHash != "" {
+		txHash := trade.TxHash
+		blockHeight := int64(trade.BlockHeight)
+		transaction.TransactionHash = &txHash
+		transaction.BlockHeight = &blockHeight
+	}
+
+	return transaction
+}
 
+// recordTradeInTx writes the transaction row for a trade, the split of its fee
+// and the pool state it leaves behind
+func (op *OrderProcessorTx) recordTradeInTx(ctx context.Context, tx *sqlx.Tx, pool *models.VirtualPool, trade *Trade, units *bondingcurve.IntTradeResult, transaction *models.VirtualPoolTransaction) error {
 	if err := op.poolRepo.CreateTransactionInTx(ctx, tx, transaction); err != nil {
 		return fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	// Update pool state within transaction
-	poolUpdate := &interfaces.PoolStateUpdate{
-		CNPYReserve:      result.NewCNPYReserve,
-		TokenReserve:     result.NewTokenReserve,
-		CurrentPriceCNPY: result.Price,
-		MarketCapUSD:     result.NewCNPYReserve,
-		TotalVolumeCNPY:  big.NewFloat(pool.TotalVolumeCNPY + cnpyReceived),
+	if accruals := op.fees.accruals(pool, trade, transaction.ID, units.Fee.Uint64()); len(accruals) > 0 {
+		if err := op.poolRepo.CreateFeeAccrualsInTx(ctx, tx, accruals); err != nil {
+			return fmt.Errorf("failed to record fee accruals: %w", err)
+		}
 	}
 
-	newTxCount := pool.TotalTransactions + 1
-	poolUpdate.TotalTransactions = &newTxCount
-
-	if err := op.poolRepo.UpdatePoolStateInTx(ctx, tx, chainID, poolUpdate); err != nil {
+	// Update pool state within transaction
+	after := *pool
+	advancePool(&after, units, transaction.CNPYAmount)
+	if err := op.poolRepo.UpdatePoolStateInTx(ctx, tx, trade.ChainID, poolStateUpdate(&after)); err != nil {
 		return fmt.Errorf("failed to update pool state: %w", err)
 	}
 
@@ -529,5 +979,155 @@ func isRetryableError(err error) bool {
 // The configuration includes the fee rate in basis points (e.g., 100 = 1%).
 // This can be used to inspect current fee settings or for simulation purposes.
 func (op *OrderProcessorTx) GetConfig() *bondingcurve.BondingCurveConfig {
-	return op.curve.GetConfig()
+	return op.config
+}
+
+// SimulateBuy simulates a buy order without executing it
+func (op *OrderProcessorTx) SimulateBuy(ctx context.Context, chainID uuid.UUID, cnpyAmount float64) (*bondingcurve.TradeResult, error) {
+	pool, err := op.poolRepo.GetPoolByChainID(ctx, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPoolNotFound, err)
+	}
+
+	result, _, err := op.tradeUnits(pool, true, baseUnits(big.NewFloat(cnpyAmount), big.NewInt(bondingcurve.MicroCNPYPerCNPY)))
+	return result, err
+}
+
+// SimulateSell simulates a sell order without executing it
+func (op *OrderProcessorTx) SimulateSell(ctx context.Context, chainID uuid.UUID, tokenAmount int64) (*bondingcurve.TradeResult, error) {
+	pool, err := op.poolRepo.GetPoolByChainID(ctx, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPoolNotFound, err)
+	}
+
+	result, _, err := op.tradeUnits(pool, false, baseUnits(big.NewFloat(float64(tokenAmount)), tokenUnit(pool.TokenDecimals)))
+	return result, err
+}
+
+// QuoteTrade prices a buy of amount CNPY or a sell of amount tokens on a
+// chain's pool at its current reserves, without executing it. The quote's
+// minimum is the amount out less slippageBasisPoints; its token and expiry are
+// left for the caller to set.
+func (op *OrderProcessorTx) QuoteTrade(ctx context.Context, chainID uuid.UUID, side string, amount *big.Float, slippageBasisPoints uint64) (*TradeQuote, error) {
+	pool, err := op.poolRepo.GetPoolByChainID(ctx, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPoolNotFound, err)
+	}
+	if !pool.AcceptsTrades() {
+		return nil, ErrPoolInactive
+	}
+
+	buy := side == models.VirtualTransactionTypeBuy
+	unitIn, unitOut := tokenUnit(pool.TokenDecimals), big.NewInt(bondingcurve.MicroCNPYPerCNPY)
+	if buy {
+		unitIn, unitOut = unitOut, unitIn
+	}
+	amountIn := baseUnits(amount, unitIn)
+	if amountIn.Sign() <= 0 || !amountIn.IsUint64() {
+		return nil, ErrZeroAmount
+	}
+
+	result, units, err := op.tradeUnits(pool, buy, amountIn)
+	if err != nil {
+		switch {
+		case errors.Is(err, bondingcurve.ErrInsufficientReserve), errors.Is(err, bondingcurve.ErrInsufficientTokens):
+			return nil, ErrInsufficientReserves
+		case errors.Is(err, bondingcurve.ErrZeroAmount), errors.Is(err, bondingcurve.ErrInvalidAmount):
+			return nil, ErrZeroAmount
+		}
+		return nil, err
+	}
+	curve, err := op.curveFor(pool)
+	if err != nil {
+		return nil, err
+	}
+	before := poolUnits(pool)
+
+	effectivePrice, _ := units.Price.Float64()
+	impact, _ := units.PriceImpact.Float64()
+	postTradePrice, _ := curve.MarginalPrice(before.After(units)).Float64()
+	amountInValue, _ := result.AmountIn.Float64()
+	amountOut, _ := result.AmountOut.Float64()
+	fee := cnpy(units.Fee.Uint64())
+
+	minOut := new(big.Int).Mul(units.AmountOut, new(big.Int).SetUint64(bondingcurve.BasisPointsDivisor-slippageBasisPoints))
+	minOut.Quo(minOut, big.NewInt(bondingcurve.BasisPointsDivisor))
+	minAmountOut, _ := unitAmount(minOut.Uint64(), unitOut).Float64()
+
+	return &TradeQuote{
+		ChainID:             chainID,
+		Side:                side,
+		AmountIn:            amountInValue,
+		AmountInUnits:       amountIn.Uint64(),
+		AmountOut:           amountOut,
+		AmountOutUnits:      units.AmountOut.Uint64(),
+		Fee:                 fee,
+		FeeUnits:            units.Fee.Uint64(),
+		EffectivePrice:      effectivePrice,
+		PriceImpact:         impact,
+		PostTradePrice:      postTradePrice,
+		SlippageBasisPoints: slippageBasisPoints,
+		MinAmountOut:        minAmountOut,
+		MinAmountOutUnits:   minOut.Uint64(),
+	}, nil
+}
+
+// amountUnits is the trade's amount in base units, unit of which make one
+func (t *Trade) amountUnits(unit *big.Int) *big.Int {
+	if t.AmountUnits != nil {
+		return new(big.Int).Set(t.AmountUnits)
+	}
+	return baseUnits(t.Amount, unit)
+}
+
+// poolUnits is a pool's exact state for pricing, with the token reserve
+// standing in for total supply as it always has for virtual pools
+func poolUnits(pool *models.VirtualPool) *bondingcurve.IntPool {
+	tokenReserve := new(big.Int).SetUint64(pool.TokenReserveUnits)
+	return bondingcurve.NewIntPool(new(big.Int).SetUint64(pool.CNPYReserveMicro), tokenReserve, tokenReserve, uint8(pool.TokenDecimals))
+}
+
+// tokenUnit is the number of base units in one whole token of a chain
+func tokenUnit(decimals int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+}
+
+// baseUnits converts a whole amount to base units, unit of which make one,
+// rounded to the nearest base unit
+func baseUnits(amount *big.Float, unit *big.Int) *big.Int {
+	scaled := new(big.Float).SetPrec(bondingcurve.Precision).Mul(amount, new(big.Float).SetInt(unit))
+	if scaled.Sign() < 0 {
+		return new(big.Int)
+	}
+	rounded, _ := scaled.Add(scaled, big.NewFloat(0.5)).Int(nil)
+	return rounded
+}
+
+// unitAmount converts base units, unit of which make one, to a whole amount at
+// the precision trades are priced at, so that it compares exactly with a
+// trade's amounts
+func unitAmount(amount uint64, unit *big.Int) *big.Float {
+	value := new(big.Float).SetPrec(bondingcurve.Precision).SetUint64(amount)
+	return value.Quo(value, new(big.Float).SetPrec(bondingcurve.Precision).SetInt(unit))
+}
+
+// cnpy converts uCNPY to CNPY
+func cnpy(micro uint64) float64 {
+	return float64(micro) / bondingcurve.MicroCNPYPerCNPY
+}
+
+// tokens converts token base units to tokens
+func tokens(units uint64, decimals int) float64 {
+	value, _ := new(big.Float).Quo(new(big.Float).SetUint64(units), new(big.Float).SetInt(tokenUnit(decimals))).Float64()
+	return value
+}
+
+// wholeTokens converts token base units to whole tokens, rounding down
+func wholeTokens(units uint64, decimals int) int64 {
+	return int64(units / tokenUnit(decimals).Uint64())
+}
+
+// MicroCNPY converts a CNPY amount to uCNPY, rounded to the nearest uCNPY
+func MicroCNPY(amount *big.Float) uint64 {
+	return baseUnits(amount, big.NewInt(bondingcurve.MicroCNPYPerCNPY)).Uint64()
 }
@@ -2,15 +2,22 @@ package services
 
 import (
 	"context"
+	"errors"
+	"math/big"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/canopy-network/canopy/lib"
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/interfaces"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockVirtualPoolTxRepository mocks the VirtualPoolTxRepository
@@ -36,6 +43,11 @@ func (m *MockVirtualPoolTxRepository) CreateTransactionInTx(ctx context.Context,
 	return args.Error(0)
 }
 
+func (m *MockVirtualPoolTxRepository) TransactionExistsInTx(ctx context.Context, tx *sqlx.Tx, txHash string, blockHeight int64) (bool, error) {
+	args := m.Called(ctx, tx, txHash, blockHeight)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockVirtualPoolTxRepository) GetUserPositionForUpdate(ctx context.Context, tx *sqlx.Tx, userID, chainID uuid.UUID) (*models.UserVirtualLPPosition, error) {
 	args := m.Called(ctx, tx, userID, chainID)
 	if args.Get(0) == nil {
@@ -49,6 +61,60 @@ func (m *MockVirtualPoolTxRepository) UpsertUserPositionInTx(ctx context.Context
 	return args.Error(0)
 }
 
+func (m *MockVirtualPoolTxRepository) PayoutExistsInTx(ctx context.Context, tx *sqlx.Tx, reference string) (bool, error) {
+	args := m.Called(ctx, tx, reference)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockVirtualPoolTxRepository) CreatePayoutInTx(ctx context.Context, tx *sqlx.Tx, payout *models.Payout) error {
+	args := m.Called(ctx, tx, payout)
+	return args.Error(0)
+}
+
+func (m *MockVirtualPoolTxRepository) GetPoolsByChainIDsForUpdate(ctx context.Context, tx *sqlx.Tx, chainIDs []uuid.UUID) ([]models.VirtualPool, error) {
+	args := m.Called(ctx, tx, chainIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.VirtualPool), args.Error(1)
+}
+
+func (m *MockVirtualPoolTxRepository) AppliedDepositsInTx(ctx context.Context, tx *sqlx.Tx, txHashes []string, blockHeight int64) (map[string]bool, error) {
+	args := m.Called(ctx, tx, txHashes, blockHeight)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]bool), args.Error(1)
+}
+
+func (m *MockVirtualPoolTxRepository) GetUserPositionsForUpdate(ctx context.Context, tx *sqlx.Tx, userIDs, chainIDs []uuid.UUID) ([]models.UserVirtualLPPosition, error) {
+	args := m.Called(ctx, tx, userIDs, chainIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UserVirtualLPPosition), args.Error(1)
+}
+
+func (m *MockVirtualPoolTxRepository) CreateTransactionsInTx(ctx context.Context, tx *sqlx.Tx, transactions []*models.VirtualPoolTransaction) error {
+	args := m.Called(ctx, tx, transactions)
+	return args.Error(0)
+}
+
+func (m *MockVirtualPoolTxRepository) UpsertUserPositionsInTx(ctx context.Context, tx *sqlx.Tx, positions []*models.UserVirtualLPPosition) error {
+	args := m.Called(ctx, tx, positions)
+	return args.Error(0)
+}
+
+func (m *MockVirtualPoolTxRepository) CreatePayoutsInTx(ctx context.Context, tx *sqlx.Tx, payouts []*models.Payout) error {
+	args := m.Called(ctx, tx, payouts)
+	return args.Error(0)
+}
+
+func (m *MockVirtualPoolTxRepository) CreateFeeAccrualsInTx(ctx context.Context, tx *sqlx.Tx, accruals []*models.FeeAccrual) error {
+	args := m.Called(ctx, tx, accruals)
+	return args.Error(0)
+}
+
 func TestIsRetryableError(t *testing.T) {
 	t.Run("nil error", func(t *testing.T) {
 		assert.False(t, isRetryableError(nil))
@@ -102,6 +168,801 @@ func TestOrderProcessorTx_ValidateOrder(t *testing.T) {
 	})
 }
 
+func TestOrderProcessorTx_ProcessDeposit(t *testing.T) {
+	chainID := uuid.New()
+	userID := uuid.New()
+	pool := &models.VirtualPool{
+		ID:                uuid.New(),
+		ChainID:           chainID,
+		CNPYReserve:       30,
+		TokenReserve:      800000000,
+		CNPYReserveMicro:  30000000,
+		TokenReserveUnits: 800000000000000,
+		TokenDecimals:     6,
+		TotalTransactions: 4,
+		IsActive:          true,
+		ChainStatus:       models.ChainStatusVirtualActive,
+	}
+	deposit := &Deposit{
+		ChainID:     chainID,
+		UserID:      userID,
+		Amount:      1000000, // 1 CNPY
+		TxHash:      "0xabc123",
+		BlockHeight: 1000,
+	}
+
+	newProcessor := func(t *testing.T) (*OrderProcessorTx, *MockVirtualPoolTxRepository, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		poolRepo := new(MockVirtualPoolTxRepository)
+		return NewOrderProcessorTx(sqlx.NewDb(db, "sqlmock"), poolRepo, new(MockUserRepository), nil), poolRepo, mock
+	}
+
+	t.Run("new deposit updates pool, transaction and position together", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(nil, nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(position *models.UserVirtualLPPosition) bool {
+			return position.UserID == userID && position.TokenBalance > 0 && position.TotalCNPYInvested == 1
+		})).Return(nil)
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(tx *models.VirtualPoolTransaction) bool {
+			return tx.TransactionType == "buy" &&
+				tx.TransactionHash != nil && *tx.TransactionHash == "0xabc123" &&
+				tx.BlockHeight != nil && *tx.BlockHeight == 1000
+		})).Return(nil)
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.MatchedBy(func(update *interfaces.PoolStateUpdate) bool {
+			return update.TotalTransactions != nil && *update.TotalTransactions == 5
+		})).Return(nil)
+
+		result, err := processor.ProcessDeposit(context.Background(), deposit)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.NewCNPYReserve.Cmp(big.NewFloat(30)))
+		poolRepo.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("already applied deposit writes nothing", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(true, nil)
+
+		result, err := processor.ProcessDeposit(context.Background(), deposit)
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, ErrDepositAlreadyApplied)
+		poolRepo.AssertNotCalled(t, "UpdatePoolStateInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		poolRepo.AssertNotCalled(t, "CreateTransactionInTx", mock.Anything, mock.Anything, mock.Anything)
+		poolRepo.AssertNotCalled(t, "UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("failed transaction insert rolls back", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(nil, nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("duplicate key value"))
+
+		_, err := processor.ProcessDeposit(context.Background(), deposit)
+		assert.ErrorContains(t, err, "failed to create transaction")
+		poolRepo.AssertNotCalled(t, "UpdatePoolStateInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("inactive pool", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		inactive := *pool
+		inactive.IsActive = false
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(&inactive, nil)
+
+		_, err := processor.ProcessDeposit(context.Background(), deposit)
+		assert.ErrorIs(t, err, ErrPoolInactive)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("deposit without hash is rejected", func(t *testing.T) {
+		processor, _, _ := newProcessor(t)
+		_, err := processor.ProcessDeposit(context.Background(), &Deposit{ChainID: chainID, UserID: userID, Amount: 1})
+		assert.ErrorIs(t, err, ErrInvalidOrder)
+	})
+}
+
+func TestOrderProcessorTx_DepositRefunds(t *testing.T) {
+	chainID := uuid.New()
+	userID := uuid.New()
+	sender := "0x" + strings.Repeat("ab", 20)
+	pool := &models.VirtualPool{
+		ID:                uuid.New(),
+		ChainID:           chainID,
+		CNPYReserve:       49.5,
+		TokenReserve:      800000000,
+		CNPYReserveMicro:  49500000,
+		TokenReserveUnits: 800000000000000,
+		TokenDecimals:     6,
+		TotalTransactions: 4,
+		IsActive:          true,
+		ChainStatus:       models.ChainStatusVirtualActive,
+	}
+	deposit := &Deposit{
+		ChainID:             chainID,
+		UserID:              userID,
+		Amount:              2000000, // 2 CNPY
+		TxHash:              "0xabc123",
+		BlockHeight:         1000,
+		Sender:              sender,
+		GraduationThreshold: 50,
+	}
+
+	newProcessor := func(t *testing.T) (*OrderProcessorTx, *MockVirtualPoolTxRepository, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		poolRepo := new(MockVirtualPoolTxRepository)
+		return NewOrderProcessorTx(sqlx.NewDb(db, "sqlmock"), poolRepo, new(MockUserRepository), nil), poolRepo, mock
+	}
+	refund := func(payoutType string, amount uint64) interface{} {
+		return mock.MatchedBy(func(payout *models.Payout) bool {
+			return payout.PayoutType == payoutType &&
+				payout.Amount == amount &&
+				payout.RecipientAddress == sender &&
+				payout.Reference == "0xabc123" &&
+				payout.Status == models.PayoutStatusPending
+		})
+	}
+
+	t.Run("deposit past the threshold buys up to it and refunds the rest", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		transactionID := uuid.New()
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(false, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(nil, nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(position *models.UserVirtualLPPosition) bool {
+			return position.TotalCNPYInvested == 0.505051 // The room below the threshold plus the fee
+		})).Return(nil)
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(2).(*models.VirtualPoolTransaction).ID = transactionID
+		}).Return(nil)
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.Anything).Return(nil)
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(payout *models.Payout) bool {
+			return payout.PayoutType == models.PayoutTypeCapRefund && payout.Amount == 1494949 &&
+				payout.VirtualPoolTransactionID != nil && *payout.VirtualPoolTransactionID == transactionID
+		})).Return(nil)
+
+		result, err := processor.ProcessDeposit(context.Background(), deposit)
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.NewCNPYReserve.Cmp(big.NewFloat(50)))
+		poolRepo.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("pool at the threshold refunds the whole deposit", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		full := *pool
+		full.CNPYReserve, full.CNPYReserveMicro = 50, 50000000
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(&full, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(false, nil)
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, refund(models.PayoutTypeCapRefund, 2000000)).Return(nil)
+
+		_, err := processor.ProcessDeposit(context.Background(), deposit)
+		assert.ErrorIs(t, err, ErrDepositRefunded)
+		poolRepo.AssertExpectations(t)
+		poolRepo.AssertNotCalled(t, "UpdatePoolStateInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("deposit capped at the threshold is held to its quoted price", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		// Quoted 30M tokens for the whole 2 CNPY, but only 0.505051 CNPY fits
+		// below the threshold. It buys 8M tokens, more than the 7,575,765
+		// that is the quoted minimum for that much.
+		blockTime := time.Unix(1700000000, 0)
+		quoted := *deposit
+		quoted.Quote = &Quote{ChainID: chainID, Side: models.VirtualTransactionTypeBuy, AmountIn: 2000000, MinAmountOut: 30000000000000, ExpiresAt: blockTime.Add(time.Minute).Unix()}
+		quoted.BlockTime = blockTime
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(false, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(nil, nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.Anything).Return(nil)
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, refund(models.PayoutTypeCapRefund, 1494949)).Return(nil)
+
+		result, err := processor.ProcessDeposit(context.Background(), &quoted)
+		require.NoError(t, err)
+		assert.Equal(t, "8000000", result.AmountOut.Text('f', 0))
+		poolRepo.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("deposit capped at the threshold below its quoted price is refunded in full", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		// 33M tokens quoted for 2 CNPY asks 8,333,342 for the 0.505051 CNPY
+		// that fits, and it buys only 8M
+		blockTime := time.Unix(1700000000, 0)
+		quoted := *deposit
+		quoted.Quote = &Quote{ChainID: chainID, Side: models.VirtualTransactionTypeBuy, AmountIn: 2000000, MinAmountOut: 33000000000000, ExpiresAt: blockTime.Add(time.Minute).Unix()}
+		quoted.BlockTime = blockTime
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(false, nil)
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, refund(models.PayoutTypeSlippageRefund, 2000000)).Return(nil)
+
+		_, err := processor.ProcessDeposit(context.Background(), &quoted)
+		assert.ErrorIs(t, err, ErrDepositRefunded)
+		poolRepo.AssertExpectations(t)
+		poolRepo.AssertNotCalled(t, "UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.Anything)
+		poolRepo.AssertNotCalled(t, "UpdatePoolStateInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("deposit larger than its quote is held to the quoted price", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		// 16M tokens quoted for 1 CNPY asks 32M for the 2 CNPY sent, and 2 CNPY
+		// buys only 30,769,230. The quote's own 16M would have let it through.
+		blockTime := time.Unix(1700000000, 0)
+		larger := *deposit
+		larger.GraduationThreshold = 0
+		larger.Quote = &Quote{ChainID: chainID, Side: models.VirtualTransactionTypeBuy, AmountIn: 1000000, MinAmountOut: 16000000000000, ExpiresAt: blockTime.Add(time.Minute).Unix()}
+		larger.BlockTime = blockTime
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(false, nil)
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, refund(models.PayoutTypeSlippageRefund, 2000000)).Return(nil)
+
+		_, err := processor.ProcessDeposit(context.Background(), &larger)
+		assert.ErrorIs(t, err, ErrDepositRefunded)
+		poolRepo.AssertExpectations(t)
+		poolRepo.AssertNotCalled(t, "UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("deposit included after its quote expired is refunded in full", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		blockTime := time.Unix(1700000000, 0)
+		late := *deposit
+		late.Quote = &Quote{ChainID: chainID, Side: models.VirtualTransactionTypeBuy, AmountIn: 2000000, MinAmountOut: 1, ExpiresAt: blockTime.Add(-time.Second).Unix()}
+		late.BlockTime = blockTime
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(false, nil)
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, refund(models.PayoutTypeSlippageRefund, 2000000)).Return(nil)
+
+		_, err := processor.ProcessDeposit(context.Background(), &late)
+		assert.ErrorIs(t, err, ErrDepositRefunded)
+		poolRepo.AssertExpectations(t)
+		poolRepo.AssertNotCalled(t, "GetUserPositionForUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("inactive pool refunds the whole deposit", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		inactive := *pool
+		inactive.IsActive = false
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(&inactive, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(false, nil)
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, refund(models.PayoutTypeInactiveRefund, 2000000)).Return(nil)
+
+		_, err := processor.ProcessDeposit(context.Background(), deposit)
+		assert.ErrorIs(t, err, ErrDepositRefunded)
+		poolRepo.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("deposit to a chain that stopped trading is refunded in full", func(t *testing.T) {
+		graduating := *pool
+		graduating.GraduationStarted = true
+		graduated := *pool
+		graduated.ChainStatus = models.ChainStatusGraduated
+
+		for name, stopped := range map[string]*models.VirtualPool{"graduating": &graduating, "graduated": &graduated} {
+			t.Run(name, func(t *testing.T) {
+				processor, poolRepo, dbMock := newProcessor(t)
+				dbMock.ExpectBegin()
+				dbMock.ExpectCommit()
+
+				poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(stopped, nil)
+				poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+				poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(false, nil)
+				poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, refund(models.PayoutTypeInactiveRefund, 2000000)).Return(nil)
+
+				_, err := processor.ProcessDeposit(context.Background(), deposit)
+				assert.ErrorIs(t, err, ErrDepositRefunded)
+				poolRepo.AssertExpectations(t)
+				poolRepo.AssertNotCalled(t, "UpdatePoolStateInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				assert.NoError(t, dbMock.ExpectationsWereMet())
+			})
+		}
+	})
+
+	t.Run("refunded deposit is not applied again", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("TransactionExistsInTx", mock.Anything, mock.Anything, "0xabc123", int64(1000)).Return(false, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(true, nil)
+
+		_, err := processor.ProcessDeposit(context.Background(), deposit)
+		assert.ErrorIs(t, err, ErrDepositAlreadyApplied)
+		poolRepo.AssertNotCalled(t, "CreatePayoutInTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("deposit to a chain that is not trading is refunded once", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(false, nil).Once()
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, refund(models.PayoutTypeInactiveRefund, 2000000)).Return(nil).Once()
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, "0xabc123").Return(true, nil).Once()
+
+		require.NoError(t, processor.RefundDeposit(context.Background(), deposit))
+		assert.ErrorIs(t, processor.RefundDeposit(context.Background(), deposit), ErrDepositAlreadyApplied)
+		poolRepo.AssertExpectations(t)
+		poolRepo.AssertNotCalled(t, "GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("refund without a sender is rejected", func(t *testing.T) {
+		processor, _, _ := newProcessor(t)
+		anonymous := *deposit
+		anonymous.Sender = ""
+		assert.ErrorIs(t, processor.RefundDeposit(context.Background(), &anonymous), ErrInvalidOrder)
+	})
+}
+
+func TestOrderProcessorTx_ProcessOrder(t *testing.T) {
+	chainID := uuid.New()
+	userID := uuid.New()
+	poolID := uuid.New()
+
+	pool := &models.VirtualPool{
+		ID:                poolID,
+		ChainID:           chainID,
+		CNPYReserve:       10000.0,
+		TokenReserve:      800000000,
+		CNPYReserveMicro:  10000000000,
+		TokenReserveUnits: 800000000000000,
+		TokenDecimals:     6,
+		CurrentPriceCNPY:  0.0000125,
+		TotalVolumeCNPY:   5000.0,
+		TotalTransactions: 10,
+		IsActive:          true,
+		ChainStatus:       models.ChainStatusVirtualActive,
+	}
+
+	newProcessor := func(t *testing.T) (*OrderProcessorTx, *MockVirtualPoolTxRepository, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		poolRepo := new(MockVirtualPoolTxRepository)
+		return NewOrderProcessorTx(sqlx.NewDb(db, "sqlmock"), poolRepo, new(MockUserRepository), nil), poolRepo, mock
+	}
+
+	buyOrder := &lib.SellOrder{
+		AmountForSale:       100,
+		RequestedAmount:     8000,
+		BuyerReceiveAddress: []byte(userID.String()),
+	}
+	sellOrder := &lib.SellOrder{
+		RequestedAmount:    5000, // Selling 5000 tokens
+		SellersSendAddress: []byte(userID.String()),
+	}
+
+	t.Run("buy with new position", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(nil, nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(position *models.UserVirtualLPPosition) bool {
+			return position.TokenBalance > 0 && position.TotalCNPYInvested == 100 && position.FirstPurchaseAt != nil
+		})).Return(nil)
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(tx *models.VirtualPoolTransaction) bool {
+			return tx.TransactionType == models.VirtualTransactionTypeBuy && tx.CNPYAmount == 100 && tx.TransactionHash == nil
+		})).Return(nil)
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.AnythingOfType("*interfaces.PoolStateUpdate")).Return(nil)
+
+		err := processor.ProcessOrder(context.Background(), buyOrder, chainID)
+		require.NoError(t, err)
+		poolRepo.AssertExpectations(t)
+		poolRepo.AssertNotCalled(t, "TransactionExistsInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("buy with existing position", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		existingPosition := &models.UserVirtualLPPosition{
+			ID:                     uuid.New(),
+			UserID:                 userID,
+			ChainID:                chainID,
+			VirtualPoolID:          poolID,
+			TokenBalance:           5000,
+			TokenBalanceUnits:      5000000000,
+			TotalCNPYInvested:      60.0,
+			TotalCNPYInvestedMicro: 60000000,
+			AverageEntryPriceCNPY:  0.012,
+		}
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(existingPosition, nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(position *models.UserVirtualLPPosition) bool {
+			return position.ID == existingPosition.ID && position.TokenBalance > 5000 && position.TotalCNPYInvested == 160
+		})).Return(nil)
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.Anything).Return(nil)
+
+		err := processor.ProcessOrder(context.Background(), buyOrder, chainID)
+		require.NoError(t, err)
+		poolRepo.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("pool not found", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(nil, errors.New("not found"))
+
+		err := processor.ProcessOrder(context.Background(), buyOrder, chainID)
+		assert.ErrorIs(t, err, ErrPoolNotFound)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("sell", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		position := &models.UserVirtualLPPosition{
+			ID:                     uuid.New(),
+			UserID:                 userID,
+			ChainID:                chainID,
+			VirtualPoolID:          poolID,
+			TokenBalance:           10000,
+			TokenBalanceUnits:      10000000000,
+			TotalCNPYInvested:      120.0,
+			TotalCNPYInvestedMicro: 120000000,
+			AverageEntryPriceCNPY:  0.012,
+		}
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(position, nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(position *models.UserVirtualLPPosition) bool {
+			return position.TokenBalance == 5000 && position.TotalCNPYWithdrawn > 0
+		})).Return(nil)
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(tx *models.VirtualPoolTransaction) bool {
+			return tx.TransactionType == models.VirtualTransactionTypeSell && tx.TokenAmount == 5000
+		})).Return(nil)
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.MatchedBy(func(update *interfaces.PoolStateUpdate) bool {
+			return update.CNPYReserve.Cmp(big.NewFloat(pool.CNPYReserve)) < 0
+		})).Return(nil)
+
+		err := processor.ProcessOrder(context.Background(), sellOrder, chainID)
+		require.NoError(t, err)
+		poolRepo.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("sell with insufficient balance writes nothing", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(&models.UserVirtualLPPosition{TokenBalance: 100, TokenBalanceUnits: 100000000}, nil)
+
+		err := processor.ProcessOrder(context.Background(), sellOrder, chainID)
+		assert.ErrorIs(t, err, ErrInsufficientBalance)
+		poolRepo.AssertNotCalled(t, "UpdatePoolStateInTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("sell without position", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(nil, nil)
+
+		err := processor.ProcessOrder(context.Background(), sellOrder, chainID)
+		assert.ErrorIs(t, err, ErrInsufficientBalance)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("failed pool update rolls back", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(nil, nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.Anything).Return(errors.New("connection reset"))
+
+		err := processor.ProcessOrder(context.Background(), buyOrder, chainID)
+		assert.ErrorContains(t, err, "failed to update pool state")
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestOrderProcessorTx_SellWithPayout(t *testing.T) {
+	chainID := uuid.New()
+	userID := uuid.New()
+	pool := &models.VirtualPool{
+		ID:                uuid.New(),
+		ChainID:           chainID,
+		CNPYReserve:       10000.0,
+		TokenReserve:      800000000,
+		CNPYReserveMicro:  10000000000,
+		TokenReserveUnits: 800000000000000,
+		TokenDecimals:     6,
+		TotalTransactions: 10,
+		IsActive:          true,
+		ChainStatus:       models.ChainStatusVirtualActive,
+	}
+	position := func() *models.UserVirtualLPPosition {
+		return &models.UserVirtualLPPosition{
+			ID:                     uuid.New(),
+			UserID:                 userID,
+			ChainID:                chainID,
+			TokenBalance:           100000,
+			TokenBalanceUnits:      100000000000,
+			TotalCNPYInvested:      1.2,
+			TotalCNPYInvestedMicro: 1200000,
+			AverageEntryPriceCNPY:  0.000012,
+		}
+	}
+	trade := func() *Trade {
+		return &Trade{
+			ChainID:         chainID,
+			UserID:          userID,
+			Type:            models.VirtualTransactionTypeSell,
+			Amount:          big.NewFloat(50000),
+			PayoutAddress:   "0x" + strings.Repeat("ab", 20),
+			PayoutReference: strings.Repeat("cd", 32),
+		}
+	}
+
+	newProcessor := func(t *testing.T) (*OrderProcessorTx, *MockVirtualPoolTxRepository, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		poolRepo := new(MockVirtualPoolTxRepository)
+		return NewOrderProcessorTx(sqlx.NewDb(db, "sqlmock"), poolRepo, new(MockUserRepository), nil), poolRepo, mock
+	}
+
+	t.Run("proceeds are queued with the sell", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		transactionID := uuid.New()
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, strings.Repeat("cd", 32)).Return(false, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(position(), nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(position *models.UserVirtualLPPosition) bool {
+			return position.TokenBalance == 50000
+		})).Return(nil)
+		var transaction *models.VirtualPoolTransaction
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			transaction = args.Get(2).(*models.VirtualPoolTransaction)
+			transaction.ID = transactionID
+		}).Return(nil)
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		var update *interfaces.PoolStateUpdate
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.Anything).Run(func(args mock.Arguments) {
+			update = args.Get(3).(*interfaces.PoolStateUpdate)
+		}).Return(nil)
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(payout *models.Payout) bool {
+			return payout.PayoutType == models.PayoutTypeSellProceeds &&
+				payout.Status == models.PayoutStatusPending &&
+				payout.UserID == userID &&
+				payout.RecipientAddress == "0x"+strings.Repeat("ab", 20) &&
+				payout.VirtualPoolTransactionID != nil && *payout.VirtualPoolTransactionID == transactionID &&
+				payout.Amount > 0
+		})).Return(nil)
+
+		result, err := processor.ExecuteTrade(context.Background(), trade())
+		require.NoError(t, err)
+		poolRepo.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+
+		// 5e10 * 1e10 / (8e14 + 5e10) = 624,960.9 uCNPY leaves the reserve, of
+		// which the seller is paid 618,710 after the fee
+		payout := poolRepo.Calls[len(poolRepo.Calls)-1].Arguments.Get(2).(*models.Payout)
+		assert.Equal(t, uint64(618710), payout.Amount)
+		assert.Equal(t, MicroCNPY(result.AmountOut), payout.Amount)
+		assert.Equal(t, uint64(618710), transaction.CNPYAmountMicro)
+		assert.Equal(t, uint64(50000000000), transaction.TokenAmountUnits)
+		assert.Equal(t, uint64(9999375040), transaction.PoolCNPYReserveAfterMicro)
+		require.NotNil(t, update.CNPYReserveMicro)
+		assert.Equal(t, uint64(9999375040), *update.CNPYReserveMicro)
+		assert.Equal(t, uint64(800050000000000), *update.TokenReserveUnits)
+	})
+
+	t.Run("fractional tokens are sold to the base unit", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, strings.Repeat("cd", 32)).Return(false, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(position(), nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(position *models.UserVirtualLPPosition) bool {
+			return position.TokenBalanceUnits == 99998500000 && position.TokenBalance == 99998
+		})).Return(nil)
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.MatchedBy(func(tx *models.VirtualPoolTransaction) bool {
+			return tx.TokenAmountUnits == 1500000
+		})).Return(nil)
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.Anything).Return(nil)
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		// Sell intents name the exact base units to sell
+		sell := trade()
+		sell.Amount, sell.AmountUnits = nil, big.NewInt(1500000)
+		_, err := processor.ExecuteTrade(context.Background(), sell)
+		require.NoError(t, err)
+		poolRepo.AssertExpectations(t)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("sell on a chain whose graduation started is rejected", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		// The holder's balance is already in the genesis; a payout would pay it twice
+		graduating := *pool
+		graduating.GraduationStarted = true
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(&graduating, nil)
+
+		_, err := processor.ExecuteTrade(context.Background(), trade())
+		assert.ErrorIs(t, err, ErrPoolInactive)
+		poolRepo.AssertNotCalled(t, "UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.Anything)
+		poolRepo.AssertNotCalled(t, "CreatePayoutInTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("replayed reference writes nothing", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, strings.Repeat("cd", 32)).Return(true, nil)
+
+		_, err := processor.ExecuteTrade(context.Background(), trade())
+		assert.ErrorIs(t, err, ErrPayoutAlreadyQueued)
+		poolRepo.AssertNotCalled(t, "UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.Anything)
+		poolRepo.AssertNotCalled(t, "CreatePayoutInTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("proceeds below minimum roll back", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(position(), nil)
+
+		sell := trade()
+		sell.MinAmountOut = big.NewFloat(1000)
+		_, err := processor.ExecuteTrade(context.Background(), sell)
+		assert.ErrorIs(t, err, ErrSlippageExceeded)
+		poolRepo.AssertNotCalled(t, "UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("failed payout insert rolls back the sell", func(t *testing.T) {
+		processor, poolRepo, dbMock := newProcessor(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+
+		poolRepo.On("GetPoolByChainIDForUpdate", mock.Anything, mock.Anything, chainID).Return(pool, nil)
+		poolRepo.On("PayoutExistsInTx", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		poolRepo.On("GetUserPositionForUpdate", mock.Anything, mock.Anything, userID, chainID).Return(position(), nil)
+		poolRepo.On("UpsertUserPositionInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("CreateTransactionInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("CreateFeeAccrualsInTx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		poolRepo.On("UpdatePoolStateInTx", mock.Anything, mock.Anything, chainID, mock.Anything).Return(nil)
+		poolRepo.On("CreatePayoutInTx", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("connection reset"))
+
+		_, err := processor.ExecuteTrade(context.Background(), trade())
+		assert.ErrorContains(t, err, "failed to queue payout")
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("payout on a buy is rejected", func(t *testing.T) {
+		processor, _, _ := newProcessor(t)
+		buy := trade()
+		buy.Type = models.VirtualTransactionTypeBuy
+
+		_, err := processor.ExecuteTrade(context.Background(), buy)
+		assert.ErrorIs(t, err, ErrInvalidOrder)
+	})
+}
+
+func TestOrderProcessorTx_ExecuteTrade(t *testing.T) {
+	processor := NewOrderProcessorTx(nil, new(MockVirtualPoolTxRepository), new(MockUserRepository), nil)
+	chainID := uuid.New()
+
+	t.Run("unknown trade type", func(t *testing.T) {
+		_, err := processor.ExecuteTrade(context.Background(), &Trade{ChainID: chainID, Type: "swap", Amount: big.NewFloat(1)})
+		assert.ErrorIs(t, err, ErrInvalidOrderType)
+	})
+
+	t.Run("zero amount", func(t *testing.T) {
+		_, err := processor.ExecuteTrade(context.Background(), &Trade{ChainID: chainID, Type: models.VirtualTransactionTypeBuy, Amount: big.NewFloat(0)})
+		assert.ErrorIs(t, err, ErrZeroAmount)
+	})
+
+	t.Run("missing amount", func(t *testing.T) {
+		_, err := processor.ExecuteTrade(context.Background(), &Trade{ChainID: chainID, Type: models.VirtualTransactionTypeSell})
+		assert.ErrorIs(t, err, ErrZeroAmount)
+	})
+}
+
 // TestConcurrentBuyOrders simulates concurrent buy orders to test for race conditions
 func TestConcurrentBuyOrders(t *testing.T) {
 	t.Skip("Integration test - requires real database")
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultQuoteSlippageBasisPoints is how far below the quoted amount out a
+	// quote's minimum is set when the caller does not choose
+	DefaultQuoteSlippageBasisPoints = 50
+
+	// MaxQuoteSlippageBasisPoints bounds the slippage a quote may allow
+	MaxQuoteSlippageBasisPoints = 5000
+
+	// DefaultQuoteTTL is how long a quote is honoured for
+	DefaultQuoteTTL = 30 * time.Second
+)
+
+var (
+	ErrInvalidQuote        = errors.New("invalid quote")
+	ErrQuoteExpired        = errors.New("quote expired")
+	ErrInvalidQuoteRequest = errors.New("invalid quote request")
+)
+
+// Quote is the part of a trade quote the launchpad signs: the least a trade of
+// AmountIn on a chain's pool may fill for before it is rejected or refunded.
+// A buyer puts the signed quote in the memo of their deposit; a seller sends
+// it with their sell intent. The trade credits ReferrerID, unless it is nil,
+// with the referrer's share of its fee.
+type Quote struct {
+	ChainID      uuid.UUID
+	Side         string // models.VirtualTransactionTypeBuy or models.VirtualTransactionTypeSell
+	AmountIn     uint64 // uCNPY on a buy, token base units on a sell
+	MinAmountOut uint64 // token base units on a buy, uCNPY on a sell
+	ExpiresAt    int64  // unix seconds
+	ReferrerID   uuid.UUID
+}
+
+// Check checks that the quote is for a trade on the given side of a chain's
+// pool and had not expired at the given time
+func (q *Quote) Check(chainID uuid.UUID, side string, at time.Time) error {
+	if q.ChainID != chainID {
+		return fmt.Errorf("%w: quoted for chain %s", ErrInvalidQuote, q.ChainID)
+	}
+	if q.Side != side {
+		return fmt.Errorf("%w: quoted for a %s", ErrInvalidQuote, q.Side)
+	}
+	if !at.Before(time.Unix(q.ExpiresAt, 0)) {
+		return ErrQuoteExpired
+	}
+	return nil
+}
+
+// MinAmountOutFor scales the quote's minimum from AmountIn to a trade of
+// amountIn, rounding up, so that a trade smaller or larger than the one quoted
+// is held to the quoted price rather than the quoted total
+func (q *Quote) MinAmountOutFor(amountIn uint64) uint64 {
+	if q.AmountIn == 0 || amountIn == q.AmountIn {
+		return q.MinAmountOut
+	}
+	scaled := new(big.Int).Mul(new(big.Int).SetUint64(q.MinAmountOut), new(big.Int).SetUint64(amountIn))
+	scaled.Add(scaled, new(big.Int).SetUint64(q.AmountIn-1))
+	scaled.Quo(scaled, new(big.Int).SetUint64(q.AmountIn))
+	if !scaled.IsUint64() {
+		return math.MaxUint64
+	}
+	return scaled.Uint64()
+}
+
+// Referrer returns the user the quote credits as referrer, or nil
+func (q *Quote) Referrer() *uuid.UUID {
+	if q.ReferrerID == uuid.Nil {
+		return nil
+	}
+	referrer := q.ReferrerID
+	return &referrer
+}
+
+// TradeQuote prices a trade on a chain's pool at its current reserves. Amounts
+// in are CNPY on a buy and tokens on a sell and amounts out the other way
+// round, each also given exactly in uCNPY or token base units. The fee is CNPY
+// either way. Prices are in CNPY per token and PriceImpact in percent. Token is
+// the signed Quote carrying MinAmountOutUnits and the referrer, if any.
+type TradeQuote struct {
+	ChainID             uuid.UUID  `json:"chain_id"`
+	Side                string     `json:"side"`
+	AmountIn            float64    `json:"amount_in"`
+	AmountInUnits       uint64     `json:"amount_in_units"`
+	AmountOut           float64    `json:"amount_out"`
+	AmountOutUnits      uint64     `json:"amount_out_units"`
+	Fee                 float64    `json:"fee"`
+	FeeUnits            uint64     `json:"fee_units"`
+	EffectivePrice      float64    `json:"effective_price"`
+	PriceImpact         float64    `json:"price_impact"`
+	PostTradePrice      float64    `json:"post_trade_price"`
+	SlippageBasisPoints uint64     `json:"slippage_bps"`
+	MinAmountOut        float64    `json:"min_amount_out"`
+	MinAmountOutUnits   uint64     `json:"min_amount_out_units"`
+	ReferrerID          *uuid.UUID `json:"referrer_id,omitempty"`
+	Token               string     `json:"quote"`
+	ExpiresAt           time.Time  `json:"expires_at"`
+}
+
+// quotePayloadSize is the size of an encoded quote: a version byte, the chain
+// ID, a side byte, the amounts and expiry as big-endian uint64s, then the
+// referrer's user ID, all zero for none
+const quotePayloadSize = 1 + 16 + 1 + 8 + 8 + 8 + 16
+
+const quoteVersion = 2
+
+// QuoteSigner signs quotes into tokens and verifies them again. A token is
+// the quote's compact binary encoding followed by its HMAC-SHA256, in
+// unpadded base64url, so that it fits in the memo of a root chain send.
+type QuoteSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewQuoteSigner creates a signer whose quotes are honoured for ttl, or for
+// DefaultQuoteTTL when ttl is not positive
+func NewQuoteSigner(secret string, ttl time.Duration) *QuoteSigner {
+	if ttl <= 0 {
+		ttl = DefaultQuoteTTL
+	}
+	return &QuoteSigner{secret: []byte(secret), ttl: ttl}
+}
+
+// TTL is how long the signer's quotes are honoured for
+func (s *QuoteSigner) TTL() time.Duration {
+	return s.ttl
+}
+
+// Sign returns the token for a quote
+func (s *QuoteSigner) Sign(q *Quote) string {
+	payload := make([]byte, quotePayloadSize)
+	payload[0] = quoteVersion
+	copy(payload[1:17], q.ChainID[:])
+	if q.Side == models.VirtualTransactionTypeSell {
+		payload[17] = 1
+	}
+	binary.BigEndian.PutUint64(payload[18:26], q.AmountIn)
+	binary.BigEndian.PutUint64(payload[26:34], q.MinAmountOut)
+	binary.BigEndian.PutUint64(payload[34:42], uint64(q.ExpiresAt))
+	copy(payload[42:58], q.ReferrerID[:])
+
+	return base64.RawURLEncoding.EncodeToString(append(payload, s.mac(payload)...))
+}
+
+// Parse verifies a token's signature and returns the quote it carries. It does
+// not check the quote's expiry; see Quote.Check.
+func (s *QuoteSigner) Parse(token string) (*Quote, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != quotePayloadSize+sha256.Size {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidQuote)
+	}
+
+	payload, signature := raw[:quotePayloadSize], raw[quotePayloadSize:]
+	if !hmac.Equal(signature, s.mac(payload)) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidQuote)
+	}
+	if payload[0] != quoteVersion || payload[17] > 1 {
+		return nil, fmt.Errorf("%w: unknown encoding", ErrInvalidQuote)
+	}
+
+	q := &Quote{
+		Side:         models.VirtualTransactionTypeBuy,
+		AmountIn:     binary.BigEndian.Uint64(payload[18:26]),
+		MinAmountOut: binary.BigEndian.Uint64(payload[26:34]),
+		ExpiresAt:    int64(binary.BigEndian.Uint64(payload[34:42])),
+	}
+	copy(q.ChainID[:], payload[1:17])
+	copy(q.ReferrerID[:], payload[42:58])
+	if payload[17] == 1 {
+		q.Side = models.VirtualTransactionTypeSell
+	}
+	return q, nil
+}
+
+func (s *QuoteSigner) mac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
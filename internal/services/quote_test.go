@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteSigner(t *testing.T) {
+	signer := NewQuoteSigner("quote-secret", time.Minute)
+	quote := &Quote{
+		ChainID:      uuid.New(),
+		Side:         models.VirtualTransactionTypeSell,
+		AmountIn:     5000000000,
+		MinAmountOut: 618710,
+		ExpiresAt:    1700000060,
+		ReferrerID:   uuid.New(),
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		token := signer.Sign(quote)
+		// Buyers send the token as the memo of their deposit
+		assert.LessOrEqual(t, len(token), 200)
+
+		parsed, err := signer.Parse(token)
+		require.NoError(t, err)
+		assert.Equal(t, quote, parsed)
+	})
+
+	t.Run("tampered token", func(t *testing.T) {
+		raw := []byte(signer.Sign(quote))
+		raw[40] ^= 1
+		_, err := signer.Parse(string(raw))
+		assert.ErrorIs(t, err, ErrInvalidQuote)
+	})
+
+	t.Run("token signed with another secret", func(t *testing.T) {
+		_, err := signer.Parse(NewQuoteSigner("other-secret", time.Minute).Sign(quote))
+		assert.ErrorIs(t, err, ErrInvalidQuote)
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		for _, token := range []string{"", "not a quote", "AAAA"} {
+			_, err := signer.Parse(token)
+			assert.ErrorIs(t, err, ErrInvalidQuote, token)
+		}
+	})
+}
+
+func TestQuoteCheck(t *testing.T) {
+	chainID := uuid.New()
+	expiresAt := time.Unix(1700000060, 0)
+	quote := &Quote{ChainID: chainID, Side: models.VirtualTransactionTypeBuy, ExpiresAt: expiresAt.Unix()}
+
+	assert.NoError(t, quote.Check(chainID, models.VirtualTransactionTypeBuy, expiresAt.Add(-time.Second)))
+	assert.ErrorIs(t, quote.Check(chainID, models.VirtualTransactionTypeBuy, expiresAt), ErrQuoteExpired)
+	assert.ErrorIs(t, quote.Check(uuid.New(), models.VirtualTransactionTypeBuy, expiresAt.Add(-time.Second)), ErrInvalidQuote)
+	assert.ErrorIs(t, quote.Check(chainID, models.VirtualTransactionTypeSell, expiresAt.Add(-time.Second)), ErrInvalidQuote)
+}
+
+func TestQuoteMinAmountOutFor(t *testing.T) {
+	quote := &Quote{AmountIn: 3000000, MinAmountOut: 1000000}
+
+	assert.Equal(t, uint64(1000000), quote.MinAmountOutFor(3000000))
+	// A third of the quoted amount needs a third of the minimum, rounded up
+	assert.Equal(t, uint64(333334), quote.MinAmountOutFor(1000000))
+	assert.Equal(t, uint64(2000000), quote.MinAmountOutFor(6000000))
+	assert.Equal(t, uint64(0), quote.MinAmountOutFor(0))
+	assert.Equal(t, uint64(math.MaxUint64), (&Quote{AmountIn: 1, MinAmountOut: 2}).MinAmountOutFor(math.MaxUint64))
+	assert.Equal(t, uint64(5), (&Quote{MinAmountOut: 5}).MinAmountOutFor(1000))
+}
+
+func TestVirtualPoolServiceQuote(t *testing.T) {
+	chainID := uuid.New()
+	pool := &models.VirtualPool{
+		ID:                uuid.New(),
+		ChainID:           chainID,
+		CNPYReserve:       1000,
+		TokenReserve:      800000000,
+		CNPYReserveMicro:  1000000000,
+		TokenReserveUnits: 800000000000000,
+		TokenDecimals:     6,
+		CurrentPriceCNPY:  0.00000125,
+		IsActive:          true,
+		ChainStatus:       models.ChainStatusVirtualActive,
+	}
+	signer := NewQuoteSigner("quote-secret", time.Minute)
+
+	newServiceWithUsers := func(t *testing.T, pool *models.VirtualPool, userRepo *MockUserRepository) *VirtualPoolService {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		poolRepo := new(MockVirtualPoolTxRepository)
+		poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
+
+		service := NewVirtualPoolService(nil, nil, userRepo, nil)
+		service.SetQuotes(NewOrderProcessorTx(sqlx.NewDb(db, "sqlmock"), poolRepo, userRepo, nil), signer)
+		return service
+	}
+	newService := func(t *testing.T, pool *models.VirtualPool) *VirtualPoolService {
+		return newServiceWithUsers(t, pool, new(MockUserRepository))
+	}
+
+	t.Run("buy quote carries a signed minimum", func(t *testing.T) {
+		before := time.Now()
+		quote, err := newService(t, pool).Quote(context.Background(), chainID.String(), models.VirtualTransactionTypeBuy, "100", 100, "")
+		require.NoError(t, err)
+
+		// The 1% fee leaves 99 CNPY to buy 99 * 800M / 1,099 tokens
+		assert.Equal(t, uint64(100000000), quote.AmountInUnits)
+		assert.Equal(t, uint64(72065514103730), quote.AmountOutUnits)
+		assert.Equal(t, uint64(1000000), quote.FeeUnits)
+		assert.Equal(t, 1.0, quote.Fee)
+		assert.Equal(t, uint64(71344858962692), quote.MinAmountOutUnits)
+		assert.InDelta(t, 72065514.10373, quote.AmountOut, 0.000001)
+		assert.InDelta(t, 71344858.962692, quote.MinAmountOut, 0.000001)
+		assert.Greater(t, quote.EffectivePrice, pool.CurrentPriceCNPY)
+		assert.Greater(t, quote.PostTradePrice, quote.EffectivePrice)
+		assert.InDelta(t, 1099/(800000000-72065514.10373), quote.PostTradePrice, 1e-15)
+		assert.Nil(t, quote.ReferrerID)
+		assert.Greater(t, quote.PriceImpact, 0.0)
+		assert.False(t, quote.ExpiresAt.Before(before.Add(time.Minute).Truncate(time.Second)))
+
+		signed, err := signer.Parse(quote.Token)
+		require.NoError(t, err)
+		assert.Equal(t, &Quote{
+			ChainID:      chainID,
+			Side:         models.VirtualTransactionTypeBuy,
+			AmountIn:     100000000,
+			MinAmountOut: 71344858962692,
+			ExpiresAt:    quote.ExpiresAt.Unix(),
+		}, signed)
+	})
+
+	t.Run("sell quote prices fractional tokens in uCNPY", func(t *testing.T) {
+		quote, err := newService(t, pool).Quote(context.Background(), chainID.String(), models.VirtualTransactionTypeSell, "1000.5", 0, "")
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1000500000), quote.AmountInUnits)
+		assert.Greater(t, quote.AmountOutUnits, uint64(0))
+		assert.Equal(t, quote.AmountOutUnits, quote.MinAmountOutUnits)
+		assert.Less(t, quote.PostTradePrice, pool.CurrentPriceCNPY)
+	})
+
+	t.Run("quote signs the referrer", func(t *testing.T) {
+		referrerID := uuid.New()
+		userRepo := new(MockUserRepository)
+		userRepo.On("GetByID", mock.Anything, referrerID).Return(&models.User{ID: referrerID}, nil)
+
+		quote, err := newServiceWithUsers(t, pool, userRepo).Quote(context.Background(), chainID.String(), models.VirtualTransactionTypeBuy, "100", 50, referrerID.String())
+		require.NoError(t, err)
+		assert.Equal(t, &referrerID, quote.ReferrerID)
+
+		signed, err := signer.Parse(quote.Token)
+		require.NoError(t, err)
+		assert.Equal(t, &referrerID, signed.Referrer())
+	})
+
+	t.Run("unknown referrer", func(t *testing.T) {
+		referrerID := uuid.New()
+		userRepo := new(MockUserRepository)
+		userRepo.On("GetByID", mock.Anything, referrerID).Return(nil, errors.New("user not found"))
+
+		_, err := newServiceWithUsers(t, pool, userRepo).Quote(context.Background(), chainID.String(), models.VirtualTransactionTypeBuy, "100", 50, referrerID.String())
+		assert.ErrorIs(t, err, ErrInvalidQuoteRequest)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		service := newService(t, pool)
+		for name, args := range map[string][]string{
+			"unknown side":    {"swap", "100"},
+			"missing amount":  {models.VirtualTransactionTypeBuy, ""},
+			"negative amount": {models.VirtualTransactionTypeBuy, "-1"},
+		} {
+			_, err := service.Quote(context.Background(), chainID.String(), args[0], args[1], 50, "")
+			assert.ErrorIs(t, err, ErrInvalidQuoteRequest, name)
+		}
+
+		_, err := service.Quote(context.Background(), chainID.String(), models.VirtualTransactionTypeBuy, "100", MaxQuoteSlippageBasisPoints+1, "")
+		assert.ErrorIs(t, err, ErrInvalidQuoteRequest)
+
+		_, err = service.Quote(context.Background(), chainID.String(), models.VirtualTransactionTypeBuy, "100", 50, "not-a-user")
+		assert.ErrorIs(t, err, ErrInvalidQuoteRequest)
+	})
+
+	t.Run("pool that no longer trades", func(t *testing.T) {
+		inactive := *pool
+		inactive.IsActive = false
+		_, err := newService(t, &inactive).Quote(context.Background(), chainID.String(), models.VirtualTransactionTypeBuy, "100", 50, "")
+		assert.ErrorIs(t, err, ErrPoolInactive)
+	})
+
+	t.Run("trade larger than the pool", func(t *testing.T) {
+		_, err := newService(t, pool).Quote(context.Background(), chainID.String(), models.VirtualTransactionTypeSell, "900000000", 50, "")
+		assert.ErrorIs(t, err, ErrInsufficientReserves)
+	})
+}
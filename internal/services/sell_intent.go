@@ -0,0 +1,98 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/canopy-network/canopy/lib/crypto"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/google/uuid"
+)
+
+// MaxSellIntentLifetime bounds how far in the future a sell intent may expire,
+// which limits how long a signed intent can be held back and replayed
+const MaxSellIntentLifetime = 10 * time.Minute
+
+var (
+	ErrInvalidSellIntent    = errors.New("invalid sell intent")
+	ErrSellIntentExpired    = errors.New("sell intent expired")
+	ErrInvalidSellSignature = errors.New("invalid sell intent signature")
+)
+
+// SellIntent is a holder's request to sell tokens back into a chain's virtual
+// pool, signed with the key of the root chain wallet that holds the position.
+// The CNPY proceeds are paid to that same wallet.
+type SellIntent struct {
+	ChainID          uuid.UUID
+	TokenAmountUnits uint64 // Token base units, so fractional balances can be sold
+	MinCNPYOut       uint64 // uCNPY, 0 accepts any price
+	Nonce            string
+	ExpiresAt        int64 // unix seconds
+	PublicKey        []byte
+	Signature        []byte
+}
+
+// NewSellIntent decodes a sell intent request for a chain
+func NewSellIntent(chainID uuid.UUID, req *models.SellIntentRequest) (*SellIntent, error) {
+	publicKey, err := hex.DecodeString(req.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed public key", ErrInvalidSellIntent)
+	}
+	signature, err := hex.DecodeString(req.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidSellIntent)
+	}
+
+	return &SellIntent{
+		ChainID:          chainID,
+		TokenAmountUnits: req.TokenAmountUnits,
+		MinCNPYOut:       req.MinCNPYOut,
+		Nonce:            req.Nonce,
+		ExpiresAt:        req.ExpiresAt,
+		PublicKey:        publicKey,
+		Signature:        signature,
+	}, nil
+}
+
+// SignBytes returns the message the holder signs:
+// "launchpad-sell:<chain id>:<token amount units>:<min cnpy out>:<nonce>:<expires at>"
+func (i *SellIntent) SignBytes() []byte {
+	return []byte(fmt.Sprintf("launchpad-sell:%s:%d:%d:%s:%d",
+		i.ChainID, i.TokenAmountUnits, i.MinCNPYOut, i.Nonce, i.ExpiresAt))
+}
+
+// Hash identifies the intent. It is the reference of the payout the sale
+// queues, so each signed intent can be honoured only once.
+func (i *SellIntent) Hash() string {
+	sum := sha256.Sum256(i.SignBytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that the intent has not expired and is signed by the key it
+// carries, and returns the wallet address of that key
+func (i *SellIntent) Verify(now time.Time) (string, error) {
+	if i.TokenAmountUnits == 0 {
+		return "", ErrZeroAmount
+	}
+
+	expiresAt := time.Unix(i.ExpiresAt, 0)
+	if !now.Before(expiresAt) {
+		return "", ErrSellIntentExpired
+	}
+	if expiresAt.Sub(now) > MaxSellIntentLifetime {
+		return "", fmt.Errorf("%w: expires more than %v from now", ErrInvalidSellIntent, MaxSellIntentLifetime)
+	}
+
+	publicKey, err := crypto.NewPublicKeyFromBytes(i.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSellIntent, err)
+	}
+	if !publicKey.VerifyBytes(i.SignBytes(), i.Signature) {
+		return "", ErrInvalidSellSignature
+	}
+
+	return "0x" + hex.EncodeToString(publicKey.Address().Bytes()), nil
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/canopy-network/canopy/lib/crypto"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/pkg/bondingcurve"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockTradeExecutor is a mock implementation of TradeExecutor
+type MockTradeExecutor struct {
+	mock.Mock
+}
+
+func (m *MockTradeExecutor) ExecuteTradeWithRetry(ctx context.Context, trade *Trade) (*bondingcurve.TradeResult, error) {
+	args := m.Called(ctx, trade)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bondingcurve.TradeResult), args.Error(1)
+}
+
+// signedSellIntent builds a sell intent request signed by a new key and returns
+// it with the wallet address of that key
+func signedSellIntent(t *testing.T, chainID uuid.UUID, tokenAmountUnits uint64, expiresAt time.Time) (*models.SellIntentRequest, string) {
+	privateKey, err := crypto.NewBLS12381PrivateKey()
+	require.NoError(t, err)
+
+	intent := &SellIntent{
+		ChainID:          chainID,
+		TokenAmountUnits: tokenAmountUnits,
+		MinCNPYOut:       500000,
+		Nonce:            "n1",
+		ExpiresAt:        expiresAt.Unix(),
+	}
+	req := &models.SellIntentRequest{
+		TokenAmountUnits: intent.TokenAmountUnits,
+		MinCNPYOut:       intent.MinCNPYOut,
+		Nonce:            intent.Nonce,
+		ExpiresAt:        intent.ExpiresAt,
+		PublicKey:        hex.EncodeToString(privateKey.PublicKey().Bytes()),
+		Signature:        hex.EncodeToString(privateKey.Sign(intent.SignBytes())),
+	}
+	return req, "0x" + hex.EncodeToString(privateKey.PublicKey().Address().Bytes())
+}
+
+func TestSellIntentVerify(t *testing.T) {
+	chainID := uuid.New()
+	now := time.Now()
+
+	t.Run("valid signature returns the signer's wallet", func(t *testing.T) {
+		req, wallet := signedSellIntent(t, chainID, 5000000000, now.Add(time.Minute))
+		intent, err := NewSellIntent(chainID, req)
+		require.NoError(t, err)
+
+		address, err := intent.Verify(now)
+		require.NoError(t, err)
+		assert.Equal(t, wallet, address)
+		assert.Len(t, intent.Hash(), 64)
+	})
+
+	t.Run("tampered amount", func(t *testing.T) {
+		req, _ := signedSellIntent(t, chainID, 5000000000, now.Add(time.Minute))
+		req.TokenAmountUnits = 50000000000
+		intent, err := NewSellIntent(chainID, req)
+		require.NoError(t, err)
+
+		_, err = intent.Verify(now)
+		assert.ErrorIs(t, err, ErrInvalidSellSignature)
+	})
+
+	t.Run("signed for another chain", func(t *testing.T) {
+		req, _ := signedSellIntent(t, uuid.New(), 5000000000, now.Add(time.Minute))
+		intent, err := NewSellIntent(chainID, req)
+		require.NoError(t, err)
+
+		_, err = intent.Verify(now)
+		assert.ErrorIs(t, err, ErrInvalidSellSignature)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		req, _ := signedSellIntent(t, chainID, 5000000000, now.Add(-time.Second))
+		intent, err := NewSellIntent(chainID, req)
+		require.NoError(t, err)
+
+		_, err = intent.Verify(now)
+		assert.ErrorIs(t, err, ErrSellIntentExpired)
+	})
+
+	t.Run("expiry too far ahead", func(t *testing.T) {
+		req, _ := signedSellIntent(t, chainID, 5000000000, now.Add(MaxSellIntentLifetime+time.Minute))
+		intent, err := NewSellIntent(chainID, req)
+		require.NoError(t, err)
+
+		_, err = intent.Verify(now)
+		assert.ErrorIs(t, err, ErrInvalidSellIntent)
+	})
+
+	t.Run("unknown key format", func(t *testing.T) {
+		req, _ := signedSellIntent(t, chainID, 5000000000, now.Add(time.Minute))
+		req.PublicKey = "abcd"
+		intent, err := NewSellIntent(chainID, req)
+		require.NoError(t, err)
+
+		_, err = intent.Verify(now)
+		assert.ErrorIs(t, err, ErrInvalidSellIntent)
+	})
+}
+
+func TestVirtualPoolServiceSell(t *testing.T) {
+	chainID := uuid.New()
+	userID := uuid.New()
+
+	t.Run("sells and queues the proceeds to the signer", func(t *testing.T) {
+		// 5,000.5 tokens: fractional balances sell to the base unit
+		req, wallet := signedSellIntent(t, chainID, 5000500000, time.Now().Add(time.Minute))
+		intent, err := NewSellIntent(chainID, req)
+		require.NoError(t, err)
+
+		userRepo := new(MockUserRepository)
+		trades := new(MockTradeExecutor)
+		userRepo.On("GetByWalletAddress", mock.Anything, wallet).Return(&models.User{ID: userID, WalletAddress: wallet}, nil)
+		trades.On("ExecuteTradeWithRetry", mock.Anything, mock.MatchedBy(func(trade *Trade) bool {
+			return trade.ChainID == chainID && trade.UserID == userID &&
+				trade.Type == models.VirtualTransactionTypeSell &&
+				trade.AmountUnits.Cmp(big.NewInt(5000500000)) == 0 &&
+				trade.MinAmountOut.Cmp(big.NewFloat(0.5)) == 0 &&
+				trade.PayoutAddress == wallet &&
+				trade.PayoutReference == intent.Hash()
+		})).Return(&bondingcurve.TradeResult{
+			AmountIn:  big.NewFloat(5000.5),
+			AmountOut: big.NewFloat(0.75),
+			Price:     big.NewFloat(0.00015),
+		}, nil)
+
+		service := NewVirtualPoolService(nil, nil, userRepo, trades)
+		result, err := service.Sell(context.Background(), chainID.String(), req)
+		require.NoError(t, err)
+		assert.Equal(t, 5000.5, result.TokensSold)
+		assert.Equal(t, uint64(5000500000), result.TokensSoldUnits)
+		assert.Equal(t, uint64(750000), result.PayoutAmount)
+		assert.Equal(t, wallet, result.PayoutAddress)
+		trades.AssertExpectations(t)
+	})
+
+	t.Run("quoted minimum above min_cnpy_out applies", func(t *testing.T) {
+		req, wallet := signedSellIntent(t, chainID, 5000000000, time.Now().Add(time.Minute))
+		signer := NewQuoteSigner("quote-secret", time.Minute)
+		req.Quote = signer.Sign(&Quote{
+			ChainID:      chainID,
+			Side:         models.VirtualTransactionTypeSell,
+			AmountIn:     5000000000,
+			MinAmountOut: 700000,
+			ExpiresAt:    time.Now().Add(time.Minute).Unix(),
+		})
+
+		userRepo := new(MockUserRepository)
+		trades := new(MockTradeExecutor)
+		userRepo.On("GetByWalletAddress", mock.Anything, wallet).Return(&models.User{ID: userID, WalletAddress: wallet}, nil)
+		trades.On("ExecuteTradeWithRetry", mock.Anything, mock.MatchedBy(func(trade *Trade) bool {
+			return trade.MinAmountOut.Cmp(unitAmount(700000, big.NewInt(bondingcurve.MicroCNPYPerCNPY))) == 0
+		})).Return(nil, ErrSlippageExceeded)
+
+		service := NewVirtualPoolService(nil, nil, userRepo, trades)
+		service.SetQuotes(nil, signer)
+		_, err := service.Sell(context.Background(), chainID.String(), req)
+		assert.ErrorIs(t, err, ErrSlippageExceeded)
+		trades.AssertExpectations(t)
+	})
+
+	t.Run("expired quote never reaches the pool", func(t *testing.T) {
+		req, wallet := signedSellIntent(t, chainID, 5000000000, time.Now().Add(time.Minute))
+		signer := NewQuoteSigner("quote-secret", time.Minute)
+		req.Quote = signer.Sign(&Quote{
+			ChainID:      chainID,
+			Side:         models.VirtualTransactionTypeSell,
+			AmountIn:     5000000000,
+			MinAmountOut: 700000,
+			ExpiresAt:    time.Now().Add(-time.Second).Unix(),
+		})
+
+		userRepo := new(MockUserRepository)
+		trades := new(MockTradeExecutor)
+		userRepo.On("GetByWalletAddress", mock.Anything, wallet).Return(&models.User{ID: userID, WalletAddress: wallet}, nil)
+
+		service := NewVirtualPoolService(nil, nil, userRepo, trades)
+		service.SetQuotes(nil, signer)
+		_, err := service.Sell(context.Background(), chainID.String(), req)
+		assert.ErrorIs(t, err, ErrQuoteExpired)
+		trades.AssertNotCalled(t, "ExecuteTradeWithRetry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown wallet has nothing to sell", func(t *testing.T) {
+		req, wallet := signedSellIntent(t, chainID, 5000000000, time.Now().Add(time.Minute))
+
+		userRepo := new(MockUserRepository)
+		trades := new(MockTradeExecutor)
+		userRepo.On("GetByWalletAddress", mock.Anything, wallet).Return(nil, errors.New("user not found"))
+
+		service := NewVirtualPoolService(nil, nil, userRepo, trades)
+		_, err := service.Sell(context.Background(), chainID.String(), req)
+		assert.ErrorIs(t, err, ErrInsufficientBalance)
+		trades.AssertNotCalled(t, "ExecuteTradeWithRetry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("bad signature never reaches the pool", func(t *testing.T) {
+		req, _ := signedSellIntent(t, chainID, 5000000000, time.Now().Add(time.Minute))
+		req.MinCNPYOut = 0
+
+		trades := new(MockTradeExecutor)
+		service := NewVirtualPoolService(nil, nil, new(MockUserRepository), trades)
+		_, err := service.Sell(context.Background(), chainID.String(), req)
+		assert.ErrorIs(t, err, ErrInvalidSellSignature)
+		trades.AssertNotCalled(t, "ExecuteTradeWithRetry", mock.Anything, mock.Anything)
+	})
+}
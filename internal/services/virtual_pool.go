@@ -3,24 +3,67 @@ package services
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"strings"
 	"time"
 
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/pkg/bondingcurve"
 	"github.com/google/uuid"
 )
 
+// TradeExecutor applies trades to virtual pools atomically. OrderProcessorTx
+// implements it.
+type TradeExecutor interface {
+	ExecuteTradeWithRetry(ctx context.Context, trade *Trade) (*bondingcurve.TradeResult, error)
+}
+
+// TradeQuoter prices trades without executing them. OrderProcessorTx
+// implements it.
+type TradeQuoter interface {
+	QuoteTrade(ctx context.Context, chainID uuid.UUID, side string, amount *big.Float, slippageBasisPoints uint64) (*TradeQuote, error)
+}
+
 type VirtualPoolService struct {
-	virtualPoolRepo interfaces.VirtualPoolRepository
+	virtualPoolRepo    interfaces.VirtualPoolRepository
+	pendingDepositRepo interfaces.PendingDepositRepository
+	userRepo           interfaces.UserRepository
+	trades             TradeExecutor
+	quoter             TradeQuoter
+	quotes             *QuoteSigner
 }
 
-func NewVirtualPoolService(virtualPoolRepo interfaces.VirtualPoolRepository) *VirtualPoolService {
+func NewVirtualPoolService(virtualPoolRepo interfaces.VirtualPoolRepository, pendingDepositRepo interfaces.PendingDepositRepository, userRepo interfaces.UserRepository, trades TradeExecutor) *VirtualPoolService {
 	return &VirtualPoolService{
-		virtualPoolRepo: virtualPoolRepo,
+		virtualPoolRepo:    virtualPoolRepo,
+		pendingDepositRepo: pendingDepositRepo,
+		userRepo:           userRepo,
+		trades:             trades,
 	}
 }
 
+// SetQuotes enables trade quotes, priced by quoter and signed by signer. Sell
+// intents may then carry a quote whose minimum the sale must meet.
+func (s *VirtualPoolService) SetQuotes(quoter TradeQuoter, signer *QuoteSigner) {
+	s.quoter = quoter
+	s.quotes = signer
+}
+
+// SellResult is the outcome of an accepted sell intent. PayoutAmount is the
+// uCNPY queued for payment to PayoutAddress.
+type SellResult struct {
+	ChainID           uuid.UUID `json:"chain_id"`
+	UserID            uuid.UUID `json:"user_id"`
+	TokensSold        float64   `json:"tokens_sold"`
+	TokensSoldUnits   uint64    `json:"tokens_sold_units"`
+	CNPYOut           float64   `json:"cnpy_out"`
+	PricePerTokenCNPY float64   `json:"price_per_token_cnpy"`
+	PayoutAddress     string    `json:"payout_address"`
+	PayoutAmount      uint64    `json:"payout_amount"`
+	PayoutReference   string    `json:"payout_reference"`
+}
+
 // GetAllPools retrieves all virtual pools with pagination
 func (s *VirtualPoolService) GetAllPools(ctx context.Context, page, limit int) ([]models.VirtualPool, *models.Pagination, error) {
 	// Build pagination
@@ -66,6 +109,162 @@ func (s *VirtualPoolService) GetPool(ctx context.Context, chainID string) (*mode
 	return pool, nil
 }
 
+// GetPendingDeposits lists the deposits to a chain's pool that have been seen on
+// the root chain but are not final yet, optionally only those from one sender
+func (s *VirtualPoolService) GetPendingDeposits(ctx context.Context, chainID string, senderAddress string) ([]models.PendingDeposit, error) {
+	chainUUID, err := uuid.Parse(chainID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain ID: %w", err)
+	}
+
+	filters := interfaces.PendingDepositFilters{
+		SenderAddress: strings.ToLower(senderAddress),
+	}
+	deposits, err := s.pendingDepositRepo.ListByChainID(ctx, chainUUID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending deposits: %w", err)
+	}
+
+	return deposits, nil
+}
+
+// Sell executes a signed sell intent against a chain's virtual pool. The tokens
+// are burned from the signer's position and the CNPY proceeds are queued as a
+// payout to the signer's wallet in the same transaction.
+func (s *VirtualPoolService) Sell(ctx context.Context, chainID string, req *models.SellIntentRequest) (*SellResult, error) {
+	chainUUID, err := uuid.Parse(chainID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain ID: %w", err)
+	}
+
+	intent, err := NewSellIntent(chainUUID, req)
+	if err != nil {
+		return nil, err
+	}
+	walletAddress, err := intent.Verify(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// A wallet the platform has never seen cannot hold a position
+	user, err := s.userRepo.GetByWalletAddress(ctx, walletAddress)
+	if err != nil {
+		return nil, ErrInsufficientBalance
+	}
+
+	trade := &Trade{
+		ChainID:         chainUUID,
+		UserID:          user.ID,
+		Type:            models.VirtualTransactionTypeSell,
+		AmountUnits:     new(big.Int).SetUint64(intent.TokenAmountUnits),
+		PayoutAddress:   walletAddress,
+		PayoutReference: intent.Hash(),
+	}
+	if intent.MinCNPYOut > 0 {
+		trade.MinAmountOut = unitAmount(intent.MinCNPYOut, big.NewInt(bondingcurve.MicroCNPYPerCNPY))
+	}
+	if req.Quote != "" {
+		quote, err := s.parseQuote(req.Quote)
+		if err != nil {
+			return nil, err
+		}
+		if err := quote.Check(chainUUID, models.VirtualTransactionTypeSell, time.Now()); err != nil {
+			return nil, err
+		}
+		// The stricter of the signed minimum and the quoted one, scaled to the
+		// tokens sold, applies
+		minCNPYOut := quote.MinAmountOutFor(intent.TokenAmountUnits)
+		if quoted := unitAmount(minCNPYOut, big.NewInt(bondingcurve.MicroCNPYPerCNPY)); trade.MinAmountOut == nil || quoted.Cmp(trade.MinAmountOut) > 0 {
+			trade.MinAmountOut = quoted
+		}
+		trade.ReferrerID = quote.Referrer()
+	}
+
+	result, err := s.trades.ExecuteTradeWithRetry(ctx, trade)
+	if err != nil {
+		return nil, err
+	}
+
+	tokensSold, _ := result.AmountIn.Float64()
+	cnpyOut, _ := result.AmountOut.Float64()
+	price, _ := result.Price.Float64()
+	return &SellResult{
+		ChainID:           chainUUID,
+		UserID:            user.ID,
+		TokensSold:        tokensSold,
+		TokensSoldUnits:   intent.TokenAmountUnits,
+		CNPYOut:           cnpyOut,
+		PricePerTokenCNPY: price,
+		PayoutAddress:     walletAddress,
+		PayoutAmount:      MicroCNPY(result.AmountOut),
+		PayoutReference:   trade.PayoutReference,
+	}, nil
+}
+
+// Quote prices a buy of amount CNPY or a sell of amount tokens on a chain's
+// virtual pool and signs a quote whose minimum is the amount out less
+// slippageBasisPoints. A deposit sent with the quote in its memo, or a sell
+// intent carrying it, is refunded or rejected if it would fill below that
+// minimum or arrives after the quote expires. A quote with a referrer, the ID
+// of an existing user, credits them with the referrer's share of the trade's fee.
+func (s *VirtualPoolService) Quote(ctx context.Context, chainID, side, amount string, slippageBasisPoints uint64, referrer string) (*TradeQuote, error) {
+	chainUUID, err := uuid.Parse(chainID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain ID: %w", err)
+	}
+	if side != models.VirtualTransactionTypeBuy && side != models.VirtualTransactionTypeSell {
+		return nil, fmt.Errorf("%w: side must be buy or sell", ErrInvalidQuoteRequest)
+	}
+	amountIn, ok := new(big.Float).SetPrec(bondingcurve.Precision).SetString(amount)
+	if !ok || amountIn.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: amount must be a positive number", ErrInvalidQuoteRequest)
+	}
+	if slippageBasisPoints > MaxQuoteSlippageBasisPoints {
+		return nil, fmt.Errorf("%w: slippage_bps must be at most %d", ErrInvalidQuoteRequest, MaxQuoteSlippageBasisPoints)
+	}
+	var referrerID uuid.UUID
+	if referrer != "" {
+		if referrerID, err = uuid.Parse(referrer); err != nil {
+			return nil, fmt.Errorf("%w: referrer must be a user ID", ErrInvalidQuoteRequest)
+		}
+	}
+	if s.quoter == nil || s.quotes == nil {
+		return nil, fmt.Errorf("trade quotes are not enabled")
+	}
+	if referrerID != uuid.Nil {
+		if _, err := s.userRepo.GetByID(ctx, referrerID); err != nil {
+			return nil, fmt.Errorf("%w: unknown referrer", ErrInvalidQuoteRequest)
+		}
+	}
+
+	quote, err := s.quoter.QuoteTrade(ctx, chainUUID, side, amountIn, slippageBasisPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	quote.ExpiresAt = time.Now().Add(s.quotes.TTL()).Truncate(time.Second)
+	quote.Token = s.quotes.Sign(&Quote{
+		ChainID:      chainUUID,
+		Side:         side,
+		AmountIn:     quote.AmountInUnits,
+		MinAmountOut: quote.MinAmountOutUnits,
+		ExpiresAt:    quote.ExpiresAt.Unix(),
+		ReferrerID:   referrerID,
+	})
+	if referrerID != uuid.Nil {
+		quote.ReferrerID = &referrerID
+	}
+	return quote, nil
+}
+
+// parseQuote verifies a quote token signed by this service
+func (s *VirtualPoolService) parseQuote(token string) (*Quote, error) {
+	if s.quotes == nil {
+		return nil, fmt.Errorf("%w: trade quotes are not enabled", ErrInvalidQuote)
+	}
+	return s.quotes.Parse(token)
+}
+
 // GetPriceHistory retrieves OHLC price history for a chain
 func (s *VirtualPoolService) GetPriceHistory(ctx context.Context, chainID string, startTime, endTime *time.Time) ([]models.PriceHistoryCandle, error) {
 	// Parse and validate chain ID
@@ -42,6 +42,14 @@ func (m *MockChainRepository) GetByAddress(ctx context.Context, address string)
 	return args.Get(0).(*models.Chain), args.Error(1)
 }
 
+func (m *MockChainRepository) GetByAddresses(ctx context.Context, addresses []string) (map[string]*models.Chain, error) {
+	args := m.Called(ctx, addresses)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]*models.Chain), args.Error(1)
+}
+
 func (m *MockChainRepository) Create(ctx context.Context, chain *models.Chain) (*models.Chain, error) {
 	args := m.Called(ctx, chain)
 	if args.Get(0) == nil {
@@ -63,6 +71,11 @@ func (m *MockChainRepository) UpdateDescription(ctx context.Context, id uuid.UUI
 	return args.Error(0)
 }
 
+func (m *MockChainRepository) UpdateAllocation(ctx context.Context, id uuid.UUID, allocation models.TokenAllocation) error {
+	args := m.Called(ctx, id, allocation)
+	return args.Error(0)
+}
+
 func (m *MockChainRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -178,6 +191,22 @@ func (m *MockChainRepository) CreateChainKey(ctx context.Context, key *models.Ch
 	return args.Get(0).(*models.ChainKey), args.Error(1)
 }
 
+func (m *MockChainRepository) GetVestingSchedulesByChainID(ctx context.Context, chainID uuid.UUID) ([]models.ChainVestingSchedule, error) {
+	args := m.Called(ctx, chainID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ChainVestingSchedule), args.Error(1)
+}
+
+func (m *MockChainRepository) ReplaceVestingSchedules(ctx context.Context, chainID uuid.UUID, schedules []models.ChainVestingSchedule) ([]models.ChainVestingSchedule, error) {
+	args := m.Called(ctx, chainID, schedules)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ChainVestingSchedule), args.Error(1)
+}
+
 func (m *MockChainRepository) GetChainKeyByChainID(ctx context.Context, chainID uuid.UUID, purpose string) (*models.ChainKey, error) {
 	args := m.Called(ctx, chainID, purpose)
 	if args.Get(0) == nil {
@@ -300,6 +329,14 @@ func (m *MockUserRepository) GetByWalletAddress(ctx context.Context, walletAddre
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetOrCreateByWalletAddresses(ctx context.Context, walletAddresses []string) ([]models.User, error) {
+	args := m.Called(ctx, walletAddresses)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
 func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
@@ -445,3 +482,193 @@ func (m *MockVirtualPoolTxRepository) UpsertUserPositionInTx(ctx context.Context
 	args := m.Called(ctx, tx, position)
 	return args.Error(0)
 }
+
+func (m *MockVirtualPoolTxRepository) PayoutExistsInTx(ctx context.Context, tx *sqlx.Tx, reference string) (bool, error) {
+	args := m.Called(ctx, tx, reference)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockVirtualPoolTxRepository) CreatePayoutInTx(ctx context.Context, tx *sqlx.Tx, payout *models.Payout) error {
+	args := m.Called(ctx, tx, payout)
+	return args.Error(0)
+}
+
+// MockChainGraduationRepository is a mock implementation of interfaces.ChainGraduationRepository
+type MockChainGraduationRepository struct {
+	mock.Mock
+}
+
+func (m *MockChainGraduationRepository) Create(ctx context.Context, graduation *models.ChainGraduation) (*models.ChainGraduation, error) {
+	args := m.Called(ctx, graduation)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChainGraduation), args.Error(1)
+}
+
+func (m *MockChainGraduationRepository) GetByChainID(ctx context.Context, chainID uuid.UUID) (*models.ChainGraduation, error) {
+	args := m.Called(ctx, chainID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChainGraduation), args.Error(1)
+}
+
+func (m *MockChainGraduationRepository) Update(ctx context.Context, graduation *models.ChainGraduation) (*models.ChainGraduation, error) {
+	args := m.Called(ctx, graduation)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChainGraduation), args.Error(1)
+}
+
+func (m *MockChainGraduationRepository) RecordDeployment(ctx context.Context, chainID uuid.UUID, status, rpcURL, message string) (*models.ChainGraduation, error) {
+	args := m.Called(ctx, chainID, status, rpcURL, message)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChainGraduation), args.Error(1)
+}
+
+func (m *MockChainGraduationRepository) List(ctx context.Context, pagination interfaces.Pagination) ([]models.ChainGraduation, int, error) {
+	args := m.Called(ctx, pagination)
+	return args.Get(0).([]models.ChainGraduation), args.Int(1), args.Error(2)
+}
+
+func (m *MockChainGraduationRepository) ListIncomplete(ctx context.Context, limit int) ([]models.ChainGraduation, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]models.ChainGraduation), args.Error(1)
+}
+
+// MockGraduatedPoolRepository is a mock implementation of interfaces.GraduatedPoolRepository
+type MockGraduatedPoolRepository struct {
+	mock.Mock
+}
+
+func (m *MockGraduatedPoolRepository) Create(ctx context.Context, pool *models.GraduatedPool) (*models.GraduatedPool, error) {
+	args := m.Called(ctx, pool)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GraduatedPool), args.Error(1)
+}
+
+func (m *MockGraduatedPoolRepository) GetPoolByChainID(ctx context.Context, chainID uuid.UUID) (*models.GraduatedPool, error) {
+	args := m.Called(ctx, chainID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GraduatedPool), args.Error(1)
+}
+
+func (m *MockGraduatedPoolRepository) GetAllPools(ctx context.Context, pagination interfaces.Pagination) ([]models.GraduatedPool, int, error) {
+	args := m.Called(ctx, pagination)
+	return args.Get(0).([]models.GraduatedPool), args.Int(1), args.Error(2)
+}
+
+func (m *MockGraduatedPoolRepository) UpdatePoolState(ctx context.Context, chainID uuid.UUID, update *interfaces.PoolStateUpdate) error {
+	args := m.Called(ctx, chainID, update)
+	return args.Error(0)
+}
+
+func (m *MockGraduatedPoolRepository) CreateTransaction(ctx context.Context, transaction *models.GraduatedPoolTransaction) error {
+	args := m.Called(ctx, transaction)
+	return args.Error(0)
+}
+
+func (m *MockGraduatedPoolRepository) GetTransactionsByPoolID(ctx context.Context, poolID uuid.UUID, pagination interfaces.Pagination) ([]models.GraduatedPoolTransaction, int, error) {
+	args := m.Called(ctx, poolID, pagination)
+	return args.Get(0).([]models.GraduatedPoolTransaction), args.Int(1), args.Error(2)
+}
+
+func (m *MockGraduatedPoolRepository) GetTransactionsByUserID(ctx context.Context, userID uuid.UUID, pagination interfaces.Pagination) ([]models.GraduatedPoolTransaction, int, error) {
+	args := m.Called(ctx, userID, pagination)
+	return args.Get(0).([]models.GraduatedPoolTransaction), args.Int(1), args.Error(2)
+}
+
+func (m *MockGraduatedPoolRepository) GetTransactionsByChainID(ctx context.Context, chainID uuid.UUID, filters interfaces.TransactionFilters, pagination interfaces.Pagination) ([]models.GraduatedPoolTransaction, int, error) {
+	args := m.Called(ctx, chainID, filters, pagination)
+	return args.Get(0).([]models.GraduatedPoolTransaction), args.Int(1), args.Error(2)
+}
+
+func (m *MockGraduatedPoolRepository) GetUserPosition(ctx context.Context, userID, chainID uuid.UUID) (*models.UserGraduatedLPPosition, error) {
+	args := m.Called(ctx, userID, chainID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserGraduatedLPPosition), args.Error(1)
+}
+
+func (m *MockGraduatedPoolRepository) UpsertUserPosition(ctx context.Context, position *models.UserGraduatedLPPosition) error {
+	args := m.Called(ctx, position)
+	return args.Error(0)
+}
+
+func (m *MockGraduatedPoolRepository) GetPositionsByChainID(ctx context.Context, chainID uuid.UUID, pagination interfaces.Pagination) ([]models.UserGraduatedLPPosition, int, error) {
+	args := m.Called(ctx, chainID, pagination)
+	return args.Get(0).([]models.UserGraduatedLPPosition), args.Int(1), args.Error(2)
+}
+
+func (m *MockGraduatedPoolRepository) GetPositionsWithUsersByChainID(ctx context.Context, chainID uuid.UUID) ([]interfaces.UserPositionWithAddress, error) {
+	args := m.Called(ctx, chainID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]interfaces.UserPositionWithAddress), args.Error(1)
+}
+
+func (m *MockGraduatedPoolRepository) MigrateFromVirtualPool(ctx context.Context, chainID uuid.UUID) (*models.GraduatedPool, error) {
+	args := m.Called(ctx, chainID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GraduatedPool), args.Error(1)
+}
+
+// MockPayoutRepository is a mock implementation of interfaces.PayoutRepository
+type MockPayoutRepository struct {
+	mock.Mock
+}
+
+func (m *MockPayoutRepository) ListDue(ctx context.Context, rootChainID uint64, limit int) ([]models.Payout, error) {
+	args := m.Called(ctx, rootChainID, limit)
+	return args.Get(0).([]models.Payout), args.Error(1)
+}
+
+func (m *MockPayoutRepository) Update(ctx context.Context, payout *models.Payout, fromStatus string) (bool, error) {
+	args := m.Called(ctx, payout, fromStatus)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockFailedEventRepository is a mock implementation of interfaces.FailedEventRepository
+type MockFailedEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockFailedEventRepository) Record(ctx context.Context, event *models.FailedEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockFailedEventRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.FailedEvent, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FailedEvent), args.Error(1)
+}
+
+func (m *MockFailedEventRepository) List(ctx context.Context, filters interfaces.FailedEventFilters, pagination interfaces.Pagination) ([]models.FailedEvent, int, error) {
+	args := m.Called(ctx, filters, pagination)
+	return args.Get(0).([]models.FailedEvent), args.Int(1), args.Error(2)
+}
+
+func (m *MockFailedEventRepository) ListDue(ctx context.Context, limit int) ([]models.FailedEvent, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]models.FailedEvent), args.Error(1)
+}
+
+func (m *MockFailedEventRepository) Update(ctx context.Context, event *models.FailedEvent) (bool, error) {
+	args := m.Called(ctx, event)
+	return args.Bool(0), args.Error(1)
+}
@@ -2,6 +2,7 @@ package validators
 
 import (
 	"context"
+	"encoding/hex"
 	"reflect"
 	"strings"
 
@@ -19,6 +20,7 @@ func New() *Validator {
 	// Register custom validation tags
 	validate.RegisterValidation("uppercase", validateUppercase)
 	validate.RegisterValidation("github_url", validateGithubURL)
+	validate.RegisterValidation("canopy_address", validateCanopyAddress)
 
 	// Register custom struct field names for better error messages
 	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
@@ -82,6 +84,10 @@ func (v *Validator) getErrorMessage(err validator.FieldError) string {
 		return "This field must be a valid GitHub URL"
 	case "datetime":
 		return "This field must be a valid datetime in RFC3339 format"
+	case "canopy_address":
+		return "This field must be a 20 byte hex address"
+	case "gtefield":
+		return "This field must be greater than or equal to " + err.Param()
 	default:
 		return "This field is invalid"
 	}
@@ -96,3 +102,8 @@ func validateGithubURL(fl validator.FieldLevel) bool {
 	url := fl.Field().String()
 	return strings.HasPrefix(url, "https://github.com/")
 }
+
+func validateCanopyAddress(fl validator.FieldLevel) bool {
+	address, err := hex.DecodeString(strings.TrimPrefix(fl.Field().String(), "0x"))
+	return err == nil && len(address) == 20
+}
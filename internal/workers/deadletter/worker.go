@@ -0,0 +1,129 @@
+package deadletter
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/enielson/launchpad/internal/models"
+)
+
+// Retrier replays the dead-letter events whose retry is due
+type Retrier interface {
+	RetryDue(ctx context.Context, limit int) ([]models.FailedEvent, error)
+}
+
+// Worker periodically retries failed on-chain events from the dead-letter queue
+type Worker struct {
+	retrier   Retrier
+	interval  time.Duration
+	batchSize int
+	stopChan  chan struct{}
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+// Config holds configuration for the dead-letter worker
+type Config struct {
+	// Interval is how often to look for events whose retry is due (default: 30 seconds)
+	Interval time.Duration
+
+	// BatchSize is the maximum number of events retried per run (default: 50)
+	BatchSize int
+}
+
+// DefaultConfig returns default configuration for the worker
+func DefaultConfig() Config {
+	return Config{
+		Interval:  30 * time.Second,
+		BatchSize: 50,
+	}
+}
+
+// NewWorker creates a new dead-letter worker
+func NewWorker(retrier Retrier, config Config) *Worker {
+	if config.Interval == 0 {
+		config.Interval = 30 * time.Second
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 50
+	}
+
+	return &Worker{
+		retrier:   retrier,
+		interval:  config.Interval,
+		batchSize: config.BatchSize,
+		stopChan:  make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start begins the dead-letter worker
+func (w *Worker) Start() error {
+	log.Printf("[DeadLetter Worker] Starting (interval: %v, batch size: %d)", w.interval, w.batchSize)
+
+	go w.run()
+
+	return nil
+}
+
+// Stop gracefully stops the dead-letter worker
+func (w *Worker) Stop() error {
+	w.stopOnce.Do(func() {
+		log.Println("[DeadLetter Worker] Stopping...")
+		close(w.stopChan)
+
+		// Wait for worker to finish current operation
+		select {
+		case <-w.done:
+			log.Println("[DeadLetter Worker] Stopped")
+		case <-time.After(10 * time.Second):
+			log.Println("[DeadLetter Worker] Stop timeout")
+		}
+	})
+
+	return nil
+}
+
+// run is the main worker loop
+func (w *Worker) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.retryDue()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// retryDue retries one batch of due events and logs the outcome of each
+func (w *Worker) retryDue() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	events, err := w.retrier.RetryDue(ctx, w.batchSize)
+	if err != nil {
+		log.Printf("[DeadLetter Worker] Error retrying events: %v", err)
+	}
+
+	for i := range events {
+		event := &events[i]
+		switch event.Status {
+		case models.FailedEventStatusResolved:
+			log.Printf("[DeadLetter Worker] Resolved %s event %s", event.EventType, event.Reference)
+		case models.FailedEventStatusFailed:
+			log.Printf("[DeadLetter Worker] Giving up on %s event %s after %d attempts: %s",
+				event.EventType, event.Reference, event.Attempts, event.LastError)
+		default:
+			log.Printf("[DeadLetter Worker] Retry %d of %s event %s failed, next at %s: %s",
+				event.Attempts, event.EventType, event.Reference, event.NextRetryAt.Format(time.RFC3339), event.LastError)
+		}
+	}
+}
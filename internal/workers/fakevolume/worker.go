@@ -11,19 +11,28 @@ import (
 
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/internal/services"
 	"github.com/enielson/launchpad/pkg/bondingcurve"
 	"github.com/google/uuid"
 )
 
+// TradeExecutor applies trades to virtual pools, updating the pool, transaction
+// history and user position atomically
+type TradeExecutor interface {
+	ExecuteTradeWithRetry(ctx context.Context, trade *services.Trade) (*bondingcurve.TradeResult, error)
+}
+
 // Worker generates fake trading volume for virtual pools
 type Worker struct {
 	chainRepo   interfaces.ChainRepository
 	poolRepo    interfaces.VirtualPoolRepository
 	userRepo    interfaces.UserRepository
+	trades      TradeExecutor
 	interval    time.Duration
 	stopChan    chan struct{}
 	done        chan struct{}
 	fakeUserIDs []uuid.UUID // Pool of fake user IDs to rotate through
+	graduation  services.GraduationNotifier
 }
 
 // Config holds configuration for the fake volume worker
@@ -43,7 +52,7 @@ func DefaultConfig() Config {
 }
 
 // NewWorker creates a new fake volume worker
-func NewWorker(chainRepo interfaces.ChainRepository, poolRepo interfaces.VirtualPoolRepository, userRepo interfaces.UserRepository, config Config) *Worker {
+func NewWorker(chainRepo interfaces.ChainRepository, poolRepo interfaces.VirtualPoolRepository, userRepo interfaces.UserRepository, trades TradeExecutor, config Config) *Worker {
 	if config.Interval == 0 {
 		config.Interval = 30 * time.Second
 	}
@@ -55,6 +64,7 @@ func NewWorker(chainRepo interfaces.ChainRepository, poolRepo interfaces.Virtual
 		chainRepo:   chainRepo,
 		poolRepo:    poolRepo,
 		userRepo:    userRepo,
+		trades:      trades,
 		interval:    config.Interval,
 		stopChan:    make(chan struct{}),
 		done:        make(chan struct{}),
@@ -62,6 +72,12 @@ func NewWorker(chainRepo interfaces.ChainRepository, poolRepo interfaces.Virtual
 	}
 }
 
+// SetGraduationNotifier registers the notifier that is signalled when a fake buy
+// pushes a pool past its chain's graduation threshold
+func (w *Worker) SetGraduationNotifier(notifier services.GraduationNotifier) {
+	w.graduation = notifier
+}
+
 // Start begins the fake volume worker
 func (w *Worker) Start() error {
 	log.Printf("[FakeVolume Worker] Starting fake volume generation (interval: %v)", w.interval)
@@ -153,6 +169,9 @@ func (w *Worker) generateSingleTransaction(ctx context.Context, chain *models.Ch
 	if err != nil {
 		return fmt.Errorf("failed to get virtual pool: %w", err)
 	}
+	if !pool.AcceptsTrades() {
+		return nil
+	}
 
 	// Determine transaction type (60% buy, 40% sell)
 	isBuy := randomInt(1, 100) <= 60
@@ -164,92 +183,42 @@ func (w *Worker) generateSingleTransaction(ctx context.Context, chain *models.Ch
 	}
 
 	if isBuy {
-		return w.executeBuy(ctx, chain, pool, user)
+		return w.executeBuy(ctx, chain, user)
 	}
-	return w.executeSell(ctx, chain, pool, user)
+	return w.executeSell(ctx, chain, user)
 }
 
 // executeBuy executes a fake buy transaction
-func (w *Worker) executeBuy(ctx context.Context, chain *models.Chain, pool *models.VirtualPool, user *models.User) error {
+func (w *Worker) executeBuy(ctx context.Context, chain *models.Chain, user *models.User) error {
 	// Random CNPY amount between 0.1 and 10.0 CNPY
 	cnpyAmount := randomFloat(0.1, 10.0)
 
-	// Convert database pool to bonding curve pool
-	bcPool := bondingcurve.NewVirtualPool(
-		big.NewFloat(pool.CNPYReserve),
-		big.NewFloat(float64(pool.TokenReserve)),
-		big.NewFloat(float64(pool.TokenReserve)),
-	)
-
-	// Create bonding curve instance with default config
-	curve := bondingcurve.NewBondingCurve(nil)
-
-	// Execute the buy
-	result, err := curve.Buy(bcPool, big.NewFloat(cnpyAmount))
-	if err != nil {
-		return fmt.Errorf("bonding curve buy failed: %w", err)
-	}
-
-	// Update pool state in database
-	totalTransactions := pool.TotalTransactions + 1
-	totalVolumeCNPY := pool.TotalVolumeCNPY + cnpyAmount
-	totalVolumeCNPYBigFloat := big.NewFloat(totalVolumeCNPY)
-	update := &interfaces.PoolStateUpdate{
-		CNPYReserve:       result.NewCNPYReserve,
-		TokenReserve:      result.NewTokenReserve,
-		CurrentPriceCNPY:  result.Price,
-		TotalVolumeCNPY:   totalVolumeCNPYBigFloat,
-		TotalTransactions: &totalTransactions,
-	}
-
-	err = w.poolRepo.UpdatePoolState(ctx, chain.ID, update)
+	result, err := w.trades.ExecuteTradeWithRetry(ctx, &services.Trade{
+		ChainID: chain.ID,
+		UserID:  user.ID,
+		Type:    models.VirtualTransactionTypeBuy,
+		Amount:  big.NewFloat(cnpyAmount),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update pool state: %w", err)
+		return fmt.Errorf("failed to execute buy: %w", err)
 	}
 
-	// Record transaction
 	tokensOutFloat, _ := result.AmountOut.Float64()
 	priceFloat, _ := result.Price.Float64()
 	newCNPYReserveFloat, _ := result.NewCNPYReserve.Float64()
-	newTokenReserveFloat, _ := result.NewTokenReserve.Float64()
-
-	transaction := &models.VirtualPoolTransaction{
-		VirtualPoolID:         pool.ID,
-		ChainID:               chain.ID,
-		UserID:                user.ID,
-		TransactionType:       "buy",
-		CNPYAmount:            cnpyAmount,
-		TokenAmount:           int64(tokensOutFloat),
-		PricePerTokenCNPY:     priceFloat,
-		TradingFeeCNPY:        0.0,
-		SlippagePercent:       0,
-		TransactionHash:       nil,
-		BlockHeight:           nil,
-		GasUsed:               nil,
-		PoolCNPYReserveAfter:  newCNPYReserveFloat,
-		PoolTokenReserveAfter: int64(newTokenReserveFloat),
-		MarketCapAfterUSD:     0,
-	}
-
-	err = w.poolRepo.CreateTransaction(ctx, transaction)
-	if err != nil {
-		return fmt.Errorf("failed to create transaction record: %w", err)
-	}
-
-	// Update user position
-	err = w.updateUserPosition(ctx, user, pool, chain, cnpyAmount, tokensOutFloat, priceFloat, true)
-	if err != nil {
-		log.Printf("[FakeVolume Worker] Warning: Failed to update user position: %v", err)
-	}
 
 	log.Printf("[FakeVolume Worker] BUY: Chain=%s, CNPY=%.4f, Tokens=%.2f, Price=%.8f",
 		chain.ChainName, cnpyAmount, tokensOutFloat, priceFloat)
 
+	if w.graduation != nil && newCNPYReserveFloat >= chain.GraduationThreshold {
+		w.graduation.Notify(chain.ID)
+	}
+
 	return nil
 }
 
 // executeSell executes a fake sell transaction
-func (w *Worker) executeSell(ctx context.Context, chain *models.Chain, pool *models.VirtualPool, user *models.User) error {
+func (w *Worker) executeSell(ctx context.Context, chain *models.Chain, user *models.User) error {
 	// Get user position (create if doesn't exist with some tokens)
 	position, err := w.poolRepo.GetUserPosition(ctx, user.ID, chain.ID)
 	if err != nil {
@@ -257,174 +226,31 @@ func (w *Worker) executeSell(ctx context.Context, chain *models.Chain, pool *mod
 	}
 
 	// If user has no position or insufficient tokens, give them some tokens first
-	if position == nil || position.TokenBalance < 1000 {
+	if position == nil || position.TokenBalanceUnits < uint64(1000*chain.TokenUnit()) {
 		// Give user tokens by doing a buy first
-		return w.executeBuy(ctx, chain, pool, user)
+		return w.executeBuy(ctx, chain, user)
 	}
 
-	// Random token amount to sell (10% to 50% of balance)
+	// Random amount to sell (10% to 50% of balance), in token base units
 	sellPercent := randomFloat(0.1, 0.5)
-	tokenAmount := float64(position.TokenBalance) * sellPercent
-
-	// Convert database pool to bonding curve pool
-	bcPool := bondingcurve.NewVirtualPool(
-		big.NewFloat(pool.CNPYReserve),
-		big.NewFloat(float64(pool.TokenReserve)),
-		big.NewFloat(float64(pool.TokenReserve)),
-	)
-
-	// Create bonding curve instance with default config
-	curve := bondingcurve.NewBondingCurve(nil)
-
-	// Execute the sell
-	result, err := curve.Sell(bcPool, big.NewFloat(tokenAmount))
+	tokenAmount := uint64(float64(position.TokenBalanceUnits) * sellPercent)
+
+	result, err := w.trades.ExecuteTradeWithRetry(ctx, &services.Trade{
+		ChainID:     chain.ID,
+		UserID:      user.ID,
+		Type:        models.VirtualTransactionTypeSell,
+		AmountUnits: new(big.Int).SetUint64(tokenAmount),
+	})
 	if err != nil {
-		return fmt.Errorf("bonding curve sell failed: %w", err)
+		return fmt.Errorf("failed to execute sell: %w", err)
 	}
 
 	cnpyOutFloat, _ := result.AmountOut.Float64()
 	priceFloat, _ := result.Price.Float64()
-	newCNPYReserveFloat, _ := result.NewCNPYReserve.Float64()
-	newTokenReserveFloat, _ := result.NewTokenReserve.Float64()
-
-	// Update pool state in database
-	totalTransactions := pool.TotalTransactions + 1
-	totalVolumeCNPY := pool.TotalVolumeCNPY + cnpyOutFloat
-	totalVolumeCNPYBigFloat := big.NewFloat(totalVolumeCNPY)
-	update := &interfaces.PoolStateUpdate{
-		CNPYReserve:       result.NewCNPYReserve,
-		TokenReserve:      result.NewTokenReserve,
-		CurrentPriceCNPY:  result.Price,
-		TotalVolumeCNPY:   totalVolumeCNPYBigFloat,
-		TotalTransactions: &totalTransactions,
-	}
-
-	err = w.poolRepo.UpdatePoolState(ctx, chain.ID, update)
-	if err != nil {
-		return fmt.Errorf("failed to update pool state: %w", err)
-	}
-
-	// Record transaction
-	transaction := &models.VirtualPoolTransaction{
-		VirtualPoolID:         pool.ID,
-		ChainID:               chain.ID,
-		UserID:                user.ID,
-		TransactionType:       "sell",
-		CNPYAmount:            cnpyOutFloat,
-		TokenAmount:           int64(tokenAmount),
-		PricePerTokenCNPY:     priceFloat,
-		TradingFeeCNPY:        0.0,
-		SlippagePercent:       0,
-		TransactionHash:       nil,
-		BlockHeight:           nil,
-		GasUsed:               nil,
-		PoolCNPYReserveAfter:  newCNPYReserveFloat,
-		PoolTokenReserveAfter: int64(newTokenReserveFloat),
-		MarketCapAfterUSD:     0,
-	}
 
-	err = w.poolRepo.CreateTransaction(ctx, transaction)
-	if err != nil {
-		return fmt.Errorf("failed to create transaction record: %w", err)
-	}
-
-	// Update user position (selling)
-	err = w.updateUserPosition(ctx, user, pool, chain, cnpyOutFloat, tokenAmount, priceFloat, false)
-	if err != nil {
-		log.Printf("[FakeVolume Worker] Warning: Failed to update user position: %v", err)
-	}
-
-	log.Printf("[FakeVolume Worker] SELL: Chain=%s, Tokens=%.2f, CNPY=%.4f, Price=%.8f",
-		chain.ChainName, tokenAmount, cnpyOutFloat, priceFloat)
-
-	return nil
-}
-
-// updateUserPosition updates or creates a user's virtual position
-func (w *Worker) updateUserPosition(ctx context.Context, user *models.User, pool *models.VirtualPool, chain *models.Chain, cnpyAmount, tokenAmount, price float64, isBuy bool) error {
-	position, err := w.poolRepo.GetUserPosition(ctx, user.ID, chain.ID)
-	if err != nil {
-		return fmt.Errorf("failed to get user position: %w", err)
-	}
-
-	now := time.Now()
-
-	if position == nil {
-		// Create new position (only for buys)
-		if !isBuy {
-			return nil
-		}
-
-		position = &models.UserVirtualLPPosition{
-			UserID:                user.ID,
-			ChainID:               chain.ID,
-			VirtualPoolID:         pool.ID,
-			TokenBalance:          int64(tokenAmount),
-			TotalCNPYInvested:     cnpyAmount,
-			TotalCNPYWithdrawn:    0,
-			AverageEntryPriceCNPY: price,
-			UnrealizedPnlCNPY:     0,
-			RealizedPnlCNPY:       0,
-			TotalReturnPercent:    0,
-			IsActive:              true,
-			FirstPurchaseAt:       &now,
-			LastActivityAt:        &now,
-		}
-	} else {
-		// Update existing position
-		if isBuy {
-			oldBalance := float64(position.TokenBalance)
-			newBalance := oldBalance + tokenAmount
-
-			// Calculate new average entry price (weighted average)
-			oldInvestment := position.TotalCNPYInvested
-			newInvestment := oldInvestment + cnpyAmount
-			position.AverageEntryPriceCNPY = newInvestment / newBalance
-
-			// Update balances
-			position.TokenBalance = int64(newBalance)
-			position.TotalCNPYInvested = newInvestment
-		} else {
-			// Sell
-			newBalance := float64(position.TokenBalance) - tokenAmount
-			if newBalance < 0 {
-				newBalance = 0
-			}
-
-			position.TokenBalance = int64(newBalance)
-			position.TotalCNPYWithdrawn += cnpyAmount
-
-			// Calculate realized PnL on this sale
-			costBasis := position.AverageEntryPriceCNPY * tokenAmount
-			realizedPnL := cnpyAmount - costBasis
-			position.RealizedPnlCNPY += realizedPnL
-		}
-
-		position.LastActivityAt = &now
-
-		// Calculate unrealized PnL for remaining position
-		if position.TokenBalance > 0 {
-			currentValueCNPY := price * float64(position.TokenBalance)
-			costBasis := position.AverageEntryPriceCNPY * float64(position.TokenBalance)
-			position.UnrealizedPnlCNPY = currentValueCNPY - costBasis
-
-			// Calculate total return percentage
-			totalInvestment := position.TotalCNPYInvested
-			if totalInvestment > 0 {
-				totalPnL := position.UnrealizedPnlCNPY + position.RealizedPnlCNPY
-				position.TotalReturnPercent = (totalPnL / totalInvestment) * 100
-			}
-		} else {
-			position.UnrealizedPnlCNPY = 0
-			position.IsActive = false
-		}
-	}
-
-	// Upsert the position
-	err = w.poolRepo.UpsertUserPosition(ctx, position)
-	if err != nil {
-		return fmt.Errorf("failed to upsert user position: %w", err)
-	}
+	tokensFloat, _ := result.AmountIn.Float64()
+	log.Printf("[FakeVolume Worker] SELL: Chain=%s, Tokens=%.6f, CNPY=%.4f, Price=%.8f",
+		chain.ChainName, tokensFloat, cnpyOutFloat, priceFloat)
 
 	return nil
 }
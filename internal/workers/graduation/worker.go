@@ -0,0 +1,281 @@
+package graduation
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/enielson/launchpad/internal/graduator"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Graduator performs the graduation of a single chain
+type Graduator interface {
+	CheckAndGraduate(ctx context.Context, chainID uuid.UUID) error
+}
+
+// Locker provides a cross-process lock per chain so that only one replica
+// attempts to graduate a given chain at a time
+type Locker interface {
+	// TryLock attempts to lock the chain without blocking. When acquired is
+	// true the caller must call release once graduation has finished.
+	TryLock(ctx context.Context, chainID uuid.UUID) (release func(), acquired bool, err error)
+}
+
+// Worker graduates virtual chains whose pools have reached the graduation threshold.
+// Chains are checked when a trade path signals them via Notify and on a periodic sweep.
+type Worker struct {
+	chainRepo      interfaces.ChainRepository
+	poolRepo       interfaces.VirtualPoolRepository
+	graduationRepo interfaces.ChainGraduationRepository
+	graduator      Graduator
+	locker         Locker
+	interval       time.Duration
+	triggers       chan uuid.UUID
+	stopChan       chan struct{}
+	done           chan struct{}
+	stopOnce       sync.Once
+}
+
+// Config holds configuration for the graduation worker
+type Config struct {
+	// Interval is how often to sweep all virtual_active chains (default: 1 minute)
+	Interval time.Duration
+
+	// QueueSize is the number of pending graduation triggers buffered between sweeps (default: 100)
+	QueueSize int
+}
+
+// DefaultConfig returns default configuration for the worker
+func DefaultConfig() Config {
+	return Config{
+		Interval:  1 * time.Minute,
+		QueueSize: 100,
+	}
+}
+
+// NewWorker creates a new graduation worker
+func NewWorker(chainRepo interfaces.ChainRepository, poolRepo interfaces.VirtualPoolRepository, graduationRepo interfaces.ChainGraduationRepository, chainGraduator Graduator, locker Locker, config Config) *Worker {
+	if config.Interval == 0 {
+		config.Interval = 1 * time.Minute
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = 100
+	}
+
+	return &Worker{
+		chainRepo:      chainRepo,
+		poolRepo:       poolRepo,
+		graduationRepo: graduationRepo,
+		graduator:      chainGraduator,
+		locker:         locker,
+		interval:       config.Interval,
+		triggers:       make(chan uuid.UUID, config.QueueSize),
+		stopChan:       make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+}
+
+// Start begins the graduation worker
+func (w *Worker) Start() error {
+	log.Printf("[Graduation Worker] Starting (sweep interval: %v)", w.interval)
+
+	go w.run()
+
+	return nil
+}
+
+// Stop gracefully stops the graduation worker
+func (w *Worker) Stop() error {
+	w.stopOnce.Do(func() {
+		log.Println("[Graduation Worker] Stopping...")
+		close(w.stopChan)
+
+		// Wait for worker to finish current operation
+		select {
+		case <-w.done:
+			log.Println("[Graduation Worker] Stopped")
+		case <-time.After(10 * time.Second):
+			log.Println("[Graduation Worker] Stop timeout")
+		}
+	})
+
+	return nil
+}
+
+// Notify queues a chain for a graduation check. It never blocks; if the queue
+// is full the chain will be picked up by the next periodic sweep.
+func (w *Worker) Notify(chainID uuid.UUID) {
+	select {
+	case w.triggers <- chainID:
+	default:
+		log.Printf("[Graduation Worker] Trigger queue full, chain %s deferred to next sweep", chainID)
+	}
+}
+
+// run is the main worker loop
+func (w *Worker) run() {
+	defer close(w.done)
+
+	// Sweep immediately on start to catch chains that crossed the threshold while we were down
+	w.sweep()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case chainID := <-w.triggers:
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			if err := w.GraduateIfReady(ctx, chainID); err != nil {
+				log.Printf("[Graduation Worker] Error graduating chain %s: %v", chainID, err)
+			}
+			cancel()
+		case <-ticker.C:
+			w.sweep()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// sweep resumes graduations whose retry is due and checks every virtual_active
+// chain against its graduation threshold
+func (w *Worker) sweep() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	pending, err := w.graduationRepo.ListIncomplete(ctx, 100)
+	if err != nil {
+		log.Printf("[Graduation Worker] Error querying pending graduations: %v", err)
+	} else {
+		for i := range pending {
+			if err := w.GraduateIfReady(ctx, pending[i].ChainID); err != nil {
+				log.Printf("[Graduation Worker] Error resuming graduation for chain %s at step %s: %v",
+					pending[i].ChainID, pending[i].NextStep(), err)
+			}
+		}
+	}
+
+	pagination := interfaces.Pagination{Page: 1, Limit: 100}
+	for {
+		pagination.Offset = (pagination.Page - 1) * pagination.Limit
+		chains, total, err := w.chainRepo.ListByStatus(ctx, models.ChainStatusVirtualActive, pagination)
+		if err != nil {
+			log.Printf("[Graduation Worker] Error querying chains: %v", err)
+			return
+		}
+
+		for i := range chains {
+			if err := w.GraduateIfReady(ctx, chains[i].ID); err != nil {
+				log.Printf("[Graduation Worker] Error graduating chain %s: %v", chains[i].ChainName, err)
+			}
+		}
+
+		if len(chains) == 0 || pagination.Page*pagination.Limit >= total {
+			return
+		}
+		pagination.Page++
+	}
+}
+
+// GraduateIfReady graduates the chain if its virtual pool has reached the
+// graduation threshold. The chain lock is held for the whole graduation so
+// that another replica cannot graduate the same chain concurrently.
+func (w *Worker) GraduateIfReady(ctx context.Context, chainID uuid.UUID) error {
+	ready, err := w.isReady(ctx, chainID)
+	if err != nil || !ready {
+		return err
+	}
+
+	release, acquired, err := w.locker.TryLock(ctx, chainID)
+	if err != nil {
+		return fmt.Errorf("failed to lock chain: %w", err)
+	}
+	if !acquired {
+		log.Printf("[Graduation Worker] Chain %s is being graduated by another worker, skipping", chainID)
+		return nil
+	}
+	defer release()
+
+	// Re-check under the lock: another replica may have graduated the chain
+	// between our first check and acquiring the lock
+	ready, err = w.isReady(ctx, chainID)
+	if err != nil || !ready {
+		return err
+	}
+
+	log.Printf("[Graduation Worker] Graduating chain %s...", chainID)
+
+	if err := w.graduator.CheckAndGraduate(ctx, chainID); err != nil {
+		if errors.Is(err, graduator.ErrRetryNotDue) {
+			return nil
+		}
+		if errors.Is(err, graduator.ErrAwaitingDeployment) {
+			log.Printf("[Graduation Worker] Chain %s is waiting for the deployer to report it running", chainID)
+			return nil
+		}
+		return err
+	}
+
+	log.Printf("[Graduation Worker] Chain %s graduated", chainID)
+	return nil
+}
+
+// isReady reports whether the chain has an unfinished graduation that is due
+// for retry, or is still virtual_active and its pool has reached the
+// graduation threshold
+func (w *Worker) isReady(ctx context.Context, chainID uuid.UUID) (bool, error) {
+	graduation, err := w.graduationRepo.GetByChainID(ctx, chainID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get graduation state: %w", err)
+	}
+	if graduation != nil {
+		due := graduation.NextRetryAt == nil || !time.Now().Before(*graduation.NextRetryAt)
+		return !graduation.IsComplete() && due, nil
+	}
+
+	chain, err := w.chainRepo.GetByID(ctx, chainID, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to get chain: %w", err)
+	}
+
+	if chain.IsGraduated || chain.Status != models.ChainStatusVirtualActive {
+		return false, nil
+	}
+
+	pool, err := w.poolRepo.GetPoolByChainID(ctx, chainID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get virtual pool: %w", err)
+	}
+
+	return pool.ReachesThreshold(chain.GraduationThreshold), nil
+}
+
+// advisoryLocker implements Locker using PostgreSQL advisory locks
+type advisoryLocker struct {
+	db *sqlx.DB
+}
+
+// NewAdvisoryLocker creates a Locker backed by PostgreSQL advisory locks
+func NewAdvisoryLocker(db *sqlx.DB) Locker {
+	return &advisoryLocker{db: db}
+}
+
+// TryLock takes the advisory lock derived from the chain ID
+func (l *advisoryLocker) TryLock(ctx context.Context, chainID uuid.UUID) (func(), bool, error) {
+	return database.TryAdvisoryLock(ctx, l.db, lockKey(chainID))
+}
+
+// lockKey folds a chain UUID into the int64 key space used by advisory locks
+func lockKey(chainID uuid.UUID) int64 {
+	return int64(binary.BigEndian.Uint64(chainID[:8]) ^ binary.BigEndian.Uint64(chainID[8:]))
+}
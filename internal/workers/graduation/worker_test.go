@@ -0,0 +1,253 @@
+package graduation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enielson/launchpad/internal/graduator"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/internal/testutil/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockGraduator mocks the Graduator interface
+type mockGraduator struct {
+	mock.Mock
+}
+
+func (m *mockGraduator) CheckAndGraduate(ctx context.Context, chainID uuid.UUID) error {
+	args := m.Called(ctx, chainID)
+	return args.Error(0)
+}
+
+// fakeLocker records lock usage and can simulate a lock held elsewhere
+type fakeLocker struct {
+	held     bool
+	err      error
+	acquired int
+	released int
+}
+
+func (l *fakeLocker) TryLock(ctx context.Context, chainID uuid.UUID) (func(), bool, error) {
+	if l.err != nil {
+		return nil, false, l.err
+	}
+	if l.held {
+		return nil, false, nil
+	}
+	l.acquired++
+	return func() { l.released++ }, true, nil
+}
+
+func buildChain(chainID uuid.UUID, status string, threshold float64) *models.Chain {
+	return &models.Chain{
+		ID:                  chainID,
+		ChainName:           "test-chain",
+		Status:              status,
+		GraduationThreshold: threshold,
+	}
+}
+
+func TestWorker_GraduateIfReady(t *testing.T) {
+	tests := []struct {
+		name             string
+		chain            *models.Chain
+		cnpyReserveMicro uint64
+		lockHeld         bool
+		lockErr          error
+		graduateErr      error
+		expectGraduate   bool
+		expectError      bool
+		expectLocked     bool
+	}{
+		{
+			name:             "threshold reached graduates chain",
+			chain:            buildChain(uuid.Nil, models.ChainStatusVirtualActive, 50000),
+			cnpyReserveMicro: 50000000000,
+			expectGraduate:   true,
+			expectLocked:     true,
+		},
+		{
+			name:             "threshold not reached is a no-op",
+			chain:            buildChain(uuid.Nil, models.ChainStatusVirtualActive, 50000),
+			cnpyReserveMicro: 49999990000,
+		},
+		{
+			name:             "already graduated chain is skipped",
+			chain:            buildChain(uuid.Nil, models.ChainStatusGraduated, 50000),
+			cnpyReserveMicro: 60000000000,
+		},
+		{
+			name:             "lock held by another replica skips graduation",
+			chain:            buildChain(uuid.Nil, models.ChainStatusVirtualActive, 50000),
+			cnpyReserveMicro: 60000000000,
+			lockHeld:         true,
+		},
+		{
+			name:             "lock error is returned",
+			chain:            buildChain(uuid.Nil, models.ChainStatusVirtualActive, 50000),
+			cnpyReserveMicro: 60000000000,
+			lockErr:          errors.New("connection refused"),
+			expectError:      true,
+		},
+		{
+			name:             "graduation failure releases lock and returns error",
+			chain:            buildChain(uuid.Nil, models.ChainStatusVirtualActive, 50000),
+			cnpyReserveMicro: 60000000000,
+			graduateErr:      errors.New("rpc unavailable"),
+			expectGraduate:   true,
+			expectError:      true,
+			expectLocked:     true,
+		},
+		{
+			name:             "waiting on the deployer is not an error",
+			chain:            buildChain(uuid.Nil, models.ChainStatusVirtualActive, 50000),
+			cnpyReserveMicro: 60000000000,
+			graduateErr:      graduator.ErrAwaitingDeployment,
+			expectGraduate:   true,
+			expectLocked:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chainID := uuid.New()
+			tt.chain.ID = chainID
+
+			chainRepo := new(mocks.MockChainRepository)
+			poolRepo := new(mocks.MockVirtualPoolRepository)
+			graduationRepo := new(mocks.MockChainGraduationRepository)
+			grad := new(mockGraduator)
+			locker := &fakeLocker{held: tt.lockHeld, err: tt.lockErr}
+
+			graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(nil, nil)
+			chainRepo.On("GetByID", mock.Anything, chainID, []string(nil)).Return(tt.chain, nil)
+			poolRepo.On("GetPoolByChainID", mock.Anything, chainID).
+				Return(&models.VirtualPool{ChainID: chainID, CNPYReserveMicro: tt.cnpyReserveMicro}, nil)
+			if tt.expectGraduate {
+				grad.On("CheckAndGraduate", mock.Anything, chainID).Return(tt.graduateErr)
+			}
+
+			worker := NewWorker(chainRepo, poolRepo, graduationRepo, grad, locker, DefaultConfig())
+			err := worker.GraduateIfReady(context.Background(), chainID)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tt.expectLocked {
+				assert.Equal(t, 1, locker.acquired)
+				assert.Equal(t, 1, locker.released, "lock must always be released")
+			} else {
+				assert.Equal(t, 0, locker.acquired)
+			}
+
+			if !tt.expectGraduate {
+				grad.AssertNotCalled(t, "CheckAndGraduate", mock.Anything, mock.Anything)
+			}
+			grad.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWorker_sweep(t *testing.T) {
+	readyID := uuid.New()
+	notReadyID := uuid.New()
+	chains := []models.Chain{
+		*buildChain(readyID, models.ChainStatusVirtualActive, 100),
+		*buildChain(notReadyID, models.ChainStatusVirtualActive, 100),
+	}
+
+	chainRepo := new(mocks.MockChainRepository)
+	poolRepo := new(mocks.MockVirtualPoolRepository)
+	graduationRepo := new(mocks.MockChainGraduationRepository)
+	grad := new(mockGraduator)
+
+	graduationRepo.On("ListIncomplete", mock.Anything, 100).Return([]models.ChainGraduation{}, nil)
+	graduationRepo.On("GetByChainID", mock.Anything, mock.Anything).Return(nil, nil)
+	chainRepo.On("ListByStatus", mock.Anything, models.ChainStatusVirtualActive, interfaces.Pagination{Page: 1, Limit: 100}).
+		Return(chains, len(chains), nil)
+	chainRepo.On("GetByID", mock.Anything, readyID, []string(nil)).Return(&chains[0], nil)
+	chainRepo.On("GetByID", mock.Anything, notReadyID, []string(nil)).Return(&chains[1], nil)
+	poolRepo.On("GetPoolByChainID", mock.Anything, readyID).Return(&models.VirtualPool{CNPYReserveMicro: 150000000}, nil)
+	poolRepo.On("GetPoolByChainID", mock.Anything, notReadyID).Return(&models.VirtualPool{CNPYReserveMicro: 50000000}, nil)
+	grad.On("CheckAndGraduate", mock.Anything, readyID).Return(nil).Once()
+
+	worker := NewWorker(chainRepo, poolRepo, graduationRepo, grad, &fakeLocker{}, DefaultConfig())
+	worker.sweep()
+
+	grad.AssertExpectations(t)
+	grad.AssertNotCalled(t, "CheckAndGraduate", mock.Anything, notReadyID)
+}
+
+func TestWorker_Notify(t *testing.T) {
+	worker := NewWorker(nil, nil, nil, nil, nil, Config{QueueSize: 1})
+
+	first := uuid.New()
+	worker.Notify(first)
+	// Queue is full; the second trigger must be dropped rather than block
+	worker.Notify(uuid.New())
+
+	assert.Equal(t, first, <-worker.triggers)
+	assert.Len(t, worker.triggers, 0)
+}
+
+func TestLockKey(t *testing.T) {
+	id := uuid.New()
+	assert.Equal(t, lockKey(id), lockKey(id))
+	assert.NotEqual(t, lockKey(id), lockKey(uuid.New()))
+}
+
+func TestWorker_GraduateIfReady_ResumesPendingGraduation(t *testing.T) {
+	chainID := uuid.New()
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name           string
+		nextRetryAt    *time.Time
+		graduateErr    error
+		expectGraduate bool
+	}{
+		{name: "retry due resumes graduation", nextRetryAt: &past, expectGraduate: true},
+		{name: "retry not due is skipped", nextRetryAt: &future},
+		{name: "backoff reported by graduator is not an error", nextRetryAt: nil, graduateErr: graduator.ErrRetryNotDue, expectGraduate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chainRepo := new(mocks.MockChainRepository)
+			poolRepo := new(mocks.MockVirtualPoolRepository)
+			graduationRepo := new(mocks.MockChainGraduationRepository)
+			grad := new(mockGraduator)
+
+			// The chain is no longer virtual_active, so readiness comes from the graduation record alone
+			graduationRepo.On("GetByChainID", mock.Anything, chainID).Return(&models.ChainGraduation{
+				ChainID:     chainID,
+				CurrentStep: models.GraduationStepDeploymentConfirmed,
+				Attempts:    2,
+				NextRetryAt: tt.nextRetryAt,
+			}, nil)
+			if tt.expectGraduate {
+				grad.On("CheckAndGraduate", mock.Anything, chainID).Return(tt.graduateErr)
+			}
+
+			worker := NewWorker(chainRepo, poolRepo, graduationRepo, grad, &fakeLocker{}, DefaultConfig())
+			err := worker.GraduateIfReady(context.Background(), chainID)
+			assert.NoError(t, err)
+
+			grad.AssertExpectations(t)
+			if !tt.expectGraduate {
+				grad.AssertNotCalled(t, "CheckAndGraduate", mock.Anything, mock.Anything)
+			}
+			chainRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
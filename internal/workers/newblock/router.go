@@ -0,0 +1,105 @@
+package newblock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/internal/services"
+)
+
+// Router runs one worker per root chain and routes work to the worker serving
+// a chain's root chain. Each worker only applies deposits to chains on its own
+// root chain, so together they cover every chain exactly once.
+type Router struct {
+	chainRepo interfaces.ChainRepository
+	workers   []*Worker          // in configured order
+	byRoot    map[uint64]*Worker // root chain ID -> worker
+}
+
+// NewRouter creates a router over workers for distinct root chains
+func NewRouter(chainRepo interfaces.ChainRepository, workers ...*Worker) (*Router, error) {
+	router := &Router{
+		chainRepo: chainRepo,
+		workers:   workers,
+		byRoot:    make(map[uint64]*Worker, len(workers)),
+	}
+	for _, worker := range workers {
+		if _, exists := router.byRoot[worker.rootChainID]; exists {
+			return nil, fmt.Errorf("more than one worker for root chain %d", worker.rootChainID)
+		}
+		router.byRoot[worker.rootChainID] = worker
+	}
+
+	return router, nil
+}
+
+// SetGraduationNotifier registers the graduation notifier with every worker
+func (r *Router) SetGraduationNotifier(notifier services.GraduationNotifier) {
+	for _, worker := range r.workers {
+		worker.SetGraduationNotifier(notifier)
+	}
+}
+
+// Start starts every worker, stopping those already started if one fails
+func (r *Router) Start() error {
+	for i, worker := range r.workers {
+		if err := worker.Start(); err != nil {
+			for _, started := range r.workers[:i] {
+				started.Stop()
+			}
+			return fmt.Errorf("failed to start worker for root chain %d: %w", worker.rootChainID, err)
+		}
+	}
+	return nil
+}
+
+// Stop stops every worker
+func (r *Router) Stop() error {
+	var errs []error
+	for _, worker := range r.workers {
+		if err := worker.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("root chain %d: %w", worker.rootChainID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Status reports the connection state and ingestion progress of each root chain
+func (r *Router) Status() []*models.RootChainStatus {
+	statuses := make([]*models.RootChainStatus, len(r.workers))
+	for i, worker := range r.workers {
+		statuses[i] = worker.Status()
+	}
+	return statuses
+}
+
+// Replay hands a deposit from the dead-letter queue to the worker for its
+// chain's root chain. A deposit recorded without a chain is offered to each
+// worker in turn until one serves the chain it was sent to.
+func (r *Router) Replay(ctx context.Context, event *models.FailedEvent) error {
+	if event.ChainID == nil {
+		for _, worker := range r.workers {
+			if err := worker.Replay(ctx, event); !errors.Is(err, ErrOtherRootChain) {
+				return err
+			}
+		}
+		return fmt.Errorf("no root chain worker serves the chain of deposit %s", event.Reference)
+	}
+
+	chain, err := r.chainRepo.GetByID(ctx, *event.ChainID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to look up chain %s: %w", event.ChainID, err)
+	}
+
+	worker, ok := r.byRoot[chain.RootChainID]
+	if !ok {
+		return fmt.Errorf("no worker for root chain %d of chain %s", chain.RootChainID, chain.ChainName)
+	}
+
+	log.Printf("[NewBlock Worker] Replaying deposit %s on root chain %d", event.Reference, chain.RootChainID)
+	return worker.Replay(ctx, event)
+}
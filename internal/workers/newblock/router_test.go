@@ -0,0 +1,133 @@
+package newblock
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRouter(t *testing.T) {
+	chainRepo := new(MockChainRepository)
+
+	_, err := NewRouter(chainRepo, &Worker{rootChainID: 1}, &Worker{rootChainID: 2}, &Worker{rootChainID: 1})
+	assert.ErrorContains(t, err, "more than one worker for root chain 1")
+}
+
+func TestRouter_Status(t *testing.T) {
+	first := &Worker{rootChainID: 2, processedHeight: 90, chainHeight: 100, confirmations: 2}
+	second := &Worker{rootChainID: 1, processedHeight: 500, chainHeight: 500}
+	router, err := NewRouter(new(MockChainRepository), first, second)
+	require.NoError(t, err)
+
+	statuses := router.Status()
+	require.Len(t, statuses, 2)
+
+	// Root chains are reported in configured order
+	assert.Equal(t, uint64(2), statuses[0].RootChainID)
+	assert.False(t, statuses[0].Connected)
+	assert.Equal(t, uint64(8), statuses[0].Lag)
+	assert.Equal(t, uint64(1), statuses[1].RootChainID)
+	assert.Equal(t, uint64(0), statuses[1].Lag)
+}
+
+func TestRouter_Replay(t *testing.T) {
+	chainID := uuid.New()
+	recipientAddress := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	senderAddress := []byte{0x01, 0x02, 0x03, 0x04}
+	height := uint64(3100)
+
+	raw, err := json.Marshal(buildTxResultWithValidSend(recipientAddress, senderAddress, 2500000))
+	require.NoError(t, err)
+
+	chain := buildChain(chainID, "SecondRootChain", uuid.New())
+	chain.RootChainID = 2
+
+	// newWorker returns a worker for a root chain whose deposits are recorded
+	newWorker := func(rootChainID uint64) (*Worker, *MockDepositProcessor) {
+		chainRepo := new(MockChainRepository)
+		chainRepo.On("GetByAddress", mock.Anything, hex.EncodeToString(recipientAddress)).Return(chain, nil).Maybe()
+		userRepo := new(MockUserRepository)
+		setupStandardUserMocks(userRepo, senderAddress)
+		deposits := new(MockDepositProcessor)
+		deposits.On("ProcessDepositWithRetry", mock.Anything, mock.MatchedBy(func(deposit *services.Deposit) bool {
+			return deposit.ChainID == chainID && deposit.BlockHeight == height
+		})).Return(buildTradeResult(2500, 31.0), nil).Maybe()
+
+		rpc := new(MockRPCClient)
+		rpc.On("CertByHeight", height).Return(finalCert(), nil).Maybe()
+
+		return &Worker{
+			rpcClient:   rpc,
+			chainRepo:   chainRepo,
+			deposits:    deposits,
+			userRepo:    userRepo,
+			logger:      NewLogger(),
+			rootChainID: rootChainID,
+		}, deposits
+	}
+
+	t.Run("deposit is replayed by its chain's root chain worker", func(t *testing.T) {
+		first, firstDeposits := newWorker(1)
+		second, secondDeposits := newWorker(2)
+		chainRepo := new(MockChainRepository)
+		chainRepo.On("GetByID", mock.Anything, chainID, []string(nil)).Return(chain, nil).Once()
+		router, err := NewRouter(chainRepo, first, second)
+		require.NoError(t, err)
+
+		event := &models.FailedEvent{EventType: models.FailedEventTypeDeposit, Reference: "0xabc123", ChainID: &chainID, BlockHeight: &height, RawEvent: string(raw)}
+		require.NoError(t, router.Replay(context.Background(), event))
+
+		firstDeposits.AssertNotCalled(t, "ProcessDepositWithRetry", mock.Anything, mock.Anything)
+		secondDeposits.AssertNumberOfCalls(t, "ProcessDepositWithRetry", 1)
+	})
+
+	t.Run("deposit without a chain is offered to each worker", func(t *testing.T) {
+		first, firstDeposits := newWorker(1)
+		second, secondDeposits := newWorker(2)
+		chainRepo := new(MockChainRepository)
+		router, err := NewRouter(chainRepo, first, second)
+		require.NoError(t, err)
+
+		event := &models.FailedEvent{EventType: models.FailedEventTypeDeposit, Reference: "0xabc123", BlockHeight: &height, RawEvent: string(raw)}
+		require.NoError(t, router.Replay(context.Background(), event))
+
+		firstDeposits.AssertNotCalled(t, "ProcessDepositWithRetry", mock.Anything, mock.Anything)
+		secondDeposits.AssertNumberOfCalls(t, "ProcessDepositWithRetry", 1)
+		chainRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("deposit to a chain no worker serves", func(t *testing.T) {
+		first, firstDeposits := newWorker(1)
+		chainRepo := new(MockChainRepository)
+		chainRepo.On("GetByID", mock.Anything, chainID, []string(nil)).Return(chain, nil)
+		router, err := NewRouter(chainRepo, first)
+		require.NoError(t, err)
+
+		event := &models.FailedEvent{EventType: models.FailedEventTypeDeposit, Reference: "0xabc123", ChainID: &chainID, BlockHeight: &height, RawEvent: string(raw)}
+		assert.ErrorContains(t, router.Replay(context.Background(), event), "no worker for root chain 2 of chain SecondRootChain")
+
+		event.ChainID = nil
+		assert.ErrorContains(t, router.Replay(context.Background(), event), "no root chain worker serves the chain of deposit 0xabc123")
+		firstDeposits.AssertNotCalled(t, "ProcessDepositWithRetry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("chain lookup failure", func(t *testing.T) {
+		first, _ := newWorker(1)
+		chainRepo := new(MockChainRepository)
+		chainRepo.On("GetByID", mock.Anything, chainID, []string(nil)).Return(nil, errors.New("connection refused"))
+		router, err := NewRouter(chainRepo, first)
+		require.NoError(t, err)
+
+		event := &models.FailedEvent{EventType: models.FailedEventTypeDeposit, Reference: "0xabc123", ChainID: &chainID, BlockHeight: &height, RawEvent: string(raw)}
+		assert.ErrorContains(t, router.Replay(context.Background(), event), "connection refused")
+	})
+}
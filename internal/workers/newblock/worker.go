@@ -3,32 +3,88 @@ package newblock
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/canopy-network/canopy/fsm"
 	"github.com/canopy-network/canopy/lib"
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/internal/services"
 	"github.com/enielson/launchpad/pkg/bondingcurve"
 	"github.com/enielson/launchpad/pkg/sub"
+	"github.com/google/uuid"
 )
 
-// RPCClient defines the interface for fetching blockchain data
+// transactionsPerPage is the page size used when fetching a block's transactions
+const transactionsPerPage = 100
+
+// RPCClient defines the interface for fetching blockchain data. It matches the
+// canopy RPC client: TransactionsByHeight returns one page of a block's
+// transactions, with TotalPages and TotalCount describing the whole block.
 type RPCClient interface {
+	Height() (*uint64, lib.ErrorI)
+	CertByHeight(height uint64) (*lib.QuorumCertificate, lib.ErrorI)
 	TransactionsByHeight(height uint64, page lib.PageParams) (*lib.Page, lib.ErrorI)
 }
 
-// Worker manages the root chain subscription and transaction processing
+// DepositProcessor applies root chain deposits to virtual pools, or refunds them
+// when the pool cannot take them. Implementations must skip deposits whose
+// transaction hash and height were already applied or refunded.
+type DepositProcessor interface {
+	ProcessDepositWithRetry(ctx context.Context, deposit *services.Deposit) (*bondingcurve.TradeResult, error)
+	RefundDepositWithRetry(ctx context.Context, deposit *services.Deposit) error
+}
+
+// BlockApplier applies every deposit at a final height, together with the
+// height's checkpoint, in a single database transaction. Deposits that cannot
+// be applied are queued for retry in the same transaction.
+type BlockApplier interface {
+	ApplyBlockWithRetry(ctx context.Context, block *services.DepositBlock) ([]services.DepositOutcome, error)
+}
+
+// ErrOtherRootChain is returned when a worker is asked to replay a deposit to
+// a chain on a root chain it does not serve
+var ErrOtherRootChain = errors.New("chain is on another root chain")
+
+// Worker manages the subscription to one root chain and applies deposits to the
+// chains on it. Deposits to chains on other root chains are ignored, so workers
+// for several root chains can share the chain repository.
 type Worker struct {
-	subscription *sub.Subscription
-	rpcClient    RPCClient
-	chainRepo    interfaces.ChainRepository
-	poolRepo     interfaces.VirtualPoolRepository
-	userRepo     interfaces.UserRepository
-	logger       sub.Logger
+	subscriptions *sub.Manager
+	subConfig     sub.Config
+	rpcClient     RPCClient
+	chainRepo     interfaces.ChainRepository
+	deposits      DepositProcessor
+	blocks        BlockApplier
+	userRepo      interfaces.UserRepository
+	checkpoints   interfaces.RootChainCheckpointRepository
+	pending       interfaces.PendingDepositRepository
+	failedEvents  interfaces.FailedEventRepository
+	logger        sub.Logger
+	graduation    services.GraduationNotifier
+	quotes        *services.QuoteSigner
+	rootChainID   uint64
+	startHeight   uint64
+
+	// confirmations is how many blocks must exist above a height before it is applied
+	confirmations uint64
+
+	// syncMu serializes backfill and live block processing
+	syncMu        sync.Mutex
+	pendingHeight uint64 // last height scanned for pending deposits, guarded by syncMu
+
+	// Ingestion progress, guarded by statusMu
+	statusMu        sync.RWMutex
+	resumed         bool   // whether processedHeight has been loaded from the checkpoint
+	processedHeight uint64 // last fully processed root chain height
+	chainHeight     uint64 // latest root chain height seen
 }
 
 // Config holds the configuration for the newblock worker
@@ -36,48 +92,107 @@ type Config struct {
 	RootChainURL    string // WebSocket URL for subscription
 	RootChainID     uint64 // Chain ID to subscribe to
 	RootChainRPCURL string // HTTP URL for RPC client
+	StartHeight     uint64 // Height to start from when no checkpoint exists; 0 starts at the first height seen
+	Confirmations   uint64 // Blocks required above a height before its deposits are applied
+
+	// Subscriptions is the manager the root chain subscription is added to, so
+	// workers for several root chains can share one. A manager is created when nil.
+	Subscriptions *sub.Manager
+
+	// Quotes verifies the trade quotes senders put in the memos of their
+	// deposits. Memos are ignored when nil.
+	Quotes *services.QuoteSigner
 }
 
 // NewWorker creates a new root chain event worker
-func NewWorker(config Config, rpcClient RPCClient, chainRepo interfaces.ChainRepository, poolRepo interfaces.VirtualPoolRepository, userRepo interfaces.UserRepository) *Worker {
+func NewWorker(config Config, rpcClient RPCClient, chainRepo interfaces.ChainRepository, deposits DepositProcessor, blocks BlockApplier, userRepo interfaces.UserRepository, checkpoints interfaces.RootChainCheckpointRepository, pending interfaces.PendingDepositRepository, failedEvents interfaces.FailedEventRepository) *Worker {
 	logger := NewLogger()
 
-	// Create subscription config
-	subConfig := sub.Config{
-		ChainId: config.RootChainID,
-		Url:     config.RootChainURL,
+	subscriptions := config.Subscriptions
+	if subscriptions == nil {
+		subscriptions = sub.NewManager(logger)
 	}
 
 	// Create worker instance
 	worker := &Worker{
-		rpcClient: rpcClient,
-		chainRepo: chainRepo,
-		poolRepo:  poolRepo,
-		userRepo:  userRepo,
-		logger:    logger,
+		subscriptions: subscriptions,
+		subConfig: sub.Config{
+			ChainId: config.RootChainID,
+			Url:     config.RootChainURL,
+		},
+		rpcClient:     rpcClient,
+		chainRepo:     chainRepo,
+		deposits:      deposits,
+		blocks:        blocks,
+		userRepo:      userRepo,
+		checkpoints:   checkpoints,
+		pending:       pending,
+		failedEvents:  failedEvents,
+		logger:        logger,
+		quotes:        config.Quotes,
+		rootChainID:   config.RootChainID,
+		startHeight:   config.StartHeight,
+		confirmations: config.Confirmations,
 	}
 
-	// Create subscription with event handler
-	worker.subscription = sub.NewSubscription(subConfig, worker.handleRootChainEvent, logger)
-
 	return worker
 }
 
-// Start begins the worker's subscription to root chain events
+// SetGraduationNotifier registers the notifier that is signalled when a deposit
+// pushes a pool past its chain's graduation threshold
+func (w *Worker) SetGraduationNotifier(notifier services.GraduationNotifier) {
+	w.graduation = notifier
+}
+
+// RootChainID returns the ID of the root chain the worker serves
+func (w *Worker) RootChainID() uint64 {
+	return w.rootChainID
+}
+
+// Start adds the worker's subscription to root chain events to its manager,
+// backfilling missed heights on every (re)connect
 func (w *Worker) Start() error {
-	log.Printf("[NewBlock Worker] Starting root chain subscription...")
-	return w.subscription.Start()
+	log.Printf("[NewBlock Worker] Starting subscription to root chain %d...", w.rootChainID)
+	return w.subscriptions.AddSubscriptionWithConnectHandler(w.subConfig, w.handleRootChainEvent, w.handleConnect)
 }
 
 // Stop gracefully shuts down the worker
 func (w *Worker) Stop() error {
-	log.Printf("[NewBlock Worker] Stopping root chain subscription...")
-	return w.subscription.Stop()
+	log.Printf("[NewBlock Worker] Stopping subscription to root chain %d...", w.rootChainID)
+	return w.subscriptions.RemoveSubscription(w.rootChainID)
 }
 
 // IsConnected returns whether the worker is connected to the root chain
 func (w *Worker) IsConnected() bool {
-	return w.subscription.IsConnected()
+	if w.subscriptions == nil {
+		return false
+	}
+	subscription, ok := w.subscriptions.GetSubscription(w.rootChainID)
+	return ok && subscription.IsConnected()
+}
+
+// servesChain reports whether deposits to a chain arrive on the worker's root chain
+func (w *Worker) servesChain(chain *models.Chain) bool {
+	return chain.RootChainID == w.rootChainID
+}
+
+// Status reports the connection state and how far ingestion trails the root
+// chain. Heights still inside the confirmation window are not counted as lag.
+func (w *Worker) Status() *models.RootChainStatus {
+	w.statusMu.RLock()
+	defer w.statusMu.RUnlock()
+
+	status := &models.RootChainStatus{
+		RootChainID:     w.rootChainID,
+		Connected:       w.IsConnected(),
+		ProcessedHeight: w.processedHeight,
+		ChainHeight:     w.chainHeight,
+		Confirmations:   w.confirmations,
+	}
+	if w.chainHeight > w.processedHeight+w.confirmations {
+		status.Lag = w.chainHeight - w.confirmations - w.processedHeight
+	}
+	return status
 }
 
 // HandleRootChainEventForTest exposes the event handler for integration testing
@@ -87,60 +202,490 @@ func (w *Worker) HandleRootChainEventForTest(info *lib.RootChainInfo) error {
 	return w.handleRootChainEvent(info)
 }
 
+// handleConnect walks every height between the checkpoint and the current root
+// chain height. It runs before events from a new connection are handled, so
+// blocks published while the worker was disconnected are not lost.
+func (w *Worker) handleConnect() {
+	height, err := w.rpcClient.Height()
+	if err != nil {
+		log.Printf("[NewBlock Worker] Failed to get root chain height for backfill: %v", err)
+		return
+	}
+	if height == nil {
+		return
+	}
+
+	if err := w.syncTo(context.Background(), *height); err != nil {
+		log.Printf("[NewBlock Worker] Backfill to height %d stopped: %v", *height, err)
+	}
+}
+
 // handleRootChainEvent processes new root chain info received from the subscription
 func (w *Worker) handleRootChainEvent(info *lib.RootChainInfo) error {
 	log.Printf("[NewBlock Worker] Processing block at height %d from root chain %d",
 		info.Height, info.RootChainId)
 
-	// Fetch transactions at this height using the RPC client
-	pageParams := lib.PageParams{
-		PageNumber: 1,
-		PerPage:    100, // Adjust as needed
+	return w.syncTo(context.Background(), info.Height)
+}
+
+// syncTo applies every final height after the last checkpoint up to target,
+// committing the checkpoint with each one, then records the sends in heights that
+// are not final yet as pending deposits. Heights at or below the checkpoint have
+// already been processed and are skipped.
+func (w *Worker) syncTo(ctx context.Context, target uint64) error {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+
+	w.observeHeight(target)
+
+	next, err := w.nextHeight(ctx, target)
+	if err != nil {
+		return err
+	}
+	if next > target {
+		log.Printf("[NewBlock Worker] Height %d already processed, skipping", target)
+		return nil
+	}
+
+	if target >= w.confirmations {
+		if err := w.applyFinalHeights(ctx, next, target-w.confirmations); err != nil {
+			return err
+		}
+	}
+
+	return w.recordPendingHeights(ctx, target)
+}
+
+// applyFinalHeights applies the heights from next through last, stopping early
+// at the first height without a quorum certificate
+func (w *Worker) applyFinalHeights(ctx context.Context, next, last uint64) error {
+	if last > next {
+		log.Printf("[NewBlock Worker] Backfilling heights %d to %d", next, last)
+	}
+
+	for height := next; height <= last; height++ {
+		block, err := w.finalBlock(height)
+		if err != nil {
+			return err
+		}
+		if block == nil {
+			log.Printf("[NewBlock Worker] No quorum certificate at height %d yet, waiting", height)
+			return nil
+		}
+
+		if err := w.processHeight(ctx, height, blockTime(block)); err != nil {
+			return err
+		}
+
+		w.statusMu.Lock()
+		w.processedHeight = height
+		w.statusMu.Unlock()
+
+		// Pending rows at or below the checkpoint are hidden from the API, so a
+		// failed cleanup only leaves stale rows behind
+		if w.confirmations > 0 {
+			if err := w.pending.DeleteThroughHeight(ctx, w.rootChainID, height); err != nil {
+				log.Printf("[NewBlock Worker] Failed to clear pending deposits through height %d: %v", height, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// finalBlock returns the block the root chain committed a quorum certificate
+// for at a height, or nil when the height has no certificate yet
+func (w *Worker) finalBlock(height uint64) (*lib.Block, error) {
+	cert, err := w.rpcClient.CertByHeight(height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quorum certificate at height %d: %w", height, err)
+	}
+	if cert == nil || len(cert.BlockHash) == 0 {
+		return nil, nil
+	}
+
+	block := new(lib.Block)
+	if err := lib.Unmarshal(cert.Block, block); err != nil {
+		return nil, fmt.Errorf("failed to decode block at height %d: %w", height, err)
+	}
+	if block.BlockHeader == nil {
+		return nil, fmt.Errorf("quorum certificate at height %d has no block header", height)
+	}
+	return block, nil
+}
+
+// blockTime returns the time of a root chain block. It is part of the block the
+// root chain committee agreed on, unlike the time a sender puts on their own
+// transaction, so quotes are checked for expiry against it.
+func blockTime(block *lib.Block) time.Time {
+	return time.UnixMicro(int64(block.BlockHeader.Time))
+}
+
+// recordPendingHeights records the deposits in every unprocessed height up to
+// target that has not been scanned yet. Nothing is pending without a
+// confirmation depth, since heights are applied as soon as they are final.
+func (w *Worker) recordPendingHeights(ctx context.Context, target uint64) error {
+	if w.confirmations == 0 {
+		return nil
+	}
+
+	w.statusMu.RLock()
+	from := max(w.processedHeight, w.pendingHeight) + 1
+	w.statusMu.RUnlock()
+
+	for height := from; height <= target; height++ {
+		if err := w.recordPendingHeight(ctx, height); err != nil {
+			return err
+		}
+		w.pendingHeight = height
+	}
+
+	return nil
+}
+
+// nextHeight returns the first height that has not been processed. Without a
+// checkpoint the worker starts at the configured start height, or at target.
+func (w *Worker) nextHeight(ctx context.Context, target uint64) (uint64, error) {
+	w.statusMu.RLock()
+	resumed, processed := w.resumed, w.processedHeight
+	w.statusMu.RUnlock()
+	if resumed {
+		return processed + 1, nil
 	}
 
-	page, err := w.rpcClient.TransactionsByHeight(info.Height, pageParams)
+	checkpoint, err := w.checkpoints.Get(ctx, w.rootChainID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch transactions at height %d: %w", info.Height, err)
+		return 0, err
+	}
+
+	next := target
+	switch {
+	case checkpoint != nil:
+		next = checkpoint.Height + 1
+	case w.startHeight > 0:
+		next = w.startHeight
+	}
+
+	w.statusMu.Lock()
+	w.resumed = true
+	if next > 0 {
+		w.processedHeight = next - 1
+	}
+	w.statusMu.Unlock()
+
+	return next, nil
+}
+
+// observeHeight records the latest root chain height seen for lag reporting
+func (w *Worker) observeHeight(height uint64) {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+	if height > w.chainHeight {
+		w.chainHeight = height
+	}
+}
+
+// processHeight applies the sends at a height to the virtual pools and saves
+// the height as the checkpoint. The whole height is applied in one database
+// transaction, so a crash never leaves it half applied. Should that transaction
+// fail, the sends are applied one at a time instead, so that a single bad send
+// cannot hold up the root chain; sends that fail are queued for retry.
+// blockTime is the time of the block at the height.
+func (w *Worker) processHeight(ctx context.Context, height uint64, blockTime time.Time) error {
+	var sends []*lib.TxResult
+	count, err := w.forEachSend(height, func(txResult *lib.TxResult, index, total int) error {
+		sends = append(sends, txResult)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		log.Printf("[NewBlock Worker] No transactions found at height %d", height)
+	}
+
+	block, chains, err := w.buildBlock(ctx, height, blockTime, sends)
+	if err != nil {
+		return err
+	}
+
+	outcomes, err := w.blocks.ApplyBlockWithRetry(ctx, block)
+	if err != nil {
+		log.Printf("[NewBlock Worker] Failed to apply height %d in one transaction, applying its sends one at a time: %v", height, err)
+		return w.processHeightBySend(ctx, height, blockTime, sends)
+	}
+
+	w.reportOutcomes(height, chains, outcomes)
+	return nil
+}
+
+// buildBlock turns the sends at a height into the block of deposits to apply.
+// Chains and users for every recipient and sender are looked up in two queries,
+// creating users for senders that have not been seen before. It also returns
+// the chains deposited to, by ID.
+func (w *Worker) buildBlock(ctx context.Context, height uint64, blockTime time.Time, sends []*lib.TxResult) (*services.DepositBlock, map[uuid.UUID]*models.Chain, error) {
+	block := &services.DepositBlock{RootChainID: w.rootChainID, Height: height}
+	chainsByID := make(map[uuid.UUID]*models.Chain)
+	if len(sends) == 0 {
+		return block, chainsByID, nil
+	}
+
+	recipients := make([]string, 0, len(sends))
+	seen := make(map[string]bool)
+	for _, txResult := range sends {
+		recipientAddress := hex.EncodeToString(txResult.Recipient)
+		if !seen[recipientAddress] {
+			seen[recipientAddress] = true
+			recipients = append(recipients, recipientAddress)
+		}
+	}
+
+	chains, err := w.chainRepo.GetByAddresses(ctx, recipients)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up chains at height %d: %w", height, err)
+	}
+
+	type send struct {
+		txResult *lib.TxResult
+		chain    *models.Chain
+		amount   uint64
+		sender   string
+	}
+	var deposits []send
+	var senders []string
+	seen = make(map[string]bool)
+	for _, txResult := range sends {
+		chain, ok := chains[hex.EncodeToString(txResult.Recipient)]
+		if !ok || !w.servesChain(chain) {
+			// Not a deposit address on this root chain - skip this transaction
+			continue
+		}
+
+		// A malformed send will never succeed, so it is skipped rather than queued for retry
+		amount, err := w.extractSendAmount(txResult)
+		if err != nil {
+			log.Printf("[NewBlock Worker] Failed to extract send amount from %s: %v", txResult.TxHash, err)
+			continue
+		}
+
+		// Convert sender address to hex string with 0x prefix (database stores addresses with 0x prefix)
+		senderAddress := "0x" + hex.EncodeToString(txResult.Sender)
+		if !seen[senderAddress] {
+			seen[senderAddress] = true
+			senders = append(senders, senderAddress)
+		}
+		deposits = append(deposits, send{txResult: txResult, chain: chain, amount: amount, sender: senderAddress})
+	}
+	if len(deposits) == 0 {
+		return block, chainsByID, nil
+	}
+
+	users, err := w.userRepo.GetOrCreateByWalletAddresses(ctx, senders)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve users at height %d: %w", height, err)
+	}
+	usersByAddress := make(map[string]*models.User, len(users))
+	for i := range users {
+		usersByAddress[users[i].WalletAddress] = &users[i]
 	}
 
-	// Process transactions
-	if page != nil && page.Results != nil {
+	for _, d := range deposits {
+		user, ok := usersByAddress[d.sender]
+		if !ok {
+			return nil, nil, fmt.Errorf("no user for sender %s at height %d", d.sender, height)
+		}
+
+		raw, err := json.Marshal(d.txResult)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode transaction %s: %w", d.txResult.TxHash, err)
+		}
+
+		block.Deposits = append(block.Deposits, &services.BlockDeposit{
+			Deposit: services.Deposit{
+				ChainID:             d.chain.ID,
+				UserID:              user.ID,
+				Amount:              d.amount,
+				TxHash:              d.txResult.TxHash,
+				BlockHeight:         height,
+				Sender:              user.WalletAddress,
+				GraduationThreshold: d.chain.GraduationThreshold,
+				Quote:               w.depositQuote(d.txResult),
+				BlockTime:           blockTime,
+			},
+			Refund:   d.chain.Status != models.ChainStatusVirtualActive,
+			RawEvent: string(raw),
+		})
+		chainsByID[d.chain.ID] = d.chain
+	}
+
+	return block, chainsByID, nil
+}
+
+// reportOutcomes logs what happened to each deposit of an applied height and
+// hands chains that reached their graduation threshold to the graduation worker
+func (w *Worker) reportOutcomes(height uint64, chains map[uuid.UUID]*models.Chain, outcomes []services.DepositOutcome) {
+	applied, refunded, queued := 0, 0, 0
+	notified := make(map[uuid.UUID]bool)
+	for _, outcome := range outcomes {
+		deposit := outcome.Deposit
+		chain := chains[deposit.ChainID]
+
+		switch {
+		case errors.Is(outcome.Err, services.ErrDepositAlreadyApplied):
+			log.Printf("[NewBlock Worker] Skipping deposit %s at height %d for chain %s: already applied",
+				deposit.TxHash, height, chain.ChainName)
+		case errors.Is(outcome.Err, services.ErrDepositRefunded):
+			refunded++
+			log.Printf("[NewBlock Worker] Refunding deposit %s of %d uCNPY to %s: chain %s is %s and its pool took none of it",
+				deposit.TxHash, deposit.Amount, deposit.Sender, chain.ChainName, chain.Status)
+		case outcome.Err != nil:
+			queued++
+			log.Printf("[NewBlock Worker] Queued deposit %s at height %d for retry: %v", deposit.TxHash, height, outcome.Err)
+		default:
+			applied++
+			result := outcome.Result
+			log.Printf("[NewBlock Worker] Successfully processed deposit for chain %s: User=%s, Tokens %.6f (Price: %.8f CNPY/token, CNPY Reserve: %.6f)",
+				chain.ChainName, deposit.UserID, result.AmountOut, result.Price, result.NewCNPYReserve)
+
+			// Hand the chain to the graduation worker once the threshold is crossed
+			if w.graduation != nil && !notified[chain.ID] && result.NewCNPYReserve.Cmp(big.NewFloat(chain.GraduationThreshold)) >= 0 {
+				notified[chain.ID] = true
+				w.graduation.Notify(chain.ID)
+			}
+		}
+	}
+
+	if len(outcomes) > 0 {
+		log.Printf("[NewBlock Worker] Height %d: %d deposit(s) applied, %d refunded, %d queued for retry",
+			height, applied, refunded, queued)
+	}
+}
+
+// processHeightBySend applies the sends at a height one transaction at a time,
+// then saves the checkpoint. Sends that fail are queued for retry; the height
+// fails only if one cannot be queued, so it is not checkpointed with a deposit
+// lost.
+func (w *Worker) processHeightBySend(ctx context.Context, height uint64, blockTime time.Time, sends []*lib.TxResult) error {
+	for i, txResult := range sends {
+		if err := w.processTransaction(ctx, txResult, i, len(sends), height, blockTime); err != nil {
+			return err
+		}
+	}
+
+	if err := w.checkpoints.Save(ctx, w.rootChainID, height); err != nil {
+		return fmt.Errorf("failed to save checkpoint at height %d: %w", height, err)
+	}
+	return nil
+}
+
+// recordPendingHeight records the sends at a height that is not final yet as
+// pending deposits, so users see them before tokens are credited
+func (w *Worker) recordPendingHeight(ctx context.Context, height uint64) error {
+	_, err := w.forEachSend(height, func(txResult *lib.TxResult, index, total int) error {
+		chain, err := w.chainRepo.GetByAddress(ctx, hex.EncodeToString(txResult.Recipient))
+		if err != nil || !w.servesChain(chain) {
+			return nil
+		}
+
+		amount, err := w.extractSendAmount(txResult)
+		if err != nil || amount == 0 {
+			return nil
+		}
+
+		deposit := &models.PendingDeposit{
+			RootChainID:     w.rootChainID,
+			ChainID:         chain.ID,
+			SenderAddress:   "0x" + hex.EncodeToString(txResult.Sender),
+			Amount:          amount,
+			TransactionHash: txResult.TxHash,
+			BlockHeight:     height,
+			FinalHeight:     height + w.confirmations,
+		}
+		if err := w.pending.Create(ctx, deposit); err != nil {
+			log.Printf("[NewBlock Worker] Failed to record pending deposit %s: %v", txResult.TxHash, err)
+			return nil
+		}
+		log.Printf("[NewBlock Worker] Pending deposit %s of %d uCNPY to chain %s, final at height %d",
+			txResult.TxHash, amount, chain.ChainName, deposit.FinalHeight)
+		return nil
+	})
+	return err
+}
+
+// forEachSend fetches every page of transactions at a height and calls fn for
+// each send, stopping at the first error fn returns. It returns the number of
+// transactions in the block.
+func (w *Worker) forEachSend(height uint64, fn func(txResult *lib.TxResult, index, total int) error) (int, error) {
+	count := 0
+	for pageNumber := 1; ; pageNumber++ {
+		pageParams := lib.PageParams{
+			PageNumber: pageNumber,
+			PerPage:    transactionsPerPage,
+		}
+
+		page, err := w.rpcClient.TransactionsByHeight(height, pageParams)
+		if err != nil {
+			return count, fmt.Errorf("failed to fetch transactions page %d at height %d: %w", pageNumber, height, err)
+		}
+		if page == nil || page.Results == nil {
+			break
+		}
+
 		// Type assert the Results to TxResults
 		txResults, ok := page.Results.(*lib.TxResults)
 		if !ok {
-			return fmt.Errorf("unexpected page results type at height %d", info.Height)
+			return count, fmt.Errorf("unexpected page results type at height %d", height)
+		}
+		if len(*txResults) == 0 {
+			break
 		}
 
-		if len(*txResults) > 0 {
-			log.Printf("[NewBlock Worker] Found %d transactions at height %d", len(*txResults), info.Height)
+		if pageNumber == 1 {
+			log.Printf("[NewBlock Worker] Found %d transactions in %d page(s) at height %d",
+				page.TotalCount, page.TotalPages, height)
+		}
 
-			for i, txResult := range *txResults {
-				// Only process send transactions
-				if txResult.MessageType != fsm.MessageSendName {
-					continue
+		for _, txResult := range *txResults {
+			// Only process send transactions
+			if txResult.MessageType == fsm.MessageSendName {
+				if err := fn(txResult, count, page.TotalCount); err != nil {
+					return count, err
 				}
-				w.processTransaction(context.Background(), txResult, i, len(*txResults), info.Height)
 			}
-		} else {
-			log.Printf("[NewBlock Worker] No transactions found at height %d", info.Height)
+			count++
+		}
+
+		if pageNumber >= page.TotalPages {
+			break
 		}
-	} else {
-		log.Printf("[NewBlock Worker] No transactions found at height %d", info.Height)
 	}
 
-	return nil
+	return count, nil
 }
 
-// processTransaction processes a single transaction from a block
-func (w *Worker) processTransaction(ctx context.Context, txResult *lib.TxResult, index int, total int, height uint64) {
+// processTransaction processes a single transaction from a block. A send to a
+// chain that cannot be applied is queued in the dead-letter queue; an error is
+// returned only when it cannot be queued either.
+func (w *Worker) processTransaction(ctx context.Context, txResult *lib.TxResult, index int, total int, height uint64, blockTime time.Time) error {
 	// Look up chain by recipient address (destination of send transaction)
 	recipientAddress := hex.EncodeToString(txResult.Recipient)
 	chain, err := w.chainRepo.GetByAddress(ctx, recipientAddress)
 	if err != nil {
-		// Chain not found or error - skip this transaction
-		log.Printf("[NewBlock Worker] Transaction %d/%d at height %d: No chain found for recipient address %s",
-			index+1, total, height, recipientAddress)
-		return
+		if isNotFound(err) {
+			// Not a deposit address - skip this transaction
+			log.Printf("[NewBlock Worker] Transaction %d/%d at height %d: No chain found for recipient address %s",
+				index+1, total, height, recipientAddress)
+			return nil
+		}
+		err = fmt.Errorf("failed to look up chain for recipient address %s: %w", recipientAddress, err)
+		log.Printf("[NewBlock Worker] Transaction %d/%d at height %d: %v", index+1, total, height, err)
+		return w.deadLetter(ctx, txResult, nil, height, err)
+	}
+	if !w.servesChain(chain) {
+		log.Printf("[NewBlock Worker] Transaction %d/%d at height %d: Chain %s for recipient address %s is on root chain %d",
+			index+1, total, height, chain.ChainName, recipientAddress, chain.RootChainID)
+		return nil
 	}
 
 	// Found a chain matching this transaction's recipient
@@ -148,19 +693,88 @@ func (w *Worker) processTransaction(ctx context.Context, txResult *lib.TxResult,
 		index+1, total, height, txResult.TxHash, txResult.MessageType,
 		txResult.Sender, chain.ChainName, chain.ID)
 
-	// Extract the send amount from the transaction
+	// Extract the send amount from the transaction. A malformed send will never
+	// succeed, so it is skipped rather than queued for retry.
 	amount, err := w.extractSendAmount(txResult)
 	if err != nil {
 		log.Printf("[NewBlock Worker] Failed to extract send amount: %v", err)
-		return
+		return nil
 	}
 
 	// Process the deposit to the virtual pool
-	err = w.processDeposit(ctx, chain, amount, txResult.Sender)
-	if err != nil {
+	if err := w.processDeposit(ctx, chain, txResult, amount, height, blockTime); err != nil {
 		log.Printf("[NewBlock Worker] Failed to process deposit: %v", err)
-		return
+		return w.deadLetter(ctx, txResult, chain, height, err)
+	}
+
+	return nil
+}
+
+// deadLetter queues a send that could not be applied so that it is retried
+// after its height has been checkpointed
+func (w *Worker) deadLetter(ctx context.Context, txResult *lib.TxResult, chain *models.Chain, height uint64, processErr error) error {
+	raw, err := json.Marshal(txResult)
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction %s for retry: %w", txResult.TxHash, err)
+	}
+
+	var chainID *uuid.UUID
+	if chain != nil {
+		chainID = &chain.ID
+	}
+	event := services.NewDepositFailedEvent(txResult.TxHash, chainID, height, string(raw), processErr)
+
+	if err := w.failedEvents.Record(ctx, event); err != nil {
+		return fmt.Errorf("failed to queue deposit %s for retry: %w", txResult.TxHash, err)
+	}
+	log.Printf("[NewBlock Worker] Queued deposit %s at height %d for retry", txResult.TxHash, height)
+
+	return nil
+}
+
+// Replay applies a deposit from the dead-letter queue again. A deposit that was
+// applied or refunded in the meantime is skipped, so replaying is always safe.
+func (w *Worker) Replay(ctx context.Context, event *models.FailedEvent) error {
+	if event.EventType != models.FailedEventTypeDeposit {
+		return fmt.Errorf("cannot replay %s event as a deposit", event.EventType)
+	}
+	if event.BlockHeight == nil {
+		return fmt.Errorf("deposit event %s has no block height", event.Reference)
+	}
+
+	txResult := new(lib.TxResult)
+	if err := json.Unmarshal([]byte(event.RawEvent), txResult); err != nil {
+		return fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	recipientAddress := hex.EncodeToString(txResult.Recipient)
+	chain, err := w.chainRepo.GetByAddress(ctx, recipientAddress)
+	if err != nil {
+		return fmt.Errorf("failed to look up chain for recipient address %s: %w", recipientAddress, err)
 	}
+	if !w.servesChain(chain) {
+		return fmt.Errorf("%w: chain %s is on root chain %d, not %d", ErrOtherRootChain, chain.ChainName, chain.RootChainID, w.rootChainID)
+	}
+
+	amount, err := w.extractSendAmount(txResult)
+	if err != nil {
+		return fmt.Errorf("failed to extract send amount: %w", err)
+	}
+
+	block, err := w.finalBlock(*event.BlockHeight)
+	if err != nil {
+		return err
+	}
+	if block == nil {
+		return fmt.Errorf("no quorum certificate at height %d", *event.BlockHeight)
+	}
+
+	return w.processDeposit(ctx, chain, txResult, amount, *event.BlockHeight, blockTime(block))
+}
+
+// isNotFound reports whether a repository error means the row does not exist
+func isNotFound(err error) bool {
+	return strings.Contains(err.Error(), "not found")
 }
 
 // extractSendAmount extracts the CNPY amount from a send transaction
@@ -190,205 +804,107 @@ func (w *Worker) extractSendAmount(txResult *lib.TxResult) (uint64, error) {
 	return sendMsg.Amount, nil
 }
 
-// processDeposit handles a CNPY deposit to a chain's virtual pool
-func (w *Worker) processDeposit(ctx context.Context, chain *models.Chain, amount uint64, sender []byte) error {
-	log.Printf("[NewBlock Worker] Processing deposit: Chain=%s, Amount=%d uCNPY, Sender=%x",
-		chain.ChainName, amount, sender)
-
-	// Get the virtual pool for this chain
-	pool, err := w.poolRepo.GetPoolByChainID(ctx, chain.ID)
+// processDeposit handles a CNPY deposit to a chain's virtual pool. Deposits are
+// keyed by transaction hash and height, so a block that is delivered again after
+// a reconnect does not mint tokens a second time.
+//
+// Deposits to chains that are not virtual_active are refunded to the sender, as
+// is any part of a deposit beyond the chain's graduation threshold. blockTime is
+// the time of the block at height, which a quote in the memo must not have
+// expired by.
+func (w *Worker) processDeposit(ctx context.Context, chain *models.Chain, txResult *lib.TxResult, amount uint64, height uint64, blockTime time.Time) error {
+	txHash := txResult.TxHash
+	log.Printf("[NewBlock Worker] Processing deposit: Chain=%s, Amount=%d uCNPY, Sender=%x, Hash=%s",
+		chain.ChainName, amount, txResult.Sender, txHash)
+
+	user, err := w.resolveUser(ctx, txResult.Sender)
 	if err != nil {
-		return fmt.Errorf("failed to get virtual pool: %w", err)
+		return err
 	}
 
-	// Convert amount from micro-CNPY (uint64) to CNPY (big.Float)
-	// 1 CNPY = 1,000,000 uCNPY
-	cnpyAmount := new(big.Float).SetUint64(amount)
-	cnpyAmount.Quo(cnpyAmount, big.NewFloat(1000000))
-
-	// Convert database pool to bonding curve pool
-	bcPool := bondingcurve.NewVirtualPool(
-		big.NewFloat(pool.CNPYReserve),
-		big.NewFloat(float64(pool.TokenReserve)),
-		big.NewFloat(float64(pool.TokenReserve)), // Using token reserve as total supply for now
-	)
-
-	// Create bonding curve instance with default config
-	curve := bondingcurve.NewBondingCurve(nil)
-
-	// Execute the buy (deposit CNPY, receive tokens)
-	result, err := curve.Buy(bcPool, cnpyAmount)
-	if err != nil {
-		return fmt.Errorf("bonding curve buy failed: %w", err)
+	deposit := &services.Deposit{
+		ChainID:             chain.ID,
+		UserID:              user.ID,
+		Amount:              amount,
+		TxHash:              txHash,
+		BlockHeight:         height,
+		Sender:              user.WalletAddress,
+		GraduationThreshold: chain.GraduationThreshold,
+		Quote:               w.depositQuote(txResult),
+		BlockTime:           blockTime,
 	}
 
-	log.Printf("[NewBlock Worker] Deposit result: TokensOut=%.6f, NewCNPYReserve=%.6f, NewTokenReserve=%.6f, Price=%.8f",
-		result.AmountOut, result.NewCNPYReserve, result.NewTokenReserve, result.Price)
-
-	// Update pool state in database
-	totalTransactions := pool.TotalTransactions + 1
-	update := &interfaces.PoolStateUpdate{
-		CNPYReserve:       result.NewCNPYReserve,
-		TokenReserve:      result.NewTokenReserve,
-		CurrentPriceCNPY:  result.Price,
-		TotalTransactions: &totalTransactions,
+	var result *bondingcurve.TradeResult
+	if chain.Status == models.ChainStatusVirtualActive {
+		result, err = w.deposits.ProcessDepositWithRetry(ctx, deposit)
+	} else {
+		err = w.deposits.RefundDepositWithRetry(ctx, deposit)
+		if err == nil {
+			err = services.ErrDepositRefunded
+		}
+	}
+	if errors.Is(err, services.ErrDepositAlreadyApplied) {
+		log.Printf("[NewBlock Worker] Skipping deposit %s at height %d for chain %s: already applied",
+			txHash, height, chain.ChainName)
+		return nil
+	}
+	if errors.Is(err, services.ErrDepositRefunded) {
+		log.Printf("[NewBlock Worker] Refunding deposit %s of %d uCNPY to %s: chain %s is %s and its pool took none of it",
+			txHash, amount, user.WalletAddress, chain.ChainName, chain.Status)
+		return nil
 	}
-
-	err = w.poolRepo.UpdatePoolState(ctx, chain.ID, update)
 	if err != nil {
-		return fmt.Errorf("failed to update pool state: %w", err)
+		return fmt.Errorf("failed to apply deposit %s: %w", txHash, err)
 	}
 
-	log.Printf("[NewBlock Worker] Successfully processed deposit for chain %s: CNPY %.6f → Tokens %.6f (Price: %.8f CNPY/token)",
-		chain.ChainName, cnpyAmount, result.AmountOut, result.Price)
+	log.Printf("[NewBlock Worker] Successfully processed deposit for chain %s: User=%s, Tokens %.6f (Price: %.8f CNPY/token, CNPY Reserve: %.6f)",
+		chain.ChainName, user.ID, result.AmountOut, result.Price, result.NewCNPYReserve)
 
-	// Record transaction in virtual_pool_transactions table
-	user, err := w.recordTransaction(ctx, pool, chain, sender, cnpyAmount, result)
-	if err != nil {
-		log.Printf("[NewBlock Worker] Warning: Failed to record transaction: %v", err)
-		// Don't fail the entire deposit if transaction recording fails
+	// Hand the chain to the graduation worker once the threshold is crossed
+	if w.graduation != nil && result.NewCNPYReserve.Cmp(big.NewFloat(chain.GraduationThreshold)) >= 0 {
+		w.graduation.Notify(chain.ID)
 	}
 
-	// Update or create user_virtual_positions for the sender
-	if user != nil {
-		err = w.updateUserPosition(ctx, user, pool, chain, cnpyAmount, result)
-		if err != nil {
-			log.Printf("[NewBlock Worker] Warning: Failed to update user position: %v", err)
-			// Don't fail the entire deposit if position update fails
-		}
+	return nil
+}
+
+// depositQuote returns the trade quote in a send's memo, if it carries one we signed
+func (w *Worker) depositQuote(txResult *lib.TxResult) *services.Quote {
+	transaction := txResult.GetTransaction()
+	if w.quotes == nil || transaction.GetMemo() == "" {
+		return nil
 	}
 
-	return nil
+	quote, err := w.quotes.Parse(transaction.GetMemo())
+	if err != nil {
+		// Most memos are not quotes
+		return nil
+	}
+	return quote
 }
 
-// recordTransaction creates a record in virtual_pool_transactions table and returns the user
-func (w *Worker) recordTransaction(ctx context.Context, pool *models.VirtualPool, chain *models.Chain, sender []byte, cnpyAmount *big.Float, result *bondingcurve.TradeResult) (*models.User, error) {
+// resolveUser looks up the user for a sender address, creating one if the
+// address has not been seen before
+func (w *Worker) resolveUser(ctx context.Context, sender []byte) (*models.User, error) {
 	// Convert sender address to hex string with 0x prefix (database stores addresses with 0x prefix)
 	senderAddress := "0x" + hex.EncodeToString(sender)
 
-	// Look up or create user by wallet address
 	user, err := w.userRepo.GetByWalletAddress(ctx, senderAddress)
-	if err != nil {
-		// If user doesn't exist, create a new one
-		newUser := &models.User{
-			WalletAddress: senderAddress,
-			Username:      nil, // Will be set when user completes profile
-			IsVerified:    false,
-		}
-		user, err = w.userRepo.Create(ctx, newUser)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create user for address %s: %w", senderAddress, err)
-		}
-		log.Printf("[NewBlock Worker] Created new user for wallet address: %s", senderAddress)
-	}
-
-	// Convert big.Float amounts to float64
-	cnpyFloat, _ := cnpyAmount.Float64()
-	tokensOutFloat, _ := result.AmountOut.Float64()
-	priceFloat, _ := result.Price.Float64()
-	newCNPYReserveFloat, _ := result.NewCNPYReserve.Float64()
-	newTokenReserveFloat, _ := result.NewTokenReserve.Float64()
-
-	// Calculate trading fee (assumed to be included in the bonding curve result)
-	// For now, we'll set it to 0 or calculate based on config
-	tradingFeeCNPY := 0.0
-
-	// Create transaction record
-	transaction := &models.VirtualPoolTransaction{
-		VirtualPoolID:         pool.ID,
-		ChainID:               chain.ID,
-		UserID:                user.ID,
-		TransactionType:       "buy",
-		CNPYAmount:            cnpyFloat,
-		TokenAmount:           int64(tokensOutFloat),
-		PricePerTokenCNPY:     priceFloat,
-		TradingFeeCNPY:        tradingFeeCNPY,
-		SlippagePercent:       0, // Could be calculated if needed
-		TransactionHash:       nil,
-		BlockHeight:           nil,
-		GasUsed:               nil,
-		PoolCNPYReserveAfter:  newCNPYReserveFloat,
-		PoolTokenReserveAfter: int64(newTokenReserveFloat),
-		MarketCapAfterUSD:     0, // Could be calculated: price * total_supply
-	}
-
-	err = w.poolRepo.CreateTransaction(ctx, transaction)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create transaction record: %w", err)
+	if err == nil {
+		return user, nil
 	}
 
-	log.Printf("[NewBlock Worker] Recorded transaction: User=%s, Type=buy, CNPY=%.6f, Tokens=%d",
-		user.ID, cnpyFloat, transaction.TokenAmount)
-
-	return user, nil
-}
-
-// updateUserPosition updates or creates a user's virtual position
-func (w *Worker) updateUserPosition(ctx context.Context, user *models.User, pool *models.VirtualPool, chain *models.Chain, cnpyAmount *big.Float, result *bondingcurve.TradeResult) error {
-	// Get existing position or create new one
-	position, err := w.poolRepo.GetUserPosition(ctx, user.ID, chain.ID)
-	if err != nil {
-		return fmt.Errorf("failed to get user position: %w", err)
-	}
-
-	// Convert amounts
-	cnpyFloat, _ := cnpyAmount.Float64()
-	tokensOutFloat, _ := result.AmountOut.Float64()
-	priceFloat, _ := result.Price.Float64()
-
-	now := time.Now()
-
-	if position == nil {
-		// Create new position
-		position = &models.UserVirtualLPPosition{
-			UserID:                user.ID,
-			ChainID:               chain.ID,
-			VirtualPoolID:         pool.ID,
-			TokenBalance:          int64(tokensOutFloat),
-			TotalCNPYInvested:     cnpyFloat,
-			TotalCNPYWithdrawn:    0,
-			AverageEntryPriceCNPY: priceFloat,
-			UnrealizedPnlCNPY:     0, // Will be calculated based on current price vs entry price
-			RealizedPnlCNPY:       0,
-			TotalReturnPercent:    0,
-			IsActive:              true,
-			FirstPurchaseAt:       &now,
-			LastActivityAt:        &now,
-		}
-	} else {
-		// Update existing position
-		oldBalance := float64(position.TokenBalance)
-		newBalance := oldBalance + tokensOutFloat
-
-		// Calculate new average entry price (weighted average)
-		oldInvestment := position.TotalCNPYInvested
-		newInvestment := oldInvestment + cnpyFloat
-		position.AverageEntryPriceCNPY = newInvestment / newBalance
-
-		// Update balances
-		position.TokenBalance = int64(newBalance)
-		position.TotalCNPYInvested = newInvestment
-		position.LastActivityAt = &now
-
-		// Calculate unrealized PnL
-		// PnL = (current_price - avg_entry_price) * token_balance
-		currentValueCNPY := priceFloat * newBalance
-		position.UnrealizedPnlCNPY = currentValueCNPY - newInvestment
-
-		// Calculate total return percentage
-		if newInvestment > 0 {
-			position.TotalReturnPercent = (position.UnrealizedPnlCNPY / newInvestment) * 100
-		}
+	// If user doesn't exist, create a new one
+	newUser := &models.User{
+		WalletAddress: senderAddress,
+		Username:      nil, // Will be set when user completes profile
+		IsVerified:    false,
 	}
-
-	// Upsert the position
-	err = w.poolRepo.UpsertUserPosition(ctx, position)
+	user, err = w.userRepo.Create(ctx, newUser)
 	if err != nil {
-		return fmt.Errorf("failed to upsert user position: %w", err)
+		return nil, fmt.Errorf("failed to create user for address %s: %w", senderAddress, err)
 	}
+	log.Printf("[NewBlock Worker] Created new user for wallet address: %s", senderAddress)
 
-	log.Printf("[NewBlock Worker] Updated user position: User=%s, Chain=%s, Balance=%d tokens, Invested=%.6f CNPY, PnL=%.6f CNPY",
-		user.ID, chain.ChainName, position.TokenBalance, position.TotalCNPYInvested, position.UnrealizedPnlCNPY)
-
-	return nil
+	return user, nil
 }
@@ -3,6 +3,8 @@ package newblock
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"testing"
@@ -12,9 +14,13 @@ import (
 	"github.com/canopy-network/canopy/lib"
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/internal/services"
+	"github.com/enielson/launchpad/internal/testutil/mocks"
+	"github.com/enielson/launchpad/pkg/bondingcurve"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
@@ -23,6 +29,26 @@ type MockRPCClient struct {
 	mock.Mock
 }
 
+func (m *MockRPCClient) Height() (*uint64, lib.ErrorI) {
+	args := m.Called()
+	if args.Get(1) != nil {
+		return nil, args.Get(1).(lib.ErrorI)
+	}
+	height := args.Get(0).(uint64)
+	return &height, nil
+}
+
+func (m *MockRPCClient) CertByHeight(height uint64) (*lib.QuorumCertificate, lib.ErrorI) {
+	args := m.Called(height)
+	if args.Get(1) != nil {
+		return nil, args.Get(1).(lib.ErrorI)
+	}
+	if args.Get(0) == nil {
+		return nil, nil
+	}
+	return args.Get(0).(*lib.QuorumCertificate), nil
+}
+
 func (m *MockRPCClient) TransactionsByHeight(height uint64, page lib.PageParams) (*lib.Page, lib.ErrorI) {
 	args := m.Called(height, page)
 	if args.Get(0) == nil {
@@ -37,6 +63,45 @@ func (m *MockRPCClient) TransactionsByHeight(height uint64, page lib.PageParams)
 	return args.Get(0).(*lib.Page), args.Get(1).(lib.ErrorI)
 }
 
+// fakeRPCClient serves fixed blocks of transactions, split into pages the way
+// the canopy RPC does
+type fakeRPCClient struct {
+	blocks   map[uint64][]*lib.TxResult
+	requests []lib.PageParams
+}
+
+func (f *fakeRPCClient) Height() (*uint64, lib.ErrorI) {
+	var height uint64
+	for h := range f.blocks {
+		if h > height {
+			height = h
+		}
+	}
+	return &height, nil
+}
+
+func (f *fakeRPCClient) CertByHeight(height uint64) (*lib.QuorumCertificate, lib.ErrorI) {
+	return finalCert(), nil
+}
+
+func (f *fakeRPCClient) TransactionsByHeight(height uint64, page lib.PageParams) (*lib.Page, lib.ErrorI) {
+	f.requests = append(f.requests, page)
+
+	txs := f.blocks[height]
+	totalPages := (len(txs) + page.PerPage - 1) / page.PerPage
+	start := min((page.PageNumber-1)*page.PerPage, len(txs))
+	end := min(start+page.PerPage, len(txs))
+	results := lib.TxResults(txs[start:end])
+
+	return &lib.Page{
+		PageParams: page,
+		Results:    &results,
+		Count:      len(results),
+		TotalPages: totalPages,
+		TotalCount: len(txs),
+	}, nil
+}
+
 // MockChainRepository mocks the ChainRepository interface
 type MockChainRepository struct {
 	mock.Mock
@@ -50,6 +115,14 @@ func (m *MockChainRepository) GetByAddress(ctx context.Context, address string)
 	return args.Get(0).(*models.Chain), args.Error(1)
 }
 
+func (m *MockChainRepository) GetByAddresses(ctx context.Context, addresses []string) (map[string]*models.Chain, error) {
+	args := m.Called(ctx, addresses)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]*models.Chain), args.Error(1)
+}
+
 func (m *MockChainRepository) Create(ctx context.Context, chain *models.Chain) (*models.Chain, error) {
 	args := m.Called(ctx, chain)
 	if args.Get(0) == nil {
@@ -87,6 +160,11 @@ func (m *MockChainRepository) UpdateDescription(ctx context.Context, id uuid.UUI
 	return args.Error(0)
 }
 
+func (m *MockChainRepository) UpdateAllocation(ctx context.Context, id uuid.UUID, allocation models.TokenAllocation) error {
+	args := m.Called(ctx, id, allocation)
+	return args.Error(0)
+}
+
 func (m *MockChainRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -215,6 +293,22 @@ func (m *MockChainRepository) CreateChainKey(ctx context.Context, key *models.Ch
 	return args.Get(0).(*models.ChainKey), args.Error(1)
 }
 
+func (m *MockChainRepository) GetVestingSchedulesByChainID(ctx context.Context, chainID uuid.UUID) ([]models.ChainVestingSchedule, error) {
+	args := m.Called(ctx, chainID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ChainVestingSchedule), args.Error(1)
+}
+
+func (m *MockChainRepository) ReplaceVestingSchedules(ctx context.Context, chainID uuid.UUID, schedules []models.ChainVestingSchedule) ([]models.ChainVestingSchedule, error) {
+	args := m.Called(ctx, chainID, schedules)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ChainVestingSchedule), args.Error(1)
+}
+
 func (m *MockChainRepository) GetChainKeyByChainID(ctx context.Context, chainID uuid.UUID, purpose string) (*models.ChainKey, error) {
 	args := m.Called(ctx, chainID, purpose)
 	if args.Get(0) == nil {
@@ -223,89 +317,114 @@ func (m *MockChainRepository) GetChainKeyByChainID(ctx context.Context, chainID
 	return args.Get(0).(*models.ChainKey), args.Error(1)
 }
 
-// MockVirtualPoolRepository mocks the VirtualPoolRepository interface
-type MockVirtualPoolRepository struct {
+// MockCheckpointRepository mocks the RootChainCheckpointRepository interface
+type MockCheckpointRepository struct {
 	mock.Mock
 }
 
-func (m *MockVirtualPoolRepository) GetPoolByChainID(ctx context.Context, chainID uuid.UUID) (*models.VirtualPool, error) {
-	args := m.Called(ctx, chainID)
+func (m *MockCheckpointRepository) Get(ctx context.Context, rootChainID uint64) (*models.RootChainCheckpoint, error) {
+	args := m.Called(ctx, rootChainID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.VirtualPool), args.Error(1)
-}
-
-func (m *MockVirtualPoolRepository) UpdatePoolState(ctx context.Context, chainID uuid.UUID, update *interfaces.PoolStateUpdate) error {
-	args := m.Called(ctx, chainID, update)
-	return args.Error(0)
+	return args.Get(0).(*models.RootChainCheckpoint), args.Error(1)
 }
 
-func (m *MockVirtualPoolRepository) CreateTransaction(ctx context.Context, transaction *models.VirtualPoolTransaction) error {
-	args := m.Called(ctx, transaction)
+func (m *MockCheckpointRepository) Save(ctx context.Context, rootChainID uint64, height uint64) error {
+	args := m.Called(ctx, rootChainID, height)
 	return args.Error(0)
 }
 
-func (m *MockVirtualPoolRepository) GetTransactionsByPoolID(ctx context.Context, poolID uuid.UUID, pagination interfaces.Pagination) ([]models.VirtualPoolTransaction, int, error) {
-	args := m.Called(ctx, poolID, pagination)
-	return args.Get(0).([]models.VirtualPoolTransaction), args.Int(1), args.Error(2)
+// MockPendingDepositRepository mocks the PendingDepositRepository interface
+type MockPendingDepositRepository struct {
+	mock.Mock
 }
 
-func (m *MockVirtualPoolRepository) GetTransactionsByUserID(ctx context.Context, userID uuid.UUID, pagination interfaces.Pagination) ([]models.VirtualPoolTransaction, int, error) {
-	args := m.Called(ctx, userID, pagination)
-	return args.Get(0).([]models.VirtualPoolTransaction), args.Int(1), args.Error(2)
+func (m *MockPendingDepositRepository) Create(ctx context.Context, deposit *models.PendingDeposit) error {
+	args := m.Called(ctx, deposit)
+	return args.Error(0)
 }
 
-func (m *MockVirtualPoolRepository) GetUserPosition(ctx context.Context, userID, chainID uuid.UUID) (*models.UserVirtualLPPosition, error) {
-	args := m.Called(ctx, userID, chainID)
+func (m *MockPendingDepositRepository) ListByChainID(ctx context.Context, chainID uuid.UUID, filters interfaces.PendingDepositFilters) ([]models.PendingDeposit, error) {
+	args := m.Called(ctx, chainID, filters)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.UserVirtualLPPosition), args.Error(1)
+	return args.Get(0).([]models.PendingDeposit), args.Error(1)
 }
 
-func (m *MockVirtualPoolRepository) UpsertUserPosition(ctx context.Context, position *models.UserVirtualLPPosition) error {
-	args := m.Called(ctx, position)
+func (m *MockPendingDepositRepository) DeleteThroughHeight(ctx context.Context, rootChainID uint64, height uint64) error {
+	args := m.Called(ctx, rootChainID, height)
 	return args.Error(0)
 }
 
-func (m *MockVirtualPoolRepository) GetPositionsByChainID(ctx context.Context, chainID uuid.UUID, pagination interfaces.Pagination) ([]models.UserVirtualLPPosition, int, error) {
-	args := m.Called(ctx, chainID, pagination)
-	return args.Get(0).([]models.UserVirtualLPPosition), args.Int(1), args.Error(2)
+// MockDepositProcessor mocks the DepositProcessor interface
+type MockDepositProcessor struct {
+	mock.Mock
 }
 
-func (m *MockVirtualPoolRepository) Create(ctx context.Context, pool *models.VirtualPool) (*models.VirtualPool, error) {
-	args := m.Called(ctx, pool)
+func (m *MockDepositProcessor) ProcessDepositWithRetry(ctx context.Context, deposit *services.Deposit) (*bondingcurve.TradeResult, error) {
+	args := m.Called(ctx, deposit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.VirtualPool), args.Error(1)
+	return args.Get(0).(*bondingcurve.TradeResult), args.Error(1)
 }
 
-func (m *MockVirtualPoolRepository) GetAllPools(ctx context.Context, pagination interfaces.Pagination) ([]models.VirtualPool, int, error) {
-	args := m.Called(ctx, pagination)
-	return args.Get(0).([]models.VirtualPool), args.Int(1), args.Error(2)
+func (m *MockDepositProcessor) RefundDepositWithRetry(ctx context.Context, deposit *services.Deposit) error {
+	args := m.Called(ctx, deposit)
+	return args.Error(0)
 }
 
-func (m *MockVirtualPoolRepository) GetTransactionsByChainID(ctx context.Context, chainID uuid.UUID, filters interfaces.TransactionFilters, pagination interfaces.Pagination) ([]models.VirtualPoolTransaction, int, error) {
-	args := m.Called(ctx, chainID, filters, pagination)
-	return args.Get(0).([]models.VirtualPoolTransaction), args.Int(1), args.Error(2)
+// fakeBlockApplier applies blocks in memory, buying with result for every
+// deposit unless errs holds an error for its hash. Like the block processor it
+// commits the checkpoint with each block, through checkpoints when set.
+type fakeBlockApplier struct {
+	checkpoints *MockCheckpointRepository
+	result      *bondingcurve.TradeResult
+	errs        map[string]error
+	err         error // fails the whole block
+	blocks      []*services.DepositBlock
 }
 
-func (m *MockVirtualPoolRepository) GetPositionsWithUsersByChainID(ctx context.Context, chainID uuid.UUID) ([]interfaces.UserPositionWithAddress, error) {
-	args := m.Called(ctx, chainID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
+func (f *fakeBlockApplier) ApplyBlockWithRetry(ctx context.Context, block *services.DepositBlock) ([]services.DepositOutcome, error) {
+	f.blocks = append(f.blocks, block)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.checkpoints != nil {
+		if err := f.checkpoints.Save(ctx, block.RootChainID, block.Height); err != nil {
+			return nil, err
+		}
 	}
-	return args.Get(0).([]interfaces.UserPositionWithAddress), args.Error(1)
+
+	outcomes := make([]services.DepositOutcome, 0, len(block.Deposits))
+	for _, deposit := range block.Deposits {
+		outcome := services.DepositOutcome{Deposit: deposit, Err: f.errs[deposit.TxHash]}
+		if outcome.Err == nil {
+			outcome.Result = f.result
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes, nil
 }
 
-func (m *MockVirtualPoolRepository) GetPriceHistory(ctx context.Context, chainID uuid.UUID, startTime, endTime time.Time) ([]interfaces.PriceHistoryCandle, error) {
-	args := m.Called(ctx, chainID, startTime, endTime)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
+// deposits returns every deposit applied so far, in order
+func (f *fakeBlockApplier) deposits() []*services.BlockDeposit {
+	var deposits []*services.BlockDeposit
+	for _, block := range f.blocks {
+		deposits = append(deposits, block.Deposits...)
 	}
-	return args.Get(0).([]interfaces.PriceHistoryCandle), args.Error(1)
+	return deposits
+}
+
+// MockGraduationNotifier records the chains it is notified about
+type MockGraduationNotifier struct {
+	notified []uuid.UUID
+}
+
+func (m *MockGraduationNotifier) Notify(chainID uuid.UUID) {
+	m.notified = append(m.notified, chainID)
 }
 
 // MockUserRepository mocks the UserRepository interface
@@ -337,6 +456,14 @@ func (m *MockUserRepository) GetByWalletAddress(ctx context.Context, walletAddre
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetOrCreateByWalletAddresses(ctx context.Context, walletAddresses []string) ([]models.User, error) {
+	args := m.Called(ctx, walletAddresses)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
 func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
@@ -453,28 +580,35 @@ func (m *MockUserRepository) UpdateProfile(ctx context.Context, userID uuid.UUID
 
 // Helper functions to create test fixtures
 
-// setupStandardUserMocks sets up the standard user repository mocks for transaction processing
-func setupStandardUserMocks(userRepo *MockUserRepository, poolRepo *MockVirtualPoolRepository, senderAddress []byte, chainID uuid.UUID) {
-	senderAddressHex := "0x" + hex.EncodeToString(senderAddress) // Include 0x prefix to match worker.go line 267
+// setupStandardUserMocks sets up the user lookup for the sender of a deposit and returns the user
+func setupStandardUserMocks(userRepo *MockUserRepository, senderAddress []byte) *models.User {
+	senderAddressHex := "0x" + hex.EncodeToString(senderAddress) // Wallet addresses are stored with a 0x prefix
 	testUser := &models.User{
 		ID:            uuid.New(),
 		WalletAddress: senderAddressHex,
 		IsVerified:    false,
 	}
 	userRepo.On("GetByWalletAddress", mock.Anything, senderAddressHex).Return(testUser, nil)
+	userRepo.On("GetOrCreateByWalletAddresses", mock.Anything, []string{senderAddressHex}).Return([]models.User{*testUser}, nil).Maybe()
+	return testUser
+}
 
-	// Mock CreateTransaction to be called
-	poolRepo.On("CreateTransaction", mock.Anything, mock.MatchedBy(func(tx *models.VirtualPoolTransaction) bool {
-		return tx.TransactionType == "buy" && tx.ChainID == chainID
-	})).Return(nil)
-
-	// Mock GetUserPosition (returning nil indicates no existing position)
-	poolRepo.On("GetUserPosition", mock.Anything, mock.Anything, chainID).Return(nil, nil)
+// setupChainMocks sets up the lookup of a chain by its deposit address, alone
+// and as part of a block
+func setupChainMocks(chainRepo *MockChainRepository, recipientAddress []byte, chain *models.Chain) {
+	address := hex.EncodeToString(recipientAddress)
+	chainRepo.On("GetByAddress", mock.Anything, address).Return(chain, nil).Maybe()
+	chainRepo.On("GetByAddresses", mock.Anything, []string{address}).Return(map[string]*models.Chain{address: chain}, nil).Maybe()
+}
 
-	// Mock UpsertUserPosition to be called
-	poolRepo.On("UpsertUserPosition", mock.Anything, mock.MatchedBy(func(pos *models.UserVirtualLPPosition) bool {
-		return pos.UserID == testUser.ID && pos.ChainID == chainID
-	})).Return(nil)
+// matchDeposit matches a deposit for the given chain, amount, hash and height
+func matchDeposit(chainID uuid.UUID, amount uint64, txHash string, height uint64) interface{} {
+	return mock.MatchedBy(func(deposit *services.Deposit) bool {
+		return deposit.ChainID == chainID &&
+			deposit.Amount == amount &&
+			deposit.TxHash == txHash &&
+			deposit.BlockHeight == height
+	})
 }
 
 // buildTxResultWithValidSend creates a TxResult with a valid send transaction
@@ -552,26 +686,18 @@ func buildChain(chainID uuid.UUID, chainName string, creatorID uuid.UUID) *model
 		IsGraduated:         false,
 		CreatedBy:           creatorID,
 		ValidatorMinStake:   1000.0,
+		RootChainID:         1,
 	}
 }
 
-// buildVirtualPool creates a test virtual pool with specified reserves
-func buildVirtualPool(poolID uuid.UUID, chainID uuid.UUID, cnpyReserve float64, tokenReserve int64, totalTransactions int) *models.VirtualPool {
-	return &models.VirtualPool{
-		ID:                    poolID,
-		ChainID:               chainID,
-		CNPYReserve:           cnpyReserve,
-		TokenReserve:          tokenReserve,
-		CurrentPriceCNPY:      cnpyReserve / float64(tokenReserve),
-		MarketCapUSD:          0,
-		TotalVolumeCNPY:       0,
-		TotalTransactions:     totalTransactions,
-		UniqueTraders:         0,
-		IsActive:              true,
-		Price24hChangePercent: 0,
-		Volume24hCNPY:         0,
-		High24hCNPY:           0,
-		Low24hCNPY:            0,
+// buildTradeResult creates a buy result leaving the pool with the given CNPY reserve
+func buildTradeResult(tokensOut float64, newCNPYReserve float64) *bondingcurve.TradeResult {
+	return &bondingcurve.TradeResult{
+		AmountOut:       big.NewFloat(tokensOut),
+		NewCNPYReserve:  big.NewFloat(newCNPYReserve),
+		NewTokenReserve: big.NewFloat(800000000 - tokensOut),
+		Price:           big.NewFloat(newCNPYReserve / 800000000),
+		PriceImpact:     big.NewFloat(0),
 	}
 }
 
@@ -579,7 +705,6 @@ func TestWorker_processTransaction(t *testing.T) {
 	// Common test fixtures
 	chainID := uuid.New()
 	creatorID := uuid.New()
-	poolID := uuid.New()
 	recipientAddress := []byte{0x01, 0x02, 0x03, 0x04}
 	recipientAddressHex := hex.EncodeToString(recipientAddress)
 	senderAddress := []byte{0x05, 0x06, 0x07, 0x08}
@@ -590,7 +715,9 @@ func TestWorker_processTransaction(t *testing.T) {
 		index        int
 		total        int
 		height       uint64
-		setupMocks   func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository)
+		setupMocks   func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository)
+		deadLettered bool     // The send is queued for retry
+		recordErr    error    // Error queueing the send for retry
 		expectedLogs []string // Expected log patterns (for manual verification)
 		activeForm   string
 	}{
@@ -600,25 +727,14 @@ func TestWorker_processTransaction(t *testing.T) {
 			index:    0,
 			total:    1,
 			height:   1000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
 				chain := buildChain(chainID, "TestChain", creatorID)
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
+				setupStandardUserMocks(userRepo, senderAddress)
 
-				pool := buildVirtualPool(poolID, chainID, 30.0, 800000000, 0)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-
-				// Expect UpdatePoolState to be called with increased reserves
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.MatchedBy(func(update *interfaces.PoolStateUpdate) bool {
-					// Verify that reserves have increased
-					return update.CNPYReserve != nil &&
-						update.TokenReserve != nil &&
-						update.CurrentPriceCNPY != nil &&
-						update.TotalTransactions != nil &&
-						*update.TotalTransactions == 1
-				})).Return(nil)
-
-				// Setup user mocks for transaction recording
-				setupStandardUserMocks(userRepo, poolRepo, senderAddress, chainID)
+				// The deposit carries the send's hash and the block height
+				deposits.On("ProcessDepositWithRetry", mock.Anything, matchDeposit(chainID, 1000000, "0xabc123", 1000)).
+					Return(buildTradeResult(26000000, 31.0), nil)
 			},
 			expectedLogs: []string{
 				"Transaction 1/1 at height 1000",
@@ -633,7 +749,7 @@ func TestWorker_processTransaction(t *testing.T) {
 			index:    0,
 			total:    1,
 			height:   1000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
 				// Chain not found error
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(nil, fmt.Errorf("chain not found"))
 			},
@@ -648,7 +764,7 @@ func TestWorker_processTransaction(t *testing.T) {
 			index:    1,
 			total:    3,
 			height:   2000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
 				chain := buildChain(chainID, "TestChain", creatorID)
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
 			},
@@ -664,7 +780,7 @@ func TestWorker_processTransaction(t *testing.T) {
 			index:    0,
 			total:    1,
 			height:   3000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
 				chain := buildChain(chainID, "TestChain", creatorID)
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
 			},
@@ -680,40 +796,40 @@ func TestWorker_processTransaction(t *testing.T) {
 			index:    0,
 			total:    1,
 			height:   4000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
 				chain := buildChain(chainID, "TestChain", creatorID)
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
+				setupStandardUserMocks(userRepo, senderAddress)
 
-				// Pool not found
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(nil, fmt.Errorf("pool not found"))
+				deposits.On("ProcessDepositWithRetry", mock.Anything, matchDeposit(chainID, 5000000, "0xabc123", 4000)).
+					Return(nil, services.ErrPoolNotFound)
 			},
+			deadLettered: true,
 			expectedLogs: []string{
 				"Failed to process deposit",
-				"failed to get virtual pool",
+				"virtual pool not found",
 			},
 			activeForm: "Processing transaction when pool not found",
 		},
 		{
-			name:     "pool state update fails",
+			name:     "block replayed after reconnect",
 			txResult: buildTxResultWithValidSend(recipientAddress, senderAddress, 2000000), // 2 CNPY
 			index:    0,
 			total:    1,
 			height:   5000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
 				chain := buildChain(chainID, "TestChain", creatorID)
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
+				setupStandardUserMocks(userRepo, senderAddress)
 
-				pool := buildVirtualPool(poolID, chainID, 30.0, 800000000, 0)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-
-				// UpdatePoolState fails
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.Anything).Return(fmt.Errorf("database connection lost"))
+				// The processor reports the send as already applied and writes nothing
+				deposits.On("ProcessDepositWithRetry", mock.Anything, matchDeposit(chainID, 2000000, "0xabc123", 5000)).
+					Return(nil, services.ErrDepositAlreadyApplied)
 			},
 			expectedLogs: []string{
-				"Failed to process deposit",
-				"failed to update pool state",
+				"Skipping deposit 0xabc123 at height 5000",
 			},
-			activeForm: "Processing transaction when pool state update fails",
+			activeForm: "Processing transaction from a replayed block",
 		},
 		{
 			name:     "large amount transaction (100 CNPY)",
@@ -721,24 +837,13 @@ func TestWorker_processTransaction(t *testing.T) {
 			index:    5,
 			total:    10,
 			height:   6000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
 				chain := buildChain(chainID, "LargeChain", creatorID)
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
+				setupStandardUserMocks(userRepo, senderAddress)
 
-				// Pool with larger reserves to handle large transaction
-				pool := buildVirtualPool(poolID, chainID, 1000.0, 8000000000, 50)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.MatchedBy(func(update *interfaces.PoolStateUpdate) bool {
-					// For large deposits, CNPY reserve should increase significantly
-					return update.CNPYReserve != nil &&
-						update.CNPYReserve.Cmp(big.NewFloat(1000.0)) > 0 && // Greater than original reserve
-						update.TotalTransactions != nil &&
-						*update.TotalTransactions == 51
-				})).Return(nil)
-
-				// Setup user mocks for transaction recording
-				setupStandardUserMocks(userRepo, poolRepo, senderAddress, chainID)
+				deposits.On("ProcessDepositWithRetry", mock.Anything, matchDeposit(chainID, 100000000, "0xabc123", 6000)).
+					Return(buildTradeResult(711000000, 1100.0), nil)
 			},
 			expectedLogs: []string{
 				"Transaction 6/10 at height 6000",
@@ -747,26 +852,25 @@ func TestWorker_processTransaction(t *testing.T) {
 			activeForm: "Processing large amount transaction (100 CNPY)",
 		},
 		{
-			name:     "zero amount transaction - bonding curve rejects",
+			name:     "zero amount transaction - processor rejects",
 			txResult: buildTxResultWithValidSend(recipientAddress, senderAddress, 0), // 0 CNPY
 			index:    0,
 			total:    1,
 			height:   7000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
 				chain := buildChain(chainID, "ZeroChain", creatorID)
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
+				setupStandardUserMocks(userRepo, senderAddress)
 
-				pool := buildVirtualPool(poolID, chainID, 30.0, 800000000, 0)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-
-				// Note: UpdatePoolState should NOT be called because bonding curve rejects zero amounts
+				deposits.On("ProcessDepositWithRetry", mock.Anything, matchDeposit(chainID, 0, "0xabc123", 7000)).
+					Return(nil, services.ErrZeroAmount)
 			},
+			deadLettered: true,
 			expectedLogs: []string{
 				"Processing deposit: Chain=ZeroChain, Amount=0 uCNPY",
 				"Failed to process deposit",
-				"bonding curve buy failed",
 			},
-			activeForm: "Processing zero amount transaction (bonding curve rejects)",
+			activeForm: "Processing zero amount transaction (processor rejects)",
 		},
 		{
 			name:     "context already cancelled before processing",
@@ -774,142 +878,84 @@ func TestWorker_processTransaction(t *testing.T) {
 			index:    0,
 			total:    1,
 			height:   8000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
-				// GetByAddress should be called but return context cancelled
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
+				// A failed lookup is not the same as an unknown address: the send is kept
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(nil, context.Canceled)
 			},
+			deadLettered: true,
 			expectedLogs: []string{
-				"No chain found for recipient address",
+				"failed to look up chain for recipient address",
+				"Queued deposit 0xabc123 at height 8000 for retry",
 			},
 			activeForm: "Processing with cancelled context",
 		},
 		{
-			name:     "multiple transactions same block - first of three",
+			name:     "new sender is created before the deposit is applied",
 			txResult: buildTxResultWithValidSend(recipientAddress, senderAddress, 500000), // 0.5 CNPY
 			index:    0,
 			total:    3,
 			height:   9000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
 				chain := buildChain(chainID, "MultiTxChain", creatorID)
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
 
-				pool := buildVirtualPool(poolID, chainID, 30.0, 800000000, 100)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
+				senderAddressHex := "0x" + hex.EncodeToString(senderAddress)
+				newUser := &models.User{ID: uuid.New(), WalletAddress: senderAddressHex}
+				userRepo.On("GetByWalletAddress", mock.Anything, senderAddressHex).Return(nil, fmt.Errorf("user not found"))
+				userRepo.On("Create", mock.Anything, mock.MatchedBy(func(user *models.User) bool {
+					return user.WalletAddress == senderAddressHex
+				})).Return(newUser, nil)
 
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.MatchedBy(func(update *interfaces.PoolStateUpdate) bool {
-					return update.TotalTransactions != nil && *update.TotalTransactions == 101
-				})).Return(nil)
-
-				// Setup user mocks for transaction recording
-				setupStandardUserMocks(userRepo, poolRepo, senderAddress, chainID)
+				deposits.On("ProcessDepositWithRetry", mock.Anything, mock.MatchedBy(func(deposit *services.Deposit) bool {
+					return deposit.UserID == newUser.ID && deposit.BlockHeight == 9000
+				})).Return(buildTradeResult(13000000, 30.5), nil)
 			},
 			expectedLogs: []string{
+				"Created new user for wallet address",
 				"Transaction 1/3 at height 9000",
 			},
-			activeForm: "Processing first of multiple transactions in block",
+			activeForm: "Processing transaction from a new sender",
 		},
 		{
-			name:     "multiple transactions same block - second of three",
+			name:     "user creation fails",
 			txResult: buildTxResultWithValidSend(recipientAddress, senderAddress, 750000), // 0.75 CNPY
 			index:    1,
 			total:    3,
 			height:   9000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
 				chain := buildChain(chainID, "MultiTxChain", creatorID)
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
 
-				// Pool state after first transaction
-				pool := buildVirtualPool(poolID, chainID, 30.5, 799000000, 101)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.MatchedBy(func(update *interfaces.PoolStateUpdate) bool {
-					return update.TotalTransactions != nil && *update.TotalTransactions == 102
-				})).Return(nil)
-
-				// Setup user mocks for transaction recording
-				setupStandardUserMocks(userRepo, poolRepo, senderAddress, chainID)
+				// The deposit must not be applied without a user to credit
+				userRepo.On("GetByWalletAddress", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("user not found"))
+				userRepo.On("Create", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("database error"))
 			},
+			deadLettered: true,
 			expectedLogs: []string{
-				"Transaction 2/3 at height 9000",
-			},
-			activeForm: "Processing second of multiple transactions in block",
-		},
-		{
-			name:     "multiple transactions same block - last of three",
-			txResult: buildTxResultWithValidSend(recipientAddress, senderAddress, 250000), // 0.25 CNPY
-			index:    2,
-			total:    3,
-			height:   9000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
-				chain := buildChain(chainID, "MultiTxChain", creatorID)
-				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
-
-				// Pool state after first two transactions
-				pool := buildVirtualPool(poolID, chainID, 31.25, 798000000, 102)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.MatchedBy(func(update *interfaces.PoolStateUpdate) bool {
-					return update.TotalTransactions != nil && *update.TotalTransactions == 103
-				})).Return(nil)
-
-				// Setup user mocks for transaction recording
-				setupStandardUserMocks(userRepo, poolRepo, senderAddress, chainID)
-			},
-			expectedLogs: []string{
-				"Transaction 3/3 at height 9000",
-			},
-			activeForm: "Processing last of multiple transactions in block",
-		},
-		{
-			name:     "transaction with very small reserve pool (near graduation)",
-			txResult: buildTxResultWithValidSend(recipientAddress, senderAddress, 10000000), // 10 CNPY
-			index:    0,
-			total:    1,
-			height:   10000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
-				chain := buildChain(chainID, "NearGraduationChain", creatorID)
-				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
-
-				// Pool with small token reserve (most tokens sold)
-				pool := buildVirtualPool(poolID, chainID, 95000.0, 5000000, 1000) // Very low token reserve
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.Anything).Return(nil)
-
-				// Setup user mocks for transaction recording
-				setupStandardUserMocks(userRepo, poolRepo, senderAddress, chainID)
-			},
-			expectedLogs: []string{
-				"Successfully processed deposit",
+				"Failed to process deposit",
+				"failed to create user for address",
 			},
-			activeForm: "Processing transaction with small reserve pool",
+			activeForm: "Processing transaction when user creation fails",
 		},
 		{
-			name:     "transaction processing with fresh pool (first transaction)",
-			txResult: buildTxResultWithValidSend(recipientAddress, senderAddress, 1000000), // 1 CNPY
+			name:     "failed deposit that cannot be queued fails the height",
+			txResult: buildTxResultWithValidSend(recipientAddress, senderAddress, 1000000),
 			index:    0,
 			total:    1,
-			height:   11000,
-			setupMocks: func(chainRepo *MockChainRepository, poolRepo *MockVirtualPoolRepository, userRepo *MockUserRepository) {
-				chain := buildChain(chainID, "FreshChain", creatorID)
+			height:   9500,
+			setupMocks: func(chainRepo *MockChainRepository, deposits *MockDepositProcessor, userRepo *MockUserRepository) {
+				chain := buildChain(chainID, "TestChain", creatorID)
 				chainRepo.On("GetByAddress", mock.Anything, recipientAddressHex).Return(chain, nil)
+				setupStandardUserMocks(userRepo, senderAddress)
 
-				// Fresh pool with initial reserves, zero transactions
-				pool := buildVirtualPool(poolID, chainID, 30.0, 800000000, 0)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.MatchedBy(func(update *interfaces.PoolStateUpdate) bool {
-					// First transaction should increment from 0 to 1
-					return update.TotalTransactions != nil && *update.TotalTransactions == 1
-				})).Return(nil)
-
-				// Setup user mocks for transaction recording
-				setupStandardUserMocks(userRepo, poolRepo, senderAddress, chainID)
+				deposits.On("ProcessDepositWithRetry", mock.Anything, mock.Anything).Return(nil, services.ErrMaxRetries)
 			},
+			deadLettered: true,
+			recordErr:    errors.New("connection refused"),
 			expectedLogs: []string{
-				"Successfully processed deposit",
+				"Failed to process deposit",
 			},
-			activeForm: "Processing first transaction on fresh pool",
+			activeForm: "Processing transaction when the dead-letter queue is unavailable",
 		},
 	}
 
@@ -917,18 +963,29 @@ func TestWorker_processTransaction(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create mock repositories
 			chainRepo := new(MockChainRepository)
-			poolRepo := new(MockVirtualPoolRepository)
+			deposits := new(MockDepositProcessor)
 			userRepo := new(MockUserRepository)
+			failedEvents := new(mocks.MockFailedEventRepository)
 
 			// Setup mocks for this test case
-			tt.setupMocks(chainRepo, poolRepo, userRepo)
+			tt.setupMocks(chainRepo, deposits, userRepo)
+			if tt.deadLettered {
+				failedEvents.On("Record", mock.Anything, mock.MatchedBy(func(event *models.FailedEvent) bool {
+					return event.EventType == models.FailedEventTypeDeposit &&
+						event.Reference == tt.txResult.TxHash &&
+						event.BlockHeight != nil && *event.BlockHeight == tt.height &&
+						event.RawEvent != "" && event.LastError != ""
+				})).Return(tt.recordErr)
+			}
 
 			// Create worker with mocks
 			worker := &Worker{
-				chainRepo: chainRepo,
-				poolRepo:  poolRepo,
-				userRepo:  userRepo,
-				logger:    NewLogger(),
+				chainRepo:    chainRepo,
+				deposits:     deposits,
+				userRepo:     userRepo,
+				failedEvents: failedEvents,
+				logger:       NewLogger(),
+				rootChainID:  1,
 			}
 
 			// Create context (cancelled for context cancellation test)
@@ -940,11 +997,21 @@ func TestWorker_processTransaction(t *testing.T) {
 			}
 
 			// Execute the function under test
-			worker.processTransaction(ctx, tt.txResult, tt.index, tt.total, tt.height)
+			err := worker.processTransaction(ctx, tt.txResult, tt.index, tt.total, tt.height, testBlockTime)
+			if tt.recordErr != nil {
+				assert.ErrorContains(t, err, "failed to queue deposit 0xabc123 for retry")
+			} else {
+				assert.NoError(t, err)
+			}
 
 			// Verify all expected mock calls were made
 			chainRepo.AssertExpectations(t)
-			poolRepo.AssertExpectations(t)
+			deposits.AssertExpectations(t)
+			userRepo.AssertExpectations(t)
+			failedEvents.AssertExpectations(t)
+			if !tt.deadLettered {
+				failedEvents.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+			}
 		})
 	}
 }
@@ -1024,120 +1091,95 @@ func TestWorker_extractSendAmount(t *testing.T) {
 func TestWorker_processDeposit(t *testing.T) {
 	chainID := uuid.New()
 	creatorID := uuid.New()
-	poolID := uuid.New()
 	senderAddress := []byte{0x01, 0x02, 0x03, 0x04}
+	txHash := "0xdeadbeef"
+	height := uint64(1000)
 
 	tests := []struct {
-		name          string
-		chain         *models.Chain
-		amount        uint64
-		sender        []byte
-		setupMocks    func(poolRepo *MockVirtualPoolRepository)
-		expectError   bool
-		errorContains string
-		activeForm    string
+		name           string
+		chain          *models.Chain
+		amount         uint64
+		result         *bondingcurve.TradeResult
+		processErr     error
+		expectError    bool
+		errorContains  string
+		expectNotified bool
+		activeForm     string
 	}{
 		{
-			name:   "successful deposit with standard amount",
-			chain:  buildChain(chainID, "TestChain", creatorID),
-			amount: 1000000, // 1 CNPY
-			sender: senderAddress,
-			setupMocks: func(poolRepo *MockVirtualPoolRepository) {
-				pool := buildVirtualPool(poolID, chainID, 30.0, 800000000, 0)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.Anything).Return(nil)
-			},
-			expectError: false,
-			activeForm:  "Processing successful deposit",
+			name:       "successful deposit with standard amount",
+			chain:      buildChain(chainID, "TestChain", creatorID),
+			amount:     1000000, // 1 CNPY
+			result:     buildTradeResult(26000000, 31.0),
+			activeForm: "Processing successful deposit",
 		},
 		{
-			name:   "pool not found for chain",
-			chain:  buildChain(chainID, "TestChain", creatorID),
-			amount: 1000000,
-			sender: senderAddress,
-			setupMocks: func(poolRepo *MockVirtualPoolRepository) {
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(nil, fmt.Errorf("pool not found"))
-			},
-			expectError:   true,
-			errorContains: "failed to get virtual pool",
-			activeForm:    "Processing deposit when pool not found",
+			name:           "deposit crossing the graduation threshold notifies",
+			chain:          buildChain(chainID, "GraduatingChain", creatorID),
+			amount:         10000000, // 10 CNPY
+			result:         buildTradeResult(1000, 100005.0),
+			expectNotified: true,
+			activeForm:     "Processing deposit that reaches graduation threshold",
 		},
 		{
-			name:   "pool state update fails",
-			chain:  buildChain(chainID, "TestChain", creatorID),
-			amount: 1000000,
-			sender: senderAddress,
-			setupMocks: func(poolRepo *MockVirtualPoolRepository) {
-				pool := buildVirtualPool(poolID, chainID, 30.0, 800000000, 0)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.Anything).Return(fmt.Errorf("database error"))
-			},
-			expectError:   true,
-			errorContains: "failed to update pool state",
-			activeForm:    "Processing deposit when update fails",
+			name:       "already applied deposit is skipped",
+			chain:      buildChain(chainID, "TestChain", creatorID),
+			amount:     1000000,
+			processErr: services.ErrDepositAlreadyApplied,
+			activeForm: "Processing replayed deposit",
 		},
 		{
-			name:   "large amount deposit (100 CNPY)",
-			chain:  buildChain(chainID, "LargeChain", creatorID),
-			amount: 100000000, // 100 CNPY
-			sender: senderAddress,
-			setupMocks: func(poolRepo *MockVirtualPoolRepository) {
-				pool := buildVirtualPool(poolID, chainID, 1000.0, 8000000000, 50)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.Anything).Return(nil)
-			},
-			expectError: false,
-			activeForm:  "Processing large amount deposit",
+			name:       "deposit refunded at the graduation cap",
+			chain:      buildChain(chainID, "TestChain", creatorID),
+			amount:     1000000,
+			processErr: services.ErrDepositRefunded,
+			activeForm: "Processing deposit to a pool at its threshold",
 		},
 		{
-			name:   "zero amount deposit - bonding curve rejects",
-			chain:  buildChain(chainID, "ZeroChain", creatorID),
-			amount: 0,
-			sender: senderAddress,
-			setupMocks: func(poolRepo *MockVirtualPoolRepository) {
-				pool := buildVirtualPool(poolID, chainID, 30.0, 800000000, 0)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-				// UpdatePoolState should NOT be called - bonding curve rejects zero amounts
-			},
+			name:          "inactive pool",
+			chain:         buildChain(chainID, "GraduatedChain", creatorID),
+			amount:        1000000,
+			processErr:    services.ErrPoolInactive,
 			expectError:   true,
-			errorContains: "bonding curve buy failed",
-			activeForm:    "Processing zero amount deposit (bonding curve rejects)",
+			errorContains: "no longer trading",
+			activeForm:    "Processing deposit to a graduated chain",
 		},
 		{
-			name:   "deposit to pool with many transactions",
-			chain:  buildChain(chainID, "PopularChain", creatorID),
-			amount: 5000000, // 5 CNPY
-			sender: senderAddress,
-			setupMocks: func(poolRepo *MockVirtualPoolRepository) {
-				pool := buildVirtualPool(poolID, chainID, 5000.0, 400000000, 9999)
-				poolRepo.On("GetPoolByChainID", mock.Anything, chainID).Return(pool, nil)
-				poolRepo.On("UpdatePoolState", mock.Anything, chainID, mock.MatchedBy(func(update *interfaces.PoolStateUpdate) bool {
-					return update.TotalTransactions != nil && *update.TotalTransactions == 10000
-				})).Return(nil)
-			},
-			expectError: false,
-			activeForm:  "Processing deposit to popular pool",
+			name:          "retries exhausted",
+			chain:         buildChain(chainID, "TestChain", creatorID),
+			amount:        1000000,
+			processErr:    services.ErrMaxRetries,
+			expectError:   true,
+			errorContains: "failed to apply deposit 0xdeadbeef",
+			activeForm:    "Processing deposit when retries are exhausted",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			poolRepo := new(MockVirtualPoolRepository)
+			deposits := new(MockDepositProcessor)
 			userRepo := new(MockUserRepository)
-			tt.setupMocks(poolRepo)
-
-			// Setup user mocks for successful cases
-			if !tt.expectError {
-				setupStandardUserMocks(userRepo, poolRepo, tt.sender, tt.chain.ID)
-			}
+			notifier := &MockGraduationNotifier{}
+
+			user := setupStandardUserMocks(userRepo, senderAddress)
+			deposits.On("ProcessDepositWithRetry", mock.Anything, mock.MatchedBy(func(deposit *services.Deposit) bool {
+				return deposit.ChainID == chainID &&
+					deposit.UserID == user.ID &&
+					deposit.Amount == tt.amount &&
+					deposit.TxHash == txHash &&
+					deposit.BlockHeight == height &&
+					deposit.Sender == user.WalletAddress &&
+					deposit.GraduationThreshold == tt.chain.GraduationThreshold
+			})).Return(tt.result, tt.processErr)
 
 			worker := &Worker{
-				poolRepo: poolRepo,
-				userRepo: userRepo,
-				logger:   NewLogger(),
+				deposits:   deposits,
+				userRepo:   userRepo,
+				logger:     NewLogger(),
+				graduation: notifier,
 			}
 
-			err := worker.processDeposit(context.Background(), tt.chain, tt.amount, tt.sender)
+			err := worker.processDeposit(context.Background(), tt.chain, &lib.TxResult{Sender: senderAddress, TxHash: txHash}, tt.amount, height, testBlockTime)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -1148,7 +1190,838 @@ func TestWorker_processDeposit(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
-			poolRepo.AssertExpectations(t)
+			if tt.expectNotified {
+				assert.Equal(t, []uuid.UUID{chainID}, notifier.notified)
+			} else {
+				assert.Empty(t, notifier.notified)
+			}
+
+			deposits.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWorker_depositQuote(t *testing.T) {
+	signer := services.NewQuoteSigner("quote-secret", time.Minute)
+	quote := &services.Quote{
+		ChainID:      uuid.New(),
+		Side:         models.VirtualTransactionTypeBuy,
+		AmountIn:     1000000,
+		MinAmountOut: 25000000,
+		ExpiresAt:    1700000060,
+	}
+	send := func(memo string) *lib.TxResult {
+		return &lib.TxResult{Transaction: &lib.Transaction{Memo: memo}}
+	}
+
+	t.Run("quote in the memo is parsed", func(t *testing.T) {
+		worker := &Worker{quotes: signer}
+		assert.Equal(t, quote, worker.depositQuote(send(signer.Sign(quote))))
+	})
+
+	t.Run("other memos are ignored", func(t *testing.T) {
+		worker := &Worker{quotes: signer}
+		assert.Nil(t, worker.depositQuote(send("gm")))
+		assert.Nil(t, worker.depositQuote(send(services.NewQuoteSigner("other-secret", time.Minute).Sign(quote))))
+	})
+
+	t.Run("memos are ignored without a signer", func(t *testing.T) {
+		assert.Nil(t, (&Worker{}).depositQuote(send(signer.Sign(quote))))
+	})
+}
+
+func TestWorker_processDepositRefund(t *testing.T) {
+	chainID := uuid.New()
+	senderAddress := []byte{0x01, 0x02, 0x03, 0x04}
+
+	tests := []struct {
+		name        string
+		refundErr   error
+		expectError bool
+	}{
+		{name: "deposit is refunded"},
+		{name: "already refunded deposit is skipped", refundErr: services.ErrDepositAlreadyApplied},
+		{name: "refund failure", refundErr: services.ErrMaxRetries, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deposits := new(MockDepositProcessor)
+			userRepo := new(MockUserRepository)
+			notifier := &MockGraduationNotifier{}
+
+			chain := buildChain(chainID, "GraduatedChain", uuid.New())
+			chain.Status = models.ChainStatusGraduated
+
+			user := setupStandardUserMocks(userRepo, senderAddress)
+			deposits.On("RefundDepositWithRetry", mock.Anything, mock.MatchedBy(func(deposit *services.Deposit) bool {
+				return deposit.ChainID == chainID && deposit.Amount == 1000000 && deposit.Sender == user.WalletAddress
+			})).Return(tt.refundErr)
+
+			worker := &Worker{
+				deposits:   deposits,
+				userRepo:   userRepo,
+				logger:     NewLogger(),
+				graduation: notifier,
+			}
+
+			err := worker.processDeposit(context.Background(), chain, &lib.TxResult{Sender: senderAddress, TxHash: "0xdeadbeef"}, 1000000, 1000, testBlockTime)
+			if tt.expectError {
+				assert.ErrorContains(t, err, "failed to apply deposit 0xdeadbeef")
+			} else {
+				assert.NoError(t, err)
+			}
+
+			deposits.AssertExpectations(t)
+			deposits.AssertNotCalled(t, "ProcessDepositWithRetry", mock.Anything, mock.Anything)
+			assert.Empty(t, notifier.notified)
+		})
+	}
+}
+
+func TestWorker_processHeight(t *testing.T) {
+	chainID := uuid.New()
+	recipientAddress := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	senderAddress := []byte{0x01, 0x02, 0x03, 0x04}
+	height := uint64(2000)
+
+	// buildBlock creates a block of sends to the chain with unique hashes, with
+	// a non-send transaction every tenth position
+	buildBlock := func(size int) []*lib.TxResult {
+		txs := make([]*lib.TxResult, 0, size)
+		for i := 0; i < size; i++ {
+			tx := buildTxResultWithValidSend(recipientAddress, senderAddress, 1000000)
+			tx.TxHash = fmt.Sprintf("0x%04d", i)
+			tx.Index = uint64(i)
+			if i%10 == 9 {
+				tx.MessageType = fsm.MessageStakeName
+			}
+			txs = append(txs, tx)
+		}
+		return txs
+	}
+
+	tests := []struct {
+		name          string
+		blockSize     int
+		expectedPages int
+		activeForm    string
+	}{
+		{
+			name:          "block filling several pages",
+			blockSize:     350,
+			expectedPages: 4,
+			activeForm:    "Processing block with a partial last page",
+		},
+		{
+			name:          "block ending on a page boundary",
+			blockSize:     300,
+			expectedPages: 3,
+			activeForm:    "Processing block with full pages",
+		},
+		{
+			name:          "block smaller than a page",
+			blockSize:     40,
+			expectedPages: 1,
+			activeForm:    "Processing block with a single page",
+		},
+		{
+			name:          "empty block",
+			blockSize:     0,
+			expectedPages: 1,
+			activeForm:    "Processing empty block",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpc := &fakeRPCClient{blocks: map[uint64][]*lib.TxResult{height: buildBlock(tt.blockSize)}}
+			chainRepo := new(MockChainRepository)
+			userRepo := new(MockUserRepository)
+			blocks := &fakeBlockApplier{result: buildTradeResult(1000, 31.0)}
+
+			setupChainMocks(chainRepo, recipientAddress, buildChain(chainID, "BusyChain", uuid.New()))
+			user := setupStandardUserMocks(userRepo, senderAddress)
+
+			worker := &Worker{
+				rpcClient:   rpc,
+				chainRepo:   chainRepo,
+				blocks:      blocks,
+				userRepo:    userRepo,
+				logger:      NewLogger(),
+				graduation:  &MockGraduationNotifier{},
+				rootChainID: 1,
+			}
+
+			require.NoError(t, worker.processHeight(context.Background(), height, testBlockTime))
+
+			// Every page is requested once, in order
+			require.Len(t, rpc.requests, tt.expectedPages)
+			for i, page := range rpc.requests {
+				assert.Equal(t, i+1, page.PageNumber)
+				assert.Equal(t, transactionsPerPage, page.PerPage)
+			}
+
+			// The whole height is applied as one block, even when it is empty
+			require.Len(t, blocks.blocks, 1)
+			assert.Equal(t, height, blocks.blocks[0].Height)
+			assert.Equal(t, uint64(1), blocks.blocks[0].RootChainID)
+
+			// Every send in the block is in it exactly once, in block order
+			var hashes []string
+			for _, deposit := range blocks.deposits() {
+				assert.Equal(t, height, deposit.BlockHeight)
+				assert.Equal(t, chainID, deposit.ChainID)
+				assert.Equal(t, user.ID, deposit.UserID)
+				assert.Equal(t, user.WalletAddress, deposit.Sender)
+				assert.False(t, deposit.Refund)
+				assert.NotEmpty(t, deposit.RawEvent)
+				hashes = append(hashes, deposit.TxHash)
+			}
+			var expected []string
+			for i := 0; i < tt.blockSize; i++ {
+				if i%10 != 9 {
+					expected = append(expected, fmt.Sprintf("0x%04d", i))
+				}
+			}
+			assert.Equal(t, expected, hashes)
+
+			// Chains and users are looked up once for the whole block
+			if tt.blockSize > 0 {
+				chainRepo.AssertNumberOfCalls(t, "GetByAddresses", 1)
+				userRepo.AssertNumberOfCalls(t, "GetOrCreateByWalletAddresses", 1)
+			}
+			chainRepo.AssertNotCalled(t, "GetByAddress", mock.Anything, mock.Anything)
+			userRepo.AssertNotCalled(t, "GetByWalletAddress", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestWorker_buildBlock(t *testing.T) {
+	activeID := uuid.New()
+	graduatedID := uuid.New()
+	activeAddress := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	graduatedAddress := []byte{0xee, 0xff, 0x00, 0x11}
+	strangerAddress := []byte{0x99, 0x99, 0x99, 0x99}
+	senderAddress := []byte{0x01, 0x02, 0x03, 0x04}
+	otherSender := []byte{0x05, 0x06, 0x07, 0x08}
+	height := uint64(2050)
+
+	active := buildChain(activeID, "ActiveChain", uuid.New())
+	graduated := buildChain(graduatedID, "GraduatedChain", uuid.New())
+	graduated.Status = models.ChainStatusGraduated
+
+	send := func(recipient, sender []byte, hash string) *lib.TxResult {
+		tx := buildTxResultWithValidSend(recipient, sender, 1000000)
+		tx.TxHash = hash
+		return tx
+	}
+	malformed := buildTxResultWithNilMessage(activeAddress, senderAddress)
+	malformed.TxHash = "0x05"
+	sends := []*lib.TxResult{
+		send(activeAddress, senderAddress, "0x01"),
+		send(strangerAddress, senderAddress, "0x02"),
+		send(graduatedAddress, otherSender, "0x03"),
+		send(activeAddress, otherSender, "0x04"),
+		malformed,
+	}
+
+	newWorker := func() (*Worker, *MockChainRepository, *MockUserRepository) {
+		chainRepo := new(MockChainRepository)
+		userRepo := new(MockUserRepository)
+		return &Worker{chainRepo: chainRepo, userRepo: userRepo, logger: NewLogger(), rootChainID: 1}, chainRepo, userRepo
+	}
+	recipients := []string{hex.EncodeToString(activeAddress), hex.EncodeToString(strangerAddress), hex.EncodeToString(graduatedAddress)}
+	senders := []string{"0x" + hex.EncodeToString(senderAddress), "0x" + hex.EncodeToString(otherSender)}
+
+	t.Run("deposits resolve chains and users in one lookup each", func(t *testing.T) {
+		worker, chainRepo, userRepo := newWorker()
+		chainRepo.On("GetByAddresses", mock.Anything, recipients).Return(map[string]*models.Chain{
+			hex.EncodeToString(activeAddress):    active,
+			hex.EncodeToString(graduatedAddress): graduated,
+		}, nil).Once()
+		first := models.User{ID: uuid.New(), WalletAddress: senders[0]}
+		second := models.User{ID: uuid.New(), WalletAddress: senders[1]}
+		userRepo.On("GetOrCreateByWalletAddresses", mock.Anything, senders).Return([]models.User{second, first}, nil).Once()
+
+		block, chains, err := worker.buildBlock(context.Background(), height, testBlockTime, sends)
+		require.NoError(t, err)
+		assert.Equal(t, height, block.Height)
+		require.Len(t, block.Deposits, 3)
+
+		assert.Equal(t, "0x01", block.Deposits[0].TxHash)
+		assert.Equal(t, activeID, block.Deposits[0].ChainID)
+		assert.Equal(t, first.ID, block.Deposits[0].UserID)
+		assert.Equal(t, uint64(1000000), block.Deposits[0].Amount)
+		assert.Equal(t, active.GraduationThreshold, block.Deposits[0].GraduationThreshold)
+		assert.True(t, testBlockTime.Equal(block.Deposits[0].BlockTime))
+		assert.False(t, block.Deposits[0].Refund)
+
+		// Deposits to a chain that no longer trades are refunded
+		assert.Equal(t, "0x03", block.Deposits[1].TxHash)
+		assert.Equal(t, second.ID, block.Deposits[1].UserID)
+		assert.True(t, block.Deposits[1].Refund)
+
+		assert.Equal(t, "0x04", block.Deposits[2].TxHash)
+		assert.Equal(t, second.WalletAddress, block.Deposits[2].Sender)
+
+		// The raw transaction is kept for the dead-letter queue
+		decoded := new(lib.TxResult)
+		require.NoError(t, json.Unmarshal([]byte(block.Deposits[0].RawEvent), decoded))
+		assert.Equal(t, "0x01", decoded.TxHash)
+
+		assert.Equal(t, map[uuid.UUID]*models.Chain{activeID: active, graduatedID: graduated}, chains)
+	})
+
+	t.Run("chain lookup failure fails the height", func(t *testing.T) {
+		worker, chainRepo, _ := newWorker()
+		chainRepo.On("GetByAddresses", mock.Anything, recipients).Return(nil, errors.New("connection refused"))
+
+		_, _, err := worker.buildBlock(context.Background(), height, testBlockTime, sends)
+		assert.ErrorContains(t, err, "failed to look up chains at height 2050")
+	})
+
+	t.Run("user lookup failure fails the height", func(t *testing.T) {
+		worker, chainRepo, userRepo := newWorker()
+		chainRepo.On("GetByAddresses", mock.Anything, recipients).Return(map[string]*models.Chain{
+			hex.EncodeToString(activeAddress): active,
+		}, nil)
+		userRepo.On("GetOrCreateByWalletAddresses", mock.Anything, senders).Return(nil, errors.New("connection refused"))
+
+		_, _, err := worker.buildBlock(context.Background(), height, testBlockTime, sends)
+		assert.ErrorContains(t, err, "failed to resolve users at height 2050")
+	})
+
+	t.Run("deposits to chains on another root chain are left out", func(t *testing.T) {
+		worker, chainRepo, userRepo := newWorker()
+		elsewhere := buildChain(graduatedID, "ElsewhereChain", uuid.New())
+		elsewhere.RootChainID = 2
+		chainRepo.On("GetByAddresses", mock.Anything, recipients).Return(map[string]*models.Chain{
+			hex.EncodeToString(activeAddress):    active,
+			hex.EncodeToString(graduatedAddress): elsewhere,
+		}, nil).Once()
+		userRepo.On("GetOrCreateByWalletAddresses", mock.Anything, senders).Return([]models.User{
+			{ID: uuid.New(), WalletAddress: senders[0]},
+			{ID: uuid.New(), WalletAddress: senders[1]},
+		}, nil).Once()
+
+		block, chains, err := worker.buildBlock(context.Background(), height, testBlockTime, sends)
+		require.NoError(t, err)
+		require.Len(t, block.Deposits, 2)
+		assert.Equal(t, "0x01", block.Deposits[0].TxHash)
+		assert.Equal(t, "0x04", block.Deposits[1].TxHash)
+		assert.Equal(t, map[uuid.UUID]*models.Chain{activeID: active}, chains)
+	})
+
+	t.Run("block without deposits skips the user lookup", func(t *testing.T) {
+		worker, chainRepo, userRepo := newWorker()
+		chainRepo.On("GetByAddresses", mock.Anything, recipients).Return(map[string]*models.Chain{}, nil)
+
+		block, _, err := worker.buildBlock(context.Background(), height, testBlockTime, sends)
+		require.NoError(t, err)
+		assert.Empty(t, block.Deposits)
+		userRepo.AssertNotCalled(t, "GetOrCreateByWalletAddresses", mock.Anything, mock.Anything)
+	})
+}
+
+func TestWorker_reportOutcomes(t *testing.T) {
+	chainID := uuid.New()
+	chain := buildChain(chainID, "BusyChain", uuid.New())
+	chains := map[uuid.UUID]*models.Chain{chainID: chain}
+	deposit := func(hash string) *services.BlockDeposit {
+		return &services.BlockDeposit{Deposit: services.Deposit{ChainID: chainID, TxHash: hash, Amount: 1000000}}
+	}
+
+	graduation := &MockGraduationNotifier{}
+	worker := &Worker{logger: NewLogger(), graduation: graduation}
+	worker.reportOutcomes(2000, chains, []services.DepositOutcome{
+		{Deposit: deposit("0x01"), Result: buildTradeResult(1000, 99999)},
+		{Deposit: deposit("0x02"), Err: services.ErrDepositAlreadyApplied},
+		{Deposit: deposit("0x03"), Result: buildTradeResult(1000, 100000)},
+		{Deposit: deposit("0x04"), Result: buildTradeResult(1000, 100000)},
+		{Deposit: deposit("0x05"), Err: services.ErrDepositRefunded},
+		{Deposit: deposit("0x06"), Err: services.ErrPoolInactive},
+	})
+
+	// A chain crossing its threshold is handed over once per height
+	assert.Equal(t, []uuid.UUID{chainID}, graduation.notified)
+}
+
+func TestWorker_processHeightFallback(t *testing.T) {
+	const rootChainID = 1
+	chainID := uuid.New()
+	recipientAddress := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	senderAddress := []byte{0x01, 0x02, 0x03, 0x04}
+	height := uint64(2100)
+
+	tests := []struct {
+		name        string
+		recordErr   error
+		expectError bool
+	}{
+		{name: "failed block is applied one send at a time and failed sends are queued"},
+		{name: "queue failure fails the height", recordErr: errors.New("connection refused"), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txs := make([]*lib.TxResult, 0, 3)
+			for i := 0; i < 3; i++ {
+				tx := buildTxResultWithValidSend(recipientAddress, senderAddress, 1000000)
+				tx.TxHash = fmt.Sprintf("0x%04d", i)
+				txs = append(txs, tx)
+			}
+			rpc := &fakeRPCClient{blocks: map[uint64][]*lib.TxResult{height: txs}}
+			chainRepo := new(MockChainRepository)
+			deposits := new(MockDepositProcessor)
+			userRepo := new(MockUserRepository)
+			checkpoints := new(MockCheckpointRepository)
+			failedEvents := new(mocks.MockFailedEventRepository)
+			blocks := &fakeBlockApplier{err: errors.New("invalid input syntax for type numeric")}
+
+			setupChainMocks(chainRepo, recipientAddress, buildChain(chainID, "BusyChain", uuid.New()))
+			setupStandardUserMocks(userRepo, senderAddress)
+			// The first deposit fails, the rest succeed
+			deposits.On("ProcessDepositWithRetry", mock.Anything, mock.MatchedBy(func(deposit *services.Deposit) bool {
+				return deposit.TxHash == "0x0000"
+			})).Return(nil, services.ErrMaxRetries)
+			deposits.On("ProcessDepositWithRetry", mock.Anything, mock.Anything).
+				Return(buildTradeResult(1000, 31.0), nil)
+			failedEvents.On("Record", mock.Anything, mock.MatchedBy(func(event *models.FailedEvent) bool {
+				return event.Reference == "0x0000" && *event.ChainID == chainID && *event.BlockHeight == height
+			})).Return(tt.recordErr)
+			checkpoints.On("Save", mock.Anything, uint64(rootChainID), height).Return(nil)
+
+			worker := &Worker{
+				rpcClient:    rpc,
+				chainRepo:    chainRepo,
+				deposits:     deposits,
+				blocks:       blocks,
+				userRepo:     userRepo,
+				checkpoints:  checkpoints,
+				failedEvents: failedEvents,
+				logger:       NewLogger(),
+				graduation:   &MockGraduationNotifier{},
+				rootChainID:  rootChainID,
+			}
+
+			err := worker.processHeight(context.Background(), height, testBlockTime)
+			require.Len(t, blocks.blocks, 1)
+			failedEvents.AssertExpectations(t)
+			if tt.expectError {
+				// The height is not finished, so it is not checkpointed and is
+				// processed again
+				assert.ErrorContains(t, err, "failed to queue deposit 0x0000 for retry")
+				deposits.AssertNumberOfCalls(t, "ProcessDepositWithRetry", 1)
+				checkpoints.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			deposits.AssertNumberOfCalls(t, "ProcessDepositWithRetry", 3)
+			checkpoints.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWorker_Replay(t *testing.T) {
+	chainID := uuid.New()
+	recipientAddress := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	senderAddress := []byte{0x01, 0x02, 0x03, 0x04}
+	height := uint64(2200)
+
+	raw, err := json.Marshal(buildTxResultWithValidSend(recipientAddress, senderAddress, 2500000))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		event          *models.FailedEvent
+		cert           *lib.QuorumCertificate
+		depositErr     error
+		otherRootChain bool
+		expectApplied  bool
+		expectedError  string
+	}{
+		{
+			name:          "deposit is decoded and applied at its height",
+			event:         &models.FailedEvent{EventType: models.FailedEventTypeDeposit, Reference: "0xabc123", BlockHeight: &height, RawEvent: string(raw)},
+			expectApplied: true,
+		},
+		{
+			name:          "deposit applied in the meantime is skipped",
+			event:         &models.FailedEvent{EventType: models.FailedEventTypeDeposit, Reference: "0xabc123", BlockHeight: &height, RawEvent: string(raw)},
+			depositErr:    services.ErrDepositAlreadyApplied,
+			expectApplied: true,
+		},
+		{
+			name:          "deposit that fails again",
+			event:         &models.FailedEvent{EventType: models.FailedEventTypeDeposit, Reference: "0xabc123", BlockHeight: &height, RawEvent: string(raw)},
+			depositErr:    services.ErrMaxRetries,
+			expectApplied: true,
+			expectedError: "failed to apply deposit 0xabc123",
+		},
+		{
+			name:           "deposit to a chain on another root chain",
+			event:          &models.FailedEvent{EventType: models.FailedEventTypeDeposit, Reference: "0xabc123", BlockHeight: &height, RawEvent: string(raw)},
+			otherRootChain: true,
+			expectedError:  "chain TestChain is on root chain 2, not 1",
+		},
+		{
+			name:          "height without a quorum certificate",
+			event:         &models.FailedEvent{EventType: models.FailedEventTypeDeposit, Reference: "0xabc123", BlockHeight: &height, RawEvent: string(raw)},
+			cert:          &lib.QuorumCertificate{},
+			expectedError: "no quorum certificate at height 2200",
+		},
+		{
+			name:          "order event",
+			event:         &models.FailedEvent{EventType: models.FailedEventTypeOrder, Reference: "0a0b", RawEvent: "{}"},
+			expectedError: "cannot replay order event as a deposit",
+		},
+		{
+			name:          "undecodable transaction",
+			event:         &models.FailedEvent{EventType: models.FailedEventTypeDeposit, Reference: "0xabc123", BlockHeight: &height, RawEvent: "not json"},
+			expectedError: "failed to decode transaction",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chainRepo := new(MockChainRepository)
+			deposits := new(MockDepositProcessor)
+			userRepo := new(MockUserRepository)
+			rpc := new(MockRPCClient)
+
+			cert := finalCert()
+			if tt.cert != nil {
+				cert = tt.cert
+			}
+			rpc.On("CertByHeight", height).Return(cert, nil)
+			if tt.cert != nil {
+				chainRepo.On("GetByAddress", mock.Anything, hex.EncodeToString(recipientAddress)).
+					Return(buildChain(chainID, "TestChain", uuid.New()), nil)
+			}
+			if tt.expectApplied {
+				chainRepo.On("GetByAddress", mock.Anything, hex.EncodeToString(recipientAddress)).
+					Return(buildChain(chainID, "TestChain", uuid.New()), nil)
+				setupStandardUserMocks(userRepo, senderAddress)
+				// Quotes are checked against the time of the block the send was in
+				deposits.On("ProcessDepositWithRetry", mock.Anything, mock.MatchedBy(func(deposit *services.Deposit) bool {
+					return deposit.TxHash == "0xabc123" && deposit.Amount == 2500000 && deposit.BlockHeight == height &&
+						deposit.BlockTime.Equal(testBlockTime)
+				})).Return(buildTradeResult(2500, 31.0), tt.depositErr)
+			}
+			if tt.otherRootChain {
+				chain := buildChain(chainID, "TestChain", uuid.New())
+				chain.RootChainID = 2
+				chainRepo.On("GetByAddress", mock.Anything, hex.EncodeToString(recipientAddress)).Return(chain, nil)
+			}
+
+			worker := &Worker{
+				rpcClient:   rpc,
+				chainRepo:   chainRepo,
+				deposits:    deposits,
+				userRepo:    userRepo,
+				logger:      NewLogger(),
+				graduation:  &MockGraduationNotifier{},
+				rootChainID: 1,
+			}
+
+			err := worker.Replay(context.Background(), tt.event)
+			if tt.otherRootChain {
+				assert.ErrorIs(t, err, ErrOtherRootChain)
+			}
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			chainRepo.AssertExpectations(t)
+			deposits.AssertExpectations(t)
 		})
 	}
 }
+
+// emptyPage is a transactions page with no results
+func emptyPage() *lib.Page {
+	return &lib.Page{Results: &lib.TxResults{}}
+}
+
+// testBlockTime is the time of the blocks in finalCert
+var testBlockTime = time.Unix(1700000000, 0)
+
+// finalCert is a quorum certificate for a committed block
+func finalCert() *lib.QuorumCertificate {
+	block, err := lib.Marshal(&lib.Block{BlockHeader: &lib.BlockHeader{Time: uint64(testBlockTime.UnixMicro())}})
+	if err != nil {
+		panic(err)
+	}
+	return &lib.QuorumCertificate{BlockHash: []byte{0x01}, Block: block}
+}
+
+func TestWorker_syncTo(t *testing.T) {
+	const rootChainID = 1
+
+	newWorker := func(rpc *MockRPCClient, checkpoints *MockCheckpointRepository, startHeight uint64) *Worker {
+		return &Worker{
+			rpcClient:   rpc,
+			blocks:      &fakeBlockApplier{checkpoints: checkpoints},
+			checkpoints: checkpoints,
+			logger:      NewLogger(),
+			rootChainID: rootChainID,
+			startHeight: startHeight,
+		}
+	}
+
+	t.Run("backfills heights missed since the checkpoint", func(t *testing.T) {
+		rpc := new(MockRPCClient)
+		rpc.On("CertByHeight", mock.Anything).Return(finalCert(), nil)
+		checkpoints := new(MockCheckpointRepository)
+		checkpoints.On("Get", mock.Anything, uint64(rootChainID)).Return(&models.RootChainCheckpoint{RootChainID: rootChainID, Height: 100}, nil).Once()
+		for height := uint64(101); height <= 104; height++ {
+			rpc.On("TransactionsByHeight", height, mock.Anything).Return(emptyPage(), nil).Once()
+			checkpoints.On("Save", mock.Anything, uint64(rootChainID), height).Return(nil).Once()
+		}
+
+		worker := newWorker(rpc, checkpoints, 0)
+		assert.NoError(t, worker.syncTo(context.Background(), 104))
+
+		status := worker.Status()
+		assert.Equal(t, uint64(104), status.ProcessedHeight)
+		assert.Equal(t, uint64(104), status.ChainHeight)
+		assert.Equal(t, uint64(0), status.Lag)
+		rpc.AssertExpectations(t)
+		checkpoints.AssertExpectations(t)
+	})
+
+	t.Run("without a checkpoint starts at the first height seen", func(t *testing.T) {
+		rpc := new(MockRPCClient)
+		rpc.On("CertByHeight", mock.Anything).Return(finalCert(), nil)
+		checkpoints := new(MockCheckpointRepository)
+		checkpoints.On("Get", mock.Anything, uint64(rootChainID)).Return(nil, nil).Once()
+		rpc.On("TransactionsByHeight", uint64(500), mock.Anything).Return(emptyPage(), nil).Once()
+		checkpoints.On("Save", mock.Anything, uint64(rootChainID), uint64(500)).Return(nil).Once()
+
+		worker := newWorker(rpc, checkpoints, 0)
+		assert.NoError(t, worker.syncTo(context.Background(), 500))
+		rpc.AssertExpectations(t)
+		checkpoints.AssertExpectations(t)
+	})
+
+	t.Run("without a checkpoint starts at the configured start height", func(t *testing.T) {
+		rpc := new(MockRPCClient)
+		rpc.On("CertByHeight", mock.Anything).Return(finalCert(), nil)
+		checkpoints := new(MockCheckpointRepository)
+		checkpoints.On("Get", mock.Anything, uint64(rootChainID)).Return(nil, nil).Once()
+		for height := uint64(498); height <= 500; height++ {
+			rpc.On("TransactionsByHeight", height, mock.Anything).Return(emptyPage(), nil).Once()
+			checkpoints.On("Save", mock.Anything, uint64(rootChainID), height).Return(nil).Once()
+		}
+
+		worker := newWorker(rpc, checkpoints, 498)
+		assert.NoError(t, worker.syncTo(context.Background(), 500))
+		rpc.AssertExpectations(t)
+		checkpoints.AssertExpectations(t)
+	})
+
+	t.Run("already processed height is skipped", func(t *testing.T) {
+		rpc := new(MockRPCClient)
+		rpc.On("CertByHeight", mock.Anything).Return(finalCert(), nil)
+		checkpoints := new(MockCheckpointRepository)
+		checkpoints.On("Get", mock.Anything, uint64(rootChainID)).Return(&models.RootChainCheckpoint{RootChainID: rootChainID, Height: 200}, nil).Once()
+
+		worker := newWorker(rpc, checkpoints, 0)
+		assert.NoError(t, worker.syncTo(context.Background(), 200))
+		assert.NoError(t, worker.syncTo(context.Background(), 150))
+
+		rpc.AssertNotCalled(t, "TransactionsByHeight", mock.Anything, mock.Anything)
+		checkpoints.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("fetch failure stops without advancing the checkpoint", func(t *testing.T) {
+		rpc := new(MockRPCClient)
+		rpc.On("CertByHeight", mock.Anything).Return(finalCert(), nil)
+		checkpoints := new(MockCheckpointRepository)
+		checkpoints.On("Get", mock.Anything, uint64(rootChainID)).Return(&models.RootChainCheckpoint{RootChainID: rootChainID, Height: 10}, nil).Once()
+		rpc.On("TransactionsByHeight", uint64(11), mock.Anything).Return(emptyPage(), nil).Once()
+		checkpoints.On("Save", mock.Anything, uint64(rootChainID), uint64(11)).Return(nil).Once()
+		rpc.On("TransactionsByHeight", uint64(12), mock.Anything).Return(nil, lib.ErrHttpStatus("500", 500, nil)).Once()
+
+		worker := newWorker(rpc, checkpoints, 0)
+		err := worker.syncTo(context.Background(), 15)
+		assert.ErrorContains(t, err, "failed to fetch transactions page 1 at height 12")
+
+		// Ingestion reports how far it trails the chain
+		status := worker.Status()
+		assert.Equal(t, uint64(11), status.ProcessedHeight)
+		assert.Equal(t, uint64(15), status.ChainHeight)
+		assert.Equal(t, uint64(4), status.Lag)
+
+		// The next attempt resumes from the failed height
+		rpc.On("TransactionsByHeight", mock.Anything, mock.Anything).Return(emptyPage(), nil)
+		checkpoints.On("Save", mock.Anything, uint64(rootChainID), mock.Anything).Return(nil)
+		assert.NoError(t, worker.syncTo(context.Background(), 15))
+		rpc.AssertCalled(t, "TransactionsByHeight", uint64(12), mock.Anything)
+		checkpoints.AssertNumberOfCalls(t, "Get", 1)
+		assert.Equal(t, uint64(15), worker.Status().ProcessedHeight)
+	})
+
+	t.Run("connect backfills to the current root chain height", func(t *testing.T) {
+		rpc := new(MockRPCClient)
+		rpc.On("CertByHeight", mock.Anything).Return(finalCert(), nil)
+		checkpoints := new(MockCheckpointRepository)
+		rpc.On("Height").Return(uint64(42), nil).Once()
+		checkpoints.On("Get", mock.Anything, uint64(rootChainID)).Return(&models.RootChainCheckpoint{RootChainID: rootChainID, Height: 40}, nil).Once()
+		for height := uint64(41); height <= 42; height++ {
+			rpc.On("TransactionsByHeight", height, mock.Anything).Return(emptyPage(), nil).Once()
+			checkpoints.On("Save", mock.Anything, uint64(rootChainID), height).Return(nil).Once()
+		}
+
+		worker := newWorker(rpc, checkpoints, 0)
+		worker.handleConnect()
+
+		assert.Equal(t, uint64(42), worker.Status().ProcessedHeight)
+		rpc.AssertExpectations(t)
+		checkpoints.AssertExpectations(t)
+	})
+}
+
+func TestWorker_syncToWithConfirmations(t *testing.T) {
+	const rootChainID = 1
+	chainID := uuid.New()
+	recipientAddress := []byte{0xaa, 0xbb, 0xcc, 0xdd}
+	senderAddress := []byte{0x01, 0x02, 0x03, 0x04}
+
+	// sendPage is a page holding a single deposit to the chain
+	sendPage := func(txHash string) *lib.Page {
+		tx := buildTxResultWithValidSend(recipientAddress, senderAddress, 1000000)
+		tx.TxHash = txHash
+		return &lib.Page{Results: &lib.TxResults{tx}, TotalPages: 1, TotalCount: 1}
+	}
+
+	newWorker := func(rpc *MockRPCClient, checkpoints *MockCheckpointRepository, pending *MockPendingDepositRepository) (*Worker, *fakeBlockApplier) {
+		chainRepo := new(MockChainRepository)
+		setupChainMocks(chainRepo, recipientAddress, buildChain(chainID, "TestChain", uuid.New()))
+		userRepo := new(MockUserRepository)
+		setupStandardUserMocks(userRepo, senderAddress)
+		blocks := &fakeBlockApplier{checkpoints: checkpoints, result: buildTradeResult(1000, 31.0)}
+
+		return &Worker{
+			rpcClient:     rpc,
+			chainRepo:     chainRepo,
+			blocks:        blocks,
+			userRepo:      userRepo,
+			checkpoints:   checkpoints,
+			pending:       pending,
+			logger:        NewLogger(),
+			graduation:    &MockGraduationNotifier{},
+			rootChainID:   rootChainID,
+			confirmations: 2,
+		}, blocks
+	}
+
+	t.Run("deposits are pending until confirmed", func(t *testing.T) {
+		rpc := new(MockRPCClient)
+		checkpoints := new(MockCheckpointRepository)
+		pending := new(MockPendingDepositRepository)
+		worker, blocks := newWorker(rpc, checkpoints, pending)
+
+		checkpoints.On("Get", mock.Anything, uint64(rootChainID)).Return(&models.RootChainCheckpoint{RootChainID: rootChainID, Height: 100}, nil).Once()
+		rpc.On("CertByHeight", mock.Anything).Return(finalCert(), nil)
+		for height := uint64(101); height <= 104; height++ {
+			if height != 103 {
+				rpc.On("TransactionsByHeight", height, mock.Anything).Return(emptyPage(), nil)
+			}
+		}
+		rpc.On("TransactionsByHeight", uint64(103), mock.Anything).Return(sendPage("0xd3"), nil)
+		checkpoints.On("Save", mock.Anything, uint64(rootChainID), mock.Anything).Return(nil)
+		pending.On("DeleteThroughHeight", mock.Anything, uint64(rootChainID), mock.Anything).Return(nil)
+		pending.On("Create", mock.Anything, mock.MatchedBy(func(deposit *models.PendingDeposit) bool {
+			return deposit.ChainID == chainID &&
+				deposit.RootChainID == rootChainID &&
+				deposit.SenderAddress == "0x"+hex.EncodeToString(senderAddress) &&
+				deposit.Amount == 1000000 &&
+				deposit.TransactionHash == "0xd3" &&
+				deposit.BlockHeight == 103 &&
+				deposit.FinalHeight == 105
+		})).Return(nil).Once()
+
+		// Heights 103 and 104 do not have two blocks above them yet
+		require.NoError(t, worker.syncTo(context.Background(), 104))
+		assert.Empty(t, blocks.deposits())
+		checkpoints.AssertNumberOfCalls(t, "Save", 2)
+		pending.AssertCalled(t, "DeleteThroughHeight", mock.Anything, uint64(rootChainID), uint64(102))
+
+		status := worker.Status()
+		assert.Equal(t, uint64(102), status.ProcessedHeight)
+		assert.Equal(t, uint64(2), status.Confirmations)
+		assert.Equal(t, uint64(0), status.Lag)
+
+		// Once height 105 exists the deposit at 103 is credited and only 105 is scanned
+		rpc.On("TransactionsByHeight", uint64(105), mock.Anything).Return(emptyPage(), nil).Once()
+
+		require.NoError(t, worker.syncTo(context.Background(), 105))
+		require.Len(t, blocks.deposits(), 1)
+		deposit := blocks.deposits()[0]
+		assert.Equal(t, chainID, deposit.ChainID)
+		assert.Equal(t, uint64(1000000), deposit.Amount)
+		assert.Equal(t, "0xd3", deposit.TxHash)
+		assert.Equal(t, uint64(103), deposit.BlockHeight)
+		pending.AssertExpectations(t)
+		pending.AssertCalled(t, "DeleteThroughHeight", mock.Anything, uint64(rootChainID), uint64(103))
+		rpc.AssertNumberOfCalls(t, "TransactionsByHeight", 6)
+		assert.Equal(t, uint64(103), worker.Status().ProcessedHeight)
+	})
+
+	t.Run("height without a quorum certificate is not applied", func(t *testing.T) {
+		rpc := new(MockRPCClient)
+		checkpoints := new(MockCheckpointRepository)
+		pending := new(MockPendingDepositRepository)
+		worker, blocks := newWorker(rpc, checkpoints, pending)
+
+		checkpoints.On("Get", mock.Anything, uint64(rootChainID)).Return(&models.RootChainCheckpoint{RootChainID: rootChainID, Height: 100}, nil).Once()
+		rpc.On("CertByHeight", uint64(101)).Return(nil, nil).Once()
+		rpc.On("TransactionsByHeight", uint64(101), mock.Anything).Return(sendPage("0xd1"), nil).Once()
+		rpc.On("TransactionsByHeight", mock.Anything, mock.Anything).Return(emptyPage(), nil)
+		pending.On("Create", mock.Anything, mock.MatchedBy(func(deposit *models.PendingDeposit) bool {
+			return deposit.TransactionHash == "0xd1" && deposit.BlockHeight == 101
+		})).Return(nil).Once()
+
+		require.NoError(t, worker.syncTo(context.Background(), 103))
+
+		assert.Empty(t, blocks.blocks)
+		checkpoints.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+		pending.AssertExpectations(t)
+		assert.Equal(t, uint64(100), worker.Status().ProcessedHeight)
+		assert.Equal(t, uint64(1), worker.Status().Lag)
+	})
+
+	t.Run("certificate without a block stops the sync", func(t *testing.T) {
+		rpc := new(MockRPCClient)
+		checkpoints := new(MockCheckpointRepository)
+		pending := new(MockPendingDepositRepository)
+		worker, _ := newWorker(rpc, checkpoints, pending)
+
+		checkpoints.On("Get", mock.Anything, uint64(rootChainID)).Return(&models.RootChainCheckpoint{RootChainID: rootChainID, Height: 100}, nil).Once()
+		rpc.On("CertByHeight", uint64(101)).Return(&lib.QuorumCertificate{BlockHash: []byte{0x01}}, nil).Once()
+
+		err := worker.syncTo(context.Background(), 103)
+		assert.ErrorContains(t, err, "quorum certificate at height 101 has no block header")
+		rpc.AssertNotCalled(t, "TransactionsByHeight", mock.Anything, mock.Anything)
+		checkpoints.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("certificate fetch failure stops the sync", func(t *testing.T) {
+		rpc := new(MockRPCClient)
+		checkpoints := new(MockCheckpointRepository)
+		pending := new(MockPendingDepositRepository)
+		worker, _ := newWorker(rpc, checkpoints, pending)
+
+		checkpoints.On("Get", mock.Anything, uint64(rootChainID)).Return(&models.RootChainCheckpoint{RootChainID: rootChainID, Height: 100}, nil).Once()
+		rpc.On("CertByHeight", uint64(101)).Return(nil, lib.ErrHttpStatus("500", 500, nil)).Once()
+
+		err := worker.syncTo(context.Background(), 103)
+		assert.ErrorContains(t, err, "failed to fetch quorum certificate at height 101")
+		rpc.AssertNotCalled(t, "TransactionsByHeight", mock.Anything, mock.Anything)
+		checkpoints.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
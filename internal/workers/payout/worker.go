@@ -0,0 +1,370 @@
+package payout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canopy-network/canopy/fsm"
+	"github.com/canopy-network/canopy/lib"
+	"github.com/canopy-network/canopy/lib/crypto"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/interfaces"
+	"github.com/enielson/launchpad/internal/services"
+)
+
+const (
+	// RetryBaseDelay is the delay before the first retry of a payout that could
+	// not be sent. Subsequent retries double the delay up to RetryMaxDelay.
+	RetryBaseDelay = 30 * time.Second
+
+	// RetryMaxDelay caps the backoff between payout retries
+	RetryMaxDelay = 30 * time.Minute
+)
+
+// RPCClient defines the root chain calls the worker makes. It matches the
+// canopy RPC client.
+type RPCClient interface {
+	Height() (*uint64, lib.ErrorI)
+	FeeParams(height uint64) (*fsm.FeeParams, lib.ErrorI)
+	TransactionByHash(hash string) (*lib.TxResult, lib.ErrorI)
+	TransactionJSON(tx json.RawMessage) (*string, lib.ErrorI)
+}
+
+// Worker sends queued payouts on the root chain from each chain's operation key.
+//
+// A pending payout is signed, and the signed send is stored with its hash
+// before it is broadcast. From then on the worker only rebroadcasts those exact
+// bytes, which the root chain accepts at most once, until the send is found at
+// a final height. A new send is built only once the stored one has outlived the
+// root chain's acceptance window without being included, at which point it can
+// never be included, so a payout is never paid twice.
+type Worker struct {
+	payoutRepo interfaces.PayoutRepository
+	chainRepo  interfaces.ChainRepository
+	rpcClient  RPCClient
+	config     Config
+	stopChan   chan struct{}
+	done       chan struct{}
+	stopOnce   sync.Once
+}
+
+// Config holds configuration for the payout worker
+type Config struct {
+	// Interval is how often to look for due payouts (default: 15 seconds)
+	Interval time.Duration
+
+	// BatchSize is the maximum number of payouts handled per tick (default: 50)
+	BatchSize int
+
+	// RootChainID and NetworkID identify the root chain sends are made on. Only
+	// payouts of chains on that root chain are sent; run one worker per root chain.
+	RootChainID uint64
+	NetworkID   uint64
+
+	// Confirmations is how many blocks must exist above a send before it is confirmed
+	Confirmations uint64
+
+	// MaxAttempts is how many times a payout may fail to be sent before it is
+	// marked failed (default: 10)
+	MaxAttempts int
+
+	// KeyPassword decrypts the chain operation keys. It has no default: the
+	// worker does not start without it.
+	KeyPassword string
+}
+
+// DefaultConfig returns default configuration for the worker
+func DefaultConfig() Config {
+	return Config{
+		Interval:    15 * time.Second,
+		BatchSize:   50,
+		NetworkID:   lib.CanopyMainnetNetworkId,
+		MaxAttempts: 10,
+	}
+}
+
+// NewWorker creates a new payout worker
+func NewWorker(payoutRepo interfaces.PayoutRepository, chainRepo interfaces.ChainRepository, rpcClient RPCClient, config Config) *Worker {
+	if config.Interval == 0 {
+		config.Interval = 15 * time.Second
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 50
+	}
+	if config.NetworkID == 0 {
+		config.NetworkID = lib.CanopyMainnetNetworkId
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = 10
+	}
+
+	return &Worker{
+		payoutRepo: payoutRepo,
+		chainRepo:  chainRepo,
+		rpcClient:  rpcClient,
+		config:     config,
+		stopChan:   make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start begins the payout worker
+func (w *Worker) Start() error {
+	if w.config.KeyPassword == "" {
+		return fmt.Errorf("payout worker for root chain %d has no key password to decrypt chain keys", w.config.RootChainID)
+	}
+
+	log.Printf("[Payout Worker] Starting for root chain %d (interval: %v)", w.config.RootChainID, w.config.Interval)
+
+	go w.run()
+
+	return nil
+}
+
+// Stop gracefully stops the payout worker
+func (w *Worker) Stop() error {
+	w.stopOnce.Do(func() {
+		log.Println("[Payout Worker] Stopping...")
+		close(w.stopChan)
+
+		// Wait for worker to finish current operation
+		select {
+		case <-w.done:
+			log.Println("[Payout Worker] Stopped")
+		case <-time.After(10 * time.Second):
+			log.Println("[Payout Worker] Stop timeout")
+		}
+	})
+
+	return nil
+}
+
+// run is the main worker loop
+func (w *Worker) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		if err := w.ProcessDue(ctx); err != nil {
+			log.Printf("[Payout Worker] Error processing payouts: %v", err)
+		}
+		cancel()
+
+		select {
+		case <-ticker.C:
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// ProcessDue sends or tracks every payout whose next attempt is due
+func (w *Worker) ProcessDue(ctx context.Context) error {
+	payouts, err := w.payoutRepo.ListDue(ctx, w.config.RootChainID, w.config.BatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to list due payouts: %w", err)
+	}
+	if len(payouts) == 0 {
+		return nil
+	}
+
+	height, rpcErr := w.rpcClient.Height()
+	if rpcErr != nil {
+		return fmt.Errorf("failed to get root chain height: %w", rpcErr)
+	}
+
+	for i := range payouts {
+		if err := w.processPayout(ctx, &payouts[i], *height); err != nil {
+			log.Printf("[Payout Worker] Error processing payout %s: %v", payouts[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// processPayout advances a single payout given the current root chain height
+func (w *Worker) processPayout(ctx context.Context, payout *models.Payout, height uint64) error {
+	switch payout.Status {
+	case models.PayoutStatusPending:
+		return w.send(ctx, payout, height)
+	case models.PayoutStatusSubmitted:
+		return w.track(ctx, payout, height)
+	default:
+		return nil
+	}
+}
+
+// send signs a pending payout, stores the signed send and broadcasts it
+func (w *Worker) send(ctx context.Context, payout *models.Payout, height uint64) error {
+	signedTx, txHash, err := w.buildSend(ctx, payout, height)
+	if err != nil {
+		return w.recordFailure(ctx, payout, models.PayoutStatusPending, err)
+	}
+
+	// Store the send before broadcasting it, so that whatever happens next the
+	// worker only ever rebroadcasts this transaction
+	payout.Status = models.PayoutStatusSubmitted
+	payout.TxHash = &txHash
+	payout.SignedTx = &signedTx
+	payout.TxCreatedHeight = &height
+	payout.LastError = nil
+	payout.NextAttemptAt = time.Now().Add(w.config.Interval)
+	stored, err := w.payoutRepo.Update(ctx, payout, models.PayoutStatusPending)
+	if err != nil {
+		return err
+	}
+	if !stored {
+		log.Printf("[Payout Worker] Payout %s was claimed by another worker, skipping", payout.ID)
+		return nil
+	}
+
+	log.Printf("[Payout Worker] Sending %d uCNPY to %s for payout %s (%s) in %s",
+		payout.Amount, payout.RecipientAddress, payout.ID, payout.PayoutType, txHash)
+
+	return w.broadcast(ctx, payout)
+}
+
+// buildSend decrypts the chain's operation key and signs a send of the payout
+// amount to its recipient, returning the signed transaction JSON and its hash
+func (w *Worker) buildSend(ctx context.Context, payout *models.Payout, height uint64) (string, string, error) {
+	key, err := w.chainRepo.GetChainKeyByChainID(ctx, payout.ChainID, models.KeyPurposeChainOperation)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get chain key: %w", err)
+	}
+	privateKey, err := services.DecryptChainKey(key, w.config.KeyPassword)
+	if err != nil {
+		return "", "", err
+	}
+
+	recipient, err := crypto.NewAddressFromString(strings.TrimPrefix(payout.RecipientAddress, "0x"))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid recipient address %s: %w", payout.RecipientAddress, err)
+	}
+
+	feeParams, rpcErr := w.rpcClient.FeeParams(0)
+	if rpcErr != nil {
+		return "", "", fmt.Errorf("failed to get fee params: %w", rpcErr)
+	}
+
+	tx, rpcErr := fsm.NewSendTransaction(privateKey, recipient, payout.Amount, w.config.NetworkID,
+		w.config.RootChainID, feeParams.SendFee, height, "launchpad payout "+payout.ID.String())
+	if rpcErr != nil {
+		return "", "", fmt.Errorf("failed to sign send: %w", rpcErr)
+	}
+
+	hash, rpcErr := tx.GetHash()
+	if rpcErr != nil {
+		return "", "", fmt.Errorf("failed to hash send: %w", rpcErr)
+	}
+	signedTx, rpcErr := lib.MarshalJSON(tx)
+	if rpcErr != nil {
+		return "", "", fmt.Errorf("failed to encode send: %w", rpcErr)
+	}
+
+	return string(signedTx), lib.BytesToString(hash), nil
+}
+
+// track checks a submitted payout's send on the root chain. An included send is
+// confirmed once final; one that can still be included is rebroadcast; one that
+// has expired unincluded is discarded so the payout is signed again.
+func (w *Worker) track(ctx context.Context, payout *models.Payout, height uint64) error {
+	if payout.TxHash == nil || payout.SignedTx == nil || payout.TxCreatedHeight == nil {
+		return fmt.Errorf("submitted payout has no stored transaction")
+	}
+
+	result, rpcErr := w.rpcClient.TransactionByHash(*payout.TxHash)
+	if rpcErr != nil {
+		return fmt.Errorf("failed to look up send %s: %w", *payout.TxHash, rpcErr)
+	}
+
+	if result != nil && result.TxHash == *payout.TxHash {
+		if height < result.Height+w.config.Confirmations {
+			payout.NextAttemptAt = time.Now().Add(w.config.Interval)
+			_, err := w.payoutRepo.Update(ctx, payout, models.PayoutStatusSubmitted)
+			return err
+		}
+
+		includedAt := result.Height
+		payout.Status = models.PayoutStatusConfirmed
+		payout.ConfirmedHeight = &includedAt
+		payout.LastError = nil
+		if _, err := w.payoutRepo.Update(ctx, payout, models.PayoutStatusSubmitted); err != nil {
+			return err
+		}
+		log.Printf("[Payout Worker] Payout %s confirmed at height %d", payout.ID, includedAt)
+		return nil
+	}
+
+	// The root chain rejects a send once its created height falls out of the
+	// acceptance range; allow for our confirmation depth before giving up on it
+	if height > *payout.TxCreatedHeight+fsm.BlockAcceptanceRange+w.config.Confirmations {
+		log.Printf("[Payout Worker] Send %s for payout %s expired at height %d without being included",
+			*payout.TxHash, payout.ID, height)
+		payout.Status = models.PayoutStatusPending
+		payout.TxHash = nil
+		payout.SignedTx = nil
+		payout.TxCreatedHeight = nil
+		return w.recordFailure(ctx, payout, models.PayoutStatusSubmitted, errors.New("send expired before it was included"))
+	}
+
+	return w.broadcast(ctx, payout)
+}
+
+// broadcast submits a submitted payout's stored send. A failed broadcast is
+// recorded and retried with backoff; it may only mean the node already has the
+// send, so it never causes a new send to be built.
+func (w *Worker) broadcast(ctx context.Context, payout *models.Payout) error {
+	_, rpcErr := w.rpcClient.TransactionJSON(json.RawMessage(*payout.SignedTx))
+	if rpcErr == nil {
+		return nil
+	}
+
+	errMsg := rpcErr.Error()
+	payout.LastError = &errMsg
+	payout.NextAttemptAt = time.Now().Add(retryBackoff(payout.Attempts + 1))
+	if _, err := w.payoutRepo.Update(ctx, payout, models.PayoutStatusSubmitted); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("failed to broadcast send %s: %w", *payout.TxHash, rpcErr)
+}
+
+// recordFailure counts a failed attempt to send a payout that has no live send,
+// scheduling a retry or marking it failed once MaxAttempts is reached
+func (w *Worker) recordFailure(ctx context.Context, payout *models.Payout, fromStatus string, sendErr error) error {
+	errMsg := sendErr.Error()
+	payout.Attempts++
+	payout.LastError = &errMsg
+	payout.NextAttemptAt = time.Now().Add(retryBackoff(payout.Attempts))
+	if payout.Attempts >= w.config.MaxAttempts {
+		payout.Status = models.PayoutStatusFailed
+		log.Printf("[Payout Worker] Payout %s failed after %d attempts: %v", payout.ID, payout.Attempts, sendErr)
+	}
+
+	if _, err := w.payoutRepo.Update(ctx, payout, fromStatus); err != nil {
+		return err
+	}
+
+	return sendErr
+}
+
+// retryBackoff returns the delay before the given retry attempt
+func retryBackoff(attempt int) time.Duration {
+	delay := RetryBaseDelay
+	for i := 1; i < attempt && delay < RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > RetryMaxDelay {
+		delay = RetryMaxDelay
+	}
+	return delay
+}
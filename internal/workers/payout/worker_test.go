@@ -0,0 +1,307 @@
+package payout
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canopy-network/canopy/fsm"
+	"github.com/canopy-network/canopy/lib"
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/services"
+	"github.com/enielson/launchpad/internal/testutil/mocks"
+	"github.com/enielson/launchpad/pkg/keygen"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRPC is a root chain that records broadcasts and serves included sends
+type fakeRPC struct {
+	height    uint64
+	sendFee   uint64
+	included  map[string]*lib.TxResult
+	submitErr lib.ErrorI
+	submitted []json.RawMessage
+
+	// onSubmit runs before a broadcast is recorded
+	onSubmit func()
+}
+
+func (f *fakeRPC) Height() (*uint64, lib.ErrorI) {
+	return &f.height, nil
+}
+
+func (f *fakeRPC) FeeParams(height uint64) (*fsm.FeeParams, lib.ErrorI) {
+	return &fsm.FeeParams{SendFee: f.sendFee}, nil
+}
+
+func (f *fakeRPC) TransactionByHash(hash string) (*lib.TxResult, lib.ErrorI) {
+	if result, ok := f.included[hash]; ok {
+		return result, nil
+	}
+	// The canopy RPC answers unknown hashes with an empty result
+	return &lib.TxResult{}, nil
+}
+
+func (f *fakeRPC) TransactionJSON(tx json.RawMessage) (*string, lib.ErrorI) {
+	if f.onSubmit != nil {
+		f.onSubmit()
+	}
+	f.submitted = append(f.submitted, tx)
+	if f.submitErr != nil {
+		return nil, f.submitErr
+	}
+	hash := "submitted"
+	return &hash, nil
+}
+
+// testKeyPassword encrypts the chain keys the tests sign payouts with
+const testKeyPassword = "test-chain-key-password"
+
+// newChainKey creates an operation key for a chain the way chain creation does
+func newChainKey(t *testing.T, chainID uuid.UUID) *models.ChainKey {
+	_, encrypted, err := keygen.GenerateEncryptedKeyPair(testKeyPassword)
+	require.NoError(t, err)
+	publicKey, err := hex.DecodeString(encrypted.PublicKey)
+	require.NoError(t, err)
+	salt, err := hex.DecodeString(encrypted.Salt)
+	require.NoError(t, err)
+
+	return &models.ChainKey{
+		ChainID:             chainID,
+		Address:             encrypted.Address,
+		PublicKey:           publicKey,
+		EncryptedPrivateKey: encrypted.EncryptedPrivateKey,
+		Salt:                salt,
+		KeyPurpose:          models.KeyPurposeChainOperation,
+		IsActive:            true,
+	}
+}
+
+func newPayout(chainID uuid.UUID) models.Payout {
+	return models.Payout{
+		ID:               uuid.New(),
+		ChainID:          chainID,
+		UserID:           uuid.New(),
+		PayoutType:       models.PayoutTypeSellProceeds,
+		RecipientAddress: "0x" + strings.Repeat("ab", 20),
+		Amount:           750000,
+		Reference:        strings.Repeat("cd", 32),
+		Status:           models.PayoutStatusPending,
+		UpdatedAt:        time.Now(),
+	}
+}
+
+func newTestWorker(payoutRepo *mocks.MockPayoutRepository, chainRepo *mocks.MockChainRepository, rpc *fakeRPC) *Worker {
+	config := DefaultConfig()
+	config.RootChainID = 1
+	config.Confirmations = 2
+	config.MaxAttempts = 3
+	config.KeyPassword = testKeyPassword
+	return NewWorker(payoutRepo, chainRepo, rpc, config)
+}
+
+func TestWorker_StartRequiresKeyPassword(t *testing.T) {
+	config := DefaultConfig()
+	config.RootChainID = 1
+	worker := NewWorker(new(mocks.MockPayoutRepository), new(mocks.MockChainRepository), &fakeRPC{}, config)
+	assert.ErrorContains(t, worker.Start(), "no key password")
+}
+
+func TestWorker_SendPending(t *testing.T) {
+	chainID := uuid.New()
+	key := newChainKey(t, chainID)
+
+	t.Run("signs, stores and then broadcasts the send", func(t *testing.T) {
+		payout := newPayout(chainID)
+		payoutRepo := new(mocks.MockPayoutRepository)
+		chainRepo := new(mocks.MockChainRepository)
+		rpc := &fakeRPC{height: 500, sendFee: 10000}
+
+		stored := false
+		rpc.onSubmit = func() { assert.True(t, stored, "send must be stored before it is broadcast") }
+
+		payoutRepo.On("ListDue", mock.Anything, uint64(1), 50).Return([]models.Payout{payout}, nil)
+		chainRepo.On("GetChainKeyByChainID", mock.Anything, chainID, models.KeyPurposeChainOperation).Return(key, nil)
+		payoutRepo.On("Update", mock.Anything, mock.MatchedBy(func(p *models.Payout) bool {
+			return p.Status == models.PayoutStatusSubmitted && p.TxHash != nil && p.SignedTx != nil &&
+				p.TxCreatedHeight != nil && *p.TxCreatedHeight == 500
+		}), models.PayoutStatusPending).Run(func(mock.Arguments) { stored = true }).Return(true, nil)
+
+		worker := newTestWorker(payoutRepo, chainRepo, rpc)
+		require.NoError(t, worker.ProcessDue(context.Background()))
+		payoutRepo.AssertExpectations(t)
+		require.Len(t, rpc.submitted, 1)
+
+		// The broadcast is the stored send: from the chain key to the recipient
+		update := payoutRepo.Calls[1].Arguments.Get(1).(*models.Payout)
+		assert.JSONEq(t, *update.SignedTx, string(rpc.submitted[0]))
+
+		tx := new(lib.Transaction)
+		require.NoError(t, lib.UnmarshalJSON(rpc.submitted[0], tx))
+		msg, err := lib.FromAny(tx.Msg)
+		require.NoError(t, err)
+		send := msg.(*fsm.MessageSend)
+		assert.Equal(t, key.Address, lib.BytesToString(send.FromAddress))
+		assert.Equal(t, strings.Repeat("ab", 20), lib.BytesToString(send.ToAddress))
+		assert.Equal(t, uint64(750000), send.Amount)
+		assert.Equal(t, uint64(10000), tx.Fee)
+		assert.Equal(t, uint64(500), tx.CreatedHeight)
+
+		hash, err := tx.GetHash()
+		require.NoError(t, err)
+		assert.Equal(t, lib.BytesToString(hash), *update.TxHash)
+	})
+
+	t.Run("payout claimed elsewhere is not broadcast", func(t *testing.T) {
+		payout := newPayout(chainID)
+		payoutRepo := new(mocks.MockPayoutRepository)
+		chainRepo := new(mocks.MockChainRepository)
+		rpc := &fakeRPC{height: 500}
+
+		payoutRepo.On("ListDue", mock.Anything, uint64(1), 50).Return([]models.Payout{payout}, nil)
+		chainRepo.On("GetChainKeyByChainID", mock.Anything, chainID, models.KeyPurposeChainOperation).Return(key, nil)
+		payoutRepo.On("Update", mock.Anything, mock.Anything, models.PayoutStatusPending).Return(false, nil)
+
+		worker := newTestWorker(payoutRepo, chainRepo, rpc)
+		require.NoError(t, worker.ProcessDue(context.Background()))
+		assert.Empty(t, rpc.submitted)
+	})
+
+	t.Run("failed broadcast keeps the stored send", func(t *testing.T) {
+		payout := newPayout(chainID)
+		payoutRepo := new(mocks.MockPayoutRepository)
+		chainRepo := new(mocks.MockChainRepository)
+		rpc := &fakeRPC{height: 500, submitErr: lib.ErrPostRequest(errors.New("connection refused"))}
+
+		payoutRepo.On("ListDue", mock.Anything, uint64(1), 50).Return([]models.Payout{payout}, nil)
+		chainRepo.On("GetChainKeyByChainID", mock.Anything, chainID, models.KeyPurposeChainOperation).Return(key, nil)
+		payoutRepo.On("Update", mock.Anything, mock.Anything, models.PayoutStatusPending).Return(true, nil)
+		payoutRepo.On("Update", mock.Anything, mock.MatchedBy(func(p *models.Payout) bool {
+			return p.Status == models.PayoutStatusSubmitted && p.TxHash != nil && p.LastError != nil && p.Attempts == 0
+		}), models.PayoutStatusSubmitted).Return(true, nil)
+
+		worker := newTestWorker(payoutRepo, chainRepo, rpc)
+		require.NoError(t, worker.ProcessDue(context.Background()))
+		payoutRepo.AssertExpectations(t)
+		assert.Len(t, rpc.submitted, 1)
+	})
+
+	t.Run("missing chain key is retried then marked failed", func(t *testing.T) {
+		payout := newPayout(chainID)
+		payout.Attempts = 2
+		payoutRepo := new(mocks.MockPayoutRepository)
+		chainRepo := new(mocks.MockChainRepository)
+		rpc := &fakeRPC{height: 500}
+
+		payoutRepo.On("ListDue", mock.Anything, uint64(1), 50).Return([]models.Payout{payout}, nil)
+		chainRepo.On("GetChainKeyByChainID", mock.Anything, chainID, models.KeyPurposeChainOperation).Return(nil, errors.New("chain key not found"))
+		payoutRepo.On("Update", mock.Anything, mock.MatchedBy(func(p *models.Payout) bool {
+			return p.Status == models.PayoutStatusFailed && p.Attempts == 3 && p.TxHash == nil &&
+				p.LastError != nil && strings.Contains(*p.LastError, "chain key not found")
+		}), models.PayoutStatusPending).Return(true, nil)
+
+		worker := newTestWorker(payoutRepo, chainRepo, rpc)
+		require.NoError(t, worker.ProcessDue(context.Background()))
+		payoutRepo.AssertExpectations(t)
+		assert.Empty(t, rpc.submitted)
+	})
+
+	t.Run("wrong key password", func(t *testing.T) {
+		_, err := services.DecryptChainKey(key, "wrong")
+		assert.ErrorContains(t, err, "failed to decrypt chain key")
+	})
+}
+
+func TestWorker_TrackSubmitted(t *testing.T) {
+	chainID := uuid.New()
+	txHash := strings.Repeat("ef", 32)
+	signedTx := `{"messageType":"send"}`
+	createdHeight := uint64(500)
+
+	submitted := func() models.Payout {
+		payout := newPayout(chainID)
+		payout.Status = models.PayoutStatusSubmitted
+		payout.TxHash = &txHash
+		payout.SignedTx = &signedTx
+		payout.TxCreatedHeight = &createdHeight
+		payout.Attempts = 1
+		return payout
+	}
+
+	t.Run("final send is confirmed", func(t *testing.T) {
+		payoutRepo := new(mocks.MockPayoutRepository)
+		chainRepo := new(mocks.MockChainRepository)
+		rpc := &fakeRPC{height: 512, included: map[string]*lib.TxResult{txHash: {TxHash: txHash, Height: 510}}}
+
+		payoutRepo.On("ListDue", mock.Anything, uint64(1), 50).Return([]models.Payout{submitted()}, nil)
+		payoutRepo.On("Update", mock.Anything, mock.MatchedBy(func(p *models.Payout) bool {
+			return p.Status == models.PayoutStatusConfirmed && p.ConfirmedHeight != nil && *p.ConfirmedHeight == 510
+		}), models.PayoutStatusSubmitted).Return(true, nil)
+
+		worker := newTestWorker(payoutRepo, chainRepo, rpc)
+		require.NoError(t, worker.ProcessDue(context.Background()))
+		payoutRepo.AssertExpectations(t)
+		assert.Empty(t, rpc.submitted)
+	})
+
+	t.Run("included send waits for confirmations", func(t *testing.T) {
+		payoutRepo := new(mocks.MockPayoutRepository)
+		chainRepo := new(mocks.MockChainRepository)
+		rpc := &fakeRPC{height: 511, included: map[string]*lib.TxResult{txHash: {TxHash: txHash, Height: 510}}}
+
+		payoutRepo.On("ListDue", mock.Anything, uint64(1), 50).Return([]models.Payout{submitted()}, nil)
+		payoutRepo.On("Update", mock.Anything, mock.MatchedBy(func(p *models.Payout) bool {
+			return p.Status == models.PayoutStatusSubmitted && p.ConfirmedHeight == nil
+		}), models.PayoutStatusSubmitted).Return(true, nil)
+
+		worker := newTestWorker(payoutRepo, chainRepo, rpc)
+		require.NoError(t, worker.ProcessDue(context.Background()))
+		payoutRepo.AssertExpectations(t)
+		assert.Empty(t, rpc.submitted)
+	})
+
+	t.Run("unincluded send is rebroadcast unchanged", func(t *testing.T) {
+		payoutRepo := new(mocks.MockPayoutRepository)
+		chainRepo := new(mocks.MockChainRepository)
+		rpc := &fakeRPC{height: createdHeight + fsm.BlockAcceptanceRange}
+
+		payoutRepo.On("ListDue", mock.Anything, uint64(1), 50).Return([]models.Payout{submitted()}, nil)
+
+		worker := newTestWorker(payoutRepo, chainRepo, rpc)
+		require.NoError(t, worker.ProcessDue(context.Background()))
+		require.Len(t, rpc.submitted, 1)
+		assert.Equal(t, signedTx, string(rpc.submitted[0]))
+		chainRepo.AssertNotCalled(t, "GetChainKeyByChainID", mock.Anything, mock.Anything, mock.Anything)
+		payoutRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("expired send is discarded so the payout is signed again", func(t *testing.T) {
+		payoutRepo := new(mocks.MockPayoutRepository)
+		chainRepo := new(mocks.MockChainRepository)
+		rpc := &fakeRPC{height: createdHeight + fsm.BlockAcceptanceRange + 3}
+
+		payoutRepo.On("ListDue", mock.Anything, uint64(1), 50).Return([]models.Payout{submitted()}, nil)
+		payoutRepo.On("Update", mock.Anything, mock.MatchedBy(func(p *models.Payout) bool {
+			return p.Status == models.PayoutStatusPending && p.TxHash == nil && p.SignedTx == nil &&
+				p.TxCreatedHeight == nil && p.Attempts == 2
+		}), models.PayoutStatusSubmitted).Return(true, nil)
+
+		worker := newTestWorker(payoutRepo, chainRepo, rpc)
+		require.NoError(t, worker.ProcessDue(context.Background()))
+		payoutRepo.AssertExpectations(t)
+		assert.Empty(t, rpc.submitted)
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, RetryBaseDelay, retryBackoff(1))
+	assert.Equal(t, 2*RetryBaseDelay, retryBackoff(2))
+	assert.Equal(t, RetryMaxDelay, retryBackoff(20))
+}
@@ -7,14 +7,20 @@ import (
 	"syscall"
 
 	"github.com/enielson/launchpad/internal/config"
+	"github.com/enielson/launchpad/internal/graduator"
+	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/postgres"
 	"github.com/enielson/launchpad/internal/server"
 	"github.com/enielson/launchpad/internal/services"
+	"github.com/enielson/launchpad/internal/workers/deadletter"
 	"github.com/enielson/launchpad/internal/workers/fakevolume"
+	"github.com/enielson/launchpad/internal/workers/graduation"
 	"github.com/enielson/launchpad/internal/workers/newblock"
+	"github.com/enielson/launchpad/internal/workers/payout"
 	sessioncleanup "github.com/enielson/launchpad/internal/workers/session_cleanup"
 	"github.com/enielson/launchpad/pkg/client/canopy"
 	"github.com/enielson/launchpad/pkg/database"
+	"github.com/enielson/launchpad/pkg/sub"
 )
 
 func main() {
@@ -38,13 +44,37 @@ func main() {
 	virtualPoolRepo := postgres.NewVirtualPoolRepository(db)
 	walletRepo := postgres.NewWalletRepository(db)
 	sessionTokenRepo := postgres.NewSessionTokenRepository(db)
+	graduationRepo := postgres.NewChainGraduationRepository(db)
+	graduatedPoolRepo := postgres.NewGraduatedPoolRepository(db)
+	checkpointRepo := postgres.NewRootChainCheckpointRepository(db)
+	pendingDepositRepo := postgres.NewPendingDepositRepository(db)
+	payoutRepo := postgres.NewPayoutRepository(db)
+	failedEventRepo := postgres.NewFailedEventRepository(db)
+
+	// Every pool-mutating path trades through the same transactional engine
+	tradeEngine := services.NewOrderProcessorTx(db, postgres.NewVirtualPoolTxRepository(db), userRepo, nil)
+	tradeEngine.SetFeeSplit(services.FeeSplit{
+		ProtocolBasisPoints: cfg.FeeProtocolBasisPoints,
+		CreatorBasisPoints:  cfg.FeeCreatorBasisPoints,
+		ReferrerBasisPoints: cfg.FeeReferrerBasisPoints,
+	})
 
 	// Initialize services
 	chainService := services.NewChainService(chainRepo, templateRepo, userRepo, virtualPoolRepo)
+	chainService.SetRootChains(cfg.RootChainIDs()...)
+	chainService.SetKeyPassword(cfg.ChainKeyPassword)
 	templateService := services.NewTemplateService(templateRepo)
-	virtualPoolService := services.NewVirtualPoolService(virtualPoolRepo)
+	virtualPoolService := services.NewVirtualPoolService(virtualPoolRepo, pendingDepositRepo, userRepo, tradeEngine)
+	quoteSigner := services.NewQuoteSigner(cfg.QuoteSecret, cfg.QuoteTTL)
+	virtualPoolService.SetQuotes(tradeEngine, quoteSigner)
 	walletService := services.NewWalletService(walletRepo)
 	userService := services.NewUserService(userRepo)
+	chainGraduator := graduator.New(chainRepo, virtualPoolRepo, graduatedPoolRepo, userRepo, graduationRepo, cfg.RootChains[0].ID, cfg.GraduationRPCURL, cfg.GraduationRPCSecret)
+	graduationService := services.NewGraduationService(graduationRepo, chainRepo, chainGraduator)
+	graduatedPoolService := services.NewGraduatedPoolService(graduatedPoolRepo)
+	deadLetterService := services.NewDeadLetterService(failedEventRepo)
+	deadLetterService.SetReplayer(models.FailedEventTypeOrder, services.NewOrderReplayer(tradeEngine))
+	feeService := services.NewFeeService(postgres.NewFeeAccrualRepository(db))
 
 	// Initialize email service (always use SMTP)
 	emailService := services.NewSMTPEmailService()
@@ -54,30 +84,98 @@ func main() {
 
 	// Create services container
 	servicesContainer := &server.Services{
-		ChainService:       chainService,
-		TemplateService:    templateService,
-		AuthService:        authService,
-		VirtualPoolService: virtualPoolService,
-		WalletService:      walletService,
-		UserService:        userService,
+		ChainService:         chainService,
+		TemplateService:      templateService,
+		AuthService:          authService,
+		VirtualPoolService:   virtualPoolService,
+		WalletService:        walletService,
+		UserService:          userService,
+		GraduationService:    graduationService,
+		GraduatedPoolService: graduatedPoolService,
+		DeadLetterService:    deadLetterService,
+		FeeService:           feeService,
 	}
 
-	// Initialize and start root chain event worker
-	workerConfig := newblock.Config{
-		RootChainURL:    cfg.RootChainURL,
-		RootChainID:     cfg.RootChainID,
-		RootChainRPCURL: cfg.RootChainRPCURL,
+	// Initialize and start graduation worker
+	graduationConfig := graduation.DefaultConfig()
+	graduationWorker := graduation.NewWorker(chainRepo, virtualPoolRepo, graduationRepo, chainGraduator, graduation.NewAdvisoryLocker(db), graduationConfig)
+
+	graduationService.SetGraduationNotifier(graduationWorker)
+
+	if err := graduationWorker.Start(); err != nil {
+		log.Fatalf("Failed to start graduation worker: %v", err)
+	}
+	defer graduationWorker.Stop()
+
+	log.Printf("Started graduation worker (sweep interval: %v)", graduationConfig.Interval)
+
+	// Initialize a root chain event worker and a payout worker for each root
+	// chain. The event workers share one subscription manager, and each worker
+	// only handles chains on its own root chain.
+	subscriptions := sub.NewManager(newblock.NewLogger())
+	blockProcessor := services.NewBlockProcessor(db, tradeEngine, postgres.NewRootChainCheckpointTxRepository(db), postgres.NewFailedEventTxRepository(db))
+	var rootChainWorkers []*newblock.Worker
+	var payoutWorkers []*payout.Worker
+	payoutConfig := payout.DefaultConfig()
+	payoutConfig.KeyPassword = cfg.ChainKeyPassword
+	for _, rootChain := range cfg.RootChains {
+		rpcClient := canopy.NewClient(rootChain.RPCURL)
+
+		workerConfig := newblock.Config{
+			RootChainURL:    rootChain.URL,
+			RootChainID:     rootChain.ID,
+			RootChainRPCURL: rootChain.RPCURL,
+			StartHeight:     rootChain.StartHeight,
+			Confirmations:   rootChain.Confirmations,
+			Subscriptions:   subscriptions,
+			Quotes:          quoteSigner,
+		}
+		rootChainWorkers = append(rootChainWorkers, newblock.NewWorker(workerConfig, rpcClient, chainRepo, tradeEngine, blockProcessor, userRepo, checkpointRepo, pendingDepositRepo, failedEventRepo))
+
+		// Payout workers send sell proceeds and refunds from chain keys
+		payoutConfig.RootChainID = rootChain.ID
+		payoutConfig.NetworkID = rootChain.NetworkID
+		payoutConfig.Confirmations = rootChain.Confirmations
+		payoutWorkers = append(payoutWorkers, payout.NewWorker(payoutRepo, chainRepo, rpcClient, payoutConfig))
+	}
+
+	rootChainRouter, err := newblock.NewRouter(chainRepo, rootChainWorkers...)
+	if err != nil {
+		log.Fatalf("Failed to create root chain workers: %v", err)
+	}
+	rootChainRouter.SetGraduationNotifier(graduationWorker)
+	servicesContainer.RootChainStatus = rootChainRouter
+	deadLetterService.SetReplayer(models.FailedEventTypeDeposit, rootChainRouter)
+
+	// Start root chain workers in background
+	if err := rootChainRouter.Start(); err != nil {
+		log.Fatalf("Failed to start root chain workers: %v", err)
 	}
-	rpcClient := canopy.NewClient(cfg.RootChainRPCURL)
-	worker := newblock.NewWorker(workerConfig, rpcClient, chainRepo, virtualPoolRepo, userRepo)
+	defer rootChainRouter.Stop()
 
-	// Start worker in background
-	if err := worker.Start(); err != nil {
-		log.Fatalf("Failed to start root chain worker: %v", err)
+	for _, rootChain := range cfg.RootChains {
+		log.Printf("Started root chain worker (ChainID: %d, URL: %s)", rootChain.ID, rootChain.URL)
 	}
-	defer worker.Stop()
 
-	log.Printf("Started root chain worker (ChainID: %d, URL: %s)", cfg.RootChainID, cfg.RootChainURL)
+	for _, payoutWorker := range payoutWorkers {
+		if err := payoutWorker.Start(); err != nil {
+			log.Fatalf("Failed to start payout worker: %v", err)
+		}
+		defer payoutWorker.Stop()
+	}
+
+	log.Printf("Started %d payout worker(s) (interval: %v)", len(payoutWorkers), payoutConfig.Interval)
+
+	// Initialize and start dead-letter worker, which retries failed deposits and orders
+	deadLetterConfig := deadletter.DefaultConfig()
+	deadLetterWorker := deadletter.NewWorker(deadLetterService, deadLetterConfig)
+
+	if err := deadLetterWorker.Start(); err != nil {
+		log.Fatalf("Failed to start dead-letter worker: %v", err)
+	}
+	defer deadLetterWorker.Stop()
+
+	log.Printf("Started dead-letter worker (interval: %v)", deadLetterConfig.Interval)
 
 	// Initialize and start session cleanup worker
 	cleanupConfig := sessioncleanup.DefaultConfig()
@@ -92,7 +190,8 @@ func main() {
 
 	// Initialize and start fake volume worker
 	fakeVolumeConfig := fakevolume.DefaultConfig()
-	fakeVolumeWorker := fakevolume.NewWorker(chainRepo, virtualPoolRepo, userRepo, fakeVolumeConfig)
+	fakeVolumeWorker := fakevolume.NewWorker(chainRepo, virtualPoolRepo, userRepo, tradeEngine, fakeVolumeConfig)
+	fakeVolumeWorker.SetGraduationNotifier(graduationWorker)
 
 	if err := fakeVolumeWorker.Start(); err != nil {
 		log.Fatalf("Failed to start fake volume worker: %v", err)
@@ -124,8 +223,16 @@ func main() {
 	select {
 	case <-sigChan:
 		log.Println("Received shutdown signal, cleaning up...")
-		if err := worker.Stop(); err != nil {
-			log.Printf("Error stopping root chain worker: %v", err)
+		if err := rootChainRouter.Stop(); err != nil {
+			log.Printf("Error stopping root chain workers: %v", err)
+		}
+		for _, payoutWorker := range payoutWorkers {
+			if err := payoutWorker.Stop(); err != nil {
+				log.Printf("Error stopping payout worker: %v", err)
+			}
+		}
+		if err := deadLetterWorker.Stop(); err != nil {
+			log.Printf("Error stopping dead-letter worker: %v", err)
 		}
 		if err := cleanupWorker.Stop(); err != nil {
 			log.Printf("Error stopping session cleanup worker: %v", err)
@@ -133,6 +240,9 @@ func main() {
 		if err := fakeVolumeWorker.Stop(); err != nil {
 			log.Printf("Error stopping fake volume worker: %v", err)
 		}
+		if err := graduationWorker.Stop(); err != nil {
+			log.Printf("Error stopping graduation worker: %v", err)
+		}
 	case err := <-errChan:
 		log.Fatalf("Server failed to start: %v", err)
 	}
@@ -0,0 +1,21 @@
+-- Create "chain_graduations" table
+CREATE TABLE "chain_graduations" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "chain_id" uuid NOT NULL,
+  "current_step" character varying(30) NOT NULL,
+  "genesis_file" text NULL,
+  "genesis_hash" character varying(66) NULL,
+  "attempts" integer NOT NULL DEFAULT 0,
+  "last_error" text NULL,
+  "last_attempt_at" timestamptz NULL,
+  "next_retry_at" timestamptz NULL,
+  "completed_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "chain_graduations_chain_id_key" UNIQUE ("chain_id"),
+  CONSTRAINT "chain_graduations_chain_id_fkey" FOREIGN KEY ("chain_id") REFERENCES "chains" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "chain_graduations_current_step_check" CHECK ((current_step)::text = ANY ((ARRAY['threshold_reached'::character varying, 'genesis_generated'::character varying, 'genesis_hash_recorded'::character varying, 'deployment_requested'::character varying, 'deployment_confirmed'::character varying, 'pool_migrated'::character varying])::text[]))
+);
+-- Create index "idx_graduations_pending" to table: "chain_graduations"
+CREATE INDEX "idx_graduations_pending" ON "chain_graduations" ("next_retry_at") WHERE (completed_at IS NULL);
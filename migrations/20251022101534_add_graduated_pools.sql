@@ -0,0 +1,91 @@
+-- Create "graduated_pools" table
+CREATE TABLE "graduated_pools" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "chain_id" uuid NOT NULL,
+  "cnpy_reserve" numeric(15,8) NOT NULL DEFAULT 0,
+  "token_reserve" bigint NOT NULL DEFAULT 0,
+  "current_price_cnpy" numeric(15,8) NOT NULL DEFAULT 0,
+  "market_cap_usd" numeric(15,2) NOT NULL DEFAULT 0,
+  "total_volume_cnpy" numeric(15,8) NOT NULL DEFAULT 0,
+  "total_transactions" integer NOT NULL DEFAULT 0,
+  "unique_traders" integer NOT NULL DEFAULT 0,
+  "is_active" boolean NOT NULL DEFAULT true,
+  "price_24h_change_percent" numeric(8,4) NULL DEFAULT 0,
+  "volume_24h_cnpy" numeric(15,8) NULL DEFAULT 0,
+  "high_24h_cnpy" numeric(15,8) NULL DEFAULT 0,
+  "low_24h_cnpy" numeric(15,8) NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "graduated_pools_chain_id_key" UNIQUE ("chain_id"),
+  CONSTRAINT "graduated_pools_chain_id_fkey" FOREIGN KEY ("chain_id") REFERENCES "chains" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_graduated_pools_active" to table: "graduated_pools"
+CREATE INDEX "idx_graduated_pools_active" ON "graduated_pools" ("is_active");
+-- Create index "idx_graduated_pools_market_cap" to table: "graduated_pools"
+CREATE INDEX "idx_graduated_pools_market_cap" ON "graduated_pools" ("market_cap_usd" DESC);
+-- Create "user_graduated_positions" table
+CREATE TABLE "user_graduated_positions" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "chain_id" uuid NOT NULL,
+  "graduated_pool_id" uuid NOT NULL,
+  "token_balance" bigint NOT NULL DEFAULT 0,
+  "total_cnpy_invested" numeric(15,8) NOT NULL DEFAULT 0,
+  "total_cnpy_withdrawn" numeric(15,8) NOT NULL DEFAULT 0,
+  "average_entry_price_cnpy" numeric(15,8) NOT NULL DEFAULT 0,
+  "unrealized_pnl_cnpy" numeric(15,8) NULL DEFAULT 0,
+  "realized_pnl_cnpy" numeric(15,8) NULL DEFAULT 0,
+  "total_return_percent" numeric(8,4) NULL DEFAULT 0,
+  "is_active" boolean NOT NULL DEFAULT true,
+  "first_purchase_at" timestamptz NULL,
+  "last_activity_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "user_graduated_positions_user_id_chain_id_key" UNIQUE ("user_id", "chain_id"),
+  CONSTRAINT "user_graduated_positions_chain_id_fkey" FOREIGN KEY ("chain_id") REFERENCES "chains" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "user_graduated_positions_graduated_pool_id_fkey" FOREIGN KEY ("graduated_pool_id") REFERENCES "graduated_pools" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "user_graduated_positions_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+-- Create index "idx_graduated_positions_active" to table: "user_graduated_positions"
+CREATE INDEX "idx_graduated_positions_active" ON "user_graduated_positions" ("is_active");
+-- Create index "idx_graduated_positions_chain" to table: "user_graduated_positions"
+CREATE INDEX "idx_graduated_positions_chain" ON "user_graduated_positions" ("chain_id");
+-- Create index "idx_graduated_positions_user" to table: "user_graduated_positions"
+CREATE INDEX "idx_graduated_positions_user" ON "user_graduated_positions" ("user_id");
+-- Create "graduated_pool_transactions" table
+CREATE TABLE "graduated_pool_transactions" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "graduated_pool_id" uuid NOT NULL,
+  "chain_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "transaction_type" character varying(20) NOT NULL,
+  "cnpy_amount" numeric(15,8) NOT NULL,
+  "token_amount" bigint NOT NULL,
+  "price_per_token_cnpy" numeric(15,8) NOT NULL,
+  "trading_fee_cnpy" numeric(15,8) NOT NULL DEFAULT 0,
+  "slippage_percent" numeric(8,4) NULL DEFAULT 0,
+  "transaction_hash" character varying(66) NULL,
+  "block_height" bigint NULL,
+  "gas_used" integer NULL,
+  "pool_cnpy_reserve_after" numeric(15,8) NOT NULL,
+  "pool_token_reserve_after" bigint NOT NULL,
+  "market_cap_after_usd" numeric(15,2) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "graduated_pool_transactions_chain_id_fkey" FOREIGN KEY ("chain_id") REFERENCES "chains" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "graduated_pool_transactions_graduated_pool_id_fkey" FOREIGN KEY ("graduated_pool_id") REFERENCES "graduated_pools" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "graduated_pool_transactions_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "graduated_pool_transactions_transaction_type_check" CHECK ((transaction_type)::text = ANY ((ARRAY['buy'::character varying, 'sell'::character varying, 'liquidity_deposit'::character varying, 'liquidity_withdrawal'::character varying])::text[]))
+);
+-- Create index "idx_gp_transactions_chain" to table: "graduated_pool_transactions"
+CREATE INDEX "idx_gp_transactions_chain" ON "graduated_pool_transactions" ("chain_id");
+-- Create index "idx_gp_transactions_pool" to table: "graduated_pool_transactions"
+CREATE INDEX "idx_gp_transactions_pool" ON "graduated_pool_transactions" ("graduated_pool_id");
+-- Create index "idx_gp_transactions_time" to table: "graduated_pool_transactions"
+CREATE INDEX "idx_gp_transactions_time" ON "graduated_pool_transactions" ("created_at" DESC);
+-- Create index "idx_gp_transactions_type" to table: "graduated_pool_transactions"
+CREATE INDEX "idx_gp_transactions_type" ON "graduated_pool_transactions" ("transaction_type");
+-- Create index "idx_gp_transactions_user" to table: "graduated_pool_transactions"
+CREATE INDEX "idx_gp_transactions_user" ON "graduated_pool_transactions" ("user_id");
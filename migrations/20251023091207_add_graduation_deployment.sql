@@ -0,0 +1,2 @@
+-- Modify "chain_graduations" table
+ALTER TABLE "chain_graduations" ADD COLUMN "deployment_status" character varying(20) NULL, ADD COLUMN "deployment_rpc_url" character varying(500) NULL, ADD COLUMN "deployment_message" text NULL, ADD COLUMN "deployment_updated_at" timestamptz NULL, ADD CONSTRAINT "chain_graduations_deployment_status_check" CHECK ((deployment_status)::text = ANY ((ARRAY['provisioning'::character varying, 'running'::character varying, 'failed'::character varying])::text[]));
@@ -0,0 +1,6 @@
+-- Modify "chains" table
+ALTER TABLE "chains" ADD COLUMN "allocation_creator_bps" integer NOT NULL DEFAULT 0, ADD COLUMN "allocation_treasury_bps" integer NOT NULL DEFAULT 0, ADD COLUMN "allocation_liquidity_bps" integer NOT NULL DEFAULT 2000, ADD COLUMN "allocation_holders_bps" integer NOT NULL DEFAULT 8000;
+-- Give existing chains a holders bucket covering their virtual pool supply
+UPDATE "chains" SET "allocation_holders_bps" = LEAST(10000, CEIL(initial_token_supply * 10000.0 / token_total_supply)), "allocation_liquidity_bps" = 10000 - LEAST(10000, CEIL(initial_token_supply * 10000.0 / token_total_supply)) WHERE token_total_supply > 0;
+-- Modify "chains" table
+ALTER TABLE "chains" ADD CONSTRAINT "chains_allocation_check" CHECK (((allocation_creator_bps >= 0) AND (allocation_treasury_bps >= 0) AND (allocation_liquidity_bps >= 0) AND (allocation_holders_bps >= 0) AND ((((allocation_creator_bps + allocation_treasury_bps) + allocation_liquidity_bps) + allocation_holders_bps) = 10000)));
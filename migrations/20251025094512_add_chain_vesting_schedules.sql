@@ -0,0 +1,16 @@
+-- Create "chain_vesting_schedules" table
+CREATE TABLE "chain_vesting_schedules" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "chain_id" uuid NOT NULL,
+  "beneficiary_address" character varying(42) NOT NULL,
+  "allocation_bps" integer NOT NULL,
+  "cliff_days" integer NOT NULL DEFAULT 0,
+  "duration_days" integer NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "chain_vesting_schedules_chain_id_fkey" FOREIGN KEY ("chain_id") REFERENCES "chains" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "chain_vesting_schedules_allocation_bps_check" CHECK ((allocation_bps > 0) AND (allocation_bps <= 10000)),
+  CONSTRAINT "chain_vesting_schedules_check" CHECK ((cliff_days >= 0) AND (duration_days > 0) AND (cliff_days <= duration_days))
+);
+-- Create index "idx_vesting_schedules_chain" to table: "chain_vesting_schedules"
+CREATE INDEX "idx_vesting_schedules_chain" ON "chain_vesting_schedules" ("chain_id");
@@ -0,0 +1,2 @@
+-- Create index "idx_vp_transactions_hash_height" to table: "virtual_pool_transactions"
+CREATE UNIQUE INDEX "idx_vp_transactions_hash_height" ON "virtual_pool_transactions" ("transaction_hash", "block_height") WHERE (transaction_hash IS NOT NULL);
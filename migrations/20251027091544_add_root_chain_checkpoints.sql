@@ -0,0 +1,8 @@
+-- Create "root_chain_checkpoints" table
+CREATE TABLE "root_chain_checkpoints" (
+  "root_chain_id" bigint NOT NULL,
+  "height" bigint NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("root_chain_id"),
+  CONSTRAINT "root_chain_checkpoints_height_check" CHECK (height >= 0)
+);
@@ -0,0 +1,22 @@
+-- Create "pending_deposits" table
+CREATE TABLE "pending_deposits" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "root_chain_id" bigint NOT NULL,
+  "chain_id" uuid NOT NULL,
+  "sender_address" character varying(42) NOT NULL,
+  "amount" bigint NOT NULL,
+  "transaction_hash" character varying(66) NOT NULL,
+  "block_height" bigint NOT NULL,
+  "final_height" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "pending_deposits_chain_id_fkey" FOREIGN KEY ("chain_id") REFERENCES "chains" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "pending_deposits_amount_check" CHECK (amount > 0),
+  CONSTRAINT "pending_deposits_check" CHECK (final_height >= block_height)
+);
+-- Create index "idx_pending_deposits_chain" to table: "pending_deposits"
+CREATE INDEX "idx_pending_deposits_chain" ON "pending_deposits" ("chain_id");
+-- Create index "idx_pending_deposits_hash_height" to table: "pending_deposits"
+CREATE UNIQUE INDEX "idx_pending_deposits_hash_height" ON "pending_deposits" ("transaction_hash", "block_height");
+-- Create index "idx_pending_deposits_root_chain_height" to table: "pending_deposits"
+CREATE INDEX "idx_pending_deposits_root_chain_height" ON "pending_deposits" ("root_chain_id", "block_height");
@@ -0,0 +1,29 @@
+-- Create "payouts" table
+CREATE TABLE "payouts" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "chain_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "virtual_pool_transaction_id" uuid NULL,
+  "payout_type" character varying(20) NOT NULL,
+  "recipient_address" character varying(42) NOT NULL,
+  "amount" bigint NOT NULL,
+  "reference" character varying(66) NOT NULL,
+  "status" character varying(20) NOT NULL DEFAULT 'pending',
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "payouts_chain_id_fkey" FOREIGN KEY ("chain_id") REFERENCES "chains" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "payouts_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "payouts_virtual_pool_transaction_id_fkey" FOREIGN KEY ("virtual_pool_transaction_id") REFERENCES "virtual_pool_transactions" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "payouts_amount_check" CHECK (amount > 0),
+  CONSTRAINT "payouts_payout_type_check" CHECK ((payout_type)::text = ANY ((ARRAY['sell_proceeds'::character varying])::text[])),
+  CONSTRAINT "payouts_status_check" CHECK ((status)::text = ANY ((ARRAY['pending'::character varying, 'submitted'::character varying, 'confirmed'::character varying, 'failed'::character varying])::text[]))
+);
+-- Create index "idx_payouts_chain" to table: "payouts"
+CREATE INDEX "idx_payouts_chain" ON "payouts" ("chain_id");
+-- Create index "idx_payouts_reference" to table: "payouts"
+CREATE UNIQUE INDEX "idx_payouts_reference" ON "payouts" ("reference");
+-- Create index "idx_payouts_status" to table: "payouts"
+CREATE INDEX "idx_payouts_status" ON "payouts" ("status", "created_at");
+-- Create index "idx_payouts_user" to table: "payouts"
+CREATE INDEX "idx_payouts_user" ON "payouts" ("user_id");
@@ -0,0 +1,8 @@
+-- Modify "payouts" table
+ALTER TABLE "payouts" DROP CONSTRAINT "payouts_payout_type_check", ADD CONSTRAINT "payouts_payout_type_check" CHECK ((payout_type)::text = ANY ((ARRAY['sell_proceeds'::character varying, 'inactive_refund'::character varying, 'cap_refund'::character varying])::text[])), ADD COLUMN "tx_hash" character varying(66) NULL, ADD COLUMN "signed_tx" text NULL, ADD COLUMN "tx_created_height" bigint NULL, ADD COLUMN "confirmed_height" bigint NULL, ADD COLUMN "attempts" integer NOT NULL DEFAULT 0, ADD COLUMN "last_error" text NULL, ADD COLUMN "next_attempt_at" timestamptz NOT NULL DEFAULT now();
+-- Drop index "idx_payouts_status" from table: "payouts"
+DROP INDEX "idx_payouts_status";
+-- Create index "idx_payouts_status" to table: "payouts"
+CREATE INDEX "idx_payouts_status" ON "payouts" ("status", "next_attempt_at");
+-- Create index "idx_payouts_tx_hash" to table: "payouts"
+CREATE INDEX "idx_payouts_tx_hash" ON "payouts" ("tx_hash");
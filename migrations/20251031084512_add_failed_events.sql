@@ -0,0 +1,26 @@
+-- Create "failed_events" table
+CREATE TABLE "failed_events" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "event_type" character varying(20) NOT NULL,
+  "reference" character varying(66) NOT NULL,
+  "chain_id" uuid NULL,
+  "block_height" bigint NULL,
+  "raw_event" text NOT NULL,
+  "status" character varying(20) NOT NULL DEFAULT 'pending',
+  "attempts" integer NOT NULL DEFAULT 1,
+  "last_error" text NOT NULL,
+  "next_retry_at" timestamptz NOT NULL DEFAULT now(),
+  "resolved_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "failed_events_chain_id_fkey" FOREIGN KEY ("chain_id") REFERENCES "chains" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "failed_events_event_type_check" CHECK ((event_type)::text = ANY ((ARRAY['deposit'::character varying, 'order'::character varying])::text[])),
+  CONSTRAINT "failed_events_status_check" CHECK ((status)::text = ANY ((ARRAY['pending'::character varying, 'failed'::character varying, 'resolved'::character varying, 'discarded'::character varying])::text[]))
+);
+-- Create index "idx_failed_events_chain" to table: "failed_events"
+CREATE INDEX "idx_failed_events_chain" ON "failed_events" ("chain_id");
+-- Create index "idx_failed_events_reference" to table: "failed_events"
+CREATE UNIQUE INDEX "idx_failed_events_reference" ON "failed_events" ("event_type", "reference");
+-- Create index "idx_failed_events_status" to table: "failed_events"
+CREATE INDEX "idx_failed_events_status" ON "failed_events" ("status", "next_retry_at");
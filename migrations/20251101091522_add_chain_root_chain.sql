@@ -0,0 +1,4 @@
+-- Modify "chains" table
+ALTER TABLE "chains" ADD COLUMN "root_chain_id" bigint NOT NULL DEFAULT 1;
+-- Create index "idx_chains_root_chain" to table: "chains"
+CREATE INDEX "idx_chains_root_chain" ON "chains" ("root_chain_id");
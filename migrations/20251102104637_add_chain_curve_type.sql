@@ -0,0 +1,2 @@
+-- Modify "chains" table
+ALTER TABLE "chains" ADD COLUMN "curve_type" character varying(20) NOT NULL DEFAULT 'constant_product', ADD COLUMN "curve_max_price" numeric(20,8) NULL, ADD COLUMN "curve_midpoint_supply" bigint NULL, ADD CONSTRAINT "chains_curve_type_check" CHECK ((curve_type)::text = ANY ((ARRAY['constant_product'::character varying, 'linear'::character varying, 'exponential'::character varying, 'capped_sigmoid'::character varying])::text[]));
@@ -0,0 +1,12 @@
+-- Modify "chains" table
+ALTER TABLE "chains" ADD COLUMN "token_decimals" smallint NOT NULL DEFAULT 6, ADD CONSTRAINT "chains_token_decimals_check" CHECK ((token_decimals >= 0) AND (token_decimals <= 6));
+-- Modify "virtual_pools" table
+ALTER TABLE "virtual_pools" ADD COLUMN "cnpy_reserve_ucnpy" numeric(30,0) NOT NULL DEFAULT 0, ADD COLUMN "token_reserve_units" numeric(30,0) NOT NULL DEFAULT 0;
+-- Modify "virtual_pool_transactions" table
+ALTER TABLE "virtual_pool_transactions" ADD COLUMN "cnpy_amount_ucnpy" numeric(30,0) NOT NULL DEFAULT 0, ADD COLUMN "token_amount_units" numeric(30,0) NOT NULL DEFAULT 0, ADD COLUMN "pool_cnpy_reserve_after_ucnpy" numeric(30,0) NOT NULL DEFAULT 0, ADD COLUMN "pool_token_reserve_after_units" numeric(30,0) NOT NULL DEFAULT 0;
+-- Modify "user_virtual_positions" table
+ALTER TABLE "user_virtual_positions" ADD COLUMN "token_balance_units" numeric(30,0) NOT NULL DEFAULT 0, ADD COLUMN "total_cnpy_invested_ucnpy" numeric(30,0) NOT NULL DEFAULT 0, ADD COLUMN "total_cnpy_withdrawn_ucnpy" numeric(30,0) NOT NULL DEFAULT 0;
+-- Backfill base unit amounts from the existing CNPY and whole token amounts
+UPDATE "virtual_pools" vp SET "cnpy_reserve_ucnpy" = round(vp.cnpy_reserve * 1000000), "token_reserve_units" = vp.token_reserve * power(10::numeric, c.token_decimals) FROM "chains" c WHERE c.id = vp.chain_id;
+UPDATE "virtual_pool_transactions" t SET "cnpy_amount_ucnpy" = round(t.cnpy_amount * 1000000), "token_amount_units" = t.token_amount * power(10::numeric, c.token_decimals), "pool_cnpy_reserve_after_ucnpy" = round(t.pool_cnpy_reserve_after * 1000000), "pool_token_reserve_after_units" = t.pool_token_reserve_after * power(10::numeric, c.token_decimals) FROM "chains" c WHERE c.id = t.chain_id;
+UPDATE "user_virtual_positions" p SET "token_balance_units" = p.token_balance * power(10::numeric, c.token_decimals), "total_cnpy_invested_ucnpy" = round(p.total_cnpy_invested * 1000000), "total_cnpy_withdrawn_ucnpy" = round(p.total_cnpy_withdrawn * 1000000) FROM "chains" c WHERE c.id = p.chain_id;
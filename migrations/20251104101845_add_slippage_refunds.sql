@@ -0,0 +1,2 @@
+-- Modify "payouts" table
+ALTER TABLE "payouts" DROP CONSTRAINT "payouts_payout_type_check", ADD CONSTRAINT "payouts_payout_type_check" CHECK ((payout_type)::text = ANY ((ARRAY['sell_proceeds'::character varying, 'inactive_refund'::character varying, 'cap_refund'::character varying, 'slippage_refund'::character varying])::text[]));
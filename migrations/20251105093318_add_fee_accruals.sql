@@ -0,0 +1,27 @@
+-- Create "fee_accruals" table
+CREATE TABLE "fee_accruals" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "chain_id" uuid NOT NULL,
+  "virtual_pool_transaction_id" uuid NOT NULL,
+  "recipient_type" character varying(20) NOT NULL,
+  "recipient_user_id" uuid NULL,
+  "amount" bigint NOT NULL,
+  "payout_id" uuid NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fee_accruals_chain_id_fkey" FOREIGN KEY ("chain_id") REFERENCES "chains" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "fee_accruals_payout_id_fkey" FOREIGN KEY ("payout_id") REFERENCES "payouts" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "fee_accruals_recipient_user_id_fkey" FOREIGN KEY ("recipient_user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "fee_accruals_virtual_pool_transaction_id_fkey" FOREIGN KEY ("virtual_pool_transaction_id") REFERENCES "virtual_pool_transactions" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "fee_accruals_amount_check" CHECK (amount > 0),
+  CONSTRAINT "fee_accruals_recipient_check" CHECK (((recipient_type)::text = 'protocol'::text) = (recipient_user_id IS NULL)),
+  CONSTRAINT "fee_accruals_recipient_type_check" CHECK ((recipient_type)::text = ANY ((ARRAY['protocol'::character varying, 'creator'::character varying, 'referrer'::character varying])::text[]))
+);
+-- Create index "idx_fee_accruals_chain" to table: "fee_accruals"
+CREATE INDEX "idx_fee_accruals_chain" ON "fee_accruals" ("chain_id");
+-- Create index "idx_fee_accruals_claimable" to table: "fee_accruals"
+CREATE INDEX "idx_fee_accruals_claimable" ON "fee_accruals" ("recipient_type", "recipient_user_id") WHERE (payout_id IS NULL);
+-- Create index "idx_fee_accruals_recipient" to table: "fee_accruals"
+CREATE INDEX "idx_fee_accruals_recipient" ON "fee_accruals" ("recipient_user_id");
+-- Create index "idx_fee_accruals_transaction" to table: "fee_accruals"
+CREATE INDEX "idx_fee_accruals_transaction" ON "fee_accruals" ("virtual_pool_transaction_id");
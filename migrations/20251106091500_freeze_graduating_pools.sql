@@ -0,0 +1,2 @@
+-- Stop trading on virtual pools whose chain has started graduating
+UPDATE "virtual_pools" SET "is_active" = false, "updated_at" = CURRENT_TIMESTAMP WHERE "chain_id" IN (SELECT "chain_id" FROM "chain_graduations") AND "is_active";
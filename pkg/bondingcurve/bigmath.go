@@ -0,0 +1,129 @@
+package bondingcurve
+
+import "math/big"
+
+// The supply curves' shapes are evaluated in big.Float when trades are priced
+// in base units. math/big has no exponential or logarithm, so the functions
+// here supply them, working at Precision plus guardBits so their results are
+// accurate to well within the slack the pricing leaves for them.
+
+// guardBits is the precision the functions below carry beyond Precision
+const guardBits = 64
+
+var (
+	bigOne = big.NewFloat(1)
+	bigTwo = big.NewFloat(2)
+
+	// bigLn2 is ln 2 = 2 atanh(1/3)
+	bigLn2 = func() *big.Float {
+		third := newWorkingFloat().Quo(bigOne, big.NewFloat(3))
+		ln2 := atanhSeries(third)
+		return ln2.Mul(ln2, bigTwo)
+	}()
+)
+
+// newWorkingFloat returns a zero big.Float at the precision the functions
+// below work at
+func newWorkingFloat() *big.Float {
+	return new(big.Float).SetPrec(Precision + guardBits)
+}
+
+// negligible reports whether term no longer changes sum at the working precision
+func negligible(term, sum *big.Float) bool {
+	return term.Sign() == 0 || (sum.Sign() != 0 && term.MantExp(nil) < sum.MantExp(nil)-Precision-guardBits)
+}
+
+// bigExp returns e^x
+func bigExp(x *big.Float) *big.Float {
+	// e^x = (e^(x/2^n))^(2^n), with x/2^n below 2^-8 so the series converges quickly
+	n := 0
+	if exp := x.MantExp(nil); exp > -8 {
+		n = exp + 8
+	}
+	result := expm1Series(newWorkingFloat().SetMantExp(x, -n))
+	result.Add(result, bigOne)
+	for i := 0; i < n; i++ {
+		result.Mul(result, result)
+	}
+	return result
+}
+
+// bigExpm1 returns e^x - 1, accurately for x near zero
+func bigExpm1(x *big.Float) *big.Float {
+	if x.MantExp(nil) < 0 {
+		return expm1Series(x)
+	}
+	result := bigExp(x)
+	return result.Sub(result, bigOne)
+}
+
+// expm1Series sums x + x^2/2! + x^3/3! + ..., which converges quickly for
+// small |x|
+func expm1Series(x *big.Float) *big.Float {
+	sum := newWorkingFloat()
+	term := newWorkingFloat().Set(x)
+	for k := 2; !negligible(term, sum); k++ {
+		sum.Add(sum, term)
+		term.Mul(term, x)
+		term.Quo(term, big.NewFloat(float64(k)))
+	}
+	return sum
+}
+
+// bigLog returns ln y for y > 0
+func bigLog(y *big.Float) *big.Float {
+	// y = m * 2^exp with 1/2 <= m < 1, and ln m = 2 atanh((m - 1) / (m + 1))
+	mant := newWorkingFloat()
+	exp := y.MantExp(mant)
+	z := newWorkingFloat().Sub(mant, bigOne)
+	z.Quo(z, newWorkingFloat().Add(mant, bigOne))
+
+	result := atanhSeries(z)
+	result.Mul(result, bigTwo)
+	return result.Add(result, newWorkingFloat().Mul(bigLn2, big.NewFloat(float64(exp))))
+}
+
+// bigLog1p returns ln(1 + x) for x > -1, accurately for x near zero
+func bigLog1p(x *big.Float) *big.Float {
+	if x.Sign() != 0 && x.MantExp(nil) >= 0 {
+		return bigLog(newWorkingFloat().Add(x, bigOne))
+	}
+
+	// ln(1 + x) = 2 atanh(x / (2 + x))
+	z := newWorkingFloat().Add(x, bigTwo)
+	z.Quo(x, z)
+	result := atanhSeries(z)
+	return result.Mul(result, bigTwo)
+}
+
+// atanhSeries sums z + z^3/3 + z^5/5 + ..., which converges quickly for
+// |z| <= 1/3
+func atanhSeries(z *big.Float) *big.Float {
+	sum := newWorkingFloat()
+	z2 := newWorkingFloat().Mul(z, z)
+	power := newWorkingFloat().Set(z)
+	term := newWorkingFloat()
+	for k := 1; !negligible(power, sum); k += 2 {
+		term.Quo(power, big.NewFloat(float64(k)))
+		sum.Add(sum, term)
+		power.Mul(power, z2)
+	}
+	return sum
+}
+
+// bigSoftplus returns ln(1 + e^x)
+func bigSoftplus(x *big.Float) *big.Float {
+	negAbs := newWorkingFloat().Abs(x)
+	result := bigLog1p(bigExp(negAbs.Neg(negAbs)))
+	if x.Sign() > 0 {
+		result.Add(result, x)
+	}
+	return result
+}
+
+// bigSoftplusInverse returns ln(e^y - 1) = y + ln(1 - e^-y) for y > 0
+func bigSoftplusInverse(y *big.Float) *big.Float {
+	complement := bigExpm1(newWorkingFloat().Neg(y))
+	result := bigLog(complement.Neg(complement))
+	return result.Add(result, y)
+}
@@ -0,0 +1,58 @@
+package bondingcurve
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestBigMath(t *testing.T) {
+	inputs := []float64{-40, -3.5, -1, -0.3, -1e-9, 1e-12, 0.25, 0.5, 1, 2.75, 30}
+
+	t.Run("agrees with math", func(t *testing.T) {
+		check := func(what string, x float64, got *big.Float, want float64) {
+			t.Helper()
+			if g, _ := got.Float64(); math.Abs(g-want) > 1e-15*math.Max(math.Abs(want), 1) {
+				t.Errorf("expected %s(%v) of %v, got %v", what, x, want, g)
+			}
+		}
+		for _, x := range inputs {
+			bx := big.NewFloat(x)
+			check("exp", x, bigExp(bx), math.Exp(x))
+			check("expm1", x, bigExpm1(bx), math.Expm1(x))
+			check("softplus", x, bigSoftplus(bx), softplus(x))
+			if x > -1 {
+				check("log1p", x, bigLog1p(bx), math.Log1p(x))
+			}
+			if x > 0 {
+				check("log", x, bigLog(bx), math.Log(x))
+				check("softplus inverse", x, bigSoftplusInverse(bx), math.Log(math.Expm1(x)))
+			}
+		}
+	})
+
+	t.Run("log inverts exp beyond float64", func(t *testing.T) {
+		for _, x := range inputs {
+			bx := big.NewFloat(x)
+			diff := newWorkingFloat().Sub(bigLog(bigExp(bx)), bx)
+			if diff.Sign() != 0 && diff.MantExp(nil) > bx.MantExp(nil)-Precision+8 {
+				t.Errorf("log(exp(%v)) is off by %s", x, diff.Text('g', 5))
+			}
+
+			diff = newWorkingFloat().Sub(bigLog1p(bigExpm1(bx)), bx)
+			if diff.Sign() != 0 && diff.MantExp(nil) > bx.MantExp(nil)-Precision+8 {
+				t.Errorf("log1p(expm1(%v)) is off by %s", x, diff.Text('g', 5))
+			}
+		}
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		zero := new(big.Float)
+		if bigExpm1(zero).Sign() != 0 || bigLog1p(zero).Sign() != 0 {
+			t.Error("expected expm1(0) and log1p(0) to be exactly zero")
+		}
+		if bigExp(zero).Cmp(bigOne) != 0 || bigLog(bigOne).Sign() != 0 {
+			t.Error("expected exp(0) = 1 and log(1) = 0")
+		}
+	})
+}
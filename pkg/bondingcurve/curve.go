@@ -1,10 +1,33 @@
 package bondingcurve
 
 import (
+	"fmt"
 	"math/big"
 )
 
-// BondingCurve implements the sum-product bonding curve mechanics
+// NewCurve creates the curve of the family params select. A nil config uses
+// the default fee and initial price; an empty type is a constant-product curve.
+func NewCurve(params CurveParams, config *BondingCurveConfig) (Curve, error) {
+	if config == nil {
+		config = NewBondingCurveConfig()
+	}
+
+	switch params.Type {
+	case "", CurveConstantProduct:
+		return NewBondingCurve(config), nil
+	case CurveLinear:
+		return NewLinearCurve(params.Slope, config)
+	case CurveExponential:
+		return NewExponentialCurve(params.Slope, config)
+	case CurveCappedSigmoid:
+		return NewCappedSigmoidCurve(params.MaxPrice, params.Slope, params.MidpointSupply, config)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCurve, params.Type)
+	}
+}
+
+// BondingCurve implements the sum-product bonding curve mechanics. It is the
+// constant-product curve family.
 type BondingCurve struct {
 	config *BondingCurveConfig
 }
@@ -19,9 +42,15 @@ func NewBondingCurve(config *BondingCurveConfig) *BondingCurve {
 	}
 }
 
+// Type reports that this is a constant-product curve
+func (bc *BondingCurve) Type() CurveType {
+	return CurveConstantProduct
+}
+
 // Buy executes a buy transaction (minting tokens)
-// Pump.fun sum-style bonding curve formula: dY = (amountInWithFee * y) / (x + amountInWithFee)
-// Where x = CNPY reserve, y = token reserve, dY = tokens to mint
+// Pump.fun sum-style bonding curve formula: dY = (dX * y) / (x + dX)
+// Where x = CNPY reserve, y = token reserve, dY = tokens to mint and dX is
+// cnpyAmountIn less the fee, which is taken in CNPY and leaves the pool
 // Special case: When both reserves are 0, uses fixed initial price
 func (bc *BondingCurve) Buy(pool *VirtualPool, cnpyAmountIn *big.Float) (*TradeResult, error) {
 	if err := pool.Validate(); err != nil {
@@ -36,39 +65,42 @@ func (bc *BondingCurve) Buy(pool *VirtualPool, cnpyAmountIn *big.Float) (*TradeR
 	x := pool.CNPYReserve  // CNPY reserve
 	y := pool.TokenReserve // Token reserve
 
-	var tokensBeforeFee *big.Float
+	// Apply fee to input (less CNPY reaches the curve)
+	cnpyToCurve := bc.config.ApplyFee(cnpyAmountIn)
+
+	var tokensOut *big.Float
 	var priceBefore *big.Float
 
 	// Special case: Pool starts at 0, use fixed initial price
 	if x.Sign() == 0 && y.Sign() == 0 {
-		// tokensOut = cnpyAmountIn / initialPrice
-		tokensBeforeFee = new(big.Float).Quo(cnpyAmountIn, bc.config.InitialPrice)
+		// tokensOut = cnpyToCurve / initialPrice
+		tokensOut = new(big.Float).Quo(cnpyToCurve, bc.config.InitialPrice)
 		priceBefore = new(big.Float).Copy(bc.config.InitialPrice)
 	} else {
 		// Calculate price before trade for price impact
 		priceBefore = pool.CurrentPrice()
 
-		// Calculate tokens using full input: dY = (cnpyAmountIn * y) / (x + cnpyAmountIn)
-		// Numerator: cnpyAmountIn * y
-		numerator := new(big.Float).Mul(cnpyAmountIn, y)
+		// Calculate tokens: dY = (cnpyToCurve * y) / (x + cnpyToCurve)
+		// Numerator: cnpyToCurve * y
+		numerator := new(big.Float).Mul(cnpyToCurve, y)
 
-		// Denominator: x + cnpyAmountIn
-		denominator := new(big.Float).Add(x, cnpyAmountIn)
+		// Denominator: x + cnpyToCurve
+		denominator := new(big.Float).Add(x, cnpyToCurve)
 
 		// Prevent division by zero
 		if denominator.Sign() == 0 {
 			return nil, ErrInsufficientReserve
 		}
 
-		// Calculate tokens before fee
-		tokensBeforeFee = new(big.Float).Quo(numerator, denominator)
+		tokensOut = new(big.Float).Quo(numerator, denominator)
 	}
 
-	// Apply fee to output (user receives fewer tokens)
-	tokensOut := bc.config.ApplyFee(tokensBeforeFee)
+	if tokensOut.Sign() == 0 {
+		return nil, ErrInvalidAmount
+	}
 
 	// Calculate new pool state
-	newCNPYReserve := new(big.Float).Add(x, cnpyAmountIn)             // Add full CNPY to reserve
+	newCNPYReserve := new(big.Float).Add(x, cnpyToCurve)              // Add CNPY less the fee to reserve
 	newTokenReserve := new(big.Float).Sub(y, tokensOut)               // Remove tokens from virtual pool (sold to user)
 	newTotalSupply := new(big.Float).Add(pool.TotalSupply, tokensOut) // Mint tokens to user
 
@@ -79,7 +111,9 @@ func (bc *BondingCurve) Buy(pool *VirtualPool, cnpyAmountIn *big.Float) (*TradeR
 	priceImpact := bc.calculatePriceImpact(priceBefore, effectivePrice)
 
 	return &TradeResult{
+		AmountIn:        new(big.Float).Copy(cnpyAmountIn),
 		AmountOut:       tokensOut,
+		Fee:             new(big.Float).Sub(cnpyAmountIn, cnpyToCurve),
 		NewCNPYReserve:  newCNPYReserve,
 		NewTokenReserve: newTokenReserve,
 		NewTotalSupply:  newTotalSupply,
@@ -153,7 +187,9 @@ func (bc *BondingCurve) Sell(pool *VirtualPool, tokenAmountIn *big.Float) (*Trad
 	priceImpact := bc.calculatePriceImpact(priceBefore, effectivePrice)
 
 	return &TradeResult{
+		AmountIn:        new(big.Float).Copy(tokenAmountIn),
 		AmountOut:       cnpyOutAfterFee,
+		Fee:             new(big.Float).Sub(cnpyOut, cnpyOutAfterFee),
 		NewCNPYReserve:  newCNPYReserve,
 		NewTokenReserve: newTokenReserve,
 		NewTotalSupply:  newTotalSupply,
@@ -174,6 +210,280 @@ func (bc *BondingCurve) SimulateSell(pool *VirtualPool, tokenAmountIn *big.Float
 	return bc.Sell(poolCopy, tokenAmountIn)
 }
 
+// BuyExactOut prices buying exactly tokenAmountOut tokens: the CNPY that
+// reaches the curve is dX = dY * x / (y - dY), and the buyer spends dX grossed
+// up by the fee. On an empty pool the tokens are priced at the initial price.
+func (bc *BondingCurve) BuyExactOut(pool *VirtualPool, tokenAmountOut *big.Float) (*TradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if tokenAmountOut == nil || tokenAmountOut.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	x := pool.CNPYReserve
+	y := pool.TokenReserve
+
+	var cnpyToCurve *big.Float
+	var priceBefore *big.Float
+	if x.Sign() == 0 && y.Sign() == 0 {
+		cnpyToCurve = new(big.Float).Mul(tokenAmountOut, bc.config.InitialPrice)
+		priceBefore = new(big.Float).Copy(bc.config.InitialPrice)
+	} else {
+		// The curve only approaches the whole token reserve
+		if tokenAmountOut.Cmp(y) >= 0 {
+			return nil, ErrInsufficientReserve
+		}
+		priceBefore = pool.CurrentPrice()
+		cnpyToCurve = new(big.Float).Mul(tokenAmountOut, x)
+		cnpyToCurve.Quo(cnpyToCurve, new(big.Float).Sub(y, tokenAmountOut))
+	}
+
+	cnpyAmountIn, err := bc.config.AmountBeforeFee(cnpyToCurve)
+	if err != nil {
+		return nil, err
+	}
+
+	effectivePrice := new(big.Float).Quo(cnpyAmountIn, tokenAmountOut)
+
+	return &TradeResult{
+		AmountIn:        cnpyAmountIn,
+		AmountOut:       new(big.Float).Copy(tokenAmountOut),
+		Fee:             new(big.Float).Sub(cnpyAmountIn, cnpyToCurve),
+		NewCNPYReserve:  new(big.Float).Add(x, cnpyToCurve),
+		NewTokenReserve: new(big.Float).Sub(y, tokenAmountOut),
+		NewTotalSupply:  new(big.Float).Add(pool.TotalSupply, tokenAmountOut),
+		Price:           effectivePrice,
+		PriceImpact:     bc.calculatePriceImpact(priceBefore, effectivePrice),
+	}, nil
+}
+
+// SellExactOut prices selling enough tokens to receive exactly cnpyAmountOut
+// CNPY, after the fee: the tokens sold are dY = dX * y / (x - dX), where dX is
+// cnpyAmountOut grossed up by the fee
+func (bc *BondingCurve) SellExactOut(pool *VirtualPool, cnpyAmountOut *big.Float) (*TradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cnpyAmountOut == nil || cnpyAmountOut.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	cnpyOut, err := bc.config.AmountBeforeFee(cnpyAmountOut)
+	if err != nil {
+		return nil, err
+	}
+
+	x := pool.CNPYReserve
+	y := pool.TokenReserve
+
+	// No number of tokens empties the CNPY reserve
+	if cnpyOut.Cmp(x) >= 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	tokenAmountIn := new(big.Float).Mul(cnpyOut, y)
+	tokenAmountIn.Quo(tokenAmountIn, new(big.Float).Sub(x, cnpyOut))
+	if tokenAmountIn.Cmp(pool.TotalSupply) > 0 {
+		return nil, ErrInsufficientTokens
+	}
+
+	effectivePrice := new(big.Float).Quo(cnpyAmountOut, tokenAmountIn)
+
+	return &TradeResult{
+		AmountIn:        tokenAmountIn,
+		AmountOut:       new(big.Float).Copy(cnpyAmountOut),
+		Fee:             new(big.Float).Sub(cnpyOut, cnpyAmountOut),
+		NewCNPYReserve:  new(big.Float).Sub(x, cnpyOut),
+		NewTokenReserve: new(big.Float).Add(y, tokenAmountIn),
+		NewTotalSupply:  new(big.Float).Sub(pool.TotalSupply, tokenAmountIn),
+		Price:           effectivePrice,
+		PriceImpact:     bc.calculatePriceImpact(pool.CurrentPrice(), effectivePrice),
+	}, nil
+}
+
+// SimulateBuyExactOut simulates BuyExactOut without modifying the pool state
+func (bc *BondingCurve) SimulateBuyExactOut(pool *VirtualPool, tokenAmountOut *big.Float) (*TradeResult, error) {
+	return bc.BuyExactOut(pool.Copy(), tokenAmountOut)
+}
+
+// SimulateSellExactOut simulates SellExactOut without modifying the pool state
+func (bc *BondingCurve) SimulateSellExactOut(pool *VirtualPool, cnpyAmountOut *big.Float) (*TradeResult, error) {
+	return bc.SellExactOut(pool.Copy(), cnpyAmountOut)
+}
+
+// BuyUnits is Buy in base units, with the fee taken in uCNPY before the rest of
+// cnpyAmountIn reaches the curve: dY = floor(dX * y / (x + dX)) for dX the
+// amount less the fee. Unlike Buy, it needs both reserves to be funded.
+func (bc *BondingCurve) BuyUnits(pool *IntPool, cnpyAmountIn *big.Int) (*IntTradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cnpyAmountIn == nil || cnpyAmountIn.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	x := pool.CNPYReserve
+	y := pool.TokenReserve
+
+	if x.Sign() == 0 || y.Sign() == 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	cnpyToCurve := bc.config.ApplyFeeUnits(cnpyAmountIn)
+	tokensOut := new(big.Int).Mul(cnpyToCurve, y)
+	tokensOut.Quo(tokensOut, new(big.Int).Add(x, cnpyToCurve))
+	if tokensOut.Sign() == 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	effectivePrice := pool.effectivePrice(cnpyAmountIn, tokensOut)
+	priceBefore := pool.SpotPrice()
+
+	return &IntTradeResult{
+		AmountIn:        new(big.Int).Set(cnpyAmountIn),
+		AmountOut:       tokensOut,
+		Fee:             new(big.Int).Sub(cnpyAmountIn, cnpyToCurve),
+		NewCNPYReserve:  new(big.Int).Add(x, cnpyToCurve),
+		NewTokenReserve: new(big.Int).Sub(y, tokensOut),
+		NewTotalSupply:  new(big.Int).Add(pool.TotalSupply, tokensOut),
+		Price:           effectivePrice,
+		PriceImpact:     priceImpact(priceBefore, effectivePrice),
+	}, nil
+}
+
+// SellUnits is Sell in base units: dX = floor(tokenAmountIn * x / (y + tokenAmountIn)),
+// of which the seller is paid all but the fee
+func (bc *BondingCurve) SellUnits(pool *IntPool, tokenAmountIn *big.Int) (*IntTradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if tokenAmountIn == nil || tokenAmountIn.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	if tokenAmountIn.Cmp(pool.TotalSupply) > 0 {
+		return nil, ErrInsufficientTokens
+	}
+
+	x := pool.CNPYReserve
+	y := pool.TokenReserve
+	if x.Sign() == 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	cnpyOut := new(big.Int).Mul(tokenAmountIn, x)
+	cnpyOut.Quo(cnpyOut, new(big.Int).Add(y, tokenAmountIn))
+
+	cnpyOutAfterFee := bc.config.ApplyFeeUnits(cnpyOut)
+	if cnpyOutAfterFee.Sign() == 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	effectivePrice := pool.effectivePrice(cnpyOutAfterFee, tokenAmountIn)
+
+	return &IntTradeResult{
+		AmountIn:        new(big.Int).Set(tokenAmountIn),
+		AmountOut:       cnpyOutAfterFee,
+		Fee:             new(big.Int).Sub(cnpyOut, cnpyOutAfterFee),
+		NewCNPYReserve:  new(big.Int).Sub(x, cnpyOut),
+		NewTokenReserve: new(big.Int).Add(y, tokenAmountIn),
+		NewTotalSupply:  new(big.Int).Sub(pool.TotalSupply, tokenAmountIn),
+		Price:           effectivePrice,
+		PriceImpact:     priceImpact(pool.SpotPrice(), effectivePrice),
+	}, nil
+}
+
+// BuyExactOutUnits inverts BuyUnits. The curve needs dX = ceil(dY * x / (y - dY))
+// uCNPY for dY tokens, and the buyer spends the least amount that leaves dX once
+// the fee is taken. The result is BuyUnits' for that amount, so AmountOut can
+// exceed tokenAmountOut where one uCNPY buys more than one base unit.
+func (bc *BondingCurve) BuyExactOutUnits(pool *IntPool, tokenAmountOut *big.Int) (*IntTradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if tokenAmountOut == nil || tokenAmountOut.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	x := pool.CNPYReserve
+	y := pool.TokenReserve
+
+	// The curve only approaches the whole token reserve
+	if x.Sign() == 0 || tokenAmountOut.Cmp(y) >= 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	cnpyToCurve := quoCeil(new(big.Int).Mul(tokenAmountOut, x), new(big.Int).Sub(y, tokenAmountOut))
+	cnpyAmountIn, err := bc.config.amountWithFeeUnits(cnpyToCurve)
+	if err != nil {
+		return nil, err
+	}
+	return bc.BuyUnits(pool, cnpyAmountIn)
+}
+
+// SellExactOutUnits inverts SellUnits. The seller is paid cnpyAmountOut once the
+// curve releases the least dX that leaves it after the fee, which takes
+// dY = ceil(dX * y / (x - dX)) tokens. The result is SellUnits' for dY.
+func (bc *BondingCurve) SellExactOutUnits(pool *IntPool, cnpyAmountOut *big.Int) (*IntTradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cnpyAmountOut == nil || cnpyAmountOut.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	cnpyOut, err := bc.config.amountWithFeeUnits(cnpyAmountOut)
+	if err != nil {
+		return nil, err
+	}
+
+	x := pool.CNPYReserve
+	y := pool.TokenReserve
+
+	// No number of tokens empties the CNPY reserve
+	if cnpyOut.Cmp(x) >= 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	tokenAmountIn := quoCeil(new(big.Int).Mul(cnpyOut, y), new(big.Int).Sub(x, cnpyOut))
+	if tokenAmountIn.Sign() == 0 {
+		tokenAmountIn.SetInt64(1)
+	}
+	if tokenAmountIn.Cmp(pool.TotalSupply) > 0 {
+		return nil, ErrInsufficientTokens
+	}
+	return bc.SellUnits(pool, tokenAmountIn)
+}
+
+// MarginalPrice is the ratio of the pool's reserves, or the initial price
+// before the pool is funded
+func (bc *BondingCurve) MarginalPrice(pool *IntPool) *big.Float {
+	if pool.CNPYReserve.Sign() == 0 || pool.TokenReserve.Sign() == 0 {
+		return new(big.Float).Copy(bc.config.InitialPrice)
+	}
+	return pool.SpotPrice()
+}
+
+// CheckInvariant checks that a trade kept x*y from falling. Rounding in
+// BuyUnits and SellUnits only ever raises it.
+func (bc *BondingCurve) CheckInvariant(before, after *IntPool) error {
+	if err := checkTrade(before, after); err != nil {
+		return err
+	}
+	k := new(big.Int).Mul(before.CNPYReserve, before.TokenReserve)
+	if new(big.Int).Mul(after.CNPYReserve, after.TokenReserve).Cmp(k) < 0 {
+		return fmt.Errorf("%w: x*y fell below %s", ErrInvariantViolated, k.String())
+	}
+	return nil
+}
+
 // GetAmountOut calculates the output amount for a given input (simulation only)
 func (bc *BondingCurve) GetAmountOut(pool *VirtualPool, amountIn *big.Float, isBuy bool) (*big.Float, error) {
 	if isBuy {
@@ -212,6 +522,11 @@ func (bc *BondingCurve) CalculateSlippage(pool *VirtualPool, amountIn *big.Float
 
 // calculatePriceImpact calculates the price impact percentage
 func (bc *BondingCurve) calculatePriceImpact(priceBefore, priceAfter *big.Float) *big.Float {
+	return priceImpact(priceBefore, priceAfter)
+}
+
+// priceImpact is the percentage by which priceAfter differs from priceBefore
+func priceImpact(priceBefore, priceAfter *big.Float) *big.Float {
 	if priceBefore.Sign() == 0 {
 		return big.NewFloat(0)
 	}
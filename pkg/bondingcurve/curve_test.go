@@ -1,6 +1,8 @@
 package bondingcurve
 
 import (
+	"errors"
+	"math"
 	"math/big"
 	"testing"
 )
@@ -68,16 +70,20 @@ func TestBondingCurve_Buy(t *testing.T) {
 		}
 
 		// Verify the bonding curve formula
-		// Pump.fun: dY = (cnpyIn * y) / (x + cnpyIn), then apply fee to output
-		expectedNumerator := new(big.Float).Mul(cnpyIn, pool.TokenReserve)
-		expectedDenominator := new(big.Float).Add(pool.CNPYReserve, cnpyIn)
-		tokensBeforeFee := new(big.Float).Quo(expectedNumerator, expectedDenominator)
-		expectedTokens := bc.config.ApplyFee(tokensBeforeFee)
+		// Pump.fun: dY = (dX * y) / (x + dX), for dX the CNPY in less the fee
+		cnpyToCurve := bc.config.ApplyFee(cnpyIn)
+		expectedNumerator := new(big.Float).Mul(cnpyToCurve, pool.TokenReserve)
+		expectedDenominator := new(big.Float).Add(pool.CNPYReserve, cnpyToCurve)
+		expectedTokens := new(big.Float).Quo(expectedNumerator, expectedDenominator)
 
 		if result.AmountOut.Cmp(expectedTokens) != 0 {
 			t.Errorf("formula calculation mismatch: expected %s, got %s",
 				expectedTokens.String(), result.AmountOut.String())
 		}
+
+		// The fee is taken in CNPY and leaves the pool
+		assertClose(t, "fee", result.Fee, big.NewFloat(1), 1e-12)
+		assertClose(t, "CNPY reserve", result.NewCNPYReserve, big.NewFloat(1099), 1e-12)
 	})
 
 	t.Run("zero amount", func(t *testing.T) {
@@ -247,6 +253,181 @@ func TestBondingCurve_SimulateSell(t *testing.T) {
 	})
 }
 
+// assertClose fails unless got is within a relative tolerance of want
+func assertClose(t *testing.T, what string, got, want *big.Float, tolerance float64) {
+	t.Helper()
+	g, _ := got.Float64()
+	w, _ := want.Float64()
+	if math.Abs(g-w) > tolerance*math.Abs(w) {
+		t.Errorf("expected %s of %v, got %v", what, w, g)
+	}
+}
+
+func TestBondingCurveConfig_AmountBeforeFee(t *testing.T) {
+	config := NewBondingCurveConfig()
+
+	amount, err := config.AmountBeforeFee(big.NewFloat(99))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClose(t, "amount before the fee", amount, big.NewFloat(100), 1e-12)
+	assertClose(t, "amount after the fee", config.ApplyFee(amount), big.NewFloat(99), 1e-12)
+
+	if _, err := (&BondingCurveConfig{FeeRateBasisPoints: BasisPointsDivisor}).AmountBeforeFee(big.NewFloat(1)); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount for a 100%% fee, got %v", err)
+	}
+}
+
+func TestBondingCurve_BuyExactOut(t *testing.T) {
+	bc := NewBondingCurve(NewBondingCurveConfig())
+
+	pool := NewVirtualPool(
+		big.NewFloat(1000),
+		big.NewFloat(800000),
+		big.NewFloat(200000),
+	)
+
+	t.Run("round trips through Buy", func(t *testing.T) {
+		result, err := bc.BuyExactOut(pool, big.NewFloat(50000))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		bought, err := bc.Buy(pool, result.AmountIn)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertClose(t, "tokens bought", bought.AmountOut, big.NewFloat(50000), 1e-12)
+		assertClose(t, "CNPY reserve", result.NewCNPYReserve, bought.NewCNPYReserve, 1e-12)
+		assertClose(t, "token reserve", result.NewTokenReserve, bought.NewTokenReserve, 1e-12)
+		assertClose(t, "price", result.Price, bought.Price, 1e-12)
+	})
+
+	t.Run("inverts Buy", func(t *testing.T) {
+		bought, err := bc.Buy(pool, big.NewFloat(250))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		result, err := bc.BuyExactOut(pool, bought.AmountOut)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertClose(t, "CNPY spent", result.AmountIn, big.NewFloat(250), 1e-12)
+	})
+
+	t.Run("fee is paid on top", func(t *testing.T) {
+		feeless, err := NewBondingCurve(&BondingCurveConfig{InitialPrice: big.NewFloat(0.01)}).BuyExactOut(pool, big.NewFloat(50000))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result, err := bc.BuyExactOut(pool, big.NewFloat(50000))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.AmountIn.Cmp(feeless.AmountIn) <= 0 {
+			t.Errorf("expected the fee to raise the CNPY spent above %s, got %s", feeless.AmountIn.String(), result.AmountIn.String())
+		}
+	})
+
+	t.Run("empty pool buys at the initial price", func(t *testing.T) {
+		empty := NewVirtualPool(big.NewFloat(0), big.NewFloat(0), big.NewFloat(0))
+
+		result, err := bc.BuyExactOut(empty, big.NewFloat(9900))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// 99 CNPY of tokens at 0.01, and the 1% fee on top
+		assertClose(t, "CNPY spent", result.AmountIn, big.NewFloat(100), 1e-12)
+		assertClose(t, "fee", result.Fee, big.NewFloat(1), 1e-12)
+	})
+
+	t.Run("whole token reserve", func(t *testing.T) {
+		if _, err := bc.BuyExactOut(pool, big.NewFloat(800000)); !errors.Is(err, ErrInsufficientReserve) {
+			t.Errorf("expected ErrInsufficientReserve, got %v", err)
+		}
+		if _, err := bc.BuyExactOut(pool, big.NewFloat(799999)); err != nil {
+			t.Errorf("unexpected error just inside the reserve: %v", err)
+		}
+	})
+
+	t.Run("zero amount", func(t *testing.T) {
+		if _, err := bc.BuyExactOut(pool, big.NewFloat(0)); !errors.Is(err, ErrZeroAmount) {
+			t.Errorf("expected ErrZeroAmount, got %v", err)
+		}
+	})
+
+	t.Run("simulation doesn't modify original pool", func(t *testing.T) {
+		if _, err := bc.SimulateBuyExactOut(pool, big.NewFloat(1000)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if pool.CNPYReserve.Cmp(big.NewFloat(1000)) != 0 || pool.TokenReserve.Cmp(big.NewFloat(800000)) != 0 {
+			t.Errorf("simulation modified the pool")
+		}
+	})
+}
+
+func TestBondingCurve_SellExactOut(t *testing.T) {
+	bc := NewBondingCurve(NewBondingCurveConfig())
+
+	pool := NewVirtualPool(
+		big.NewFloat(1000),
+		big.NewFloat(800000),
+		big.NewFloat(200000),
+	)
+
+	t.Run("round trips through Sell", func(t *testing.T) {
+		result, err := bc.SellExactOut(pool, big.NewFloat(10))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		sold, err := bc.Sell(pool, result.AmountIn)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertClose(t, "CNPY received", sold.AmountOut, big.NewFloat(10), 1e-12)
+		assertClose(t, "CNPY reserve", result.NewCNPYReserve, sold.NewCNPYReserve, 1e-12)
+		assertClose(t, "total supply", result.NewTotalSupply, sold.NewTotalSupply, 1e-12)
+	})
+
+	t.Run("inverts Sell", func(t *testing.T) {
+		sold, err := bc.Sell(pool, big.NewFloat(5000))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		result, err := bc.SellExactOut(pool, sold.AmountOut)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertClose(t, "tokens sold", result.AmountIn, big.NewFloat(5000), 1e-12)
+	})
+
+	t.Run("whole CNPY reserve", func(t *testing.T) {
+		// 990 CNPY after the fee is the whole reserve before it
+		if _, err := bc.SellExactOut(pool, big.NewFloat(990)); !errors.Is(err, ErrInsufficientReserve) {
+			t.Errorf("expected ErrInsufficientReserve, got %v", err)
+		}
+	})
+
+	t.Run("more tokens than supply", func(t *testing.T) {
+		// 200,000 tokens return at most 200 CNPY before the fee
+		if _, err := bc.SellExactOut(pool, big.NewFloat(250)); !errors.Is(err, ErrInsufficientTokens) {
+			t.Errorf("expected ErrInsufficientTokens, got %v", err)
+		}
+	})
+
+	t.Run("simulation doesn't modify original pool", func(t *testing.T) {
+		if _, err := bc.SimulateSellExactOut(pool, big.NewFloat(10)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if pool.CNPYReserve.Cmp(big.NewFloat(1000)) != 0 || pool.TotalSupply.Cmp(big.NewFloat(200000)) != 0 {
+			t.Errorf("simulation modified the pool")
+		}
+	})
+}
+
 func TestBondingCurve_GetAmountOut(t *testing.T) {
 	bc := NewBondingCurve(NewBondingCurveConfig())
 
@@ -63,15 +63,15 @@ func Example() {
 	// Current Price: 0.00125 CNPY per token
 	//
 	// Buy simulation (100 CNPY):
-	// Tokens received: 72000
-	// Effective price: 0.001388888889 CNPY per token
-	// Price impact: 11.11111111%
+	// Tokens received: 72065.5141
+	// Effective price: 0.001387626263 CNPY per token
+	// Price impact: 11.01010101%
 	//
 	// After buy transaction:
-	// New CNPY Reserve: 1100
-	// New Token Reserve: 728000
-	// New Total Supply: 272000
-	// New Price: 0.001510989011 CNPY per token
+	// New CNPY Reserve: 1099
+	// New Token Reserve: 727934.4859
+	// New Total Supply: 272065.5141
+	// New Price: 0.00150975125 CNPY per token
 }
 
 // ExampleBondingCurve_Buy demonstrates the buy mechanism with fee calculation
@@ -97,7 +97,7 @@ func ExampleBondingCurve_Buy() {
 	}
 
 	fmt.Printf("Input: %s CNPY\n", cnpyAmount.String())
-	fmt.Printf("Fee: %s CNPY\n", config.CalculateFee(cnpyAmount).String())
+	fmt.Printf("Fee: %s CNPY\n", result.Fee.String())
 	fmt.Printf("Amount after fee: %s CNPY\n", config.ApplyFee(cnpyAmount).String())
 	fmt.Printf("Tokens received: %s\n", result.AmountOut.String())
 	fmt.Printf("Effective price: %s CNPY per token\n", result.Price.String())
@@ -106,8 +106,8 @@ func ExampleBondingCurve_Buy() {
 	// Input: 50 CNPY
 	// Fee: 1 CNPY
 	// Amount after fee: 49 CNPY
-	// Tokens received: 35636.36364
-	// Effective price: 0.001403061224 CNPY per token
+	// Tokens received: 35701.27505
+	// Effective price: 0.001400510204 CNPY per token
 }
 
 // ExampleBondingCurve_Sell demonstrates the sell mechanism with token burning
@@ -207,10 +207,10 @@ func ExampleBondingCurve_SimulateBuy() {
 
 	// Output:
 	// Buy simulations:
-	// Buy 10 CNPY → 7841.584158 tokens (price impact: 2.02020202%)
-	// Buy 50 CNPY → 37714.28571 tokens (price impact: 6.060606061%)
-	// Buy 100 CNPY → 72000 tokens (price impact: 11.11111111%)
-	// Buy 500 CNPY → 264000 tokens (price impact: 51.51515152%)
+	// Buy 10 CNPY → 7842.36063 tokens (price impact: 2.01010101%)
+	// Buy 50 CNPY → 37732.25345 tokens (price impact: 6.01010101%)
+	// Buy 100 CNPY → 72065.5141 tokens (price impact: 11.01010101%)
+	// Buy 500 CNPY → 264882.9431 tokens (price impact: 51.01010101%)
 	//
 	// Pool state unchanged after simulations:
 	// CNPY Reserve: 1000 (was 1000)
@@ -252,10 +252,10 @@ func ExampleBondingCurve_GetOptimalTradeSize() {
 
 	// Output:
 	// Optimal buy sizes for different slippage tolerances:
-	// Max 1% → 9 CNPY (actual impact: 1.919191919%)
-	// Max 5% → 39 CNPY (actual impact: 4.949494949%)
-	// Max 10% → 89 CNPY (actual impact: 10%)
-	// Max 20% → 187 CNPY (actual impact: 19.8989899%)
+	// Max 1% → 9 CNPY (actual impact: 1.91010101%)
+	// Max 5% → 39 CNPY (actual impact: 4.91010101%)
+	// Max 10% → 89 CNPY (actual impact: 9.91010101%)
+	// Max 20% → 189 CNPY (actual impact: 19.91010101%)
 }
 
 // ExampleBondingCurveConfig_ApplyFee demonstrates fee calculations
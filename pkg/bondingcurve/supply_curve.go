@@ -0,0 +1,733 @@
+package bondingcurve
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// supplyShape describes a curve by the price it quotes once supply tokens have
+// been sold along it, and the CNPY reserve that selling them collects: the
+// integral of the price from zero to supply. supply inverts reserve.
+// bigReserve and bigSupply are reserve and supply evaluated in big.Float, for
+// pricing in base units.
+type supplyShape interface {
+	price(supply float64) float64
+	reserve(supply float64) float64
+	supply(reserve float64) float64
+	bigReserve(supply *big.Float) *big.Float
+	bigSupply(reserve *big.Float) *big.Float
+}
+
+// slackBits sets the margin pricing in base units leaves for error in
+// evaluating the shapes: an amount is reduced by 2^-slackBits of the largest
+// value it was computed from before it is rounded down. That is far above the
+// error of evaluating the shapes at Precision, and far below a base unit for
+// any pool whose amounts fit in uint64.
+const slackBits = 128
+
+// supplyCurve prices trades along a supplyShape. The pool's CNPY reserve
+// fixes its position on the curve, so a buy moves the pool from
+// supply(x) to supply(x + dX) for dX the CNPY in less the fee and mints the
+// difference, and a sell moves it back down by the tokens sold. Fees are taken
+// in CNPY, from the input of a buy and the output of a sell, and leave the
+// pool, as on the constant-product curve.
+//
+// Buy, Sell and their exact-out forms evaluate the shapes in float64. The
+// *Units forms evaluate them in big.Float and round every amount toward the
+// pool, so that CheckInvariant holds exactly.
+type supplyCurve struct {
+	curveType CurveType
+	shape     supplyShape
+	config    *BondingCurveConfig
+}
+
+// Type reports the curve family
+func (c *supplyCurve) Type() CurveType {
+	return c.curveType
+}
+
+// Buy mints the tokens cnpyAmountIn less the fee moves the pool along the
+// curve by. The pool's token reserve caps what can be bought.
+func (c *supplyCurve) Buy(pool *VirtualPool, cnpyAmountIn *big.Float) (*TradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cnpyAmountIn == nil || cnpyAmountIn.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	x := pool.CNPYReserve
+	y := pool.TokenReserve
+
+	// Apply fee to input (less CNPY reaches the curve)
+	cnpyToCurve := c.config.ApplyFee(cnpyAmountIn)
+	reserve, _ := x.Float64()
+	amountIn, _ := cnpyToCurve.Float64()
+
+	supplyBefore := c.shape.supply(reserve)
+	supplyAfter := c.shape.supply(reserve + amountIn)
+	tokensOut := big.NewFloat(supplyAfter - supplyBefore)
+	if tokensOut.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if tokensOut.Cmp(y) > 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	effectivePrice := new(big.Float).Quo(cnpyAmountIn, tokensOut)
+	priceBefore := big.NewFloat(c.shape.price(supplyBefore))
+
+	return &TradeResult{
+		AmountIn:        new(big.Float).Copy(cnpyAmountIn),
+		AmountOut:       tokensOut,
+		Fee:             new(big.Float).Sub(cnpyAmountIn, cnpyToCurve),
+		NewCNPYReserve:  new(big.Float).Add(x, cnpyToCurve),
+		NewTokenReserve: new(big.Float).Sub(y, tokensOut),
+		NewTotalSupply:  new(big.Float).Add(pool.TotalSupply, tokensOut),
+		Price:           effectivePrice,
+		PriceImpact:     priceImpact(priceBefore, effectivePrice),
+	}, nil
+}
+
+// Sell pays out the CNPY the pool's reserve falls by when tokenAmountIn tokens
+// are returned to the curve
+func (c *supplyCurve) Sell(pool *VirtualPool, tokenAmountIn *big.Float) (*TradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if tokenAmountIn == nil || tokenAmountIn.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	if tokenAmountIn.Cmp(pool.TotalSupply) > 0 {
+		return nil, ErrInsufficientTokens
+	}
+
+	x := pool.CNPYReserve
+	y := pool.TokenReserve
+	reserve, _ := x.Float64()
+	amountIn, _ := tokenAmountIn.Float64()
+
+	supplyBefore := c.shape.supply(reserve)
+	supplyAfter := supplyBefore - amountIn
+	if supplyAfter < 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	// The reserve left at the lower supply, clamped against rounding in the
+	// float64 shapes
+	reserveAfter := min(max(c.shape.reserve(supplyAfter), 0), reserve)
+	cnpyOut := big.NewFloat(reserve - reserveAfter)
+	if cnpyOut.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	// Apply fees to the output
+	cnpyOutAfterFee := c.config.ApplyFee(cnpyOut)
+
+	effectivePrice := new(big.Float).Quo(cnpyOutAfterFee, tokenAmountIn)
+	priceBefore := big.NewFloat(c.shape.price(supplyBefore))
+
+	return &TradeResult{
+		AmountIn:        new(big.Float).Copy(tokenAmountIn),
+		AmountOut:       cnpyOutAfterFee,
+		Fee:             new(big.Float).Sub(cnpyOut, cnpyOutAfterFee),
+		NewCNPYReserve:  new(big.Float).Sub(x, cnpyOut),
+		NewTokenReserve: new(big.Float).Add(y, tokenAmountIn),
+		NewTotalSupply:  new(big.Float).Sub(pool.TotalSupply, tokenAmountIn),
+		Price:           effectivePrice,
+		PriceImpact:     priceImpact(priceBefore, effectivePrice),
+	}, nil
+}
+
+// BuyExactOut prices buying exactly tokenAmountOut tokens: the CNPY that moves
+// the pool up the curve by tokenAmountOut, grossed up by the fee
+func (c *supplyCurve) BuyExactOut(pool *VirtualPool, tokenAmountOut *big.Float) (*TradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if tokenAmountOut == nil || tokenAmountOut.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	x := pool.CNPYReserve
+	y := pool.TokenReserve
+	if tokenAmountOut.Cmp(y) > 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	reserve, _ := x.Float64()
+	tokens, _ := tokenAmountOut.Float64()
+	supplyBefore := c.shape.supply(reserve)
+	cnpyToCurve := big.NewFloat(c.shape.reserve(supplyBefore+tokens) - reserve)
+	if cnpyToCurve.Sign() <= 0 || cnpyToCurve.IsInf() {
+		return nil, ErrInvalidAmount
+	}
+
+	cnpyAmountIn, err := c.config.AmountBeforeFee(cnpyToCurve)
+	if err != nil {
+		return nil, err
+	}
+
+	effectivePrice := new(big.Float).Quo(cnpyAmountIn, tokenAmountOut)
+	priceBefore := big.NewFloat(c.shape.price(supplyBefore))
+
+	return &TradeResult{
+		AmountIn:        cnpyAmountIn,
+		AmountOut:       new(big.Float).Copy(tokenAmountOut),
+		Fee:             new(big.Float).Sub(cnpyAmountIn, cnpyToCurve),
+		NewCNPYReserve:  new(big.Float).Add(x, cnpyToCurve),
+		NewTokenReserve: new(big.Float).Sub(y, tokenAmountOut),
+		NewTotalSupply:  new(big.Float).Add(pool.TotalSupply, tokenAmountOut),
+		Price:           effectivePrice,
+		PriceImpact:     priceImpact(priceBefore, effectivePrice),
+	}, nil
+}
+
+// SellExactOut prices selling enough tokens to receive exactly cnpyAmountOut
+// CNPY, after the fee: the tokens that move the pool down the curve until its
+// reserve has fallen by cnpyAmountOut grossed up by the fee
+func (c *supplyCurve) SellExactOut(pool *VirtualPool, cnpyAmountOut *big.Float) (*TradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cnpyAmountOut == nil || cnpyAmountOut.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	cnpyOut, err := c.config.AmountBeforeFee(cnpyAmountOut)
+	if err != nil {
+		return nil, err
+	}
+
+	x := pool.CNPYReserve
+	y := pool.TokenReserve
+	if cnpyOut.Cmp(x) > 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	reserve, _ := x.Float64()
+	amountOut, _ := cnpyOut.Float64()
+	supplyBefore := c.shape.supply(reserve)
+	tokenAmountIn := big.NewFloat(supplyBefore - c.shape.supply(max(reserve-amountOut, 0)))
+	if tokenAmountIn.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if tokenAmountIn.Cmp(pool.TotalSupply) > 0 {
+		return nil, ErrInsufficientTokens
+	}
+
+	effectivePrice := new(big.Float).Quo(cnpyAmountOut, tokenAmountIn)
+	priceBefore := big.NewFloat(c.shape.price(supplyBefore))
+
+	return &TradeResult{
+		AmountIn:        tokenAmountIn,
+		AmountOut:       new(big.Float).Copy(cnpyAmountOut),
+		Fee:             new(big.Float).Sub(cnpyOut, cnpyAmountOut),
+		NewCNPYReserve:  new(big.Float).Sub(x, cnpyOut),
+		NewTokenReserve: new(big.Float).Add(y, tokenAmountIn),
+		NewTotalSupply:  new(big.Float).Sub(pool.TotalSupply, tokenAmountIn),
+		Price:           effectivePrice,
+		PriceImpact:     priceImpact(priceBefore, effectivePrice),
+	}, nil
+}
+
+// BuyUnits is Buy in base units, with the fee taken in uCNPY before the rest
+// reaches the curve and the tokens minted rounded down
+func (c *supplyCurve) BuyUnits(pool *IntPool, cnpyAmountIn *big.Int) (*IntTradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cnpyAmountIn == nil || cnpyAmountIn.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	cnpyToCurve := c.config.ApplyFeeUnits(cnpyAmountIn)
+	tokensOut := c.tokensFor(pool, cnpyToCurve)
+	if tokensOut.Sign() == 0 {
+		return nil, ErrInvalidAmount
+	}
+	if tokensOut.Cmp(pool.TokenReserve) > 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	reserve, _ := pool.CNPY(pool.CNPYReserve).Float64()
+	effectivePrice := pool.effectivePrice(cnpyAmountIn, tokensOut)
+	priceBefore := big.NewFloat(c.shape.price(c.shape.supply(reserve)))
+
+	return &IntTradeResult{
+		AmountIn:        new(big.Int).Set(cnpyAmountIn),
+		AmountOut:       tokensOut,
+		Fee:             new(big.Int).Sub(cnpyAmountIn, cnpyToCurve),
+		NewCNPYReserve:  new(big.Int).Add(pool.CNPYReserve, cnpyToCurve),
+		NewTokenReserve: new(big.Int).Sub(pool.TokenReserve, tokensOut),
+		NewTotalSupply:  new(big.Int).Add(pool.TotalSupply, tokensOut),
+		Price:           effectivePrice,
+		PriceImpact:     priceImpact(priceBefore, effectivePrice),
+	}, nil
+}
+
+// SellUnits is Sell in base units, with the CNPY paid out rounded down
+func (c *supplyCurve) SellUnits(pool *IntPool, tokenAmountIn *big.Int) (*IntTradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if tokenAmountIn == nil || tokenAmountIn.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	if tokenAmountIn.Cmp(pool.TotalSupply) > 0 {
+		return nil, ErrInsufficientTokens
+	}
+
+	cnpyOut, err := c.cnpyFor(pool, tokenAmountIn)
+	if err != nil {
+		return nil, err
+	}
+	cnpyOutAfterFee := c.config.ApplyFeeUnits(cnpyOut)
+	if cnpyOutAfterFee.Sign() == 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	reserve, _ := pool.CNPY(pool.CNPYReserve).Float64()
+	effectivePrice := pool.effectivePrice(cnpyOutAfterFee, tokenAmountIn)
+	priceBefore := big.NewFloat(c.shape.price(c.shape.supply(reserve)))
+
+	return &IntTradeResult{
+		AmountIn:        new(big.Int).Set(tokenAmountIn),
+		AmountOut:       cnpyOutAfterFee,
+		Fee:             new(big.Int).Sub(cnpyOut, cnpyOutAfterFee),
+		NewCNPYReserve:  new(big.Int).Sub(pool.CNPYReserve, cnpyOut),
+		NewTokenReserve: new(big.Int).Add(pool.TokenReserve, tokenAmountIn),
+		NewTotalSupply:  new(big.Int).Sub(pool.TotalSupply, tokenAmountIn),
+		Price:           effectivePrice,
+		PriceImpact:     priceImpact(priceBefore, effectivePrice),
+	}, nil
+}
+
+// BuyExactOutUnits inverts BuyUnits, searching out from the shape's estimate
+// for the least uCNPY that moves the pool up the curve by tokenAmountOut. The
+// buyer spends the least amount that leaves that once the fee is taken, and
+// the result is BuyUnits' for it.
+func (c *supplyCurve) BuyExactOutUnits(pool *IntPool, tokenAmountOut *big.Int) (*IntTradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if tokenAmountOut == nil || tokenAmountOut.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	if tokenAmountOut.Cmp(pool.TokenReserve) > 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	reserve, _ := pool.CNPY(pool.CNPYReserve).Float64()
+	tokens, _ := pool.Tokens(tokenAmountOut).Float64()
+	estimate := c.shape.reserve(c.shape.supply(reserve)+tokens) - reserve
+	if math.IsInf(estimate, 0) || math.IsNaN(estimate) {
+		return nil, ErrInvalidAmount
+	}
+
+	cnpyToCurve, err := leastUnits(floatToUnits(estimate, big.NewInt(MicroCNPYPerCNPY)), func(amount *big.Int) bool {
+		return c.tokensFor(pool, amount).Cmp(tokenAmountOut) >= 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	cnpyAmountIn, err := c.config.amountWithFeeUnits(cnpyToCurve)
+	if err != nil {
+		return nil, err
+	}
+	return c.BuyUnits(pool, cnpyAmountIn)
+}
+
+// SellExactOutUnits inverts SellUnits, searching out from the shape's estimate
+// for the fewest tokens that the curve releases enough uCNPY for to pay
+// cnpyAmountOut after the fee. The result is SellUnits' for them.
+func (c *supplyCurve) SellExactOutUnits(pool *IntPool, cnpyAmountOut *big.Int) (*IntTradeResult, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cnpyAmountOut == nil || cnpyAmountOut.Sign() <= 0 {
+		return nil, ErrZeroAmount
+	}
+
+	cnpyOut, err := c.config.amountWithFeeUnits(cnpyAmountOut)
+	if err != nil {
+		return nil, err
+	}
+	if cnpyOut.Cmp(pool.CNPYReserve) > 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	// Selling more than the supply would pay out the same as selling all of it
+	enough := func(amount *big.Int) bool {
+		if amount.Cmp(pool.TotalSupply) > 0 {
+			amount = pool.TotalSupply
+		}
+		if amount.Sign() == 0 {
+			return false
+		}
+		paid, err := c.cnpyFor(pool, amount)
+		return err == nil && paid.Cmp(cnpyOut) >= 0
+	}
+	if !enough(pool.TotalSupply) {
+		return nil, ErrInsufficientTokens
+	}
+
+	reserve, _ := pool.CNPY(pool.CNPYReserve).Float64()
+	amountOut, _ := pool.CNPY(cnpyOut).Float64()
+	estimate := c.shape.supply(reserve) - c.shape.supply(max(reserve-amountOut, 0))
+
+	tokenAmountIn, err := leastUnits(floatToUnits(estimate, pool.TokenUnit()), enough)
+	if err != nil {
+		return nil, err
+	}
+	return c.SellUnits(pool, tokenAmountIn)
+}
+
+// MarginalPrice is the price the shape quotes at the supply the pool's CNPY
+// reserve has bought
+func (c *supplyCurve) MarginalPrice(pool *IntPool) *big.Float {
+	reserve, _ := pool.CNPY(pool.CNPYReserve).Float64()
+	return big.NewFloat(c.shape.price(c.shape.supply(reserve)))
+}
+
+// CheckInvariant checks that the reserve a trade leaves still pays for the
+// curve: the supply the reserve had bought before the trade, moved by the
+// tokens the trade minted or burned, is priced with the reserve integral and
+// rounded up to the uCNPY, and the reserve after the trade must cover it. A
+// buy that mints more than its CNPY pays for, or a sell that pays out more
+// than its tokens return, leaves the reserve short.
+func (c *supplyCurve) CheckInvariant(before, after *IntPool) error {
+	if err := checkTrade(before, after); err != nil {
+		return err
+	}
+
+	minted := new(big.Int).Sub(after.TotalSupply, before.TotalSupply)
+	if minted.Sign() == 0 {
+		if after.CNPYReserve.Cmp(before.CNPYReserve) < 0 {
+			return fmt.Errorf("%w: reserve fell without a trade", ErrInvariantViolated)
+		}
+		return nil
+	}
+
+	supply := newWorkingFloat().Add(c.position(before), before.Tokens(minted))
+	if supply.Sign() < 0 {
+		return fmt.Errorf("%w: %s tokens burned past the start of the curve", ErrInvariantViolated, new(big.Int).Neg(minted).String())
+	}
+	required := unitsUp(c.shape.bigReserve(supply), big.NewInt(MicroCNPYPerCNPY))
+	if after.CNPYReserve.Cmp(required) < 0 {
+		return fmt.Errorf("%w: reserve of %s uCNPY is below the %s uCNPY the curve requires", ErrInvariantViolated, after.CNPYReserve.String(), required.String())
+	}
+	return nil
+}
+
+// tokensFor is the token base units cnpyAmountIn uCNPY moves the pool up the
+// curve by, rounded down
+func (c *supplyCurve) tokensFor(pool *IntPool, cnpyAmountIn *big.Int) *big.Int {
+	supplyBefore := c.position(pool)
+	supplyAfter := c.shape.bigSupply(pool.CNPY(new(big.Int).Add(pool.CNPYReserve, cnpyAmountIn)))
+	return unitsDown(newWorkingFloat().Sub(supplyAfter, supplyBefore), supplyAfter, pool.TokenUnit())
+}
+
+// cnpyFor is the uCNPY the reserve falls by, rounded down, when tokenAmountIn
+// base units return to the curve
+func (c *supplyCurve) cnpyFor(pool *IntPool, tokenAmountIn *big.Int) (*big.Int, error) {
+	supplyAfter := newWorkingFloat().Sub(c.position(pool), pool.Tokens(tokenAmountIn))
+	if supplyAfter.Sign() < 0 {
+		return nil, ErrInsufficientReserve
+	}
+
+	reserve := pool.CNPY(pool.CNPYReserve)
+	cnpyOut := newWorkingFloat().Sub(reserve, c.shape.bigReserve(supplyAfter))
+	cnpyOutUnits := unitsDown(cnpyOut, reserve, big.NewInt(MicroCNPYPerCNPY))
+	if cnpyOutUnits.Cmp(pool.CNPYReserve) > 0 {
+		return nil, ErrInsufficientReserve
+	}
+	return cnpyOutUnits, nil
+}
+
+// position is the supply the pool's CNPY reserve has bought along the curve
+func (c *supplyCurve) position(pool *IntPool) *big.Float {
+	return c.shape.bigSupply(pool.CNPY(pool.CNPYReserve))
+}
+
+// unitsDown converts amount, in whole CNPY or tokens, to base units after
+// taking off the slack for scale, the largest value amount was computed from,
+// and rounds down. Amounts that leave nothing are zero.
+func unitsDown(amount, scale *big.Float, unit *big.Int) *big.Int {
+	slack := newWorkingFloat().Abs(scale)
+	slack.SetMantExp(slack, -slackBits)
+	units := newWorkingFloat().Sub(amount, slack)
+	if units.Sign() <= 0 {
+		return new(big.Int)
+	}
+	result, _ := units.Mul(units, new(big.Float).SetInt(unit)).Int(nil)
+	return result
+}
+
+// unitsUp converts amount, in whole CNPY or tokens, to base units, rounding up
+func unitsUp(amount *big.Float, unit *big.Int) *big.Int {
+	units := newWorkingFloat().Mul(amount, new(big.Float).SetInt(unit))
+	result, accuracy := units.Int(nil)
+	if accuracy == big.Below {
+		result.Add(result, big.NewInt(1))
+	}
+	return result
+}
+
+// SimulateBuy simulates a buy without modifying the pool state
+func (c *supplyCurve) SimulateBuy(pool *VirtualPool, cnpyAmountIn *big.Float) (*TradeResult, error) {
+	return c.Buy(pool.Copy(), cnpyAmountIn)
+}
+
+// SimulateSell simulates a sell without modifying the pool state
+func (c *supplyCurve) SimulateSell(pool *VirtualPool, tokenAmountIn *big.Float) (*TradeResult, error) {
+	return c.Sell(pool.Copy(), tokenAmountIn)
+}
+
+// SimulateBuyExactOut simulates BuyExactOut without modifying the pool state
+func (c *supplyCurve) SimulateBuyExactOut(pool *VirtualPool, tokenAmountOut *big.Float) (*TradeResult, error) {
+	return c.BuyExactOut(pool.Copy(), tokenAmountOut)
+}
+
+// SimulateSellExactOut simulates SellExactOut without modifying the pool state
+func (c *supplyCurve) SimulateSellExactOut(pool *VirtualPool, cnpyAmountOut *big.Float) (*TradeResult, error) {
+	return c.SellExactOut(pool.Copy(), cnpyAmountOut)
+}
+
+// GetConfig returns the bonding curve configuration
+func (c *supplyCurve) GetConfig() *BondingCurveConfig {
+	return c.config
+}
+
+// LinearCurve quotes InitialPrice + slope*s CNPY per token once s tokens have
+// been sold
+type LinearCurve struct {
+	supplyCurve
+}
+
+// NewLinearCurve creates a linear curve starting at the config's initial price
+func NewLinearCurve(slope float64, config *BondingCurveConfig) (*LinearCurve, error) {
+	if config == nil {
+		config = NewBondingCurveConfig()
+	}
+	initialPrice, err := positiveInitialPrice(config)
+	if err != nil {
+		return nil, err
+	}
+	if slope < 0 || math.IsNaN(slope) || math.IsInf(slope, 0) {
+		return nil, fmt.Errorf("%w: linear slope must be zero or more, got %v", ErrInvalidCurveParams, slope)
+	}
+
+	return &LinearCurve{supplyCurve{
+		curveType: CurveLinear,
+		shape:     linearShape{initialPrice: initialPrice, slope: slope},
+		config:    config,
+	}}, nil
+}
+
+type linearShape struct {
+	initialPrice float64
+	slope        float64
+}
+
+func (s linearShape) price(supply float64) float64 {
+	return s.initialPrice + s.slope*supply
+}
+
+// reserve = p0*s + m*s^2/2
+func (s linearShape) reserve(supply float64) float64 {
+	return supply * (s.initialPrice + s.slope*supply/2)
+}
+
+// supply solves the reserve quadratic for s, in the form that stays accurate
+// for small slopes and reduces to reserve/p0 for a flat curve
+func (s linearShape) supply(reserve float64) float64 {
+	return 2 * reserve / (math.Sqrt(s.initialPrice*s.initialPrice+2*s.slope*reserve) + s.initialPrice)
+}
+
+func (s linearShape) bigReserve(supply *big.Float) *big.Float {
+	result := newWorkingFloat().Mul(big.NewFloat(s.slope/2), supply)
+	result.Add(result, big.NewFloat(s.initialPrice))
+	return result.Mul(result, supply)
+}
+
+func (s linearShape) bigSupply(reserve *big.Float) *big.Float {
+	initialPrice := big.NewFloat(s.initialPrice)
+	root := newWorkingFloat().Mul(big.NewFloat(2*s.slope), reserve)
+	root.Add(root, newWorkingFloat().Mul(initialPrice, initialPrice))
+	root = newWorkingFloat().Sqrt(root)
+	root.Add(root, initialPrice)
+	result := newWorkingFloat().Mul(bigTwo, reserve)
+	return result.Quo(result, root)
+}
+
+// ExponentialCurve quotes InitialPrice * e^(rate*s) CNPY per token once s
+// tokens have been sold
+type ExponentialCurve struct {
+	supplyCurve
+}
+
+// NewExponentialCurve creates an exponential curve starting at the config's
+// initial price and growing at rate per token
+func NewExponentialCurve(rate float64, config *BondingCurveConfig) (*ExponentialCurve, error) {
+	if config == nil {
+		config = NewBondingCurveConfig()
+	}
+	initialPrice, err := positiveInitialPrice(config)
+	if err != nil {
+		return nil, err
+	}
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		return nil, fmt.Errorf("%w: exponential growth rate must be positive, got %v", ErrInvalidCurveParams, rate)
+	}
+
+	return &ExponentialCurve{supplyCurve{
+		curveType: CurveExponential,
+		shape:     exponentialShape{initialPrice: initialPrice, rate: rate},
+		config:    config,
+	}}, nil
+}
+
+type exponentialShape struct {
+	initialPrice float64
+	rate         float64
+}
+
+func (s exponentialShape) price(supply float64) float64 {
+	return s.initialPrice * math.Exp(s.rate*supply)
+}
+
+// reserve = p0 * (e^(k*s) - 1) / k
+func (s exponentialShape) reserve(supply float64) float64 {
+	return s.initialPrice * math.Expm1(s.rate*supply) / s.rate
+}
+
+func (s exponentialShape) supply(reserve float64) float64 {
+	return math.Log1p(s.rate*reserve/s.initialPrice) / s.rate
+}
+
+func (s exponentialShape) bigReserve(supply *big.Float) *big.Float {
+	result := bigExpm1(newWorkingFloat().Mul(big.NewFloat(s.rate), supply))
+	result.Mul(result, big.NewFloat(s.initialPrice))
+	return result.Quo(result, big.NewFloat(s.rate))
+}
+
+func (s exponentialShape) bigSupply(reserve *big.Float) *big.Float {
+	x := newWorkingFloat().Mul(big.NewFloat(s.rate), reserve)
+	result := bigLog1p(x.Quo(x, big.NewFloat(s.initialPrice)))
+	return result.Quo(result, big.NewFloat(s.rate))
+}
+
+// CappedSigmoidCurve quotes maxPrice / (1 + e^(-rate*(s - midpoint))) CNPY per
+// token once s tokens have been sold. The price starts low, climbs fastest
+// around the midpoint supply and approaches maxPrice without passing it. The
+// config's initial price is not used; the curve's own start is
+// maxPrice / (1 + e^(rate*midpoint)).
+type CappedSigmoidCurve struct {
+	supplyCurve
+}
+
+// NewCappedSigmoidCurve creates a sigmoid curve capped at maxPrice
+func NewCappedSigmoidCurve(maxPrice, rate, midpointSupply float64, config *BondingCurveConfig) (*CappedSigmoidCurve, error) {
+	if config == nil {
+		config = NewBondingCurveConfig()
+	}
+	if !(maxPrice > 0) || math.IsInf(maxPrice, 0) {
+		return nil, fmt.Errorf("%w: sigmoid max price must be positive, got %v", ErrInvalidCurveParams, maxPrice)
+	}
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		return nil, fmt.Errorf("%w: sigmoid growth rate must be positive, got %v", ErrInvalidCurveParams, rate)
+	}
+	if midpointSupply < 0 || math.IsNaN(midpointSupply) || math.IsInf(midpointSupply, 0) {
+		return nil, fmt.Errorf("%w: sigmoid midpoint supply must be zero or more, got %v", ErrInvalidCurveParams, midpointSupply)
+	}
+
+	return &CappedSigmoidCurve{supplyCurve{
+		curveType: CurveCappedSigmoid,
+		shape:     sigmoidShape{maxPrice: maxPrice, rate: rate, midpoint: midpointSupply},
+		config:    config,
+	}}, nil
+}
+
+type sigmoidShape struct {
+	maxPrice float64
+	rate     float64
+	midpoint float64
+}
+
+func (s sigmoidShape) price(supply float64) float64 {
+	return s.maxPrice / (1 + math.Exp(-s.rate*(supply-s.midpoint)))
+}
+
+// reserve = pmax/k * (softplus(k*(s - mid)) - softplus(-k*mid))
+func (s sigmoidShape) reserve(supply float64) float64 {
+	return s.maxPrice / s.rate * (softplus(s.rate*(supply-s.midpoint)) - softplus(-s.rate*s.midpoint))
+}
+
+func (s sigmoidShape) supply(reserve float64) float64 {
+	return s.midpoint + softplusInverse(s.rate*reserve/s.maxPrice+softplus(-s.rate*s.midpoint))/s.rate
+}
+
+func (s sigmoidShape) bigReserve(supply *big.Float) *big.Float {
+	rate := big.NewFloat(s.rate)
+	x := newWorkingFloat().Sub(supply, big.NewFloat(s.midpoint))
+	result := bigSoftplus(x.Mul(x, rate))
+	result.Sub(result, s.bigStart())
+	result.Mul(result, big.NewFloat(s.maxPrice))
+	return result.Quo(result, rate)
+}
+
+func (s sigmoidShape) bigSupply(reserve *big.Float) *big.Float {
+	rate := big.NewFloat(s.rate)
+	y := newWorkingFloat().Mul(rate, reserve)
+	y.Quo(y, big.NewFloat(s.maxPrice))
+	result := bigSoftplusInverse(y.Add(y, s.bigStart()))
+	result.Quo(result, rate)
+	return result.Add(result, big.NewFloat(s.midpoint))
+}
+
+// bigStart is softplus(-k*mid), where the reserve integral starts
+func (s sigmoidShape) bigStart() *big.Float {
+	x := newWorkingFloat().Mul(big.NewFloat(s.rate), big.NewFloat(s.midpoint))
+	return bigSoftplus(x.Neg(x))
+}
+
+// softplus is ln(1 + e^x), computed without overflow for large x
+func softplus(x float64) float64 {
+	return max(x, 0) + math.Log1p(math.Exp(-math.Abs(x)))
+}
+
+// softplusInverse is ln(e^y - 1) for y > 0
+func softplusInverse(y float64) float64 {
+	return y + math.Log1p(-math.Exp(-y))
+}
+
+// positiveInitialPrice returns the config's initial price, which the linear
+// and exponential curves start from
+func positiveInitialPrice(config *BondingCurveConfig) (float64, error) {
+	if config.InitialPrice == nil || config.InitialPrice.Sign() <= 0 {
+		return 0, fmt.Errorf("%w: initial price must be positive", ErrInvalidCurveParams)
+	}
+	initialPrice, _ := config.InitialPrice.Float64()
+	return initialPrice, nil
+}
+
+// Each curve family satisfies Curve
+var (
+	_ Curve = (*BondingCurve)(nil)
+	_ Curve = (*LinearCurve)(nil)
+	_ Curve = (*ExponentialCurve)(nil)
+	_ Curve = (*CappedSigmoidCurve)(nil)
+)
@@ -0,0 +1,343 @@
+package bondingcurve
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestNewCurve(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  CurveParams
+		want    CurveType
+		wantErr error
+	}{
+		{"empty type is constant product", CurveParams{}, CurveConstantProduct, nil},
+		{"constant product", CurveParams{Type: CurveConstantProduct}, CurveConstantProduct, nil},
+		{"linear", CurveParams{Type: CurveLinear, Slope: 0.00000001}, CurveLinear, nil},
+		{"exponential", CurveParams{Type: CurveExponential, Slope: 0.00000001}, CurveExponential, nil},
+		{"capped sigmoid", CurveParams{Type: CurveCappedSigmoid, Slope: 0.00000001, MaxPrice: 1, MidpointSupply: 400000000}, CurveCappedSigmoid, nil},
+		{"unknown type", CurveParams{Type: "quadratic"}, "", ErrUnknownCurve},
+		{"negative linear slope", CurveParams{Type: CurveLinear, Slope: -1}, "", ErrInvalidCurveParams},
+		{"exponential without growth", CurveParams{Type: CurveExponential}, "", ErrInvalidCurveParams},
+		{"sigmoid without cap", CurveParams{Type: CurveCappedSigmoid, Slope: 0.00000001}, "", ErrInvalidCurveParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curve, err := NewCurve(tt.params, nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if curve.Type() != tt.want {
+				t.Errorf("expected %s curve, got %s", tt.want, curve.Type())
+			}
+			if curve.GetConfig().FeeRateBasisPoints != DefaultFeeRateBasisPoints {
+				t.Errorf("expected default fee rate, got %d", curve.GetConfig().FeeRateBasisPoints)
+			}
+		})
+	}
+}
+
+// supplyCurves returns one curve of each supply-priced family, without fees
+func supplyCurves(t *testing.T) map[string]Curve {
+	t.Helper()
+
+	config := &BondingCurveConfig{InitialPrice: big.NewFloat(0.01)}
+	curves := make(map[string]Curve)
+	for _, params := range []CurveParams{
+		{Type: CurveLinear, Slope: 0.00000001},
+		{Type: CurveExponential, Slope: 0.00000001},
+		{Type: CurveCappedSigmoid, Slope: 0.00000001, MaxPrice: 1, MidpointSupply: 400000000},
+	} {
+		curve, err := NewCurve(params, config)
+		if err != nil {
+			t.Fatalf("failed to create %s curve: %v", params.Type, err)
+		}
+		curves[string(params.Type)] = curve
+	}
+	return curves
+}
+
+func TestSupplyCurve_Shapes(t *testing.T) {
+	shapes := map[string]supplyShape{
+		"linear":      linearShape{initialPrice: 0.01, slope: 0.00000001},
+		"flat":        linearShape{initialPrice: 0.01},
+		"exponential": exponentialShape{initialPrice: 0.01, rate: 0.00000001},
+		"sigmoid":     sigmoidShape{maxPrice: 1, rate: 0.00000001, midpoint: 400000000},
+	}
+
+	for name, shape := range shapes {
+		t.Run(name, func(t *testing.T) {
+			previous := shape.price(0)
+			for _, supply := range []float64{1000, 1000000, 100000000, 400000000, 800000000} {
+				// supply inverts reserve
+				got := shape.supply(shape.reserve(supply))
+				if math.Abs(got-supply)/supply > 1e-9 {
+					t.Errorf("supply(reserve(%v)) = %v", supply, got)
+				}
+
+				// the big.Float forms agree, and invert each other far beyond float64
+				reserve := shape.bigReserve(big.NewFloat(supply))
+				assertClose(t, "reserve", reserve, big.NewFloat(shape.reserve(supply)), 1e-9)
+				diff := newWorkingFloat().Sub(shape.bigSupply(reserve), big.NewFloat(supply))
+				if diff.Sign() != 0 && diff.MantExp(nil) > big.NewFloat(supply).MantExp(nil)-200 {
+					t.Errorf("bigSupply(bigReserve(%v)) is off by %s", supply, diff.Text('g', 5))
+				}
+
+				// the price never falls as supply grows
+				price := shape.price(supply)
+				if price < previous {
+					t.Errorf("price fell from %v to %v at supply %v", previous, price, supply)
+				}
+				previous = price
+			}
+		})
+	}
+
+	t.Run("sigmoid is capped", func(t *testing.T) {
+		shape := sigmoidShape{maxPrice: 1, rate: 0.00000001, midpoint: 400000000}
+		if price := shape.price(1e12); price > 1 {
+			t.Errorf("expected price at or below cap of 1, got %v", price)
+		}
+		if price := shape.price(400000000); math.Abs(price-0.5) > 1e-12 {
+			t.Errorf("expected half the cap at the midpoint, got %v", price)
+		}
+	})
+}
+
+func TestSupplyCurve_Buy(t *testing.T) {
+	t.Run("flat linear curve sells at the initial price", func(t *testing.T) {
+		curve, err := NewLinearCurve(0, NewBondingCurveConfig())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(0))
+
+		result, err := curve.Buy(pool, big.NewFloat(100))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The 1% fee leaves 99 CNPY, which buys 9,900 tokens at 0.01
+		tokens, _ := result.AmountOut.Float64()
+		if math.Abs(tokens-9900) > 1e-6 {
+			t.Errorf("expected 9900 tokens, got %v", tokens)
+		}
+		reserve, _ := result.NewCNPYReserve.Float64()
+		if reserve != 1099 {
+			t.Errorf("expected CNPY reserve of 1099, got %v", reserve)
+		}
+	})
+
+	for name, curve := range supplyCurves(t) {
+		t.Run(name+" price rises with each buy", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(0))
+
+			previous := big.NewFloat(0)
+			for i := 0; i < 5; i++ {
+				result, err := curve.Buy(pool, big.NewFloat(1000))
+				if err != nil {
+					t.Fatalf("buy %d failed: %v", i, err)
+				}
+				if result.Price.Cmp(previous) <= 0 {
+					t.Errorf("buy %d: price %s did not rise above %s", i, result.Price.String(), previous.String())
+				}
+				previous = result.Price
+				pool = NewVirtualPool(result.NewCNPYReserve, result.NewTokenReserve, result.NewTotalSupply)
+			}
+		})
+
+		t.Run(name+" buy beyond the token reserve", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(10), big.NewFloat(0))
+
+			if _, err := curve.Buy(pool, big.NewFloat(1000)); !errors.Is(err, ErrInsufficientReserve) {
+				t.Errorf("expected ErrInsufficientReserve, got %v", err)
+			}
+		})
+
+		t.Run(name+" zero amount", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(0))
+
+			if _, err := curve.Buy(pool, big.NewFloat(0)); !errors.Is(err, ErrZeroAmount) {
+				t.Errorf("expected ErrZeroAmount, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSupplyCurve_Sell(t *testing.T) {
+	for name, curve := range supplyCurves(t) {
+		t.Run(name+" selling a buy back returns its CNPY", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(0))
+
+			bought, err := curve.Buy(pool, big.NewFloat(500))
+			if err != nil {
+				t.Fatalf("buy failed: %v", err)
+			}
+			pool = NewVirtualPool(bought.NewCNPYReserve, bought.NewTokenReserve, bought.NewTotalSupply)
+
+			sold, err := curve.Sell(pool, bought.AmountOut)
+			if err != nil {
+				t.Fatalf("sell failed: %v", err)
+			}
+
+			// Without fees the round trip is lossless
+			cnpyOut, _ := sold.AmountOut.Float64()
+			if math.Abs(cnpyOut-500) > 1e-6 {
+				t.Errorf("expected 500 CNPY back, got %v", cnpyOut)
+			}
+			reserve, _ := sold.NewCNPYReserve.Float64()
+			if math.Abs(reserve-1000) > 1e-6 {
+				t.Errorf("expected CNPY reserve back at 1000, got %v", reserve)
+			}
+		})
+
+		t.Run(name+" sell more than total supply", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(100))
+
+			if _, err := curve.Sell(pool, big.NewFloat(101)); !errors.Is(err, ErrInsufficientTokens) {
+				t.Errorf("expected ErrInsufficientTokens, got %v", err)
+			}
+		})
+
+		t.Run(name+" sell past the start of the curve", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1), big.NewFloat(800000000), big.NewFloat(800000000))
+
+			if _, err := curve.Sell(pool, big.NewFloat(700000000)); !errors.Is(err, ErrInsufficientReserve) {
+				t.Errorf("expected ErrInsufficientReserve, got %v", err)
+			}
+		})
+
+		t.Run(name+" simulation doesn't modify original pool", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(100000))
+
+			if _, err := curve.SimulateSell(pool, big.NewFloat(1000)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pool.CNPYReserve.Cmp(big.NewFloat(1000)) != 0 || pool.TotalSupply.Cmp(big.NewFloat(100000)) != 0 {
+				t.Errorf("simulation modified the pool")
+			}
+		})
+	}
+}
+
+func TestSupplyCurve_ExactOut(t *testing.T) {
+	config := NewBondingCurveConfig()
+	curves := make(map[string]Curve)
+	for _, params := range []CurveParams{
+		{Type: CurveLinear, Slope: 0.00000001},
+		{Type: CurveExponential, Slope: 0.00000001},
+		{Type: CurveCappedSigmoid, Slope: 0.00000001, MaxPrice: 1, MidpointSupply: 400000000},
+	} {
+		curve, err := NewCurve(params, config)
+		if err != nil {
+			t.Fatalf("failed to create %s curve: %v", params.Type, err)
+		}
+		curves[string(params.Type)] = curve
+	}
+
+	for name, curve := range curves {
+		t.Run(name+" buy round trips through Buy", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(0))
+
+			result, err := curve.BuyExactOut(pool, big.NewFloat(1000000))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			bought, err := curve.Buy(pool, result.AmountIn)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertClose(t, "tokens bought", bought.AmountOut, big.NewFloat(1000000), 1e-9)
+		})
+
+		t.Run(name+" sell round trips through Sell", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(50000))
+
+			result, err := curve.SellExactOut(pool, big.NewFloat(100))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sold, err := curve.Sell(pool, result.AmountIn)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertClose(t, "CNPY received", sold.AmountOut, big.NewFloat(100), 1e-9)
+			assertClose(t, "CNPY reserve", result.NewCNPYReserve, sold.NewCNPYReserve, 1e-9)
+		})
+
+		t.Run(name+" buy beyond the token reserve", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(10), big.NewFloat(0))
+
+			if _, err := curve.BuyExactOut(pool, big.NewFloat(11)); !errors.Is(err, ErrInsufficientReserve) {
+				t.Errorf("expected ErrInsufficientReserve, got %v", err)
+			}
+		})
+
+		t.Run(name+" sell beyond the CNPY reserve", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(800000000))
+
+			// 990 CNPY after the fee is the whole reserve before it
+			if _, err := curve.SellExactOut(pool, big.NewFloat(991)); !errors.Is(err, ErrInsufficientReserve) {
+				t.Errorf("expected ErrInsufficientReserve, got %v", err)
+			}
+		})
+
+		t.Run(name+" sell more than total supply", func(t *testing.T) {
+			pool := NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(100))
+
+			if _, err := curve.SellExactOut(pool, big.NewFloat(100)); !errors.Is(err, ErrInsufficientTokens) {
+				t.Errorf("expected ErrInsufficientTokens, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSupplyCurve_UnitsRoundTowardPool(t *testing.T) {
+	for name, curve := range supplyCurves(t) {
+		t.Run(name+" never pays back more than a buy spent", func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 500; i++ {
+				// Amounts from 1 uCNPY up, where rounding matters most
+				pool := NewIntPool(big.NewInt(rng.Int63n(100000000000)), new(big.Int).Mul(big.NewInt(800000000), big.NewInt(1000000)), big.NewInt(0), uint8(rng.Intn(MaxTokenDecimals+1)))
+				spent := big.NewInt(rng.Int63n(1000000) + 1)
+				if i%2 == 0 {
+					spent.Mul(spent, big.NewInt(rng.Int63n(100000)+1))
+				}
+
+				bought, err := curve.BuyUnits(pool, spent)
+				if errors.Is(err, ErrInvalidAmount) {
+					continue
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				after := pool.After(bought)
+				sold, err := curve.SellUnits(after, bought.AmountOut)
+				if errors.Is(err, ErrInvalidAmount) {
+					continue
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if sold.AmountOut.Cmp(spent) > 0 {
+					t.Fatalf("%s uCNPY bought %s base units that sold for %s uCNPY", spent.String(), bought.AmountOut.String(), sold.AmountOut.String())
+				}
+				if sold.NewCNPYReserve.Cmp(pool.CNPYReserve) < 0 {
+					t.Fatalf("round trip left the reserve at %s uCNPY, below the %s it started at", sold.NewCNPYReserve.String(), pool.CNPYReserve.String())
+				}
+			}
+		})
+	}
+}
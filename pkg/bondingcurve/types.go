@@ -14,7 +14,9 @@ type VirtualPool struct {
 
 // TradeResult represents the result of a buy or sell operation
 type TradeResult struct {
+	AmountIn        *big.Float `json:"amount_in"`         // CNPY spent (buy) or tokens sold (sell)
 	AmountOut       *big.Float `json:"amount_out"`        // tokens received (buy) or CNPY received (sell)
+	Fee             *big.Float `json:"fee"`               // CNPY fee, taken from AmountIn (buy) or before AmountOut (sell)
 	NewCNPYReserve  *big.Float `json:"new_cnpy_reserve"`  // updated CNPY reserve after trade
 	NewTokenReserve *big.Float `json:"new_token_reserve"` // updated token reserve after trade
 	NewTotalSupply  *big.Float `json:"new_total_supply"`  // updated total supply after trade
@@ -22,6 +24,97 @@ type TradeResult struct {
 	PriceImpact     *big.Float `json:"price_impact"`      // price impact percentage
 }
 
+// Curve prices trades against a virtual pool. The curve families differ in
+// how the price moves as tokens are bought and sold, and share the fee and
+// initial price settings of a BondingCurveConfig.
+type Curve interface {
+	// Type reports the curve family
+	Type() CurveType
+
+	// Buy prices spending cnpyAmountIn CNPY on tokens
+	Buy(pool *VirtualPool, cnpyAmountIn *big.Float) (*TradeResult, error)
+
+	// Sell prices selling tokenAmountIn tokens back for CNPY
+	Sell(pool *VirtualPool, tokenAmountIn *big.Float) (*TradeResult, error)
+
+	// SimulateBuy prices a buy against a copy of the pool
+	SimulateBuy(pool *VirtualPool, cnpyAmountIn *big.Float) (*TradeResult, error)
+
+	// SimulateSell prices a sell against a copy of the pool
+	SimulateSell(pool *VirtualPool, tokenAmountIn *big.Float) (*TradeResult, error)
+
+	// BuyExactOut prices the CNPY needed to receive exactly tokenAmountOut
+	// tokens after the fee
+	BuyExactOut(pool *VirtualPool, tokenAmountOut *big.Float) (*TradeResult, error)
+
+	// SellExactOut prices the tokens that must be sold to receive exactly
+	// cnpyAmountOut CNPY after the fee
+	SellExactOut(pool *VirtualPool, cnpyAmountOut *big.Float) (*TradeResult, error)
+
+	// SimulateBuyExactOut prices an exact-output buy against a copy of the pool
+	SimulateBuyExactOut(pool *VirtualPool, tokenAmountOut *big.Float) (*TradeResult, error)
+
+	// SimulateSellExactOut prices an exact-output sell against a copy of the pool
+	SimulateSellExactOut(pool *VirtualPool, cnpyAmountOut *big.Float) (*TradeResult, error)
+
+	// BuyUnits prices spending cnpyAmountIn uCNPY on tokens, in base units
+	BuyUnits(pool *IntPool, cnpyAmountIn *big.Int) (*IntTradeResult, error)
+
+	// SellUnits prices selling tokenAmountIn token base units for uCNPY
+	SellUnits(pool *IntPool, tokenAmountIn *big.Int) (*IntTradeResult, error)
+
+	// BuyExactOutUnits prices the BuyUnits trade that spends the least uCNPY
+	// to receive at least tokenAmountOut token base units
+	BuyExactOutUnits(pool *IntPool, tokenAmountOut *big.Int) (*IntTradeResult, error)
+
+	// SellExactOutUnits prices the SellUnits trade that sells the fewest token
+	// base units to receive at least cnpyAmountOut uCNPY after the fee
+	SellExactOutUnits(pool *IntPool, cnpyAmountOut *big.Int) (*IntTradeResult, error)
+
+	// MarginalPrice is the CNPY per whole token the curve quotes for the next
+	// token bought at the pool's state
+	MarginalPrice(pool *IntPool) *big.Float
+
+	// CheckInvariant checks that a trade in base units took the pool from
+	// before to after as the curve allows, returning ErrInvariantViolated
+	// if not
+	CheckInvariant(before, after *IntPool) error
+
+	// GetConfig returns the fee and initial price settings
+	GetConfig() *BondingCurveConfig
+}
+
+// CurveType names a bonding curve family
+type CurveType string
+
+// Bonding curve families
+const (
+	// CurveConstantProduct prices from the ratio of the pool's reserves, as
+	// pump.fun does
+	CurveConstantProduct CurveType = "constant_product"
+
+	// CurveLinear raises the price by Slope CNPY for every token sold
+	CurveLinear CurveType = "linear"
+
+	// CurveExponential grows the price by a factor of e every 1/Slope tokens
+	CurveExponential CurveType = "exponential"
+
+	// CurveCappedSigmoid rises slowly, steepest at MidpointSupply tokens,
+	// and levels off at MaxPrice
+	CurveCappedSigmoid CurveType = "capped_sigmoid"
+)
+
+// CurveParams selects a curve family and shapes it. Slope is the linear
+// price increase per token, or the growth rate of the exponential and
+// sigmoid curves. MaxPrice and MidpointSupply are used by the capped sigmoid
+// only.
+type CurveParams struct {
+	Type           CurveType
+	Slope          float64
+	MaxPrice       float64
+	MidpointSupply float64
+}
+
 // BondingCurveConfig contains configuration for the bonding curve
 type BondingCurveConfig struct {
 	FeeRateBasisPoints uint64     `json:"fee_rate_basis_points"` // Fee rate in basis points (e.g., 100 = 1%)
@@ -53,6 +146,8 @@ var (
 	ErrZeroAmount          = errors.New("trade amount must be greater than zero")
 	ErrInsufficientTokens  = errors.New("insufficient tokens for sell")
 	ErrPoolNotInitialized  = errors.New("virtual pool not properly initialized")
+	ErrUnknownCurve        = errors.New("unknown bonding curve type")
+	ErrInvalidCurveParams  = errors.New("invalid bonding curve parameters")
 )
 
 // NewVirtualPool creates a new virtual pool with initial reserves
@@ -127,6 +222,23 @@ func (config *BondingCurveConfig) ApplyFee(amount *big.Float) *big.Float {
 	return amountAfterFee
 }
 
+// AmountBeforeFee inverts ApplyFee, returning the amount that is
+// amountAfterFee once the fee is taken. A fee of 100% leaves nothing to
+// invert and returns ErrInvalidAmount.
+func (config *BondingCurveConfig) AmountBeforeFee(amountAfterFee *big.Float) (*big.Float, error) {
+	if config.FeeRateBasisPoints >= BasisPointsDivisor {
+		return nil, ErrInvalidAmount
+	}
+	if config.FeeRateBasisPoints == 0 {
+		return new(big.Float).Copy(amountAfterFee), nil
+	}
+
+	amount := new(big.Float).Mul(amountAfterFee, new(big.Float).SetUint64(BasisPointsDivisor))
+	amount.Quo(amount, new(big.Float).SetUint64(BasisPointsDivisor-config.FeeRateBasisPoints))
+
+	return amount, nil
+}
+
 // CalculateFee calculates the fee amount for a given input
 func (config *BondingCurveConfig) CalculateFee(amount *big.Float) *big.Float {
 	if config.FeeRateBasisPoints == 0 {
@@ -0,0 +1,270 @@
+package bondingcurve
+
+import (
+	"errors"
+	"math/big"
+)
+
+// The integer mode prices trades in base units: uCNPY for CNPY and a chain's
+// token base units, 10^TokenDecimals to the token. Every division rounds in
+// the pool's favour: amounts paid out are rounded down and fees rounded up,
+// so rounding never moves value out of the pool, and the curve's invariant
+// can be checked exactly after each trade.
+
+// MicroCNPYPerCNPY is the number of uCNPY in one CNPY
+const MicroCNPYPerCNPY = 1000000
+
+// MaxTokenDecimals bounds the token decimals of an IntPool. Callers keep
+// balances in uint64 base units, and at 6 decimals that still holds a supply of
+// 18 trillion tokens. The chains table enforces the same limit.
+const MaxTokenDecimals = 6
+
+// ErrInvariantViolated indicates that a trade would leave the pool in a state
+// its curve does not allow
+var ErrInvariantViolated = errors.New("bonding curve invariant violated")
+
+// IntPool is the state of a virtual pool in base units
+type IntPool struct {
+	CNPYReserve   *big.Int // uCNPY
+	TokenReserve  *big.Int // token base units left on the curve
+	TotalSupply   *big.Int // token base units held outside the pool
+	TokenDecimals uint8
+}
+
+// IntTradeResult is the result of a trade priced in base units. AmountOut is
+// in uCNPY for a sell and token base units for a buy. Fee is always uCNPY: on a
+// buy it is taken from AmountIn before the rest reaches the curve, and on a
+// sell from the CNPY the curve releases before AmountOut is paid. Either way it
+// leaves the pool's reserves. Price and PriceImpact are in CNPY per whole token
+// and percent, for display.
+type IntTradeResult struct {
+	AmountIn        *big.Int
+	AmountOut       *big.Int
+	Fee             *big.Int
+	NewCNPYReserve  *big.Int
+	NewTokenReserve *big.Int
+	NewTotalSupply  *big.Int
+	Price           *big.Float
+	PriceImpact     *big.Float
+}
+
+// NewIntPool creates a pool in base units from copies of the given amounts
+func NewIntPool(cnpyReserve, tokenReserve, totalSupply *big.Int, tokenDecimals uint8) *IntPool {
+	return &IntPool{
+		CNPYReserve:   new(big.Int).Set(cnpyReserve),
+		TokenReserve:  new(big.Int).Set(tokenReserve),
+		TotalSupply:   new(big.Int).Set(totalSupply),
+		TokenDecimals: tokenDecimals,
+	}
+}
+
+// Copy creates a deep copy of the pool
+func (p *IntPool) Copy() *IntPool {
+	return NewIntPool(p.CNPYReserve, p.TokenReserve, p.TotalSupply, p.TokenDecimals)
+}
+
+// After returns the pool as a trade leaves it
+func (p *IntPool) After(result *IntTradeResult) *IntPool {
+	return NewIntPool(result.NewCNPYReserve, result.NewTokenReserve, result.NewTotalSupply, p.TokenDecimals)
+}
+
+// Validate checks that the pool holds a valid state
+func (p *IntPool) Validate() error {
+	if p.CNPYReserve == nil || p.TokenReserve == nil || p.TotalSupply == nil || p.TokenDecimals > MaxTokenDecimals {
+		return ErrPoolNotInitialized
+	}
+	if p.CNPYReserve.Sign() < 0 || p.TokenReserve.Sign() < 0 || p.TotalSupply.Sign() < 0 {
+		return ErrPoolNotInitialized
+	}
+	return nil
+}
+
+// TokenUnit is the number of base units in one whole token
+func (p *IntPool) TokenUnit() *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.TokenDecimals)), nil)
+}
+
+// CNPY converts uCNPY to CNPY
+func (p *IntPool) CNPY(micro *big.Int) *big.Float {
+	return unitsToFloat(micro, big.NewInt(MicroCNPYPerCNPY))
+}
+
+// Tokens converts token base units to whole tokens
+func (p *IntPool) Tokens(units *big.Int) *big.Float {
+	return unitsToFloat(units, p.TokenUnit())
+}
+
+// SpotPrice is the pool's reserve ratio in CNPY per whole token
+func (p *IntPool) SpotPrice() *big.Float {
+	if p.TokenReserve.Sign() == 0 {
+		return big.NewFloat(0)
+	}
+	return new(big.Float).SetPrec(Precision).Quo(p.CNPY(p.CNPYReserve), p.Tokens(p.TokenReserve))
+}
+
+// effectivePrice is the CNPY per whole token a trade of cnpy uCNPY for tokens
+// base units works out at
+func (p *IntPool) effectivePrice(cnpy, tokens *big.Int) *big.Float {
+	if tokens.Sign() == 0 {
+		return big.NewFloat(0)
+	}
+	return new(big.Float).SetPrec(Precision).Quo(p.CNPY(cnpy), p.Tokens(tokens))
+}
+
+// TradeResult converts a trade against the pool to whole CNPY and tokens
+func (r *IntTradeResult) TradeResult(pool *IntPool, buy bool) *TradeResult {
+	amountOut := pool.CNPY(r.AmountOut)
+	if buy {
+		amountOut = pool.Tokens(r.AmountOut)
+	}
+	amountIn := pool.Tokens(r.AmountIn)
+	if buy {
+		amountIn = pool.CNPY(r.AmountIn)
+	}
+	return &TradeResult{
+		AmountIn:        amountIn,
+		AmountOut:       amountOut,
+		Fee:             pool.CNPY(r.Fee),
+		NewCNPYReserve:  pool.CNPY(r.NewCNPYReserve),
+		NewTokenReserve: pool.Tokens(r.NewTokenReserve),
+		NewTotalSupply:  pool.Tokens(r.NewTotalSupply),
+		Price:           r.Price,
+		PriceImpact:     r.PriceImpact,
+	}
+}
+
+// checkTrade checks what every curve's trades must preserve: no reserve goes
+// negative and tokens only move between the pool and holders
+func checkTrade(before, after *IntPool) error {
+	if after.CNPYReserve.Sign() < 0 || after.TokenReserve.Sign() < 0 || after.TotalSupply.Sign() < 0 {
+		return ErrInvariantViolated
+	}
+	tokensBefore := new(big.Int).Add(before.TokenReserve, before.TotalSupply)
+	tokensAfter := new(big.Int).Add(after.TokenReserve, after.TotalSupply)
+	if tokensBefore.Cmp(tokensAfter) != 0 {
+		return ErrInvariantViolated
+	}
+	return nil
+}
+
+// ApplyFeeUnits returns amount less the fee, rounded down
+func (config *BondingCurveConfig) ApplyFeeUnits(amount *big.Int) *big.Int {
+	afterFee := new(big.Int).Mul(amount, big.NewInt(int64(BasisPointsDivisor-config.FeeRateBasisPoints)))
+	return afterFee.Quo(afterFee, big.NewInt(BasisPointsDivisor))
+}
+
+// FeeUnits returns the fee on amount, rounded up so that amount less the fee
+// is ApplyFeeUnits(amount)
+func (config *BondingCurveConfig) FeeUnits(amount *big.Int) *big.Int {
+	return new(big.Int).Sub(amount, config.ApplyFeeUnits(amount))
+}
+
+// AmountBeforeFeeUnits returns the largest amount that is amountAfterFee once
+// the fee is taken. A fee of 100% leaves nothing to invert and returns
+// ErrInvalidAmount.
+func (config *BondingCurveConfig) AmountBeforeFeeUnits(amountAfterFee *big.Int) (*big.Int, error) {
+	if config.FeeRateBasisPoints >= BasisPointsDivisor {
+		return nil, ErrInvalidAmount
+	}
+
+	// The largest amount with amount * (D - fee) / D < amountAfterFee + 1
+	amount := new(big.Int).Add(amountAfterFee, big.NewInt(1))
+	amount.Mul(amount, big.NewInt(BasisPointsDivisor))
+	amount.Sub(amount, big.NewInt(1))
+	return amount.Quo(amount, big.NewInt(int64(BasisPointsDivisor-config.FeeRateBasisPoints))), nil
+}
+
+// amountWithFeeUnits returns the least amount that is at least amountAfterFee
+// once the fee is taken
+func (config *BondingCurveConfig) amountWithFeeUnits(amountAfterFee *big.Int) (*big.Int, error) {
+	if amountAfterFee.Sign() <= 0 {
+		return new(big.Int), nil
+	}
+	amount, err := config.AmountBeforeFeeUnits(new(big.Int).Sub(amountAfterFee, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	return amount.Add(amount, big.NewInt(1)), nil
+}
+
+// quoCeil divides a by a positive b, rounding up
+func quoCeil(a, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	if r.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// maxSearchDoublings bounds how far leastUnits searches above its estimate
+const maxSearchDoublings = 128
+
+// leastUnits returns the least positive amount that enough holds for, searching
+// out from estimate in doubling steps and then bisecting. enough must hold for
+// every amount above one it holds for. If it doesn't hold within
+// maxSearchDoublings steps of the estimate, leastUnits returns ErrInvalidAmount.
+func leastUnits(estimate *big.Int, enough func(*big.Int) bool) (*big.Int, error) {
+	one := big.NewInt(1)
+	if estimate.Cmp(one) < 0 {
+		estimate = one
+	}
+
+	// enough holds at hi and not at lo, or lo is zero
+	lo, hi := new(big.Int), new(big.Int)
+	step := big.NewInt(1)
+	if enough(estimate) {
+		hi.Set(estimate)
+		for {
+			lo.Sub(hi, step)
+			if lo.Sign() <= 0 {
+				lo.SetInt64(0)
+				break
+			}
+			if !enough(lo) {
+				break
+			}
+			hi.Set(lo)
+			step.Lsh(step, 1)
+		}
+	} else {
+		lo.Set(estimate)
+		for i := 0; ; i++ {
+			if i == maxSearchDoublings {
+				return nil, ErrInvalidAmount
+			}
+			hi.Add(lo, step)
+			if enough(hi) {
+				break
+			}
+			lo.Set(hi)
+			step.Lsh(step, 1)
+		}
+	}
+
+	for new(big.Int).Sub(hi, lo).Cmp(one) > 0 {
+		mid := new(big.Int).Add(lo, hi)
+		mid.Rsh(mid, 1)
+		if enough(mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, nil
+}
+
+// unitsToFloat divides an amount in base units by the size of one unit
+func unitsToFloat(amount, unit *big.Int) *big.Float {
+	value := new(big.Float).SetPrec(Precision).SetInt(amount)
+	return value.Quo(value, new(big.Float).SetPrec(Precision).SetInt(unit))
+}
+
+// floatToUnits converts a whole amount to base units, rounding down. Negative
+// amounts are zero.
+func floatToUnits(amount float64, unit *big.Int) *big.Int {
+	if !(amount > 0) {
+		return new(big.Int)
+	}
+	units, _ := new(big.Float).SetPrec(Precision).Mul(big.NewFloat(amount), new(big.Float).SetInt(unit)).Int(nil)
+	return units
+}
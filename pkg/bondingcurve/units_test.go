@@ -0,0 +1,450 @@
+package bondingcurve
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// intPool builds a pool in base units with 6 token decimals
+func intPool(cnpyReserve, tokenReserve, totalSupply int64) *IntPool {
+	return NewIntPool(big.NewInt(cnpyReserve), big.NewInt(tokenReserve), big.NewInt(totalSupply), 6)
+}
+
+func TestIntPool_Validate(t *testing.T) {
+	pool := NewIntPool(big.NewInt(1), big.NewInt(1), big.NewInt(0), MaxTokenDecimals)
+	if err := pool.Validate(); err != nil {
+		t.Errorf("unexpected error for %d decimals: %v", MaxTokenDecimals, err)
+	}
+	pool.TokenDecimals = MaxTokenDecimals + 1
+	if err := pool.Validate(); !errors.Is(err, ErrPoolNotInitialized) {
+		t.Errorf("expected ErrPoolNotInitialized for %d decimals, got %v", pool.TokenDecimals, err)
+	}
+}
+
+func TestApplyFeeUnits(t *testing.T) {
+	config := NewBondingCurveConfig()
+
+	tests := []struct {
+		amount int64
+		want   int64
+	}{
+		{10000, 9900},
+		{199, 197}, // 197.01, rounded down
+		{1, 0},
+		{0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := config.ApplyFeeUnits(big.NewInt(tt.amount)); got.Int64() != tt.want {
+			t.Errorf("ApplyFeeUnits(%d) = %s, want %d", tt.amount, got.String(), tt.want)
+		}
+		if fee := config.FeeUnits(big.NewInt(tt.amount)); fee.Int64() != tt.amount-tt.want {
+			t.Errorf("FeeUnits(%d) = %s, want %d", tt.amount, fee.String(), tt.amount-tt.want)
+		}
+	}
+}
+
+func TestAmountBeforeFeeUnits(t *testing.T) {
+	config := NewBondingCurveConfig()
+
+	for _, afterFee := range []int64{0, 1, 98, 99, 100, 9900, 123456789} {
+		amount, err := config.AmountBeforeFeeUnits(big.NewInt(afterFee))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := config.ApplyFeeUnits(amount); got.Int64() != afterFee {
+			t.Errorf("ApplyFeeUnits(AmountBeforeFeeUnits(%d)) = %s", afterFee, got.String())
+		}
+		if got := config.ApplyFeeUnits(new(big.Int).Add(amount, big.NewInt(1))); got.Int64() <= afterFee {
+			t.Errorf("AmountBeforeFeeUnits(%d) = %s is not the largest such amount", afterFee, amount.String())
+		}
+	}
+
+	if _, err := (&BondingCurveConfig{FeeRateBasisPoints: BasisPointsDivisor}).AmountBeforeFeeUnits(big.NewInt(1)); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount for a 100%% fee, got %v", err)
+	}
+}
+
+func TestBondingCurve_BuyUnits(t *testing.T) {
+	bc := NewBondingCurve(NewBondingCurveConfig())
+
+	t.Run("takes the fee in uCNPY and rounds the tokens out down", func(t *testing.T) {
+		pool := intPool(1000, 3000, 0)
+
+		// A fee of 0.07 uCNPY rounds up to 1, leaving 6 for the curve:
+		// 6 * 3000 / 1006 = 17.89 tokens, 17 after rounding
+		result, err := bc.BuyUnits(pool, big.NewInt(7))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.AmountOut.Int64() != 17 || result.Fee.Int64() != 1 {
+			t.Errorf("expected 17 tokens and a fee of 1, got %s and %s", result.AmountOut.String(), result.Fee.String())
+		}
+		if result.NewCNPYReserve.Int64() != 1006 || result.NewTokenReserve.Int64() != 2983 || result.NewTotalSupply.Int64() != 17 {
+			t.Errorf("unexpected pool state %s/%s/%s", result.NewCNPYReserve.String(), result.NewTokenReserve.String(), result.NewTotalSupply.String())
+		}
+		if err := bc.CheckInvariant(pool, pool.After(result)); err != nil {
+			t.Errorf("invariant: %v", err)
+		}
+	})
+
+	t.Run("matches the float curve", func(t *testing.T) {
+		// 1,000 CNPY and 800M tokens, buying with 100 CNPY
+		pool := NewIntPool(big.NewInt(1000000000), new(big.Int).Mul(big.NewInt(800000000), big.NewInt(1000000)), big.NewInt(0), 6)
+
+		result, err := bc.BuyUnits(pool, big.NewInt(100000000))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected, err := bc.Buy(NewVirtualPool(big.NewFloat(1000), big.NewFloat(800000000), big.NewFloat(0)), big.NewFloat(100))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fee, _ := pool.CNPY(result.Fee).Float64(); fee != 1 || expected.Fee.Cmp(big.NewFloat(1)) != 0 {
+			t.Errorf("expected both to take a fee of 1 CNPY, got %v and %s", fee, expected.Fee.String())
+		}
+
+		got, _ := pool.Tokens(result.AmountOut).Float64()
+		want, _ := expected.AmountOut.Float64()
+		if want-got < 0 || want-got > 0.000002 {
+			t.Errorf("expected %v tokens to the base unit, got %v", want, got)
+		}
+	})
+
+	t.Run("amount too small to buy a base unit", func(t *testing.T) {
+		pool := intPool(1000000, 10, 0)
+
+		if _, err := bc.BuyUnits(pool, big.NewInt(1)); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("expected ErrInvalidAmount, got %v", err)
+		}
+	})
+
+	t.Run("zero amount", func(t *testing.T) {
+		if _, err := bc.BuyUnits(intPool(1000, 3000, 0), big.NewInt(0)); !errors.Is(err, ErrZeroAmount) {
+			t.Errorf("expected ErrZeroAmount, got %v", err)
+		}
+	})
+
+	t.Run("unfunded pool", func(t *testing.T) {
+		if _, err := bc.BuyUnits(intPool(0, 0, 0), big.NewInt(100)); !errors.Is(err, ErrInsufficientReserve) {
+			t.Errorf("expected ErrInsufficientReserve, got %v", err)
+		}
+	})
+}
+
+func TestBondingCurve_SellUnits(t *testing.T) {
+	bc := NewBondingCurve(NewBondingCurveConfig())
+
+	t.Run("rounds the CNPY out down and keeps the fee in the pool", func(t *testing.T) {
+		pool := intPool(1007, 2981, 19)
+
+		// 19 * 1007 / 3000 = 6.38 uCNPY, 6 after rounding and 5.94 after the fee
+		result, err := bc.SellUnits(pool, big.NewInt(19))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.AmountOut.Int64() != 5 || result.Fee.Int64() != 1 {
+			t.Errorf("expected 5 uCNPY and a fee of 1, got %s and %s", result.AmountOut.String(), result.Fee.String())
+		}
+		if result.NewCNPYReserve.Int64() != 1001 || result.NewTokenReserve.Int64() != 3000 || result.NewTotalSupply.Int64() != 0 {
+			t.Errorf("unexpected pool state %s/%s/%s", result.NewCNPYReserve.String(), result.NewTokenReserve.String(), result.NewTotalSupply.String())
+		}
+	})
+
+	t.Run("sell more than total supply", func(t *testing.T) {
+		if _, err := bc.SellUnits(intPool(1000, 3000, 10), big.NewInt(11)); !errors.Is(err, ErrInsufficientTokens) {
+			t.Errorf("expected ErrInsufficientTokens, got %v", err)
+		}
+	})
+}
+
+func TestBondingCurve_ExactOutUnits(t *testing.T) {
+	bc := NewBondingCurve(NewBondingCurveConfig())
+
+	t.Run("buy inverts BuyUnits", func(t *testing.T) {
+		// 17 tokens take ceil(17 * 1000 / 2983) = 6 uCNPY on the curve, and
+		// 7 uCNPY is the least that leaves 6 after the fee
+		result, err := bc.BuyExactOutUnits(intPool(1000, 3000, 0), big.NewInt(17))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.AmountIn.Int64() != 7 || result.AmountOut.Int64() != 17 || result.Fee.Int64() != 1 {
+			t.Errorf("expected 7 uCNPY for 17 tokens and a fee of 1, got %s, %s and %s", result.AmountIn.String(), result.AmountOut.String(), result.Fee.String())
+		}
+	})
+
+	t.Run("sell inverts SellUnits", func(t *testing.T) {
+		// 5 uCNPY after the fee needs 6 from the curve, which takes
+		// ceil(6 * 2981 / 1001) = 18 tokens
+		result, err := bc.SellExactOutUnits(intPool(1007, 2981, 19), big.NewInt(5))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.AmountIn.Int64() != 18 || result.AmountOut.Int64() != 5 || result.Fee.Int64() != 1 {
+			t.Errorf("expected 18 tokens for 5 uCNPY and a fee of 1, got %s, %s and %s", result.AmountIn.String(), result.AmountOut.String(), result.Fee.String())
+		}
+	})
+
+	t.Run("whole token reserve", func(t *testing.T) {
+		if _, err := bc.BuyExactOutUnits(intPool(1000, 3000, 0), big.NewInt(3000)); !errors.Is(err, ErrInsufficientReserve) {
+			t.Errorf("expected ErrInsufficientReserve, got %v", err)
+		}
+	})
+
+	t.Run("whole CNPY reserve", func(t *testing.T) {
+		if _, err := bc.SellExactOutUnits(intPool(1000, 3000, 3000), big.NewInt(990)); !errors.Is(err, ErrInsufficientReserve) {
+			t.Errorf("expected ErrInsufficientReserve, got %v", err)
+		}
+	})
+
+	t.Run("more tokens than supply", func(t *testing.T) {
+		// 10 tokens return at most 3 uCNPY
+		if _, err := bc.SellExactOutUnits(intPool(1000, 3000, 10), big.NewInt(3)); !errors.Is(err, ErrInsufficientTokens) {
+			t.Errorf("expected ErrInsufficientTokens, got %v", err)
+		}
+	})
+
+	t.Run("zero amount", func(t *testing.T) {
+		if _, err := bc.BuyExactOutUnits(intPool(1000, 3000, 0), big.NewInt(0)); !errors.Is(err, ErrZeroAmount) {
+			t.Errorf("expected ErrZeroAmount, got %v", err)
+		}
+		if _, err := bc.SellExactOutUnits(intPool(1000, 3000, 10), big.NewInt(0)); !errors.Is(err, ErrZeroAmount) {
+			t.Errorf("expected ErrZeroAmount, got %v", err)
+		}
+	})
+}
+
+func TestCurve_ExactOutUnitsRoundTrip(t *testing.T) {
+	config := NewBondingCurveConfig()
+	curves := map[string]Curve{"constant_product": NewBondingCurve(config)}
+	for _, params := range []CurveParams{
+		{Type: CurveLinear, Slope: 0.00000001},
+		{Type: CurveExponential, Slope: 0.00000001},
+		{Type: CurveCappedSigmoid, Slope: 0.00000001, MaxPrice: 1, MidpointSupply: 400000000},
+	} {
+		curve, err := NewCurve(params, config)
+		if err != nil {
+			t.Fatalf("failed to create %s curve: %v", params.Type, err)
+		}
+		curves[string(params.Type)] = curve
+	}
+
+	for name, curve := range curves {
+		// 1,000 CNPY and 800M tokens, after a 5,000 CNPY buy
+		pool := NewIntPool(big.NewInt(1000000000), new(big.Int).Mul(big.NewInt(800000000), big.NewInt(1000000)), big.NewInt(0), 6)
+		bought, err := curve.BuyUnits(pool, big.NewInt(5000000000))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		pool = pool.After(bought)
+
+		t.Run(name+" buys spend the least uCNPY BuyUnits accepts", func(t *testing.T) {
+			for _, tokens := range []int64{1, 999999, 1000000, 123456789012, 50000000000000} {
+				want := big.NewInt(tokens)
+				result, err := curve.BuyExactOutUnits(pool, want)
+				if err != nil {
+					t.Fatalf("%d tokens: unexpected error: %v", tokens, err)
+				}
+				if result.AmountOut.Cmp(want) < 0 {
+					t.Errorf("%d tokens: bought only %s", tokens, result.AmountOut.String())
+				}
+
+				executed, err := curve.BuyUnits(pool, result.AmountIn)
+				if err != nil {
+					t.Fatalf("%d tokens: unexpected error: %v", tokens, err)
+				}
+				if executed.AmountOut.Cmp(result.AmountOut) != 0 || executed.Fee.Cmp(result.Fee) != 0 || executed.NewCNPYReserve.Cmp(result.NewCNPYReserve) != 0 {
+					t.Errorf("%d tokens: BuyUnits executes %s tokens for a fee of %s, quoted %s for %s", tokens, executed.AmountOut.String(), executed.Fee.String(), result.AmountOut.String(), result.Fee.String())
+				}
+
+				less, err := curve.BuyUnits(pool, new(big.Int).Sub(result.AmountIn, big.NewInt(1)))
+				if err == nil && less.AmountOut.Cmp(want) >= 0 {
+					t.Errorf("%d tokens: %s uCNPY is not the least, one less buys %s", tokens, result.AmountIn.String(), less.AmountOut.String())
+				}
+			}
+		})
+
+		t.Run(name+" sells the fewest tokens SellUnits pays for", func(t *testing.T) {
+			for _, micro := range []int64{1, 999999, 1000000, 123456789, 4000000000} {
+				want := big.NewInt(micro)
+				result, err := curve.SellExactOutUnits(pool, want)
+				if err != nil {
+					t.Fatalf("%d uCNPY: unexpected error: %v", micro, err)
+				}
+				if result.AmountOut.Cmp(want) < 0 {
+					t.Errorf("%d uCNPY: paid only %s", micro, result.AmountOut.String())
+				}
+
+				executed, err := curve.SellUnits(pool, result.AmountIn)
+				if err != nil {
+					t.Fatalf("%d uCNPY: unexpected error: %v", micro, err)
+				}
+				if executed.AmountOut.Cmp(result.AmountOut) != 0 || executed.Fee.Cmp(result.Fee) != 0 || executed.NewCNPYReserve.Cmp(result.NewCNPYReserve) != 0 {
+					t.Errorf("%d uCNPY: SellUnits executes %s uCNPY for a fee of %s, quoted %s for %s", micro, executed.AmountOut.String(), executed.Fee.String(), result.AmountOut.String(), result.Fee.String())
+				}
+
+				less, err := curve.SellUnits(pool, new(big.Int).Sub(result.AmountIn, big.NewInt(1)))
+				if err == nil && less.AmountOut.Cmp(want) >= 0 {
+					t.Errorf("%d uCNPY: %s tokens is not the fewest, one less pays %s", micro, result.AmountIn.String(), less.AmountOut.String())
+				}
+			}
+		})
+
+		t.Run(name+" inverts an exact-in buy", func(t *testing.T) {
+			spent := big.NewInt(250000000)
+			bought, err := curve.BuyUnits(pool, spent)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result, err := curve.BuyExactOutUnits(pool, bought.AmountOut)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.AmountIn.Cmp(spent) > 0 {
+				t.Errorf("expected at most %s uCNPY for %s tokens, got %s", spent.String(), bought.AmountOut.String(), result.AmountIn.String())
+			}
+		})
+	}
+}
+
+func TestCurve_UnitsInvariant(t *testing.T) {
+	curves := supplyCurves(t)
+	curves["constant_product"] = NewBondingCurve(NewBondingCurveConfig())
+
+	for name, curve := range curves {
+		t.Run(name+" holds across many trades", func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			pool := NewIntPool(big.NewInt(1000000000), new(big.Int).Mul(big.NewInt(800000000), big.NewInt(1000000)), big.NewInt(0), 6)
+
+			for i := 0; i < 2000; i++ {
+				var result *IntTradeResult
+				var err error
+				if pool.TotalSupply.Sign() > 0 && rng.Intn(2) == 0 {
+					result, err = curve.SellUnits(pool, new(big.Int).Rand(rng, pool.TotalSupply))
+				} else {
+					result, err = curve.BuyUnits(pool, big.NewInt(rng.Int63n(50000000000)+1))
+				}
+				if errors.Is(err, ErrZeroAmount) || errors.Is(err, ErrInvalidAmount) {
+					continue
+				}
+				if err != nil {
+					t.Fatalf("trade %d failed: %v", i, err)
+				}
+
+				after := pool.After(result)
+				if err := curve.CheckInvariant(pool, after); err != nil {
+					t.Fatalf("trade %d: %v", i, err)
+				}
+				pool = after
+			}
+		})
+
+		t.Run(name+" rejects a trade that overpays", func(t *testing.T) {
+			pool := NewIntPool(big.NewInt(1000000000), new(big.Int).Mul(big.NewInt(800000000), big.NewInt(1000000)), big.NewInt(0), 6)
+
+			result, err := curve.BuyUnits(pool, big.NewInt(100000000))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Hand out tokens beyond what was paid for
+			result.NewTokenReserve.Sub(result.NewTokenReserve, result.NewTotalSupply)
+			result.NewTotalSupply.Add(result.NewTotalSupply, result.NewTotalSupply)
+			if err := curve.CheckInvariant(pool, pool.After(result)); !errors.Is(err, ErrInvariantViolated) {
+				t.Errorf("expected ErrInvariantViolated, got %v", err)
+			}
+		})
+	}
+
+	for name, curve := range supplyCurves(t) {
+		pool := NewIntPool(big.NewInt(1000000000), new(big.Int).Mul(big.NewInt(800000000), big.NewInt(1000000)), big.NewInt(0), 6)
+		bought, err := curve.BuyUnits(pool, big.NewInt(5000000000))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		pool = pool.After(bought)
+
+		t.Run(name+" rejects a buy that mints one base unit too many", func(t *testing.T) {
+			result, err := curve.BuyUnits(pool, big.NewInt(123456789))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := curve.CheckInvariant(pool, pool.After(result)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			after := pool.After(result)
+			after.TokenReserve.Sub(after.TokenReserve, big.NewInt(1))
+			after.TotalSupply.Add(after.TotalSupply, big.NewInt(1))
+			if err := curve.CheckInvariant(pool, after); !errors.Is(err, ErrInvariantViolated) {
+				t.Errorf("expected ErrInvariantViolated, got %v", err)
+			}
+		})
+
+		t.Run(name+" rejects a sell that pays one uCNPY too many", func(t *testing.T) {
+			result, err := curve.SellUnits(pool, big.NewInt(987654321))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := curve.CheckInvariant(pool, pool.After(result)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			after := pool.After(result)
+			after.CNPYReserve.Sub(after.CNPYReserve, big.NewInt(1))
+			if err := curve.CheckInvariant(pool, after); !errors.Is(err, ErrInvariantViolated) {
+				t.Errorf("expected ErrInvariantViolated, got %v", err)
+			}
+		})
+
+		t.Run(name+" rejects a sell that drains the reserve", func(t *testing.T) {
+			// The supply was bought on top of the pool's first 1,000 CNPY, which stays
+			after := pool.Copy()
+			after.TokenReserve.Add(after.TokenReserve, pool.TotalSupply)
+			after.TotalSupply.SetInt64(0)
+			after.CNPYReserve.SetInt64(0)
+			if err := curve.CheckInvariant(pool, after); !errors.Is(err, ErrInvariantViolated) {
+				t.Errorf("expected ErrInvariantViolated, got %v", err)
+			}
+		})
+	}
+
+	t.Run("tokens must be conserved", func(t *testing.T) {
+		bc := NewBondingCurve(NewBondingCurveConfig())
+		if err := bc.CheckInvariant(intPool(1000, 3000, 0), intPool(1000, 3000, 1)); !errors.Is(err, ErrInvariantViolated) {
+			t.Errorf("expected ErrInvariantViolated, got %v", err)
+		}
+	})
+}
+
+func TestCurve_MarginalPrice(t *testing.T) {
+	bc := NewBondingCurve(NewBondingCurveConfig())
+
+	t.Run("constant product quotes the reserve ratio", func(t *testing.T) {
+		// 1,000 CNPY against 800M tokens
+		pool := NewIntPool(big.NewInt(1000000000), new(big.Int).Mul(big.NewInt(800000000), big.NewInt(1000000)), big.NewInt(0), 6)
+		assertClose(t, "price", bc.MarginalPrice(pool), big.NewFloat(0.00000125), 1e-12)
+	})
+
+	t.Run("constant product quotes the initial price before funding", func(t *testing.T) {
+		assertClose(t, "price", bc.MarginalPrice(intPool(0, 0, 0)), big.NewFloat(DefaultInitialPrice), 1e-12)
+	})
+
+	for name, curve := range supplyCurves(t) {
+		t.Run(name+" brackets the effective price of a buy", func(t *testing.T) {
+			pool := NewIntPool(big.NewInt(0), new(big.Int).Mul(big.NewInt(800000000), big.NewInt(1000000)), big.NewInt(0), 6)
+
+			result, err := curve.BuyUnits(pool, big.NewInt(100000000))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			before := curve.MarginalPrice(pool)
+			after := curve.MarginalPrice(pool.After(result))
+			if before.Cmp(result.Price) > 0 || after.Cmp(result.Price) < 0 {
+				t.Errorf("expected %s <= %s <= %s", before.String(), result.Price.String(), after.String())
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"log"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -29,7 +32,7 @@ func Connect(databaseURL string) (*sqlx.DB, error) {
 }
 
 // Transaction executes a function within a database transaction
-func Transaction(db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+func Transaction(db *sqlx.DB, fn func(*sqlx.Tx) error) (err error) {
 	tx, err := db.Beginx()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -50,6 +53,46 @@ func Transaction(db *sqlx.DB, fn func(*sqlx.Tx) error) error {
 	return err
 }
 
+// TryAdvisoryLock attempts to take a session-level PostgreSQL advisory lock on a
+// dedicated connection without blocking. When acquired is true the caller must
+// invoke release to unlock and return the connection to the pool.
+func TryAdvisoryLock(ctx context.Context, db *sqlx.DB, key int64) (release func(), acquired bool, err error) {
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection for advisory lock: %w", err)
+	}
+
+	if err := conn.GetContext(ctx, &acquired, "SELECT pg_try_advisory_lock($1)", key); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	release = func() {
+		defer conn.Close()
+
+		// Use a fresh context so the lock is released even if ctx was cancelled
+		var unlocked bool
+		err := conn.GetContext(context.Background(), &unlocked, "SELECT pg_advisory_unlock($1)", key)
+		if err == nil && unlocked {
+			return
+		}
+
+		// The session may still hold the lock. Discard the connection instead of
+		// returning it to the pool, which ends the session and drops the lock.
+		log.Printf("Failed to release advisory lock %d (unlocked: %v, error: %v), discarding connection", key, unlocked, err)
+		conn.Raw(func(driverConn any) error {
+			return driver.ErrBadConn
+		})
+	}
+
+	return release, true, nil
+}
+
 // sanitizeString removes null bytes from a string to ensure PostgreSQL UTF-8 compatibility
 func sanitizeString(s string) string {
 	// PostgreSQL UTF-8 encoding doesn't allow null bytes (0x00)
@@ -1,7 +1,12 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
 )
 
 func TestSanitizeString(t *testing.T) {
@@ -88,3 +93,50 @@ func TestNullString(t *testing.T) {
 		}
 	})
 }
+
+func TestTryAdvisoryLock(t *testing.T) {
+	newDB := func(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Failed to create mock database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return sqlx.NewDb(db, "sqlmock"), mock
+	}
+	lock := func(t *testing.T, db *sqlx.DB) func() {
+		release, acquired, err := TryAdvisoryLock(context.Background(), db, 42)
+		if err != nil || !acquired {
+			t.Fatalf("Expected lock to be acquired, got acquired=%v err=%v", acquired, err)
+		}
+		return release
+	}
+
+	t.Run("release unlocks and returns the connection to the pool", func(t *testing.T) {
+		db, mock := newDB(t)
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(42).WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+		mock.ExpectQuery("SELECT pg_advisory_unlock").WithArgs(42).WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+
+		lock(t, db)()
+		if open := db.Stats().OpenConnections; open != 1 {
+			t.Errorf("Expected the connection to be kept, got %d open", open)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("failed unlock discards the connection", func(t *testing.T) {
+		db, mock := newDB(t)
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(42).WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+		mock.ExpectQuery("SELECT pg_advisory_unlock").WithArgs(42).WillReturnError(errors.New("connection reset"))
+		mock.ExpectClose()
+
+		lock(t, db)()
+		if open := db.Stats().OpenConnections; open != 0 {
+			t.Errorf("Expected the connection holding the lock to be closed, got %d open", open)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
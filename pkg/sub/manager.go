@@ -27,6 +27,13 @@ func NewManager(logger Logger) *Manager {
 
 // AddSubscription adds a new root chain subscription
 func (m *Manager) AddSubscription(config Config, handler EventHandler) error {
+	return m.AddSubscriptionWithConnectHandler(config, handler, nil)
+}
+
+// AddSubscriptionWithConnectHandler adds a new root chain subscription that
+// calls onConnect after every successful connection, before events from that
+// connection are handled
+func (m *Manager) AddSubscriptionWithConnectHandler(config Config, handler EventHandler, onConnect ConnectHandler) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -39,6 +46,9 @@ func (m *Manager) AddSubscription(config Config, handler EventHandler) error {
 
 	// Create new subscription
 	subscription := NewSubscription(config, handler, m.logger)
+	if onConnect != nil {
+		subscription.SetConnectHandler(onConnect)
+	}
 	m.subscriptions[config.ChainId] = subscription
 
 	// Start the subscription
@@ -24,6 +24,7 @@ type Subscription struct {
 	conn        *websocket.Conn    // the underlying websocket connection
 	info        *lib.RootChainInfo // cached root chain info from the publisher
 	handler     EventHandler       // callback function for processing events
+	onConnect   ConnectHandler     // callback run after each successful connection
 	logger      Logger             // logging interface
 	stopCh      chan struct{}      // channel to signal shutdown
 	mu          sync.RWMutex       // mutex for thread safety
@@ -46,6 +47,14 @@ func NewSubscription(config Config, handler EventHandler, logger Logger) *Subscr
 	}
 }
 
+// SetConnectHandler registers a callback that runs after every successful
+// connection. Events are not read from the connection until it returns.
+func (s *Subscription) SetConnectHandler(handler ConnectHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onConnect = handler
+}
+
 // Start begins the subscription with automatic reconnection
 func (s *Subscription) Start() error {
 	go s.connectWithBackoff()
@@ -137,10 +146,16 @@ func (s *Subscription) connectWithBackoff() {
 		s.mu.Lock()
 		s.conn = conn
 		s.isConnected = true
+		onConnect := s.onConnect
 		s.mu.Unlock()
 
 		s.logger.Infof("Successfully connected to rootChainId=%d", s.config.ChainId)
 
+		// Let the owner catch up on anything missed before live events resume
+		if onConnect != nil {
+			onConnect()
+		}
+
 		// Start listening for messages
 		go s.listen()
 		return
@@ -1,10 +1,14 @@
 package sub
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/canopy-network/canopy/lib"
+	"github.com/gorilla/websocket"
 )
 
 // testLogger implements Logger interface using testing.T
@@ -93,6 +97,62 @@ func TestSubscriptionCreation(t *testing.T) {
 	}
 }
 
+func TestConnectHandlerRunsBeforeEvents(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		message, _ := lib.Marshal(&lib.RootChainInfo{RootChainId: 1, Height: 42})
+		conn.WriteMessage(websocket.BinaryMessage, message)
+
+		// Hold the connection open until the client goes away
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var calls []string
+	done := make(chan struct{})
+
+	handler := func(info *lib.RootChainInfo) error {
+		mu.Lock()
+		calls = append(calls, "event")
+		mu.Unlock()
+		close(done)
+		return nil
+	}
+
+	subscription := NewSubscription(Config{ChainId: 1, Url: server.URL}, handler, nil)
+	subscription.SetConnectHandler(func() {
+		// Events must wait for the connect handler, however long it takes
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		calls = append(calls, "connect")
+		mu.Unlock()
+	})
+
+	if err := subscription.Start(); err != nil {
+		t.Fatalf("Failed to start subscription: %v", err)
+	}
+	defer subscription.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2 || calls[0] != "connect" || calls[1] != "event" {
+		t.Errorf("Expected connect handler before event, got %v", calls)
+	}
+}
+
 func TestManagerOperations(t *testing.T) {
 	manager := NewManager(nil)
 
@@ -139,6 +199,48 @@ func TestManagerOperations(t *testing.T) {
 	}
 }
 
+func TestManagerConnectHandler(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Hold the connection open until the client goes away
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	manager := NewManager(nil)
+	defer manager.StopAll()
+
+	connected := make(chan uint64, 2)
+	handler := func(info *lib.RootChainInfo) error {
+		return nil
+	}
+
+	for _, chainId := range []uint64{1, 2} {
+		err := manager.AddSubscriptionWithConnectHandler(Config{ChainId: chainId, Url: server.URL}, handler, func() {
+			connected <- chainId
+		})
+		if err != nil {
+			t.Fatalf("Failed to add subscription for chainId=%d: %v", chainId, err)
+		}
+	}
+
+	seen := make(map[uint64]bool)
+	for len(seen) < 2 {
+		select {
+		case chainId := <-connected:
+			seen[chainId] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for connect handlers, got %v", seen)
+		}
+	}
+}
+
 func TestConfigStruct(t *testing.T) {
 	config := Config{
 		ChainId: 42,
@@ -49,6 +49,10 @@ type Config struct {
 // EventHandler is called when new root chain info is received
 type EventHandler func(info *lib.RootChainInfo) error
 
+// ConnectHandler is called each time the subscription (re)connects, before any
+// events from the new connection are delivered
+type ConnectHandler func()
+
 // Retry implements exponential backoff retry logic
 type Retry struct {
 	waitTimeMS uint64 // time to wait in milliseconds
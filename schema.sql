@@ -123,6 +123,7 @@ CREATE TABLE chains (
 
     -- Economic parameters
     token_total_supply BIGINT NOT NULL DEFAULT 1000000000,
+    token_decimals SMALLINT NOT NULL DEFAULT 6 CHECK (token_decimals BETWEEN 0 AND 6), -- Token base units are 10^-token_decimals of a token
     block_time_seconds INTEGER, -- Block time in seconds: 5, 10, 20, 30, 60, 120, 300, 600, 1800
     upgrade_block_height BIGINT, -- Block height for upgrades
     block_reward_amount DECIMAL(15,8), -- Block reward amount
@@ -132,7 +133,10 @@ CREATE TABLE chains (
     -- Bonding curve parameters
     initial_cnpy_reserve DECIMAL(15,8) NOT NULL DEFAULT 10000.00000000,
     initial_token_supply BIGINT NOT NULL DEFAULT 800000000,
-    bonding_curve_slope DECIMAL(15,8) NOT NULL DEFAULT 0.00000001,
+    bonding_curve_slope DECIMAL(15,8) NOT NULL DEFAULT 0.00000001, -- Linear price increase per token, or exponential and sigmoid growth rate
+    curve_type VARCHAR(20) NOT NULL DEFAULT 'constant_product' CHECK (curve_type IN ('constant_product', 'linear', 'exponential', 'capped_sigmoid')),
+    curve_max_price DECIMAL(20,8), -- Price cap of a capped_sigmoid curve
+    curve_midpoint_supply BIGINT, -- Tokens sold where a capped_sigmoid curve is steepest
 
     -- Launch configuration
     scheduled_launch_time TIMESTAMP WITH TIME ZONE,
@@ -146,13 +150,27 @@ CREATE TABLE chains (
 
     -- Network and deployment info
     chain_id VARCHAR(50) UNIQUE, -- Set when chain is deployed
+    root_chain_id BIGINT NOT NULL DEFAULT 1, -- Root chain that takes deposits for the virtual pool and anchors the deployed chain
     genesis_hash VARCHAR(64), -- Set when chain is deployed
     validator_min_stake DECIMAL(15,8) DEFAULT 1000.00000000,
 
+    -- Genesis token allocation, in basis points of token_total_supply
+    allocation_creator_bps INTEGER NOT NULL DEFAULT 0,
+    allocation_treasury_bps INTEGER NOT NULL DEFAULT 0,
+    allocation_liquidity_bps INTEGER NOT NULL DEFAULT 2000,
+    allocation_holders_bps INTEGER NOT NULL DEFAULT 8000,
+
     -- Audit trail
     created_by UUID NOT NULL REFERENCES users(id),
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    -- Allocation buckets must cover the whole supply
+    CONSTRAINT chains_allocation_check CHECK (
+        allocation_creator_bps >= 0 AND allocation_treasury_bps >= 0 AND
+        allocation_liquidity_bps >= 0 AND allocation_holders_bps >= 0 AND
+        allocation_creator_bps + allocation_treasury_bps + allocation_liquidity_bps + allocation_holders_bps = 10000
+    )
 );
 
 -- GitHub repository connections for chain development and auto-upgrade functionality
@@ -229,6 +247,11 @@ CREATE TABLE virtual_pools (
     current_price_cnpy DECIMAL(15,8) NOT NULL DEFAULT 0,
     market_cap_usd DECIMAL(15,2) NOT NULL DEFAULT 0,
 
+    -- Exact reserves that trades are priced from; cnpy_reserve and token_reserve are
+    -- derived from them for display, token_reserve rounded down, and are never read back
+    cnpy_reserve_ucnpy NUMERIC(30,0) NOT NULL DEFAULT 0,
+    token_reserve_units NUMERIC(30,0) NOT NULL DEFAULT 0, -- Token base units
+
     -- Trading metrics
     total_volume_cnpy DECIMAL(15,8) NOT NULL DEFAULT 0,
     total_transactions INTEGER NOT NULL DEFAULT 0,
@@ -265,18 +288,25 @@ CREATE TABLE virtual_pool_transactions (
     token_amount BIGINT NOT NULL,
     price_per_token_cnpy DECIMAL(15,8) NOT NULL,
 
+    -- Exact amounts traded, in uCNPY and token base units; cnpy_amount, token_amount and
+    -- the pool_*_after columns are derived from them for display
+    cnpy_amount_ucnpy NUMERIC(30,0) NOT NULL DEFAULT 0,
+    token_amount_units NUMERIC(30,0) NOT NULL DEFAULT 0,
+
     -- Fees and slippage
     trading_fee_cnpy DECIMAL(15,8) NOT NULL DEFAULT 0,
     slippage_percent DECIMAL(8,4) DEFAULT 0,
 
     -- Blockchain transaction details
-    transaction_hash VARCHAR(66), -- Set when transaction is executed
+    transaction_hash VARCHAR(66), -- Set when transaction is executed; root chain deposits are unique per hash and height
     block_height BIGINT,
     gas_used INTEGER,
 
     -- State after transaction
     pool_cnpy_reserve_after DECIMAL(15,8) NOT NULL,
     pool_token_reserve_after BIGINT NOT NULL,
+    pool_cnpy_reserve_after_ucnpy NUMERIC(30,0) NOT NULL DEFAULT 0,
+    pool_token_reserve_after_units NUMERIC(30,0) NOT NULL DEFAULT 0,
     market_cap_after_usd DECIMAL(15,2) NOT NULL,
 
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
@@ -297,6 +327,109 @@ CREATE TABLE user_virtual_positions (
     total_cnpy_withdrawn DECIMAL(15,8) NOT NULL DEFAULT 0,
     average_entry_price_cnpy DECIMAL(15,8) NOT NULL DEFAULT 0,
 
+    -- Exact balance and totals; token_balance, total_cnpy_invested and total_cnpy_withdrawn
+    -- are derived from them for display, token_balance rounded down, and are never read back
+    token_balance_units NUMERIC(30,0) NOT NULL DEFAULT 0, -- Token base units
+    total_cnpy_invested_ucnpy NUMERIC(30,0) NOT NULL DEFAULT 0,
+    total_cnpy_withdrawn_ucnpy NUMERIC(30,0) NOT NULL DEFAULT 0,
+
+    -- Performance metrics
+    unrealized_pnl_cnpy DECIMAL(15,8) DEFAULT 0,
+    realized_pnl_cnpy DECIMAL(15,8) DEFAULT 0,
+    total_return_percent DECIMAL(8,4) DEFAULT 0,
+
+    -- Position status
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    first_purchase_at TIMESTAMP WITH TIME ZONE,
+    last_activity_at TIMESTAMP WITH TIME ZONE,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    -- Ensure one position per user per chain
+    UNIQUE(user_id, chain_id)
+);
+
+-- Liquidity pool state for chains that have graduated to their own network
+-- Seeded from the final virtual pool reserves and tracks trading on the live chain
+CREATE TABLE graduated_pools (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    chain_id UUID NOT NULL REFERENCES chains(id) ON DELETE CASCADE,
+
+    -- Current pool state
+    cnpy_reserve DECIMAL(15,8) NOT NULL DEFAULT 0,
+    token_reserve BIGINT NOT NULL DEFAULT 0,
+    current_price_cnpy DECIMAL(15,8) NOT NULL DEFAULT 0,
+    market_cap_usd DECIMAL(15,2) NOT NULL DEFAULT 0,
+
+    -- Trading metrics
+    total_volume_cnpy DECIMAL(15,8) NOT NULL DEFAULT 0,
+    total_transactions INTEGER NOT NULL DEFAULT 0,
+    unique_traders INTEGER NOT NULL DEFAULT 0,
+
+    -- Pool status
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    -- Performance tracking
+    price_24h_change_percent DECIMAL(8,4) DEFAULT 0,
+    volume_24h_cnpy DECIMAL(15,8) DEFAULT 0,
+    high_24h_cnpy DECIMAL(15,8) DEFAULT 0,
+    low_24h_cnpy DECIMAL(15,8) DEFAULT 0,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    -- Ensure one pool per chain
+    UNIQUE(chain_id)
+);
+
+-- Swaps and liquidity deposits/withdrawals within graduated pools
+CREATE TABLE graduated_pool_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    graduated_pool_id UUID NOT NULL REFERENCES graduated_pools(id) ON DELETE CASCADE,
+    chain_id UUID NOT NULL REFERENCES chains(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+
+    -- Transaction details
+    transaction_type VARCHAR(20) NOT NULL CHECK (transaction_type IN ('buy', 'sell', 'liquidity_deposit', 'liquidity_withdrawal')),
+    cnpy_amount DECIMAL(15,8) NOT NULL,
+    token_amount BIGINT NOT NULL,
+    price_per_token_cnpy DECIMAL(15,8) NOT NULL,
+
+    -- Fees and slippage
+    trading_fee_cnpy DECIMAL(15,8) NOT NULL DEFAULT 0,
+    slippage_percent DECIMAL(8,4) DEFAULT 0,
+
+    -- Blockchain transaction details
+    transaction_hash VARCHAR(66),
+    block_height BIGINT,
+    gas_used INTEGER,
+
+    -- State after transaction
+    pool_cnpy_reserve_after DECIMAL(15,8) NOT NULL,
+    pool_token_reserve_after BIGINT NOT NULL,
+    market_cap_after_usd DECIMAL(15,2) NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- User positions in graduated pools
+-- Carried over from user_virtual_positions when a chain graduates
+CREATE TABLE user_graduated_positions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    user_id UUID NOT NULL REFERENCES users(id),
+    chain_id UUID NOT NULL REFERENCES chains(id),
+    graduated_pool_id UUID NOT NULL REFERENCES graduated_pools(id),
+
+    -- Current position
+    token_balance BIGINT NOT NULL DEFAULT 0,
+    total_cnpy_invested DECIMAL(15,8) NOT NULL DEFAULT 0,
+    total_cnpy_withdrawn DECIMAL(15,8) NOT NULL DEFAULT 0,
+    average_entry_price_cnpy DECIMAL(15,8) NOT NULL DEFAULT 0,
+
     -- Performance metrics
     unrealized_pnl_cnpy DECIMAL(15,8) DEFAULT 0,
     realized_pnl_cnpy DECIMAL(15,8) DEFAULT 0,
@@ -350,6 +483,28 @@ CREATE TABLE chain_assets (
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
 );
 
+-- Vesting schedules locking part of a creator's genesis allocation
+-- Each schedule is emitted into genesis as stakes released to the beneficiary over time
+CREATE TABLE chain_vesting_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    chain_id UUID NOT NULL REFERENCES chains(id) ON DELETE CASCADE,
+
+    -- Address receiving the tokens as they vest
+    beneficiary_address VARCHAR(42) NOT NULL,
+
+    -- Share of total supply, carved out of the creator bucket
+    allocation_bps INTEGER NOT NULL CHECK (allocation_bps > 0 AND allocation_bps <= 10000),
+
+    -- Nothing vests before the cliff; vesting is linear until the end of the duration
+    cliff_days INTEGER NOT NULL DEFAULT 0,
+    duration_days INTEGER NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CHECK (cliff_days >= 0 AND duration_days > 0 AND cliff_days <= duration_days)
+);
+
 -- Cryptographic keys for chain operations and governance
 -- Stores encrypted private keys, public keys, and addresses for each chain
 -- Uses Argon2 + AES-GCM encryption compatible with crypto.EncryptedPrivateKey
@@ -381,6 +536,151 @@ CREATE TABLE chain_keys (
     UNIQUE(address) -- Address must be globally unique
 );
 
+-- Persisted graduation progress for chains that reached their graduation threshold
+-- Each step is recorded as it completes so graduation can resume after a failure
+CREATE TABLE chain_graduations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    chain_id UUID NOT NULL REFERENCES chains(id) ON DELETE CASCADE,
+
+    -- Last step that completed successfully
+    current_step VARCHAR(30) NOT NULL CHECK (current_step IN ('threshold_reached', 'genesis_generated', 'genesis_hash_recorded', 'deployment_requested', 'deployment_confirmed', 'pool_migrated')),
+
+    -- Genesis produced during graduation
+    genesis_file TEXT,
+    genesis_hash VARCHAR(66),
+
+    -- Retry tracking for the next step
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    next_retry_at TIMESTAMP WITH TIME ZONE,
+
+    completed_at TIMESTAMP WITH TIME ZONE,
+
+    -- Deployment progress reported by the deployer callback
+    deployment_status VARCHAR(20) CHECK (deployment_status IN ('provisioning', 'running', 'failed')),
+    deployment_rpc_url VARCHAR(500),
+    deployment_message TEXT,
+    deployment_updated_at TIMESTAMP WITH TIME ZONE,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    -- Ensure one graduation per chain
+    UNIQUE(chain_id)
+);
+
+-- Last root chain height the newblock worker has fully processed
+-- Read on startup and after each reconnect to backfill blocks missed while disconnected
+CREATE TABLE root_chain_checkpoints (
+    root_chain_id BIGINT PRIMARY KEY,
+    height BIGINT NOT NULL CHECK (height >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Root chain deposits that have been observed but are not yet final
+-- Rows are removed once their height is applied to the virtual pool
+CREATE TABLE pending_deposits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    root_chain_id BIGINT NOT NULL,
+    chain_id UUID NOT NULL REFERENCES chains(id) ON DELETE CASCADE,
+
+    -- Send details, amount in uCNPY
+    sender_address VARCHAR(42) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    transaction_hash VARCHAR(66) NOT NULL,
+    block_height BIGINT NOT NULL,
+
+    -- Root chain height at which the deposit is applied
+    final_height BIGINT NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CHECK (final_height >= block_height)
+);
+
+-- CNPY owed to users, paid from the chain's operation key on the root chain
+-- Sell proceeds and deposit refunds are queued in the same transaction as the trade or deposit that produced them
+CREATE TABLE payouts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    chain_id UUID NOT NULL REFERENCES chains(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    virtual_pool_transaction_id UUID REFERENCES virtual_pool_transactions(id),
+
+    payout_type VARCHAR(20) NOT NULL CHECK (payout_type IN ('sell_proceeds', 'inactive_refund', 'cap_refund', 'slippage_refund')),
+
+    -- Root chain recipient and amount in uCNPY
+    recipient_address VARCHAR(42) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+
+    -- Identifies the request that created the payout so it is queued at most once
+    reference VARCHAR(66) NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'submitted', 'confirmed', 'failed')),
+
+    -- The signed send, kept so retries rebroadcast the same transaction
+    tx_hash VARCHAR(66),
+    signed_tx TEXT,
+    tx_created_height BIGINT,
+    confirmed_height BIGINT,
+
+    -- Retry tracking
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- On-chain events that could not be processed, kept so they can be retried instead of lost
+-- A deposit is keyed by its transaction hash, an order by its order ID
+CREATE TABLE failed_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    event_type VARCHAR(20) NOT NULL CHECK (event_type IN ('deposit', 'order')),
+    reference VARCHAR(66) NOT NULL,
+    chain_id UUID REFERENCES chains(id) ON DELETE CASCADE,
+    block_height BIGINT,
+
+    -- The event as received, in JSON, so it can be replayed unchanged
+    raw_event TEXT NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'failed', 'resolved', 'discarded')),
+
+    -- Retry tracking
+    attempts INTEGER NOT NULL DEFAULT 1,
+    last_error TEXT NOT NULL,
+    next_retry_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Each trade's fee split among the protocol treasury, the chain creator and the referrer, in uCNPY
+-- Written with the trade; an accrual is claimable until a payout settles it
+CREATE TABLE fee_accruals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    chain_id UUID NOT NULL REFERENCES chains(id),
+    virtual_pool_transaction_id UUID NOT NULL REFERENCES virtual_pool_transactions(id),
+
+    -- Protocol accruals belong to the treasury; the others to a user
+    recipient_type VARCHAR(20) NOT NULL CHECK (recipient_type IN ('protocol', 'creator', 'referrer')),
+    recipient_user_id UUID REFERENCES users(id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+
+    payout_id UUID REFERENCES payouts(id),
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fee_accruals_recipient_check CHECK ((recipient_type = 'protocol') = (recipient_user_id IS NULL))
+);
+
 -- Trigger to update the updated_at timestamp on record modification
 CREATE OR REPLACE FUNCTION update_updated_at_column()
 RETURNS TRIGGER AS $$
@@ -399,8 +699,13 @@ CREATE TRIGGER update_repos_updated_at BEFORE UPDATE ON chain_repositories FOR E
 CREATE TRIGGER update_social_updated_at BEFORE UPDATE ON chain_social_links FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_pools_updated_at BEFORE UPDATE ON virtual_pools FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_positions_updated_at BEFORE UPDATE ON user_virtual_positions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_graduated_pools_updated_at BEFORE UPDATE ON graduated_pools FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_graduated_positions_updated_at BEFORE UPDATE ON user_graduated_positions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_assets_updated_at BEFORE UPDATE ON chain_assets FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_chain_keys_updated_at BEFORE UPDATE ON chain_keys FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_graduations_updated_at BEFORE UPDATE ON chain_graduations FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_payouts_updated_at BEFORE UPDATE ON payouts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_failed_events_updated_at BEFORE UPDATE ON failed_events FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
 -- Create indexes separately
 -- Indexes for chains table
@@ -409,6 +714,7 @@ CREATE INDEX idx_chains_creator ON chains (created_by);
 CREATE INDEX idx_chains_template ON chains (template_id);
 CREATE INDEX idx_chains_launch_time ON chains (scheduled_launch_time);
 CREATE INDEX idx_chains_graduation ON chains (is_graduated, graduation_time);
+CREATE INDEX idx_chains_root_chain ON chains (root_chain_id);
 
 -- Indexes for chain_templates table
 CREATE INDEX idx_templates_category ON chain_templates (template_category);
@@ -445,6 +751,7 @@ CREATE INDEX idx_vp_transactions_user ON virtual_pool_transactions (user_id);
 CREATE INDEX idx_vp_transactions_chain ON virtual_pool_transactions (chain_id);
 CREATE INDEX idx_vp_transactions_time ON virtual_pool_transactions (created_at DESC);
 CREATE INDEX idx_vp_transactions_type ON virtual_pool_transactions (transaction_type);
+CREATE UNIQUE INDEX idx_vp_transactions_hash_height ON virtual_pool_transactions (transaction_hash, block_height) WHERE transaction_hash IS NOT NULL;
 
 -- Indexes for user_virtual_positions table
 CREATE INDEX idx_positions_user ON user_virtual_positions (user_id);
@@ -452,18 +759,63 @@ CREATE INDEX idx_positions_chain ON user_virtual_positions (chain_id);
 CREATE INDEX idx_positions_active ON user_virtual_positions (is_active);
 CREATE INDEX idx_positions_pnl ON user_virtual_positions (unrealized_pnl_cnpy DESC);
 
+-- Indexes for graduated_pools table
+CREATE INDEX idx_graduated_pools_active ON graduated_pools (is_active);
+CREATE INDEX idx_graduated_pools_market_cap ON graduated_pools (market_cap_usd DESC);
+
+-- Indexes for graduated_pool_transactions table
+CREATE INDEX idx_gp_transactions_pool ON graduated_pool_transactions (graduated_pool_id);
+CREATE INDEX idx_gp_transactions_user ON graduated_pool_transactions (user_id);
+CREATE INDEX idx_gp_transactions_chain ON graduated_pool_transactions (chain_id);
+CREATE INDEX idx_gp_transactions_time ON graduated_pool_transactions (created_at DESC);
+CREATE INDEX idx_gp_transactions_type ON graduated_pool_transactions (transaction_type);
+
+-- Indexes for user_graduated_positions table
+CREATE INDEX idx_graduated_positions_user ON user_graduated_positions (user_id);
+CREATE INDEX idx_graduated_positions_chain ON user_graduated_positions (chain_id);
+CREATE INDEX idx_graduated_positions_active ON user_graduated_positions (is_active);
+
 -- Indexes for chain_assets table
 CREATE INDEX idx_assets_chain ON chain_assets (chain_id);
 CREATE INDEX idx_assets_type ON chain_assets (asset_type);
 CREATE INDEX idx_assets_primary ON chain_assets (is_primary);
 CREATE INDEX idx_assets_moderation ON chain_assets (moderation_status);
 
+-- Indexes for chain_vesting_schedules table
+CREATE INDEX idx_vesting_schedules_chain ON chain_vesting_schedules (chain_id);
+
 -- Indexes for chain_keys table
 CREATE INDEX idx_chain_keys_chain ON chain_keys (chain_id);
 CREATE INDEX idx_chain_keys_address ON chain_keys (address);
 CREATE INDEX idx_chain_keys_active ON chain_keys (is_active);
 CREATE INDEX idx_chain_keys_purpose ON chain_keys (key_purpose);
 
+-- Indexes for chain_graduations table
+CREATE INDEX idx_graduations_pending ON chain_graduations (next_retry_at) WHERE completed_at IS NULL;
+
+-- Indexes for pending_deposits table
+CREATE INDEX idx_pending_deposits_chain ON pending_deposits (chain_id);
+CREATE UNIQUE INDEX idx_pending_deposits_hash_height ON pending_deposits (transaction_hash, block_height);
+CREATE INDEX idx_pending_deposits_root_chain_height ON pending_deposits (root_chain_id, block_height);
+
+-- Indexes for payouts table
+CREATE INDEX idx_payouts_chain ON payouts (chain_id);
+CREATE UNIQUE INDEX idx_payouts_reference ON payouts (reference);
+CREATE INDEX idx_payouts_status ON payouts (status, next_attempt_at);
+CREATE INDEX idx_payouts_tx_hash ON payouts (tx_hash);
+CREATE INDEX idx_payouts_user ON payouts (user_id);
+
+-- Indexes for failed_events table
+CREATE INDEX idx_failed_events_chain ON failed_events (chain_id);
+CREATE UNIQUE INDEX idx_failed_events_reference ON failed_events (event_type, reference);
+CREATE INDEX idx_failed_events_status ON failed_events (status, next_retry_at);
+
+-- Indexes for fee_accruals table
+CREATE INDEX idx_fee_accruals_chain ON fee_accruals (chain_id);
+CREATE INDEX idx_fee_accruals_claimable ON fee_accruals (recipient_type, recipient_user_id) WHERE payout_id IS NULL;
+CREATE INDEX idx_fee_accruals_recipient ON fee_accruals (recipient_user_id);
+CREATE INDEX idx_fee_accruals_transaction ON fee_accruals (virtual_pool_transaction_id);
+
 -- General-purpose wallet keypairs for various purposes (users, chains, treasury, etc.)
 -- Stores encrypted BLS12-381 keypairs using Argon2 + AES-GCM encryption
 -- This table stores flexible-purpose wallets, while chain_keys is for chain-specific operational keys
@@ -2,6 +2,7 @@ package fixtures
 
 import (
 	"context"
+	"math"
 	"time"
 
 	"github.com/enielson/launchpad/internal/models"
@@ -115,6 +116,8 @@ type ChainFixture struct {
 	InitialCNPYReserve float64
 	InitialTokenSupply int64
 	BondingCurveSlope  float64
+	CurveType          string
+	RootChainID        uint64
 }
 
 // DefaultChain returns a chain fixture with default values
@@ -132,6 +135,8 @@ func DefaultChain(creatorID uuid.UUID) *ChainFixture {
 		InitialCNPYReserve: 100.0,
 		InitialTokenSupply: 800000000,
 		BondingCurveSlope:  0.00000001,
+		CurveType:          models.CurveTypeConstantProduct,
+		RootChainID:        models.DefaultRootChainID,
 	}
 }
 
@@ -147,6 +152,12 @@ func (c *ChainFixture) WithStatus(status string) *ChainFixture {
 	return c
 }
 
+// WithRootChain sets the root chain the chain takes deposits on
+func (c *ChainFixture) WithRootChain(rootChainID uint64) *ChainFixture {
+	c.RootChainID = rootChainID
+	return c
+}
+
 // WithTokenSymbol sets the token symbol
 func (c *ChainFixture) WithTokenSymbol(symbol string) *ChainFixture {
 	c.TokenSymbol = symbol
@@ -161,15 +172,21 @@ func (c *ChainFixture) WithBondingCurve(cnpyReserve float64, tokenSupply int64,
 	return c
 }
 
+// WithCurveType sets the bonding curve family the chain trades on
+func (c *ChainFixture) WithCurveType(curveType string) *ChainFixture {
+	c.CurveType = curveType
+	return c
+}
+
 // Create persists the chain to the database (works with *sqlx.DB or *sqlx.Tx)
 func (c *ChainFixture) Create(ctx context.Context, db sqlx.ExtContext) (*models.Chain, error) {
 	query := `
 		INSERT INTO chains (
 			chain_name, token_symbol, chain_description, template_id, consensus_mechanism,
 			token_total_supply, created_by, status, initial_cnpy_reserve,
-			initial_token_supply, bonding_curve_slope
+			initial_token_supply, bonding_curve_slope, curve_type, root_chain_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -185,6 +202,8 @@ func (c *ChainFixture) Create(ctx context.Context, db sqlx.ExtContext) (*models.
 		InitialCNPYReserve: c.InitialCNPYReserve,
 		InitialTokenSupply: c.InitialTokenSupply,
 		BondingCurveSlope:  c.BondingCurveSlope,
+		CurveType:          c.CurveType,
+		RootChainID:        c.RootChainID,
 	}
 
 	result := struct {
@@ -196,7 +215,8 @@ func (c *ChainFixture) Create(ctx context.Context, db sqlx.ExtContext) (*models.
 	err := sqlx.GetContext(ctx, db, &result, query,
 		chain.ChainName, chain.TokenSymbol, chain.ChainDescription, chain.TemplateID,
 		chain.ConsensusMechanism, chain.TokenTotalSupply, chain.CreatedBy, chain.Status,
-		&chain.InitialCNPYReserve, &chain.InitialTokenSupply, &chain.BondingCurveSlope)
+		&chain.InitialCNPYReserve, &chain.InitialTokenSupply, &chain.BondingCurveSlope, chain.CurveType,
+		chain.RootChainID)
 
 	if err != nil {
 		return nil, err
@@ -325,10 +345,10 @@ func (p *VirtualPoolFixture) WithReserves(cnpy float64, tokens int64) *VirtualPo
 func (p *VirtualPoolFixture) Create(ctx context.Context, db sqlx.ExtContext) (*models.VirtualPool, error) {
 	query := `
 		INSERT INTO virtual_pools (
-			chain_id, cnpy_reserve, token_reserve, current_price_cnpy,
-			total_transactions, is_active
+			chain_id, cnpy_reserve, token_reserve, cnpy_reserve_ucnpy, token_reserve_units,
+			current_price_cnpy, total_transactions, is_active
 		)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -336,6 +356,9 @@ func (p *VirtualPoolFixture) Create(ctx context.Context, db sqlx.ExtContext) (*m
 		ChainID:           p.ChainID,
 		CNPYReserve:       p.CNPYReserve,
 		TokenReserve:      p.TokenReserve,
+		CNPYReserveMicro:  microCNPY(p.CNPYReserve),
+		TokenReserveUnits: tokenUnits(p.TokenReserve),
+		TokenDecimals:     models.DefaultTokenDecimals,
 		CurrentPriceCNPY:  p.CurrentPriceCNPY,
 		TotalTransactions: p.TotalTransactions,
 		IsActive:          p.IsActive,
@@ -348,8 +371,8 @@ func (p *VirtualPoolFixture) Create(ctx context.Context, db sqlx.ExtContext) (*m
 	}{}
 
 	err := sqlx.GetContext(ctx, db, &result, query,
-		pool.ChainID, pool.CNPYReserve, pool.TokenReserve, pool.CurrentPriceCNPY,
-		pool.TotalTransactions, pool.IsActive)
+		pool.ChainID, pool.CNPYReserve, pool.TokenReserve, pool.CNPYReserveMicro, pool.TokenReserveUnits,
+		pool.CurrentPriceCNPY, pool.TotalTransactions, pool.IsActive)
 
 	if err != nil {
 		return nil, err
@@ -403,22 +426,25 @@ func (p *UserPositionFixture) Create(ctx context.Context, db sqlx.ExtContext) (*
 	query := `
 		INSERT INTO user_virtual_positions (
 			user_id, chain_id, virtual_pool_id, token_balance, total_cnpy_invested,
+			token_balance_units, total_cnpy_invested_ucnpy,
 			average_entry_price_cnpy, unrealized_pnl_cnpy, realized_pnl_cnpy, is_active
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at, updated_at
 	`
 
 	position := &models.UserVirtualLPPosition{
-		UserID:                p.UserID,
-		ChainID:               p.ChainID,
-		VirtualPoolID:         p.VirtualPoolID,
-		TokenBalance:          p.TokenBalance,
-		TotalCNPYInvested:     p.TotalCNPYInvested,
-		AverageEntryPriceCNPY: p.AverageEntryPriceCNPY,
-		UnrealizedPnlCNPY:     p.UnrealizedPnlCNPY,
-		RealizedPnlCNPY:       p.RealizedPnlCNPY,
-		IsActive:              p.IsActive,
+		UserID:                 p.UserID,
+		ChainID:                p.ChainID,
+		VirtualPoolID:          p.VirtualPoolID,
+		TokenBalance:           p.TokenBalance,
+		TotalCNPYInvested:      p.TotalCNPYInvested,
+		TokenBalanceUnits:      tokenUnits(p.TokenBalance),
+		TotalCNPYInvestedMicro: microCNPY(p.TotalCNPYInvested),
+		AverageEntryPriceCNPY:  p.AverageEntryPriceCNPY,
+		UnrealizedPnlCNPY:      p.UnrealizedPnlCNPY,
+		RealizedPnlCNPY:        p.RealizedPnlCNPY,
+		IsActive:               p.IsActive,
 	}
 
 	result := struct {
@@ -429,7 +455,8 @@ func (p *UserPositionFixture) Create(ctx context.Context, db sqlx.ExtContext) (*
 
 	err := sqlx.GetContext(ctx, db, &result, query,
 		position.UserID, position.ChainID, position.VirtualPoolID,
-		position.TokenBalance, position.TotalCNPYInvested, position.AverageEntryPriceCNPY,
+		position.TokenBalance, position.TotalCNPYInvested,
+		position.TokenBalanceUnits, position.TotalCNPYInvestedMicro, position.AverageEntryPriceCNPY,
 		position.UnrealizedPnlCNPY, position.RealizedPnlCNPY, position.IsActive)
 
 	if err != nil {
@@ -597,9 +624,10 @@ func (t *VirtualPoolTransactionFixture) Create(ctx context.Context, db sqlx.ExtC
 			virtual_pool_id, chain_id, user_id, transaction_type, cnpy_amount, token_amount,
 			price_per_token_cnpy, trading_fee_cnpy, slippage_percent, transaction_hash,
 			block_height, gas_used, pool_cnpy_reserve_after, pool_token_reserve_after,
-			market_cap_after_usd
+			market_cap_after_usd, cnpy_amount_ucnpy, token_amount_units,
+			pool_cnpy_reserve_after_ucnpy, pool_token_reserve_after_units
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 		RETURNING id, created_at
 	`
 
@@ -612,30 +640,35 @@ func (t *VirtualPoolTransactionFixture) Create(ctx context.Context, db sqlx.ExtC
 		t.VirtualPoolID, t.ChainID, t.UserID, t.TransactionType, t.CNPYAmount,
 		t.TokenAmount, t.PricePerTokenCNPY, t.TradingFeeCNPY, t.SlippagePercent,
 		t.TransactionHash, t.BlockHeight, t.GasUsed, t.PoolCNPYReserveAfter,
-		t.PoolTokenReserveAfter, t.MarketCapAfterUSD)
+		t.PoolTokenReserveAfter, t.MarketCapAfterUSD, microCNPY(t.CNPYAmount), tokenUnits(t.TokenAmount),
+		microCNPY(t.PoolCNPYReserveAfter), tokenUnits(t.PoolTokenReserveAfter))
 
 	if err != nil {
 		return nil, err
 	}
 
 	transaction := &models.VirtualPoolTransaction{
-		ID:                    result.ID,
-		VirtualPoolID:         t.VirtualPoolID,
-		ChainID:               t.ChainID,
-		UserID:                t.UserID,
-		TransactionType:       t.TransactionType,
-		CNPYAmount:            t.CNPYAmount,
-		TokenAmount:           t.TokenAmount,
-		PricePerTokenCNPY:     t.PricePerTokenCNPY,
-		TradingFeeCNPY:        t.TradingFeeCNPY,
-		SlippagePercent:       t.SlippagePercent,
-		TransactionHash:       t.TransactionHash,
-		BlockHeight:           t.BlockHeight,
-		GasUsed:               t.GasUsed,
-		PoolCNPYReserveAfter:  t.PoolCNPYReserveAfter,
-		PoolTokenReserveAfter: t.PoolTokenReserveAfter,
-		MarketCapAfterUSD:     t.MarketCapAfterUSD,
-		CreatedAt:             result.CreatedAt,
+		ID:                         result.ID,
+		VirtualPoolID:              t.VirtualPoolID,
+		ChainID:                    t.ChainID,
+		UserID:                     t.UserID,
+		TransactionType:            t.TransactionType,
+		CNPYAmount:                 t.CNPYAmount,
+		TokenAmount:                t.TokenAmount,
+		CNPYAmountMicro:            microCNPY(t.CNPYAmount),
+		TokenAmountUnits:           tokenUnits(t.TokenAmount),
+		PricePerTokenCNPY:          t.PricePerTokenCNPY,
+		TradingFeeCNPY:             t.TradingFeeCNPY,
+		SlippagePercent:            t.SlippagePercent,
+		TransactionHash:            t.TransactionHash,
+		BlockHeight:                t.BlockHeight,
+		GasUsed:                    t.GasUsed,
+		PoolCNPYReserveAfter:       t.PoolCNPYReserveAfter,
+		PoolTokenReserveAfter:      t.PoolTokenReserveAfter,
+		PoolCNPYReserveAfterMicro:  microCNPY(t.PoolCNPYReserveAfter),
+		PoolTokenReserveAfterUnits: tokenUnits(t.PoolTokenReserveAfter),
+		MarketCapAfterUSD:          t.MarketCapAfterUSD,
+		CreatedAt:                  result.CreatedAt,
 	}
 
 	return transaction, nil
@@ -934,3 +967,14 @@ var SampleDataIDs = struct {
 		TestChainAlpha:        uuid.MustParse("550e8400-e29b-41d4-a716-446655442005"),
 	},
 }
+
+// microCNPY converts a fixture's CNPY amount to uCNPY
+func microCNPY(cnpy float64) uint64 {
+	return uint64(math.Round(cnpy * 1000000))
+}
+
+// tokenUnits converts a fixture's whole tokens to base units at the default
+// token decimals
+func tokenUnits(tokens int64) uint64 {
+	return uint64(tokens) * uint64(math.Pow10(models.DefaultTokenDecimals))
+}
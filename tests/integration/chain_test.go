@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/enielson/launchpad/tests/testutils"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -642,3 +644,187 @@ func TestUpdateChainDescriptionNonexistent(t *testing.T) {
 
 	t.Logf("Successfully handled nonexistent chain")
 }
+
+// TestUpdateChainAllocation tests replacing the genesis token allocation of a draft chain
+func TestUpdateChainAllocation(t *testing.T) {
+	var draftChainID, activeChainID uuid.UUID
+
+	// Setup: Create a draft chain and a chain whose virtual pool is already open
+	testutils.WithTestDB(t, func(db *sqlx.DB) {
+		creatorID := uuid.MustParse(testutils.TestUserID)
+
+		draftFixture := fixtures.DefaultChain(creatorID)
+		draftFixture.ChainName = fmt.Sprintf("Allocation Test Chain %d", time.Now().UnixNano())
+		draft, err := draftFixture.
+			WithTokenSymbol("ALLOC").
+			Create(context.Background(), db)
+		require.NoError(t, err)
+		draftChainID = draft.ID
+
+		activeFixture := fixtures.DefaultChain(creatorID)
+		activeFixture.ChainName = fmt.Sprintf("Allocation Active Chain %d", time.Now().UnixNano())
+		active, err := activeFixture.
+			WithTokenSymbol("ALLOCA").
+			WithStatus(models.ChainStatusVirtualActive).
+			Create(context.Background(), db)
+		require.NoError(t, err)
+		activeChainID = active.ID
+
+		t.Cleanup(func() {
+			db.ExecContext(context.Background(),
+				"DELETE FROM chains WHERE id = ANY($1)", pq.Array([]uuid.UUID{draftChainID, activeChainID}))
+		})
+	})
+
+	client := testutils.NewTestClient()
+
+	tests := []struct {
+		name           string
+		chainID        *uuid.UUID
+		request        map[string]interface{}
+		expectedStatus int
+		description    string
+	}{
+		{
+			name:    "Valid allocation",
+			chainID: &draftChainID,
+			request: map[string]interface{}{
+				"creator_bps": 1000, "treasury_bps": 500, "liquidity_bps": 500, "holders_bps": 8000,
+			},
+			expectedStatus: http.StatusOK,
+			description:    "Should replace the allocation of a draft chain",
+		},
+		{
+			name:    "Buckets do not sum to total supply",
+			chainID: &draftChainID,
+			request: map[string]interface{}{
+				"creator_bps": 1000, "treasury_bps": 0, "liquidity_bps": 500, "holders_bps": 8000,
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should fail when the buckets sum to less than 10000 basis points",
+		},
+		{
+			name:    "Holders bucket below virtual pool supply",
+			chainID: &draftChainID,
+			request: map[string]interface{}{
+				"creator_bps": 2000, "treasury_bps": 0, "liquidity_bps": 1000, "holders_bps": 7000,
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should fail when holders cannot be paid what the virtual pool sells",
+		},
+		{
+			name:    "Chain not in draft",
+			chainID: &activeChainID,
+			request: map[string]interface{}{
+				"creator_bps": 0, "treasury_bps": 0, "liquidity_bps": 2000, "holders_bps": 8000,
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			description:    "Should fail once the virtual pool is open",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updatePath := testutils.GetAPIPath(fmt.Sprintf("/chains/%s/allocation", *tt.chainID))
+			resp, body := client.Put(t, updatePath, tt.request)
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("%s: expected status %d, got %d. Body: %s",
+					tt.description, tt.expectedStatus, resp.StatusCode, string(body))
+			}
+		})
+	}
+
+	// Verify the valid allocation persisted
+	getPath := testutils.GetAPIPath(fmt.Sprintf("/chains/%s", draftChainID))
+	resp, body := client.Get(t, getPath)
+	testutils.AssertStatusOK(t, resp)
+
+	var fetchResponse struct {
+		Data models.Chain `json:"data"`
+	}
+	testutils.UnmarshalResponse(t, body, &fetchResponse)
+
+	expected := models.TokenAllocation{CreatorBps: 1000, TreasuryBps: 500, LiquidityBps: 500, HoldersBps: 8000}
+	if fetchResponse.Data.TokenAllocation != expected {
+		t.Errorf("Persisted allocation mismatch: expected=%+v, got %+v", expected, fetchResponse.Data.TokenAllocation)
+	}
+}
+
+// TestUpdateVestingSchedules tests replacing the vesting schedules of a draft chain
+func TestUpdateVestingSchedules(t *testing.T) {
+	var chainID uuid.UUID
+
+	// Setup: Create a draft chain
+	testutils.WithTestDB(t, func(db *sqlx.DB) {
+		creatorID := uuid.MustParse(testutils.TestUserID)
+
+		chainFixture := fixtures.DefaultChain(creatorID)
+		chainFixture.ChainName = fmt.Sprintf("Vesting Test Chain %d", time.Now().UnixNano())
+		chain, err := chainFixture.
+			WithTokenSymbol("VEST").
+			Create(context.Background(), db)
+		require.NoError(t, err)
+		chainID = chain.ID
+
+		t.Cleanup(func() {
+			db.ExecContext(context.Background(),
+				"DELETE FROM chains WHERE id = $1", chainID)
+		})
+	})
+
+	client := testutils.NewTestClient()
+
+	// Step 1: Give the creator a bucket to vest from
+	allocationPath := testutils.GetAPIPath(fmt.Sprintf("/chains/%s/allocation", chainID))
+	resp, body := client.Put(t, allocationPath, map[string]interface{}{
+		"creator_bps": 1000, "treasury_bps": 0, "liquidity_bps": 1000, "holders_bps": 8000,
+	})
+	testutils.AssertStatusOK(t, resp)
+
+	vestingPath := testutils.GetAPIPath(fmt.Sprintf("/chains/%s/vesting", chainID))
+	schedule := func(bps int) map[string]interface{} {
+		return map[string]interface{}{
+			"schedules": []map[string]interface{}{{
+				"beneficiary_address": "0x" + strings.Repeat("ab", 20),
+				"allocation_bps":      bps,
+				"cliff_days":          180,
+				"duration_days":       720,
+			}},
+		}
+	}
+
+	// Step 2: Schedules locking more than the creator bucket are rejected
+	resp, body = client.Put(t, vestingPath, schedule(1200))
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for over-allocated vesting, got %d. Body: %s", resp.StatusCode, string(body))
+	}
+
+	// Step 3: A schedule within the creator bucket is stored
+	resp, body = client.Put(t, vestingPath, schedule(600))
+	testutils.AssertStatusOK(t, resp)
+
+	// Step 4: The chain detail shows the schedule
+	resp, body = client.Get(t, testutils.GetAPIPath(fmt.Sprintf("/chains/%s", chainID)))
+	testutils.AssertStatusOK(t, resp)
+
+	var fetchResponse struct {
+		Data models.Chain `json:"data"`
+	}
+	testutils.UnmarshalResponse(t, body, &fetchResponse)
+
+	if len(fetchResponse.Data.VestingSchedules) != 1 {
+		t.Fatalf("Expected 1 vesting schedule, got %d", len(fetchResponse.Data.VestingSchedules))
+	}
+	if got := fetchResponse.Data.VestingSchedules[0]; got.AllocationBps != 600 || got.CliffDays != 180 || got.DurationDays != 720 {
+		t.Errorf("Persisted vesting schedule mismatch: %+v", got)
+	}
+
+	// Step 5: Shrinking the creator bucket below the schedule is rejected
+	resp, body = client.Put(t, allocationPath, map[string]interface{}{
+		"creator_bps": 500, "treasury_bps": 0, "liquidity_bps": 1500, "holders_bps": 8000,
+	})
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 when the creator bucket no longer covers vesting, got %d. Body: %s", resp.StatusCode, string(body))
+	}
+}
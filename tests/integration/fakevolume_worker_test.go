@@ -10,6 +10,7 @@ import (
 
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/postgres"
+	"github.com/enielson/launchpad/internal/services"
 	"github.com/enielson/launchpad/internal/workers/fakevolume"
 	"github.com/enielson/launchpad/tests/fixtures"
 	"github.com/enielson/launchpad/tests/testutils"
@@ -45,12 +46,13 @@ func TestFakeVolumeWorker(t *testing.T) {
 		templateRepo := postgres.NewChainTemplateRepository(db)
 		chainRepo := postgres.NewChainRepository(db, userRepo, templateRepo)
 		poolRepo := postgres.NewVirtualPoolRepository(db)
+		tradeEngine := services.NewOrderProcessorTx(db, postgres.NewVirtualPoolTxRepository(db), userRepo, nil)
 
 		// Initialize fake volume worker with very short interval for testing
 		config := fakevolume.Config{
 			Interval: 1 * time.Second,
 		}
-		worker := fakevolume.NewWorker(chainRepo, poolRepo, userRepo, config)
+		worker := fakevolume.NewWorker(chainRepo, poolRepo, userRepo, tradeEngine, config)
 
 		// Start worker
 		err = worker.Start()
@@ -162,12 +164,13 @@ func TestFakeVolumeWorkerMultipleChains(t *testing.T) {
 		templateRepo := postgres.NewChainTemplateRepository(db)
 		chainRepo := postgres.NewChainRepository(db, userRepo, templateRepo)
 		poolRepo := postgres.NewVirtualPoolRepository(db)
+		tradeEngine := services.NewOrderProcessorTx(db, postgres.NewVirtualPoolTxRepository(db), userRepo, nil)
 
 		// Initialize fake volume worker
 		config := fakevolume.Config{
 			Interval: 1 * time.Second,
 		}
-		worker := fakevolume.NewWorker(chainRepo, poolRepo, userRepo, config)
+		worker := fakevolume.NewWorker(chainRepo, poolRepo, userRepo, tradeEngine, config)
 
 		// Start worker
 		err = worker.Start()
@@ -217,12 +220,13 @@ func TestFakeVolumeWorkerNoChains(t *testing.T) {
 		templateRepo := postgres.NewChainTemplateRepository(db)
 		chainRepo := postgres.NewChainRepository(db, userRepo, templateRepo)
 		poolRepo := postgres.NewVirtualPoolRepository(db)
+		tradeEngine := services.NewOrderProcessorTx(db, postgres.NewVirtualPoolTxRepository(db), userRepo, nil)
 
 		// Initialize fake volume worker
 		config := fakevolume.Config{
 			Interval: 1 * time.Second,
 		}
-		worker := fakevolume.NewWorker(chainRepo, poolRepo, userRepo, config)
+		worker := fakevolume.NewWorker(chainRepo, poolRepo, userRepo, tradeEngine, config)
 
 		// Start worker
 		err := worker.Start()
@@ -0,0 +1,111 @@
+//go:build integration
+
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/enielson/launchpad/internal/models"
+	"github.com/enielson/launchpad/internal/repository/postgres"
+	"github.com/enielson/launchpad/internal/services"
+	"github.com/enielson/launchpad/tests/fixtures"
+	"github.com/enielson/launchpad/tests/testutils"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGraduationFreezesVirtualPool tests that balances written into the genesis
+// can no longer be sold back to the virtual pool
+func TestGraduationFreezesVirtualPool(t *testing.T) {
+	testutils.WithTestDB(t, func(db *sqlx.DB) {
+		ctx := context.Background()
+
+		user, err := fixtures.DefaultUser().
+			WithEmail(fmt.Sprintf("graduation%d@example.com", time.Now().UnixNano())).
+			WithUsername(fmt.Sprintf("graduation%d", time.Now().UnixNano())).
+			Create(ctx, db)
+		require.NoError(t, err)
+
+		chain, err := fixtures.DefaultChain(user.ID).
+			WithStatus(models.ChainStatusVirtualActive).
+			Create(ctx, db)
+		require.NoError(t, err)
+
+		pool, err := fixtures.DefaultVirtualPool(chain.ID).Create(ctx, db)
+		require.NoError(t, err)
+
+		position, err := fixtures.DefaultUserPosition(user.ID, chain.ID, pool.ID).
+			WithPosition(1000, 10.0, 0.01).
+			Create(ctx, db)
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			db.ExecContext(context.Background(),
+				"DELETE FROM payouts WHERE chain_id = $1", chain.ID)
+			db.ExecContext(context.Background(),
+				"DELETE FROM chain_graduations WHERE chain_id = $1", chain.ID)
+			db.ExecContext(context.Background(),
+				"DELETE FROM user_virtual_positions WHERE chain_id = $1", chain.ID)
+			db.ExecContext(context.Background(),
+				"DELETE FROM virtual_pool_transactions WHERE chain_id = $1", chain.ID)
+			db.ExecContext(context.Background(),
+				"DELETE FROM virtual_pools WHERE chain_id = $1", chain.ID)
+			db.ExecContext(context.Background(),
+				"DELETE FROM chains WHERE id = $1", chain.ID)
+			db.ExecContext(context.Background(),
+				"DELETE FROM users WHERE id = $1", user.ID)
+		})
+
+		// Start the graduation and build the genesis from the current holders
+		graduations := postgres.NewChainGraduationRepository(db)
+		now := time.Now()
+		graduation, err := graduations.Create(ctx, &models.ChainGraduation{
+			ChainID:       chain.ID,
+			CurrentStep:   models.GraduationStepThresholdReached,
+			LastAttemptAt: &now,
+		})
+		require.NoError(t, err)
+
+		genesis := `{"accounts":[]}`
+		graduation.CurrentStep = models.GraduationStepGenesisGenerated
+		graduation.GenesisFile = &genesis
+		_, err = graduations.Update(ctx, graduation)
+		require.NoError(t, err)
+
+		var isActive bool
+		err = db.GetContext(ctx, &isActive,
+			"SELECT is_active FROM virtual_pools WHERE chain_id = $1", chain.ID)
+		require.NoError(t, err)
+		assert.False(t, isActive, "pool should stop trading when the graduation starts")
+
+		// A holder already in the genesis tries to sell for a CNPY payout
+		userRepo := postgres.NewUserRepository(db)
+		tradeEngine := services.NewOrderProcessorTx(db, postgres.NewVirtualPoolTxRepository(db), userRepo, nil)
+		_, err = tradeEngine.ExecuteTrade(ctx, &services.Trade{
+			ChainID:         chain.ID,
+			UserID:          user.ID,
+			Type:            models.VirtualTransactionTypeSell,
+			AmountUnits:     new(big.Int).SetUint64(position.TokenBalanceUnits),
+			PayoutAddress:   "graduation-sell-address",
+			PayoutReference: fmt.Sprintf("graduation-sell-%d", time.Now().UnixNano()),
+		})
+		require.ErrorIs(t, err, services.ErrPoolInactive)
+
+		var balance uint64
+		err = db.GetContext(ctx, &balance,
+			"SELECT token_balance_units FROM user_virtual_positions WHERE id = $1", position.ID)
+		require.NoError(t, err)
+		assert.Equal(t, position.TokenBalanceUnits, balance)
+
+		var payouts int
+		err = db.GetContext(ctx, &payouts,
+			"SELECT COUNT(*) FROM payouts WHERE chain_id = $1", chain.ID)
+		require.NoError(t, err)
+		assert.Zero(t, payouts)
+	})
+}
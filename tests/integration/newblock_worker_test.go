@@ -13,6 +13,7 @@ import (
 	"github.com/canopy-network/canopy/lib"
 	"github.com/enielson/launchpad/internal/models"
 	"github.com/enielson/launchpad/internal/repository/postgres"
+	"github.com/enielson/launchpad/internal/services"
 	"github.com/enielson/launchpad/internal/workers/newblock"
 	"github.com/enielson/launchpad/pkg/database"
 	"github.com/enielson/launchpad/tests/fixtures"
@@ -41,6 +42,22 @@ func (m *MockRPCClient) SetErrorAtHeight(height uint64, err lib.ErrorI) {
 	m.errorByHeight[height] = err
 }
 
+// Height returns the highest height with configured transactions
+func (m *MockRPCClient) Height() (*uint64, lib.ErrorI) {
+	var height uint64
+	for h := range m.txResultsByHeight {
+		if h > height {
+			height = h
+		}
+	}
+	return &height, nil
+}
+
+// CertByHeight treats every height as committed
+func (m *MockRPCClient) CertByHeight(height uint64) (*lib.QuorumCertificate, lib.ErrorI) {
+	return &lib.QuorumCertificate{BlockHash: []byte{0x01}}, nil
+}
+
 func (m *MockRPCClient) TransactionsByHeight(height uint64, page lib.PageParams) (*lib.Page, lib.ErrorI) {
 	// Check for error first
 	if err, exists := m.errorByHeight[height]; exists {
@@ -61,13 +78,16 @@ func (m *MockRPCClient) TransactionsByHeight(height uint64, page lib.PageParams)
 		}, nil
 	}
 
-	// Wrap in TxResults type
-	results := lib.TxResults(txResults)
+	// Split into pages the way the canopy RPC does
+	totalPages := (len(txResults) + page.PerPage - 1) / page.PerPage
+	start := min((page.PageNumber-1)*page.PerPage, len(txResults))
+	end := min(start+page.PerPage, len(txResults))
+	results := lib.TxResults(txResults[start:end])
 
 	return &lib.Page{
 		PageParams: page,
-		Count:      len(txResults),
-		TotalPages: 1,
+		Count:      len(results),
+		TotalPages: totalPages,
 		TotalCount: len(txResults),
 		Results:    &results,
 	}, nil
@@ -98,7 +118,8 @@ func buildSendTransaction(senderAddress, recipientAddress []byte, amount uint64,
 			MessageType: fsm.MessageSendName,
 			Msg:         anyMsg,
 		},
-		TxHash: fmt.Sprintf("0x%d", height),
+		// Unique per sender and height so sends in the same block are not deduplicated
+		TxHash: fmt.Sprintf("0x%x%d", senderAddress, height),
 	}
 }
 
@@ -139,9 +160,12 @@ func TestNewBlockWorkerProcessing(t *testing.T) {
 	}
 	defer db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", testUser.ID)
 
-	// Create test chain with fixtures
+	// Create test chain with fixtures. A unique root chain ID keeps this run's
+	// checkpoint separate from earlier runs against the same database.
+	rootChainID := uint64(timestamp)
 	testChain, err := fixtures.DefaultChain(testUser.ID).
 		WithStatus(models.ChainStatusVirtualActive).
+		WithRootChain(rootChainID).
 		Create(ctx, db)
 	if err != nil {
 		t.Fatalf("Failed to create test chain: %v", err)
@@ -181,11 +205,17 @@ func TestNewBlockWorkerProcessing(t *testing.T) {
 	// Create worker config
 	workerConfig := newblock.Config{
 		RootChainURL: "ws://localhost:50002", // Won't actually connect
-		RootChainID:  1,
+		RootChainID:  rootChainID,
 	}
+	checkpointRepo := postgres.NewRootChainCheckpointRepository(db)
+	defer db.ExecContext(ctx, "DELETE FROM root_chain_checkpoints WHERE root_chain_id = $1", rootChainID)
 
 	// Create worker with mock RPC client
-	worker := newblock.NewWorker(workerConfig, mockRPC, chainRepo, poolRepo, userRepo)
+	depositProcessor := services.NewOrderProcessorTx(db, postgres.NewVirtualPoolTxRepository(db), userRepo, nil)
+	pendingDepositRepo := postgres.NewPendingDepositRepository(db)
+	failedEventRepo := postgres.NewFailedEventRepository(db)
+	blockProcessor := services.NewBlockProcessor(db, depositProcessor, postgres.NewRootChainCheckpointTxRepository(db), postgres.NewFailedEventTxRepository(db))
+	worker := newblock.NewWorker(workerConfig, mockRPC, chainRepo, depositProcessor, blockProcessor, userRepo, checkpointRepo, pendingDepositRepo, failedEventRepo)
 
 	t.Run("process_send_transaction_to_chain", func(t *testing.T) {
 		// Create a test user with a unique wallet address
@@ -229,6 +259,55 @@ func TestNewBlockWorkerProcessing(t *testing.T) {
 		t.Logf("Pool updated: CNPY Reserve %.2f, Token Reserve %d", updatedPool.CNPYReserve, updatedPool.TokenReserve)
 	})
 
+	t.Run("replayed_block_is_applied_once", func(t *testing.T) {
+		senderAddressHex := fmt.Sprintf("%016x%024x", time.Now().UnixNano(), 0x5678)
+		senderUser, err := fixtures.DefaultUser().
+			WithEmail(fmt.Sprintf("replay_%d@test.com", time.Now().UnixNano())).
+			WithUsername(fmt.Sprintf("replay_%d", time.Now().UnixNano())).
+			WithWallet("0x"+senderAddressHex).
+			Create(ctx, db)
+		if err != nil {
+			t.Fatalf("Failed to create sender user: %v", err)
+		}
+		defer db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", senderUser.ID)
+		senderAddress, _ := hex.DecodeString(senderAddressHex)
+		height := uint64(1500)
+
+		txResult := buildSendTransaction(senderAddress, chainAddress, 2000000, height)
+		mockRPC.SetTransactionsAtHeight(height, []*lib.TxResult{txResult})
+
+		rootChainInfo := &lib.RootChainInfo{
+			RootChainId: 1,
+			Height:      height,
+			Timestamp:   uint64(time.Now().Unix()),
+		}
+
+		assert.NoError(t, worker.HandleRootChainEventForTest(rootChainInfo))
+		poolAfterFirst, err := poolRepo.GetPoolByChainID(ctx, testChain.ID)
+		assert.NoError(t, err)
+		positionAfterFirst, err := poolRepo.GetUserPosition(ctx, senderUser.ID, testChain.ID)
+		assert.NoError(t, err)
+
+		// Deliver the same block again, as happens after a reconnect
+		assert.NoError(t, worker.HandleRootChainEventForTest(rootChainInfo))
+		poolAfterReplay, err := poolRepo.GetPoolByChainID(ctx, testChain.ID)
+		assert.NoError(t, err)
+		positionAfterReplay, err := poolRepo.GetUserPosition(ctx, senderUser.ID, testChain.ID)
+		assert.NoError(t, err)
+
+		assert.Equal(t, poolAfterFirst.CNPYReserve, poolAfterReplay.CNPYReserve)
+		assert.Equal(t, poolAfterFirst.TokenReserve, poolAfterReplay.TokenReserve)
+		assert.Equal(t, poolAfterFirst.TotalTransactions, poolAfterReplay.TotalTransactions)
+		assert.Equal(t, positionAfterFirst.TokenBalance, positionAfterReplay.TokenBalance)
+
+		var recorded int
+		err = db.GetContext(ctx, &recorded,
+			"SELECT COUNT(*) FROM virtual_pool_transactions WHERE transaction_hash = $1 AND block_height = $2",
+			txResult.TxHash, int64(height))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, recorded)
+	})
+
 	t.Run("sequential_buys_matching_bonding_curve_test", func(t *testing.T) {
 		// This test matches TestBondingCurve_SequentialBuys in pkg/bondingcurve/curve_test.go
 		// Using the same initial pool state (100 CNPY, 2000 tokens) and buy amounts (100, 200, 300)
@@ -326,6 +405,93 @@ func TestNewBlockWorkerProcessing(t *testing.T) {
 		t.Logf("Total Transactions: %d\n", poolAfter.TotalTransactions)
 	})
 
+	t.Run("missed_heights_are_backfilled", func(t *testing.T) {
+		senderAddressHex := fmt.Sprintf("%016x%024x", time.Now().UnixNano(), 0x9abc)
+		senderUser, err := fixtures.DefaultUser().
+			WithEmail(fmt.Sprintf("backfill_%d@test.com", time.Now().UnixNano())).
+			WithUsername(fmt.Sprintf("backfill_%d", time.Now().UnixNano())).
+			WithWallet("0x"+senderAddressHex).
+			Create(ctx, db)
+		if err != nil {
+			t.Fatalf("Failed to create sender user: %v", err)
+		}
+		defer db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", senderUser.ID)
+		senderAddress, _ := hex.DecodeString(senderAddressHex)
+
+		// A deposit lands in a block the worker never receives an event for
+		missedHeight := uint64(2500)
+		mockRPC.SetTransactionsAtHeight(missedHeight, []*lib.TxResult{
+			buildSendTransaction(senderAddress, chainAddress, 1000000, missedHeight),
+		})
+
+		poolBefore, err := poolRepo.GetPoolByChainID(ctx, testChain.ID)
+		assert.NoError(t, err)
+
+		rootChainInfo := &lib.RootChainInfo{
+			RootChainId: 1,
+			Height:      missedHeight + 10,
+			Timestamp:   uint64(time.Now().Unix()),
+		}
+		assert.NoError(t, worker.HandleRootChainEventForTest(rootChainInfo))
+
+		poolAfter, err := poolRepo.GetPoolByChainID(ctx, testChain.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, poolBefore.TotalTransactions+1, poolAfter.TotalTransactions)
+
+		checkpoint, err := checkpointRepo.Get(ctx, rootChainID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, checkpoint) {
+			assert.Equal(t, missedHeight+10, checkpoint.Height)
+		}
+		assert.Equal(t, uint64(0), worker.Status().Lag)
+	})
+
+	t.Run("busy_block_spans_several_pages", func(t *testing.T) {
+		senderAddressHex := fmt.Sprintf("%016x%024x", time.Now().UnixNano(), 0xdef0)
+		senderUser, err := fixtures.DefaultUser().
+			WithEmail(fmt.Sprintf("busy_%d@test.com", time.Now().UnixNano())).
+			WithUsername(fmt.Sprintf("busy_%d", time.Now().UnixNano())).
+			WithWallet("0x"+senderAddressHex).
+			Create(ctx, db)
+		if err != nil {
+			t.Fatalf("Failed to create sender user: %v", err)
+		}
+		defer db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", senderUser.ID)
+		senderAddress, _ := hex.DecodeString(senderAddressHex)
+
+		// 250 sends fill three pages of the block
+		height := uint64(2600)
+		sends := 250
+		txs := make([]*lib.TxResult, 0, sends)
+		for i := 0; i < sends; i++ {
+			tx := buildSendTransaction(senderAddress, chainAddress, 10000, height)
+			tx.TxHash = fmt.Sprintf("0x%s%d", senderAddressHex, i)
+			txs = append(txs, tx)
+		}
+		mockRPC.SetTransactionsAtHeight(height, txs)
+
+		poolBefore, err := poolRepo.GetPoolByChainID(ctx, testChain.ID)
+		assert.NoError(t, err)
+
+		rootChainInfo := &lib.RootChainInfo{
+			RootChainId: 1,
+			Height:      height,
+			Timestamp:   uint64(time.Now().Unix()),
+		}
+		assert.NoError(t, worker.HandleRootChainEventForTest(rootChainInfo))
+
+		poolAfter, err := poolRepo.GetPoolByChainID(ctx, testChain.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, poolBefore.TotalTransactions+sends, poolAfter.TotalTransactions)
+
+		var applied int
+		err = db.GetContext(ctx, &applied,
+			"SELECT COUNT(*) FROM virtual_pool_transactions WHERE user_id = $1 AND block_height = $2",
+			senderUser.ID, height)
+		assert.NoError(t, err)
+		assert.Equal(t, sends, applied)
+	})
+
 	t.Run("no_transactions_for_our_chain", func(t *testing.T) {
 		height := uint64(3000)
 		otherAddress := []byte{0x01, 0x02, 0x03, 0x04}